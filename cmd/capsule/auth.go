@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/auth"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+func newAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the API key and Claude login stored in the volume",
+		Long: `The Anthropic API key is stored at ` + auth.APIKeyFile + ` in the encrypted volume and
+exposed in the container through ANTHROPIC_API_KEY_FILE. Claude Code's OAuth login
+is stored at ` + auth.OAuthCredentialsFile + `. These commands view, rotate, remove,
+and verify them.`,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set or rotate the API key",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthSet,
+	}
+	setCmd.Flags().Bool("key-stdin", false, "Read the API key from stdin instead of a prompt")
+	setCmd.Flags().Bool("test", false, "Verify the key against the API before saving it")
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show which credentials are stored (the key is masked)",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthShow,
+	}
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset",
+		Short: "Remove the API key (or the OAuth login with --oauth)",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthUnset,
+	}
+	unsetCmd.Flags().Bool("oauth", false, "Remove Claude Code's OAuth login instead of the API key")
+	unsetCmd.Flags().Bool("all", false, "Remove both the API key and the OAuth login")
+	unsetCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	testCmd := &cobra.Command{
+		Use:   "test",
+		Short: "Verify the stored API key against the Anthropic API",
+		Args:  cobra.NoArgs,
+		RunE:  runAuthTest,
+	}
+
+	cmd.AddCommand(setCmd, showCmd, unsetCmd, testCmd)
+	for _, sub := range cmd.Commands() {
+		sub.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+		sub.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	}
+
+	return cmd
+}
+
+func runAuthSet(cmd *cobra.Command, args []string) error {
+	keyStdin, err := cmd.Flags().GetBool("key-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid key-stdin flag: %w", err)
+	}
+	testKey, err := cmd.Flags().GetBool("test")
+	if err != nil {
+		return fmt.Errorf("invalid test flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	if keyStdin && passwordStdin {
+		return fmt.Errorf("--key-stdin and --password-stdin cannot be used together; set %s for the password instead", terminal.PasswordEnvVar)
+	}
+
+	// Read the key before mounting so a typo doesn't leave the volume mounted
+	var key string
+	if keyStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read API key from stdin: %w", err)
+		}
+		key = strings.TrimSpace(string(data))
+	} else {
+		secureKey, err := terminal.ReadPasswordSecure("Enter API key: ")
+		if err != nil {
+			return fmt.Errorf("failed to read API key: %w", err)
+		}
+		key = strings.TrimSpace(secureKey.String())
+		secureKey.Clear()
+	}
+	if key == "" {
+		return fmt.Errorf("API key cannot be empty")
+	}
+
+	if testKey {
+		fmt.Println("Verifying API key...")
+		if err := auth.TestAPIKey(cmd.Context(), auth.DefaultAPIBaseURL, key); err != nil {
+			return fmt.Errorf("API key not saved: %w", err)
+		}
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	previous, err := auth.ReadAPIKey(mountPoint)
+	if err != nil {
+		return err
+	}
+	if err := auth.WriteAPIKey(mountPoint, key); err != nil {
+		return err
+	}
+
+	if previous != "" && previous != key {
+		fmt.Printf("API key rotated: %s -> %s\n", auth.MaskKey(previous), auth.MaskKey(key))
+	} else {
+		fmt.Printf("API key saved: %s\n", auth.MaskKey(key))
+	}
+	fmt.Println("Running containers pick up the new key on their next read of ANTHROPIC_API_KEY_FILE.")
+	return nil
+}
+
+func runAuthShow(cmd *cobra.Command, args []string) error {
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	key, err := auth.ReadAPIKey(mountPoint)
+	if err != nil {
+		return err
+	}
+	keyFile, err := auth.Stat(mountPoint, auth.APIKeyFile)
+	if err != nil {
+		return err
+	}
+	oauth, err := auth.Stat(mountPoint, auth.OAuthCredentialsFile)
+	if err != nil {
+		return err
+	}
+
+	if key != "" {
+		fmt.Printf("API key:      %s (updated %s)\n", auth.MaskKey(key), formatModTime(keyFile.Modified))
+	} else {
+		fmt.Println("API key:      not set")
+	}
+	if oauth.Present {
+		fmt.Printf("Claude login: present (updated %s)\n", formatModTime(oauth.Modified))
+	} else {
+		fmt.Println("Claude login: not logged in")
+	}
+	return nil
+}
+
+func runAuthUnset(cmd *cobra.Command, args []string) error {
+	oauthOnly, err := cmd.Flags().GetBool("oauth")
+	if err != nil {
+		return fmt.Errorf("invalid oauth flag: %w", err)
+	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+	if oauthOnly && all {
+		return fmt.Errorf("--oauth and --all cannot be used together")
+	}
+
+	removeKey := !oauthOnly || all
+	removeOAuth := oauthOnly || all
+
+	var what []string
+	if removeKey {
+		what = append(what, "the API key")
+	}
+	if removeOAuth {
+		what = append(what, "the Claude login")
+	}
+	if !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("Remove %s from the volume?", strings.Join(what, " and ")))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if removeKey {
+		removed, err := auth.RemoveAPIKey(mountPoint)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Println("API key removed.")
+		} else {
+			fmt.Println("No API key was set.")
+		}
+	}
+	if removeOAuth {
+		removed, err := auth.RemoveOAuth(mountPoint)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Println("Claude login removed. Run 'claude' in the container to log in again.")
+		} else {
+			fmt.Println("No Claude login was stored.")
+		}
+	}
+	return nil
+}
+
+func runAuthTest(cmd *cobra.Command, args []string) error {
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	key, err := auth.ReadAPIKey(mountPoint)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("no API key set; run 'capsule auth set'")
+	}
+
+	fmt.Printf("Testing API key %s...\n", auth.MaskKey(key))
+	if err := auth.TestAPIKey(cmd.Context(), auth.DefaultAPIBaseURL, key); err != nil {
+		return err
+	}
+	fmt.Println("API key is valid.")
+	return nil
+}
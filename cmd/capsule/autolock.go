@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/autolock"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/daemon"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/notify"
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// autolockLogFile is the LaunchAgent's log under the capsule config directory.
+const autolockLogFile = "autolock.log"
+
+const (
+	// defaultUnlockedWarning is how long a volume may stay mounted before the
+	// watcher posts a reminder.
+	defaultUnlockedWarning = 4 * time.Hour
+
+	// monitorInterval is how often the watcher checks mounts and containers.
+	monitorInterval = time.Minute
+)
+
+func newAutolockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "autolock",
+		Short: "Lock all volumes when the screen locks",
+		Long: `Watches macOS screen-lock and screensaver notifications and runs 'capsule lock --all'
+when they fire, stopping every capsule container and unmounting every volume.
+
+Unless --notify=false, the watcher also posts macOS notifications when it locks,
+when a capsule container exits on its own, and when a volume has stayed mounted
+longer than --unlocked-warning.
+
+'autolock run' watches in the foreground. 'autolock install' registers a LaunchAgent
+so the watcher starts at login.`,
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Watch for screen lock in the foreground",
+		Args:  cobra.NoArgs,
+		RunE:  runAutolockRun,
+	}
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Start the watcher at login with a LaunchAgent",
+		Args:  cobra.NoArgs,
+		RunE:  runAutolockInstall,
+	}
+	for _, sub := range []*cobra.Command{runCmd, installCmd} {
+		sub.Flags().String("on", string(autolock.ScreenLocked),
+			fmt.Sprintf("Comma-separated events that trigger a lock: %s, %s", autolock.ScreenLocked, autolock.ScreensaverStarted))
+		sub.Flags().Bool("notify", true, "Post macOS notifications for locks, crashed containers, and long unlocks")
+		sub.Flags().Duration("unlocked-warning", defaultUnlockedWarning, "Notify when a volume has been mounted this long (0 to disable)")
+	}
+
+	cmd.AddCommand(
+		runCmd,
+		installCmd,
+		&cobra.Command{
+			Use:   "uninstall",
+			Short: "Remove the LaunchAgent",
+			Args:  cobra.NoArgs,
+			RunE:  runAutolockUninstall,
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show whether the LaunchAgent is installed and running",
+			Args:  cobra.NoArgs,
+			RunE:  runAutolockStatus,
+		},
+	)
+
+	return cmd
+}
+
+func runAutolockRun(cmd *cobra.Command, args []string) error {
+	onFlag, err := cmd.Flags().GetString("on")
+	if err != nil {
+		return fmt.Errorf("invalid on flag: %w", err)
+	}
+	events, err := autolock.ParseEvents(onFlag)
+	if err != nil {
+		return err
+	}
+	notifyEnabled, err := cmd.Flags().GetBool("notify")
+	if err != nil {
+		return fmt.Errorf("invalid notify flag: %w", err)
+	}
+	unlockedWarning, err := cmd.Flags().GetDuration("unlocked-warning")
+	if err != nil {
+		return fmt.Errorf("invalid unlocked-warning flag: %w", err)
+	}
+
+	ctx := cmd.Context()
+	if notifyEnabled {
+		go monitorSessions(ctx, notify.NewMonitor(unlockedWarning))
+	}
+
+	fmt.Fprintf(os.Stderr, "Watching for %s (Ctrl+C to stop)...\n", onFlag)
+	return autolock.Watch(ctx, events, func(event autolock.Event) {
+		fmt.Fprintf(os.Stderr, "%s: %s, locking all capsule volumes\n", time.Now().Format(time.RFC3339), event)
+		// Finish locking even if capsule is being stopped
+		result, err := runLockAll(context.WithoutCancel(ctx), output.FormatText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if result.locked > 0 || result.stopped > 0 {
+			reportDaemonEvent(daemon.Event{Type: daemon.EventAutoLock, Reason: string(event)})
+		}
+		if notifyEnabled && (result.locked > 0 || result.stopped > 0) {
+			postNotification(notify.Locked(string(event), result.locked, result.stopped))
+		}
+	})
+}
+
+// monitorSessions checks mounted volumes and exited containers until ctx is
+// cancelled, posting the notifications the monitor says are due.
+func monitorSessions(ctx context.Context, monitor *notify.Monitor) {
+	volumeManager, err := volume.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: session notifications disabled: %v\n", err)
+		return
+	}
+	dockerManager := docker.NewManager()
+
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+	for {
+		var mountPoints []string
+		if mounted, err := volumeManager.ListMounted(ctx); err == nil {
+			for _, v := range mounted {
+				mountPoints = append(mountPoints, v.MountPoint)
+			}
+		}
+		var exited []notify.ExitedContainer
+		if containers, err := dockerManager.ListExited(ctx); err == nil {
+			for _, c := range containers {
+				exited = append(exited, notify.ExitedContainer{Name: c.Name, Status: c.Status})
+			}
+		}
+
+		for _, note := range monitor.Check(time.Now(), mountPoints, exited) {
+			postNotification(note)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// postNotification shows a notification and records it in the watcher's log.
+func postNotification(note notify.Notification) {
+	fmt.Fprintf(os.Stderr, "%s: %s: %s\n", time.Now().Format(time.RFC3339), note.Title, note.Message)
+	if err := notify.Send(note.Title, note.Message); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+func runAutolockInstall(cmd *cobra.Command, args []string) error {
+	onFlag, err := cmd.Flags().GetString("on")
+	if err != nil {
+		return fmt.Errorf("invalid on flag: %w", err)
+	}
+	if _, err := autolock.ParseEvents(onFlag); err != nil {
+		return err
+	}
+	notifyEnabled, err := cmd.Flags().GetBool("notify")
+	if err != nil {
+		return fmt.Errorf("invalid notify flag: %w", err)
+	}
+	unlockedWarning, err := cmd.Flags().GetDuration("unlocked-warning")
+	if err != nil {
+		return fmt.Errorf("invalid unlocked-warning flag: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate capsule binary: %w", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return fmt.Errorf("failed to locate capsule binary: %w", err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	logPath := filepath.Join(homeDir, constants.CapsuleConfigDir, autolockLogFile)
+	if err := os.MkdirAll(filepath.Dir(logPath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(logPath), err)
+	}
+
+	plistPath, err := autolock.LaunchAgentPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(plistPath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(plistPath), err)
+	}
+
+	// Reinstalling replaces the running agent with the new settings
+	_ = exec.Command("launchctl", "unload", plistPath).Run()
+
+	plist := autolock.LaunchAgentPlist([]string{executable, "autolock", "run", "--on", onFlag,
+		fmt.Sprintf("--notify=%t", notifyEnabled), "--unlocked-warning", unlockedWarning.String()}, logPath)
+	if err := os.WriteFile(plistPath, []byte(plist), constants.PublicFilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", plistPath, err)
+	}
+	if output, err := exec.Command("launchctl", "load", "-w", plistPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to load LaunchAgent: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	fmt.Printf("Auto-lock installed: volumes lock on %s.\n", onFlag)
+	fmt.Printf("LaunchAgent: %s\n", plistPath)
+	fmt.Printf("Log:         %s\n", logPath)
+	return nil
+}
+
+func runAutolockUninstall(cmd *cobra.Command, args []string) error {
+	plistPath, err := autolock.LaunchAgentPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(plistPath); os.IsNotExist(err) {
+		fmt.Println("Auto-lock is not installed.")
+		return nil
+	}
+
+	if output, err := exec.Command("launchctl", "unload", "-w", plistPath).CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: launchctl unload failed: %v: %s\n", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Remove(plistPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", plistPath, err)
+	}
+	fmt.Println("Auto-lock uninstalled.")
+	return nil
+}
+
+func runAutolockStatus(cmd *cobra.Command, args []string) error {
+	plistPath, err := autolock.LaunchAgentPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(plistPath); os.IsNotExist(err) {
+		fmt.Println("Auto-lock: not installed")
+		return nil
+	}
+
+	state := "installed, not running"
+	if exec.Command("launchctl", "list", autolock.LaunchAgentLabel).Run() == nil {
+		state = "installed, running"
+	}
+	fmt.Printf("Auto-lock: %s\n", state)
+	fmt.Printf("LaunchAgent: %s\n", plistPath)
+	return nil
+}
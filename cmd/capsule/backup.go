@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/remotebackup"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup --remote s3://BUCKET/PREFIX",
+		Short: "Upload the locked volume image to S3, encrypted on this machine",
+		Long: `Uploads the volume image, with its key stretching parameters and manifest,
+to S3 in 64 MB chunks. Each chunk is encrypted with AES-256-GCM before it
+leaves this machine, under a key stretched with Argon2id from a backup
+password you choose; the bucket only ever holds ciphertext. An index
+recording each chunk's checksum is uploaded last and authenticated with the
+same key, so a restore detects a wrong password and any missing, altered,
+or reordered chunk.
+
+The volume must be locked, so the image doesn't change while it uploads.
+An interrupted backup resumes where it stopped when run again with the same
+password, as long as the image hasn't changed.
+
+Uploads use the AWS CLI (aws), with its usual credentials and profiles.
+Keep the backup password somewhere other than this machine: without it the
+backup cannot be restored.`,
+		Args: cobra.NoArgs,
+		RunE: runBackup,
+	}
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+
+	listCmd := &cobra.Command{
+		Use:   "list --remote s3://BUCKET/PREFIX",
+		Short: "List the complete backups at a remote, oldest first",
+		Args:  cobra.NoArgs,
+		RunE:  runBackupList,
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore --remote s3://BUCKET/PREFIX",
+		Short: "Download a backup to a new volume and upgrade it",
+		Long: `Downloads a backup, the latest unless --id is given, checks every chunk
+against its index, and writes the image to --volume (a name or path, as
+bootstrap's --volume takes it) or to the global volume path. The volume is
+then mounted once with its own password to bring it up to this version of
+capsule, as 'capsule bootstrap --from-backup' does.`,
+		Args: cobra.NoArgs,
+		RunE: runBackupRestore,
+	}
+	restoreCmd.Flags().String("id", "", "Backup to restore (default: the latest; see 'capsule backup list')")
+	restoreCmd.Flags().String("volume", "", "Path or name of the volume to create (default: the global volume)")
+	restoreCmd.Flags().String("password-file", "", "Read the volume's password from a file only you can read (mode 0600)")
+	restoreCmd.Flags().Bool("password-stdin", false, "Read the volume's password from stdin instead of terminal prompt")
+
+	cmd.AddCommand(listCmd, restoreCmd)
+	for _, c := range []*cobra.Command{cmd, listCmd, restoreCmd} {
+		c.Flags().String("remote", "", "Where backups are kept, as s3://BUCKET/PREFIX (required)")
+	}
+	for _, c := range []*cobra.Command{cmd, restoreCmd} {
+		c.Flags().String("backup-password-file", "", "Read the backup password from a file only you can read (mode 0600)")
+	}
+
+	return cmd
+}
+
+// remoteStoreFromFlags returns the store for --remote.
+func remoteStoreFromFlags(cmd *cobra.Command) (remotebackup.Remote, remotebackup.Store, error) {
+	value, err := cmd.Flags().GetString("remote")
+	if err != nil {
+		return remotebackup.Remote{}, nil, fmt.Errorf("invalid remote flag: %w", err)
+	}
+	if value == "" {
+		return remotebackup.Remote{}, nil, fmt.Errorf("--remote is required, e.g. --remote s3://my-bucket/capsule")
+	}
+	remote, err := remotebackup.ParseRemote(value)
+	if err != nil {
+		return remotebackup.Remote{}, nil, err
+	}
+	return remote, &remotebackup.S3{Remote: remote}, nil
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("backup-password-file")
+	if err != nil {
+		return fmt.Errorf("invalid backup-password-file flag: %w", err)
+	}
+	remote, store, err := remoteStoreFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	releaseLocks, err := lockOperation(cmd.CommandPath(), "", volumePath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if mountPoint := volumeManager.GetMountPoint(ctx, volumePath); mountPoint != "" {
+		return fmt.Errorf("volume is mounted at %s; run 'capsule lock' first so the image doesn't change while it uploads", mountPoint)
+	}
+	statePath, err := remotebackup.StatePath(remote.String(), volumePath)
+	if err != nil {
+		return err
+	}
+
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = readNewPasswordFile(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordConfirmSecure("Enter the backup password: ", "Confirm backup password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	indicator := startProgress(fmt.Sprintf("Uploading %s to %s", filepath.Base(volumePath), remote))
+	index, err := remotebackup.Backup(ctx, remotebackup.BackupOptions{
+		Store:      store,
+		Remote:     remote.String(),
+		VolumePath: volumePath,
+		Password:   password,
+		Version:    version,
+		StatePath:  statePath,
+		Progress: func(done, total int) {
+			indicator.SetPercent(float64(done) / float64(total) * 100)
+			indicator.SetDetail(fmt.Sprintf("chunk %d of %d", done, total))
+		},
+	})
+	indicator.Done(err)
+	if err != nil {
+		return fmt.Errorf("backup failed: %w\nRun the same command again to resume", err)
+	}
+	fmt.Printf("Backed up %s (%s) to %s as %s.\n", volumePath, formatBytes(index.ImageSize), remote, index.ID)
+	return nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	remote, store, err := remoteStoreFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	ids, err := remotebackup.List(cmd.Context(), store)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		infof("No backups at %s.\n", remote)
+		return nil
+	}
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	id, err := cmd.Flags().GetString("id")
+	if err != nil {
+		return fmt.Errorf("invalid id flag: %w", err)
+	}
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	backupPasswordFile, err := cmd.Flags().GetString("backup-password-file")
+	if err != nil {
+		return fmt.Errorf("invalid backup-password-file flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	if passwordStdin && passwordFile != "" {
+		return fmt.Errorf("--password-stdin and --password-file cannot be used together")
+	}
+	remote, store, err := remoteStoreFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath := pathResolver.GetDefaultVolumePath()
+	if volumePathFlag != "" {
+		volumePath = pathResolver.ResolveName(volumePathFlag)
+	}
+	volumePath, err = filepath.Abs(volumePath)
+	if err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if volumeManager.Exists(volumePath) {
+		return fmt.Errorf("volume already exists at %s", volumePath)
+	}
+
+	ids, err := remotebackup.List(ctx, store)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(ids) == 0:
+		return fmt.Errorf("no backups at %s", remote)
+	case id == "":
+		id = ids[len(ids)-1]
+	case !slices.Contains(ids, id):
+		return fmt.Errorf("no backup %s at %s (see 'capsule backup list')", id, remote)
+	}
+
+	var password *terminal.SecurePassword
+	if backupPasswordFile != "" {
+		password, err = terminal.ReadPasswordFromFileSecure(backupPasswordFile)
+	} else {
+		password, err = terminal.ReadPasswordSecure("Enter the backup password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	indicator := startProgress(fmt.Sprintf("Downloading backup %s to %s", id, volumePath))
+	_, err = remotebackup.Restore(ctx, store, id, volumePath, password, func(done, total int) {
+		indicator.SetPercent(float64(done) / float64(total) * 100)
+		indicator.SetDetail(fmt.Sprintf("chunk %d of %d", done, total))
+	})
+	indicator.Done(err)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return upgradeRestoredVolume(ctx, volumeManager, volumePath, passwordFile, passwordStdin, nil)
+}
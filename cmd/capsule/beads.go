@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/beads"
+)
+
+func newBeadsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "beads",
+		Short: "Manage the bd issue tracker installed in the volume",
+	}
+
+	cmd.AddCommand(newBeadsStatusCmd(), newBeadsInstallCmd())
+
+	return cmd
+}
+
+func newBeadsStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the installed bd version and per-project database sizes",
+		Args:  cobra.NoArgs,
+		RunE:  runBeadsStatus,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+
+	return cmd
+}
+
+func newBeadsInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install or upgrade bd to the version pinned by this capsule release",
+		Args:  cobra.NoArgs,
+		RunE:  runBeadsInstall,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	cmd.Flags().Bool("force", false, "Reinstall even if the pinned version is already installed")
+
+	return cmd
+}
+
+func runBeadsStatus(cmd *cobra.Command, args []string) error {
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	status, err := beads.GetStatus(mountPoint)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Beads Status")
+	fmt.Println("============")
+	fmt.Println()
+	fmt.Printf("Pinned:     %s\n", status.PinnedVersion)
+	switch {
+	case status.InstalledVersion == "":
+		fmt.Println("Installed:  No")
+	case status.UpToDate():
+		fmt.Printf("Installed:  %s\n", status.InstalledVersion)
+	default:
+		fmt.Printf("Installed:  %s (upgrade available)\n", status.InstalledVersion)
+	}
+
+	fmt.Println()
+	if len(status.Projects) == 0 {
+		fmt.Println("No project databases.")
+	} else {
+		fmt.Println("Project databases:")
+		var total int64
+		for _, p := range status.Projects {
+			fmt.Printf("  %-50s %10s\n", p.RepoID, formatBytes(p.Bytes))
+			total += p.Bytes
+		}
+		fmt.Printf("  %-50s %10s\n", "Total", formatBytes(total))
+	}
+
+	if !status.UpToDate() {
+		fmt.Println("\nRun 'capsule beads install' to install the pinned version.")
+	}
+	return nil
+}
+
+func runBeadsInstall(cmd *cobra.Command, args []string) error {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("invalid force flag: %w", err)
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	installed := beads.InstalledVersion(mountPoint)
+	if installed == beads.Version && !force {
+		fmt.Printf("bd %s is already installed. Use --force to reinstall.\n", installed)
+		return nil
+	}
+
+	fmt.Printf("Installing bd %s...\n", beads.Version)
+	if err := beads.Install(mountPoint); err != nil {
+		return fmt.Errorf("failed to install bd: %w", err)
+	}
+
+	if installed != "" && installed != beads.Version {
+		fmt.Printf("bd upgraded from %s to %s.\n", installed, beads.Version)
+	} else {
+		fmt.Printf("bd %s installed.\n", beads.Version)
+	}
+	fmt.Println("Restart running containers to pick up the new binary.")
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/bench"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// benchExecsPerIteration is how many commands each iteration runs in the
+// container; exec is fast enough that one sample is mostly noise.
+const benchExecsPerIteration = 5
+
+func newBenchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Time mounting, container start, and exec on this machine",
+		Long: `Times the operations a session is made of: mounting the volume, starting the
+container, running a command in it (docker exec), and unmounting the volume.
+Each iteration does all four, in that order, against a scratch volume with a
+random password, created for the run and deleted afterwards. Your volumes
+are not touched.
+
+Every run is recorded in ~/.capsule/` + bench.FileName + ` with the Docker version it
+ran against, and compared with the previous run: operations whose median got
+more than 25% slower are flagged, so a slowdown after a Docker Desktop update
+is easy to spot. --history lists the recorded runs.`,
+		Args: cobra.NoArgs,
+		RunE: runBench,
+	}
+
+	cmd.Flags().Int("iterations", 3, "How many times to mount, start, exec, and unmount")
+	cmd.Flags().String("image", docker.DefaultImageName, "Image to start the container from")
+	cmd.Flags().Bool("history", false, "List recorded runs instead of benchmarking")
+	cmd.Flags().Bool("json", false, "Print the run, or with --history the runs, as JSON")
+
+	return cmd
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	iterations, err := cmd.Flags().GetInt("iterations")
+	if err != nil {
+		return fmt.Errorf("invalid iterations flag: %w", err)
+	}
+	if iterations < 1 {
+		return fmt.Errorf("invalid iterations flag: must be at least 1")
+	}
+	imageName, err := cmd.Flags().GetString("image")
+	if err != nil {
+		return fmt.Errorf("invalid image flag: %w", err)
+	}
+	showHistory, err := cmd.Flags().GetBool("history")
+	if err != nil {
+		return fmt.Errorf("invalid history flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("invalid json flag: %w", err)
+	}
+
+	path, err := bench.DefaultPath()
+	if err != nil {
+		return err
+	}
+	runs, err := bench.Load(path)
+	if err != nil {
+		return err
+	}
+	if showHistory {
+		return printBenchHistory(runs, asJSON)
+	}
+
+	ctx := cmd.Context()
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	dockerManager := docker.NewManager()
+	dockerVersion, err := dockerManager.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if !embedded.ImageExists(imageName) {
+		return fmt.Errorf("docker image %s not found: build it with 'capsule build-image'", imageName)
+	}
+
+	// Keep stdout for the results, so they can be piped or parsed
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	run, err := benchmark(ctx, volumeManager, dockerManager, imageName, iterations)
+	os.Stdout = stdout
+	if err != nil {
+		return err
+	}
+	run.DockerVersion = dockerVersion
+	if err := bench.Append(path, run); err != nil {
+		slog.Warn("failed to record benchmark run", "error", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(run)
+	}
+	var previous *bench.Run
+	if len(runs) > 0 {
+		previous = &runs[len(runs)-1]
+	}
+	return printBenchRun(run, previous)
+}
+
+// benchmark times iterations of mount, container start, exec, and unmount
+// against a scratch volume, which it creates first and removes at the end.
+func benchmark(ctx context.Context, volumeManager volume.VolumeManager, dockerManager *docker.Manager, imageName string, iterations int) (bench.Run, error) {
+	dir, err := os.MkdirTemp("", "capsule-bench-")
+	if err != nil {
+		return bench.Run{}, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	workspace := filepath.Join(dir, "workspace")
+	if err := os.Mkdir(workspace, constants.DirPermissions); err != nil {
+		return bench.Run{}, fmt.Errorf("failed to create scratch workspace: %w", err)
+	}
+
+	secret := make([]byte, 24)
+	rand.Read(secret)
+	password := terminal.NewSecurePassword([]byte(hex.EncodeToString(secret)))
+	defer password.Clear()
+
+	// An identity volume holds nothing but its layout, so it is quick to create
+	volumePath := filepath.Join(dir, "bench"+volume.DefaultImageFormat.Ext())
+	infoln("Creating a scratch volume...")
+	err = volumeManager.Bootstrap(ctx, volume.BootstrapConfig{
+		VolumePath: volumePath,
+		SizeGB:     constants.MinVolumeSizeGB,
+		Password:   password,
+		Version:    version,
+		Identity:   true,
+	})
+	if err != nil {
+		return bench.Run{}, fmt.Errorf("failed to create scratch volume: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	containerName := "capsule-bench-" + hex.EncodeToString(suffix)
+	var mountPoint string
+	var containerStarted bool
+	defer func() {
+		// Clean up after a failure or Ctrl+C
+		cleanupCtx := context.WithoutCancel(ctx)
+		if containerStarted {
+			dockerManager.RemoveContainer(cleanupCtx, containerName)
+		}
+		if mountPoint != "" {
+			volumeManager.Unmount(cleanupCtx, mountPoint)
+		}
+	}()
+
+	var recorder bench.Recorder
+	for i := range iterations {
+		infof("Iteration %d of %d...\n", i+1, iterations)
+		err := recorder.Time(bench.OpMount, func() error {
+			var err error
+			mountPoint, err = volumeManager.Mount(ctx, volumePath, password)
+			return err
+		})
+		if err != nil {
+			return bench.Run{}, fmt.Errorf("failed to mount scratch volume: %w", err)
+		}
+
+		config := docker.ContainerConfig{
+			ImageName:        imageName,
+			ContainerName:    containerName,
+			VolumeMountPoint: mountPoint,
+			WorkspacePath:    workspace,
+			VolumePath:       volumePath,
+			CapsuleVersion:   version,
+		}
+		containerStarted = true
+		if err := recorder.Time(bench.OpContainerStart, func() error { return dockerManager.Start(ctx, config) }); err != nil {
+			return bench.Run{}, err
+		}
+		for range benchExecsPerIteration {
+			err := recorder.Time(bench.OpExec, func() error {
+				return dockerManager.ExecCommand(ctx, containerName, docker.ExecOptions{Command: []string{"true"}})
+			})
+			if err != nil {
+				return bench.Run{}, fmt.Errorf("failed to run a command in the container: %w", err)
+			}
+		}
+		if err := dockerManager.RemoveContainer(ctx, containerName); err != nil {
+			return bench.Run{}, err
+		}
+		containerStarted = false
+
+		if err := recorder.Time(bench.OpUnmount, func() error { return volumeManager.Unmount(ctx, mountPoint) }); err != nil {
+			return bench.Run{}, fmt.Errorf("failed to unmount scratch volume: %w", err)
+		}
+		mountPoint = ""
+	}
+
+	return bench.Run{
+		Time:       time.Now().UTC(),
+		Version:    version,
+		Iterations: iterations,
+		Timings:    recorder.Timings(),
+	}, nil
+}
+
+// printBenchRun prints the run's timings next to the previous run's, flagging
+// operations that got slower by more than bench.RegressionThreshold.
+func printBenchRun(run bench.Run, previous *bench.Run) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tMEDIAN\tMIN\tMAX\tPREVIOUS\tCHANGE")
+	var regressions int
+	for _, t := range run.Timings {
+		prevMedian, change := "-", "-"
+		if previous != nil {
+			if prev, ok := previous.Timing(t.Op); ok {
+				delta := bench.Change(prev, t)
+				prevMedian = formatMillis(prev.MedianMS)
+				change = fmt.Sprintf("%+.0f%%", delta*100)
+				if delta > bench.RegressionThreshold {
+					change += " slower"
+					regressions++
+				}
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Op,
+			formatMillis(t.MedianMS), formatMillis(t.MinMS), formatMillis(t.MaxMS), prevMedian, change)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nDocker %s, %d iteration(s)\n", run.DockerVersion, run.Iterations)
+	if previous == nil {
+		return nil
+	}
+	if previous.DockerVersion != run.DockerVersion {
+		fmt.Printf("Docker was %s at the previous run (%s).\n", previous.DockerVersion, previous.Time.Local().Format("2006-01-02 15:04"))
+	}
+	if regressions > 0 {
+		fmt.Printf("%d operation(s) more than %.0f%% slower than the previous run.\n", regressions, bench.RegressionThreshold*100)
+	}
+	return nil
+}
+
+// printBenchHistory lists recorded runs, oldest first, with each operation's
+// median.
+func printBenchHistory(runs []bench.Run, asJSON bool) error {
+	if asJSON {
+		if runs == nil {
+			runs = []bench.Run{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(runs)
+	}
+	if len(runs) == 0 {
+		fmt.Println("No benchmark runs recorded. Run 'capsule bench'.")
+		return nil
+	}
+
+	ops := []string{bench.OpMount, bench.OpContainerStart, bench.OpExec, bench.OpUnmount}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tCAPSULE\tDOCKER\tMOUNT\tCONTAINER_START\tEXEC\tUNMOUNT")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s", run.Time.Local().Format("2006-01-02 15:04"), run.Version, run.DockerVersion)
+		for _, op := range ops {
+			median := "-"
+			if t, ok := run.Timing(op); ok {
+				median = formatMillis(t.MedianMS)
+			}
+			fmt.Fprintf(tw, "\t%s", median)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// formatMillis renders a duration given in milliseconds, switching to
+// seconds from one second up.
+func formatMillis(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.2fs", ms/1000)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+func newClaudeVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "claude-version",
+		Short: "Show the Claude Code release in the image and the newest one",
+		Long: `Reports the Claude Code release installed in the image capsule start uses,
+the pin from claude_code_version in ~/.capsule/config.json, and the newest
+release on npm.
+
+--upgrade rebuilds the image with the newest release after asking for
+confirmation; add --dry-run to see what it would rebuild. It refuses when the config pins an exact release: change the pin
+instead, so the upgrade is recorded where every build sees it.`,
+		Args: cobra.NoArgs,
+		RunE: runClaudeVersion,
+	}
+
+	cmd.Flags().String("flavor", "", "Image flavor: slim, standard, or full (default from config, else standard)")
+	cmd.Flags().String("template", "", "Check the image for a template: go, node, python, or ml")
+	cmd.Flags().Bool("upgrade", false, "Rebuild the image with the newest Claude Code release")
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	addDryRunFlag(cmd)
+
+	return cmd
+}
+
+func runClaudeVersion(cmd *cobra.Command, args []string) error {
+	flavorFlag, err := cmd.Flags().GetString("flavor")
+	if err != nil {
+		return fmt.Errorf("invalid flavor flag: %w", err)
+	}
+	templateFlag, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	template, err := embedded.ParseTemplate(templateFlag)
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	upgrade, err := cmd.Flags().GetBool("upgrade")
+	if err != nil {
+		return fmt.Errorf("invalid upgrade flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+
+	ctx := cmd.Context()
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	opts, err := imageBuildOptions(ctx, settings, flavorFlag)
+	if err != nil {
+		return err
+	}
+	opts.Template = template
+	imageName := docker.ImageName(opts.Flavor, opts.Template)
+	if !embedded.ImageExists(imageName) {
+		return fmt.Errorf("image %s not found; build it with 'capsule build-image'", imageName)
+	}
+
+	installed, err := embedded.InstalledClaudeCodeVersion(ctx, imageName)
+	if err != nil {
+		return err
+	}
+	var proxyEnv []string
+	if !settings.NoHostProxy {
+		proxyEnv = docker.HostProxyEnv(ctx)
+	}
+	latest, latestErr := embedded.LatestClaudeCodeVersion(ctx, imageName, proxyEnv)
+
+	fmt.Printf("Image:      %s\n", imageName)
+	if opts.ClaudeCodeVersion != "" {
+		fmt.Printf("Pinned:     %s\n", opts.ClaudeCodeVersion)
+	} else {
+		fmt.Println("Pinned:     No (latest when the image was built)")
+	}
+	fmt.Printf("Installed:  %s\n", installed)
+	switch {
+	case latestErr != nil:
+		fmt.Println("Latest:     unknown")
+	case latest == installed:
+		fmt.Printf("Latest:     %s (up to date)\n", latest)
+	default:
+		fmt.Printf("Latest:     %s (upgrade available)\n", latest)
+	}
+
+	if !upgrade {
+		if latestErr == nil && latest != installed {
+			fmt.Println("\nRun 'capsule claude-version --upgrade' to rebuild the image with it.")
+		}
+		return nil
+	}
+	if latestErr != nil {
+		return latestErr
+	}
+	if latest == installed {
+		fmt.Println("\nNothing to upgrade.")
+		return nil
+	}
+	if embedded.IsExactRelease(opts.ClaudeCodeVersion) {
+		return fmt.Errorf("claude_code_version in %s pins %s; set it to %s and run 'capsule build-image' to upgrade",
+			config.SettingsFile, opts.ClaudeCodeVersion, latest)
+	}
+
+	if dryRun {
+		var plan dryRunPlan
+		if opts.ClaudeCodeVersion == "" {
+			plan.add("Rebuild %s with Claude Code %s", imageName, latest)
+		} else {
+			plan.add("Rebuild %s without the build cache, installing the release %s points to", imageName, opts.ClaudeCodeVersion)
+		}
+		fmt.Println()
+		plan.print(cmd.CommandPath() + " --upgrade")
+		return nil
+	}
+	if !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("Rebuild %s with Claude Code %s?", imageName, latest))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	if opts.ClaudeCodeVersion == "" {
+		opts.ClaudeCodeVersion = latest
+	} else {
+		// A dist-tag pin such as stable only moves on a fresh build
+		opts.NoCache = true
+	}
+	if err := buildImage(ctx, imageName, opts); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	// A stable pin can trail the newest release, so report what was installed
+	upgraded, err := embedded.InstalledClaudeCodeVersion(ctx, imageName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Claude Code upgraded from %s to %s.\n", installed, upgraded)
+	fmt.Println("Restart running containers to use it.")
+	return nil
+}
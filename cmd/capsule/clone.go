@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/auth"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/migrate"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// bytesPerGB converts a volume's capacity to the GB bootstrap sizes it in.
+const bytesPerGB = 1 << 30
+
+func newCloneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone NAME",
+		Short: "Create a new volume with a copy of an existing volume's contents",
+		Long: `Creates a new encrypted volume with its own password and copies the
+contents of an existing volume into it: CLAUDE.md, skills, settings, memory,
+shell history, and repos/ folders. Use it to start a client-specific
+environment from a well-tuned personal one.
+
+NAME is a volume name or path, as --volume takes it; a plain name creates
+~/.capsule/volumes/NAME.sparseimage, so it can then be used with
+--volume NAME. The source is --volume, or the volume for the current
+directory. It is mounted read-only if it isn't mounted already.
+
+--no-credentials leaves out auth/ (the API key) and Claude Code's OAuth login,
+so the new volume starts logged out. The new volume is as large as the source
+unless --size is given, and is upgraded to this version of capsule.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runClone,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of the volume to copy (auto-detected if not specified)")
+	cmd.Flags().Bool("no-credentials", false, "Leave out auth/ and Claude Code's OAuth login")
+	cmd.Flags().Int("size", 0, "New volume size in GB (default: the source volume's size)")
+	cmd.Flags().String("password-file", "", "Read the new volume's password from a file only you can read (mode 0600)")
+
+	return cmd
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	noCredentials, err := cmd.Flags().GetBool("no-credentials")
+	if err != nil {
+		return fmt.Errorf("invalid no-credentials flag: %w", err)
+	}
+	size, err := cmd.Flags().GetInt("size")
+	if err != nil {
+		return fmt.Errorf("invalid size flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	if size != 0 && (size < constants.MinVolumeSizeGB || size > constants.MaxVolumeSizeGB) {
+		return fmt.Errorf("volume size must be between %d and %d GB", constants.MinVolumeSizeGB, constants.MaxVolumeSizeGB)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	sourcePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	targetPath, err := filepath.Abs(pathResolver.ResolveName(args[0]))
+	if err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	// A name gets the source's format; a path names its own
+	if !volume.HasImageExt(args[0]) {
+		targetPath = volume.WithFormat(targetPath, volume.FormatOf(sourcePath))
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if volumeManager.Exists(targetPath) {
+		return fmt.Errorf("volume already exists at %s", targetPath)
+	}
+
+	sourceMount, release, err := mountForHostAccess(ctx, volumeManager, sourcePath, false, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if size == 0 {
+		capacity, err := volume.FilesystemCapacity(sourceMount)
+		if err != nil {
+			return err
+		}
+		size = int((capacity.TotalBytes + bytesPerGB - 1) / bytesPerGB)
+		size = min(max(size, constants.MinVolumeSizeGB), constants.MaxVolumeSizeGB)
+	}
+	template, err := embedded.ReadTemplateFile(sourceMount)
+	if err != nil {
+		return err
+	}
+	var exclude []string
+	if noCredentials {
+		exclude = auth.IdentityPaths
+	}
+
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = readNewPasswordFile(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordConfirmSecure("Enter a password for the new volume: ", "Confirm password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	fmt.Printf("Cloning %s to %s (%d GB)...\n", sourcePath, targetPath, size)
+	cfg := volume.BootstrapConfig{
+		VolumePath: targetPath,
+		SizeGB:     size,
+		Password:   password,
+		Version:    version,
+		Template:   template,
+		Format:     volume.FormatOf(targetPath),
+		Setup: func(mountPoint string) error {
+			if err := volume.CloneContents(sourceMount, mountPoint, exclude); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", sourcePath, err)
+			}
+			_, err := migrate.UpgradeVolume(mountPoint, version)
+			return err
+		},
+	}
+	if err := volumeManager.Bootstrap(ctx, cfg); err != nil {
+		return fmt.Errorf("clone failed: %w", err)
+	}
+
+	fmt.Printf("Cloned %s to %s.\n", sourcePath, targetPath)
+	if noCredentials {
+		fmt.Println("Credentials were left out; log in or run 'capsule auth set' in the new volume.")
+	}
+	fmt.Println("")
+	fmt.Println("Next step:")
+	fmt.Printf("  capsule start --volume %s\n", args[0])
+	return nil
+}
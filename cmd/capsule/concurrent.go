@@ -0,0 +1,33 @@
+package main
+
+import "golang.org/x/sync/errgroup"
+
+// runConcurrently runs fns in parallel and returns the first error as soon as
+// it happens. Functions still running are left to finish in the background;
+// callers return the error and exit, so their results are never used.
+func runConcurrently(fns ...func() error) error {
+	var g errgroup.Group
+	firstErr := make(chan error, 1)
+	for _, fn := range fns {
+		g.Go(func() error {
+			err := fn()
+			if err != nil {
+				select {
+				case firstErr <- err:
+				default:
+				}
+			}
+			return err
+		})
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- g.Wait() }()
+
+	select {
+	case err := <-firstErr:
+		return err
+	case err := <-done:
+		return err
+	}
+}
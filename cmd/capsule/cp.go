@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/progress"
+	"github.com/jeanhaley32/claude-capsule/internal/transfer"
+)
+
+func newCpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cp SRC DEST",
+		Short: "Copy files between the host and the workspace's container",
+		Long: `Copies a file or directory into or out of the container for the current
+workspace, like docker cp without needing the container name. Exactly one of
+SRC and DEST is a container path, written with the capsule: prefix; relative
+container paths are taken from /workspace.
+
+If DEST is an existing directory, SRC is copied into it; otherwise SRC is
+copied to DEST. The container must be running.
+
+Examples:
+  capsule cp capsule:build/report.html .
+  capsule cp ./fixtures capsule:/claude-env/home/fixtures
+  capsule cp notes.md capsule:`,
+		Args: cobra.ExactArgs(2),
+		RunE: runCp,
+	}
+}
+
+func runCp(cmd *cobra.Command, args []string) error {
+	src, err := transfer.ParseLocation(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid source: %w", err)
+	}
+	dst, err := transfer.ParseLocation(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+	if src.Container == dst.Container {
+		return fmt.Errorf("exactly one of SRC and DEST must be a container path (%s...)", transfer.Prefix)
+	}
+
+	containerName, _, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return fmt.Errorf("container %s is not running; start it with 'capsule start'", containerName)
+	}
+
+	if dst.Container {
+		return copyIn(ctx, dockerManager, containerName, src.Path, dst.Path)
+	}
+	return copyOut(ctx, dockerManager, containerName, src.Path, dst.Path)
+}
+
+// copyIn copies a host file or directory into the container.
+func copyIn(ctx context.Context, dockerManager docker.DockerManager, containerName, src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	total, err := transfer.Size(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+
+	kind, _, err := statContainerPath(ctx, dockerManager, containerName, dst)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && kind == "file" {
+		return fmt.Errorf("cannot copy directory %s over file %s%s", src, transfer.Prefix, dst)
+	}
+	dir, name, err := copyTarget(dst, kind == "directory", filepath.Base(src), path.Dir, path.Base)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(transfer.Write(pw, src, name))
+	}()
+
+	indicator := startProgress(fmt.Sprintf("Copying %s to %s%s", src, transfer.Prefix, path.Join(dir, name)))
+	err = dockerManager.CopyTo(ctx, containerName, dir, countProgress(pr, total, indicator))
+	pr.CloseWithError(err)
+	indicator.Done(err)
+	return err
+}
+
+// copyOut copies a file or directory from the container to the host.
+func copyOut(ctx context.Context, dockerManager docker.DockerManager, containerName, src, dst string) error {
+	kind, total, err := statContainerPath(ctx, dockerManager, containerName, src)
+	if err != nil {
+		return err
+	}
+	if kind == "" {
+		return fmt.Errorf("%s%s does not exist", transfer.Prefix, src)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	exists := err == nil
+	if kind == "directory" && exists && !info.IsDir() {
+		return fmt.Errorf("cannot copy directory %s%s over file %s", transfer.Prefix, src, dst)
+	}
+	dir, name, err := copyTarget(dst, exists && info.IsDir(), path.Base(src), filepath.Dir, filepath.Base)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("destination directory %s does not exist", dir)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(dockerManager.CopyFrom(ctx, containerName, src, pw))
+	}()
+
+	indicator := startProgress(fmt.Sprintf("Copying %s%s to %s", transfer.Prefix, src, filepath.Join(dir, name)))
+	err = transfer.Extract(countProgress(pr, total, indicator), dir, name)
+	if err == nil {
+		// Drain the tar padding so docker cp exits cleanly
+		_, err = io.Copy(io.Discard, pr)
+	}
+	pr.CloseWithError(err)
+	indicator.Done(err)
+	return err
+}
+
+// copyTarget returns the directory to extract into and the name the copy gets
+// there, following docker cp: into dst if it is a directory, otherwise as dst.
+func copyTarget(dst string, dstIsDir bool, srcName string, dirOf, baseOf func(string) string) (string, string, error) {
+	if dstIsDir {
+		return dst, srcName, nil
+	}
+	if strings.HasSuffix(dst, "/") {
+		return "", "", fmt.Errorf("destination directory %s does not exist", dst)
+	}
+	return dirOf(dst), baseOf(dst), nil
+}
+
+// statContainerPath reports whether p in the container is a "directory", a
+// "file", or missing (""), and how many bytes it holds.
+func statContainerPath(ctx context.Context, dockerManager docker.DockerManager, containerName, p string) (string, int64, error) {
+	const script = `if [ -d "$1" ]; then echo directory; elif [ -e "$1" ]; then echo file; else exit 0; fi
+du -sb "$1" 2>/dev/null | cut -f1`
+	var stdout, stderr bytes.Buffer
+	err := dockerManager.ExecCommand(ctx, containerName, docker.ExecOptions{
+		Command: []string{"sh", "-c", script, "sh", p},
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to check %s%s: %w: %s", transfer.Prefix, p, err, strings.TrimSpace(stderr.String()))
+	}
+	fields := strings.Fields(stdout.String())
+	if len(fields) == 0 {
+		return "", 0, nil
+	}
+	var size int64 = -1
+	if len(fields) > 1 {
+		if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			size = n
+		}
+	}
+	return fields[0], size, nil
+}
+
+// countProgress reports bytes read from r against total on indicator.
+// A non-positive total leaves the percentage unknown.
+func countProgress(r io.Reader, total int64, indicator *progress.Indicator) io.Reader {
+	return &transfer.CountingReader{R: r, Report: func(n int64) {
+		if total > 0 {
+			indicator.SetPercent(float64(n) * 100 / float64(total))
+		}
+		indicator.SetDetail(formatBytes(n))
+	}}
+}
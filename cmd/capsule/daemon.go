@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/daemon"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newDaemonCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Serve a local API for status, lock, unlock, and sessions",
+		Long: `Runs in the foreground and serves a JSON API on a unix socket, so menu-bar
+apps and editor extensions can control capsules without running the CLI for every
+action. Only your user can connect to the socket.
+
+  GET    /v1/status            volumes and running sessions
+  POST   /v1/unlock            {"volume": "/path/to/capsule.sparseimage", "password": "..."}
+  POST   /v1/lock              {"volume": "..."} or {"all": true}
+  GET    /v1/sessions          running capsule containers
+  DELETE /v1/sessions/{name}   stop a container
+
+With --metrics-addr, Prometheus metrics (mount and container start latency,
+active sessions, auto-lock events) are served on http://ADDR/metrics.
+
+An omitted volume means the default volume in ~/.capsule/volumes. For example:
+  curl --unix-socket ~/.capsule/run/` + daemon.SocketName + ` http://capsule/v1/status`,
+		Args: cobra.NoArgs,
+		RunE: runDaemon,
+	}
+	cmd.PersistentFlags().String("socket", "", "Socket path (default ~/.capsule/run/"+daemon.SocketName+")")
+	cmd.Flags().String("metrics-addr", "", "Serve Prometheus metrics on this localhost address, e.g. 127.0.0.1:9477 (default off)")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Query a running daemon for volumes and sessions",
+		Args:  cobra.NoArgs,
+		RunE:  runDaemonStatus,
+	})
+
+	return cmd
+}
+
+// daemonSocketPath returns the --socket flag or the default socket path.
+func daemonSocketPath(cmd *cobra.Command) (string, error) {
+	socketPath, err := cmd.Flags().GetString("socket")
+	if err != nil {
+		return "", fmt.Errorf("invalid socket flag: %w", err)
+	}
+	if socketPath != "" {
+		return socketPath, nil
+	}
+	return daemon.DefaultSocketPath()
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	socketPath, err := daemonSocketPath(cmd)
+	if err != nil {
+		return err
+	}
+	metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+	if err != nil {
+		return fmt.Errorf("invalid metrics-addr flag: %w", err)
+	}
+	backend, err := newDaemonBackend()
+	if err != nil {
+		return err
+	}
+
+	server, err := daemon.Listen(socketPath, backend)
+	if err != nil {
+		return err
+	}
+	if metricsAddr != "" {
+		if err := server.ListenMetrics(metricsAddr); err != nil {
+			os.Remove(server.SocketPath())
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Serving metrics on http://%s%s\n", server.MetricsAddr(), daemon.MetricsPath)
+	}
+
+	fmt.Fprintf(os.Stderr, "Capsule daemon listening on %s (Ctrl+C to stop)\n", server.SocketPath())
+	return server.Serve(cmd.Context())
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	socketPath, err := daemonSocketPath(cmd)
+	if err != nil {
+		return err
+	}
+
+	status, err := daemon.NewClient(socketPath).Status(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("DAEMON_VERSION=%s\n", status.Version)
+	for _, v := range status.Volumes {
+		state := "locked"
+		if v.Mounted {
+			state = "mounted at " + v.MountPoint
+		} else if !v.Exists {
+			state = "missing"
+		}
+		fmt.Printf("VOLUME=%s (%s)\n", v.Path, state)
+	}
+	for _, s := range status.Sessions {
+		fmt.Printf("SESSION=%s %s\n", s.Container, s.Workspace)
+	}
+	return nil
+}
+
+// reportTimeout bounds event reports so a wedged daemon can't stall the CLI.
+const reportTimeout = 2 * time.Second
+
+// reportDaemonEvent sends an event to a running daemon for its metrics. It is
+// best effort: without a daemon there is nothing to report to.
+func reportDaemonEvent(event daemon.Event) {
+	socketPath, err := daemon.DefaultSocketPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	if err := daemon.NewClient(socketPath).ReportEvent(ctx, event); err != nil {
+		slog.Debug("failed to report event to daemon", "type", event.Type, "error", err)
+	}
+}
+
+// daemonBackend implements the daemon API with the same managers the CLI uses.
+type daemonBackend struct {
+	volumeManager volume.VolumeManager
+	dockerManager docker.DockerManager
+	pathResolver  *volume.PathResolver
+}
+
+func newDaemonBackend() (*daemonBackend, error) {
+	volumeManager, err := volume.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	return &daemonBackend{
+		volumeManager: volumeManager,
+		dockerManager: docker.NewManager(),
+		pathResolver:  pathResolver,
+	}, nil
+}
+
+// volumePath returns the requested volume, given as a path or name, or the
+// default volume. The daemon has no meaningful working directory, so local
+// volumes must be named explicitly.
+func (b *daemonBackend) volumePath(requested string) (string, error) {
+	volumePath := b.pathResolver.ResolveName(requested)
+	if volumePath == "" {
+		volumePath = b.pathResolver.GetDefaultVolumePath()
+	}
+	if !b.volumeManager.Exists(volumePath) {
+		return "", fmt.Errorf("volume %s: %w", volumePath, daemon.ErrNotFound)
+	}
+	return volumePath, nil
+}
+
+func (b *daemonBackend) Status(ctx context.Context) (*daemon.Status, error) {
+	status := &daemon.Status{Version: version}
+
+	mounted, err := b.volumeManager.ListMounted(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mounted volumes: %w", err)
+	}
+	defaultPath := b.pathResolver.GetDefaultVolumePath()
+	sawDefault := false
+	for _, v := range mounted {
+		status.Volumes = append(status.Volumes, daemon.Volume{
+			Path:       v.ImagePath,
+			Exists:     true,
+			Mounted:    true,
+			MountPoint: v.MountPoint,
+		})
+		sawDefault = sawDefault || v.ImagePath == defaultPath
+	}
+	if !sawDefault {
+		status.Volumes = append(status.Volumes, daemon.Volume{
+			Path:   defaultPath,
+			Exists: b.volumeManager.Exists(defaultPath),
+		})
+	}
+
+	status.Sessions, err = b.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (b *daemonBackend) Unlock(ctx context.Context, requested string, password *terminal.SecurePassword) (*daemon.UnlockResponse, error) {
+	volumePath, err := b.volumePath(requested)
+	if err != nil {
+		return nil, err
+	}
+	if existingMount := b.volumeManager.GetMountPoint(ctx, volumePath); existingMount != "" {
+		return &daemon.UnlockResponse{Volume: volumePath, MountPoint: existingMount, AlreadyMounted: true}, nil
+	}
+
+	mountPoint, err := b.volumeManager.Mount(ctx, volumePath, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount volume: %w", err)
+	}
+	return &daemon.UnlockResponse{Volume: volumePath, MountPoint: mountPoint}, nil
+}
+
+func (b *daemonBackend) Lock(ctx context.Context, req daemon.LockRequest) (*daemon.LockResponse, error) {
+	// A client that disconnects must not leave a volume half locked
+	ctx = context.WithoutCancel(ctx)
+	if req.All {
+		result, err := lockEverything(ctx, b.volumeManager, b.dockerManager)
+		if err != nil {
+			return nil, err
+		}
+		if result.failures > 0 {
+			return nil, fmt.Errorf("%d containers or volumes could not be secured", result.failures)
+		}
+		return &daemon.LockResponse{ContainersStopped: result.stopped, VolumesLocked: result.locked}, nil
+	}
+
+	volumePath, err := b.volumePath(req.Volume)
+	if err != nil {
+		return nil, err
+	}
+	mountPoint := b.volumeManager.GetMountPoint(ctx, volumePath)
+	if mountPoint == "" {
+		return &daemon.LockResponse{}, nil
+	}
+
+	// Stop only the containers with something from this volume mounted
+	resp := &daemon.LockResponse{}
+	containers, err := b.dockerManager.ListRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, containerName := range containers {
+		sources, err := b.dockerManager.MountSources(ctx, containerName)
+		if err != nil || !mountsPath(sources, mountPoint) {
+			continue
+		}
+		if err := b.dockerManager.Stop(ctx, containerName); err != nil {
+			return nil, fmt.Errorf("failed to stop %s: %w", containerName, err)
+		}
+		resp.ContainersStopped++
+	}
+
+	if err := b.volumeManager.Unmount(ctx, mountPoint); err != nil {
+		return nil, fmt.Errorf("failed to unmount volume: %w", err)
+	}
+	resp.VolumesLocked = 1
+	return resp, nil
+}
+
+func (b *daemonBackend) Sessions(ctx context.Context) ([]daemon.Session, error) {
+	containers, err := b.dockerManager.ListRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]daemon.Session, 0, len(containers))
+	for _, containerName := range containers {
+		session := daemon.Session{Container: containerName}
+		if workspace, err := b.dockerManager.WorkspaceMount(ctx, containerName); err == nil {
+			session.Workspace = workspace
+		}
+		if startedAt, err := b.dockerManager.StartedAt(ctx, containerName); err == nil {
+			session.StartedAt = startedAt
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (b *daemonBackend) StopSession(ctx context.Context, containerName string) error {
+	if !b.dockerManager.IsRunning(ctx, containerName) {
+		return fmt.Errorf("container %s: %w", containerName, daemon.ErrNotFound)
+	}
+	return b.dockerManager.Stop(ctx, containerName)
+}
+
+// mountsPath reports whether any of sources is dir or lies inside it.
+func mountsPath(sources []string, dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, source := range sources {
+		source = filepath.Clean(source)
+		if source == dir || strings.HasPrefix(source, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// dfCompactMinBytes is the least reclaimable space 'capsule df' suggests compacting for.
+const dfCompactMinBytes = 256 << 20
+
+// dfReport is the output of 'capsule df'.
+type dfReport struct {
+	Volume      string          `json:"volume"`
+	MountPoint  string          `json:"mount_point"`
+	ImageBytes  int64           `json:"image_bytes"`
+	Capacity    volume.Capacity `json:"capacity"`
+	Directories []dfDirectory   `json:"directories"`
+}
+
+// dfDirectory is the usage of one top-level directory in the volume.
+type dfDirectory struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+func newDfCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "df",
+		Short: "Show volume size, free space, and usage per directory",
+		Long: `Reports how much space the sparse image takes on disk, the volume's capacity
+and free space, and how much each top-level directory in the volume (home,
+repos, auth, ...) holds. A locked volume is mounted read-only for the report
+and locked again afterwards.
+
+The image only grows as the volume is written and doesn't shrink when files
+are deleted, so when it is much larger than the space in use, df suggests
+compacting it; when the volume is nearly full, it suggests resizing.`,
+		Args: cobra.NoArgs,
+		RunE: runDf,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	cmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+func runDf(cmd *cobra.Command, args []string) error {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("invalid json flag: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+
+	mountPoint, release, err := mountForHostAccess(cmd.Context(), volumeManager, volumePath, passwordStdin, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	report := dfReport{Volume: volumePath, MountPoint: mountPoint}
+	if report.ImageBytes, err = volume.ImageSize(volumePath); err != nil {
+		return err
+	}
+	if report.Capacity, err = volume.FilesystemCapacity(mountPoint); err != nil {
+		return err
+	}
+	if report.Directories, err = topLevelUsage(mountPoint); err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printDfReport(report)
+	return nil
+}
+
+// topLevelUsage totals each top-level directory of the mounted volume.
+// Loose files at the top level are grouped under ".".
+func topLevelUsage(mountPoint string) ([]dfDirectory, error) {
+	entries, err := os.ReadDir(mountPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", mountPoint, err)
+	}
+
+	var dirs []dfDirectory
+	loose := dfDirectory{Name: "."}
+	for _, entry := range entries {
+		// Filesystem metadata such as .fseventsd and .Spotlight-V100 isn't capsule data
+		if entry.Name()[0] == '.' {
+			continue
+		}
+		if !entry.IsDir() {
+			if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+				loose.Bytes += info.Size()
+				loose.Files++
+			}
+			continue
+		}
+		usage, err := repo.DiskUsage(filepath.Join(mountPoint, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", entry.Name(), err)
+		}
+		dirs = append(dirs, dfDirectory{Name: entry.Name(), Bytes: usage.Bytes, Files: usage.Files})
+	}
+	if loose.Files > 0 {
+		dirs = append(dirs, loose)
+	}
+	return dirs, nil
+}
+
+func printDfReport(report dfReport) {
+	capacity := report.Capacity
+	fmt.Printf("Volume:    %s\n", report.Volume)
+	fmt.Printf("On disk:   %s\n", formatBytes(report.ImageBytes))
+	fmt.Printf("Capacity:  %s\n", formatBytes(capacity.TotalBytes))
+	fmt.Printf("Used:      %s\n", formatBytes(capacity.UsedBytes))
+	fmt.Printf("Free:      %s (%d%%)\n", formatBytes(capacity.FreeBytes), capacity.FreePercent())
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DIRECTORY\tSIZE\tFILES")
+	for _, d := range report.Directories {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", d.Name, formatBytes(d.Bytes), d.Files)
+	}
+	w.Flush()
+
+	// Deleted files leave their blocks allocated in the image until it is compacted
+	if reclaimable := report.ImageBytes - capacity.UsedBytes; reclaimable >= dfCompactMinBytes && reclaimable*4 >= report.ImageBytes {
+		fmt.Printf("\nAbout %s could be reclaimed. Lock the volume, then run:\n  hdiutil compact %s\n", formatBytes(reclaimable), report.Volume)
+	}
+	if capacity.FreePercent() < config.DefaultLowSpacePercent {
+		if sizeGB := volume.ExpandedSizeGB(capacity.TotalBytes, constants.MaxVolumeSizeGB); sizeGB > 0 {
+			fmt.Printf("\nThe volume is nearly full. Lock it, then grow it with:\n  hdiutil resize -size %dg %s\n", sizeGB, report.Volume)
+		} else {
+			fmt.Println("\nThe volume is nearly full and already at the maximum size.")
+		}
+	}
+}
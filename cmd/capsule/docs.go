@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+)
+
+func newDocsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Manage shadow documentation",
+	}
+
+	cmd.AddCommand(newDocsSyncCmd())
+
+	return cmd
+}
+
+func newDocsSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Mirror shadow docs to a git branch in the workspace repository",
+		Long: `Commits the contents of _docs to a branch (default ` + repo.DefaultDocsBranch + `) in the
+workspace repository without touching the working tree or current branch.
+The memory database and beads data are excluded.
+
+To sync automatically on every 'capsule stop', opt in per repository:
+  git config ` + repo.DocsSyncKey + ` true
+
+Optional settings:
+  git config ` + repo.DocsBranchKey + ` team/docs       # branch name
+  git config ` + repo.DocsRemoteKey + ` <remote|url>    # push after each sync (e.g. a separate docs repo)`,
+		Args: cobra.NoArgs,
+		RunE: runDocsSync,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().String("workspace", "", "Workspace path (defaults to current directory or git root)")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+
+	return cmd
+}
+
+func runDocsSync(cmd *cobra.Command, args []string) error {
+	workspaceFlag, err := cmd.Flags().GetString("workspace")
+	if err != nil {
+		return fmt.Errorf("invalid workspace flag: %w", err)
+	}
+
+	repoIdentifier := newRepoIdentifier()
+	workspacePath := workspaceFlag
+	if workspacePath == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		workspacePath, err = repoIdentifier.GetWorkspaceRoot(cwd)
+		if err != nil {
+			return fmt.Errorf("failed to determine workspace root: %w", err)
+		}
+	}
+	workspacePath, err = filepath.Abs(workspacePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	repoID, err := repoIdentifier.GetRepoID(workspacePath)
+	if err != nil {
+		return fmt.Errorf("failed to identify repository: %w", err)
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	opts := repo.LoadDocsSyncOptions(workspacePath)
+	result, err := repo.SyncDocsBranch(workspacePath, docsync.RepoDocsRoot(mountPoint, repoID), opts)
+	if err != nil {
+		return fmt.Errorf("docs sync failed: %w", err)
+	}
+	printDocsSyncResult(result)
+	return nil
+}
+
+// syncDocsOnStop mirrors shadow docs to git if the workspace opted in.
+// Failures are reported as warnings so they never block stopping a container.
+func syncDocsOnStop(workspacePath, repoID, mountPoint string) {
+	if mountPoint == "" {
+		return
+	}
+	opts := repo.LoadDocsSyncOptions(workspacePath)
+	if !opts.Enabled {
+		return
+	}
+
+	infof("Syncing shadow docs to branch %s...\n", opts.Branch)
+	result, err := repo.SyncDocsBranch(workspacePath, docsync.RepoDocsRoot(mountPoint, repoID), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: docs sync failed: %v\n", err)
+		return
+	}
+	printDocsSyncResult(result)
+}
+
+func printDocsSyncResult(result *repo.DocsSyncResult) {
+	if !result.Changed {
+		fmt.Printf("Shadow docs unchanged on %s.\n", result.Branch)
+		return
+	}
+	fmt.Printf("Committed shadow docs to %s (%s).\n", result.Branch, result.Commit[:min(12, len(result.Commit))])
+	if result.Pushed {
+		fmt.Println("Pushed docs branch.")
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/clipboard"
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/envfile"
+	"github.com/jeanhaley32/claude-capsule/internal/gitidentity"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/openbridge"
+	"github.com/jeanhaley32/claude-capsule/internal/signproxy"
+	"github.com/jeanhaley32/claude-capsule/internal/trust"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// dryRunPlan collects the steps a command would take under --dry-run, so they
+// can be printed instead of carried out.
+type dryRunPlan struct {
+	steps []string
+}
+
+func (p *dryRunPlan) add(format string, args ...any) {
+	p.steps = append(p.steps, fmt.Sprintf(format, args...))
+}
+
+// run adds a step that runs an external command, shown with the same
+// redaction as the log.
+func (p *dryRunPlan) run(what string, argv ...string) {
+	p.add("%s:\n       %s", what, strings.Join(logging.RedactArgs(argv), " "))
+}
+
+// print writes the numbered steps to stdout.
+func (p *dryRunPlan) print(command string) {
+	if len(p.steps) == 0 {
+		fmt.Printf("Dry run: %s has nothing to do.\n", command)
+		return
+	}
+	fmt.Printf("Dry run: %s would:\n", command)
+	p.list()
+	fmt.Println("Nothing was changed.")
+}
+
+// list writes the numbered steps to stdout.
+func (p *dryRunPlan) list() {
+	for i, step := range p.steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+}
+
+// addDryRunFlag registers --dry-run.
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Show the mounts, container commands, and deletions without carrying them out")
+}
+
+// startPlan is what 'capsule start' or 'capsule run' has resolved before it
+// changes anything, enough to list the steps it would take.
+type startPlan struct {
+	command        string
+	dockerManager  docker.DockerManager
+	volumeManager  volume.VolumeManager
+	volumePath     string
+	identityPath   string
+	containerName  string
+	workspaces     []docker.Workspace
+	untrusted      bool
+	settings       *config.Settings
+	buildOptions   embedded.BuildOptions
+	restart        docker.RestartPolicy
+	env            []string // From --env
+	envFiles       []string
+	secretSources  []string
+	gitIdentity    *gitidentity.Identity
+	dotfiles       int
+	ramAuth        bool
+	signCommits    bool
+	clipboardMode  clipboard.Mode
+	browserBridge  bool
+	detach         bool
+	keepAlive      bool
+	keepAliveGrace time.Duration
+	run            *headlessRun
+}
+
+// print lists the steps of the start in the order startSession takes them.
+// Nothing is mounted or created; the container's 'docker run' is shown with
+// the mount point the volume would get, without the RAM disk's auth mount.
+func (s startPlan) print(ctx context.Context) error {
+	var plan dryRunPlan
+	if s.run == nil && runningSessionMount(ctx, s.dockerManager, s.volumeManager, s.containerName, s.volumePath, s.workspaces) != "" {
+		if s.detach {
+			plan.print(s.command)
+			return nil
+		}
+		for _, source := range s.secretSources {
+			plan.add("Read secrets from %s for the shell's environment", source)
+		}
+		plan.run("Open another shell in the running container", "docker", "exec", "-it", s.containerName, "/usr/bin/fish")
+		plan.print(s.command)
+		return nil
+	}
+	if orphans, err := findOrphanedContainers(ctx, s.dockerManager, s.volumeManager); err == nil {
+		for _, o := range orphansOfVolume(orphans, s.volumePath) {
+			plan.run(fmt.Sprintf("Remove orphaned container %s (its volume at %s is no longer mounted)", o.name, o.mountPoint),
+				"docker", "rm", "-f", o.name)
+		}
+	}
+	if !s.untrusted {
+		store, err := trust.LoadDefault()
+		if err != nil {
+			return err
+		}
+		for _, w := range s.workspaces {
+			if !store.IsTrusted(w.Path) {
+				plan.add("Ask whether to trust %s", w.Path)
+			}
+		}
+	}
+	for _, source := range s.secretSources {
+		plan.add("Read secrets from %s for the shell's environment", source)
+	}
+	if s.containerExists(ctx) {
+		plan.run("Remove the existing container", "docker", "rm", "-f", s.containerName)
+	}
+
+	mountPoint := s.volumeManager.GetMountPoint(ctx, s.volumePath)
+	mounted := mountPoint != ""
+	if mounted {
+		plan.add("Use the volume already mounted at %s", mountPoint)
+	} else {
+		mountDir, err := config.MountDir()
+		if err != nil {
+			return err
+		}
+		mountPoint = volume.MountPointFor(mountDir, s.volumePath)
+		plan.run("Ask for the volume password and mount "+s.volumePath,
+			"hdiutil", "attach", "-stdinpass", "-mountpoint", mountPoint, s.volumePath)
+	}
+	identityMount := ""
+	if s.identityPath != "" {
+		if identityMount = s.volumeManager.GetMountPoint(ctx, s.identityPath); identityMount != "" {
+			plan.add("Use the identity volume already mounted at %s", identityMount)
+		} else {
+			mountDir, err := config.MountDir()
+			if err != nil {
+				return err
+			}
+			identityMount = volume.MountPointFor(mountDir, s.identityPath)
+			plan.run("Ask for the identity volume password and mount "+s.identityPath,
+				"hdiutil", "attach", "-stdinpass", "-mountpoint", identityMount, s.identityPath)
+		}
+	}
+	if s.gitIdentity != nil {
+		plan.add("Set the volume's git identity to %s <%s>", s.gitIdentity.Name, s.gitIdentity.Email)
+	}
+	if s.dotfiles > 0 {
+		plan.add("Copy %d dotfiles into the volume's home directory", s.dotfiles)
+	}
+
+	// The image depends on the volume's template, which can only be read once it is mounted
+	imageName := docker.ImageName(s.buildOptions.Flavor, "")
+	if mounted {
+		template, err := embedded.ReadTemplateFile(mountPoint)
+		if err != nil {
+			return err
+		}
+		imageName = docker.ImageName(s.buildOptions.Flavor, template)
+		if !embedded.ImageExists(imageName) {
+			plan.add("Build image %s", imageName)
+		}
+	} else {
+		plan.add("Build the image for the volume's template if it is missing (%s without one)", imageName)
+	}
+	if s.ramAuth {
+		plan.add("Stage auth/ on an encrypted RAM disk and mount it at /claude-env/auth in the container")
+	}
+	plan.add("Clear Docker Desktop's VM cache and refresh its view of %s", mountPoint)
+	if identityMount != "" {
+		plan.add("Refresh Docker Desktop's view of %s", identityMount)
+	}
+
+	containerConfig, err := s.containerConfig(ctx, &plan, imageName, mountPoint, mounted)
+	if err != nil {
+		return err
+	}
+	containerConfig.IdentityMountPoint = identityMount
+	plan.run("Create the container", append([]string{"docker"}, docker.RunArgs(containerConfig)...)...)
+
+	var repoIDs []string
+	for _, w := range s.workspaces {
+		repoIDs = append(repoIDs, w.RepoID)
+	}
+	plan.add("Link _docs in the container to repos/%s in the volume", strings.Join(repoIDs, ", repos/"))
+	if s.run != nil {
+		plan.add("Run claude -p with the prompt in the container")
+		if s.run.keepRunning {
+			plan.add("Leave %s running", s.containerName)
+		} else {
+			plan.run("Stop and remove the container", "docker", "stop", s.containerName)
+		}
+		if s.run.lock {
+			plan.add("Unmount the volume at %s", mountPoint)
+		}
+	} else if s.detach {
+		plan.add("Leave %s running for 'capsule enter'", s.containerName)
+	} else {
+		plan.add("Open a shell in %s", s.containerName)
+		switch {
+		case !s.keepAlive:
+			plan.run("Stop and remove the container when the shell exits, leaving the volume mounted", "docker", "stop", s.containerName)
+		case s.keepAliveGrace == 0:
+			plan.add("Leave %s running when the shell exits, until 'capsule stop' or 'capsule lock'", s.containerName)
+		default:
+			plan.run(fmt.Sprintf("Leave %s running when the shell exits, and stop it in the background once no shell has been open for %s", s.containerName, s.keepAliveGrace),
+				"capsule", "stop", "--idle", s.keepAliveGrace.String(), "--container", s.containerName)
+		}
+	}
+	plan.print(s.command)
+	return nil
+}
+
+// containerExists reports whether a container with the start's name exists,
+// running or not; start removes it before creating its own.
+func (s startPlan) containerExists(ctx context.Context) bool {
+	if s.dockerManager.IsRunning(ctx, s.containerName) {
+		return true
+	}
+	exited, err := s.dockerManager.ListExited(ctx)
+	if err != nil {
+		return false
+	}
+	for _, c := range exited {
+		if c.Name == s.containerName {
+			return true
+		}
+	}
+	return false
+}
+
+// containerConfig builds the container's configuration as startSession
+// would, adding a step for each host-side bridge it would start.
+func (s startPlan) containerConfig(ctx context.Context, plan *dryRunPlan, imageName, mountPoint string, mounted bool) (docker.ContainerConfig, error) {
+	c := docker.ContainerConfig{
+		ImageName:        imageName,
+		ContainerName:    s.containerName,
+		VolumeMountPoint: mountPoint,
+		WorkspacePath:    s.workspaces[0].Path,
+		Untrusted:        s.untrusted,
+		RepoID:           s.workspaces[0].RepoID,
+		DNS:              s.settings.DNS,
+		DNSSearch:        s.settings.DNSSearch,
+		Restart:          s.restart,
+		VolumePath:       s.volumePath,
+		CapsuleVersion:   version,
+	}
+	if len(s.workspaces) > 1 {
+		c.Workspaces = s.workspaces
+	}
+	if len(s.buildOptions.CACerts) > 0 {
+		path, err := caBundlePath(s.containerName)
+		if err != nil {
+			return c, err
+		}
+		c.CABundle = path
+		plan.add("Write the CA bundle to %s", path)
+	}
+
+	if !s.settings.NoHostProxy {
+		c.Env = append(c.Env, docker.HostProxyEnv(ctx)...)
+	}
+	if s.buildOptions.ClaudeCodeVersion != "" {
+		c.Env = append(c.Env, "DISABLE_AUTOUPDATER=1")
+	}
+	for _, path := range s.envFiles {
+		if envfile.IsVolumePath(path) && !mounted {
+			plan.add("Read environment variables from %s once the volume is mounted", path)
+			continue
+		}
+		fileEnv, err := envfile.ParseFile(path, mountPoint)
+		if err != nil {
+			return c, err
+		}
+		c.Env = append(c.Env, fileEnv...)
+	}
+	c.Env = append(c.Env, s.env...)
+
+	if s.signCommits {
+		socketPath, err := signproxy.SocketPath(s.containerName)
+		if err != nil {
+			return c, err
+		}
+		c.SigningSocket, c.SigningSocketTarget = socketPath, signproxy.ContainerSocketPath
+		plan.add("Start the signing proxy at %s", socketPath)
+	}
+	if s.clipboardMode != clipboard.ModeOff {
+		socketPath, err := clipboard.SocketPath(s.containerName)
+		if err != nil {
+			return c, err
+		}
+		c.ClipboardSocket, c.ClipboardSocketTarget = socketPath, clipboard.ContainerSocketPath
+		plan.add("Start the clipboard bridge (%s) at %s", s.clipboardMode, socketPath)
+	}
+	if s.browserBridge {
+		socketPath, err := openbridge.SocketPath(s.containerName)
+		if err != nil {
+			return c, err
+		}
+		c.OpenSocket, c.OpenSocketTarget = socketPath, openbridge.ContainerSocketPath
+		c.Env = append(c.Env, "BROWSER="+openbridge.ShimPath)
+		plan.add("Start the browser bridge at %s", socketPath)
+	}
+	return c, nil
+}
+
+// planLock lists the steps lock takes: stopping the containers, which hold
+// the volume mounts open, then unmounting each volume.
+func planLock(containers, mountPoints []string) *dryRunPlan {
+	var plan dryRunPlan
+	for _, name := range containers {
+		plan.run("Stop and remove container "+name, "docker", "stop", name)
+	}
+	for _, mountPoint := range mountPoints {
+		plan.run("Destroy the volume's auth RAM disk if it has one, then unmount the volume (with hdiutil detach if this fails)",
+			"diskutil", "unmount", mountPoint)
+	}
+	return &plan
+}
+
+// printLockAllPlan lists what 'capsule lock --all' would stop and unmount.
+func printLockAllPlan(ctx context.Context, command string) error {
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	containers, err := docker.NewManager().ListRunning(ctx)
+	if err != nil {
+		return err
+	}
+	mounted, err := volumeManager.ListMounted(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list mounted volumes: %w", err)
+	}
+	mountPoints := make([]string, len(mounted))
+	for i, v := range mounted {
+		mountPoints[i] = v.MountPoint
+	}
+	planLock(containers, mountPoints).print(command)
+	return nil
+}
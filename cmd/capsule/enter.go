@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// containerFlags are the start flags that only take effect when the container
+// is created, so a start that attaches to a running one ignores them.
+var containerFlags = []string{
+	"untrusted", "git-identity", "sign", "ram-auth", "identity-volume", "flavor", "no-host-proxy", "dns", "dns-search",
+	"restart", "env", "env-file", "clipboard", "no-browser-bridge",
+}
+
+func newEnterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "enter",
+		Short: "Open another shell in the workspace's running container",
+		Long: `Opens a new shell in the container 'capsule start' is running for the current
+workspace, without start's preflight checks, so it takes well under a second.
+It fails if the container isn't running.
+
+The container still stops when the shell 'capsule start' opened exits, taking
+shells opened with enter with it, unless start was given --keep-alive or
+--detach.`,
+		Args: cobra.NoArgs,
+		RunE: runEnter,
+	}
+	return cmd
+}
+
+func runEnter(cmd *cobra.Command, args []string) error {
+	containerName, _, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return fmt.Errorf("container %s is not running; use 'capsule start'", containerName)
+	}
+	return enterShell(ctx, dockerManager, containerName, nil, "")
+}
+
+// enterShell opens a shell in a running container and waits for it to exit.
+// The exit codes of a normal exit or Ctrl+C are not errors.
+func enterShell(ctx context.Context, dockerManager docker.DockerManager, containerName string, env []string, record string) error {
+	return shellExitError(dockerManager.Exec(ctx, containerName, env, record))
+}
+
+// shellExitError returns the error to report for how the shell exited:
+// nil for exit codes 0 and 130 (Ctrl+C at the prompt).
+func shellExitError(execErr error) error {
+	if execErr == nil {
+		return nil
+	}
+	if exitErr, ok := execErr.(*exec.ExitError); ok {
+		code := exitErr.ExitCode()
+		if code == 0 || code == 130 {
+			return nil
+		}
+	}
+	return fmt.Errorf("shell exited with error: %w", execErr)
+}
+
+// runningSessionMount returns the volume's mount point if containerName is
+// already running from that mount for workspacePath, so a start can attach a
+// shell instead of creating the container again; otherwise it returns "".
+// A container serving another worktree, or one left behind by an unmounted
+// volume, doesn't count: the full start reports or cleans those up.
+func runningSessionMount(ctx context.Context, dockerManager docker.DockerManager, volumeManager volume.VolumeManager,
+	containerName, volumePath string, workspaces []docker.Workspace) string {
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return ""
+	}
+	mountPoint := volumeManager.GetMountPoint(ctx, volumePath)
+	if mountPoint == "" {
+		return ""
+	}
+	sources, err := dockerManager.MountSources(ctx, containerName)
+	if err != nil || !mountsPath(sources, mountPoint) {
+		return ""
+	}
+	if len(workspaces) == 1 {
+		if mounted, err := dockerManager.WorkspaceMount(ctx, containerName); err != nil || mounted != workspaces[0].Path {
+			return ""
+		}
+	}
+	return mountPoint
+}
+
+// warnIgnoredContainerFlags notes the container flags a start gave that can't
+// apply to the container it is attaching to.
+func warnIgnoredContainerFlags(cmd *cobra.Command) {
+	var ignored []string
+	for _, name := range containerFlags {
+		if cmd.Flags().Lookup(name) != nil && cmd.Flags().Changed(name) {
+			ignored = append(ignored, "--"+name)
+		}
+	}
+	if len(ignored) > 0 {
+		infoErrf("Ignoring %s: the container is already running. Stop it with 'capsule stop' to apply them.\n", strings.Join(ignored, ", "))
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/envmanifest"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/migrate"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newManifestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Describe an environment in YAML and bootstrap it on another machine",
+		Long: `An environment manifest is a YAML description of a capsule environment: the
+volume's size, cipher, filesystem, language template, Argon2 key stretching,
+and context files, the skills installed in it, and the portable settings in
+~/.capsule/config.json. Apply it on another machine to bootstrap the same
+environment there.
+
+The manifest holds no volume contents. Of the settings it leaves out
+build_args, which often carry tokens, and host paths (mount_dir, ca_certs,
+dotfiles, volumes, identity_volume); apply keeps the target machine's own.
+Skills that don't ship with capsule are listed but not copied; bring them
+across with 'capsule template export' and 'capsule template import'.
+
+This is unrelated to the signed volume manifest that 'capsule verify' checks.`,
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [FILE]",
+		Short: "Write the volume's environment manifest (to stdout without FILE)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runManifestExport,
+	}
+	exportCmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	exportCmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply FILE",
+		Short: "Bootstrap a new volume and write config.json from a manifest",
+		Long: `Bootstraps a new volume as the manifest describes it and writes its settings
+to ~/.capsule/config.json, asking first if that would change the file.
+Settings a manifest doesn't carry, such as build_args, keep their values.
+
+The volume is created at --volume, a name or path as bootstrap's --volume
+takes it, or at the global volume path. Context files the manifest lists are
+read from the same paths on this machine; missing ones are skipped with a
+warning.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runManifestApply,
+	}
+	applyCmd.Flags().String("volume", "", "Path or name of the volume to create (default: the global volume)")
+	applyCmd.Flags().String("password-file", "", "Read the new volume's password from a file only you can read (mode 0600)")
+	applyCmd.Flags().BoolP("yes", "y", false, "Update config.json without asking")
+
+	cmd.AddCommand(exportCmd, applyCmd)
+	return cmd
+}
+
+func runManifestExport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	mountPoint, release, err := mountForHostAccess(ctx, volumeManager, volumePath, passwordStdin, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	v, err := manifestVolume(volumePath, mountPoint)
+	if err != nil {
+		return err
+	}
+	skills, err := envmanifest.ListSkills(mountPoint)
+	if err != nil {
+		return err
+	}
+	m, err := envmanifest.New(version, v, skills, settings)
+	if err != nil {
+		return err
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	// It lists this machine's context file paths, so it isn't world-readable
+	if err := os.WriteFile(args[0], data, constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	infof("Wrote the environment manifest of %s to %s.\n", volumePath, args[0])
+	return nil
+}
+
+// manifestVolume describes how the volume mounted at mountPoint was
+// bootstrapped. Volumes bootstrapped before their metadata was recorded
+// take the size of their filesystem and the default cipher and filesystem.
+func manifestVolume(volumePath, mountPoint string) (envmanifest.Volume, error) {
+	var v envmanifest.Volume
+	if _, err := os.Stat(kdf.Path(volumePath)); err == nil {
+		v.Argon2 = true
+	}
+	if format := volume.FormatOf(volumePath); format != volume.DefaultImageFormat {
+		v.Format = format
+	}
+	meta, err := volume.ReadMetadata(mountPoint)
+	if err != nil {
+		return v, err
+	}
+	if meta != nil {
+		v.SizeGB, v.Encryption, v.Filesystem, v.Template, v.ContextFiles =
+			meta.SizeGB, meta.Encryption, meta.Filesystem, meta.Template, meta.ContextFiles
+		return v, nil
+	}
+	capacity, err := volume.FilesystemCapacity(mountPoint)
+	if err != nil {
+		return v, err
+	}
+	v.SizeGB = int((capacity.TotalBytes + bytesPerGB - 1) / bytesPerGB)
+	v.SizeGB = min(max(v.SizeGB, constants.MinVolumeSizeGB), constants.MaxVolumeSizeGB)
+	v.Template, err = embedded.ReadTemplateFile(mountPoint)
+	return v, err
+}
+
+func runManifestApply(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m, err := envmanifest.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath := pathResolver.GetDefaultVolumePath()
+	if volumePathFlag != "" {
+		volumePath = pathResolver.ResolveName(volumePathFlag)
+	}
+	// A path names its own format; otherwise the manifest's is used
+	if !volume.HasImageExt(volumePathFlag) {
+		volumePath = m.ImagePath(volumePath)
+	}
+	volumePath, err = filepath.Abs(volumePath)
+	if err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if volumeManager.Exists(volumePath) {
+		return fmt.Errorf("volume already exists at %s", volumePath)
+	}
+
+	settingsPath, err := config.DefaultSettingsPath()
+	if err != nil {
+		return err
+	}
+	current, err := config.LoadSettings(settingsPath)
+	if err != nil {
+		return err
+	}
+	settings, err := m.MergeSettings(current)
+	if err != nil {
+		return err
+	}
+	writeSettings := !reflect.DeepEqual(current, settings)
+	if writeSettings && !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("Update %s with the manifest's settings?", settingsPath))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	cfg, missing := m.BootstrapConfig(volumePath)
+	for _, path := range missing {
+		fmt.Fprintf(os.Stderr, "Warning: context file %s does not exist on this machine; skipping it.\n", path)
+	}
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = readNewPasswordFile(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordConfirmSecure("Enter a password for the new volume: ", "Confirm password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	var missingSkills []string
+	cfg.Password = password
+	cfg.Version = version
+	cfg.Setup = func(mountPoint string) error {
+		if _, err := migrate.UpgradeVolume(mountPoint, version); err != nil {
+			return err
+		}
+		installed, err := envmanifest.ListSkills(mountPoint)
+		if err != nil {
+			return err
+		}
+		for _, skill := range m.Skills {
+			if !slices.Contains(installed, skill) {
+				missingSkills = append(missingSkills, skill)
+			}
+		}
+		return nil
+	}
+
+	fmt.Printf("Bootstrapping %s (%d GB) from %s...\n", volumePath, cfg.SizeGB, args[0])
+	if err := volumeManager.Bootstrap(ctx, cfg); err != nil {
+		return fmt.Errorf("bootstrap failed: %w", err)
+	}
+	if writeSettings {
+		if err := config.SaveSettings(settingsPath, settings); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote the manifest's settings to %s.\n", settingsPath)
+	}
+
+	fmt.Printf("Bootstrapped %s from %s.\n", volumePath, args[0])
+	if len(missingSkills) > 0 {
+		fmt.Printf("Skills not installed: %s. Copy them with 'capsule template export' and 'capsule template import'.\n",
+			strings.Join(missingSkills, ", "))
+	}
+	fmt.Println("")
+	fmt.Println("Next step:")
+	if volumePathFlag != "" {
+		fmt.Printf("  capsule start --volume %s\n", volumePathFlag)
+	} else {
+		fmt.Println("  capsule start")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/oplock"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// Exit statuses, so scripts can tell failures apart. They are listed in the
+// README; don't renumber them. 'capsule run' exits with Claude's own status
+// once Claude has run.
+const (
+	exitError             = 1   // Any failure not listed below
+	exitNotBootstrapped   = 2   // No volume found; run 'capsule bootstrap'
+	exitAuthFailed        = 3   // The volume password was wrong
+	exitDockerUnavailable = 4   // Docker isn't running or can't be reached
+	exitMountConflict     = 5   // The volume, its mount point, or the container is in use elsewhere
+	exitBusy              = 6   // Another capsule command holds the workspace or volume lock
+	exitInterrupted       = 130 // Stopped by Ctrl+C, SIGTERM, or SIGHUP
+)
+
+// exitCodeFor returns the status capsule exits with after err.
+func exitCodeFor(err error) int {
+	var codeErr exitCodeError
+	var notFound *volume.VolumeNotFoundError
+	var busy *oplock.BusyError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &codeErr):
+		return codeErr.code
+	case errors.As(err, &notFound):
+		return exitNotBootstrapped
+	case errors.Is(err, volume.ErrAuthFailed):
+		return exitAuthFailed
+	case docker.IsDaemonUnavailable(err):
+		return exitDockerUnavailable
+	case errors.Is(err, volume.ErrMountBusy), docker.IsMountCacheError(err):
+		return exitMountConflict
+	case errors.As(err, &busy):
+		return exitBusy
+	default:
+		return exitError
+	}
+}
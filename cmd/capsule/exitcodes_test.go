@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/oplock"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "other", err: errors.New("boom"), want: exitError},
+		{name: "not bootstrapped", err: fmt.Errorf("resolve: %w", &volume.VolumeNotFoundError{LocalPath: "capsule.sparseimage"}), want: exitNotBootstrapped},
+		{name: "wrong password", err: fmt.Errorf("failed to mount volume: %w", volume.ErrAuthFailed), want: exitAuthFailed},
+		{name: "docker unavailable", err: fmt.Errorf("start: %w", docker.ErrDaemonUnavailable), want: exitDockerUnavailable},
+		{name: "mount busy", err: fmt.Errorf("mount: %w", volume.ErrMountBusy), want: exitMountConflict},
+		{name: "lock held", err: fmt.Errorf("lock: %w", &oplock.BusyError{What: "this workspace"}), want: exitBusy},
+		{name: "interrupted", err: fmt.Errorf("start: %w", context.Canceled), want: exitInterrupted},
+		{name: "command status", err: exitCodeError{code: 42, err: errors.New("claude exited with status 42")}, want: 42},
+		{name: "wrapped command status", err: fmt.Errorf("run: %w", exitCodeError{code: exitMountConflict, err: errors.New("in use")}), want: exitMountConflict},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("exitCodeFor(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/history"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// gcTempMinAge is how old a capsule temp entry must be before gc treats it as
+// orphaned; younger ones may belong to a build still in progress.
+const gcTempMinAge = 24 * time.Hour
+
+// gcItem is one thing gc found to clean up.
+type gcItem struct {
+	kind   string
+	name   string
+	detail string
+	action string // Past tense for the report; empty means "Removed"
+	remove func(ctx context.Context) error
+}
+
+func newGcCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove leftover containers, images, temp files, and abandoned repo folders",
+		Long: `Finds what capsule leaves behind over time and removes it:
+
+  - claude-* containers that exited on their own and were never removed, or
+    whose volume is no longer mounted
+  - untagged capsule images left over from rebuilds
+  - capsule-* temp directories and files older than a day
+  - repos/ folders in the mounted volume whose workspaces, as recorded in
+    'capsule history', no longer exist; these are archived to archive/repos/
+    rather than deleted
+
+Containers using a mounted volume, images in use, and repo folders of running
+containers are never touched. Repo folders are only checked while the volume is mounted. Use
+--dry-run to see the list without changing anything.`,
+		Args: cobra.NoArgs,
+		RunE: runGc,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("dry-run", false, "Show what would be removed without changing anything")
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runGc(cmd *cobra.Command, args []string) error {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+
+	var items []gcItem
+	found, err := gcContainers(ctx, dockerManager)
+	if err != nil {
+		return err
+	}
+	items = append(items, found...)
+	if found, err = gcImages(ctx); err != nil {
+		return err
+	}
+	items = append(items, found...)
+	items = append(items, gcTempEntries(time.Now())...)
+
+	containerName, _, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	if mountPoint := findMountPoint(ctx, volumePathFlag, cwd, containerName); mountPoint != "" {
+		found, err := gcRepos(ctx, dockerManager, mountPoint)
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+	} else {
+		fmt.Println("Volume not mounted; skipping repos/ (run 'capsule unlock' to include it).")
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Nothing to clean up.")
+		return nil
+	}
+	for _, item := range items {
+		fmt.Printf("  %-10s %s  %s\n", item.kind, item.name, item.detail)
+	}
+	fmt.Printf("\n%d items\n", len(items))
+
+	if dryRun {
+		fmt.Println("Dry run: nothing changed.")
+		return nil
+	}
+	if !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("Remove %d items?", len(items)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	var failed int
+	for _, item := range items {
+		if err := item.remove(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s %s\n", cmp.Or(item.action, "Removed"), item.kind, item.name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d of %d items", failed, len(items))
+	}
+	return nil
+}
+
+// gcContainers finds capsule containers that exited without being removed,
+// and running ones whose volume is no longer mounted.
+func gcContainers(ctx context.Context, dockerManager docker.DockerManager) ([]gcItem, error) {
+	exited, err := dockerManager.ListExited(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []orphanedContainer
+	if volumeManager, err := volume.New(); err == nil {
+		if orphans, err = findOrphanedContainers(ctx, dockerManager, volumeManager); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []gcItem
+	details := make(map[string]string)
+	for _, c := range exited {
+		details[c.Name] = c.Status
+	}
+	for _, o := range orphans {
+		details[o.name] = fmt.Sprintf("volume at %s is no longer mounted", o.mountPoint)
+	}
+	for _, name := range slices.Sorted(maps.Keys(details)) {
+		items = append(items, gcItem{
+			kind:   "container",
+			name:   name,
+			detail: details[name],
+			remove: func(ctx context.Context) error { return dockerManager.RemoveContainer(ctx, name) },
+		})
+	}
+	return items, nil
+}
+
+// gcImages finds untagged capsule images.
+func gcImages(ctx context.Context) ([]gcItem, error) {
+	images, err := embedded.DanglingImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []gcItem
+	for _, image := range images {
+		items = append(items, gcItem{
+			kind:   "image",
+			name:   image.ID,
+			detail: fmt.Sprintf("%s, created %s", image.Size, image.Created),
+			remove: func(ctx context.Context) error { return embedded.RemoveImage(ctx, image.ID) },
+		})
+	}
+	return items, nil
+}
+
+// gcTempEntries finds capsule temp directories and files, such as build
+// contexts, left behind by interrupted runs.
+func gcTempEntries(now time.Time) []gcItem {
+	matches, _ := filepath.Glob(filepath.Join(os.TempDir(), "capsule-*"))
+	var items []gcItem
+	for _, path := range matches {
+		info, err := os.Lstat(path)
+		if err != nil || now.Sub(info.ModTime()) < gcTempMinAge {
+			continue
+		}
+		items = append(items, gcItem{
+			kind:   "temp",
+			name:   path,
+			detail: "modified " + formatModTime(info.ModTime()),
+			remove: func(context.Context) error { return os.RemoveAll(path) },
+		})
+	}
+	return items
+}
+
+// gcRepos finds repos/ folders whose workspaces are gone. Folders history
+// never recorded are left alone, since nothing says they are abandoned.
+func gcRepos(ctx context.Context, dockerManager docker.DockerManager, mountPoint string) ([]gcItem, error) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := history.Load(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	abandoned := history.AbandonedRepoIDs(sessions, func(workspace string) bool {
+		_, err := os.Stat(workspace)
+		return err == nil
+	})
+
+	stored, err := repo.ListStored(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	var items []gcItem
+	for _, r := range stored {
+		if !slices.Contains(abandoned, r.ID) || dockerManager.IsRunning(ctx, repo.ContainerName(r.ID)) {
+			continue
+		}
+		items = append(items, gcItem{
+			kind:   "repo",
+			name:   r.ID,
+			detail: fmt.Sprintf("%s, workspace no longer exists; will be archived", formatBytes(r.Bytes)),
+			action: "Archived",
+			remove: func(context.Context) error {
+				_, err := repo.ArchiveStored(mountPoint, r.ID)
+				return err
+			},
+		})
+	}
+	return items, nil
+}
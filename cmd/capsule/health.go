@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+)
+
+// watchContainerHealth keeps the session's _docs symlinks working, setting
+// them up again if the container restarts or one of them breaks, and says so
+// on stderr. The returned function stops watching.
+func watchContainerHealth(ctx context.Context, dockerManager docker.DockerManager, containerName string, symlinks []docker.SymlinkTarget) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	watch := &docker.HealthWatch{
+		Manager:       dockerManager,
+		ContainerName: containerName,
+		Symlinks:      symlinks,
+		OnRepair: func(reason string, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\n[capsule] Shadow docs are unavailable because %s, and setting them up again failed: %v\n", reason, err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "\n[capsule] Set up shadow docs again because %s.\n", reason)
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
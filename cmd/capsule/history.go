@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/history"
+)
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show past sessions and the time spent in each workspace",
+		Long: `Lists the sessions started with 'capsule start', oldest first, with how long
+each lasted and how its shell exited. Sessions are recorded in
+~/.capsule/` + history.FileName + `.
+
+A session shows as interrupted when capsule was killed, or the machine slept or
+shut down, before the shell exited.`,
+		Args: cobra.NoArgs,
+		RunE: runHistory,
+	}
+
+	cmd.Flags().String("since", "", "Only sessions started after a duration ago (36h, 7d, 2w) or a date (2026-01-31)")
+	cmd.Flags().String("workspace", "", "Only sessions with a workspace at or under this path (\".\" for the current directory)")
+	cmd.Flags().String("container", "", "Only sessions in this container")
+	cmd.Flags().String("status", "", "Only sessions with this status: completed, failed, running, or interrupted")
+	cmd.Flags().Int("limit", 0, "Only the most recent N sessions")
+	cmd.Flags().Bool("json", false, "Print sessions as JSON")
+
+	return cmd
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	since, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return fmt.Errorf("invalid since flag: %w", err)
+	}
+	workspace, err := cmd.Flags().GetString("workspace")
+	if err != nil {
+		return fmt.Errorf("invalid workspace flag: %w", err)
+	}
+	containerName, err := cmd.Flags().GetString("container")
+	if err != nil {
+		return fmt.Errorf("invalid container flag: %w", err)
+	}
+	status, err := cmd.Flags().GetString("status")
+	if err != nil {
+		return fmt.Errorf("invalid status flag: %w", err)
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return fmt.Errorf("invalid limit flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("invalid json flag: %w", err)
+	}
+
+	now := time.Now()
+	filter := history.Filter{Container: containerName, Status: status, Limit: limit}
+	if filter.Since, err = history.ParseSince(since, now); err != nil {
+		return err
+	}
+	switch status {
+	case "", history.StatusCompleted, history.StatusFailed, history.StatusRunning, history.StatusInterrupted:
+	default:
+		return fmt.Errorf("invalid status %q: use completed, failed, running, or interrupted", status)
+	}
+	if workspace != "" {
+		if filter.Workspace, err = filepath.Abs(workspace); err != nil {
+			return fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+	}
+
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+	dockerManager := docker.NewManager()
+	sessions, err := history.Load(path, func(container string) bool {
+		return dockerManager.IsRunning(cmd.Context(), container)
+	})
+	if err != nil {
+		return err
+	}
+	sessions = filter.Apply(sessions)
+
+	if asJSON {
+		if sessions == nil {
+			sessions = []history.Session{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No sessions recorded.")
+		return nil
+	}
+
+	var total time.Duration
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tDURATION\tSTATUS\tCONTAINER\tWORKSPACE")
+	for _, s := range sessions {
+		duration := "-"
+		if d := s.Duration(now); d > 0 || !s.End.IsZero() {
+			duration = formatDuration(d)
+			total += d
+		}
+		sessionStatus := s.Status
+		if s.Status == history.StatusFailed && s.ExitCode != nil {
+			sessionStatus = fmt.Sprintf("%s (%d)", s.Status, *s.ExitCode)
+		}
+		if s.Untrusted {
+			sessionStatus += ", untrusted"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			s.Start.Local().Format("2006-01-02 15:04"), duration, sessionStatus, s.Container, strings.Join(s.Workspaces, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d session(s), %s total\n", len(sessions), formatDuration(total))
+	return nil
+}
+
+// recordSessionStart writes a session's start to the history and returns a
+// function that records its end from the shell's exit error. History is best
+// effort: failures are logged and never stop the session.
+func recordSessionStart(workspaces []docker.Workspace, containerName string, untrusted bool) func(execErr error) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		slog.Warn("session history disabled", "error", err)
+		return func(error) {}
+	}
+
+	event := history.Event{
+		Event:     history.EventStart,
+		ID:        history.NewID(),
+		Time:      time.Now().UTC(),
+		Container: containerName,
+		Untrusted: untrusted,
+	}
+	for _, w := range workspaces {
+		event.Workspaces = append(event.Workspaces, w.Path)
+		event.RepoIDs = append(event.RepoIDs, w.RepoID)
+	}
+	if err := history.Append(path, event); err != nil {
+		slog.Warn("failed to record session start", "error", err)
+		return func(error) {}
+	}
+
+	return func(execErr error) {
+		code := 0
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(execErr, &exitErr):
+			code = exitErr.ExitCode()
+		case execErr != nil:
+			code = -1
+		}
+		// Ctrl+C at the prompt is a normal way to leave the shell
+		if code == 130 {
+			code = 0
+		}
+		end := history.Event{Event: history.EventEnd, ID: event.ID, Time: time.Now().UTC(), ExitCode: &code}
+		if err := history.Append(path, end); err != nil {
+			slog.Warn("failed to record session end", "error", err)
+		}
+	}
+}
+
+// formatDuration renders a duration as hours and minutes, or minutes and
+// seconds when shorter than an hour.
+func formatDuration(d time.Duration) string {
+	if d < time.Hour {
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/auth"
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// identityVolumeFile is the default file name of an identity volume in the
+// global volume directory.
+const identityVolumeFile = "identity.sparseimage"
+
+// resolveIdentityVolume returns the identity volume a session mounts next to
+// the data volume: --identity-volume's, else the identity_volume setting's,
+// or "" for none. The volume must exist.
+func resolveIdentityVolume(pathResolver *volume.PathResolver, flag string, settings *config.Settings) (string, error) {
+	value := flag
+	if value == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		if value, err = settings.IdentityVolumePath(homeDir); err != nil {
+			return "", err
+		}
+	}
+	if value == "" {
+		return "", nil
+	}
+	path, err := filepath.Abs(pathResolver.ResolveName(value))
+	if err != nil {
+		return "", fmt.Errorf("invalid identity volume path: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("identity volume not found at %s: create it with 'capsule bootstrap --identity'", path)
+	}
+	return path, nil
+}
+
+// mountIdentityVolume mounts the identity volume at identityPath, or reuses
+// its mount, and prepares it for the container's credential mounts. It stays
+// mounted after the session, like the data volume, until it is locked.
+func mountIdentityVolume(ctx context.Context, volumeManager volume.VolumeManager, identityPath string) (string, error) {
+	mountPoint := volumeManager.GetMountPoint(ctx, identityPath)
+	if mountPoint != "" {
+		infof("Identity volume already mounted at %s\n", mountPoint)
+	} else {
+		password, err := readVolumePassword("", "Enter identity volume password: ")
+		if err != nil {
+			return "", fmt.Errorf("password error: %w", err)
+		}
+		defer password.Clear()
+
+		infoln("Mounting identity volume...")
+		if mountPoint, err = volumeManager.Mount(ctx, identityPath, password); err != nil {
+			return "", fmt.Errorf("failed to mount identity volume: %w", err)
+		}
+		infof("Identity volume mounted at %s\n", mountPoint)
+	}
+	if err := auth.PrepareIdentity(mountPoint); err != nil {
+		return "", err
+	}
+	return mountPoint, nil
+}
+
+// containersUsingMount returns the running containers that bind-mount
+// something from the volume mounted at mountPoint.
+func containersUsingMount(ctx context.Context, dockerManager docker.DockerManager, mountPoint string) ([]string, error) {
+	names, err := dockerManager.ListRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var using []string
+	for _, name := range names {
+		sources, err := dockerManager.MountSources(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, source := range sources {
+			if capsuleMountPointOf(source, []string{filepath.Dir(mountPoint)}) == filepath.Clean(mountPoint) {
+				using = append(using, name)
+				break
+			}
+		}
+	}
+	return using, nil
+}
+
+// runLockIdentity stops the containers that mount the identity volume, then
+// unmounts it, leaving the data volumes mounted.
+func runLockIdentity(ctx context.Context, command string, format output.Format, dryRun bool) error {
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	identityPath, err := resolveIdentityVolume(pathResolver, "", settings)
+	if err != nil {
+		return err
+	}
+	if identityPath == "" {
+		return fmt.Errorf("no identity volume is configured: set identity_volume in %s", config.SettingsFile)
+	}
+
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	mountPoint := volumeManager.GetMountPoint(ctx, identityPath)
+	if mountPoint == "" {
+		infoErrf("Identity volume is not mounted. Nothing to lock.\n")
+		return printLockResult(format, output.LockResult{Status: output.StatusNotMounted, VolumePaths: []string{identityPath}})
+	}
+
+	dockerManager := docker.NewManager()
+	containers, err := containersUsingMount(ctx, dockerManager, mountPoint)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		planLock(containers, []string{mountPoint}).print(command)
+		return nil
+	}
+
+	releaseLocks, err := lockOperation(command, "", identityPath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+
+	// The containers hold the credential mounts open
+	var stopped int
+	for _, name := range containers {
+		infoErrf("Stopping container %s, which uses the identity volume...\n", name)
+		if err := dockerManager.Stop(ctx, name); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", name, err)
+		}
+		stopped++
+	}
+
+	infoErrf("Unmounting identity volume at %s...\n", mountPoint)
+	if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
+		return fmt.Errorf("failed to unmount identity volume: %w", err)
+	}
+	infoErrf("Identity volume locked. Your credentials are now secured; data volumes stay mounted.\n")
+	return printLockResult(format, output.LockResult{
+		Status:            output.StatusLocked,
+		VolumePaths:       []string{identityPath},
+		ContainersStopped: stopped,
+		VolumesLocked:     1,
+	})
+}
+
+// useIdentityVolume sets identity_volume to the identity volume just
+// bootstrapped at volumePath, unless another one is already set, and prints
+// the next steps.
+func useIdentityVolume(volumePath string) error {
+	settingsPath, err := config.DefaultSettingsPath()
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadSettings(settingsPath)
+	if err != nil {
+		return err
+	}
+	if settings.IdentityVolume == "" {
+		settings.IdentityVolume = volumePath
+		if err := config.SaveSettings(settingsPath, settings); err != nil {
+			return err
+		}
+		fmt.Printf("Set identity_volume in %s: sessions now take their credentials from it.\n", settingsPath)
+	} else {
+		fmt.Printf("identity_volume is already set to %s; pass --identity-volume %s to use this one.\n", settings.IdentityVolume, volumePath)
+	}
+	fmt.Println("")
+	fmt.Println("Next steps:")
+	fmt.Printf("  capsule auth set --volume %s   (or log in to Claude in the next session)\n", volumePath)
+	fmt.Println("  capsule start")
+	return nil
+}
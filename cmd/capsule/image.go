@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+)
+
+func newBuildImageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build-image",
+		Short: "Build the Docker image",
+		Long: `Build the Docker image from the embedded Dockerfile. This is done automatically on first start.
+
+--flavor picks the image variant: slim (only what capsule and Claude Code need,
+builds in about a minute), standard, or full (adds compilers, Go, and Python
+tooling). image_flavor in ~/.capsule/config.json sets the default.
+
+With --template, builds the image for a bootstrap template instead
+(claude-capsule-<template>:latest).
+
+--base-image swaps the Dockerfile's base (node:20-slim), e.g. for an internal
+hardened image; it must be Debian-based with Node and npm. --build-arg sets any
+other ARG, such as GO_VERSION for the go template. base_image and build_args in
+~/.capsule/config.json apply to every build, including the automatic one on
+start; flags override them.
+
+Builds use BuildKit and reuse unchanged layers, so a rebuild after a capsule
+upgrade only redoes the layers that changed. Images carry inline cache
+metadata: push one to a registry and pass it to --cache-from (or cache_from in
+the config) to seed builds on other machines. --no-cache rebuilds everything.
+
+--claude-version pins the Claude Code release installed in the image, e.g.
+1.0.58 (claude_code_version in the config sets it for every build). Without a
+pin, the build installs whatever npm considers latest. See 'capsule
+claude-version' to compare the installed release with the newest one.`,
+		RunE: runBuildImage,
+	}
+
+	cmd.Flags().Bool("force", false, "Rebuild even if image already exists")
+	cmd.Flags().String("flavor", "", "Image flavor: slim, standard, or full (default from config, else standard)")
+	cmd.Flags().String("template", "", "Build the image for a template: go, node, python, or ml")
+	cmd.Flags().String("base-image", "", "Base image to build on instead of node:20-slim")
+	cmd.Flags().StringArray("build-arg", nil, "Set a Dockerfile build argument: KEY=VALUE (repeatable)")
+	cmd.Flags().StringArray("cache-from", nil, "Reuse layers from an image, e.g. one pushed to your registry (repeatable)")
+	cmd.Flags().Bool("no-cache", false, "Rebuild every layer from scratch (implies --force)")
+	cmd.Flags().Bool("no-host-proxy", false, "Don't pass the host's HTTP(S)_PROXY settings to the build")
+	cmd.Flags().String("claude-version", "", "Claude Code release to install, e.g. 1.0.58 (default from config, else latest)")
+
+	return cmd
+}
+
+func runBuildImage(cmd *cobra.Command, args []string) error {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("invalid force flag: %w", err)
+	}
+	flavorFlag, err := cmd.Flags().GetString("flavor")
+	if err != nil {
+		return fmt.Errorf("invalid flavor flag: %w", err)
+	}
+	templateFlag, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	template, err := embedded.ParseTemplate(templateFlag)
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	baseImage, err := cmd.Flags().GetString("base-image")
+	if err != nil {
+		return fmt.Errorf("invalid base-image flag: %w", err)
+	}
+	buildArgFlags, err := cmd.Flags().GetStringArray("build-arg")
+	if err != nil {
+		return fmt.Errorf("invalid build-arg flag: %w", err)
+	}
+	for _, arg := range buildArgFlags {
+		if err := embedded.ValidateBuildArg(arg); err != nil {
+			return fmt.Errorf("invalid build-arg flag: %w", err)
+		}
+	}
+	if baseImage != "" {
+		buildArgFlags = append(buildArgFlags, embedded.BaseImageArg+"="+baseImage)
+	}
+
+	cacheFrom, err := cmd.Flags().GetStringArray("cache-from")
+	if err != nil {
+		return fmt.Errorf("invalid cache-from flag: %w", err)
+	}
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	if err != nil {
+		return fmt.Errorf("invalid no-cache flag: %w", err)
+	}
+	noHostProxy, err := cmd.Flags().GetBool("no-host-proxy")
+	if err != nil {
+		return fmt.Errorf("invalid no-host-proxy flag: %w", err)
+	}
+	claudeVersion, err := cmd.Flags().GetString("claude-version")
+	if err != nil {
+		return fmt.Errorf("invalid claude-version flag: %w", err)
+	}
+	if claudeVersion != "" {
+		if err := embedded.ValidateClaudeCodeVersion(claudeVersion); err != nil {
+			return fmt.Errorf("invalid claude-version flag: %w", err)
+		}
+	}
+
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	settings.NoHostProxy = settings.NoHostProxy || noHostProxy
+	if claudeVersion != "" {
+		settings.ClaudeCodeVersion = claudeVersion
+	}
+	opts, err := imageBuildOptions(cmd.Context(), settings, flavorFlag)
+	if err != nil {
+		return err
+	}
+	opts.Template = template
+	opts.BuildArgs = append(opts.BuildArgs, buildArgFlags...)
+	opts.CacheFrom = append(opts.CacheFrom, cacheFrom...)
+	opts.NoCache = noCache
+	imageName := docker.ImageName(opts.Flavor, opts.Template)
+
+	if !force && !noCache && embedded.ImageExists(imageName) && imageMatchesPin(cmd.Context(), imageName, opts.ClaudeCodeVersion) {
+		fmt.Printf("Docker image '%s' already exists. Use --force to rebuild.\n", imageName)
+		return nil
+	}
+
+	if err := buildImage(cmd.Context(), imageName, opts); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+
+	fmt.Println("Docker image built successfully!")
+	return nil
+}
+
+func newImageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "Move Docker images to machines without registry access",
+	}
+
+	cmd.AddCommand(newImageExportCmd(), newImageImportCmd())
+
+	return cmd
+}
+
+func newImageExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Save the capsule image to a file",
+		Long: `Saves the image capsule start would use (or the one chosen with --flavor and
+--template) to an archive with docker save, along with the capsule version that
+built it. Copy the archive to an air-gapped machine and load it with
+'capsule image import'.`,
+		Args: cobra.NoArgs,
+		RunE: runImageExport,
+	}
+
+	cmd.Flags().String("to", "capsule-image.tar", "Archive to write")
+	cmd.Flags().String("flavor", "", "Image flavor: slim, standard, or full (default from config, else standard)")
+	cmd.Flags().String("template", "", "Export the image for a template: go, node, python, or ml")
+
+	return cmd
+}
+
+func newImageImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Load a capsule image from a file written by 'capsule image export'",
+		Args:  cobra.NoArgs,
+		RunE:  runImageImport,
+	}
+
+	cmd.Flags().String("from", "capsule-image.tar", "Archive to read")
+
+	return cmd
+}
+
+func runImageExport(cmd *cobra.Command, args []string) error {
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return fmt.Errorf("invalid to flag: %w", err)
+	}
+	flavorFlag, err := cmd.Flags().GetString("flavor")
+	if err != nil {
+		return fmt.Errorf("invalid flavor flag: %w", err)
+	}
+	templateFlag, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	template, err := embedded.ParseTemplate(templateFlag)
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	opts, err := imageBuildOptions(cmd.Context(), settings, flavorFlag)
+	if err != nil {
+		return err
+	}
+
+	meta := embedded.ImageArchive{
+		CapsuleVersion: version,
+		Image:          docker.ImageName(opts.Flavor, template),
+		Flavor:         opts.Flavor,
+		Template:       template,
+	}
+	if !embedded.ImageExists(meta.Image) {
+		return fmt.Errorf("image %s not found; build it with 'capsule build-image'", meta.Image)
+	}
+	fmt.Printf("Exporting %s to %s...\n", meta.Image, to)
+	if err := embedded.ExportImage(cmd.Context(), to, meta); err != nil {
+		return err
+	}
+	if info, err := os.Stat(to); err == nil {
+		fmt.Printf("Wrote %s (%s).\n", to, formatBytes(info.Size()))
+	}
+	return nil
+}
+
+func runImageImport(cmd *cobra.Command, args []string) error {
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return fmt.Errorf("invalid from flag: %w", err)
+	}
+
+	// Check the metadata before handing gigabytes to docker load
+	meta, err := embedded.ReadImageArchive(from)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", from, err)
+	}
+	fmt.Printf("Importing %s (exported by capsule %s on %s)...\n",
+		meta.Image, meta.CapsuleVersion, meta.Created.Local().Format("2006-01-02"))
+	if _, err := embedded.ImportImage(cmd.Context(), from); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %s.\n", meta.Image)
+	if meta.CapsuleVersion != version {
+		fmt.Printf("Note: this is capsule %s; the image may differ from what this release would build.\n", version)
+	}
+	if meta.Flavor != embedded.DefaultFlavor || meta.Template != "" {
+		fmt.Println("capsule start uses it for volumes and settings with the same flavor and template.")
+	}
+	return nil
+}
+
+// ensureImage builds an image if it doesn't exist yet, and rebuilds it if
+// claude_code_version pins a different Claude Code release than it was built with.
+func ensureImage(ctx context.Context, imageName string, opts embedded.BuildOptions) error {
+	if !embedded.ImageExists(imageName) {
+		infof("Docker image '%s' not found.\n", imageName)
+	} else if !imageMatchesPin(ctx, imageName, opts.ClaudeCodeVersion) {
+		infof("Docker image '%s' was built without the pinned Claude Code %s; rebuilding.\n", imageName, opts.ClaudeCodeVersion)
+	} else {
+		return nil
+	}
+	if err := buildImage(ctx, imageName, opts); err != nil {
+		return fmt.Errorf("failed to build Docker image: %w", err)
+	}
+	infoln("Docker image built successfully!")
+	return nil
+}
+
+// imageMatchesPin reports whether an existing image was built with the
+// Claude Code pin claudeVersion. Without a pin, any image matches.
+func imageMatchesPin(ctx context.Context, imageName, claudeVersion string) bool {
+	if claudeVersion == "" {
+		return true
+	}
+	pin, err := embedded.ImageClaudeCodePin(ctx, imageName)
+	return err == nil && pin == claudeVersion
+}
+
+// buildImage builds an image and, when Claude Code is pinned to an exact
+// release, checks that the release is what ended up installed.
+func buildImage(ctx context.Context, imageName string, opts embedded.BuildOptions) error {
+	if err := embedded.BuildImage(ctx, imageName, opts); err != nil {
+		return err
+	}
+	if !embedded.IsExactRelease(opts.ClaudeCodeVersion) {
+		return nil
+	}
+	installed, err := embedded.InstalledClaudeCodeVersion(ctx, imageName)
+	if err != nil {
+		return fmt.Errorf("failed to verify Claude Code: %w", err)
+	}
+	if installed != opts.ClaudeCodeVersion {
+		return fmt.Errorf("image %s has Claude Code %s installed, but %s is pinned", imageName, installed, opts.ClaudeCodeVersion)
+	}
+	return nil
+}
+
+// imageBuildOptions returns the build options from ~/.capsule/config.json,
+// with the flavor named by the --flavor flag if it is set. Unless turned off,
+// the host's proxy settings are passed as build args.
+func imageBuildOptions(ctx context.Context, settings *config.Settings, flavorFlag string) (embedded.BuildOptions, error) {
+	opts := embedded.BuildOptions{CapsuleVersion: version}
+	var err error
+	if flavorFlag != "" {
+		if opts.Flavor, err = embedded.ParseFlavor(flavorFlag); err != nil {
+			return opts, fmt.Errorf("invalid flavor flag: %w", err)
+		}
+	} else if opts.Flavor, err = embedded.ParseFlavor(settings.ImageFlavor); err != nil {
+		return opts, fmt.Errorf("invalid image_flavor in %s: %w", config.SettingsFile, err)
+	}
+
+	opts.BuildArgs = settings.ImageBuildArgs()
+	for _, arg := range opts.BuildArgs {
+		if err := embedded.ValidateBuildArg(arg); err != nil {
+			return opts, fmt.Errorf("invalid build_args in %s: %w", config.SettingsFile, err)
+		}
+	}
+	opts.CacheFrom = settings.CacheFrom
+	if settings.ClaudeCodeVersion != "" {
+		if err := embedded.ValidateClaudeCodeVersion(settings.ClaudeCodeVersion); err != nil {
+			return opts, fmt.Errorf("invalid claude_code_version in %s: %w", config.SettingsFile, err)
+		}
+		opts.ClaudeCodeVersion = settings.ClaudeCodeVersion
+	}
+
+	// Proxy settings go first so build_args and --build-arg can override them
+	if !settings.NoHostProxy {
+		opts.BuildArgs = append(docker.HostProxyEnv(ctx), opts.BuildArgs...)
+	}
+	if opts.CACerts, err = caCertBundle(settings); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// caCertBundle reads the ca_certs files in ~/.capsule/config.json into one
+// PEM bundle, or returns nil if none are configured.
+func caCertBundle(settings *config.Settings) ([]byte, error) {
+	if len(settings.CACerts) == 0 {
+		return nil, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	paths, err := settings.CACertPaths(homeDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", config.SettingsFile, err)
+	}
+	return embedded.ReadCACerts(paths)
+}
+
+// writeCABundle writes a container's CA bundle under ~/.capsule/run for
+// mounting, returning its path.
+func writeCABundle(containerName string, bundle []byte) (string, error) {
+	path, err := caBundlePath(containerName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, bundle, constants.PublicFilePermissions); err != nil {
+		return "", fmt.Errorf("failed to write CA bundle: %w", err)
+	}
+	return path, nil
+}
+
+// caBundlePath returns ~/.capsule/run/<container>-ca.pem.
+func caBundlePath(containerName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, containerName+"-ca.pem"), nil
+}
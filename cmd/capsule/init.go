@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the encrypted volume if there is none, then start",
+		Long: `Sets up capsule in one step. If no volume is found for the current directory,
+init walks through 'capsule bootstrap' (location, size, and a new password),
+mounts the new volume with that password, and continues as 'capsule start':
+the image is built if needed and you land in the container's shell.
+
+With --from-backup, init restores a backed-up volume image instead of
+creating one, as 'capsule bootstrap --from-backup' does, and starts with it.
+
+If a volume already exists, init is the same as 'capsule start'.
+
+It takes the flags of both commands. --volume and --password-file apply to
+creating the volume and to starting with it.`,
+		Args: cobra.NoArgs,
+		RunE: runInit,
+	}
+
+	addStartFlags(cmd)
+	addShellFlags(cmd)
+	addBootstrapFlags(cmd)
+
+	return cmd
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	if volumePath, exists := pathResolver.ResolveVolumePath(volumePathFlag, cwd); exists {
+		infof("Using the volume at %s.\n", volumePath)
+		return startSession(cmd, nil)
+	}
+	if dryRun {
+		return fmt.Errorf("no volume found; --dry-run needs one (run 'capsule init' without it to create the volume)")
+	}
+
+	infoln("No encrypted volume found. Let's create one.")
+	infoln("")
+	return bootstrapVolume(cmd, func(volumeManager volume.VolumeManager, volumePath string, password *terminal.SecurePassword) error {
+		// Mount with the password just entered, so start reuses the mount
+		// instead of asking for it again
+		infoln("")
+		infoln("Mounting the new volume...")
+		if _, err := volumeManager.Mount(cmd.Context(), volumePath, password); err != nil {
+			return fmt.Errorf("failed to mount volume: %w", err)
+		}
+		return startSession(cmd, nil)
+	})
+}
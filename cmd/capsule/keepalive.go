@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+)
+
+// idlePollInterval is how often 'capsule stop --idle' checks for open shells.
+const idlePollInterval = 15 * time.Second
+
+// scheduleIdleStop starts 'capsule stop --idle' in the background, so a
+// container kept alive after its shell exits is stopped once no shell has
+// been open in it for grace. It runs in its own process group, so closing
+// the terminal doesn't take it down; its messages go to capsule's log.
+func scheduleIdleStop(containerName, workspacePath string, grace time.Duration) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate capsule binary: %w", err)
+	}
+	cmd := exec.Command(executable, "stop", "--quiet", "--idle", grace.String(), "--container", containerName)
+	cmd.Dir = workspacePath
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to schedule stop of %s: %w", containerName, err)
+	}
+	slog.Info("scheduled idle stop", "container", containerName, "grace", grace.String(), "pid", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// waitUntilIdle waits until no shell has been open in the container for
+// idle, returning true. It returns false if the container stops or is
+// started again in the meantime, since the container it was waiting for is
+// gone either way.
+func waitUntilIdle(ctx context.Context, dockerManager docker.DockerManager, containerName string, idle time.Duration) (bool, error) {
+	startedAt, err := dockerManager.StartedAt(ctx, containerName)
+	if err != nil {
+		return false, err
+	}
+	idleSince := time.Now()
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for {
+		if !dockerManager.IsRunning(ctx, containerName) {
+			return false, nil
+		}
+		if restartedAt, err := dockerManager.StartedAt(ctx, containerName); err == nil && !restartedAt.Equal(startedAt) {
+			return false, nil
+		}
+		if sessions, err := dockerManager.ExecSessions(ctx, containerName); err != nil || sessions > 0 {
+			idleSince = time.Now()
+		}
+		if time.Since(idleSince) >= idle {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
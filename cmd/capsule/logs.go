@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+)
+
+func newLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the output of the workspace's container",
+		Long: `Shows docker logs for the container of the current workspace, without needing
+its claude-<hash> name. This works while the container is running and after it
+exits on its own; 'capsule stop' removes the container along with its logs.`,
+		Args: cobra.NoArgs,
+		RunE: runLogs,
+	}
+
+	cmd.Flags().BoolP("follow", "f", false, "Keep streaming new output until interrupted")
+	cmd.Flags().IntP("tail", "n", 0, "Show only the last N lines (default all)")
+	cmd.Flags().String("since", "", "Show output since a timestamp or relative time (e.g. 10m)")
+	cmd.Flags().BoolP("timestamps", "t", false, "Prefix each line with its timestamp")
+
+	return cmd
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	follow, err := cmd.Flags().GetBool("follow")
+	if err != nil {
+		return fmt.Errorf("invalid follow flag: %w", err)
+	}
+	tail, err := cmd.Flags().GetInt("tail")
+	if err != nil {
+		return fmt.Errorf("invalid tail flag: %w", err)
+	}
+	if tail < 0 {
+		return fmt.Errorf("invalid tail flag: must not be negative")
+	}
+	since, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return fmt.Errorf("invalid since flag: %w", err)
+	}
+	timestamps, err := cmd.Flags().GetBool("timestamps")
+	if err != nil {
+		return fmt.Errorf("invalid timestamps flag: %w", err)
+	}
+
+	containerName, _, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+	if !dockerManager.IsRunning(ctx, containerName) {
+		exited, err := dockerManager.ListExited(ctx)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(exited, func(c docker.ExitedContainer) bool { return c.Name == containerName }) {
+			return fmt.Errorf("no container for this workspace (%s); start one with 'capsule start'", containerName)
+		}
+	}
+
+	err = dockerManager.Logs(ctx, containerName, docker.LogOptions{
+		Follow:     follow,
+		Tail:       tail,
+		Since:      since,
+		Timestamps: timestamps,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+	})
+	if follow && ctx.Err() != nil {
+		// Interrupting --follow is how it is meant to end
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("docker logs failed: %w", err)
+	}
+	return nil
+}
@@ -1,84 +1,125 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jeanhaley32/claude-capsule/internal/auth"
+	"github.com/jeanhaley32/claude-capsule/internal/clipboard"
+	"github.com/jeanhaley32/claude-capsule/internal/config"
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/daemon"
 	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/dotfiles"
 	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/envfile"
+	"github.com/jeanhaley32/claude-capsule/internal/gitidentity"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/output"
 	"github.com/jeanhaley32/claude-capsule/internal/platform"
 	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/secrets"
+	"github.com/jeanhaley32/claude-capsule/internal/startstate"
 	"github.com/jeanhaley32/claude-capsule/internal/state"
 	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/transcript"
 	"github.com/jeanhaley32/claude-capsule/internal/volume"
 )
 
 var version = "0.3.0"
 
-// setupShutdownHandler registers signal handlers for graceful shutdown.
-// Returns a cancel function that should be deferred to cleanup the handler.
-// The cleanup function is ONLY called when a signal is received, not on normal exit.
-func setupShutdownHandler(cleanup func()) func() {
-	sigChan := make(chan os.Signal, 1)
-	done := make(chan struct{})
+// worktreePolicy is set from the --worktree-policy persistent flag.
+// Empty means each repository's configured policy (default: shared).
+var worktreePolicy repo.WorktreePolicy
+
+// subproject is set from the --subproject persistent flag.
+var subproject string
+
+// newRepoIdentifier returns a repository identifier honoring --worktree-policy
+// and --subproject. Without --subproject, a subproject configured in git
+// (capsule.subproject) that contains the current directory is used.
+func newRepoIdentifier() *repo.DefaultIdentifier {
+	identifier := repo.NewIdentifierWithPolicy(worktreePolicy)
+	identifier.Subproject = subproject
+	if identifier.Subproject == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			identifier.Subproject = repo.DetectSubproject(cwd)
+		}
+	}
+	return identifier
+}
 
+// signalContext returns a context canceled by the first SIGINT, SIGTERM, or
+// SIGHUP. Canceling it stops the hdiutil or docker command in progress, so the
+// interrupted command returns and runs its own cleanup. A second signal gets
+// the default behavior and exits at once.
+func signalContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
 		select {
 		case sig := <-sigChan:
-			fmt.Fprintf(os.Stderr, "\nReceived signal: %v\n", sig)
-			fmt.Fprintf(os.Stderr, "Cleaning up and locking volume...\n")
-			cleanup()
-			os.Exit(1)
-		case <-done:
+			signal.Stop(sigChan)
+			slog.Info("signal received", "signal", sig.String())
+			fmt.Fprintf(os.Stderr, "\nReceived signal: %v (send it again to exit immediately)\n", sig)
+			cancel()
+		case <-ctx.Done():
 			// Normal exit - handler cancelled, do nothing
-			return
 		}
 	}()
 
-	return func() {
+	return ctx, func() {
 		signal.Stop(sigChan)
-		close(done)
+		cancel()
 	}
 }
 
-// createShutdownCleanup creates a cleanup function that locks the specified volume.
-func createShutdownCleanup(volumePath, containerName string) func() {
-	return func() {
-		volumeManager, err := volume.New()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not create volume manager: %v\n", err)
-			return
-		}
+// lockOnInterrupt stops the container and locks the volume after a signal
+// canceled ctx. It runs with a context of its own, since ctx is already done.
+func lockOnInterrupt(ctx context.Context, volumePath, containerName string) {
+	if ctx.Err() == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	infoErrf("Cleaning up and locking volume...\n")
 
-		// Stop container if running
-		dockerManager := docker.NewManager()
-		if dockerManager.IsRunning(containerName) {
-			fmt.Fprintf(os.Stderr, "Stopping container %s...\n", containerName)
-			if err := dockerManager.Stop(containerName); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to stop container: %v\n", err)
-			}
+	volumeManager, err := volume.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not create volume manager: %v\n", err)
+		return
+	}
+
+	// Stop container if running
+	dockerManager := docker.NewManager()
+	if dockerManager.IsRunning(ctx, containerName) {
+		infoErrf("Stopping container %s...\n", containerName)
+		if err := dockerManager.Stop(ctx, containerName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stop container: %v\n", err)
 		}
+	}
 
-		// Get the mount point for this specific volume (not any volume)
-		mountPoint := volumeManager.GetMountPoint(volumePath)
-		if mountPoint != "" {
-			fmt.Fprintf(os.Stderr, "Locking volume at %s...\n", mountPoint)
-			if err := volumeManager.Unmount(mountPoint); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to unmount volume: %v\n", err)
-			} else {
-				fmt.Fprintf(os.Stderr, "Volume locked successfully.\n")
-			}
+	// Get the mount point for this specific volume (not any volume)
+	mountPoint := volumeManager.GetMountPoint(ctx, volumePath)
+	if mountPoint != "" {
+		infoErrf("Locking volume at %s...\n", mountPoint)
+		if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to unmount volume: %v\n", err)
+		} else {
+			infoErrf("Volume locked successfully.\n")
 		}
 	}
 }
@@ -91,7 +132,7 @@ func getContainerNameForCwd() (string, string, error) {
 		return "", "", fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	repoIdentifier := repo.NewIdentifier()
+	repoIdentifier := newRepoIdentifier()
 	workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd)
 	if err != nil {
 		workspacePath = cwd
@@ -105,27 +146,259 @@ func getContainerNameForCwd() (string, string, error) {
 	return containerName, cwd, nil
 }
 
+// findMountPoint returns the mount point of the volume the workspace's
+// container uses, or "" if it is not mounted.
+func findMountPoint(ctx context.Context, volumePathFlag, cwd, containerName string) string {
+	volumeManager, err := volume.New()
+	if err != nil {
+		return ""
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return ""
+	}
+	volumePath, _ := resolveWorkspaceVolumePath(ctx, pathResolver, volumeManager, volumePathFlag, cwd, containerName)
+	return volumeManager.GetMountPoint(ctx, volumePath)
+}
+
+// mountForHostAccess returns a mount point for the volume, mounting it if necessary,
+// read-only if readOnly is set. The returned release function unmounts the volume
+// only if this call mounted it, so an existing session's mount is left untouched.
+// It unmounts even after ctx is canceled.
+func mountForHostAccess(ctx context.Context, volumeManager volume.VolumeManager, volumePath string, passwordStdin, readOnly bool) (string, func(), error) {
+	if existingMount := volumeManager.GetMountPoint(ctx, volumePath); existingMount != "" {
+		return existingMount, func() {}, nil
+	}
+
+	password, err := terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter volume password: ")
+	if err != nil {
+		return "", nil, fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	mount := volumeManager.Mount
+	if readOnly {
+		mount = volumeManager.MountReadOnly
+	}
+	infoErrf("Mounting encrypted volume...\n")
+	mountPoint, err := mount(ctx, volumePath, password)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to mount volume: %w", err)
+	}
+
+	release := func() {
+		if err := volumeManager.Unmount(context.WithoutCancel(ctx), mountPoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to unmount volume: %v\n", err)
+		}
+	}
+	return mountPoint, release, nil
+}
+
+// mountVolumeFromFlags resolves the --volume flag and mounts the volume for host access.
+func mountVolumeFromFlags(cmd *cobra.Command) (string, func(), error) {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	volumeManager, err := volume.New()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create volume manager: %w", err)
+	}
+
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return mountForHostAccess(cmd.Context(), volumeManager, volumePath, passwordStdin, false)
+}
+
+// readVolumePassword reads the volume password from passwordFile if set,
+// otherwise prompts for it.
+func readVolumePassword(passwordFile, prompt string) (*terminal.SecurePassword, error) {
+	if passwordFile != "" {
+		return terminal.ReadPasswordFromFileSecure(passwordFile)
+	}
+	return terminal.ReadPasswordSecure(prompt)
+}
+
+// readNewPasswordFile reads a new volume password from a file and rejects it if
+// it is too weak.
+func readNewPasswordFile(passwordFile string) (*terminal.SecurePassword, error) {
+	password, err := terminal.ReadPasswordFromFileSecure(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := terminal.CheckNewPassword(password); err != nil {
+		password.Clear()
+		return nil, err
+	}
+	return password, nil
+}
+
+// formatBytes renders a byte count using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// legacyBinaryName is what the binary was called before capsule. 'make
+// install-compat' links it to capsule so old scripts keep working.
+const legacyBinaryName = "claude-env"
+
 func main() {
+	if filepath.Base(os.Args[0]) == legacyBinaryName {
+		fmt.Fprintf(os.Stderr, "Warning: '%s' is deprecated and will be removed in a future release; run 'capsule' instead.\n", legacyBinaryName)
+	}
+
+	// closeLog is set once logging is configured
+	var closeLog func()
 	rootCmd := &cobra.Command{
 		Use:   "capsule",
 		Short: "Claude Capsule workspace environment",
 		Long:  "A containerized, security-focused workspace for Claude Code with encrypted credential storage.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logLevel, err := cmd.Flags().GetString("log-level")
+			if err != nil {
+				return fmt.Errorf("invalid log-level flag: %w", err)
+			}
+			logFile, err := cmd.Flags().GetString("log-file")
+			if err != nil {
+				return fmt.Errorf("invalid log-file flag: %w", err)
+			}
+			verbose, err := cmd.Flags().GetBool("verbose")
+			if err != nil {
+				return fmt.Errorf("invalid verbose flag: %w", err)
+			}
+			closer, err := logging.Setup(logging.Options{Level: logLevel, File: logFile, Verbose: verbose})
+			if err != nil {
+				return err
+			}
+			closeLog = closer
+			slog.Info("command started", "command", cmd.CommandPath(), "version", version)
+
+			policy, err := cmd.Flags().GetString("worktree-policy")
+			if err != nil {
+				return fmt.Errorf("invalid worktree-policy flag: %w", err)
+			}
+			worktreePolicy, err = repo.ParseWorktreePolicy(policy)
+			if err != nil {
+				return err
+			}
+			subproject, err = cmd.Flags().GetString("subproject")
+			if err != nil {
+				return fmt.Errorf("invalid subproject flag: %w", err)
+			}
+			quiet, err = cmd.Flags().GetBool("quiet")
+			if err != nil {
+				return fmt.Errorf("invalid quiet flag: %w", err)
+			}
+			return nil
+		},
 	}
 
+	rootCmd.PersistentFlags().String("worktree-policy", "",
+		"How git worktrees map to _docs and containers: shared or per-worktree (default: git config "+repo.WorktreePolicyKey+", else shared)")
+	rootCmd.PersistentFlags().String("subproject", "",
+		"Monorepo subdirectory (relative to the repo root) with its own _docs and memory (default: matching git config "+repo.SubprojectKey+")")
+	rootCmd.PersistentFlags().String("log-level", logging.DefaultLevel,
+		"Log detail on stderr: debug (shows every hdiutil and docker invocation), info, warn, or error")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false,
+		"Print every external command (hdiutil, diskutil, docker, ...) on stderr with its exit status and duration")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false,
+		"Don't print progress messages; results, prompts, warnings, and errors are still shown")
+	rootCmd.PersistentFlags().String("log-file", "",
+		"Log file, rotated at 5 MB (default ~/.capsule/logs/"+logging.FileName+"; \""+logging.Off+"\" to disable)")
+
 	rootCmd.AddCommand(
 		newBootstrapCmd(),
+		newInitCmd(),
 		newStartCmd(),
+		newEnterCmd(),
+		newRunCmd(),
 		newStopCmd(),
 		newUnlockCmd(),
 		newLockCmd(),
 		newStatusCmd(),
 		newBuildImageCmd(),
+		newImageCmd(),
+		newClaudeVersionCmd(),
+		newMemoryCmd(),
+		newBeadsCmd(),
+		newReposCmd(),
+		newDfCmd(),
+		newDocsCmd(),
+		newTrustCmd(),
+		newAuthCmd(),
+		newAutolockCmd(),
+		newVerifyCmd(),
+		newScanCmd(),
+		newCpCmd(),
+		newLogsCmd(),
+		newStatsCmd(),
+		newBenchCmd(),
+		newGcCmd(),
+		newMigrateCmd(),
+		newCloneCmd(),
+		newBackupCmd(),
+		newSyncCmd(),
+		newVolumeCmd(),
+		newTemplateCmd(),
+		newManifestCmd(),
+		newSchemaCmd(),
+		newPreflightCmd(),
+		newPluginCmd(),
+		newDaemonCmd(),
+		newHistoryCmd(),
+		newTranscriptsCmd(),
 		newVersionCmd(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// External capsule-<name> subcommands bypass cobra so their flags pass through untouched
+	if code, ok := runPluginIfAny(rootCmd, os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	ctx, stop := signalContext()
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if closeLog != nil {
+		// Logged at info so the file records it without repeating it on stderr
+		if err != nil {
+			slog.Info("command failed", "error", err)
+		}
+		closeLog()
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "Interrupted.")
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(exitCodeFor(err))
 	}
 }
 
@@ -133,20 +406,69 @@ func newBootstrapCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "bootstrap",
 		Short: "Create new encrypted volume and initialize environment",
-		RunE:  runBootstrap,
+		Long: `Creates the encrypted volume and initializes the environment.
+
+Without flags, bootstrap asks where to store the volume, how large to make it,
+and for a new password. For scripts and CI, --non-interactive never prompts:
+the location defaults to --global, the size to 2 GB, and the password must come
+from --password-stdin, --password-file, or CAPSULE_PASSWORD. Missing or weak inputs are reported
+before anything is created.
+  echo "$PASS" | capsule bootstrap --non-interactive --password-stdin --global --size 4
+
+--from-backup copies a backed-up volume image into place instead of creating a
+volume, then mounts it once with the backup's password to upgrade it to this
+version of capsule.
+  capsule bootstrap --from-backup ~/Backups/capsule.sparseimage --global
+
+--identity creates a small identity volume for the credentials instead, in
+~/.capsule/volumes/` + identityVolumeFile + ` unless --volume says otherwise, and
+sets identity_volume in the config if it is unset. Sessions then mount it next
+to the data volume, and 'capsule lock --identity' locks only the credentials.
+  capsule bootstrap --identity --api-key "$KEY"`,
+		RunE: runBootstrap,
 	}
 
+	cmd.Flags().String("volume", "", "Explicit path for encrypted volume")
+	cmd.Flags().String("password-file", "", "Read the new password from a file only you can read (mode 0600)")
+	cmd.Flags().Bool("identity", false, "Create an identity volume that holds only the credentials (default size 1 GB)")
+	addBootstrapFlags(cmd)
+
+	return cmd
+}
+
+// addBootstrapFlags registers the flags bootstrap and init share, apart from
+// --volume and --password-file, which init takes from the start flags.
+func addBootstrapFlags(cmd *cobra.Command) {
 	cmd.Flags().Int("size", 0, "Volume size in GB (prompts if not specified)")
 	cmd.Flags().String("api-key", "", "Claude API key (optional, can be added later)")
-	cmd.Flags().String("volume", "", "Explicit path for encrypted volume")
 	cmd.Flags().Bool("local", false, "Create volume in current directory")
 	cmd.Flags().Bool("global", false, "Create volume in ~/.capsule/volumes/ (default)")
 	cmd.Flags().StringSlice("context", []string{}, "Markdown files to extend Claude context (can be specified multiple times)")
-
-	return cmd
+	cmd.Flags().Bool("argon2", false, "Stretch the password with Argon2id before hdiutil (parameters are stored next to the image as <volume>"+kdf.FileSuffix+")")
+	cmd.Flags().Bool("non-interactive", false, "Never prompt; use defaults and fail if a required input is missing")
+	cmd.Flags().Bool("password-stdin", false, "Read the new password from stdin instead of terminal prompt")
+	cmd.Flags().Bool("verify", false, "Remount the new volume and check it against its manifest before finishing")
+	cmd.Flags().String("encryption", string(volume.DefaultEncryption), "Volume cipher: AES-128 or AES-256")
+	cmd.Flags().String("fs", string(volume.DefaultFilesystem), `Volume filesystem: APFS, "Case-sensitive APFS", or HFS+`)
+	cmd.Flags().String("template", "", "Language preset for the image and CLAUDE.md: go, node, python, or ml")
+	cmd.Flags().String("format", string(volume.DefaultImageFormat), "Image format: sparseimage (one file) or sparsebundle (a directory of bands, friendlier to Time Machine and sync tools)")
+	cmd.Flags().Int("band-size", 0, fmt.Sprintf("Band size of a sparsebundle in MB (default %d)", volume.DefaultBandSizeMB))
+	cmd.Flags().String("from-backup", "", "Restore a backed-up volume image instead of creating a new volume, and upgrade it to this version")
 }
 
+// newVolumeFlags are the bootstrap flags that describe a new volume, which a
+// restored backup already has.
+var newVolumeFlags = []string{"size", "api-key", "context", "argon2", "verify", "encryption", "fs", "template", "format", "band-size"}
+
 func runBootstrap(cmd *cobra.Command, args []string) error {
+	return bootstrapVolume(cmd, nil)
+}
+
+// bootstrapVolume creates the volume as the command's flags and prompts
+// describe. If then is set, it is called with the new volume and its password
+// instead of printing the next step, before the password is cleared.
+func bootstrapVolume(cmd *cobra.Command, then func(volumeManager volume.VolumeManager, volumePath string, password *terminal.SecurePassword) error) error {
+	ctx := cmd.Context()
 	size, err := cmd.Flags().GetInt("size")
 	if err != nil {
 		return fmt.Errorf("invalid size flag: %w", err)
@@ -171,6 +493,97 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("invalid context flag: %w", err)
 	}
+	stretchKey, err := cmd.Flags().GetBool("argon2")
+	if err != nil {
+		return fmt.Errorf("invalid argon2 flag: %w", err)
+	}
+	nonInteractive, err := cmd.Flags().GetBool("non-interactive")
+	if err != nil {
+		return fmt.Errorf("invalid non-interactive flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	verify, err := cmd.Flags().GetBool("verify")
+	if err != nil {
+		return fmt.Errorf("invalid verify flag: %w", err)
+	}
+	encryptionFlag, err := cmd.Flags().GetString("encryption")
+	if err != nil {
+		return fmt.Errorf("invalid encryption flag: %w", err)
+	}
+	encryption, err := volume.ParseEncryption(encryptionFlag)
+	if err != nil {
+		return fmt.Errorf("invalid encryption flag: %w", err)
+	}
+	fsFlag, err := cmd.Flags().GetString("fs")
+	if err != nil {
+		return fmt.Errorf("invalid fs flag: %w", err)
+	}
+	filesystem, err := volume.ParseFilesystem(fsFlag)
+	if err != nil {
+		return fmt.Errorf("invalid fs flag: %w", err)
+	}
+	templateFlag, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	template, err := embedded.ParseTemplate(templateFlag)
+	if err != nil {
+		return fmt.Errorf("invalid template flag: %w", err)
+	}
+	formatFlag, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("invalid format flag: %w", err)
+	}
+	format, err := volume.ParseImageFormat(formatFlag)
+	if err != nil {
+		return fmt.Errorf("invalid format flag: %w", err)
+	}
+	bandSize, err := cmd.Flags().GetInt("band-size")
+	if err != nil {
+		return fmt.Errorf("invalid band-size flag: %w", err)
+	}
+	fromBackup, err := cmd.Flags().GetString("from-backup")
+	if err != nil {
+		return fmt.Errorf("invalid from-backup flag: %w", err)
+	}
+	// Only bootstrap has --identity; init always creates a data volume
+	identity := false
+	if cmd.Flags().Lookup("identity") != nil {
+		if identity, err = cmd.Flags().GetBool("identity"); err != nil {
+			return fmt.Errorf("invalid identity flag: %w", err)
+		}
+	}
+	if identity {
+		for _, name := range []string{"local", "template", "context", "from-backup"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--identity and --%s cannot be used together", name)
+			}
+		}
+	}
+	if passwordStdin && passwordFile != "" {
+		return fmt.Errorf("--password-stdin and --password-file cannot be used together")
+	}
+	if fromBackup != "" {
+		for _, name := range newVolumeFlags {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--from-backup and --%s cannot be used together: the backup's volume is restored as it is", name)
+			}
+		}
+	}
+	// Piped stdin holds the password, so it can't also answer prompts
+	if passwordStdin {
+		nonInteractive = true
+	}
+	if nonInteractive && !passwordStdin && passwordFile == "" && os.Getenv(terminal.PasswordEnvVar) == "" {
+		return fmt.Errorf("--non-interactive requires a password: pipe it with --password-stdin, use --password-file, or set %s", terminal.PasswordEnvVar)
+	}
 	// Convert context files to absolute paths
 	for i, ctxFile := range contextFiles {
 		if !filepath.IsAbs(ctxFile) {
@@ -180,6 +593,10 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 			}
 			contextFiles[i] = absCtxPath
 		}
+		// Checked now so a typo doesn't leave a half-initialized volume behind
+		if _, err := os.Stat(contextFiles[i]); err != nil {
+			return fmt.Errorf("invalid context file: %w", err)
+		}
 	}
 
 	// Create path resolver
@@ -196,7 +613,7 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 
 	// Determine volume path based on flags or interactive prompt
 	var volumePath string
-	locationSpecified := volumePathFlag != "" || localFlag || globalFlag
+	locationSpecified := volumePathFlag != "" || localFlag || globalFlag || nonInteractive || identity
 
 	if volumePathFlag != "" {
 		// Explicit path provided
@@ -207,11 +624,13 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("invalid volume path: %w", err)
 			}
 		}
+	} else if identity {
+		volumePath = filepath.Join(pathResolver.GetGlobalVolumeDir(), identityVolumeFile)
 	} else if localFlag {
 		// Local flag
 		volumePath = pathResolver.GetLocalVolumePath(cwd)
-	} else if globalFlag {
-		// Global flag
+	} else if globalFlag || nonInteractive {
+		// Global flag, or the default when prompts are off
 		volumePath = pathResolver.GetDefaultVolumePath()
 	} else {
 		// Interactive prompt for location
@@ -230,9 +649,23 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Without --format, the path's extension names the format
+	if cmd.Flags().Changed("format") && volumePathFlag == "" {
+		volumePath = volume.WithFormat(volumePath, format)
+	} else if !cmd.Flags().Changed("format") {
+		format = volume.FormatOf(volumePath)
+	}
+
+	if fromBackup != "" {
+		return restoreBackup(ctx, fromBackup, volumePath, passwordFile, passwordStdin, then)
+	}
+
 	// Prompt for size if not specified
 	if size == 0 {
-		if locationSpecified {
+		if identity {
+			// The credentials need next to no space
+			size = constants.MinVolumeSizeGB
+		} else if locationSpecified {
 			// If location was specified via flag, use default size
 			size = 2
 		} else {
@@ -255,54 +688,87 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create volume manager: %w", err)
 	}
 
-	// Check if volume already exists
-	if volumeManager.Exists(volumePath) {
-		return fmt.Errorf("already bootstrapped: volume exists at %s\nUse 'capsule start' to begin a session", volumePath)
+	// Check if volume already exists, in either format
+	for _, f := range volume.ImageFormats {
+		if existing := volume.WithFormat(volumePath, f); volumeManager.Exists(existing) {
+			return fmt.Errorf("already bootstrapped: volume exists at %s\nUse 'capsule start' to begin a session", existing)
+		}
 	}
 
 	// Prompt for password
-	password, err := terminal.ReadPasswordConfirmSecure(
-		"Enter encryption password: ",
-		"Confirm password: ",
-	)
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = readNewPasswordFile(passwordFile)
+	} else if nonInteractive {
+		password, err = terminal.ReadNewPasswordNonInteractive(passwordStdin)
+	} else {
+		password, err = terminal.ReadPasswordConfirmSecure(
+			"Enter encryption password: ",
+			"Confirm password: ",
+		)
+	}
 	if err != nil {
 		return fmt.Errorf("password error: %w", err)
 	}
 	defer password.Clear()
 
-	fmt.Printf("Creating encrypted volume at %s...\n", volumePath)
+	fmt.Printf("Creating encrypted volume at %s (%s, %s, %s)...\n", volumePath, encryption, filesystem, format)
 
 	// Bootstrap the volume
+	var phases []string
+	var total time.Duration
 	cfg := volume.BootstrapConfig{
 		VolumePath:   volumePath,
 		SizeGB:       size,
 		Password:     password,
 		ContextFiles: contextFiles,
 		Version:      version,
+		StretchKey:   stretchKey,
+		Encryption:   encryption,
+		Filesystem:   filesystem,
+		Template:     template,
+		Format:       format,
+		BandSizeMB:   bandSize,
+		Identity:     identity,
+		Verify:       verify,
+		OnPhase: func(phase string, elapsed time.Duration) {
+			phases = append(phases, fmt.Sprintf("%s %.1fs", phase, elapsed.Seconds()))
+			total += elapsed
+		},
 	}
 
-	if err := volumeManager.Bootstrap(cfg); err != nil {
-		return fmt.Errorf("bootstrap failed: %w", err)
-	}
-
-	// If API key provided, write it to the volume
+	// Write the API key while the new volume is mounted, before the manifest is signed
 	if apiKey != "" {
-		// Mount volume to write API key
-		mountPoint, err := volumeManager.Mount(volumePath, password)
-		if err != nil {
-			fmt.Printf("Warning: Could not mount volume to save API key: %v\n", err)
-		} else {
-			apiKeyPath := filepath.Join(mountPoint, "auth", "api-key")
-			if err := os.WriteFile(apiKeyPath, []byte(apiKey), constants.FilePermissions); err != nil {
+		cfg.Setup = func(mountPoint string) error {
+			if err := auth.WriteAPIKey(mountPoint, apiKey); err != nil {
 				fmt.Printf("Warning: Could not save API key: %v\n", err)
 			}
-			if err := volumeManager.Unmount(mountPoint); err != nil {
-				fmt.Printf("Warning: Could not unmount volume after saving API key: %v\n", err)
-			}
+			return nil
 		}
 	}
 
+	if err := volumeManager.Bootstrap(ctx, cfg); err != nil {
+		return fmt.Errorf("bootstrap failed: %w", err)
+	}
+
 	fmt.Println("Volume created successfully!")
+	fmt.Printf("Took %.1fs (%s)\n", total.Seconds(), strings.Join(phases, ", "))
+	if verify {
+		fmt.Println("Verified: the volume remounts and matches its manifest.")
+	}
+	if stretchKey {
+		fmt.Printf("Key stretching parameters: %s\n", kdf.Path(volumePath))
+		fmt.Println("Back this file up with the volume: without it the volume cannot be unlocked.")
+	}
+	if then != nil {
+		return then(volumeManager, volumePath, password)
+	}
+	if identity {
+		return useIdentityVolume(volumePath)
+	}
+	if template != "" {
+		fmt.Printf("Template: %s (its image is built on first start)\n", template)
+	}
 	fmt.Println("")
 	fmt.Println("Next step:")
 	fmt.Println("  capsule start")
@@ -314,259 +780,900 @@ func newStartCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Mount volume and start container",
-		RunE:  runStart,
+		Long: `Mounts the encrypted volume, starts a container for the workspace, and enters a shell.
+
+Repeat --workspace to mount several projects into one container for tasks that span
+repositories. Each is mounted at /workspaces/<name> with its own _docs folder and
+repository ID, and the container stops when you exit the shell.
+
+--workspaces-file starts a separate container for each directory listed in a
+file, one per line, all at once and detached. The volumes they use are
+mounted first with one password prompt, then a table shows how each start
+went; attach with 'capsule enter' in a workspace's directory.
+
+--dry-run prints the mounts, container commands, and deletions start would
+make, without making them.`,
+		RunE: runStart,
 	}
 
-	cmd.Flags().String("volume", "", "Path to encrypted volume (auto-detected if not specified)")
-	cmd.Flags().String("workspace", "", "Workspace path (defaults to current directory or git root)")
+	addStartFlags(cmd)
+	addShellFlags(cmd)
+	cmd.Flags().String("workspaces-file", "", "Start a container for each directory listed in this file, one per line, at once and detached")
 
 	return cmd
 }
 
+// addShellFlags registers the flags for the interactive shell, which start
+// and init open and run does not.
+func addShellFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("detach", "d", false, "Start the container and return without opening a shell (use 'capsule enter' to attach)")
+	cmd.Flags().String("keep-alive", "", "Leave the container running when the shell exits: always, or a duration such as 30m to stop it once idle that long (default from config, else off)")
+	cmd.Flags().Lookup("keep-alive").NoOptDefVal = config.KeepAliveAlways
+	cmd.Flags().Bool("record", false, "Record the shell session into the volume (see 'capsule transcripts')")
+	cmd.Flags().String("clipboard", "", "Bridge pbcopy/pbpaste to the host clipboard: copy, copy-paste, or off (default from config, else off)")
+	cmd.Flags().Lookup("clipboard").NoOptDefVal = string(clipboard.ModeCopy)
+	cmd.Flags().Bool("no-browser-bridge", false, "Don't open URLs from the container in the host browser")
+}
+
+// addStartFlags registers the flags start and run share.
+func addStartFlags(cmd *cobra.Command) {
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().StringArray("workspace", nil, "Workspace path (defaults to current directory or git root); repeat to mount several")
+	cmd.Flags().Bool("untrusted", false, "Start without the credential home mounted (only this project's _docs)")
+	cmd.Flags().Bool("git-identity", false, "Copy git user.name/email from the host and store git credentials in the volume")
+	cmd.Flags().Bool("sign", false, "Sign commits and tags with your host gpg key through a host-side signing proxy")
+	cmd.Flags().Bool("ram-auth", false, "Keep auth/ on an encrypted RAM disk for this session; it is destroyed on lock")
+	cmd.Flags().String("identity-volume", "", "Path or name of the identity volume with the credentials (default from config, else none)")
+	cmd.Flags().String("flavor", "", "Image flavor: slim, standard, or full (default from config, else standard)")
+	cmd.Flags().Bool("no-host-proxy", false, "Don't pass the host's HTTP(S)_PROXY settings to the container or image build")
+	cmd.Flags().StringArray("dns", nil, "DNS server IP for the container, replacing dns in the config (repeatable)")
+	cmd.Flags().StringArray("dns-search", nil, "DNS search domain for the container, replacing dns_search in the config (repeatable)")
+	cmd.Flags().String("restart", "", "Container restart policy after Docker restarts: no or unless-stopped (default from config, else no)")
+	cmd.Flags().StringArray("secret", nil, "Inject a 1Password secret as an env var: op://vault/item/field=ENV_NAME (repeatable)")
+	cmd.Flags().StringArray("env", nil, "Set a container environment variable: KEY=VALUE, or KEY to copy it from the host (repeatable)")
+	cmd.Flags().StringArray("env-file", nil, "Read environment variables from a file; prefix with volume: for files under config/env in the volume (repeatable)")
+	cmd.Flags().StringArray("vault-path", nil, "Inject each key of a HashiCorp Vault secret as an env var, renewing leases during the session (repeatable)")
+	cmd.Flags().String("password-file", "", "Read the volume password from a file only you can read (mode 0600)")
+	addDryRunFlag(cmd)
+}
+
 func runStart(cmd *cobra.Command, args []string) error {
+	workspacesFile, err := cmd.Flags().GetString("workspaces-file")
+	if err != nil {
+		return fmt.Errorf("invalid workspaces-file flag: %w", err)
+	}
+	if workspacesFile != "" {
+		return runStartWorkspaces(cmd, workspacesFile)
+	}
+	return startSession(cmd, nil)
+}
+
+// startSession mounts the volume, starts the workspace's container, and runs
+// a session in it: the interactive shell, or the headless prompt if run is set.
+// With --detach it returns once the container is up, and with --keep-alive it
+// leaves the container running after the shell exits.
+func startSession(cmd *cobra.Command, run *headlessRun) error {
+	ctx := cmd.Context()
 	volumePathFlag, err := cmd.Flags().GetString("volume")
 	if err != nil {
 		return fmt.Errorf("invalid volume flag: %w", err)
 	}
-	workspaceFlag, err := cmd.Flags().GetString("workspace")
+	workspaceFlags, err := cmd.Flags().GetStringArray("workspace")
 	if err != nil {
 		return fmt.Errorf("invalid workspace flag: %w", err)
 	}
-
-	// Get current directory once for reuse
-	cwd, err := os.Getwd()
+	untrusted, err := cmd.Flags().GetBool("untrusted")
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return fmt.Errorf("invalid untrusted flag: %w", err)
 	}
-
-	// Create managers
-	volumeManager, err := volume.New()
-	if err != nil {
-		return fmt.Errorf("failed to create volume manager: %w", err)
+	var features startFeatures
+	if features.gitIdentity, err = cmd.Flags().GetBool("git-identity"); err != nil {
+		return fmt.Errorf("invalid git-identity flag: %w", err)
 	}
-	dockerManager := docker.NewManager()
-	repoIdentifier := repo.NewIdentifier()
-
-	// Create path resolver
-	pathResolver, err := volume.NewPathResolver()
-	if err != nil {
-		return fmt.Errorf("failed to create path resolver: %w", err)
+	if features.sign, err = cmd.Flags().GetBool("sign"); err != nil {
+		return fmt.Errorf("invalid sign flag: %w", err)
 	}
-
-	// Find volume path using priority rules
-	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
-	if err != nil {
-		return err
+	if features.ramAuth, err = cmd.Flags().GetBool("ram-auth"); err != nil {
+		return fmt.Errorf("invalid ram-auth flag: %w", err)
 	}
-
-	// Determine workspace
-	workspacePath := workspaceFlag
-	if workspacePath == "" {
-		workspacePath, err = repoIdentifier.GetWorkspaceRoot(cwd)
-		if err != nil {
-			return fmt.Errorf("failed to determine workspace root: %w", err)
-		}
+	identityVolumeFlag, err := cmd.Flags().GetString("identity-volume")
+	if err != nil {
+		return fmt.Errorf("invalid identity-volume flag: %w", err)
 	}
-	workspacePath, err = filepath.Abs(workspacePath)
+	flavorFlag, err := cmd.Flags().GetString("flavor")
 	if err != nil {
-		return fmt.Errorf("failed to resolve workspace path: %w", err)
+		return fmt.Errorf("invalid flavor flag: %w", err)
 	}
-
-	// Get repo ID for symlink and container name
-	repoID, err := repoIdentifier.GetRepoID(workspacePath)
+	noHostProxy, err := cmd.Flags().GetBool("no-host-proxy")
 	if err != nil {
-		return fmt.Errorf("failed to identify repository: %w", err)
+		return fmt.Errorf("invalid no-host-proxy flag: %w", err)
 	}
-
-	// Get unique container name for this workspace
-	containerName, err := repoIdentifier.GetContainerName(workspacePath)
+	settings, err := config.LoadDefaultSettings()
 	if err != nil {
-		return fmt.Errorf("failed to generate container name: %w", err)
+		return err
 	}
-
-	// Check if Docker image exists, build if needed
-	if !embedded.ImageExists(docker.DefaultImageName) {
-		fmt.Printf("Docker image '%s' not found. Building...\n", docker.DefaultImageName)
-		if err := embedded.BuildImage(docker.DefaultImageName); err != nil {
-			return fmt.Errorf("failed to build Docker image: %w", err)
+	settings.NoHostProxy = settings.NoHostProxy || noHostProxy
+	// Recording and the clipboard are for the interactive shell; run always keeps a transcript
+	var shell shellOptions
+	if run == nil {
+		if shell, err = parseShellFlags(cmd, settings, &features); err != nil {
+			return err
 		}
-		fmt.Println("Docker image built successfully!")
 	}
-
-	// Verify Docker Desktop can access /tmp for encrypted volume mounts
-	fmt.Println("Checking Docker file sharing configuration...")
-	if err := dockerManager.CheckTmpFileSharing(); err != nil {
-		return fmt.Errorf("Docker file sharing check failed: %w", err)
+	if cmd.Flags().Changed("dns") {
+		if settings.DNS, err = cmd.Flags().GetStringArray("dns"); err != nil {
+			return fmt.Errorf("invalid dns flag: %w", err)
+		}
 	}
-
-	// Pre-start cleanup: remove any stale container from previous runs
-	// This prevents Docker mount conflicts even with stopped containers
-	fmt.Println("Checking for stale containers...")
-	if err := dockerManager.RemoveContainer(containerName); err == nil {
-		fmt.Println("Removed stale container.")
-		time.Sleep(docker.MountReleaseDelay)
+	if cmd.Flags().Changed("dns-search") {
+		if settings.DNSSearch, err = cmd.Flags().GetStringArray("dns-search"); err != nil {
+			return fmt.Errorf("invalid dns-search flag: %w", err)
+		}
 	}
-
-	// Check if volume is already mounted (reuse existing mount for fast re-entry)
-	var mountPoint string
-	var password *terminal.SecurePassword
-	if existingMount := volumeManager.GetMountPoint(volumePath); existingMount != "" {
-		fmt.Printf("Volume already mounted at %s\n", existingMount)
-		mountPoint = existingMount
-	} else {
-		// Prompt for password only when we need to mount
-		password, err = terminal.ReadPasswordSecure("Enter volume password: ")
+	if err := docker.ValidateDNS(settings.DNS, settings.DNSSearch); err != nil {
+		return err
+	}
+	restartPolicy, err := docker.ParseRestartPolicy(settings.Restart)
+	if err != nil {
+		return fmt.Errorf("invalid restart in %s: %w", config.SettingsFile, err)
+	}
+	if cmd.Flags().Changed("restart") {
+		restartFlag, err := cmd.Flags().GetString("restart")
 		if err != nil {
-			return fmt.Errorf("password error: %w", err)
+			return fmt.Errorf("invalid restart flag: %w", err)
 		}
-		defer password.Clear()
-
-		// Mount volume
-		fmt.Println("Mounting encrypted volume...")
-		mountPoint, err = volumeManager.Mount(volumePath, password)
+		if restartPolicy, err = docker.ParseRestartPolicy(restartFlag); err != nil {
+			return fmt.Errorf("invalid restart flag: %w", err)
+		}
+	}
+	features.dotfiles = make([]dotfiles.Entry, 0, len(settings.Dotfiles))
+	for _, spec := range settings.Dotfiles {
+		entry, err := dotfiles.Parse(spec)
 		if err != nil {
-			return fmt.Errorf("failed to mount volume: %w", err)
+			return err
 		}
-		fmt.Printf("Volume mounted at %s\n", mountPoint)
+		features.dotfiles = append(features.dotfiles, entry)
 	}
-
-	// Setup shutdown handler to lock volume on crash/termination
-	// This ensures the volume is secured if the process is killed unexpectedly
-	cancelShutdown := setupShutdownHandler(createShutdownCleanup(volumePath, containerName))
-	defer cancelShutdown()
-
+	buildOptions, err := imageBuildOptions(ctx, settings, flavorFlag)
+	if err != nil {
+		return err
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	secretFlags, err := cmd.Flags().GetStringArray("secret")
+	if err != nil {
+		return fmt.Errorf("invalid secret flag: %w", err)
+	}
+	secretRefs, err := secrets.ParseSpecs(secretFlags)
+	if err != nil {
+		return err
+	}
+	envFlags, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		return fmt.Errorf("invalid env flag: %w", err)
+	}
+	envFiles, err := cmd.Flags().GetStringArray("env-file")
+	if err != nil {
+		return fmt.Errorf("invalid env-file flag: %w", err)
+	}
+	var flagEnv []string
+	for _, entry := range envFlags {
+		assignment, ok, err := envfile.ParseAssignment(entry)
+		if err != nil {
+			return fmt.Errorf("invalid env flag: %w", err)
+		}
+		if ok {
+			flagEnv = append(flagEnv, assignment)
+		}
+	}
+	// Catch problems in host env files before prompting for a password
+	for _, path := range envFiles {
+		if !envfile.IsVolumePath(path) {
+			if _, err := envfile.ParseFile(path, ""); err != nil {
+				return err
+			}
+		}
+	}
+	vaultPaths, err := cmd.Flags().GetStringArray("vault-path")
+	if err != nil {
+		return fmt.Errorf("invalid vault-path flag: %w", err)
+	}
+	var secretProviders []secrets.Provider
+	if len(secretRefs) > 0 {
+		secretProviders = append(secretProviders, &secrets.OnePassword{Secrets: secretRefs})
+	}
+	for _, path := range vaultPaths {
+		vault, err := secrets.NewVault(path)
+		if err != nil {
+			return fmt.Errorf("invalid vault-path %s: %w", path, err)
+		}
+		vault.OnRenewError = func(err error) {
+			fmt.Fprintf(os.Stderr, "\nWarning: %v\n", err)
+		}
+		secretProviders = append(secretProviders, vault)
+	}
+
+	// Get current directory once for reuse
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Create managers
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	dockerManager := docker.NewManager()
+	repoIdentifier := newRepoIdentifier()
+
+	// Create path resolver
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	// Find volume path using priority rules
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+
+	// Credentials come from the identity volume, if there is one, which
+	// untrusted containers never see
+	var identityPath string
+	if !untrusted {
+		if identityPath, err = resolveIdentityVolume(pathResolver, identityVolumeFlag, settings); err != nil {
+			return err
+		}
+		if identityPath == volumePath {
+			return fmt.Errorf("%s is both the volume and the identity volume", volumePath)
+		}
+	}
+
+	// Determine workspaces and their repo IDs (for symlinks and container name)
+	workspaces, err := resolveWorkspaces(repoIdentifier, workspaceFlags, cwd)
+	if err != nil {
+		return err
+	}
+	workspacePath, repoID := workspaces[0].Path, workspaces[0].RepoID
+	multiWorkspace := len(workspaces) > 1
+
+	// Get unique container name for this workspace (or set of workspaces)
+	var containerName string
+	if multiWorkspace {
+		containerName = multiWorkspaceContainerName(workspaces)
+	} else {
+		containerName, err = repoIdentifier.GetContainerName(workspacePath)
+		if err != nil {
+			return fmt.Errorf("failed to generate container name: %w", err)
+		}
+	}
+
+	// Only trusted workspaces get the credential volume mounted
+	if untrusted {
+		infoln("Starting untrusted: credentials and Claude home will not be mounted.")
+		features.applyUntrustedRestrictions()
+	} else if !dryRun {
+		for _, w := range workspaces {
+			if err := confirmWorkspaceTrust(w.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Read the host identity before prompting for a password so a missing setting fails fast
+	var gitIdentity gitidentity.Identity
+	if features.gitIdentity {
+		if gitIdentity, err = gitidentity.HostIdentity(workspacePath); err != nil {
+			return fmt.Errorf("failed to read git identity: %w", err)
+		}
+	}
+	var signingKey string
+	if features.sign {
+		if signingKey, err = gitidentity.HostSigningKey(workspacePath); err != nil {
+			return fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+
+	if dryRun {
+		var secretSources []string
+		if len(secretRefs) > 0 {
+			secretSources = append(secretSources, fmt.Sprintf("1Password (%d secrets)", len(secretRefs)))
+		}
+		for _, path := range vaultPaths {
+			secretSources = append(secretSources, "Vault at "+path)
+		}
+		plan := startPlan{
+			command:        cmd.CommandPath(),
+			dockerManager:  dockerManager,
+			volumeManager:  volumeManager,
+			volumePath:     volumePath,
+			identityPath:   identityPath,
+			containerName:  containerName,
+			workspaces:     workspaces,
+			untrusted:      untrusted,
+			settings:       settings,
+			buildOptions:   buildOptions,
+			restart:        restartPolicy,
+			env:            flagEnv,
+			envFiles:       envFiles,
+			secretSources:  secretSources,
+			dotfiles:       len(features.dotfiles),
+			ramAuth:        features.ramAuth,
+			signCommits:    features.sign,
+			clipboardMode:  features.clipboard,
+			browserBridge:  features.browserBridge,
+			detach:         shell.detach,
+			keepAlive:      shell.keepAlive,
+			keepAliveGrace: shell.keepAliveGrace,
+			run:            run,
+		}
+		if features.gitIdentity {
+			plan.gitIdentity = &gitIdentity
+		}
+		return plan.print(ctx)
+	}
+
+	// Secrets are resolved on the host and only passed to the shell's environment.
+	// Closing the session stops lease renewal and revokes short-lived credentials.
+	secretSession, err := secrets.Open(cmd.Context(), secretProviders)
+	if err != nil {
+		return fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+	defer func() {
+		if err := secretSession.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to release secrets: %v\n", err)
+		}
+	}()
+	secretEnv := secretSession.Env()
+	if len(secretEnv) > 0 {
+		infof("Resolved secrets: %s\n", strings.Join(secrets.Names(secretEnv), ", "))
+	}
+
+	// Another shell into a running session needs none of the preflight below
+	if run == nil {
+		if mountPoint := runningSessionMount(ctx, dockerManager, volumeManager, containerName, volumePath, workspaces); mountPoint != "" {
+			warnIgnoredContainerFlags(cmd)
+			if shell.detach {
+				infof("Container %s is already running.\n", containerName)
+				return nil
+			}
+			infof("Container %s is already running; opening another shell. (type 'exit' to leave)\n", containerName)
+			var recordPath string
+			if shell.record {
+				recordPath = createShellTranscript(mountPoint, repoID)
+			}
+			return enterShell(ctx, dockerManager, containerName, secretEnv, recordPath)
+		}
+	}
+
+	// Hold the workspace and volume until the session is attached, so a second
+	// start can't mount or create the container at the same time
+	releaseLocks, err := lockOperation(cmd.CommandPath(), containerName, volumePath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+
+	// Clear out mount points and containers a crashed run left behind so they
+	// aren't mistaken for mounted volumes or collide with this session's
+	// mounts. Only this volume's: other starts may be mounting theirs.
+	volumeManager.RemoveStaleMountPoints(ctx, volumePath)
+	removeOrphanedContainers(ctx, dockerManager, volumeManager, volumePath)
+
+	// Undo whatever a crashed or killed start of this container left behind,
+	// then track this start's progress so the next one can do the same for it
+	statePath, err := startstate.Path(containerName)
+	if err != nil {
+		return err
+	}
+	if err := recoverInterruptedStart(ctx, statePath, dockerManager, volumeManager); err != nil {
+		return err
+	}
+	tracker, err := startstate.Begin(statePath, containerName, volumePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracker.Close(); err != nil {
+			slog.Warn("failed to clear start state", "error", err)
+		}
+	}()
+	advance := func(phase startstate.Phase, update func(*startstate.State)) {
+		if err := tracker.Advance(phase, update); err != nil {
+			slog.Warn("failed to record start phase", "phase", phase, "error", err)
+		}
+	}
+	// Until the session is attached, a failure or interrupt rolls back what
+	// the start did. Steps lockOnInterrupt already undid are skipped.
+	attached := false
+	defer func() {
+		if !attached {
+			rollbackStart(context.WithoutCancel(ctx), tracker.State(), dockerManager, volumeManager)
+		}
+	}()
+
+	infoln("Checking file sharing and stale containers...")
+	existingMount, err := checkStartHost(ctx, dockerManager, volumeManager, containerName, volumePath, workspacePath, multiWorkspace)
+	if err != nil {
+		return err
+	}
+
+	// Check if volume is already mounted (reuse existing mount for fast re-entry)
+	var mountPoint string
+	var password *terminal.SecurePassword
+	if existingMount != "" {
+		infof("Volume already mounted at %s\n", existingMount)
+		mountPoint = existingMount
+	} else {
+		// Prompt for password only when we need to mount
+		password, err = readVolumePassword(passwordFile, "Enter volume password: ")
+		if err != nil {
+			return fmt.Errorf("password error: %w", err)
+		}
+		defer password.Clear()
+
+		// Mount volume
+		infoln("Mounting encrypted volume...")
+		mountStarted := time.Now()
+		mountPoint, err = volumeManager.Mount(ctx, volumePath, password)
+		if err != nil {
+			reportDaemonEvent(daemon.Event{Type: daemon.EventUnlockFailed})
+			return fmt.Errorf("failed to mount volume: %w", err)
+		}
+		reportDaemonEvent(daemon.Event{Type: daemon.EventMount, Seconds: time.Since(mountStarted).Seconds()})
+		infof("Volume mounted at %s\n", mountPoint)
+	}
+	advance(startstate.PhaseMounted, func(s *startstate.State) {
+		s.MountPoint = mountPoint
+		s.MountedVolume = existingMount == ""
+	})
+
+	if err := prepareHome(mountPoint, features, gitIdentity); err != nil {
+		return err
+	}
+
+	// The identity volume stays mounted if the start fails, like a volume
+	// that was already mounted; 'capsule lock --identity' unmounts it
+	var identityMount string
+	if identityPath != "" {
+		if identityMount, err = mountIdentityVolume(ctx, volumeManager, identityPath); err != nil {
+			return err
+		}
+	}
+
+	// Lock the volume if a signal interrupts anything from here on. The signal
+	// cancels ctx, which stops the docker command in progress first.
+	defer lockOnInterrupt(ctx, volumePath, containerName)
+
+	// The volume records its bootstrap template, which with the flavor decides the image
+	if buildOptions.Template, err = embedded.ReadTemplateFile(mountPoint); err != nil {
+		return err
+	}
+	imageName := docker.ImageName(buildOptions.Flavor, buildOptions.Template)
+	if err := ensureImage(ctx, imageName, buildOptions); err != nil {
+		return err
+	}
+
+	// The RAM disk replaces auth/ in the container. Unmounting the volume
+	// with auth/, the identity volume if there is one, destroys it, so it is
+	// staged again after every remount.
+	var authDir string
+	stageAuth := func() error {
+		if !features.ramAuth {
+			return nil
+		}
+		authSource := mountPoint
+		if identityMount != "" {
+			authSource = identityMount
+		}
+		authDir, err = volumeManager.StageAuth(ctx, authSource)
+		if err != nil {
+			return fmt.Errorf("failed to stage auth on RAM disk: %w", err)
+		}
+		infof("Auth staged on RAM disk at %s\n", authDir)
+		return nil
+	}
+	if err := stageAuth(); err != nil {
+		return err
+	}
+
 	// Clear VM cache and refresh Docker's VirtioFS view of the mount point
 	// This is necessary because Docker Desktop caches mount information,
 	// and freshly mounted volumes may not be visible without cache clearing
-	fmt.Println("Preparing Docker mount...")
-	if err := dockerManager.ClearVMCache(); err != nil {
+	infoln("Preparing Docker mount...")
+	if err := dockerManager.ClearVMCache(ctx); err != nil {
 		// Non-fatal: log warning but continue
 		fmt.Fprintf(os.Stderr, "Warning: failed to clear VM cache: %v\n", err)
 	}
-	if err := dockerManager.RefreshMountCache(mountPoint); err != nil {
-		// Non-fatal: if refresh fails, the actual mount will report a clearer error
-		fmt.Fprintf(os.Stderr, "Warning: cache refresh failed (will retry on mount): %v\n", err)
+	for _, path := range []string{mountPoint, identityMount} {
+		if path == "" {
+			continue
+		}
+		if err := dockerManager.RefreshMountCache(ctx, path); err != nil {
+			// Non-fatal: if refresh fails, the actual mount will report a clearer error
+			fmt.Fprintf(os.Stderr, "Warning: cache refresh failed (will retry on mount): %v\n", err)
+		}
 	}
+	advance(startstate.PhaseCacheRefreshed, nil)
 
 	// Start container with retry on Docker mount cache errors
-	fmt.Println("Starting container...")
+	infoln("Starting container...")
 	containerConfig := docker.ContainerConfig{
-		ImageName:        docker.DefaultImageName,
-		ContainerName:    containerName,
-		VolumeMountPoint: mountPoint,
-		WorkspacePath:    workspacePath,
+		ImageName:          imageName,
+		ContainerName:      containerName,
+		VolumeMountPoint:   mountPoint,
+		WorkspacePath:      workspacePath,
+		Untrusted:          untrusted,
+		RepoID:             repoID,
+		AuthDir:            authDir,
+		IdentityMountPoint: identityMount,
+		DNS:                settings.DNS,
+		DNSSearch:          settings.DNSSearch,
+		Restart:            restartPolicy,
+		VolumePath:         volumePath,
+		CapsuleVersion:     version,
+	}
+	if multiWorkspace {
+		containerConfig.Workspaces = workspaces
+	}
+	if len(buildOptions.CACerts) > 0 {
+		if containerConfig.CABundle, err = writeCABundle(containerName, buildOptions.CACerts); err != nil {
+			return err
+		}
 	}
 
-	startErr := dockerManager.Start(containerConfig)
-	if startErr != nil && strings.Contains(startErr.Error(), "file exists") {
-		// Docker Desktop has stale mount cache - clean up and retry
-		fmt.Println("Docker mount cache conflict detected, cleaning up...")
+	if containerConfig.Env, err = containerEnv(ctx, settings, buildOptions, envFiles, flagEnv, mountPoint); err != nil {
+		return err
+	}
+
+	closeBridges, err := setupBridges(containerName, workspacePath, signingKey, features, &containerConfig)
+	if err != nil {
+		return err
+	}
+	defer closeBridges()
+
+	containerStarted := time.Now()
+	// Docker Desktop can hold a stale mount cache entry for a remounted volume.
+	// Between attempts, release the container and volume so the cache can
+	// refresh, then remount before trying again. A volume held by 'capsule
+	// start --workspaces-file' stays mounted: the other workspaces it starts
+	// are using it, and this start has no password to remount it with.
+	var remountErr error
+	hadPassword := password != nil
+	volumeHeld := existingMount != "" && os.Getenv(heldVolumeEnv) == volumePath
+	startPolicy := docker.MountCacheRetry
+	startPolicy.OnRetry = func(err error, attempt int) {
+		if docker.IsMountCacheError(err) {
+			infoln("Docker mount cache conflict detected, cleaning up...")
+		} else {
+			infoln("Docker is restarting, cleaning up...")
+		}
 
 		// Remove any partial container (errors ignored - container may not exist)
-		if err := dockerManager.RemoveContainer(containerName); err != nil {
+		if err := dockerManager.RemoveContainer(ctx, containerName); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: container removal failed: %v\n", err)
 		}
 
 		// Unmount and remove mount directory (Unmount now handles directory cleanup)
-		if err := volumeManager.Unmount(mountPoint); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: volume unmount failed: %v\n", err)
-		}
-
-		// Wait for Docker Desktop to clear its cache
-		fmt.Println("Waiting for Docker to refresh...")
-		time.Sleep(docker.CacheRefreshDelay)
-
-		// If we didn't have a password (volume was pre-mounted), prompt now
-		if password == nil {
-			password, err = terminal.ReadPasswordSecure("Enter volume password to remount: ")
-			if err != nil {
-				return fmt.Errorf("password error: %w", err)
+		if !volumeHeld {
+			if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: volume unmount failed: %v\n", err)
+			}
+			if err := tracker.Retreat(startstate.PhaseStarting); err != nil {
+				slog.Warn("failed to record start phase", "phase", startstate.PhaseStarting, "error", err)
 			}
-			defer password.Clear()
 		}
+		infof("Waiting for Docker to refresh (attempt %d of %d)...\n", attempt+1, startPolicy.Attempts)
+	}
+	startErr := startPolicy.Do(ctx, "start container", func(attempt int) error {
+		if attempt > 0 && volumeHeld {
+			infoln("Retrying container start...")
+		} else if attempt > 0 {
+			// If we didn't have a password (volume was pre-mounted), prompt now
+			if password == nil {
+				password, remountErr = readVolumePassword(passwordFile, "Enter volume password to remount: ")
+				if remountErr != nil {
+					remountErr = fmt.Errorf("password error: %w", remountErr)
+					return remountErr
+				}
+			}
 
-		// Remount
-		fmt.Println("Remounting volume...")
-		mountPoint, err = volumeManager.Mount(volumePath, password)
-		if err != nil {
-			return fmt.Errorf("failed to remount volume after cleanup: %w", err)
+			infoln("Remounting volume...")
+			mountPoint, remountErr = volumeManager.Mount(ctx, volumePath, password)
+			if remountErr != nil {
+				remountErr = fmt.Errorf("failed to remount volume after cleanup: %w", remountErr)
+				return remountErr
+			}
+			containerConfig.VolumeMountPoint = mountPoint
+			advance(startstate.PhaseMounted, func(s *startstate.State) {
+				s.MountPoint = mountPoint
+				s.MountedVolume = true
+			})
+			advance(startstate.PhaseCacheRefreshed, nil)
+			if remountErr = stageAuth(); remountErr != nil {
+				return remountErr
+			}
+			containerConfig.AuthDir = authDir
+			infoln("Retrying container start...")
 		}
-
-		// Update config with new mount point
-		containerConfig.VolumeMountPoint = mountPoint
-
-		// Retry start
-		fmt.Println("Retrying container start...")
-		startErr = dockerManager.Start(containerConfig)
+		return dockerManager.Start(ctx, containerConfig)
+	})
+	if !hadPassword && password != nil {
+		defer password.Clear()
+	}
+	if remountErr != nil {
+		return remountErr
 	}
 
 	if startErr != nil {
-		// Clean up any partially created container before returning error
-		fmt.Println("Cleaning up failed container...")
-		if err := dockerManager.RemoveContainer(containerName); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: container removal failed: %v\n", err)
+		return fmt.Errorf("failed to start container: %w", startErr)
+	}
+	reportDaemonEvent(daemon.Event{Type: daemon.EventContainerStart, Seconds: time.Since(containerStarted).Seconds()})
+	advance(startstate.PhaseContainerCreated, nil)
+	recordWorkspaceMount(ctx, volumeManager, volumePath, mountPoint, containerName, workspaces)
+	infoln("Container started!")
+
+	// Setup symlinks inside container. Each call points the shell's BEADS_DIR at
+	// its repository, so set up the first workspace last to make it the default.
+	var symlinks []docker.SymlinkTarget
+	for i := len(workspaces) - 1; i >= 0; i-- {
+		workspaceDir := docker.ContainerWorkspaceDir
+		if multiWorkspace {
+			workspaceDir = workspaces[i].ContainerPath()
 		}
-
-		if unmountErr := volumeManager.Unmount(mountPoint); unmountErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: volume unmount failed: %v\n", unmountErr)
+		symlinks = append(symlinks, docker.SymlinkTarget{RepoID: workspaces[i].RepoID, WorkspaceDir: workspaceDir})
+	}
+	setupSymlinks := func(ctx context.Context) error {
+		for _, s := range symlinks {
+			if err := dockerManager.SetupWorkspaceSymlink(ctx, containerName, s.RepoID, s.WorkspaceDir); err != nil {
+				return fmt.Errorf("failed to setup workspace symlink: %w", err)
+			}
 		}
-		return fmt.Errorf("failed to start container: %w", startErr)
+		return nil
 	}
-	fmt.Println("Container started!")
-
-	// Setup symlink inside container
-	fmt.Println("Setting up shadow documentation...")
-	if err := dockerManager.SetupWorkspaceSymlink(containerName, repoID); err != nil {
-		// Clean up on failure
-		if stopErr := dockerManager.Stop(containerName); stopErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: cleanup failed to stop container: %v\n", stopErr)
+	infoln("Setting up shadow documentation...")
+	if err := setupSymlinks(ctx); err != nil {
+		return err
+	}
+	advance(startstate.PhaseSymlinked, nil)
+	if multiWorkspace {
+		for _, w := range workspaces {
+			infof("  %s -> %s\n", w.ContainerPath(), w.Path)
 		}
-		if unmountErr := volumeManager.Unmount(mountPoint); unmountErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: cleanup failed to unmount volume: %v\n", unmountErr)
+	}
+	if shell.detach {
+		// Nothing is left to roll back; the container runs until 'capsule stop' or 'capsule lock'
+		advance(startstate.PhaseAttached, nil)
+		attached = true
+		infof("Container %s is running. Open a shell with 'capsule enter'; stop it with 'capsule stop'.\n", containerName)
+		return nil
+	}
+	var recordPath string
+	if run == nil && shell.record {
+		recordPath = createShellTranscript(mountPoint, workspaces[0].RepoID)
+	}
+	if run == nil {
+		infoln("")
+		infoln("Entering container... (type 'exit' to leave)")
+		infoln("")
+	}
+
+	// Exec into container and wait for the shell or prompt to finish
+	advance(startstate.PhaseAttached, nil)
+	attached = true
+	releaseLocks()
+	endSession := recordSessionStart(workspaces, containerName, untrusted)
+	stopSpaceWatch := watchVolumeSpace(ctx, volumeManager, volumePath, mountPoint)
+	stopHealthWatch := watchContainerHealth(ctx, dockerManager, containerName, symlinks)
+	var execErr error
+	var lost bool // Set when the container went away and could not be brought back
+	if run != nil {
+		workspaceDir := docker.ContainerWorkspaceDir
+		if multiWorkspace {
+			workspaceDir = workspaces[0].ContainerPath()
 		}
-		return fmt.Errorf("failed to setup workspace symlink: %w", err)
+		execErr = run.exec(ctx, dockerManager, containerName, workspaceDir, secretEnv, mountPoint, workspaces[0].RepoID)
+	} else {
+		execErr, lost = attachShell(ctx, dockerManager, containerConfig, secretEnv, recordPath, mountPoint, workspaces[0].RepoID, setupSymlinks)
+	}
+	stopHealthWatch()
+	stopSpaceWatch()
+	endSession(execErr)
+	if ctx.Err() != nil {
+		// A signal ended the session; lockOnInterrupt stops the container
+		return ctx.Err()
+	}
+	if lost {
+		fmt.Fprintln(os.Stderr, "The container could not be restarted. Run 'capsule start' again once Docker is running.")
+		return execErr
 	}
-	fmt.Println("")
-	fmt.Println("Entering container... (type 'exit' to leave)")
-	fmt.Println("")
-
-	// Exec into container and wait for user to exit
-	execErr := dockerManager.Exec(containerName)
 
 	// Clean up after user exits the shell
-	fmt.Println("")
-	fmt.Println("Cleaning up...")
+	infoln("")
+	infoln("Cleaning up...")
 
 	// Stop container (keep volume mounted for fast re-entry)
-	if err := dockerManager.Stop(containerName); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to stop container: %v\n", err)
-	} else {
-		fmt.Println("Container stopped.")
+	stopSessionContainer(ctx, dockerManager, containerName, workspaces[0].Path, run != nil && run.keepRunning, shell)
+
+	for _, w := range workspaces {
+		syncDocsOnStop(w.Path, w.RepoID, mountPoint)
 	}
 
-	fmt.Println("Volume remains unlocked for quick re-entry.")
-	fmt.Println("Run 'capsule lock' when done to secure your credentials.")
+	if run != nil {
+		return run.finish(ctx, volumeManager, mountPoint, execErr)
+	}
 
-	// Ignore common exit codes (0 = normal, 130 = Ctrl+C)
-	if execErr != nil {
-		if exitErr, ok := execErr.(*exec.ExitError); ok {
-			code := exitErr.ExitCode()
-			if code == 0 || code == 130 {
-				return nil
+	infoln("Volume remains unlocked for quick re-entry.")
+	infoln("Run 'capsule lock' when done to secure your credentials.")
+
+	return shellExitError(execErr)
+}
+
+// checkStartHost checks that Docker can share the mount directory and that
+// containerName is free for the workspace, removing a stale container by
+// that name, and returns where the volume is mounted already, if it is. The
+// checks are independent docker and hdiutil calls, so they run together and
+// stop at the first failure. The image depends on the volume's template, so
+// it is checked once the volume is mounted.
+func checkStartHost(ctx context.Context, dockerManager docker.DockerManager, volumeManager volume.VolumeManager, containerName, volumePath, workspacePath string, multiWorkspace bool) (string, error) {
+	var existingMount string
+	err := runConcurrently(
+		func() error {
+			// Verify Docker Desktop can access the directory encrypted volumes mount under
+			mountDir, err := config.MountDir()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(mountDir, constants.DirPermissions); err != nil {
+				return fmt.Errorf("failed to create mount directory %s: %w", mountDir, err)
+			}
+			if err := dockerManager.CheckFileSharing(ctx, mountDir); err != nil {
+				return fmt.Errorf("Docker file sharing check failed: %w", err)
+			}
+			return nil
+		},
+		func() error {
+			// Under the shared worktree policy, worktrees of one repository share a container name.
+			// Refuse to replace a container that is serving a different worktree.
+			if !multiWorkspace && dockerManager.IsRunning(ctx, containerName) {
+				if mounted, err := dockerManager.WorkspaceMount(ctx, containerName); err == nil && mounted != "" && mounted != workspacePath {
+					return exitCodeError{code: exitMountConflict, err: fmt.Errorf("container %s is running for %s\nStop it first, or use --worktree-policy %s to give each worktree its own container and _docs",
+						containerName, mounted, repo.WorktreePerWorktree)}
+				}
+			}
+
+			// Pre-start cleanup: remove any stale container from previous runs
+			// This prevents Docker mount conflicts even with stopped containers
+			if err := dockerManager.RemoveContainer(ctx, containerName); err == nil {
+				infoln("Removed stale container.")
+				time.Sleep(docker.MountReleaseDelay)
 			}
+			return nil
+		},
+		func() error {
+			existingMount = volumeManager.GetMountPoint(ctx, volumePath)
+			return nil
+		},
+	)
+	return existingMount, err
+}
+
+// containerEnv returns the container's environment: the host's proxy
+// settings, then the env files in order, then --env, so later values win.
+// Env files in the volume are read from mountPoint.
+func containerEnv(ctx context.Context, settings *config.Settings, buildOptions embedded.BuildOptions, envFiles, flagEnv []string, mountPoint string) ([]string, error) {
+	var env []string
+	if !settings.NoHostProxy {
+		env = append(env, docker.HostProxyEnv(ctx)...)
+	}
+	if buildOptions.ClaudeCodeVersion != "" {
+		// A pinned release only changes when the image is rebuilt
+		env = append(env, "DISABLE_AUTOUPDATER=1")
+	}
+	for _, path := range envFiles {
+		fileEnv, err := envfile.ParseFile(path, mountPoint)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("shell exited with error: %w", execErr)
+		env = append(env, fileEnv...)
 	}
+	return append(env, flagEnv...), nil
+}
 
+// stopSessionContainer stops the container once its session has ended,
+// unless keepRunning or the keep-alive setting leaves it running.
+func stopSessionContainer(ctx context.Context, dockerManager docker.DockerManager, containerName, workspacePath string, keepRunning bool, shell shellOptions) {
+	switch {
+	case keepRunning:
+		infof("Container %s left running.\n", containerName)
+	case shell.keepAlive && shell.keepAliveGrace == 0:
+		infof("Container %s left running. Open a shell with 'capsule enter'; stop it with 'capsule stop'.\n", containerName)
+	case shell.keepAlive:
+		if err := scheduleIdleStop(containerName, workspacePath, shell.keepAliveGrace); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; stop it with 'capsule stop'\n", err)
+		} else {
+			infof("Container %s left running; it stops once no shell has been open for %s. Open a shell with 'capsule enter'.\n", containerName, shell.keepAliveGrace)
+		}
+	default:
+		if err := dockerManager.Stop(ctx, containerName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stop container: %v\n", err)
+		} else {
+			infoln("Container stopped.")
+		}
+	}
+}
+
+// createShellTranscript creates the file a recorded shell session is written
+// to, returning its path. A failure is reported and the session goes unrecorded.
+func createShellTranscript(mountPoint, repoID string) string {
+	f, err := transcript.Create(mountPoint, repoID, transcript.KindShell, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: session will not be recorded: %v\n", err)
+		return ""
+	}
+	f.Close()
+	infof("Recording session to %s in the volume.\n", filepath.Join(transcript.Dir("", repoID), filepath.Base(f.Name())))
+	return f.Name()
+}
+
+// syncDotfiles copies the configured host dotfiles into the volume's home,
+// reporting what changed and which were not found on the host.
+func syncDotfiles(mountPoint string, entries []dotfiles.Entry) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	result, err := dotfiles.Sync(homeDir, mountPoint, entries)
+	if err != nil {
+		return fmt.Errorf("failed to copy dotfiles: %w", err)
+	}
+	for _, name := range result.Missing {
+		fmt.Fprintf(os.Stderr, "Warning: dotfile ~/%s not found, skipping\n", name)
+	}
+	if len(result.Updated) > 0 {
+		infof("Dotfiles updated: %s\n", strings.Join(result.Updated, ", "))
+	}
 	return nil
 }
 
 func newStopCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop container (keeps volume mounted)",
-		RunE:  runStop,
+		Long: `Stops the container for the current workspace. The volume stays mounted.
+If the repository has opted in with 'git config capsule.docsSync true', shadow
+docs are mirrored to a git branch first (see 'capsule docs sync --help').
+
+With --scan, the workspace is checked for leaked credentials first (see 'capsule scan --help').
+
+With --idle, stop waits until no shell has been open in the container for that
+long before stopping it, and gives up if the container stops or is restarted
+in the meantime. This is how 'capsule start --keep-alive=DURATION' stops a
+container it left running. --container names the container to stop instead
+of the workspace's; the workspace's docs are then not synced.
+
+With --all, every running container capsule created is stopped, found by the
+label capsule sets when it creates them; containers from before capsule
+labeled them are left running (use 'capsule lock --all' for those). Volumes
+stay mounted and docs are not synced.`,
+		RunE: runStop,
 	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Duration("idle", 0, "Wait until no shell has been open in the container this long, then stop it")
+	cmd.Flags().String("container", "", "Container to stop (defaults to the current workspace's)")
+	cmd.Flags().Bool("all", false, "Stop every running capsule container, leaving volumes mounted")
+	addScanFlag(cmd)
+
+	return cmd
 }
 
 func newUnlockCmd() *cobra.Command {
@@ -577,23 +1684,29 @@ func newUnlockCmd() *cobra.Command {
 This allows injecting files or accessing the volume from external processes.
 
 Output is in KEY=VALUE format for easy parsing:
-  MOUNT_POINT=/tmp/capsule-abc123
+  MOUNT_POINT=/Users/you/.capsule/mounts/Capsule-abc123
   STATUS=mounted
 
+With --output json, it is a versioned JSON envelope instead (see 'capsule schema').
+
 Password can be provided via:
   - Interactive prompt (default)
   - --password-stdin flag: echo $PASS | capsule unlock --password-stdin
+  - --password-file flag: a file readable only by you (mode 0600)
   - CAPSULE_PASSWORD environment variable`,
 		RunE: runUnlock,
 	}
 
-	cmd.Flags().String("volume", "", "Path to encrypted volume (auto-detected if not specified)")
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
 	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	cmd.Flags().String("password-file", "", "Read password from a file only you can read (mode 0600)")
+	addOutputFlag(cmd)
 
 	return cmd
 }
 
 func runUnlock(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	volumePathFlag, err := cmd.Flags().GetString("volume")
 	if err != nil {
 		return fmt.Errorf("invalid volume flag: %w", err)
@@ -602,6 +1715,17 @@ func runUnlock(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("invalid password-stdin flag: %w", err)
 	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	if passwordStdin && passwordFile != "" {
+		return fmt.Errorf("--password-stdin and --password-file cannot be used together")
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Get current directory
 	cwd, err := os.Getwd()
@@ -627,35 +1751,59 @@ func runUnlock(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	releaseLocks, err := lockOperation(cmd.CommandPath(), "", volumePath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+
 	// Check if already mounted
-	if existingMount := volumeManager.GetMountPoint(volumePath); existingMount != "" {
-		// Output parsable values
-		fmt.Printf("MOUNT_POINT=%s\n", existingMount)
-		fmt.Printf("STATUS=already_mounted\n")
-		fmt.Printf("VOLUME_PATH=%s\n", volumePath)
-		return nil
+	if existingMount := volumeManager.GetMountPoint(ctx, volumePath); existingMount != "" {
+		return printUnlockResult(format, output.UnlockResult{
+			Status:     output.StatusAlreadyMounted,
+			MountPoint: existingMount,
+			VolumePath: volumePath,
+		})
 	}
 
 	// Get password from multiple sources
-	password, err := terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter volume password: ")
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = terminal.ReadPasswordFromFileSecure(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter volume password: ")
+	}
 	if err != nil {
 		return fmt.Errorf("password error: %w", err)
 	}
 	defer password.Clear()
 
 	// Mount volume
-	fmt.Fprintf(os.Stderr, "Mounting encrypted volume...\n")
-	mountPoint, err := volumeManager.Mount(volumePath, password)
+	infoErrf("Mounting encrypted volume...\n")
+	mountStarted := time.Now()
+	mountPoint, err := volumeManager.Mount(ctx, volumePath, password)
 	if err != nil {
+		reportDaemonEvent(daemon.Event{Type: daemon.EventUnlockFailed})
 		return fmt.Errorf("failed to mount volume: %w", err)
 	}
+	reportDaemonEvent(daemon.Event{Type: daemon.EventMount, Seconds: time.Since(mountStarted).Seconds()})
 
-	// Output parsable values to stdout
-	fmt.Printf("MOUNT_POINT=%s\n", mountPoint)
-	fmt.Printf("STATUS=mounted\n")
-	fmt.Printf("VOLUME_PATH=%s\n", volumePath)
+	infoErrf("Volume unlocked. Run 'capsule lock' to secure.\n")
+	return printUnlockResult(format, output.UnlockResult{
+		Status:     output.StatusMounted,
+		MountPoint: mountPoint,
+		VolumePath: volumePath,
+	})
+}
 
-	fmt.Fprintf(os.Stderr, "Volume unlocked. Run 'capsule lock' to secure.\n")
+// printUnlockResult prints what unlock did as KEY=VALUE lines or JSON.
+func printUnlockResult(format output.Format, result output.UnlockResult) error {
+	if format == output.FormatJSON {
+		return output.Write(os.Stdout, "unlock", result)
+	}
+	fmt.Printf("MOUNT_POINT=%s\n", result.MountPoint)
+	fmt.Printf("STATUS=%s\n", result.Status)
+	fmt.Printf("VOLUME_PATH=%s\n", result.VolumePath)
 	return nil
 }
 
@@ -666,21 +1814,82 @@ func newLockCmd() *cobra.Command {
 		Long: `Unmounts the encrypted volume, securing all credentials and data.
 Use this when you're done working for the day.
 
+With --all, every running capsule container is stopped and every mounted
+capsule volume is unmounted, regardless of the current directory.
+
+With --identity, only the identity volume is unmounted, after stopping the
+containers that mount it; the data volume stays mounted for other tools.
+
+With --scan, the workspace is checked for leaked credentials first (see 'capsule scan --help').
+
+With --dry-run, the containers it would stop and volumes it would unmount are
+listed and nothing is changed.
+
 Output is in KEY=VALUE format for easy parsing:
-  STATUS=locked`,
+  STATUS=locked
+
+With --output json, it is a versioned JSON envelope instead (see 'capsule schema').`,
 		RunE: runLock,
 	}
 
-	cmd.Flags().String("volume", "", "Path to encrypted volume (auto-detected if not specified)")
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("all", false, "Stop all capsule containers and lock all mounted capsule volumes")
+	cmd.Flags().Bool("identity", false, "Lock only the identity volume, stopping the containers that use it")
+	addScanFlag(cmd)
+	addOutputFlag(cmd)
+	addDryRunFlag(cmd)
 
 	return cmd
 }
 
 func runLock(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	volumePathFlag, err := cmd.Flags().GetString("volume")
 	if err != nil {
 		return fmt.Errorf("invalid volume flag: %w", err)
 	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	identity, err := cmd.Flags().GetBool("identity")
+	if err != nil {
+		return fmt.Errorf("invalid identity flag: %w", err)
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	if dryRun && format == output.FormatJSON {
+		return fmt.Errorf("--dry-run and --output json cannot be used together")
+	}
+	if all {
+		if volumePathFlag != "" {
+			return fmt.Errorf("--all and --volume cannot be used together")
+		}
+		if identity {
+			return fmt.Errorf("--all and --identity cannot be used together")
+		}
+		// --all backs auto-lock, which must never be held up by a prompt or a block
+		if cmd.Flags().Changed("scan") {
+			return fmt.Errorf("--all and --scan cannot be used together")
+		}
+		if dryRun {
+			return printLockAllPlan(ctx, cmd.CommandPath())
+		}
+		_, err := runLockAll(ctx, format)
+		return err
+	}
+	if identity {
+		if volumePathFlag != "" {
+			return fmt.Errorf("--identity and --volume cannot be used together")
+		}
+		return runLockIdentity(ctx, cmd.CommandPath(), format, dryRun)
+	}
 
 	// Get container name and cwd for current directory
 	containerName, cwd, err := getContainerNameForCwd()
@@ -700,60 +1909,293 @@ func runLock(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create path resolver: %w", err)
 	}
 
-	// Find volume path using priority rules (allow non-existent for status reporting)
-	volumePath, _ := pathResolver.ResolveVolumePath(volumePathFlag, cwd)
+	// Find the workspace's volume, which may not be the one the path rules
+	// pick (allow non-existent for status reporting)
+	volumePath, _ := resolveWorkspaceVolumePath(ctx, pathResolver, volumeManager, volumePathFlag, cwd, containerName)
 
 	// Get the mount point for this specific volume (not any volume)
-	mountPoint := volumeManager.GetMountPoint(volumePath)
+	mountPoint := volumeManager.GetMountPoint(ctx, volumePath)
 	if mountPoint == "" {
-		fmt.Printf("STATUS=not_mounted\n")
-		fmt.Printf("VOLUME_PATH=%s\n", volumePath)
-		fmt.Fprintf(os.Stderr, "Volume is not mounted. Nothing to lock.\n")
-		return nil
+		infoErrf("Volume is not mounted. Nothing to lock.\n")
+		return printLockResult(format, output.LockResult{Status: output.StatusNotMounted, VolumePaths: []string{volumePath}})
 	}
 
-	// Stop any running container first
+	// Other workspaces' containers started from the same volume would be left
+	// with their mounts pointing at nothing
 	dockerManager := docker.NewManager()
-	if dockerManager.IsRunning(containerName) {
-		fmt.Fprintf(os.Stderr, "Stopping running container %s...\n", containerName)
-		if err := dockerManager.Stop(containerName); err != nil {
+	var containers []string
+	candidates := []string{containerName}
+	if m, ok := volumeMount(ctx, volumeManager, volumePath); ok {
+		candidates = append(candidates, m.Containers()...)
+	}
+	for _, name := range candidates {
+		if !slices.Contains(containers, name) && dockerManager.IsRunning(ctx, name) {
+			containers = append(containers, name)
+		}
+	}
+	if dryRun {
+		planLock(containers, []string{mountPoint}).print(cmd.CommandPath())
+		return nil
+	}
+
+	if err := scanWorkspaceBeforeLock(cmd, cwd, containerName); err != nil {
+		return err
+	}
+
+	releaseLocks, err := lockOperation(cmd.CommandPath(), containerName, volumePath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+
+	// Stop the containers using the volume first
+	var stopped int
+	for _, name := range containers {
+		infoErrf("Stopping running container %s...\n", name)
+		if err := dockerManager.Stop(ctx, name); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to stop container: %v\n", err)
+		} else {
+			stopped++
 		}
 	}
 
 	// Unmount the specific volume
-	fmt.Fprintf(os.Stderr, "Unmounting encrypted volume at %s...\n", mountPoint)
-	if err := volumeManager.Unmount(mountPoint); err != nil {
+	infoErrf("Unmounting encrypted volume at %s...\n", mountPoint)
+	if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
 		return fmt.Errorf("failed to unmount volume: %w", err)
 	}
+	forgetVolumeMounts(volumePath)
 
-	// Output parsable values to stdout
-	fmt.Printf("STATUS=locked\n")
-	fmt.Printf("VOLUME_PATH=%s\n", volumePath)
+	infoErrf("Volume locked. Your credentials are now secured.\n")
+	return printLockResult(format, output.LockResult{
+		Status:            output.StatusLocked,
+		VolumePaths:       []string{volumePath},
+		ContainersStopped: stopped,
+		VolumesLocked:     1,
+	})
+}
 
-	fmt.Fprintf(os.Stderr, "Volume locked. Your credentials are now secured.\n")
+// printLockResult prints what lock did as KEY=VALUE lines or JSON.
+func printLockResult(format output.Format, result output.LockResult) error {
+	if format == output.FormatJSON {
+		return output.Write(os.Stdout, "lock", result)
+	}
+	for _, volumePath := range result.VolumePaths {
+		fmt.Printf("VOLUME_PATH=%s\n", volumePath)
+	}
+	fmt.Printf("STATUS=%s\n", result.Status)
 	return nil
 }
 
+// runLockAll stops every capsule container, then unmounts every capsule volume,
+// printing the result in format. Containers go first because they hold the
+// volume mounts open.
+func runLockAll(ctx context.Context, format output.Format) (lockResult, error) {
+	volumeManager, err := volume.New()
+	if err != nil {
+		return lockResult{}, fmt.Errorf("failed to create volume manager: %w", err)
+	}
+
+	result, err := lockEverything(ctx, volumeManager, docker.NewManager())
+	if err != nil {
+		return result, err
+	}
+	printed := output.LockResult{
+		Status:            output.StatusLocked,
+		VolumePaths:       result.imagePaths,
+		ContainersStopped: result.stopped,
+		VolumesLocked:     result.locked,
+	}
+	if result.failures > 0 {
+		printed.Status = output.StatusPartial
+	} else if result.locked == 0 {
+		printed.Status = output.StatusNotMounted
+	}
+	if printed.VolumePaths == nil {
+		printed.VolumePaths = []string{}
+	}
+	if err := printLockResult(format, printed); err != nil {
+		return result, err
+	}
+	if format == output.FormatText {
+		fmt.Printf("CONTAINERS_STOPPED=%d\n", result.stopped)
+		fmt.Printf("VOLUMES_LOCKED=%d\n", result.locked)
+	}
+
+	if result.failures > 0 {
+		return result, fmt.Errorf("%d containers or volumes could not be secured", result.failures)
+	}
+	if result.locked == 0 && result.stopped == 0 {
+		infoErrf("No capsule volumes mounted. Nothing to lock.\n")
+	} else {
+		infoErrf("Locked %d volumes and stopped %d containers.\n", result.locked, result.stopped)
+	}
+	return result, nil
+}
+
+// lockResult counts what lockEverything secured.
+type lockResult struct {
+	stopped    int
+	locked     int
+	failures   int
+	imagePaths []string // Images of the volumes unmounted, where known
+}
+
+// lockEverything stops every running capsule container and unmounts every
+// capsule volume, reporting progress on stderr. Individual failures are
+// counted rather than returned so the rest still get secured.
+func lockEverything(ctx context.Context, volumeManager volume.VolumeManager, dockerManager docker.DockerManager) (lockResult, error) {
+	var result lockResult
+	containers, err := dockerManager.ListRunning(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	for _, containerName := range containers {
+		infoErrf("Stopping container %s...\n", containerName)
+		if err := dockerManager.Stop(ctx, containerName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stop %s: %v\n", containerName, err)
+			result.failures++
+			continue
+		}
+		result.stopped++
+	}
+
+	// A volume the cache hasn't caught up with would be left unlocked
+	mounted, err := volumeManager.ListMountedUncached(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list mounted volumes: %w", err)
+	}
+	for _, v := range mounted {
+		infoErrf("Unmounting encrypted volume at %s...\n", v.MountPoint)
+		if err := volumeManager.Unmount(ctx, v.MountPoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to unmount %s: %v\n", v.MountPoint, err)
+			result.failures++
+			continue
+		}
+		if v.ImagePath != "" {
+			result.imagePaths = append(result.imagePaths, v.ImagePath)
+		}
+		result.locked++
+	}
+	forgetVolumeMounts(result.imagePaths...)
+	return result, nil
+}
+
 func runStop(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+
+	idle, err := cmd.Flags().GetDuration("idle")
+	if err != nil {
+		return fmt.Errorf("invalid idle flag: %w", err)
+	}
+	containerFlag, err := cmd.Flags().GetString("container")
+	if err != nil {
+		return fmt.Errorf("invalid container flag: %w", err)
+	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	if all {
+		if idle > 0 || containerFlag != "" || cmd.Flags().Changed("scan") {
+			return fmt.Errorf("--all cannot be combined with --idle, --container, or --scan")
+		}
+		return stopAll(cmd)
+	}
+
 	// Get container name for current directory
-	containerName, _, err := getContainerNameForCwd()
+	containerName, cwd, err := getContainerNameForCwd()
 	if err != nil {
 		return err
 	}
+	if containerFlag != "" {
+		containerName = containerFlag
+	}
 
 	dockerManager := docker.NewManager()
+	if idle > 0 {
+		idled, err := waitUntilIdle(ctx, dockerManager, containerName, idle)
+		if err != nil {
+			return err
+		}
+		if !idled {
+			infof("Container %s stopped or was restarted while waiting; leaving it alone.\n", containerName)
+			return nil
+		}
+	}
+
+	if err := scanWorkspaceBeforeLock(cmd, cwd, containerName); err != nil {
+		return err
+	}
+
+	releaseLocks, err := lockOperation(cmd.CommandPath(), containerName, "")
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
 
 	// Stop container (symlink inside container is destroyed with it)
-	fmt.Printf("Stopping container %s...\n", containerName)
-	if err := dockerManager.Stop(containerName); err != nil {
+	infof("Stopping container %s...\n", containerName)
+	if err := dockerManager.Stop(ctx, containerName); err != nil {
 		fmt.Printf("Warning: Failed to stop container: %v\n", err)
 	} else {
-		fmt.Println("Container stopped.")
+		infoln("Container stopped.")
+	}
+
+	// Mirror shadow docs to git if this repository opted in
+	repoIdentifier := newRepoIdentifier()
+	if workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd); err == nil && containerFlag == "" {
+		if repoID, err := repoIdentifier.GetRepoID(workspacePath); err == nil {
+			syncDocsOnStop(workspacePath, repoID, findMountPoint(ctx, volumePathFlag, cwd, containerName))
+		}
 	}
 
 	// Keep volume mounted for quick re-entry
-	fmt.Println("Volume remains mounted. Run 'capsule lock' to unmount and secure.")
+	infoln("Volume remains mounted. Run 'capsule lock' to unmount and secure.")
+	return nil
+}
+
+// stopAll stops every running container capsule labeled, leaving volumes
+// mounted. A container another capsule command is working on is skipped.
+func stopAll(cmd *cobra.Command) error {
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+	containers, err := dockerManager.ListLabeled(ctx)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		infoln("No capsule containers running.")
+		return nil
+	}
+
+	var stopped, failures int
+	for _, containerName := range containers {
+		releaseLocks, err := lockOperation(cmd.CommandPath(), containerName, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", containerName, err)
+			failures++
+			continue
+		}
+		infof("Stopping container %s...\n", containerName)
+		err = dockerManager.Stop(ctx, containerName)
+		releaseLocks()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stop %s: %v\n", containerName, err)
+			failures++
+			continue
+		}
+		stopped++
+	}
+
+	infof("Stopped %d containers. Volumes remain mounted; run 'capsule lock --all' to unmount them.\n", stopped)
+	if failures > 0 {
+		return fmt.Errorf("%d containers could not be stopped", failures)
+	}
 	return nil
 }
 
@@ -761,19 +2203,53 @@ func newStatusCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show environment status",
-		RunE:  runStatus,
+		Long: `Shows the volume, mount, container, and _docs symlink for the current workspace.
+
+With --watch, the display refreshes until Ctrl+C, highlighting what changed and
+listing recent transitions, including whether Docker is running. When stdout is
+not a terminal, each transition is printed as a timestamped line instead.
+
+With --all, it shows every workspace instead: the volumes capsule knows of (those
+in ~/.capsule/volumes and the local volumes of workspaces in 'capsule history'),
+every mounted capsule volume, and every capsule container with its workspaces.`,
+		RunE: runStatus,
 	}
 
-	cmd.Flags().String("volume", "", "Path to encrypted volume")
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume")
+	cmd.Flags().Bool("all", false, "Show every volume, mount, and container rather than the current workspace's")
+	cmd.Flags().BoolP("watch", "w", false, "Refresh the status until interrupted, highlighting changes")
+	cmd.Flags().Duration("interval", constants.StatusWatchInterval, "Refresh interval for --watch")
 
 	return cmd
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	volumePathFlag, err := cmd.Flags().GetString("volume")
 	if err != nil {
 		return fmt.Errorf("invalid volume flag: %w", err)
 	}
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		return fmt.Errorf("invalid watch flag: %w", err)
+	}
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return fmt.Errorf("invalid interval flag: %w", err)
+	}
+	if watch && interval < time.Second {
+		return fmt.Errorf("invalid interval %s: must be at least 1s", interval)
+	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	if all {
+		if watch {
+			return fmt.Errorf("--all cannot be used with --watch")
+		}
+		return printStatusAll(ctx, volumePathFlag)
+	}
 
 	// Get container name and cwd for current directory
 	containerName, cwd, err := getContainerNameForCwd()
@@ -787,50 +2263,31 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create path resolver: %w", err)
 	}
 
-	// Find volume path using priority rules (allow non-existent for status display)
-	volumePath, _ := pathResolver.ResolveVolumePath(volumePathFlag, cwd)
+	volumeManager, volumeErr := volume.New()
+	if volumeErr == nil && state.CheckDockerRunning() == nil {
+		removeOrphanedContainers(ctx, docker.NewManager(), volumeManager, "")
+	}
+
+	// Find the workspace's volume, which may not be the one the path rules
+	// pick (allow non-existent for status display)
+	volumePath, _ := resolveWorkspaceVolumePath(ctx, pathResolver, volumeManager, volumePathFlag, cwd, containerName)
 
-	// Create detector
-	detector := state.NewDetector(volumePath, containerName, cwd)
-	envState := detector.Detect()
+	if watch {
+		return watchStatus(ctx, volumeManager, volumePath, containerName, cwd, interval)
+	}
 
 	// Display status
 	fmt.Println("Claude Environment Status")
 	fmt.Println("=========================")
 	fmt.Println()
-
-	// Volume status
-	if envState.VolumeExists {
-		fmt.Printf("Volume:     %s (exists)\n", envState.VolumePath)
-	} else {
-		fmt.Printf("Volume:     %s (not found)\n", volumePath)
-	}
-
-	// Mount status
-	if envState.VolumeMounted {
-		fmt.Printf("Mounted:    Yes (%s)\n", envState.MountPoint)
-	} else {
-		fmt.Println("Mounted:    No")
-	}
-
-	// Container status
-	if envState.ContainerRunning {
-		fmt.Printf("Container:  Running (%s)\n", envState.ContainerName)
-	} else if envState.ContainerExists {
-		fmt.Printf("Container:  Stopped (%s)\n", envState.ContainerName)
-	} else {
-		fmt.Println("Container:  Not created")
+	for _, field := range statusFields(ctx, volumeManager, volumePath, containerName, cwd) {
+		printStatusField(field, false)
 	}
 
-	// Symlink status
-	if envState.SymlinkExists {
-		if envState.SymlinkBroken {
-			fmt.Printf("Symlink:    Broken (%s)\n", envState.SymlinkPath)
-		} else {
-			fmt.Printf("Symlink:    Active (%s)\n", envState.SymlinkPath)
+	if volumeErr == nil && volumeManager.GetMountPoint(ctx, volumePath) == "" {
+		if warning := staleVolumeWarning(volumePath, staleVolumeMonths(), time.Now()); warning != "" {
+			fmt.Println("\n" + warning)
 		}
-	} else {
-		fmt.Println("Symlink:    Not created")
 	}
 
 	// Docker status
@@ -847,37 +2304,100 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func newBuildImageCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "build-image",
-		Short: "Build the Docker image",
-		Long:  "Build the Docker image from the embedded Dockerfile. This is done automatically on first start.",
-		RunE:  runBuildImage,
+// statusFields detects the environment state for the workspace at cwd.
+// volumeManager may be nil if there is none on this platform.
+func statusFields(ctx context.Context, volumeManager volume.VolumeManager, volumePath, containerName, cwd string) []state.Field {
+	var mountPoint string
+	if volumeManager != nil {
+		mountPoint = volumeManager.GetMountPoint(ctx, volumePath)
 	}
+	envState := state.NewDetector(volumePath, mountPoint, containerName, cwd).Detect()
+	var fields []state.Field
 
-	cmd.Flags().Bool("force", false, "Rebuild even if image already exists")
+	// Volume status
+	if envState.VolumeExists {
+		fields = append(fields, state.Field{Name: "Volume", Value: envState.VolumePath + " (exists)"})
+	} else {
+		fields = append(fields, state.Field{Name: "Volume", Value: volumePath + " (not found)"})
+	}
 
-	return cmd
-}
+	if lastUsed, err := volume.LastUsed(volumePath); err == nil && envState.VolumeExists {
+		fields = append(fields, state.Field{Name: "Last used", Value: lastUsed.Local().Format("2006-01-02")})
+	}
 
-func runBuildImage(cmd *cobra.Command, args []string) error {
-	force, err := cmd.Flags().GetBool("force")
-	if err != nil {
-		return fmt.Errorf("invalid force flag: %w", err)
+	// Mount status, and what the volume holds, which can only be read while it is mounted
+	if envState.VolumeMounted {
+		fields = append(fields, state.Field{Name: "Mounted", Value: fmt.Sprintf("Yes (%s)", envState.MountPoint)})
+		if metadata, err := volume.ReadMetadata(envState.MountPoint); err != nil {
+			fields = append(fields, state.Field{Name: "Contents", Value: err.Error()})
+		} else if metadata != nil {
+			fields = append(fields, state.Field{Name: "Contents", Value: metadata.String()})
+		}
+	} else {
+		fields = append(fields, state.Field{Name: "Mounted", Value: "No"})
 	}
 
-	if !force && embedded.ImageExists(docker.DefaultImageName) {
-		fmt.Printf("Docker image '%s' already exists. Use --force to rebuild.\n", docker.DefaultImageName)
-		return nil
+	// Container status
+	if envState.ContainerRunning {
+		fields = append(fields, state.Field{Name: "Container", Value: fmt.Sprintf("Running (%s)", envState.ContainerName)})
+	} else if envState.ContainerExists {
+		fields = append(fields, state.Field{Name: "Container", Value: fmt.Sprintf("Stopped (%s)", envState.ContainerName)})
+	} else {
+		fields = append(fields, state.Field{Name: "Container", Value: "Not created"})
 	}
 
-	fmt.Printf("Building Docker image '%s'...\n", docker.DefaultImageName)
-	if err := embedded.BuildImage(docker.DefaultImageName); err != nil {
-		return fmt.Errorf("failed to build image: %w", err)
+	// Git status, shown only when the checkout isn't on a branch
+	if checkout, err := repo.Describe(cwd); err == nil && (checkout.Detached || checkout.Bare) {
+		fields = append(fields, state.Field{Name: "Git", Value: checkout.String()})
 	}
 
-	fmt.Println("Docker image built successfully!")
-	return nil
+	// Worktree status
+	repoIdentifier := newRepoIdentifier()
+	if workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd); err == nil {
+		if info, err := repo.DetectWorktree(workspacePath); err == nil && info.Linked {
+			fields = append(fields, state.Field{Name: "Worktree", Value: fmt.Sprintf("%s (policy: %s)", info.Name, repoIdentifier.ResolvePolicy(workspacePath))})
+		}
+	}
+	if repoIdentifier.Subproject != "" {
+		fields = append(fields, state.Field{Name: "Subproject", Value: repoIdentifier.Subproject})
+	}
+
+	// Symlink status
+	if envState.SymlinkExists {
+		if envState.SymlinkBroken {
+			fields = append(fields, state.Field{Name: "Symlink", Value: fmt.Sprintf("Broken (%s)", envState.SymlinkPath)})
+		} else {
+			fields = append(fields, state.Field{Name: "Symlink", Value: fmt.Sprintf("Active (%s)", envState.SymlinkPath)})
+		}
+	} else {
+		fields = append(fields, state.Field{Name: "Symlink", Value: "Not created"})
+	}
+
+	return fields
+}
+
+// staleVolumeMonths returns how many months a volume may go unmounted before
+// status warns about it, or 0 if the warning is off or the settings are
+// unreadable.
+func staleVolumeMonths() int {
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return 0
+	}
+	return settings.StaleVolumeThreshold()
+}
+
+// staleVolumeWarning returns a warning if the volume at volumePath has gone
+// unmounted for months, or "" if it hasn't. Callers check that it isn't
+// mounted now, since a long-running mount is in use.
+func staleVolumeWarning(volumePath string, months int, now time.Time) string {
+	lastUsed, err := volume.LastUsed(volumePath)
+	if err != nil || !volume.IsStale(lastUsed, now, months) {
+		return ""
+	}
+	return fmt.Sprintf(`Warning: %s has not been mounted since %s (over %d months).
+If its project is abandoned, move it somewhere offline or delete it, with its
+sidecar files, so the credentials it holds are retired.`, volumePath, lastUsed.Local().Format("2006-01-02"), months)
 }
 
 func newVersionCmd() *cobra.Command {
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func TestStaleVolumeWarning(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	volumePath := filepath.Join(t.TempDir(), "capsule.sparseimage")
+	if got := staleVolumeWarning(volumePath, 6, now); got != "" {
+		t.Errorf("staleVolumeWarning() for a missing volume = %q, want none", got)
+	}
+
+	if err := os.WriteFile(volumePath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	lastMount := now.AddDate(0, -7, 0)
+	if err := volume.RecordMount(volumePath, lastMount); err != nil {
+		t.Fatal(err)
+	}
+	got := staleVolumeWarning(volumePath, 6, now)
+	for _, want := range []string{volumePath, lastMount.Local().Format("2006-01-02"), "over 6 months"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("staleVolumeWarning() = %q, want it to mention %q", got, want)
+		}
+	}
+	if got := staleVolumeWarning(volumePath, 12, now); got != "" {
+		t.Errorf("staleVolumeWarning() within the threshold = %q, want none", got)
+	}
+	if got := staleVolumeWarning(volumePath, 0, now); got != "" {
+		t.Errorf("staleVolumeWarning() with the warning off = %q, want none", got)
+	}
+}
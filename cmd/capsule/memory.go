@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newMemoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Query the collaboration memory from the host",
+	}
+
+	cmd.AddCommand(newMemorySearchCmd())
+
+	return cmd
+}
+
+func newMemorySearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search the memory database without starting a container",
+		Long: `Searches the current repository's memory database directly on the host.
+Uses the existing mount if the volume is unlocked; otherwise mounts it for
+the duration of the search and locks it again afterwards.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runMemorySearch,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().String("workspace", "", "Workspace path (defaults to current directory or git root)")
+	cmd.Flags().String("repo", "", "Repository ID to search (overrides workspace detection)")
+	cmd.Flags().StringSlice("tags", []string{}, "Tags to include in the search")
+	cmd.Flags().Int("limit", 10, "Maximum number of results")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+
+	return cmd
+}
+
+func runMemorySearch(cmd *cobra.Command, args []string) error {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	workspaceFlag, err := cmd.Flags().GetString("workspace")
+	if err != nil {
+		return fmt.Errorf("invalid workspace flag: %w", err)
+	}
+	repoID, err := cmd.Flags().GetString("repo")
+	if err != nil {
+		return fmt.Errorf("invalid repo flag: %w", err)
+	}
+	tags, err := cmd.Flags().GetStringSlice("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return fmt.Errorf("invalid limit flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	query := strings.Join(args, " ")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Determine which repository's memory to search
+	if repoID == "" {
+		repoIdentifier := newRepoIdentifier()
+		workspacePath := workspaceFlag
+		if workspacePath == "" {
+			workspacePath, err = repoIdentifier.GetWorkspaceRoot(cwd)
+			if err != nil {
+				return fmt.Errorf("failed to determine workspace root: %w", err)
+			}
+		}
+		workspacePath, err = filepath.Abs(workspacePath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+		repoID, err = repoIdentifier.GetRepoID(workspacePath)
+		if err != nil {
+			return fmt.Errorf("failed to identify repository: %w", err)
+		}
+	}
+
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+
+	mountPoint, release, err := mountForHostAccess(cmd.Context(), volumeManager, volumePath, passwordStdin, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	store, err := docsync.OpenReadOnly(docsync.RepoDocsRoot(mountPoint, repoID))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	results, err := store.Search(query, tags, limit)
+	if err != nil {
+		return err
+	}
+
+	docsync.WriteResults(os.Stdout, query, results)
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/migrate"
+	"github.com/jeanhaley32/claude-capsule/internal/state"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move volumes, containers, and images from claude-env to capsule naming",
+		Long: `Finds what the older claude-env binary left behind and moves it to capsule's
+naming:
+
+  - claude-env.sparseimage in ~/.claude-env (or ~/.claude-env/volumes) and in
+    ~/.capsule/volumes moves to ~/.capsule/volumes/capsule.sparseimage; one in
+    the current directory or its workspace root is renamed capsule.sparseimage
+    in place
+  - the volume's VERSION file is rewritten for this capsule version, which
+    mounts it, so you are asked for its password
+  - a volume still mounted at /Volumes/ClaudeEnv is unmounted first
+  - the portable-claude container and image are removed
+
+A volume is skipped if capsule's volume already exists where it would go. Use
+--dry-run to see the list without changing anything.`,
+		Args: cobra.NoArgs,
+		RunE: runMigrate,
+	}
+
+	cmd.Flags().String("password-file", "", "Read the volume password from a file readable only by you (mode 0600)")
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	addDryRunFlag(cmd)
+
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+
+	ctx := cmd.Context()
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	dirs := []string{cwd}
+	if root, err := newRepoIdentifier().GetWorkspaceRoot(cwd); err == nil && root != cwd {
+		dirs = append(dirs, root)
+	}
+	moves := migrate.FindVolumes(homeDir, dirs)
+	_, err = os.Stat(migrate.LegacyMountPoint)
+	legacyMounted := err == nil
+
+	var containers, images []string
+	if err := state.CheckDockerRunning(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: Docker is not running; legacy containers and images were not checked.")
+	} else {
+		if containers, err = migrate.Containers(ctx); err != nil {
+			return err
+		}
+		if images, err = migrate.Images(ctx); err != nil {
+			return err
+		}
+	}
+
+	var plan dryRunPlan
+	for _, name := range containers {
+		plan.run("Remove legacy container "+name, "docker", "rm", "-f", name)
+	}
+	if legacyMounted {
+		plan.run("Unmount the legacy volume", "diskutil", "unmount", migrate.LegacyMountPoint)
+	}
+	for _, m := range moves {
+		plan.add("Move %s to %s", m.From, m.To)
+		plan.add("Mount %s and rewrite its VERSION for capsule %s", m.To, version)
+	}
+	for _, image := range images {
+		plan.run("Remove legacy image "+image, "docker", "image", "rm", image)
+	}
+	if len(plan.steps) == 0 {
+		fmt.Println("Nothing to migrate: no claude-env volumes, mounts, containers, or images found.")
+		return nil
+	}
+	if dryRun {
+		plan.print(cmd.CommandPath())
+		return nil
+	}
+	fmt.Println("capsule migrate will:")
+	plan.list()
+	if !yes {
+		confirmed, err := terminal.PromptConfirm("Migrate?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	var failures int
+	dockerManager := docker.NewManager()
+	for _, name := range containers {
+		if err := dockerManager.RemoveContainer(ctx, name); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			failures++
+			continue
+		}
+		fmt.Printf("Removed container %s\n", name)
+	}
+
+	if legacyMounted || len(moves) > 0 {
+		volumeManager, err := volume.New()
+		if err != nil {
+			return fmt.Errorf("failed to create volume manager: %w", err)
+		}
+		if legacyMounted {
+			if err := volumeManager.Unmount(ctx, migrate.LegacyMountPoint); err != nil {
+				// Moving a mounted image would leave it attached under the old path
+				return fmt.Errorf("failed to unmount %s: %w", migrate.LegacyMountPoint, err)
+			}
+			fmt.Printf("Unmounted %s\n", migrate.LegacyMountPoint)
+		}
+		for _, m := range moves {
+			if err := migrateVolume(ctx, volumeManager, m, passwordFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				failures++
+			}
+		}
+	}
+
+	for _, image := range images {
+		if err := embedded.RemoveImage(ctx, image); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			failures++
+			continue
+		}
+		fmt.Printf("Removed image %s\n", image)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d migration steps failed", failures)
+	}
+	fmt.Println("Migration complete. Run 'capsule start' to use the volume.")
+	return nil
+}
+
+// migrateVolume moves a legacy volume and its sidecars into place, then
+// mounts it to rewrite its VERSION file and re-sign its manifest.
+func migrateVolume(ctx context.Context, volumeManager volume.VolumeManager, m migrate.VolumeMove, passwordFile string) error {
+	if err := migrate.MoveVolume(m); err != nil {
+		return err
+	}
+	fmt.Printf("Moved %s to %s\n", m.From, m.To)
+
+	password, err := readVolumePassword(passwordFile, fmt.Sprintf("Password for %s: ", m.To))
+	if err != nil {
+		return err
+	}
+	defer password.Clear()
+	mountPoint, err := volumeManager.Mount(ctx, m.To, password)
+	if err != nil {
+		return fmt.Errorf("failed to mount %s to rewrite its VERSION: %w", m.To, err)
+	}
+	rewritten, err := migrate.RewriteVersionFile(mountPoint, version)
+	if err == nil && rewritten {
+		err = volumeManager.WriteManifest(ctx, m.To, mountPoint, password)
+	}
+	if unmountErr := volumeManager.Unmount(ctx, mountPoint); unmountErr != nil && err == nil {
+		err = unmountErr
+	}
+	if err != nil {
+		return err
+	}
+	if rewritten {
+		fmt.Printf("Rewrote VERSION in %s for capsule %s\n", m.To, version)
+	}
+	return nil
+}
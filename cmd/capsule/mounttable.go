@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/mounttable"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// recordWorkspaceMount records in the mount table that containerName runs
+// from the volume at volumePath, mounted at mountPoint, dropping volumes that
+// are no longer mounted. Failures are logged, not returned: without the
+// entry, commands fall back to the usual volume resolution.
+func recordWorkspaceMount(ctx context.Context, volumeManager volume.VolumeManager, volumePath, mountPoint, containerName string, workspaces []docker.Workspace) {
+	path, err := mounttable.Path()
+	if err != nil {
+		slog.Warn("failed to record workspace mount", "error", err)
+		return
+	}
+	mounted := mountedImages(ctx, volumeManager)
+	paths := make([]string, len(workspaces))
+	for i, w := range workspaces {
+		paths[i] = w.Path
+	}
+	err = mounttable.Update(path, func(t *mounttable.Table) {
+		if mounted != nil {
+			t.Prune(mounted)
+		}
+		t.Bind(volumePath, mountPoint, mounttable.Workspace{Container: containerName, Paths: paths, Started: time.Now().UTC()})
+	})
+	if err != nil {
+		slog.Warn("failed to record workspace mount", "error", err)
+	}
+}
+
+// forgetVolumeMounts removes the volumes at volumePaths from the mount table
+// once they are unmounted.
+func forgetVolumeMounts(volumePaths ...string) {
+	path, err := mounttable.Path()
+	if err != nil {
+		slog.Warn("failed to update mount table", "error", err)
+		return
+	}
+	err = mounttable.Update(path, func(t *mounttable.Table) {
+		for _, volumePath := range volumePaths {
+			t.Forget(volumePath)
+		}
+	})
+	if err != nil {
+		slog.Warn("failed to update mount table", "error", err)
+	}
+}
+
+// workspaceMount returns the mount table's entry for the volume containerName
+// was started from, if that volume is still mounted where the entry says.
+func workspaceMount(ctx context.Context, volumeManager volume.VolumeManager, containerName string) (mounttable.Mount, bool) {
+	path, err := mounttable.Path()
+	if err != nil {
+		return mounttable.Mount{}, false
+	}
+	table, err := mounttable.Load(path)
+	if err != nil {
+		slog.Debug("failed to read mount table", "error", err)
+		return mounttable.Mount{}, false
+	}
+	m, ok := table.ForContainer(containerName)
+	if !ok || volumeManager.GetMountPoint(ctx, m.VolumePath) != m.MountPoint {
+		return mounttable.Mount{}, false
+	}
+	return m, true
+}
+
+// volumeMount returns the mount table's entry for the volume at volumePath,
+// if it is still mounted where the entry says.
+func volumeMount(ctx context.Context, volumeManager volume.VolumeManager, volumePath string) (mounttable.Mount, bool) {
+	path, err := mounttable.Path()
+	if err != nil {
+		return mounttable.Mount{}, false
+	}
+	table, err := mounttable.Load(path)
+	if err != nil {
+		slog.Debug("failed to read mount table", "error", err)
+		return mounttable.Mount{}, false
+	}
+	m, ok := table.ForVolume(volumePath)
+	if !ok || volumeManager.GetMountPoint(ctx, m.VolumePath) != m.MountPoint {
+		return mounttable.Mount{}, false
+	}
+	return m, true
+}
+
+// resolveWorkspaceVolumePath resolves the volume a command run in a workspace
+// acts on: --volume's if given, else the mounted volume the workspace's
+// container was started from, else the usual local and global rules. It
+// reports whether the volume exists, like ResolveVolumePath.
+func resolveWorkspaceVolumePath(ctx context.Context, pathResolver *volume.PathResolver, volumeManager volume.VolumeManager, volumePathFlag, cwd, containerName string) (string, bool) {
+	if volumePathFlag == "" && volumeManager != nil {
+		if m, ok := workspaceMount(ctx, volumeManager, containerName); ok {
+			return m.VolumePath, true
+		}
+	}
+	return pathResolver.ResolveVolumePath(volumePathFlag, cwd)
+}
+
+// mountedImages maps the image path of each mounted volume to its mount
+// point, or returns nil if the volumes can't be listed.
+func mountedImages(ctx context.Context, volumeManager volume.VolumeManager) map[string]string {
+	mounted, err := volumeManager.ListMounted(ctx)
+	if err != nil {
+		slog.Debug("failed to list mounted volumes", "error", err)
+		return nil
+	}
+	images := make(map[string]string)
+	for _, v := range mounted {
+		if v.ImagePath != "" {
+			images[filepath.Clean(v.ImagePath)] = v.MountPoint
+		}
+	}
+	return images
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/oplock"
+)
+
+// lockOperation takes the workspace lock for containerName, then the volume
+// lock for volumePath, skipping either if empty, so that operation (e.g.
+// "capsule start") can't race another capsule process on them. Workspace
+// always comes before volume, so two processes never wait on each other. A
+// volume lock held by this process's parent is shared rather than busy: that
+// is 'capsule start --workspaces-file', which holds its volumes while the
+// starts it runs use them. The returned function releases both and may be
+// called more than once.
+func lockOperation(operation, containerName, volumePath string) (func(), error) {
+	dir, err := oplock.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	var held []*oplock.Lock
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			if err := held[i].Release(); err != nil {
+				slog.Warn("failed to release lock", "error", err)
+			}
+		}
+	}
+	if containerName != "" {
+		l, err := oplock.Acquire(filepath.Join(dir, oplock.WorkspaceName(containerName)), "this workspace", operation)
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, l)
+	}
+	if volumePath != "" {
+		l, err := oplock.Acquire(filepath.Join(dir, oplock.VolumeName(volumePath)), "this volume", operation)
+		var busy *oplock.BusyError
+		switch {
+		case errors.As(err, &busy) && busy.Holder != nil && busy.Holder.PID == os.Getppid():
+			slog.Debug("sharing the parent's volume lock", "volume", volumePath, "pid", busy.Holder.PID)
+		case err != nil:
+			release()
+			return nil, err
+		default:
+			held = append(held, l)
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// orphanedContainer is a capsule container that bind-mounts a volume which is
+// no longer mounted, so its mounts point at nothing.
+type orphanedContainer struct {
+	name       string
+	mountPoint string
+}
+
+// findOrphanedContainers returns the running and exited capsule containers
+// whose volume mount point is gone, e.g. because the volume was ejected or
+// capsule crashed before stopping them.
+func findOrphanedContainers(ctx context.Context, dockerManager docker.DockerManager, volumeManager volume.VolumeManager) ([]orphanedContainer, error) {
+	mountDir, err := config.MountDir()
+	if err != nil {
+		return nil, err
+	}
+	mountDirs := []string{mountDir, constants.LegacyMountDir}
+
+	// Another capsule may have mounted a volume since the cache was written,
+	// and its containers must not be taken for orphans
+	mountedVolumes, err := volumeManager.ListMountedUncached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mounted := make(map[string]bool)
+	for _, v := range mountedVolumes {
+		mounted[filepath.Clean(v.MountPoint)] = true
+	}
+
+	names, err := dockerManager.ListRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exited, err := dockerManager.ListExited(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range exited {
+		names = append(names, c.Name)
+	}
+
+	var orphans []orphanedContainer
+	for _, name := range names {
+		sources, err := dockerManager.MountSources(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, source := range sources {
+			if mountPoint := capsuleMountPointOf(source, mountDirs); mountPoint != "" && !mounted[mountPoint] {
+				orphans = append(orphans, orphanedContainer{name: name, mountPoint: mountPoint})
+				break
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// capsuleMountPointOf returns the capsule mount point that path is in, or ""
+// if it isn't inside one.
+func capsuleMountPointOf(path string, mountDirs []string) string {
+	for _, dir := range mountDirs {
+		rel, err := filepath.Rel(dir, filepath.Clean(path))
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		first, _, _ := strings.Cut(rel, string(filepath.Separator))
+		if strings.HasPrefix(first, constants.MountPointNamePrefix) {
+			return filepath.Join(dir, first)
+		}
+	}
+	return ""
+}
+
+// orphansOfVolume returns the orphans whose mount point is the volume at
+// volumePath's, in the mount directory or the legacy one.
+func orphansOfVolume(orphans []orphanedContainer, volumePath string) []orphanedContainer {
+	name := filepath.Base(volume.MountPointFor("", volumePath))
+	var matched []orphanedContainer
+	for _, o := range orphans {
+		if filepath.Base(o.mountPoint) == name {
+			matched = append(matched, o)
+		}
+	}
+	return matched
+}
+
+// removeOrphanedContainers removes orphaned containers, which would otherwise
+// break the next session on their mount point with mount conflicts, only
+// those on the volume at volumePath if it isn't empty. Failures are logged,
+// not returned, so they never block the command that runs this.
+func removeOrphanedContainers(ctx context.Context, dockerManager docker.DockerManager, volumeManager volume.VolumeManager, volumePath string) {
+	orphans, err := findOrphanedContainers(ctx, dockerManager, volumeManager)
+	if err != nil {
+		slog.Debug("failed to check for orphaned containers", "error", err)
+		return
+	}
+	if volumePath != "" {
+		orphans = orphansOfVolume(orphans, volumePath)
+	}
+	for _, o := range orphans {
+		if err := dockerManager.RemoveContainer(ctx, o.name); err != nil {
+			slog.Warn("failed to remove orphaned container", "container", o.name, "error", err)
+			continue
+		}
+		slog.Info("removed orphaned container", "container", o.name, "mount_point", o.mountPoint)
+		infof("Removed orphaned container %s (its volume at %s is no longer mounted).\n", o.name, o.mountPoint)
+	}
+}
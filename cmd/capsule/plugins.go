@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/plugin"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newPluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "List external subcommands",
+		Long: `Any executable named ` + plugin.Prefix + `<name> on PATH runs as 'capsule <name>', unless
+<name> is a built-in command. Arguments after the name are passed through unchanged.
+
+Plugins receive the current session in the environment:
+  ` + plugin.EnvVolumePath + `        resolved volume image (empty if none was found)
+  ` + plugin.EnvMountPoint + `        where the volume is mounted (empty if locked)
+  ` + plugin.EnvContainerName + `     container for the current workspace
+  ` + plugin.EnvContainerRunning + `  true if that container is running
+  ` + plugin.EnvWorkspace + `          workspace root for the current directory
+  ` + plugin.EnvRepoID + `            repository ID used for _docs and memory
+  ` + plugin.EnvVersion + `, ` + plugin.EnvBinary + `
+
+The volume password is never passed to plugins.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List plugins found on PATH",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginList(cmd.Root())
+		},
+	})
+
+	return cmd
+}
+
+func runPluginList(root *cobra.Command) error {
+	plugins, shadowed := plugin.List(os.Getenv("PATH"))
+	if len(plugins) == 0 {
+		fmt.Printf("No plugins found. Put an executable named %s<name> on PATH.\n", plugin.Prefix)
+		return nil
+	}
+
+	for _, p := range plugins {
+		note := ""
+		if isBuiltinCommand(root, p.Name) {
+			note = "  (ignored: built-in command of the same name)"
+		}
+		fmt.Printf("%-16s %s%s\n", p.Name, p.Path, note)
+	}
+	for _, p := range shadowed {
+		fmt.Fprintf(os.Stderr, "Warning: %s is shadowed by an earlier %s%s on PATH\n", p.Path, plugin.Prefix, p.Name)
+	}
+	return nil
+}
+
+// runPluginIfAny runs the plugin named by the first argument if it is not a
+// built-in command. It reports whether a plugin ran and the exit code to use.
+func runPluginIfAny(root *cobra.Command, args []string) (int, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || isBuiltinCommand(root, args[0]) {
+		return 0, false
+	}
+	p, err := plugin.Lookup(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1, true
+	}
+	if p == nil {
+		return 0, false // let cobra report the unknown command
+	}
+
+	if closeLog, err := logging.Setup(logging.Options{}); err == nil {
+		defer closeLog()
+	}
+	slog.Info("plugin started", "plugin", p.Name, "path", p.Path)
+
+	cmd := p.Command(args[1:], pluginContext())
+	done := logging.Command(cmd)
+	err = cmd.Run()
+	done(err)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to run plugin %s: %v\n", p.Name, err)
+		return 1, true
+	}
+	return 0, true
+}
+
+// isBuiltinCommand reports whether name is a capsule command or alias.
+// Built-ins always win over plugins.
+func isBuiltinCommand(root *cobra.Command, name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range root.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginContext resolves what a plugin needs to know about the current session.
+// Anything that can't be resolved is left empty rather than failing the plugin.
+// Plugins run before capsule's own signal handling, so the lookups aren't
+// cancellable.
+func pluginContext() plugin.Context {
+	ctx := plugin.Context{Version: version}
+	if binary, err := os.Executable(); err == nil {
+		ctx.Binary = binary
+	}
+
+	containerName, cwd, err := getContainerNameForCwd()
+	if err != nil {
+		return ctx
+	}
+	ctx.ContainerName = containerName
+	ctx.ContainerRunning = docker.NewManager().IsRunning(context.Background(), containerName)
+
+	repoIdentifier := newRepoIdentifier()
+	if workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd); err == nil {
+		ctx.Workspace = workspacePath
+		if repoID, err := repoIdentifier.GetRepoID(workspacePath); err == nil {
+			ctx.RepoID = repoID
+		}
+	}
+
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return ctx
+	}
+	volumePath, err := pathResolver.ResolveVolumePathStrict("", cwd)
+	if err != nil {
+		return ctx
+	}
+	ctx.VolumePath = volumePath
+	if volumeManager, err := volume.New(); err == nil {
+		ctx.MountPoint = volumeManager.GetMountPoint(context.Background(), volumePath)
+	}
+	return ctx
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+	"github.com/jeanhaley32/claude-capsule/internal/preflight"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newPreflightCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that this machine can run capsule, for scripts and CI",
+		Long: `Checks everything capsule depends on and reports each check as pass, fail,
+or skip, without changing anything:
+
+  platform       capsule runs on macOS
+  docker         the docker CLI is installed, and its version
+  docker_daemon  the Docker daemon answers, and its version
+  hdiutil        hdiutil is installed (macOS), and its version
+  cryptsetup     cryptsetup is installed (Linux), and its version
+  file_sharing   Docker can bind mount the mount directory
+  disk_space     the disk holding the volume has --min-free-gb free
+
+Checks that need the daemon are skipped when it isn't running. preflight exits
+with status 1 if any check failed. With --output json it prints the report as
+a JSON envelope (see 'capsule schema').`,
+		Args: cobra.NoArgs,
+		RunE: runPreflight,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume whose disk to check (auto-detected if not specified)")
+	cmd.Flags().Int("min-free-gb", 2, "Free disk space in GB the volume's disk needs")
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+func runPreflight(cmd *cobra.Command, args []string) error {
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	minFreeGB, err := cmd.Flags().GetInt("min-free-gb")
+	if err != nil {
+		return fmt.Errorf("invalid min-free-gb flag: %w", err)
+	}
+	if minFreeGB < 0 {
+		return fmt.Errorf("invalid min-free-gb flag: must not be negative")
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	// The volume may not exist yet; its disk is where bootstrap would put it
+	volumePath, _ := pathResolver.ResolveVolumePath(volumePathFlag, cwd)
+	mountDir, err := config.MountDir()
+	if err != nil {
+		return err
+	}
+
+	dockerManager := docker.NewManager()
+	checker := preflight.New(mountDir, filepath.Dir(volumePath), int64(minFreeGB)<<30, dockerManager.CheckFileSharing)
+	result := checker.Check(cmd.Context())
+	if err := cmd.Context().Err(); err != nil {
+		return err
+	}
+
+	if format == output.FormatJSON {
+		if err := output.Write(os.Stdout, "preflight", result); err != nil {
+			return err
+		}
+	} else {
+		printPreflightResult(result)
+	}
+
+	if failed := preflight.Failed(result); len(failed) > 0 {
+		return fmt.Errorf("preflight failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// printPreflightResult prints the report as KEY=VALUE lines, one set per
+// check, keyed by the check's name in upper case.
+func printPreflightResult(result output.PreflightResult) {
+	for _, check := range result.Checks {
+		key := strings.ToUpper(check.Name)
+		fmt.Printf("%s=%s\n", key, check.Status)
+		if check.Version != "" {
+			fmt.Printf("%s_VERSION=%s\n", key, check.Version)
+		}
+		fmt.Printf("%s_DETAIL=%s\n", key, check.Detail)
+	}
+	fmt.Printf("STATUS=%s\n", result.Status)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeanhaley32/claude-capsule/internal/progress"
+)
+
+// quiet is set from the --quiet persistent flag. It silences progress
+// messages; results, prompts, warnings, and errors are still printed.
+var quiet bool
+
+// infof prints a progress message on stdout unless --quiet is set.
+func infof(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// infoln prints a progress line on stdout unless --quiet is set.
+func infoln(args ...any) {
+	if !quiet {
+		fmt.Println(args...)
+	}
+}
+
+// infoErrf prints a progress message on stderr unless --quiet is set, for
+// commands that keep stdout for their results.
+func infoErrf(format string, args ...any) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// startProgress starts a progress indicator on stdout, or one that draws
+// nothing with --quiet.
+func startProgress(label string) *progress.Indicator {
+	if quiet {
+		return progress.Discard(label)
+	}
+	return progress.Start(os.Stdout, label)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/daemon"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+)
+
+// maxReattaches caps how many times one session restarts its container and
+// re-attaches, so a container that keeps dying doesn't loop forever.
+const maxReattaches = 3
+
+// sessionLost reports whether an interactive shell that ended with execErr
+// ended because its container went away, e.g. when Docker Desktop restarted,
+// rather than because the user exited. A shell that exits with an error
+// leaves its container running, and started at attachedAt; one with the
+// unless-stopped restart policy may already be running again, but restarted.
+func sessionLost(ctx context.Context, dockerManager docker.DockerManager, containerName string, attachedAt time.Time, execErr error) bool {
+	if execErr == nil || ctx.Err() != nil {
+		return false
+	}
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return true
+	}
+	startedAt, err := dockerManager.StartedAt(ctx, containerName)
+	return err == nil && !attachedAt.IsZero() && startedAt.After(attachedAt)
+}
+
+// restartSessionContainer waits for the Docker daemon to come back, then
+// starts the session's container again with the same config and reruns
+// setupSymlinks in it.
+func restartSessionContainer(ctx context.Context, dockerManager docker.DockerManager, config docker.ContainerConfig, setupSymlinks func(context.Context) error) error {
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Lost the container. Waiting for Docker...")
+	if err := dockerManager.WaitForDaemon(ctx, docker.DaemonReconnectTimeout); err != nil {
+		return err
+	}
+	slog.Info("restarting container after losing it", "container", config.ContainerName)
+
+	// A restarted Docker Desktop may hold stale VirtioFS entries for the volumes
+	for _, mountPoint := range []string{config.VolumeMountPoint, config.IdentityMountPoint} {
+		if mountPoint == "" {
+			continue
+		}
+		if err := dockerManager.RefreshMountCache(ctx, mountPoint); err != nil {
+			slog.Debug("cache refresh failed", "error", err)
+		}
+	}
+	infof("Restarting container %s...\n", config.ContainerName)
+	started := time.Now()
+	err := docker.DaemonRetry.Do(ctx, "restart container", func(int) error {
+		return dockerManager.Start(ctx, config)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	if err := setupSymlinks(ctx); err != nil {
+		return err
+	}
+	reportDaemonEvent(daemon.Event{Type: daemon.EventContainerStart, Seconds: time.Since(started).Seconds()})
+	return nil
+}
+
+// attachShell runs the interactive shell in the session's container,
+// recording it to recordPath if that isn't empty. If Docker Desktop
+// restarts, the shell dies with the container; both are brought back, up to
+// maxReattaches times, rather than dropping the user at the host prompt,
+// and a recording continues in a new transcript. lost reports that the
+// container went away and could not be restarted.
+func attachShell(ctx context.Context, dockerManager docker.DockerManager, config docker.ContainerConfig, env []string, recordPath, mountPoint, repoID string, setupSymlinks func(context.Context) error) (execErr error, lost bool) {
+	for reattaches := 0; ; reattaches++ {
+		attachedAt, _ := dockerManager.StartedAt(ctx, config.ContainerName)
+		execErr = dockerManager.Exec(ctx, config.ContainerName, env, recordPath)
+		if reattaches == maxReattaches || !sessionLost(ctx, dockerManager, config.ContainerName, attachedAt, execErr) {
+			return execErr, false
+		}
+		if err := restartSessionContainer(ctx, dockerManager, config, setupSymlinks); err != nil {
+			return err, true
+		}
+		if recordPath != "" {
+			recordPath = createShellTranscript(mountPoint, repoID)
+		}
+		infoln("Re-attaching... (type 'exit' to leave)")
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// Default age after which a repository folder is considered stale by 'repos prune'.
+const defaultPruneDays = 90
+
+func newReposCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repos",
+		Short: "Manage per-repository data stored in the volume",
+		Long: `Each project gets a folder under repos/ in the encrypted volume holding its
+shadow docs, memory database, and beads issues. These commands list, inspect,
+archive, and prune those folders.`,
+	}
+
+	cmd.AddCommand(
+		newReposListCmd(),
+		newReposShowCmd(),
+		newReposArchiveCmd(),
+		newReposPruneCmd(),
+	)
+
+	for _, sub := range cmd.Commands() {
+		sub.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+		sub.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	}
+
+	return cmd
+}
+
+func newReposListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List repository folders with disk usage and last modified time",
+		Args:  cobra.NoArgs,
+		RunE:  runReposList,
+	}
+}
+
+func newReposShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [repo-id]",
+		Short: "Show details for a repository folder (defaults to the current workspace)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runReposShow,
+	}
+}
+
+func newReposArchiveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "archive <repo-id>...",
+		Short: "Compress repository folders into archive/repos/ and remove them",
+		Long: `Compresses each repository folder into a tar.gz under archive/repos/ inside
+the encrypted volume, then removes the folder. Archives stay encrypted with the volume.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runReposArchive,
+	}
+}
+
+func newReposPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete (or archive) repository folders not modified recently",
+		Long: `Removes repository folders whose contents have not changed within --older-than days.
+Folders belonging to a running container are skipped. Use --archive to keep a
+compressed copy and --dry-run to preview.`,
+		Args: cobra.NoArgs,
+		RunE: runReposPrune,
+	}
+
+	cmd.Flags().Int("older-than", defaultPruneDays, "Prune folders not modified in this many days")
+	cmd.Flags().Bool("archive", false, "Archive folders instead of deleting them")
+	cmd.Flags().Bool("dry-run", false, "Show what would be pruned without changing anything")
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runReposList(cmd *cobra.Command, args []string) error {
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	repos, err := repo.ListStored(mountPoint)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		fmt.Println("No repository data in volume.")
+		return nil
+	}
+
+	dockerManager := docker.NewManager()
+	var total int64
+	fmt.Printf("%-50s %10s %7s  %-16s %s\n", "Repository", "Size", "Files", "Last Modified", "Container")
+	for _, r := range repos {
+		container := "-"
+		if dockerManager.IsRunning(cmd.Context(), repo.ContainerName(r.ID)) {
+			container = "running"
+		}
+		fmt.Printf("%-50s %10s %7d  %-16s %s\n", r.ID, formatBytes(r.Bytes), r.Files, formatModTime(r.LastModified), container)
+		total += r.Bytes
+	}
+	fmt.Printf("\n%d repositories, %s total\n", len(repos), formatBytes(total))
+	return nil
+}
+
+func runReposShow(cmd *cobra.Command, args []string) error {
+	var repoID string
+	if len(args) == 1 {
+		repoID = args[0]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		repoIdentifier := newRepoIdentifier()
+		workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd)
+		if err != nil {
+			return fmt.Errorf("failed to determine workspace root: %w", err)
+		}
+		repoID, err = repoIdentifier.GetRepoID(workspacePath)
+		if err != nil {
+			return fmt.Errorf("failed to identify repository: %w", err)
+		}
+	}
+	if err := repo.ValidateRepoID(repoID); err != nil {
+		return err
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	stored, err := repo.GetStored(mountPoint, repoID)
+	if err != nil {
+		return err
+	}
+
+	containerName := repo.ContainerName(stored.ID)
+	containerStatus := "not running"
+	if docker.NewManager().IsRunning(cmd.Context(), containerName) {
+		containerStatus = "running"
+	}
+
+	fmt.Printf("Repository:     %s\n", stored.ID)
+	fmt.Printf("Path:           %s\n", stored.Path)
+	fmt.Printf("Size:           %s (%d files)\n", formatBytes(stored.Bytes), stored.Files)
+	fmt.Printf("Last modified:  %s\n", formatModTime(stored.LastModified))
+	fmt.Printf("Container:      %s (%s)\n", containerName, containerStatus)
+
+	entries, err := os.ReadDir(stored.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", stored.Path, err)
+	}
+	if len(entries) > 0 {
+		fmt.Println("\nContents:")
+		for _, entry := range entries {
+			usage, err := repo.DiskUsage(filepath.Join(stored.Path, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to measure %s: %w", entry.Name(), err)
+			}
+			name := entry.Name()
+			if entry.IsDir() {
+				name += "/"
+			}
+			fmt.Printf("  %-40s %10s  %s\n", name, formatBytes(usage.Bytes), formatModTime(usage.LastModified))
+		}
+	}
+	return nil
+}
+
+func runReposArchive(cmd *cobra.Command, args []string) error {
+	for _, repoID := range args {
+		if err := repo.ValidateRepoID(repoID); err != nil {
+			return err
+		}
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	dockerManager := docker.NewManager()
+	for _, repoID := range args {
+		if dockerManager.IsRunning(cmd.Context(), repo.ContainerName(repoID)) {
+			return fmt.Errorf("repository %s is in use by a running container; run 'capsule stop' first", repoID)
+		}
+		archivePath, err := repo.ArchiveStored(mountPoint, repoID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Archived %s to %s\n", repoID, archivePath)
+	}
+	return nil
+}
+
+func runReposPrune(cmd *cobra.Command, args []string) error {
+	olderThan, err := cmd.Flags().GetInt("older-than")
+	if err != nil {
+		return fmt.Errorf("invalid older-than flag: %w", err)
+	}
+	archive, err := cmd.Flags().GetBool("archive")
+	if err != nil {
+		return fmt.Errorf("invalid archive flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+	if olderThan < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	repos, err := repo.ListStored(mountPoint)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -olderThan)
+	dockerManager := docker.NewManager()
+	var stale []repo.StoredRepo
+	var reclaimed int64
+	for _, r := range repos {
+		if !r.LastModified.Before(cutoff) {
+			continue
+		}
+		if dockerManager.IsRunning(cmd.Context(), repo.ContainerName(r.ID)) {
+			fmt.Printf("Skipping %s (container running)\n", r.ID)
+			continue
+		}
+		stale = append(stale, r)
+		reclaimed += r.Bytes
+	}
+
+	if len(stale) == 0 {
+		fmt.Printf("No repository folders older than %d days.\n", olderThan)
+		return nil
+	}
+
+	action := "Delete"
+	if archive {
+		action = "Archive"
+	}
+	fmt.Printf("Repository folders not modified in %d days:\n", olderThan)
+	for _, r := range stale {
+		fmt.Printf("  %-50s %10s  %s\n", r.ID, formatBytes(r.Bytes), formatModTime(r.LastModified))
+	}
+	fmt.Printf("\n%d folders, %s\n", len(stale), formatBytes(reclaimed))
+
+	if dryRun {
+		fmt.Println("Dry run: nothing changed.")
+		return nil
+	}
+
+	if !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("%s %d folders?", action, len(stale)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	for _, r := range stale {
+		if archive {
+			archivePath, err := repo.ArchiveStored(mountPoint, r.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Archived %s to %s\n", r.ID, archivePath)
+			continue
+		}
+		if err := repo.RemoveStored(mountPoint, r.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s\n", r.ID)
+	}
+	return nil
+}
+
+// formatModTime renders a modification time for tabular output.
+func formatModTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
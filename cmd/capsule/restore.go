@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeanhaley32/claude-capsule/internal/migrate"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// restoreBackup bootstraps volumePath from a backed-up volume image instead
+// of creating a new one. The copy is mounted once with the backup's password
+// to bring it up to this version of capsule, then unmounted. then is called
+// as in bootstrapVolume.
+func restoreBackup(ctx context.Context, backupPath, volumePath, passwordFile string, passwordStdin bool, then func(volumeManager volume.VolumeManager, volumePath string, password *terminal.SecurePassword) error) error {
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if volumeManager.Exists(volumePath) {
+		return fmt.Errorf("already bootstrapped: volume exists at %s\nUse 'capsule start' to begin a session", volumePath)
+	}
+
+	fmt.Printf("Restoring %s to %s...\n", backupPath, volumePath)
+	if err := volume.RestoreImage(backupPath, volumePath); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return upgradeRestoredVolume(ctx, volumeManager, volumePath, passwordFile, passwordStdin, then)
+}
+
+// upgradeRestoredVolume mounts a volume just restored to volumePath with the
+// backup's password to bring it up to this version of capsule, then
+// unmounts it and calls then, if set.
+func upgradeRestoredVolume(ctx context.Context, volumeManager volume.VolumeManager, volumePath, passwordFile string, passwordStdin bool, then func(volumeManager volume.VolumeManager, volumePath string, password *terminal.SecurePassword) error) error {
+	var err error
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = terminal.ReadPasswordFromFileSecure(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter the backup's password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	mountPoint, err := volumeManager.Mount(ctx, volumePath, password)
+	if err != nil {
+		return fmt.Errorf("failed to mount the restored volume at %s: %w", volumePath, err)
+	}
+	steps, err := migrate.UpgradeVolume(mountPoint, version)
+	if err == nil && len(steps) > 0 {
+		err = volumeManager.WriteManifest(ctx, volumePath, mountPoint, password)
+	}
+	if unmountErr := volumeManager.Unmount(ctx, mountPoint); unmountErr != nil && err == nil {
+		err = unmountErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upgrade the restored volume at %s: %w", volumePath, err)
+	}
+
+	for _, step := range steps {
+		fmt.Printf("  %s\n", step)
+	}
+	fmt.Println("Volume restored successfully!")
+	if then != nil {
+		return then(volumeManager, volumePath, password)
+	}
+	fmt.Println("")
+	fmt.Println("Next step:")
+	fmt.Println("  capsule start")
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/transcript"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// runOutputFormats are the claude -p output formats 'capsule run' accepts.
+var runOutputFormats = []string{"text", "json", "stream-json"}
+
+// headlessRun is a prompt 'capsule run' gives Claude Code in place of the
+// interactive shell.
+type headlessRun struct {
+	prompt      string
+	claudeArgs  []string
+	keepRunning bool
+	lock        bool
+	stdout      io.Writer // Claude's output; capsule's own messages go to stderr
+
+	transcriptPath string // Set once the transcript is created
+}
+
+// exitCodeError makes capsule exit with a command's status instead of 1.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e exitCodeError) Error() string { return e.err.Error() }
+func (e exitCodeError) Unwrap() error { return e.err }
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run PROMPT",
+		Short: "Run Claude Code on a prompt without an interactive shell",
+		Long: `Starts the workspace's container like 'capsule start', then runs claude -p with
+PROMPT in the workspace instead of opening a shell. Claude's output streams to
+stdout, and capsule's own messages go to stderr. A PROMPT of - is read from stdin.
+
+The prompt and output are saved in the volume under repos/<repoID>/sessions/.
+Afterwards the container is stopped and the volume stays unlocked; --lock locks
+it, and --keep-running leaves the container up instead. capsule exits with
+Claude's exit status, so scripts and CI jobs can check it. Use --password-file
+where there is no terminal to type the volume password.`,
+		Example: `  capsule run "Summarize the open TODOs in this repository"
+  capsule run --lock --password-file ~/.capsule/ci-password --output-format json - < prompt.md`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRun,
+	}
+
+	addStartFlags(cmd)
+	cmd.Flags().Bool("lock", false, "Lock the volume when Claude finishes")
+	cmd.Flags().Bool("keep-running", false, "Leave the container running when Claude finishes")
+	cmd.Flags().String("output-format", "text", "Claude's output format: "+strings.Join(runOutputFormats, ", "))
+	cmd.Flags().StringArray("claude-arg", nil, "Extra argument for claude, e.g. --claude-arg=--max-turns=5 (repeatable)")
+
+	return cmd
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	lock, err := cmd.Flags().GetBool("lock")
+	if err != nil {
+		return fmt.Errorf("invalid lock flag: %w", err)
+	}
+	keepRunning, err := cmd.Flags().GetBool("keep-running")
+	if err != nil {
+		return fmt.Errorf("invalid keep-running flag: %w", err)
+	}
+	if lock && keepRunning {
+		return fmt.Errorf("--lock and --keep-running cannot be used together")
+	}
+	outputFormat, err := cmd.Flags().GetString("output-format")
+	if err != nil {
+		return fmt.Errorf("invalid output-format flag: %w", err)
+	}
+	if !slices.Contains(runOutputFormats, outputFormat) {
+		return fmt.Errorf("invalid output-format flag: %q (use %s)", outputFormat, strings.Join(runOutputFormats, ", "))
+	}
+	claudeArgs, err := cmd.Flags().GetStringArray("claude-arg")
+	if err != nil {
+		return fmt.Errorf("invalid claude-arg flag: %w", err)
+	}
+
+	prompt := args[0]
+	if prompt == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt from stdin: %w", err)
+		}
+		prompt = string(data)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("prompt is empty")
+	}
+
+	run := &headlessRun{
+		prompt:      prompt,
+		claudeArgs:  append([]string{"--output-format", outputFormat}, claudeArgs...),
+		keepRunning: keepRunning,
+		lock:        lock,
+		stdout:      os.Stdout,
+	}
+	// Keep stdout for Claude's output alone, so it can be piped or parsed
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	return startSession(cmd, run)
+}
+
+// exec runs the prompt in the container, streaming Claude's output and
+// saving it with the prompt as a transcript in the volume.
+func (r *headlessRun) exec(ctx context.Context, dockerManager docker.DockerManager, containerName, workspaceDir string, env []string, mountPoint, repoID string) error {
+	started := time.Now()
+	f, err := transcript.Create(mountPoint, repoID, transcript.KindRun, started)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r.transcriptPath = f.Name()
+	fmt.Fprintf(f, "# capsule run, %s\n# claude -p %s\n\n%s\n\n# Output\n\n",
+		started.Format(time.RFC3339), strings.Join(r.claudeArgs, " "), strings.TrimRight(r.prompt, "\n"))
+
+	fmt.Println("Running prompt...")
+	fmt.Println("")
+	// The prompt goes in on stdin rather than as an argument, so it never
+	// shows up in the host's process list and has no length limit
+	return dockerManager.ExecCommand(ctx, containerName, docker.ExecOptions{
+		Command: append([]string{"claude", "-p"}, r.claudeArgs...),
+		Dir:     workspaceDir,
+		Env:     env,
+		Stdin:   strings.NewReader(r.prompt),
+		Stdout:  io.MultiWriter(r.stdout, f),
+		Stderr:  io.MultiWriter(os.Stderr, f),
+	})
+}
+
+// finish reports where the transcript was saved, locks the volume if asked,
+// and turns Claude's exit status into capsule's.
+func (r *headlessRun) finish(ctx context.Context, volumeManager volume.VolumeManager, mountPoint string, execErr error) error {
+	if r.transcriptPath != "" {
+		if rel, err := filepath.Rel(mountPoint, r.transcriptPath); err == nil {
+			fmt.Printf("Transcript saved in the volume at %s\n", rel)
+		}
+	}
+
+	if r.lock {
+		if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to lock volume: %v\n", err)
+		} else {
+			fmt.Println("Volume locked.")
+		}
+	} else {
+		fmt.Println("Volume remains unlocked. Run 'capsule lock' when done to secure your credentials.")
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(execErr, &exitErr) {
+		return exitCodeError{code: exitErr.ExitCode(), err: fmt.Errorf("claude exited with status %d", exitErr.ExitCode())}
+	}
+	if execErr != nil {
+		return fmt.Errorf("failed to run prompt: %w", execErr)
+	}
+	return nil
+}
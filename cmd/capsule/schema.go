@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+)
+
+func newSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of --output json",
+		Long: fmt.Sprintf(`Prints the JSON Schema describing what unlock, lock, and preflight print with
+--output json. Every envelope carries "version" (currently %d), "command", and
+"result". The version only changes when a field is removed or changes meaning;
+new fields may appear at any time, so ignore fields you don't know.`, output.Version),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := os.Stdout.Write(output.Schema())
+			return err
+		},
+	}
+}
+
+// addOutputFlag registers --output for commands with machine-readable output.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output", string(output.FormatText), "Output format: text (KEY=VALUE lines) or json (see 'capsule schema')")
+}
+
+// outputFormat returns the format chosen with --output.
+func outputFormat(cmd *cobra.Command) (output.Format, error) {
+	flag, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", fmt.Errorf("invalid output flag: %w", err)
+	}
+	format, err := output.ParseFormat(flag)
+	if err != nil {
+		return "", fmt.Errorf("invalid output flag: %w", err)
+	}
+	return format, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/secretscan"
+)
+
+// Secret scan modes for --scan and git config capsule.secretScan.
+const (
+	scanModeOff   = "off"
+	scanModeWarn  = "warn"
+	scanModeBlock = "block"
+)
+
+// sessionStartMargin widens the scan window to cover clock drift between the
+// host and Docker Desktop's VM.
+const sessionStartMargin = time.Minute
+
+// addScanFlag adds --scan to a command that ends a session.
+func addScanFlag(cmd *cobra.Command) {
+	cmd.Flags().String("scan", "",
+		"Scan the workspace for leaked credentials first: warn or block (default: git config "+repo.SecretScanKey+", else off)")
+	cmd.Flags().Lookup("scan").NoOptDefVal = scanModeWarn
+}
+
+func newScanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan [path]",
+		Short: "Scan the workspace for leaked credentials",
+		Long: `Looks for API keys, tokens, and private keys in the workspace, which is the
+unencrypted side of a session. While the workspace's container is running, only
+files modified since it started are scanned; use --all to scan every file.
+
+Lines containing '` + secretscan.AllowComment + `' are ignored.
+
+'capsule stop --scan' and 'capsule lock --scan' run the same scan first; with
+--scan=block they refuse to continue while credentials are found. Set a default
+per repository with:
+  git config ` + repo.SecretScanKey + ` warn|block`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runScan,
+	}
+
+	cmd.Flags().Bool("all", false, "Scan every file, not just those modified during the session")
+
+	return cmd
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+
+	containerName, cwd, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	root := cwd
+	if len(args) == 1 {
+		root = args[0]
+	} else if workspacePath, err := newRepoIdentifier().GetWorkspaceRoot(cwd); err == nil {
+		root = workspacePath
+	}
+
+	var since time.Time
+	if !all {
+		since = sessionStart(cmd.Context(), docker.NewManager(), containerName)
+	}
+
+	findings, err := secretscan.Scan(root, secretscan.Options{Since: since})
+	if err != nil {
+		return err
+	}
+	printSecretFindings(root, since, findings)
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d possible credential(s)", len(findings))
+	}
+	return nil
+}
+
+// scanWorkspaceBeforeLock runs the --scan check for stop and lock. It returns an
+// error only in block mode when credentials are found, so the session is left
+// running for the user to clean up.
+func scanWorkspaceBeforeLock(cmd *cobra.Command, cwd, containerName string) error {
+	mode, err := cmd.Flags().GetString("scan")
+	if err != nil {
+		return fmt.Errorf("invalid scan flag: %w", err)
+	}
+
+	workspacePath, err := newRepoIdentifier().GetWorkspaceRoot(cwd)
+	if err != nil {
+		workspacePath = cwd
+	}
+	if mode == "" {
+		mode = repo.LoadSecretScanMode(workspacePath)
+	}
+	switch mode {
+	case "", scanModeOff:
+		return nil
+	case scanModeWarn, scanModeBlock:
+	default:
+		return fmt.Errorf("invalid scan mode %q: use %s, %s, or %s", mode, scanModeWarn, scanModeBlock, scanModeOff)
+	}
+
+	since := sessionStart(cmd.Context(), docker.NewManager(), containerName)
+	findings, err := secretscan.Scan(workspacePath, secretscan.Options{Since: since})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: secret scan failed: %v\n", err)
+		return nil
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	printSecretFindings(workspacePath, since, findings)
+	if mode == scanModeBlock {
+		return fmt.Errorf("found %d possible credential(s) in the workspace; remove them (or mark intended ones with %s) and try again, or pass --scan=%s",
+			len(findings), secretscan.AllowComment, scanModeOff)
+	}
+	fmt.Fprintln(os.Stderr, "These files are not encrypted and remain on disk after the volume is locked.")
+	return nil
+}
+
+// sessionStart returns when the workspace's container started, or zero (scan
+// everything) if it isn't running.
+func sessionStart(ctx context.Context, dockerManager docker.DockerManager, containerName string) time.Time {
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return time.Time{}
+	}
+	startedAt, err := dockerManager.StartedAt(ctx, containerName)
+	if err != nil {
+		return time.Time{}
+	}
+	return startedAt.Add(-sessionStartMargin)
+}
+
+// printSecretFindings writes a scan report to stderr.
+func printSecretFindings(root string, since time.Time, findings []secretscan.Finding) {
+	scope := "all files"
+	if !since.IsZero() {
+		scope = "files changed this session"
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(os.Stderr, "No credentials found in %s (%s).\n", root, scope)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nPossible credentials in %s (%s):\n", root, scope)
+	for _, f := range findings {
+		fmt.Fprintf(os.Stderr, "  %s:%d  %s  %s\n", f.Path, f.Line, f.Description, f.Redacted)
+	}
+	fmt.Fprintln(os.Stderr)
+}
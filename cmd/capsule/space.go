@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// watchVolumeSpace warns on stderr when the mounted volume runs low on space
+// during a session, and grows it if auto_expand_max_gb is configured. The
+// returned function stops watching.
+func watchVolumeSpace(ctx context.Context, volumeManager volume.VolumeManager, volumePath, mountPoint string) func() {
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not watching volume space: %v\n", err)
+		return func() {}
+	}
+	threshold := settings.LowSpaceThreshold()
+	if threshold == 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	watch := &volume.SpaceWatch{
+		MountPoint: mountPoint,
+		LowPercent: threshold,
+		OnLow: func(c volume.Capacity) {
+			fmt.Fprintf(os.Stderr, "\n[capsule] Volume is low on space: %s free of %s (%d%%).\n",
+				formatBytes(c.FreeBytes), formatBytes(c.TotalBytes), c.FreePercent())
+			expandVolume(ctx, volumeManager, volumePath, c, settings.AutoExpandMaxGB)
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// expandVolume grows a nearly full volume up to maxGB, or tells the user how
+// to make room when auto-expand is off or the volume is at its cap.
+func expandVolume(ctx context.Context, volumeManager volume.VolumeManager, volumePath string, c volume.Capacity, maxGB int) {
+	if maxGB == 0 {
+		fmt.Fprintln(os.Stderr, "[capsule] Free up space, or lock the volume and grow it (see 'capsule df').")
+		return
+	}
+	sizeGB := volume.ExpandedSizeGB(c.TotalBytes, maxGB)
+	if sizeGB == 0 {
+		fmt.Fprintf(os.Stderr, "[capsule] The volume is already at auto_expand_max_gb (%d GB); free up space.\n", maxGB)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[capsule] Expanding the volume to %d GB...\n", sizeGB)
+	if err := volumeManager.Resize(ctx, volumePath, sizeGB); err != nil {
+		fmt.Fprintf(os.Stderr, "[capsule] Could not expand the volume: %v\n", err)
+		fmt.Fprintf(os.Stderr, "[capsule] Lock it, then run: hdiutil resize -size %dg %s\n", sizeGB, volumePath)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[capsule] Volume expanded to %d GB.\n", sizeGB)
+}
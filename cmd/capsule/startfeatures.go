@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/clipboard"
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/dotfiles"
+	"github.com/jeanhaley32/claude-capsule/internal/gitidentity"
+	"github.com/jeanhaley32/claude-capsule/internal/openbridge"
+	"github.com/jeanhaley32/claude-capsule/internal/signproxy"
+	"github.com/spf13/cobra"
+)
+
+// startFeatures are the optional parts of a session that 'capsule start'
+// sets up around the container.
+type startFeatures struct {
+	gitIdentity   bool             // --git-identity
+	dotfiles      []dotfiles.Entry // The dotfiles setting
+	ramAuth       bool             // --ram-auth
+	sign          bool             // --sign
+	clipboard     clipboard.Mode   // --clipboard, or the clipboard setting
+	browserBridge bool             // Off with --no-browser-bridge or the no_browser_bridge setting
+}
+
+// shellOptions are the settings of an interactive session, which 'capsule
+// run' doesn't have.
+type shellOptions struct {
+	record         bool          // --record, or the record_sessions setting
+	detach         bool          // --detach
+	keepAlive      bool          // --keep-alive, or the keep_alive setting
+	keepAliveGrace time.Duration // How long a kept-alive container waits for a shell; 0 waits forever
+}
+
+// parseShellFlags reads the flags of an interactive session, with the
+// bridges it sets in features. A detached session has neither bridges nor
+// a recording, as they run in this process, which exits once the container
+// is up.
+func parseShellFlags(cmd *cobra.Command, settings *config.Settings, features *startFeatures) (shellOptions, error) {
+	var shell shellOptions
+	noBrowserBridge, err := cmd.Flags().GetBool("no-browser-bridge")
+	if err != nil {
+		return shell, fmt.Errorf("invalid no-browser-bridge flag: %w", err)
+	}
+	features.browserBridge = !noBrowserBridge && !settings.NoBrowserBridge
+
+	record, err := cmd.Flags().GetBool("record")
+	if err != nil {
+		return shell, fmt.Errorf("invalid record flag: %w", err)
+	}
+	shell.record = record || settings.RecordSessions
+
+	if features.clipboard, err = clipboard.ParseMode(settings.Clipboard); err != nil {
+		return shell, fmt.Errorf("invalid clipboard in %s: %w", config.SettingsFile, err)
+	}
+	if cmd.Flags().Changed("clipboard") {
+		clipboardFlag, err := cmd.Flags().GetString("clipboard")
+		if err != nil {
+			return shell, fmt.Errorf("invalid clipboard flag: %w", err)
+		}
+		if features.clipboard, err = clipboard.ParseMode(clipboardFlag); err != nil {
+			return shell, fmt.Errorf("invalid clipboard flag: %w", err)
+		}
+	}
+
+	if shell.detach, err = cmd.Flags().GetBool("detach"); err != nil {
+		return shell, fmt.Errorf("invalid detach flag: %w", err)
+	}
+	if shell.detach {
+		if features.sign {
+			return shell, fmt.Errorf("--sign needs an attached session and cannot be used with --detach")
+		}
+		if cmd.Flags().Changed("clipboard") && features.clipboard != clipboard.ModeOff {
+			return shell, fmt.Errorf("--clipboard needs an attached session and cannot be used with --detach")
+		}
+		if record {
+			return shell, fmt.Errorf("--record needs an attached session and cannot be used with --detach")
+		}
+		shell.record, features.clipboard, features.browserBridge = false, clipboard.ModeOff, false
+	}
+
+	if shell.keepAlive, shell.keepAliveGrace, err = config.ParseKeepAlive(settings.KeepAlive); err != nil {
+		return shell, fmt.Errorf("invalid keep_alive in %s: %w", config.SettingsFile, err)
+	}
+	if cmd.Flags().Changed("keep-alive") {
+		keepAliveFlag, err := cmd.Flags().GetString("keep-alive")
+		if err != nil {
+			return shell, fmt.Errorf("invalid keep-alive flag: %w", err)
+		}
+		if shell.keepAlive, shell.keepAliveGrace, err = config.ParseKeepAlive(keepAliveFlag); err != nil {
+			return shell, fmt.Errorf("invalid keep-alive flag: %w", err)
+		}
+	}
+	return shell, nil
+}
+
+// applyUntrustedRestrictions turns off the features an untrusted container
+// can't have, saying which of those that were asked for are skipped.
+func (f *startFeatures) applyUntrustedRestrictions() {
+	if f.gitIdentity {
+		infoln("Skipping --git-identity: untrusted containers do not use the volume's home directory.")
+		f.gitIdentity = false
+	}
+	if len(f.dotfiles) > 0 {
+		infoln("Skipping dotfiles: untrusted containers do not use the volume's home directory.")
+		f.dotfiles = nil
+	}
+	if f.clipboard != clipboard.ModeOff {
+		infoln("Skipping the clipboard bridge: untrusted containers cannot reach the host clipboard.")
+		f.clipboard = clipboard.ModeOff
+	}
+	if f.ramAuth {
+		infoln("Skipping --ram-auth: untrusted containers do not mount the volume's auth directory.")
+		f.ramAuth = false
+	}
+	if f.sign {
+		infoln("Skipping --sign: untrusted containers cannot use the host's signing key.")
+		f.sign = false
+	}
+	// Untrusted containers could use the host browser for phishing. The
+	// bridge is on by default, so skipping it goes unmentioned.
+	f.browserBridge = false
+}
+
+// prepareHome writes the host's git identity and the dotfiles into the home
+// directory on the volume mounted at mountPoint.
+func prepareHome(mountPoint string, features startFeatures, identity gitidentity.Identity) error {
+	if features.gitIdentity {
+		if err := gitidentity.Configure(mountPoint, identity); err != nil {
+			return fmt.Errorf("failed to configure git identity: %w", err)
+		}
+		infof("Git identity: %s <%s>\n", identity.Name, identity.Email)
+	}
+	if len(features.dotfiles) > 0 {
+		return syncDotfiles(mountPoint, features.dotfiles)
+	}
+	return nil
+}
+
+// setupBridges starts the host side of the signing proxy, clipboard bridge,
+// and browser bridge, as features asks, and points containerConfig at their
+// sockets. The returned function stops them; on error, the ones already
+// started are stopped.
+func setupBridges(containerName, workspacePath, signingKey string, features startFeatures, containerConfig *docker.ContainerConfig) (closeBridges func(), err error) {
+	var servers []interface{ Close() error }
+	closeBridges = func() {
+		for _, s := range servers {
+			s.Close()
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeBridges()
+		}
+	}()
+
+	// Signatures are made host-side; the container only gets a socket to ask for them
+	if features.sign {
+		socketPath, err := signproxy.SocketPath(containerName)
+		if err != nil {
+			return nil, err
+		}
+		signServer, err := signproxy.Listen(socketPath, signingKey, gitidentity.HostGPGProgram(workspacePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to start signing proxy: %w", err)
+		}
+		servers = append(servers, signServer)
+		go signServer.Serve()
+
+		containerConfig.SigningSocket = socketPath
+		containerConfig.SigningSocketTarget = signproxy.ContainerSocketPath
+		containerConfig.Env = append(containerConfig.Env, signServer.GitEnv()...)
+		infof("Signing proxy ready (key %s).\n", signingKey)
+	}
+
+	// pbcopy and pbpaste in the container reach the host clipboard through a socket
+	if features.clipboard != clipboard.ModeOff {
+		socketPath, err := clipboard.SocketPath(containerName)
+		if err != nil {
+			return nil, err
+		}
+		clipboardServer, err := clipboard.Listen(socketPath, features.clipboard)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start clipboard bridge: %w", err)
+		}
+		servers = append(servers, clipboardServer)
+		go clipboardServer.Serve()
+
+		containerConfig.ClipboardSocket = socketPath
+		containerConfig.ClipboardSocketTarget = clipboard.ContainerSocketPath
+		infof("Clipboard bridge ready (%s).\n", features.clipboard)
+	}
+
+	// URLs opened in the container, such as Claude Code's login, open in the host browser
+	if features.browserBridge {
+		socketPath, err := openbridge.SocketPath(containerName)
+		if err != nil {
+			return nil, err
+		}
+		openServer, err := openbridge.Listen(socketPath, containerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start browser bridge: %w", err)
+		}
+		servers = append(servers, openServer)
+		go openServer.Serve()
+
+		containerConfig.OpenSocket = socketPath
+		containerConfig.OpenSocketTarget = openbridge.ContainerSocketPath
+		containerConfig.Env = append(containerConfig.Env, "BROWSER="+openbridge.ShimPath)
+	}
+	return closeBridges, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/startstate"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// recoverInterruptedStart rolls back a start of the same container that left
+// its state file behind because the process crashed or was killed. It fails
+// if that start is still running in another process.
+func recoverInterruptedStart(ctx context.Context, statePath string, dockerManager docker.DockerManager, volumeManager volume.VolumeManager) error {
+	prev, err := startstate.Load(statePath)
+	if err != nil {
+		slog.Warn("ignoring unreadable start state", "error", err)
+		return nil
+	}
+	if prev == nil {
+		return nil
+	}
+	if prev.PID != os.Getpid() && prev.Alive() {
+		if prev.Phase != startstate.PhaseAttached {
+			return fmt.Errorf("another capsule start (pid %d) is starting %s; wait for it to finish", prev.PID, prev.Container)
+		}
+		// A session attached in another terminal; the usual re-entry handling applies
+		return nil
+	}
+	infof("Recovering from an interrupted start of %s (stopped after %s)...\n", prev.Container, prev.Phase)
+	rollbackStart(ctx, *prev, dockerManager, volumeManager)
+	return nil
+}
+
+// rollbackStart undoes what a failed or interrupted start did, as recorded in
+// its state. The volume is left mounted if it has since been remounted
+// elsewhere or another container is using it.
+func rollbackStart(ctx context.Context, s startstate.State, dockerManager docker.DockerManager, volumeManager volume.VolumeManager) {
+	for _, step := range s.Rollback() {
+		switch step {
+		case startstate.StepRemoveContainer:
+			infoln("Cleaning up failed container...")
+			if err := dockerManager.RemoveContainer(ctx, s.Container); err != nil {
+				// The start may have failed before the container existed
+				slog.Debug("container removal failed during rollback", "container", s.Container, "error", err)
+			}
+		case startstate.StepUnmount:
+			if filepath.Clean(volumeManager.GetMountPoint(ctx, s.VolumePath)) != filepath.Clean(s.MountPoint) {
+				continue
+			}
+			if user := mountPointUser(ctx, dockerManager, s.MountPoint, s.Container); user != "" {
+				infof("Leaving volume mounted: it is in use by %s.\n", user)
+				continue
+			}
+			if err := volumeManager.Unmount(ctx, s.MountPoint); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: volume unmount failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// mountPointUser returns a running container other than exclude that
+// bind-mounts something inside mountPoint, or "" if there is none.
+func mountPointUser(ctx context.Context, dockerManager docker.DockerManager, mountPoint, exclude string) string {
+	names, err := dockerManager.ListRunning(ctx)
+	if err != nil {
+		return ""
+	}
+	for _, name := range names {
+		if name == exclude {
+			continue
+		}
+		if sources, err := dockerManager.MountSources(ctx, name); err == nil && mountsPath(sources, mountPoint) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/envfile"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// heldVolumeEnv names, for each start 'capsule start --workspaces-file' runs,
+// the volume it holds mounted for them. A start doesn't unmount that volume
+// to retry after a Docker mount cache conflict, as the others are using it.
+const heldVolumeEnv = "CAPSULE_HELD_VOLUME"
+
+// workspaceStart is one workspace brought up by 'capsule start
+// --workspaces-file'.
+type workspaceStart struct {
+	dir           string // As listed in the file; the start runs here
+	workspacePath string // The workspace root the start resolves dir to
+	containerName string
+	volumePath    string
+	wasRunning    bool
+
+	err     error
+	output  string // What the start printed, for a failure
+	elapsed time.Duration
+}
+
+// run starts the workspace's container with 'capsule start --detach' in its
+// directory, with args and the workspace's volume, which the caller holds
+// mounted. Its output is kept for the summary rather than interleaved with
+// the other starts'.
+func (s *workspaceStart) run(executable string, args []string) {
+	args = append([]string{"start", "--volume=" + s.volumePath}, args...)
+	// Not tied to ctx: Ctrl+C reaches the starts too, as they share the
+	// terminal, and a second signal would make them exit without cleaning up
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = s.dir
+	cmd.Env = append(os.Environ(), heldVolumeEnv+"="+s.volumePath)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	started := time.Now()
+	done := logging.Command(cmd)
+	s.err = cmd.Run()
+	done(s.err)
+	s.elapsed = time.Since(started)
+	s.output = strings.TrimSpace(out.String())
+}
+
+// runStartWorkspaces brings up the container of every workspace listed in
+// listPath at once, detached. The volumes they use are mounted first, asking
+// for one password that is tried on each and only asking again for a volume
+// it doesn't open, and images are built once, so the concurrent starts never
+// prompt or build.
+func runStartWorkspaces(cmd *cobra.Command, listPath string) error {
+	ctx := cmd.Context()
+	if cmd.Flags().Changed("workspace") {
+		return fmt.Errorf("--workspaces-file cannot be used with --workspace")
+	}
+	if cmd.Flags().Changed("dry-run") {
+		return fmt.Errorf("--workspaces-file cannot be used with --dry-run")
+	}
+	for _, name := range []string{"record", "sign", "clipboard"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s needs an attached session and cannot be used with --workspaces-file, which starts detached", name)
+		}
+	}
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	untrusted, err := cmd.Flags().GetBool("untrusted")
+	if err != nil {
+		return fmt.Errorf("invalid untrusted flag: %w", err)
+	}
+	identityVolumeFlag, err := cmd.Flags().GetString("identity-volume")
+	if err != nil {
+		return fmt.Errorf("invalid identity-volume flag: %w", err)
+	}
+	flavorFlag, err := cmd.Flags().GetString("flavor")
+	if err != nil {
+		return fmt.Errorf("invalid flavor flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	dirs, err := readWorkspacesFile(listPath)
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadDefaultSettings()
+	if err != nil {
+		return err
+	}
+	buildOptions, err := imageBuildOptions(ctx, settings, flavorFlag)
+	if err != nil {
+		return err
+	}
+
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	dockerManager := docker.NewManager()
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	// Resolve each workspace as a start run in its directory would
+	starts := make([]*workspaceStart, len(dirs))
+	byContainer := make(map[string]string)
+	var volumePaths []string
+	for i, dir := range dirs {
+		identifier := repo.NewIdentifierWithPolicy(worktreePolicy)
+		identifier.Subproject = subproject
+		if identifier.Subproject == "" {
+			identifier.Subproject = repo.DetectSubproject(dir)
+		}
+		workspaces, err := resolveWorkspaces(identifier, nil, dir)
+		if err != nil {
+			return err
+		}
+		containerName, err := identifier.GetContainerName(workspaces[0].Path)
+		if err != nil {
+			return fmt.Errorf("failed to generate container name: %w", err)
+		}
+		if other, ok := byContainer[containerName]; ok {
+			return fmt.Errorf("%s and %s share container %s; list one of them", other, dir, containerName)
+		}
+		byContainer[containerName] = dir
+		volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, dir)
+		if err != nil {
+			return err
+		}
+		// A relative --volume is relative to here, not to the start's directory
+		if volumePath, err = filepath.Abs(volumePath); err != nil {
+			return fmt.Errorf("invalid volume path: %w", err)
+		}
+		if !slices.Contains(volumePaths, volumePath) {
+			volumePaths = append(volumePaths, volumePath)
+		}
+		starts[i] = &workspaceStart{
+			dir:           dir,
+			workspacePath: workspaces[0].Path,
+			containerName: containerName,
+			volumePath:    volumePath,
+			wasRunning:    dockerManager.IsRunning(ctx, containerName),
+		}
+	}
+
+	// Ask now, one workspace at a time, rather than in the starts
+	if !untrusted {
+		for _, s := range starts {
+			if err := confirmWorkspaceTrust(s.workspacePath); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Hold the volumes until every start is done; the starts share the locks
+	for _, volumePath := range volumePaths {
+		releaseLock, err := lockOperation(cmd.CommandPath(), "", volumePath)
+		if err != nil {
+			return err
+		}
+		defer releaseLock()
+	}
+	mountPoints, err := mountWorkspaceVolumes(ctx, volumeManager, volumePaths, passwordFile)
+	if err != nil {
+		return err
+	}
+	var identityPath string
+	if !untrusted {
+		identityPath, err = resolveIdentityVolume(pathResolver, identityVolumeFlag, settings)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(volumePaths, identityPath) {
+			return fmt.Errorf("%s is both a volume and the identity volume", identityPath)
+		}
+		if identityPath != "" {
+			if _, err := mountIdentityVolume(ctx, volumeManager, identityPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Build each image the volumes need once, instead of once per start
+	built := make(map[string]bool)
+	for _, volumePath := range volumePaths {
+		options := buildOptions
+		if options.Template, err = embedded.ReadTemplateFile(mountPoints[volumePath]); err != nil {
+			return err
+		}
+		imageName := docker.ImageName(options.Flavor, options.Template)
+		if built[imageName] {
+			continue
+		}
+		if err := ensureImage(ctx, imageName, options); err != nil {
+			return err
+		}
+		built[imageName] = true
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the capsule executable: %w", err)
+	}
+	args, err := forwardedStartArgs(cmd, identityPath)
+	if err != nil {
+		return err
+	}
+	infof("Starting %d workspaces...\n", len(starts))
+	var wg sync.WaitGroup
+	for _, s := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.run(executable, args)
+			if s.err != nil {
+				infof("  %s failed after %s\n", s.workspacePath, s.elapsed.Round(100*time.Millisecond))
+			} else {
+				infof("  %s is up (%s)\n", s.workspacePath, s.elapsed.Round(100*time.Millisecond))
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed, err := printWorkspaceStarts(ctx, dockerManager, starts, mountPoints)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspaces failed to start", failed, len(starts))
+	}
+	fmt.Println("\nAttach to one with 'capsule enter' in its directory.")
+	return nil
+}
+
+// mountWorkspaceVolumes mounts each of volumePaths that isn't mounted, and
+// returns every volume's mount point. The password read for the first one
+// is tried on the rest, so volumes sharing a password are unlocked with one
+// prompt; a volume it doesn't open is asked for by name.
+func mountWorkspaceVolumes(ctx context.Context, volumeManager volume.VolumeManager, volumePaths []string, passwordFile string) (map[string]string, error) {
+	mountPoints := make(map[string]string)
+	var shared *terminal.SecurePassword
+	defer func() {
+		if shared != nil {
+			shared.Clear()
+		}
+	}()
+	for _, volumePath := range volumePaths {
+		if mountPoint := volumeManager.GetMountPoint(ctx, volumePath); mountPoint != "" {
+			infof("Volume %s already mounted at %s\n", volumePath, mountPoint)
+			mountPoints[volumePath] = mountPoint
+			continue
+		}
+		if shared == nil {
+			password, err := readVolumePassword(passwordFile, "Enter volume password: ")
+			if err != nil {
+				return nil, fmt.Errorf("password error: %w", err)
+			}
+			shared = password
+		}
+
+		infof("Mounting %s...\n", volumePath)
+		mountPoint, err := volumeManager.Mount(ctx, volumePath, shared)
+		if errors.Is(err, volume.ErrAuthFailed) && len(volumePaths) > 1 && passwordFile == "" {
+			password, err2 := readVolumePassword("", fmt.Sprintf("Enter password for %s: ", volumePath))
+			if err2 != nil {
+				return nil, fmt.Errorf("password error: %w", err2)
+			}
+			mountPoint, err = volumeManager.Mount(ctx, volumePath, password)
+			password.Clear()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to mount %s: %w", volumePath, err)
+		}
+		infof("Volume mounted at %s\n", mountPoint)
+		mountPoints[volumePath] = mountPoint
+	}
+	return mountPoints, nil
+}
+
+// forwardedStartArgs returns the arguments of each workspace's own start:
+// the flags given to 'capsule start --workspaces-file', other than the ones
+// it handles itself, with --detach and --quiet. The starts run in their
+// workspaces' directories, so paths are made absolute, and the identity
+// volume is passed as identityPath, the one already mounted. The volume is
+// left to the caller, as it can differ between workspaces.
+func forwardedStartArgs(cmd *cobra.Command, identityPath string) ([]string, error) {
+	args := []string{"--detach", "--quiet"}
+	if identityPath != "" {
+		args = append(args, "--identity-volume="+identityPath)
+	}
+	var err error
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "workspaces-file", "password-file", "detach", "quiet", "volume", "identity-volume":
+			return
+		}
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range values.GetSlice() {
+				if f.Name == "env-file" && !envfile.IsVolumePath(v) {
+					abs, absErr := filepath.Abs(v)
+					if absErr != nil {
+						err = fmt.Errorf("invalid env-file %s: %w", v, absErr)
+						return
+					}
+					v = abs
+				}
+				args = append(args, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args, err
+}
+
+// printWorkspaceStarts prints a table of the starts, then the output of the
+// ones that failed, and returns how many failed.
+func printWorkspaceStarts(ctx context.Context, dockerManager docker.DockerManager, starts []*workspaceStart, mountPoints map[string]string) (int, error) {
+	var failed int
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WORKSPACE\tCONTAINER\tSTATUS\tTIME\tMOUNT")
+	for _, s := range starts {
+		status := "started"
+		switch {
+		case s.err != nil:
+			status = "failed"
+			failed++
+		case s.wasRunning:
+			status = "already running"
+		case !dockerManager.IsRunning(ctx, s.containerName):
+			status = "not running"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.workspacePath, s.containerName, status,
+			s.elapsed.Round(100*time.Millisecond), mountPoints[s.volumePath])
+	}
+	if err := tw.Flush(); err != nil {
+		return failed, err
+	}
+	for _, s := range starts {
+		if s.err == nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "\n%s:\n", s.workspacePath)
+		if s.output != "" {
+			fmt.Fprintln(os.Stderr, s.output)
+		} else {
+			fmt.Fprintln(os.Stderr, s.err)
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestForwardedStartArgs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	// The working directory may be a symlink, as on macOS
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newStartCmd()
+	err = cmd.ParseFlags([]string{
+		"--workspaces-file=workspaces.txt",
+		"--password-file=/tmp/password",
+		"--volume=team",
+		"--identity-volume=other",
+		"--detach=false",
+		"--untrusted",
+		"--env=A=1",
+		"--env=B",
+		"--env-file=local.env",
+		"--env-file=volume:shared.env",
+		"--restart=unless-stopped",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := forwardedStartArgs(cmd, "/volumes/identity.sparseimage")
+	if err != nil {
+		t.Fatalf("forwardedStartArgs() error = %v", err)
+	}
+	want := []string{
+		"--detach",
+		"--quiet",
+		"--identity-volume=/volumes/identity.sparseimage",
+		"--env=A=1",
+		"--env=B",
+		"--env-file=" + filepath.Join(cwd, "local.env"),
+		"--env-file=volume:shared.env",
+		"--restart=unless-stopped",
+		"--untrusted=true",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("forwardedStartArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestForwardedStartArgsWithoutIdentity(t *testing.T) {
+	cmd := newStartCmd()
+	if err := cmd.ParseFlags([]string{"--workspaces-file=workspaces.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := forwardedStartArgs(cmd, "")
+	if err != nil {
+		t.Fatalf("forwardedStartArgs() error = %v", err)
+	}
+	if want := []string{"--detach", "--quiet"}; !slices.Equal(got, want) {
+		t.Errorf("forwardedStartArgs() = %q, want %q", got, want)
+	}
+}
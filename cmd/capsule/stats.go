@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+)
+
+func newStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show CPU, memory, and I/O of the workspace's container",
+		Long: `Shows live resource usage for the container of the current workspace,
+refreshing every --interval until interrupted. With --once, prints a single
+sample and exits; add --json for a machine-readable sample.
+
+Without a terminal, each refresh is printed as one line (or one JSON object
+per line with --json).`,
+		Args: cobra.NoArgs,
+		RunE: runStats,
+	}
+
+	cmd.Flags().Bool("once", false, "Print one sample and exit")
+	cmd.Flags().Bool("json", false, "Print samples as JSON")
+	cmd.Flags().Duration("interval", constants.StatsInterval, "Refresh interval")
+
+	return cmd
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	once, err := cmd.Flags().GetBool("once")
+	if err != nil {
+		return fmt.Errorf("invalid once flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("invalid json flag: %w", err)
+	}
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return fmt.Errorf("invalid interval flag: %w", err)
+	}
+	if interval < time.Second {
+		return fmt.Errorf("invalid interval %s: must be at least 1s", interval)
+	}
+
+	containerName, _, err := getContainerNameForCwd()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	dockerManager := docker.NewManager()
+	if !dockerManager.IsRunning(ctx, containerName) {
+		return fmt.Errorf("container %s is not running; start it with 'capsule start'", containerName)
+	}
+
+	live := !once && !asJSON && term.IsTerminal(int(os.Stdout.Fd()))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := dockerManager.Stats(ctx, containerName)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case asJSON:
+			if err := json.NewEncoder(os.Stdout).Encode(stats); err != nil {
+				return err
+			}
+		case live:
+			fmt.Print(ansiClear)
+			fmt.Printf("Container %s (every %s, Ctrl+C to stop)  %s\n\n", containerName, interval, time.Now().Format(time.TimeOnly))
+			printStats(stats)
+		default:
+			fmt.Printf("%s  %s\n", time.Now().Format(time.TimeOnly), statsLine(stats))
+		}
+		if once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printStats prints one sample as aligned fields for the live view.
+func printStats(stats docker.ContainerStats) {
+	fmt.Printf("%-12s%.1f%%\n", "CPU:", stats.CPUPercent)
+	fmt.Printf("%-12s%s / %s (%.1f%%)\n", "Memory:", formatBytes(stats.MemoryBytes), formatBytes(stats.MemoryLimit), stats.MemoryPercent)
+	fmt.Printf("%-12s%s in, %s out\n", "Network:", formatBytes(stats.NetRxBytes), formatBytes(stats.NetTxBytes))
+	fmt.Printf("%-12s%s read, %s written\n", "Disk:", formatBytes(stats.BlockRead), formatBytes(stats.BlockWrite))
+	fmt.Printf("%-12s%d\n", "Processes:", stats.PIDs)
+}
+
+// statsLine formats one sample on a single line for logs and pipes.
+func statsLine(stats docker.ContainerStats) string {
+	return fmt.Sprintf("cpu %.1f%%  mem %s (%.1f%%)  net %s/%s  disk %s/%s  pids %d",
+		stats.CPUPercent, formatBytes(stats.MemoryBytes), stats.MemoryPercent,
+		formatBytes(stats.NetRxBytes), formatBytes(stats.NetTxBytes),
+		formatBytes(stats.BlockRead), formatBytes(stats.BlockWrite), stats.PIDs)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/history"
+	"github.com/jeanhaley32/claude-capsule/internal/state"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// printStatusAll shows every known volume, every mounted capsule volume, and
+// every capsule container, with the workspaces each container serves.
+// volumePathFlag, if set, is listed among the known volumes.
+func printStatusAll(ctx context.Context, volumePathFlag string) error {
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	dockerManager := docker.NewManager()
+	dockerRunning := state.CheckDockerRunning() == nil
+
+	var mounted []volume.MountedVolume
+	listedMounts := false
+	if volumeManager, err := volume.New(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't list mounted volumes: %v\n", err)
+	} else if mounted, err = volumeManager.ListMounted(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else {
+		listedMounts = true
+	}
+	mountPoints := make(map[string]string)
+	for _, v := range mounted {
+		if v.ImagePath != "" {
+			mountPoints[filepath.Clean(v.ImagePath)] = v.MountPoint
+		}
+	}
+
+	var containers []containerStatus
+	if dockerRunning {
+		if containers, err = listContainerStatus(ctx, dockerManager); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	// Workspaces from the history and the containers may each have a local volume
+	var dirs []string
+	if path, err := history.DefaultPath(); err == nil {
+		if sessions, err := history.Load(path, nil); err == nil {
+			for _, s := range sessions {
+				dirs = append(dirs, s.Workspaces...)
+			}
+		}
+	}
+	for _, c := range containers {
+		dirs = append(dirs, c.workspaces...)
+	}
+	volumePaths := pathResolver.KnownVolumePaths(dirs)
+	if volumePathFlag != "" {
+		volumePaths = append([]string{pathResolver.ResolveName(volumePathFlag)}, volumePaths...)
+	}
+
+	var rows [][]string
+	var warnings []string
+	months, now := staleVolumeMonths(), time.Now()
+	for _, path := range volumePaths {
+		mountPoint := mountPoints[filepath.Clean(path)]
+		lastUsed := "-"
+		if t, err := volume.LastUsed(path); err == nil {
+			lastUsed = t.Local().Format("2006-01-02")
+		}
+		rows = append(rows, []string{path, orDash(mountPoint), lastUsed, orDash(volumeContents(mountPoint))})
+		if listedMounts && mountPoint == "" {
+			if warning := staleVolumeWarning(path, months, now); warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+	if err := printStatusTable([]string{"VOLUME", "MOUNTED AT", "LAST USED", "CONTENTS"}, rows, "No volumes found."); err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Println("\n" + warning)
+	}
+	fmt.Println()
+
+	rows = nil
+	for _, v := range mounted {
+		rows = append(rows, []string{v.MountPoint, orDash(v.ImagePath)})
+	}
+	if err := printStatusTable([]string{"MOUNT POINT", "VOLUME"}, rows, "No capsule volumes mounted."); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if !dockerRunning {
+		fmt.Println("Warning: Docker is not running; containers not shown.")
+		return nil
+	}
+	rows = nil
+	for _, c := range containers {
+		rows = append(rows, []string{c.name, c.status, orDash(strings.Join(c.workspaces, ", "))})
+	}
+	return printStatusTable([]string{"CONTAINER", "STATUS", "WORKSPACE"}, rows, "No capsule containers.")
+}
+
+// printStatusTable prints rows under header in aligned columns, or none if
+// there are no rows.
+func printStatusTable(header []string, rows [][]string, none string) error {
+	if len(rows) == 0 {
+		fmt.Println(none)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// containerStatus is one capsule container in 'capsule status --all'.
+type containerStatus struct {
+	name       string
+	status     string
+	workspaces []string
+}
+
+// listContainerStatus returns the running and exited capsule containers.
+// Their workspaces come from the label set at creation, or for a container
+// created before capsule labeled them, from its /workspace mount.
+func listContainerStatus(ctx context.Context, dockerManager docker.DockerManager) ([]containerStatus, error) {
+	running, err := dockerManager.ListRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exited, err := dockerManager.ListExited(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var containers []containerStatus
+	for _, name := range running {
+		containers = append(containers, containerStatus{name: name, status: "running"})
+	}
+	for _, c := range exited {
+		containers = append(containers, containerStatus{name: c.Name, status: c.Status})
+	}
+	for i, c := range containers {
+		if labels, err := dockerManager.Labels(ctx, c.name); err == nil && labels[constants.LabelWorkspace] != "" {
+			containers[i].workspaces = filepath.SplitList(labels[constants.LabelWorkspace])
+		} else if mount, err := dockerManager.WorkspaceMount(ctx, c.name); err == nil && mount != "" {
+			containers[i].workspaces = []string{mount}
+		}
+	}
+	return containers, nil
+}
+
+// volumeContents summarizes the metadata of the volume mounted at
+// mountPoint, or returns "" if it isn't mounted or has none.
+func volumeContents(mountPoint string) string {
+	if mountPoint == "" {
+		return ""
+	}
+	metadata, err := volume.ReadMetadata(mountPoint)
+	if err != nil || metadata == nil {
+		return ""
+	}
+	return metadata.String()
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
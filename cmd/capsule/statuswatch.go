@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/state"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// maxStatusTransitions is how many recent transitions 'status --watch' lists.
+const maxStatusTransitions = 10
+
+// ANSI sequences for the terminal display.
+const (
+	ansiClear     = "\033[H\033[2J"
+	ansiHighlight = "\033[1;33m"
+	ansiReset     = "\033[0m"
+)
+
+// watchStatus refreshes the status every interval until interrupted. On a
+// terminal it redraws the screen, highlighting fields that changed in the last
+// few refreshes; otherwise it prints the status once and then one line per
+// transition, which suits piping to a file.
+func watchStatus(ctx context.Context, volumeManager volume.VolumeManager, volumePath, containerName, cwd string, interval time.Duration) error {
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev []state.Field
+	var transitions []string
+	changedAt := make(map[string]time.Time)
+
+	for {
+		now := time.Now()
+		fields := append(statusFields(ctx, volumeManager, volumePath, containerName, cwd), dockerStatusFields()...)
+
+		if prev != nil {
+			for _, t := range state.Diff(prev, fields) {
+				changedAt[t.Name] = now
+				line := fmt.Sprintf("%s  %s: %s -> %s", now.Format(time.TimeOnly), t.Name, valueOrNone(t.From), valueOrNone(t.To))
+				transitions = append(transitions, line)
+				if !tty {
+					fmt.Println(line)
+				}
+			}
+			if len(transitions) > maxStatusTransitions {
+				transitions = transitions[len(transitions)-maxStatusTransitions:]
+			}
+		}
+
+		if tty {
+			fmt.Print(ansiClear)
+			fmt.Printf("Claude Environment Status (every %s, Ctrl+C to stop)  %s\n", interval, now.Format(time.TimeOnly))
+			fmt.Println("=========================")
+			fmt.Println()
+			for _, field := range fields {
+				changed, ok := changedAt[field.Name]
+				printStatusField(field, ok && now.Sub(changed) < 3*interval)
+			}
+			if len(transitions) > 0 {
+				fmt.Println("\nRecent changes:")
+				for _, line := range transitions {
+					fmt.Println("  " + line)
+				}
+			}
+		} else if prev == nil {
+			for _, field := range fields {
+				printStatusField(field, false)
+			}
+		}
+		prev = fields
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dockerStatusFields reports the Docker daemon and image, which the one-shot
+// status shows only as warnings.
+func dockerStatusFields() []state.Field {
+	if err := state.CheckDockerRunning(); err != nil {
+		return []state.Field{{Name: "Docker", Value: "Not running"}}
+	}
+	image := "Not found (run 'capsule build-image')"
+	if state.CheckImageExists(docker.DefaultImageName) {
+		image = "Present (" + docker.DefaultImageName + ")"
+	}
+	return []state.Field{
+		{Name: "Docker", Value: "Running"},
+		{Name: "Image", Value: image},
+	}
+}
+
+// printStatusField prints one aligned status line, optionally highlighted.
+func printStatusField(field state.Field, highlight bool) {
+	line := fmt.Sprintf("%-12s%s", field.Name+":", field.Value)
+	if highlight {
+		line = ansiHighlight + line + ansiReset
+	}
+	fmt.Println(line)
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+	"github.com/jeanhaley32/claude-capsule/internal/volumesync"
+)
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync --peer USER@HOST",
+		Short: "Bring the volume image in step with another machine's copy over SSH",
+		Long: `Copies the volume image between this machine and another one over SSH, in
+whichever direction has the newer work, so a desktop and a laptop can share
+one environment. rsync's delta transfer sends only the parts of the image
+that changed, not the whole image. The key stretching parameters and
+manifest next to the image travel with it.
+
+Both copies must be locked. After each sync both machines record the image
+they now share; the next sync copies from the side that changed since then.
+If both changed, or the two have never been synced and differ, sync refuses
+and --push or --pull chooses which copy to keep.
+
+The peer's image is at the same path relative to its home directory, or at
+--peer-volume. The peer needs rsync and SSH access; capsule itself need not
+be installed there.`,
+		Args: cobra.NoArgs,
+		RunE: runSync,
+	}
+
+	cmd.Flags().String("peer", "", "The other machine, as an SSH destination such as user@host (required)")
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().String("peer-volume", "", "Path of the volume image on the peer (default: the same path relative to the home directory)")
+	cmd.Flags().Bool("push", false, "Copy this machine's image to the peer, even if the peer's changed too")
+	cmd.Flags().Bool("pull", false, "Copy the peer's image to this machine, even if this one changed too")
+	addDryRunFlag(cmd)
+
+	return cmd
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	peer, err := cmd.Flags().GetString("peer")
+	if err != nil {
+		return fmt.Errorf("invalid peer flag: %w", err)
+	}
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	peerVolume, err := cmd.Flags().GetString("peer-volume")
+	if err != nil {
+		return fmt.Errorf("invalid peer-volume flag: %w", err)
+	}
+	push, err := cmd.Flags().GetBool("push")
+	if err != nil {
+		return fmt.Errorf("invalid push flag: %w", err)
+	}
+	pull, err := cmd.Flags().GetBool("pull")
+	if err != nil {
+		return fmt.Errorf("invalid pull flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("invalid dry-run flag: %w", err)
+	}
+	if peer == "" {
+		return fmt.Errorf("--peer is required, e.g. --peer me@laptop.local")
+	}
+	if push && pull {
+		return fmt.Errorf("--push and --pull cannot be used together")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath, exists := pathResolver.ResolveVolumePath(volumePathFlag, cwd)
+	if volumePath, err = filepath.Abs(volumePath); err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	if volume.FormatOf(volumePath) == volume.FormatSparseBundle {
+		return fmt.Errorf("sync does not support sparse bundles yet; convert %s with 'capsule volume convert --format sparseimage' first", volumePath)
+	}
+	releaseLocks, err := lockOperation(cmd.CommandPath(), "", volumePath)
+	if err != nil {
+		return err
+	}
+	defer releaseLocks()
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if mountPoint := volumeManager.GetMountPoint(ctx, volumePath); mountPoint != "" {
+		return fmt.Errorf("volume is mounted at %s; run 'capsule lock' first", mountPoint)
+	}
+
+	if peerVolume == "" {
+		if peerVolume, err = peerPathFor(ctx, peer, volumePath); err != nil {
+			return err
+		}
+	}
+	if err := checkPeerLocked(ctx, peer, peerVolume); err != nil {
+		return err
+	}
+
+	var local *volumesync.Stamp
+	if exists {
+		stamp, err := volumesync.LocalStamp(volumePath)
+		if err != nil {
+			return err
+		}
+		local = &stamp
+	}
+	peerStamp, err := peerStampOf(ctx, peer, peerVolume)
+	if err != nil {
+		return err
+	}
+	synced, err := volumesync.ReadSynced(volumePath)
+	if err != nil {
+		return err
+	}
+	direction, err := volumesync.Decide(local, peerStamp, synced)
+	switch {
+	case push && local == nil:
+		return fmt.Errorf("no volume at %s to push", volumePath)
+	case pull && peerStamp == nil:
+		return fmt.Errorf("no volume at %s:%s to pull", peer, peerVolume)
+	case push:
+		direction, err = volumesync.Push, nil
+	case pull:
+		direction, err = volumesync.Pull, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	remote := peer + ":" + peerVolume
+	if dryRun {
+		var plan dryRunPlan
+		switch direction {
+		case volumesync.Push:
+			plan.run("Copy the changed parts of the image to the peer", volumesync.RsyncArgs(volumePath, remote)...)
+			plan.add("Copy or remove the key stretching parameters and manifest on the peer to match")
+		case volumesync.Pull:
+			plan.run("Copy the changed parts of the peer's image", volumesync.RsyncArgs(remote, volumePath)...)
+			plan.add("Copy or remove the key stretching parameters and manifest here to match")
+		}
+		plan.add("Record the synced image in %s on both machines", volumesync.SyncedPath(volumePath))
+		plan.print(cmd.CommandPath())
+		return nil
+	}
+
+	switch direction {
+	case volumesync.None:
+		infof("%s and %s already match.\n", volumePath, remote)
+	case volumesync.Push:
+		infof("Pushing %s to %s...\n", volumePath, remote)
+		if err := pushVolume(ctx, peer, volumePath, peerVolume); err != nil {
+			return err
+		}
+	case volumesync.Pull:
+		infof("Pulling %s to %s...\n", remote, volumePath)
+		if err := pullVolume(ctx, peer, volumePath, peerVolume); err != nil {
+			return err
+		}
+	}
+
+	stamp, err := volumesync.LocalStamp(volumePath)
+	if err != nil {
+		return err
+	}
+	if err := volumesync.WriteSynced(volumePath, stamp); err != nil {
+		return err
+	}
+	record := fmt.Sprintf("printf '%%s\\n' %s > %s", volumesync.Quote(stamp.String()), volumesync.Quote(volumesync.SyncedPath(peerVolume)))
+	if _, err := runOnPeer(ctx, peer, record); err != nil {
+		return fmt.Errorf("failed to record the sync on %s: %w", peer, err)
+	}
+	if direction != volumesync.None {
+		infof("Synced %s with %s.\n", volumePath, remote)
+	}
+	return nil
+}
+
+// pushVolume copies the image and its sidecars to the peer, removing
+// sidecars there that this machine's image doesn't have.
+func pushVolume(ctx context.Context, peer, volumePath, peerVolume string) error {
+	if err := runRsync(ctx, volumePath, peer+":"+peerVolume); err != nil {
+		return err
+	}
+	peerSidecars := volume.PortableSidecars(peerVolume)
+	var stale []string
+	for i, sidecar := range volume.PortableSidecars(volumePath) {
+		if _, err := os.Stat(sidecar); err != nil {
+			stale = append(stale, volumesync.Quote(peerSidecars[i]))
+			continue
+		}
+		if err := runRsync(ctx, sidecar, peer+":"+peerSidecars[i]); err != nil {
+			return err
+		}
+	}
+	if len(stale) > 0 {
+		if _, err := runOnPeer(ctx, peer, "rm -f "+strings.Join(stale, " ")); err != nil {
+			return fmt.Errorf("failed to remove stale files on %s: %w", peer, err)
+		}
+	}
+	return nil
+}
+
+// pullVolume copies the peer's image and sidecars here, removing sidecars
+// here that the peer's image doesn't have.
+func pullVolume(ctx context.Context, peer, volumePath, peerVolume string) error {
+	if err := os.MkdirAll(filepath.Dir(volumePath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(volumePath), err)
+	}
+	if err := runRsync(ctx, peer+":"+peerVolume, volumePath); err != nil {
+		return err
+	}
+	peerSidecars := volume.PortableSidecars(peerVolume)
+	out, err := runOnPeer(ctx, peer, volumesync.PeerExistingScript(peerSidecars))
+	if err != nil {
+		return fmt.Errorf("failed to list files on %s: %w", peer, err)
+	}
+	existing := strings.Split(strings.TrimSpace(out), "\n")
+	for i, sidecar := range volume.PortableSidecars(volumePath) {
+		if !slices.Contains(existing, peerSidecars[i]) {
+			if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", sidecar, err)
+			}
+			continue
+		}
+		if err := runRsync(ctx, peer+":"+peerSidecars[i], sidecar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peerPathFor returns the path of volumePath on the peer: the same path
+// relative to the home directory, or the same absolute path outside it.
+func peerPathFor(ctx context.Context, peer, volumePath string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	rel, err := filepath.Rel(homeDir, volumePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return volumePath, nil
+	}
+	peerHome, err := runOnPeer(ctx, peer, `printf '%s' "$HOME"`)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", peer, err)
+	}
+	if peerHome == "" {
+		return "", fmt.Errorf("failed to find the home directory on %s", peer)
+	}
+	return filepath.Join(peerHome, rel), nil
+}
+
+// checkPeerLocked fails if the peer has peerVolume mounted.
+func checkPeerLocked(ctx context.Context, peer, peerVolume string) error {
+	out, err := runOnPeer(ctx, peer, "hdiutil info -plist")
+	if err != nil {
+		return fmt.Errorf("failed to check whether the volume is mounted on %s: %w", peer, err)
+	}
+	mounted, err := volume.ParseHdiutilInfo([]byte(out))
+	if err != nil {
+		return fmt.Errorf("%s: %w", peer, err)
+	}
+	for _, v := range mounted {
+		if filepath.Clean(v.ImagePath) == filepath.Clean(peerVolume) {
+			return fmt.Errorf("volume is mounted on %s at %s; run 'capsule lock' there first", peer, v.MountPoint)
+		}
+	}
+	return nil
+}
+
+// peerStampOf returns the stamp of the peer's image, or nil if it has none.
+func peerStampOf(ctx context.Context, peer, peerVolume string) (*volumesync.Stamp, error) {
+	out, err := runOnPeer(ctx, peer, volumesync.PeerStampScript(peerVolume))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the volume on %s: %w", peer, err)
+	}
+	if strings.TrimSpace(out) == "" {
+		return nil, nil
+	}
+	stamp, err := volumesync.ParseStamp(out)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", peer, err)
+	}
+	return &stamp, nil
+}
+
+// runOnPeer runs a shell script on the peer over SSH and returns its output.
+func runOnPeer(ctx context.Context, peer, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ssh", peer, script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	done := logging.Command(cmd)
+	out, err := cmd.Output()
+	done(err)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// runRsync copies src to dst, showing rsync's progress unless --quiet.
+func runRsync(ctx context.Context, src, dst string) error {
+	argv := volumesync.RsyncArgs(src, dst)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if !quiet {
+		cmd.Stdout = os.Stdout
+	}
+	cmd.Stderr = os.Stderr
+	done := logging.Command(cmd)
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("rsync of %s failed: %w", src, err)
+	}
+	return nil
+}
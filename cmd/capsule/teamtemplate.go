@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/teamtemplate"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Share a volume's setup with a team, without its credentials",
+		Long: `A team template is a gzipped tarball of the parts of a volume that make up its
+setup: CLAUDE.md, settings.json, skills, the language template, and each
+repository's shadow docs under repos/. Import it into a new volume to start
+from the same setup.
+
+Credentials are never exported. auth/ and the OAuth login are not among the
+shared files, settings.json loses its env, apiKeyHelper, and other credential
+keys, and any file that looks like it holds a secret is left out and listed.
+Transcripts and the memory index are left out too.`,
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export FILE",
+		Short: "Write the volume's shareable setup to a tarball",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runTemplateExport,
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Copy a team template into the volume, replacing the files it contains",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runTemplateImport,
+	}
+	importCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
+	cmd.AddCommand(exportCmd, importCmd)
+	for _, sub := range cmd.Commands() {
+		sub.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+		sub.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	}
+
+	return cmd
+}
+
+func runTemplateExport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	output := args[0]
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists", output)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	mountPoint, release, err := mountForHostAccess(ctx, volumeManager, volumePath, passwordStdin, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	template, err := embedded.ReadTemplateFile(mountPoint)
+	if err != nil {
+		return err
+	}
+	tmp := output + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.PublicFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	skipped, err := teamtemplate.Export(f, mountPoint, teamtemplate.Metadata{CapsuleVersion: version, Template: template})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, output)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to export template: %w", err)
+	}
+
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "Left out %s: %s\n", s.Path, s.Reason)
+	}
+	fmt.Printf("Exported the setup of %s to %s.\n", volumePath, output)
+	return nil
+}
+
+func runTemplateImport(cmd *cobra.Command, args []string) error {
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("invalid yes flag: %w", err)
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open template: %w", err)
+	}
+	defer f.Close()
+
+	if !yes {
+		confirmed, err := terminal.PromptConfirm(fmt.Sprintf("Replace the volume's CLAUDE.md, settings.json, skills, and docs with those in %s?", args[0]))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("aborted (use --yes to skip confirmation)")
+		}
+	}
+
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	meta, written, err := teamtemplate.Import(f, mountPoint)
+	if err != nil {
+		return fmt.Errorf("failed to import template (%d files were written): %w", len(written), err)
+	}
+	fmt.Printf("Imported %d files from a template exported by capsule %s on %s.\n", len(written), meta.CapsuleVersion, meta.Created.Local().Format("2006-01-02"))
+	if meta.Template != "" {
+		fmt.Printf("It uses the %s template; its image is built on the next start.\n", meta.Template)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/transcript"
+)
+
+func newTranscriptsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transcripts",
+		Short: "Review recorded sessions stored in the volume",
+		Long: `Sessions started with 'capsule start --record' (or record_sessions in
+~/.capsule/config.json) and every 'capsule run' are saved under
+repos/<repoID>/sessions/ in the encrypted volume. These commands list and show
+them for the current workspace, or the repository given with --repo.`,
+	}
+
+	cmd.AddCommand(newTranscriptsListCmd(), newTranscriptsShowCmd())
+
+	for _, sub := range cmd.Commands() {
+		sub.Flags().String("repo", "", "Repository ID (defaults to the current workspace)")
+		sub.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+		sub.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	}
+
+	return cmd
+}
+
+func newTranscriptsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List recorded sessions, oldest first",
+		Args:  cobra.NoArgs,
+		RunE:  runTranscriptsList,
+	}
+}
+
+func newTranscriptsShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [name]",
+		Short: "Print a recorded session (defaults to the most recent)",
+		Long: `Prints a transcript as plain text: colors and other terminal control sequences
+are removed, and progress lines that were redrawn show only their final state.
+--raw prints the recording as captured, for replaying in a terminal.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runTranscriptsShow,
+	}
+
+	cmd.Flags().Bool("raw", false, "Print the recording with terminal control sequences intact")
+
+	return cmd
+}
+
+func runTranscriptsList(cmd *cobra.Command, args []string) error {
+	repoID, err := repoIDFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	infos, err := transcript.List(mountPoint, repoID)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Printf("No transcripts for %s.\n", repoID)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTARTED\tKIND\tSIZE")
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, info.Started.Local().Format("2006-01-02 15:04"), info.Kind, formatBytes(info.Bytes))
+	}
+	return w.Flush()
+}
+
+func runTranscriptsShow(cmd *cobra.Command, args []string) error {
+	raw, err := cmd.Flags().GetBool("raw")
+	if err != nil {
+		return fmt.Errorf("invalid raw flag: %w", err)
+	}
+	repoID, err := repoIDFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+	mountPoint, release, err := mountVolumeFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		infos, err := transcript.List(mountPoint, repoID)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return fmt.Errorf("no transcripts for %s", repoID)
+		}
+		name = infos[len(infos)-1].Name
+	}
+	path, err := transcript.Path(mountPoint, repoID, name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	if !raw {
+		data = transcript.Clean(data)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// repoIDFromFlag returns the --repo flag, or the current workspace's repository ID.
+func repoIDFromFlag(cmd *cobra.Command) (string, error) {
+	repoID, err := cmd.Flags().GetString("repo")
+	if err != nil {
+		return "", fmt.Errorf("invalid repo flag: %w", err)
+	}
+	if repoID != "" {
+		return repoID, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	repoIdentifier := newRepoIdentifier()
+	workspacePath, err := repoIdentifier.GetWorkspaceRoot(cwd)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine workspace root: %w", err)
+	}
+	repoID, err = repoIdentifier.GetRepoID(workspacePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to identify repository: %w", err)
+	}
+	return repoID, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/trust"
+)
+
+func newTrustCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Manage workspaces allowed to run with your credentials",
+		Long: `Capsule only mounts the credential volume into containers for trusted workspaces.
+The first 'capsule start' in a new directory asks whether to trust it; answers
+are recorded in ~/.capsule/` + trust.FileName + `. Use 'capsule start --untrusted' to run a
+workspace without the credential home mounted.`,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List trusted workspaces",
+			Args:  cobra.NoArgs,
+			RunE:  runTrustList,
+		},
+		&cobra.Command{
+			Use:   "add [path]",
+			Short: "Trust a workspace (defaults to the current workspace)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE:  runTrustAdd,
+		},
+		&cobra.Command{
+			Use:   "remove [path]",
+			Short: "Revoke trust for a workspace (defaults to the current workspace)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE:  runTrustRemove,
+		},
+	)
+
+	return cmd
+}
+
+// trustTarget returns the path argument, or the current workspace root if none was given.
+func trustTarget(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	workspacePath, err := newRepoIdentifier().GetWorkspaceRoot(cwd)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine workspace root: %w", err)
+	}
+	return workspacePath, nil
+}
+
+func runTrustList(cmd *cobra.Command, args []string) error {
+	store, err := trust.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if len(store.Workspaces) == 0 {
+		fmt.Println("No trusted workspaces.")
+		return nil
+	}
+	for _, w := range store.Workspaces {
+		fmt.Printf("%-60s trusted %s\n", w.Path, w.TrustedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func runTrustAdd(cmd *cobra.Command, args []string) error {
+	target, err := trustTarget(args)
+	if err != nil {
+		return err
+	}
+	store, err := trust.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := store.Add(target); err != nil {
+		return err
+	}
+	canonical, _ := trust.Canonicalize(target)
+	fmt.Printf("Trusted %s\n", canonical)
+	return nil
+}
+
+func runTrustRemove(cmd *cobra.Command, args []string) error {
+	target, err := trustTarget(args)
+	if err != nil {
+		return err
+	}
+	store, err := trust.LoadDefault()
+	if err != nil {
+		return err
+	}
+	removed, err := store.Remove(target)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Printf("%s was not trusted.\n", target)
+		return nil
+	}
+	fmt.Printf("Revoked trust for %s\n", target)
+	return nil
+}
+
+// confirmWorkspaceTrust returns nil if workspacePath is trusted, prompting on
+// first use and recording a "yes" answer. Declining returns an error that
+// points at --untrusted.
+func confirmWorkspaceTrust(workspacePath string) error {
+	store, err := trust.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if store.IsTrusted(workspacePath) {
+		return nil
+	}
+
+	fmt.Printf("Workspace %s has not been used with capsule before.\n", workspacePath)
+	fmt.Println("Trusted workspaces run with your encrypted volume (credentials, Claude home) mounted.")
+	trusted, err := terminal.PromptConfirm("Trust this directory?")
+	if err != nil {
+		return err
+	}
+	if !trusted {
+		return fmt.Errorf("workspace not trusted: %s\nRun 'capsule trust add' to trust it, or 'capsule start --untrusted' to start without credentials", workspacePath)
+	}
+
+	if err := store.Add(workspacePath); err != nil {
+		return fmt.Errorf("failed to record trust: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the volume against its signed manifest",
+		Long: `Every volume has a manifest next to its image (<image>` + manifest.FileSuffix + `) recording
+the image UUID, encryption header, creation time, capsule version, and top-level layout,
+signed with a key derived from the volume password. Mounting checks it automatically and
+warns if the image was replaced or its header altered.
+
+'capsule verify' runs the same check and exits non-zero on a mismatch. After an intended
+change (such as restoring from a backup or adding a passphrase), '--accept' re-signs the
+manifest for the volume as it is now.
+
+The volume must be locked so the password can be confirmed by mounting it.`,
+		Args: cobra.NoArgs,
+		RunE: runVerify,
+	}
+
+	cmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	cmd.Flags().Bool("password-stdin", false, "Read password from stdin instead of terminal prompt")
+	cmd.Flags().Bool("accept", false, "Re-sign the manifest for the volume as it is now")
+
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	accept, err := cmd.Flags().GetBool("accept")
+	if err != nil {
+		return fmt.Errorf("invalid accept flag: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+
+	volumePath, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+
+	// A wrong password would look like a forged manifest, so insist on mounting
+	// here where hdiutil confirms the password first
+	if volumeManager.GetMountPoint(ctx, volumePath) != "" {
+		return fmt.Errorf("volume is mounted; run 'capsule lock' first so the password can be verified")
+	}
+
+	password, err := terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter volume password: ")
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	fmt.Fprintf(os.Stderr, "Mounting encrypted volume...\n")
+	mountPoint, err := volumeManager.Mount(ctx, volumePath, password)
+	if err != nil {
+		return fmt.Errorf("failed to mount volume: %w", err)
+	}
+	defer func() {
+		if err := volumeManager.Unmount(ctx, mountPoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to unmount volume: %v\n", err)
+		}
+	}()
+
+	problems, err := volumeManager.VerifyManifest(ctx, volumePath, mountPoint, password)
+	if err != nil {
+		return err
+	}
+
+	if accept {
+		if err := volumeManager.WriteManifest(ctx, volumePath, mountPoint, password); err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			fmt.Printf("Accepted %d change(s); manifest re-signed: %s\n", len(problems), manifest.Path(volumePath))
+		} else {
+			fmt.Printf("Manifest re-signed: %s\n", manifest.Path(volumePath))
+		}
+		return nil
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("Volume %s does NOT match its manifest:\n", volumePath)
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return fmt.Errorf("volume verification failed (use --accept if these changes are expected)")
+	}
+
+	m, err := manifest.Load(volumePath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Volume %s matches its manifest.\n", volumePath)
+	fmt.Printf("  Image UUID: %s\n", m.Header.UUID)
+	fmt.Printf("  Created:    %s\n", m.CreatedAt.Local().Format("2006-01-02 15:04"))
+	if m.CapsuleVersion != "" {
+		fmt.Printf("  Version:    capsule %s\n", m.CapsuleVersion)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func newVolumeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "volume",
+		Short: "Manage volume images",
+	}
+
+	moveCmd := &cobra.Command{
+		Use:   "move --to global|local|PATH",
+		Short: "Move the volume image, with the files kept next to it",
+		Long: `Moves the volume image, along with its key stretching parameters, manifest,
+and other files kept next to it, and points the named volumes in
+~/.capsule/config.json that refer to it at the new path.
+
+--to global moves it to ~/.capsule/volumes, keeping its file name; --to
+local moves it to capsule.sparseimage in the current directory, where
+capsule finds it for this directory. Any other value is a path, or a
+directory to move it into. The volume must be locked.
+
+Afterwards the order in which capsule resolves volumes from the current
+directory is printed, with the one a start here would now use.`,
+		Args: cobra.NoArgs,
+		RunE: runVolumeMove,
+	}
+	moveCmd.Flags().String("to", "", "Where to move the volume: global, local, or a path (required)")
+	moveCmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+
+	convertCmd := &cobra.Command{
+		Use:   "convert --format sparsebundle|sparseimage",
+		Short: "Convert the volume image to another format",
+		Long: `Writes a copy of the volume image in another format, with the same password,
+cipher, and key stretching parameters, then removes the original.
+
+A sparseimage is one file, which changes as a whole with every write, so
+Time Machine and sync tools copy all of it again after a session. A
+sparsebundle is a directory of fixed-size bands, and only the bands that
+changed need copying. --band-size sets their size in MB.
+
+The copy is written next to the original, with the new format's extension,
+so there must be room for both. Named volumes in ~/.capsule/config.json that
+refer to the original are pointed at the copy. The volume must be locked.`,
+		Args: cobra.NoArgs,
+		RunE: runVolumeConvert,
+	}
+	convertCmd.Flags().String("format", "", "Format to convert to: sparsebundle or sparseimage (required)")
+	convertCmd.Flags().Int("band-size", 0, fmt.Sprintf("Band size of a sparsebundle in MB (default %d)", volume.DefaultBandSizeMB))
+	convertCmd.Flags().String("volume", "", "Path or name of encrypted volume (auto-detected if not specified)")
+	convertCmd.Flags().String("password-file", "", "Read the volume password from a file only you can read (mode 0600)")
+	convertCmd.Flags().Bool("password-stdin", false, "Read the volume password from stdin instead of terminal prompt")
+	convertCmd.Flags().Bool("keep-original", false, "Keep the original image next to the converted one")
+
+	cmd.AddCommand(moveCmd, convertCmd)
+	return cmd
+}
+
+func runVolumeMove(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return fmt.Errorf("invalid to flag: %w", err)
+	}
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	if to == "" {
+		return fmt.Errorf("--to is required: global, local, or a path")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	src, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	if src, err = filepath.Abs(src); err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	dst, err := moveDestination(pathResolver, to, src, cwd)
+	if err != nil {
+		return err
+	}
+	if dst == src {
+		return fmt.Errorf("volume is already at %s", src)
+	}
+
+	for _, path := range []string{src, dst} {
+		releaseLocks, err := lockOperation(cmd.CommandPath(), "", path)
+		if err != nil {
+			return err
+		}
+		defer releaseLocks()
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if mountPoint := volumeManager.GetMountPoint(ctx, src); mountPoint != "" {
+		return fmt.Errorf("volume is mounted at %s; run 'capsule lock' first", mountPoint)
+	}
+
+	if err := volume.MoveImage(src, dst); err != nil {
+		return fmt.Errorf("move failed: %w", err)
+	}
+	fmt.Printf("Moved %s to %s.\n", src, dst)
+
+	if err := updateVolumeSettings(src, dst, "moved"); err != nil {
+		return err
+	}
+
+	// Re-read the names, so the resolution shown reflects the update
+	if pathResolver, err = volume.NewPathResolver(); err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	printResolution(pathResolver, cwd, dst)
+	return nil
+}
+
+func runVolumeConvert(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	formatFlag, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("invalid format flag: %w", err)
+	}
+	bandSize, err := cmd.Flags().GetInt("band-size")
+	if err != nil {
+		return fmt.Errorf("invalid band-size flag: %w", err)
+	}
+	volumePathFlag, err := cmd.Flags().GetString("volume")
+	if err != nil {
+		return fmt.Errorf("invalid volume flag: %w", err)
+	}
+	passwordFile, err := cmd.Flags().GetString("password-file")
+	if err != nil {
+		return fmt.Errorf("invalid password-file flag: %w", err)
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return fmt.Errorf("invalid password-stdin flag: %w", err)
+	}
+	keepOriginal, err := cmd.Flags().GetBool("keep-original")
+	if err != nil {
+		return fmt.Errorf("invalid keep-original flag: %w", err)
+	}
+	if formatFlag == "" {
+		return fmt.Errorf("--format is required: sparsebundle or sparseimage")
+	}
+	format, err := volume.ParseImageFormat(formatFlag)
+	if err != nil {
+		return fmt.Errorf("invalid format flag: %w", err)
+	}
+	if passwordStdin && passwordFile != "" {
+		return fmt.Errorf("--password-stdin and --password-file cannot be used together")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	pathResolver, err := volume.NewPathResolver()
+	if err != nil {
+		return fmt.Errorf("failed to create path resolver: %w", err)
+	}
+	src, err := pathResolver.ResolveVolumePathStrict(volumePathFlag, cwd)
+	if err != nil {
+		return err
+	}
+	if src, err = filepath.Abs(src); err != nil {
+		return fmt.Errorf("invalid volume path: %w", err)
+	}
+	if volume.FormatOf(src) == format {
+		return fmt.Errorf("%s is already a %s", src, format)
+	}
+	dst := volume.WithFormat(src, format)
+
+	for _, path := range []string{src, dst} {
+		releaseLocks, err := lockOperation(cmd.CommandPath(), "", path)
+		if err != nil {
+			return err
+		}
+		defer releaseLocks()
+	}
+	volumeManager, err := volume.New()
+	if err != nil {
+		return fmt.Errorf("failed to create volume manager: %w", err)
+	}
+	if mountPoint := volumeManager.GetMountPoint(ctx, src); mountPoint != "" {
+		return fmt.Errorf("volume is mounted at %s; run 'capsule lock' first", mountPoint)
+	}
+
+	var password *terminal.SecurePassword
+	if passwordFile != "" {
+		password, err = terminal.ReadPasswordFromFileSecure(passwordFile)
+	} else {
+		password, err = terminal.ReadPasswordMultiSourceSecure(passwordStdin, "Enter volume password: ")
+	}
+	if err != nil {
+		return fmt.Errorf("password error: %w", err)
+	}
+	defer password.Clear()
+
+	fmt.Printf("Converting %s to a %s at %s...\n", src, format, dst)
+	err = volumeManager.Convert(ctx, volume.ConvertConfig{
+		VolumePath: src,
+		Target:     dst,
+		Format:     format,
+		BandSizeMB: bandSize,
+		Password:   password,
+	})
+	if err != nil {
+		return fmt.Errorf("convert failed: %w", err)
+	}
+	fmt.Printf("Converted %s to %s.\n", src, dst)
+
+	if !keepOriginal {
+		paths, err := volume.SidecarPaths(src)
+		if err != nil {
+			return err
+		}
+		for _, path := range append(paths, src) {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("failed to remove the original %s: %w", path, err)
+			}
+		}
+		fmt.Printf("Removed the original %s.\n", src)
+	}
+
+	if err := updateVolumeSettings(src, dst, "converted"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// updateVolumeSettings points the volumes and identity_volume settings that
+// name the image at src at dst, which it was moved or converted to.
+func updateVolumeSettings(src, dst, done string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	settingsPath, err := config.DefaultSettingsPath()
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadSettings(settingsPath)
+	if err != nil {
+		return err
+	}
+	names, err := settings.MoveVolume(homeDir, src, dst)
+	if err != nil {
+		return err
+	}
+	identity, err := settings.MoveIdentityVolume(homeDir, src, dst)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 && !identity {
+		return nil
+	}
+	if err := config.SaveSettings(settingsPath, settings); err != nil {
+		return fmt.Errorf("volume %s, but the settings still point at %s: %w", done, src, err)
+	}
+	if len(names) > 0 {
+		fmt.Printf("Updated the volumes setting for %s.\n", strings.Join(names, ", "))
+	}
+	if identity {
+		fmt.Println("Updated the identity_volume setting.")
+	}
+	return nil
+}
+
+// moveDestination returns the image path --to names for the volume at src.
+func moveDestination(pathResolver *volume.PathResolver, to, src, cwd string) (string, error) {
+	switch to {
+	case "global":
+		return filepath.Join(pathResolver.GetGlobalVolumeDir(), filepath.Base(src)), nil
+	case "local":
+		return volume.WithFormat(pathResolver.GetLocalVolumePath(cwd), volume.FormatOf(src)), nil
+	}
+	dst, err := filepath.Abs(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid --to path: %w", err)
+	}
+	if info, err := os.Stat(dst); (err == nil && info.IsDir()) || strings.HasSuffix(to, string(filepath.Separator)) {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+	if ext := volume.FormatOf(src).Ext(); filepath.Ext(dst) != ext {
+		return "", fmt.Errorf("--to must name a %s image or a directory, got %s", ext, to)
+	}
+	return dst, nil
+}
+
+// printResolution lists where capsule looks for a volume from cwd, in order,
+// and which one a start there would use.
+func printResolution(pathResolver *volume.PathResolver, cwd, moved string) {
+	found := func(path string) string {
+		if _, err := os.Stat(path); err == nil {
+			return "found"
+		}
+		return "not found"
+	}
+	local, global := pathResolver.GetLocalVolumePath(cwd), pathResolver.GetDefaultVolumePath()
+
+	fmt.Println("")
+	fmt.Printf("Volume resolution from %s:\n", cwd)
+	fmt.Println("  1. --volume NAME or PATH, if given")
+	fmt.Printf("  2. Local:  %s (%s)\n", local, found(local))
+	fmt.Printf("  3. Global: %s (%s)\n", global, found(global))
+	resolved, exists := pathResolver.ResolveVolumePath("", cwd)
+	if exists {
+		fmt.Printf("'capsule start' here now uses %s.\n", resolved)
+	} else {
+		fmt.Println("'capsule start' here finds no volume.")
+	}
+	if resolved != moved {
+		fmt.Printf("Use --volume %s to use the moved volume.\n", moved)
+	}
+}
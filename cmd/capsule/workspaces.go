@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docker"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+)
+
+// unsafeWorkspaceNameRegex matches characters not allowed in a /workspaces/<name> directory.
+var unsafeWorkspaceNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// resolveWorkspaces turns the --workspace flags into absolute workspace paths with
+// repo IDs and unique mount names. With no flags, the workspace root of cwd is used.
+func resolveWorkspaces(repoIdentifier *repo.DefaultIdentifier, paths []string, cwd string) ([]docker.Workspace, error) {
+	if len(paths) == 0 {
+		root, err := repoIdentifier.GetWorkspaceRoot(cwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine workspace root: %w", err)
+		}
+		paths = []string{root}
+	}
+
+	seenPaths := make(map[string]bool)
+	seenNames := make(map[string]bool)
+	workspaces := make([]docker.Workspace, 0, len(paths))
+	for _, p := range paths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+		if seenPaths[absPath] {
+			return nil, fmt.Errorf("workspace %s specified more than once", absPath)
+		}
+		seenPaths[absPath] = true
+
+		repoID, err := repoIdentifier.GetRepoID(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to identify repository %s: %w", absPath, err)
+		}
+
+		// Directory names can collide (e.g. two checkouts named "app"); number the later ones
+		base := workspaceName(absPath)
+		name := base
+		for i := 2; seenNames[name]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		seenNames[name] = true
+
+		workspaces = append(workspaces, docker.Workspace{Name: name, Path: absPath, RepoID: repoID})
+	}
+	return workspaces, nil
+}
+
+// readWorkspacesFile reads the directories listed in a --workspaces-file, one
+// per line. Blank lines and lines starting with # are skipped, a leading ~/
+// is the home directory, and relative paths are relative to the file.
+func readWorkspacesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspaces file: %w", err)
+	}
+	defer f.Close()
+
+	base := filepath.Dir(path)
+	seen := make(map[string]bool)
+	var dirs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "~/"); ok {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get home directory: %w", err)
+			}
+			line = filepath.Join(homeDir, rest)
+		} else if !filepath.IsAbs(line) {
+			line = filepath.Join(base, line)
+		}
+		dir, err := filepath.Abs(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("workspace %s in %s is not a directory", dir, path)
+		}
+		if seen[dir] {
+			return nil, fmt.Errorf("workspace %s is listed more than once in %s", dir, path)
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read workspaces file: %w", err)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no workspaces listed in %s", path)
+	}
+	return dirs, nil
+}
+
+// workspaceName derives the /workspaces/<name> directory from a workspace path.
+func workspaceName(workspacePath string) string {
+	name := unsafeWorkspaceNameRegex.ReplaceAllString(filepath.Base(workspacePath), "-")
+	name = strings.TrimLeft(name, "_.-")
+	if name == "" {
+		return "workspace"
+	}
+	return name
+}
+
+// multiWorkspaceContainerName returns a container name unique to a set of workspaces.
+func multiWorkspaceContainerName(workspaces []docker.Workspace) string {
+	ids := make([]string, len(workspaces))
+	for i, w := range workspaces {
+		ids[i] = w.RepoID
+	}
+	return repo.ContainerName(strings.Join(ids, "+"))
+}
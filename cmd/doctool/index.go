@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+)
+
+func newIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Manage the document index",
+	}
+
+	cmd.AddCommand(
+		newIndexInitCmd(),
+		newIndexRegisterCmd(),
+		newIndexReadCmd(),
+		newIndexUpdateCmd(),
+		newIndexTagCmd(),
+		newIndexQueryCmd(),
+		newIndexStatsCmd(),
+		newIndexTagsCmd(),
+	)
+
+	return cmd
+}
+
+func newIndexInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Initialize the index database",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := docsRoot(cmd)
+			if err != nil {
+				return err
+			}
+			store, err := docsync.Open(root)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.Init(); err != nil {
+				return err
+			}
+			fmt.Printf("Database initialized at %s\n", docsync.DBPath(root))
+			return nil
+		},
+	}
+}
+
+func newIndexRegisterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "register <path>",
+		Short: "Register a document with a genre and subject tags",
+		Long: `Registers a document in the index.
+Genre (controlled): ` + strings.Join(docsync.ValidGenres, ", ") + `
+Tags (free-form): at least one required, new tags created automatically`,
+		Args: cobra.ExactArgs(1),
+		RunE: runIndexRegister,
+	}
+	cmd.Flags().String("genre", "", "Document genre")
+	cmd.Flags().String("tags", "", "Comma-separated subject tags")
+	return cmd
+}
+
+func runIndexRegister(cmd *cobra.Command, args []string) error {
+	genre, err := cmd.Flags().GetString("genre")
+	if err != nil {
+		return fmt.Errorf("invalid genre flag: %w", err)
+	}
+	tagsFlag, err := cmd.Flags().GetString("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tags := splitList(tagsFlag)
+	if len(tags) == 0 {
+		existing, _ := store.ExistingTags()
+		if len(existing) > 0 {
+			fmt.Printf("Existing tags: %s\n", strings.Join(existing[:min(20, len(existing))], ", "))
+		}
+		return fmt.Errorf("at least one subject tag required. Use --tags tag1,tag2")
+	}
+
+	result, err := store.Register(args[0], genre, tags, "")
+	if err != nil {
+		return err
+	}
+	printRegistered(result)
+	return nil
+}
+
+func newIndexReadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "read <path>",
+		Short: "Mark a document as accessed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			ok, err := store.MarkRead(args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("Document not in index: %s\n", args[0])
+				return nil
+			}
+			fmt.Printf("Marked as read: %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newIndexUpdateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "update <path>",
+		Short: "Mark a document as modified",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			ts, err := store.MarkUpdated(args[0])
+			if err != nil {
+				return err
+			}
+			if ts == "" {
+				fmt.Printf("Document not in index: %s\n", args[0])
+				return nil
+			}
+			fmt.Printf("Marked as updated: %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newIndexTagCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tag <path> <tag1,tag2,...>",
+		Short: "Add subject tags to an indexed document",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			tags := splitList(args[1])
+			ok, err := store.AddTags(args[0], tags)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("Document not in index: %s\n", args[0])
+				return nil
+			}
+			fmt.Printf("Added tags to %s: %s\n", args[0], strings.Join(tags, ", "))
+			return nil
+		},
+	}
+}
+
+func newIndexQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "query <stale|tag|all|untracked> [tag]",
+		Short:     "Query the index",
+		Args:      cobra.RangeArgs(1, 2),
+		ValidArgs: []string{"stale", "tag", "all", "untracked"},
+		RunE:      runIndexQuery,
+	}
+	cmd.Flags().Int("days", 30, "Staleness threshold in days (for 'stale')")
+	return cmd
+}
+
+func runIndexQuery(cmd *cobra.Command, args []string) error {
+	days, err := cmd.Flags().GetInt("days")
+	if err != nil {
+		return fmt.Errorf("invalid days flag: %w", err)
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "stale":
+		docs, err := store.QueryStale(days)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			fmt.Printf("No stale documents (>%d days)\n", days)
+			return nil
+		}
+		fmt.Printf("Stale documents (>%d days):\n\n", days)
+		for _, d := range docs {
+			fmt.Printf("  %s (%d days)\n", d.Path, d.DaysStale)
+		}
+
+	case "tag":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: doctool index query tag <tagname>")
+		}
+		docs, err := store.QueryByTag(args[1])
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			fmt.Printf("No documents with tag: %s\n", args[1])
+			return nil
+		}
+		fmt.Printf("Documents tagged '%s':\n\n", args[1])
+		for _, d := range docs {
+			fmt.Printf("  %s\n", d.Path)
+		}
+
+	case "all":
+		docs, err := store.QueryAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			fmt.Println("No documents in index")
+			return nil
+		}
+		fmt.Printf("%-45s %-25s %-12s\n", "Path", "Tags", "Updated")
+		fmt.Println(strings.Repeat("-", 85))
+		for _, d := range docs {
+			fmt.Printf("%-45s %-25s %-12s\n", d.Path, truncate(d.Tags, 23), truncate(d.UpdatedAt, 10))
+		}
+
+	case "untracked":
+		untracked, err := store.QueryUntracked()
+		if err != nil {
+			return err
+		}
+		if len(untracked) == 0 {
+			fmt.Println("All documents are indexed")
+			return nil
+		}
+		fmt.Printf("Untracked documents (%d):\n\n", len(untracked))
+		for _, path := range untracked {
+			fmt.Printf("  %s\n", path)
+		}
+
+	default:
+		return fmt.Errorf("unknown query type: %s (valid: stale, tag, all, untracked)", args[0])
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func newIndexStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show index statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			stats, err := store.IndexStatistics()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Documentation Index Statistics")
+			fmt.Println(strings.Repeat("=", 40))
+			fmt.Printf("Total documents:     %d\n", stats.TotalDocs)
+			fmt.Printf("Stale (>30 days):    %d\n", stats.StaleDocs)
+			fmt.Printf("Never accessed:      %d\n", stats.NeverRead)
+			fmt.Printf("Total tags:          %d\n", stats.TotalTags)
+			fmt.Printf("Tags in use:         %d\n", stats.UsedTags)
+			return nil
+		},
+	}
+}
+
+func newIndexTagsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags",
+		Short: "Manage subject tags",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List tags with document counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			tags, err := store.ListTags()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-25s %-6s %-12s\n", "Tag", "Docs", "Created")
+			fmt.Println(strings.Repeat("-", 45))
+			for _, t := range tags {
+				created := truncate(t.CreatedAt, 10)
+				if created == "" {
+					created = "seeded"
+				}
+				fmt.Printf("%-25s %-6d %-12s\n", t.Name, t.DocCount, created)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add <tag1,tag2,...>",
+		Short: "Create tags ahead of use",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			tags := splitList(strings.ToLower(args[0]))
+			for _, tag := range tags {
+				similar, err := store.SuggestSimilarTags(tag)
+				if err != nil {
+					return err
+				}
+				if len(similar) > 0 {
+					fmt.Printf("⚠️  '%s' similar to existing: %s\n", tag, strings.Join(similar, ", "))
+				}
+			}
+			if err := store.CreateTags(tags); err != nil {
+				return err
+			}
+			for _, tag := range tags {
+				fmt.Printf("Added tag: %s\n", tag)
+			}
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "search <partial>",
+		Short: "Find tags containing a substring",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			partial := strings.ToLower(args[0])
+			matches, err := store.SearchTags(partial)
+			if err != nil {
+				return err
+			}
+			if len(matches) == 0 {
+				fmt.Printf("No tags matching '%s'\n", partial)
+				return nil
+			}
+			fmt.Printf("Tags matching '%s':\n", partial)
+			for _, name := range matches {
+				fmt.Printf("  %s\n", name)
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
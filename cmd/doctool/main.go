@@ -0,0 +1,492 @@
+// Command doctool manages shadow documentation and collaboration memory inside the
+// capsule container. It is cross-compiled for Linux, embedded in the capsule binary,
+// and installed into the encrypted volume at bootstrap.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+)
+
+// Issue markers used in human-readable output.
+const (
+	markError   = "❌"
+	markWarning = "⚠️"
+	markInfo    = "ℹ️"
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "doctool",
+		Short: "Documentation lifecycle and memory tooling for _docs/",
+		Long: `doctool manages the shadow documentation in /workspace/_docs: templates,
+validation, link checking, a tagged document index, and the FTS5-backed
+collaboration memory. Run 'doctool mcp' to serve the same tools over MCP.
+
+Genres: ` + strings.Join(docsync.ValidGenres, ", ") + `
+Tags: Free-form subject tags (at least one required on register)`,
+		SilenceUsage: true,
+	}
+
+	rootCmd.PersistentFlags().String("docs-root", docsync.DefaultDocsRoot, "Shadow documentation directory")
+
+	rootCmd.AddCommand(
+		newCreateCmd(),
+		newValidateCmd(),
+		newLintCmd(),
+		newLinksCmd(),
+		newArchiveCmd(),
+		newMigrateCmd(),
+		newIndexCmd(),
+		newReportCmd(),
+		newMemoryCmd(),
+		newMCPCmd(),
+	)
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// docsRoot returns the --docs-root flag value.
+func docsRoot(cmd *cobra.Command) (string, error) {
+	root, err := cmd.Flags().GetString("docs-root")
+	if err != nil {
+		return "", fmt.Errorf("invalid docs-root flag: %w", err)
+	}
+	return root, nil
+}
+
+// openStore opens the index for the --docs-root directory, which must already be initialized.
+func openStore(cmd *cobra.Command) (*docsync.Store, error) {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return docsync.OpenExisting(root)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func issueMarker(severity string) string {
+	switch severity {
+	case docsync.SeverityError:
+		return markError
+	case docsync.SeverityWarning:
+		return markWarning
+	default:
+		return markInfo
+	}
+}
+
+func issueLine(issue docsync.Issue) string {
+	if issue.Line > 0 {
+		return fmt.Sprintf(" (line %d)", issue.Line)
+	}
+	return ""
+}
+
+func newCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create <type> <name>",
+		Short: "Create a document from a template",
+		Long:  "Creates a document from a template and registers it. Types: " + strings.Join(docsync.TemplateTypes, ", "),
+		Args:  cobra.ExactArgs(2),
+		RunE:  runCreate,
+	}
+	cmd.Flags().String("tags", "", "Comma-separated subject tags")
+	return cmd
+}
+
+func runCreate(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+	tagsFlag, err := cmd.Flags().GetString("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+	docType, name := args[0], args[1]
+
+	path, content, err := docsync.RenderTemplate(root, docType, name, "")
+	if err != nil {
+		return err
+	}
+
+	fullPath := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), constants.PublicFilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Created: %s\n", path)
+
+	result := docsync.Validate(root, path)
+	if len(result.Issues) > 0 {
+		fmt.Printf("\nValidation (%d errors, %d warnings):\n", result.ErrorCount(), result.WarningCount())
+		for _, issue := range result.Issues {
+			fmt.Printf("  %s %s\n", issueMarker(issue.Severity), issue.Message)
+		}
+	}
+
+	// Template type doubles as genre; subject tags are inferred from the shadow path
+	genre := docType
+	if !docsync.IsValidGenre(genre) {
+		genre = "reference"
+	}
+	tags := splitList(tagsFlag)
+	for _, area := range []string{"infra", "agents", "apps", "shared", "pipelines"} {
+		if (strings.Contains(path, "shadow/"+area) || strings.Contains(path, area+"/")) && !slices.Contains(tags, area) {
+			tags = append(tags, area)
+		}
+	}
+	if len(tags) == 0 {
+		tags = []string{"untagged"}
+	}
+
+	store, err := docsync.OpenExisting(root)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	registered, err := store.Register(path, genre, tags, "")
+	if err != nil {
+		return err
+	}
+	printRegistered(registered)
+	return nil
+}
+
+func printRegistered(r *docsync.RegisterResult) {
+	if len(r.Suggestions) > 0 {
+		fmt.Println("⚠️  Similar tags already exist:")
+		for tag, similar := range r.Suggestions {
+			fmt.Printf("   '%s' → did you mean: %s?\n", tag, strings.Join(similar, ", "))
+		}
+		fmt.Println("   (Proceeding with your tags. Use existing tags to avoid duplication.)")
+	}
+	fmt.Printf("Registered: %s\n", r.Path)
+	fmt.Printf("  Genre: %s\n", r.Genre)
+	fmt.Printf("  Tags: %s\n", strings.Join(r.Tags, ", "))
+}
+
+func newValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Validate a document (or all documents with --all)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runValidate,
+	}
+	cmd.Flags().Bool("all", false, "Validate every document under _docs/")
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	if !all && len(args) == 0 {
+		return fmt.Errorf("usage: doctool validate <path> | --all")
+	}
+
+	// Validating counts as reading; the index is optional here
+	store, _ := docsync.OpenExisting(root)
+	if store != nil {
+		defer store.Close()
+	}
+	markRead := func(path string) {
+		if store != nil {
+			store.MarkRead(path)
+		}
+	}
+
+	if !all {
+		path := args[0]
+		result := docsync.Validate(root, path)
+		markRead(path)
+
+		if len(result.Issues) == 0 {
+			fmt.Printf("✅ %s is valid\n", path)
+			return nil
+		}
+		fmt.Printf("Validation issues for %s (%s):\n", path, result.DocType)
+		for _, issue := range result.Issues {
+			fmt.Printf("  %s %s%s\n", issueMarker(issue.Severity), issue.Message, issueLine(issue))
+		}
+		return nil
+	}
+
+	results, err := docsync.ValidateAll(root)
+	if err != nil {
+		return err
+	}
+	errors, warnings := 0, 0
+	for _, r := range results {
+		errors += r.ErrorCount()
+		warnings += r.WarningCount()
+		markRead(r.Path)
+	}
+
+	fmt.Printf("Validated %d documents\n", len(results))
+	fmt.Printf("Errors: %d, Warnings: %d\n", errors, warnings)
+	for _, r := range results {
+		if len(r.Issues) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", r.Path)
+		for _, issue := range r.Issues {
+			fmt.Printf("  %s %s%s\n", issueMarker(issue.Severity), issue.Message, issueLine(issue))
+		}
+	}
+	return nil
+}
+
+func newLintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint",
+		Short: "Validate all documents and check links",
+		Args:  cobra.NoArgs,
+		RunE:  runLint,
+	}
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+
+	results, err := docsync.ValidateAll(root)
+	if err != nil {
+		return err
+	}
+	linkIssues, err := docsync.CheckAllLinks(root)
+	if err != nil {
+		return err
+	}
+
+	errors, warnings, broken := 0, 0, 0
+	for _, r := range results {
+		errors += r.ErrorCount()
+		warnings += r.WarningCount()
+	}
+	for _, issues := range linkIssues {
+		broken += len(issues)
+	}
+
+	fmt.Println("Documentation Lint Report")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Documents: %d\n", len(results))
+	fmt.Printf("Errors: %d\n", errors)
+	fmt.Printf("Warnings: %d\n", warnings)
+	fmt.Printf("Broken links: %d\n", broken)
+
+	if errors > 0 || warnings > 0 {
+		fmt.Println("\nValidation Issues:")
+		for _, r := range results {
+			if r.ErrorCount()+r.WarningCount() == 0 {
+				continue
+			}
+			fmt.Printf("\n  %s:\n", r.Path)
+			for _, issue := range r.Issues {
+				if issue.Severity != docsync.SeverityInfo {
+					fmt.Printf("    %s %s\n", issueMarker(issue.Severity), issue.Message)
+				}
+			}
+		}
+	}
+
+	if len(linkIssues) > 0 {
+		fmt.Println("\nBroken Links:")
+		for _, path := range sortedKeys(linkIssues) {
+			fmt.Printf("\n  %s:\n", path)
+			for _, issue := range linkIssues[path] {
+				fmt.Printf("    %s %s (line %d)\n", markError, issue.Message, issue.Line)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func newLinksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "links",
+		Short: "Check internal links",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "check",
+		Short: "List broken internal links",
+		Args:  cobra.NoArgs,
+		RunE:  runLinksCheck,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "report",
+		Short: "Summarize link integrity",
+		Args:  cobra.NoArgs,
+		RunE:  runLinksReport,
+	})
+
+	return cmd
+}
+
+func runLinksCheck(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+	issues, err := docsync.CheckAllLinks(root)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("✅ All internal links are valid")
+		return nil
+	}
+
+	total := 0
+	for _, i := range issues {
+		total += len(i)
+	}
+	fmt.Printf("Found %d broken links:\n", total)
+	for _, path := range sortedKeys(issues) {
+		fmt.Printf("\n  %s:\n", path)
+		for _, issue := range issues[path] {
+			fmt.Printf("    Line %d: %s\n", issue.Line, issue.Message)
+		}
+	}
+	return nil
+}
+
+func runLinksReport(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+	issues, err := docsync.CheckAllLinks(root)
+	if err != nil {
+		return err
+	}
+	files, err := docsync.MarkdownFiles(root)
+	if err != nil {
+		return err
+	}
+
+	total, broken := 0, 0
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(root, f))
+		if err != nil {
+			continue
+		}
+		total += len(docsync.FindLinks(string(data)))
+	}
+	for _, i := range issues {
+		broken += len(i)
+	}
+
+	health := 100.0
+	if total > 0 {
+		health = float64(total-broken) / float64(total) * 100
+	}
+
+	fmt.Println("Link Integrity Report")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Total internal links: %d\n", total)
+	fmt.Printf("Broken links: %d\n", broken)
+	fmt.Printf("Health: %.1f%%\n", health)
+	return nil
+}
+
+func newArchiveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "archive <path> [reason...]",
+		Short: "Mark a document as deprecated",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := docsRoot(cmd)
+			if err != nil {
+				return err
+			}
+			if err := docsync.Archive(root, args[0], strings.Join(args[1:], " ")); err != nil {
+				return err
+			}
+			fmt.Printf("Archived: %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate <old_path> <new_path>",
+		Short: "Move a document and update references to it",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			updated, err := store.Migrate(args[0], args[1], true)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Moved: %s -> %s\n", args[0], args[1])
+			for _, path := range updated {
+				fmt.Printf("  Updated reference in: %s\n", path)
+			}
+			return nil
+		},
+	}
+}
+
+func newMCPCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve doc-sync tools over MCP (JSON-RPC on stdio)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := docsRoot(cmd)
+			if err != nil {
+				return err
+			}
+			return docsync.NewServer(root).Serve(os.Stdin, os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+)
+
+// recentPreviewLength limits the one-line preview shown by 'memory recent'.
+const recentPreviewLength = 60
+
+func newMemoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "FTS5-based memory for AI collaboration",
+	}
+
+	cmd.AddCommand(
+		newMemoryIngestCmd(),
+		newMemoryRefreshCmd(),
+		newMemoryAddCmd(),
+		newMemorySearchCmd(),
+		newMemoryStatsCmd(),
+		newMemoryRecentCmd(),
+	)
+
+	return cmd
+}
+
+func newMemoryIngestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ingest [path]",
+		Short: "Add a new document (or all indexed documents with --all) to memory",
+		Long:  "Adds new documents to memory. Use 'memory refresh' to update documents already ingested.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runMemoryIngest,
+	}
+	cmd.Flags().Bool("all", false, "Ingest every indexed document")
+	cmd.Flags().String("tags", "", "Comma-separated tags (inferred from the path if omitted)")
+	return cmd
+}
+
+func runMemoryIngest(cmd *cobra.Command, args []string) error {
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	tagsFlag, err := cmd.Flags().GetString("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+	if !all && len(args) == 0 {
+		return fmt.Errorf("usage: doctool memory ingest <path> | --all")
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if !all {
+		count, err := store.Ingest(args[0], splitList(tagsFlag))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Ingested: %s (%d chunks)\n", args[0], count)
+		return nil
+	}
+
+	docs, err := store.QueryAll()
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, d := range docs {
+		count, err := store.Ingest(d.Path, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		fmt.Printf("Ingested: %s (%d chunks)\n", d.Path, count)
+		total += count
+	}
+	fmt.Printf("\nTotal: %d chunks from %d documents\n", total, len(docs))
+	return nil
+}
+
+func newMemoryRefreshCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "refresh [path]",
+		Short: "Rebuild document chunks from their source files",
+		Long: `Deletes a document's chunks and re-ingests its current content.
+Notes and decisions are preserved. Use --all to refresh every ingested document.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runMemoryRefresh,
+	}
+	cmd.Flags().Bool("all", false, "Refresh every ingested document")
+	return cmd
+}
+
+func runMemoryRefresh(cmd *cobra.Command, args []string) error {
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return fmt.Errorf("invalid all flag: %w", err)
+	}
+	if !all && len(args) == 0 {
+		return fmt.Errorf("usage: doctool memory refresh <path> | --all")
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	path := ""
+	if !all {
+		path = args[0]
+	}
+	result, err := store.Refresh(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Refreshed %d source(s)\n", result.Sources)
+	fmt.Printf("  Deleted: %d stale chunks\n", result.Deleted)
+	fmt.Printf("  Ingested: %d fresh chunks\n", result.Ingested)
+	return nil
+}
+
+func newMemoryAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [content...]",
+		Short: "Add a note or decision to memory",
+		Long:  "Adds a note directly. Content is read from stdin when not given as arguments.",
+		RunE:  runMemoryAdd,
+	}
+	cmd.Flags().String("tags", "", "Comma-separated tags (required)")
+	cmd.Flags().String("type", "note", "Memory type (note, decision, session)")
+	cmd.Flags().String("source", "", "Source identifier (defaults to session:YYYY-MM-DD)")
+	return cmd
+}
+
+func runMemoryAdd(cmd *cobra.Command, args []string) error {
+	tagsFlag, err := cmd.Flags().GetString("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+	chunkType, err := cmd.Flags().GetString("type")
+	if err != nil {
+		return fmt.Errorf("invalid type flag: %w", err)
+	}
+	source, err := cmd.Flags().GetString("source")
+	if err != nil {
+		return fmt.Errorf("invalid source flag: %w", err)
+	}
+
+	content := strings.Join(args, " ")
+	if strings.TrimSpace(content) == "" {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Println("Enter content (Ctrl+D to finish):")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read content from stdin: %w", err)
+		}
+		content = strings.TrimSpace(string(data))
+	}
+
+	if content == "" {
+		return fmt.Errorf("content cannot be empty. Usage: doctool memory add <content> --tags t1,t2")
+	}
+	tags := splitList(tagsFlag)
+	if len(tags) == 0 {
+		return fmt.Errorf("tags required. Use --tags t1,t2")
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	added, err := store.Add(content, tags, source, chunkType)
+	if err != nil {
+		return err
+	}
+	if !added {
+		fmt.Println("Note already exists (duplicate content)")
+		return nil
+	}
+
+	fmt.Printf("Added %s: %s...\n", chunkType, truncate(content, 50))
+	fmt.Printf("  Tags: %s\n", strings.Join(tags, ", "))
+	if source != "" {
+		fmt.Printf("  Source: %s\n", source)
+	}
+	return nil
+}
+
+func newMemorySearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query...>",
+		Short: "Search memory by text and tags",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runMemorySearch,
+	}
+	cmd.Flags().String("tags", "", "Comma-separated tags to include in the search")
+	cmd.Flags().Int("limit", 10, "Maximum number of results")
+	cmd.Flags().Bool("json", false, "Print results as JSON")
+	return cmd
+}
+
+func runMemorySearch(cmd *cobra.Command, args []string) error {
+	tagsFlag, err := cmd.Flags().GetString("tags")
+	if err != nil {
+		return fmt.Errorf("invalid tags flag: %w", err)
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return fmt.Errorf("invalid limit flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("invalid json flag: %w", err)
+	}
+	query := strings.Join(args, " ")
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	results, err := store.Search(query, splitList(tagsFlag), limit)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if results == nil {
+			results = []docsync.Result{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	docsync.WriteResults(os.Stdout, query, results)
+	return nil
+}
+
+func newMemoryStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show memory statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStore(cmd)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			stats, err := store.MemoryStatistics()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Memory System Statistics")
+			fmt.Println(strings.Repeat("=", 40))
+			fmt.Printf("Total chunks:        %d\n", stats.TotalChunks)
+			fmt.Printf("Unique sources:      %d\n", stats.UniqueSources)
+			fmt.Println("\nBy type:")
+			for _, chunkType := range sortedKeys(stats.ByType) {
+				fmt.Printf("  %-15s %d\n", chunkType, stats.ByType[chunkType])
+			}
+			fmt.Println("\nTop sources:")
+			for _, sc := range stats.TopSources {
+				fmt.Printf("  %-35s %d\n", sc.Source, sc.Count)
+			}
+			return nil
+		},
+	}
+}
+
+func newMemoryRecentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recent",
+		Short: "List recently added memories",
+		Args:  cobra.NoArgs,
+		RunE:  runMemoryRecent,
+	}
+	cmd.Flags().Int("days", 7, "Number of days to look back")
+	cmd.Flags().Int("limit", 10, "Maximum number of results")
+	return cmd
+}
+
+func runMemoryRecent(cmd *cobra.Command, args []string) error {
+	days, err := cmd.Flags().GetInt("days")
+	if err != nil {
+		return fmt.Errorf("invalid days flag: %w", err)
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return fmt.Errorf("invalid limit flag: %w", err)
+	}
+
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	results, err := store.Recent(days, limit)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Printf("No memories in the last %d days\n", days)
+		return nil
+	}
+
+	fmt.Printf("Recent memories (last %d days):\n\n", days)
+	for _, r := range results {
+		preview := strings.ReplaceAll(truncate(r.Content, recentPreviewLength), "\n", " ")
+		fmt.Printf("[%s] (%dd ago) %s - %s...\n", truncate(r.CreatedAt, 10), r.AgeDays, r.Type, preview)
+		fmt.Printf("  Tags: %s\n\n", r.Tags)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+)
+
+// Report thresholds and limits.
+const (
+	staleDays          = 30
+	veryStaleDays      = 90
+	maxUntrackedListed = 10
+)
+
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate documentation reports",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "freshness",
+		Short: "Report documents that have not been updated recently",
+		Args:  cobra.NoArgs,
+		RunE:  runFreshnessReport,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "quality",
+		Short: "Report validation issues across all documents",
+		Args:  cobra.NoArgs,
+		RunE:  runQualityReport,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "coverage",
+		Short: "Report documents per tag and untracked documents",
+		Args:  cobra.NoArgs,
+		RunE:  runCoverageReport,
+	})
+
+	return cmd
+}
+
+func runFreshnessReport(cmd *cobra.Command, args []string) error {
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	stale, err := store.QueryStale(staleDays)
+	if err != nil {
+		return err
+	}
+	veryStale, err := store.QueryStale(veryStaleDays)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Documentation Freshness Report")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("\nStale (>%d days): %d\n", staleDays, len(stale))
+	fmt.Printf("Very stale (>%d days): %d\n", veryStaleDays, len(veryStale))
+
+	if len(veryStale) > 0 {
+		fmt.Println("\nVery Stale Documents:")
+		for _, d := range veryStale {
+			fmt.Printf("  %s (%d days)\n", d.Path, d.DaysStale)
+		}
+	}
+	if len(stale) > 0 {
+		fmt.Println("\nStale Documents:")
+		for _, d := range stale {
+			if d.DaysStale <= veryStaleDays {
+				fmt.Printf("  %s (%d days)\n", d.Path, d.DaysStale)
+			}
+		}
+	}
+	return nil
+}
+
+func runQualityReport(cmd *cobra.Command, args []string) error {
+	root, err := docsRoot(cmd)
+	if err != nil {
+		return err
+	}
+	results, err := docsync.ValidateAll(root)
+	if err != nil {
+		return err
+	}
+
+	errors, warnings := 0, 0
+	for _, r := range results {
+		errors += r.ErrorCount()
+		warnings += r.WarningCount()
+	}
+
+	fmt.Println("Documentation Quality Report")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("\nDocuments checked: %d\n", len(results))
+	fmt.Printf("Total errors: %d\n", errors)
+	fmt.Printf("Total warnings: %d\n", warnings)
+
+	if errors > 0 || warnings > 0 {
+		fmt.Println("\nIssues by document:")
+		for _, r := range results {
+			if len(r.Issues) == 0 {
+				continue
+			}
+			fmt.Printf("\n  %s (%s):\n", r.Path, r.DocType)
+			for _, issue := range r.Issues {
+				fmt.Printf("    %s %s%s\n", issueMarker(issue.Severity), issue.Message, issueLine(issue))
+			}
+		}
+	}
+	return nil
+}
+
+func runCoverageReport(cmd *cobra.Command, args []string) error {
+	store, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tags, err := store.ListTags()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Documentation Coverage Report")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Println("\nDocuments by Tag:")
+	for _, t := range tags {
+		bar := "░"
+		if t.DocCount > 0 {
+			bar = strings.Repeat("█", t.DocCount)
+		}
+		fmt.Printf("  %-20s %3d %s\n", t.Name, t.DocCount, bar)
+	}
+
+	untracked, err := store.QueryUntracked()
+	if err != nil {
+		return err
+	}
+	if len(untracked) > 0 {
+		fmt.Printf("\nUntracked documents: %d\n", len(untracked))
+		for _, path := range untracked[:min(maxUntrackedListed, len(untracked))] {
+			fmt.Printf("  %s\n", path)
+		}
+		if len(untracked) > maxUntrackedListed {
+			fmt.Printf("  ... and %d more\n", len(untracked)-maxUntrackedListed)
+		}
+	}
+	return nil
+}
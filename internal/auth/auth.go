@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Credential locations inside the encrypted volume, relative to the volume root.
+const (
+	// APIKeyFile holds the Anthropic API key (exposed in the container as ANTHROPIC_API_KEY_FILE).
+	APIKeyFile = "auth/api-key"
+
+	// OAuthCredentialsFile holds Claude Code's OAuth login on Linux.
+	OAuthCredentialsFile = "home/.claude/.credentials.json"
+)
+
+// IdentityPaths are the credential locations an identity volume holds in
+// place of the data volume, relative to the volume root.
+var IdentityPaths = []string{filepath.Dir(APIKeyFile), OAuthCredentialsFile}
+
+// DefaultAPIBaseURL is the Anthropic API endpoint used to verify keys.
+const DefaultAPIBaseURL = "https://api.anthropic.com"
+
+const (
+	apiVersion  = "2023-06-01"
+	testTimeout = 15 * time.Second
+)
+
+// Credential describes a stored credential file.
+type Credential struct {
+	Path     string
+	Present  bool
+	Modified time.Time
+}
+
+// ReadAPIKey returns the stored API key, or "" if none is set.
+func ReadAPIKey(mountPoint string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, APIKeyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read API key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// WriteAPIKey stores the API key with owner-only permissions, replacing any existing key.
+func WriteAPIKey(mountPoint, key string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("API key cannot be empty")
+	}
+
+	path := filepath.Join(mountPoint, APIKeyFile)
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(key), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write API key: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// RemoveAPIKey deletes the stored API key. Returns false if none was set.
+func RemoveAPIKey(mountPoint string) (bool, error) {
+	return removeFile(filepath.Join(mountPoint, APIKeyFile))
+}
+
+// RemoveOAuth deletes Claude Code's stored OAuth login. Returns false if none was set.
+func RemoveOAuth(mountPoint string) (bool, error) {
+	return removeFile(filepath.Join(mountPoint, OAuthCredentialsFile))
+}
+
+// PrepareIdentity creates what a session mounts from the identity volume at
+// mountPoint: the auth/ directory, and an empty OAuthCredentialsFile until
+// Claude Code logs in. Docker can only bind-mount a file that exists, and the
+// mount keeps a login from landing in the data volume instead.
+func PrepareIdentity(mountPoint string) error {
+	if err := os.MkdirAll(filepath.Join(mountPoint, filepath.Dir(APIKeyFile)), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create auth directory: %w", err)
+	}
+	path := filepath.Join(mountPoint, OAuthCredentialsFile)
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, constants.FilePermissions)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// Stat reports whether a credential file (APIKeyFile or OAuthCredentialsFile)
+// exists. An empty file, such as PrepareIdentity's placeholder, holds no
+// credential and is reported as absent.
+func Stat(mountPoint, file string) (Credential, error) {
+	cred := Credential{Path: filepath.Join(mountPoint, file)}
+	info, err := os.Stat(cred.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cred, nil
+		}
+		return cred, fmt.Errorf("failed to stat %s: %w", cred.Path, err)
+	}
+	cred.Present = info.Size() > 0
+	cred.Modified = info.ModTime()
+	return cred, nil
+}
+
+// MaskKey shows enough of a key to recognize it without revealing it.
+func MaskKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:7] + "..." + key[len(key)-4:]
+}
+
+// TestAPIKey verifies the key by listing models with the Anthropic API.
+func TestAPIKey(ctx context.Context, baseURL, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, testTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/v1/models?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", key)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// Surface the API's error message when there is one
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("API returned %s: %s", resp.Status, apiErr.Error.Message)
+	}
+	return fmt.Errorf("API returned %s", resp.Status)
+}
+
+func removeFile(path string) (bool, error) {
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	mountPoint := t.TempDir()
+
+	if key, err := ReadAPIKey(mountPoint); err != nil || key != "" {
+		t.Fatalf("ReadAPIKey() on empty volume = %q, %v", key, err)
+	}
+	if err := WriteAPIKey(mountPoint, "  sk-ant-REDACTED\n"); err != nil {
+		t.Fatalf("WriteAPIKey() error = %v", err)
+	}
+	key, err := ReadAPIKey(mountPoint)
+	if err != nil || key != "sk-ant-REDACTED" {
+		t.Fatalf("ReadAPIKey() = %q, %v", key, err)
+	}
+	info, err := os.Stat(filepath.Join(mountPoint, APIKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("API key permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	removed, err := RemoveAPIKey(mountPoint)
+	if err != nil || !removed {
+		t.Fatalf("RemoveAPIKey() = %v, %v", removed, err)
+	}
+	if removed, _ := RemoveAPIKey(mountPoint); removed {
+		t.Errorf("RemoveAPIKey() twice = true, want false")
+	}
+}
+
+func TestPrepareIdentity(t *testing.T) {
+	mountPoint := t.TempDir()
+	if err := PrepareIdentity(mountPoint); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(mountPoint, filepath.Dir(APIKeyFile))); err != nil || !info.IsDir() {
+		t.Errorf("auth directory = %v, %v", info, err)
+	}
+	if cred, err := Stat(mountPoint, OAuthCredentialsFile); err != nil || cred.Present {
+		t.Errorf("Stat() of the placeholder = %+v, %v; want absent", cred, err)
+	}
+
+	// A login is kept
+	login := filepath.Join(mountPoint, OAuthCredentialsFile)
+	if err := os.WriteFile(login, []byte(`{"claudeAiOauth":{}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrepareIdentity(mountPoint); err != nil {
+		t.Fatal(err)
+	}
+	if cred, err := Stat(mountPoint, OAuthCredentialsFile); err != nil || !cred.Present {
+		t.Errorf("Stat() after login = %+v, %v; want present", cred, err)
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	if got := MaskKey("sk-ant-REDACTED"); got != "sk-ant-...mnop" {
+		t.Errorf("MaskKey() = %q", got)
+	}
+	if got := MaskKey("short"); got != "*****" {
+		t.Errorf("MaskKey(short) = %q", got)
+	}
+}
+
+func TestTestAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("anthropic-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("x-api-key") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	if err := TestAPIKey(context.Background(), server.URL, "good"); err != nil {
+		t.Errorf("TestAPIKey(good) error = %v", err)
+	}
+	err := TestAPIKey(context.Background(), server.URL, "bad")
+	if err == nil || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Errorf("TestAPIKey(bad) error = %v, want API message", err)
+	}
+}
@@ -0,0 +1,151 @@
+package autolock
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Event is a macOS session event that can trigger a lock.
+type Event string
+
+const (
+	// ScreenLocked fires when the screen locks (lock shortcut, lid close, sleep with password).
+	ScreenLocked Event = "screen-locked"
+
+	// ScreensaverStarted fires when the screensaver starts, which may be before the screen locks.
+	ScreensaverStarted Event = "screensaver-started"
+)
+
+// notifications maps distributed notification names to events.
+var notifications = map[string]Event{
+	"com.apple.screenIsLocked":       ScreenLocked,
+	"com.apple.screensaver.didstart": ScreensaverStarted,
+}
+
+// LaunchAgentLabel identifies the auto-lock LaunchAgent.
+const LaunchAgentLabel = "com.jeanhaley32.capsule.autolock"
+
+// observerScript subscribes to distributed notifications from JavaScript for
+// Automation, so no compiled helper is needed. Each notification name is
+// printed on its own line (console.log writes to stderr).
+const observerScript = `
+ObjC.import('Foundation');
+ObjC.registerSubclass({
+  name: 'CapsuleAutoLockObserver',
+  methods: {
+    'notify:': {
+      types: ['void', ['id']],
+      implementation: function (n) { console.log(ObjC.unwrap(n.name)); }
+    }
+  }
+});
+var observer = $.CapsuleAutoLockObserver.alloc.init;
+var center = $.NSDistributedNotificationCenter.defaultCenter;
+NAMES.forEach(function (name) {
+  center.addObserverSelectorNameObject(observer, 'notify:', name, $());
+});
+$.NSRunLoop.currentRunLoop.run;
+`
+
+// Watch reports the requested events until ctx is cancelled or the helper exits.
+func Watch(ctx context.Context, events []Event, onEvent func(Event)) error {
+	var names []string
+	for name, event := range notifications {
+		for _, wanted := range events {
+			if event == wanted {
+				names = append(names, fmt.Sprintf("%q", name))
+			}
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no events to watch")
+	}
+
+	script := strings.Replace(observerScript, "NAMES", "["+strings.Join(names, ", ")+"]", 1)
+	cmd := exec.CommandContext(ctx, "osascript", "-l", "JavaScript", "-e", script)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start notification helper: %w", err)
+	}
+
+	scanEvents(stderr, onEvent)
+
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("notification helper exited: %w", err)
+	}
+	return nil
+}
+
+// scanEvents reads notification names from the helper and reports known events.
+func scanEvents(r io.Reader, onEvent func(Event)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if event, ok := notifications[strings.TrimSpace(scanner.Text())]; ok {
+			onEvent(event)
+		}
+	}
+}
+
+// ParseEvents parses a comma-separated list of event names.
+func ParseEvents(value string) ([]Event, error) {
+	var events []Event
+	for _, part := range strings.Split(value, ",") {
+		switch Event(strings.TrimSpace(part)) {
+		case ScreenLocked:
+			events = append(events, ScreenLocked)
+		case ScreensaverStarted:
+			events = append(events, ScreensaverStarted)
+		default:
+			return nil, fmt.Errorf("unknown event %q (valid: %s, %s)", part, ScreenLocked, ScreensaverStarted)
+		}
+	}
+	return events, nil
+}
+
+// LaunchAgentPath returns ~/Library/LaunchAgents/<label>.plist.
+func LaunchAgentPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", LaunchAgentLabel+".plist"), nil
+}
+
+// LaunchAgentPlist returns a LaunchAgent that runs the watcher at login and
+// restarts it if it exits.
+func LaunchAgentPlist(program []string, logPath string) string {
+	var args strings.Builder
+	for _, arg := range program {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", html.EscapeString(arg))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, LaunchAgentLabel, args.String(), html.EscapeString(logPath), html.EscapeString(logPath))
+}
@@ -0,0 +1,41 @@
+package autolock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanEvents(t *testing.T) {
+	output := "com.apple.screensaver.didstart\nsome osascript warning\ncom.apple.screenIsLocked\n"
+
+	var got []Event
+	scanEvents(strings.NewReader(output), func(e Event) { got = append(got, e) })
+
+	if len(got) != 2 || got[0] != ScreensaverStarted || got[1] != ScreenLocked {
+		t.Errorf("scanEvents() = %v, want [%s %s]", got, ScreensaverStarted, ScreenLocked)
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("screen-locked, screensaver-started")
+	if err != nil || len(events) != 2 {
+		t.Fatalf("ParseEvents() = %v, %v", events, err)
+	}
+	if _, err := ParseEvents("lid-closed"); err == nil {
+		t.Errorf("ParseEvents(lid-closed) error = nil, want error")
+	}
+}
+
+func TestLaunchAgentPlist(t *testing.T) {
+	plist := LaunchAgentPlist([]string{"/usr/local/bin/capsule", "autolock", "run", "--on", "screen-locked"}, "/Users/me/.capsule/autolock.log")
+	for _, want := range []string{
+		"<string>" + LaunchAgentLabel + "</string>",
+		"<string>/usr/local/bin/capsule</string>",
+		"<string>--on</string>",
+		"<key>KeepAlive</key>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package beads
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+)
+
+// Version is the pinned bd release installed into the volume.
+const Version = "0.29.0"
+
+// releaseBaseURL is the GitHub release download location for bd.
+const releaseBaseURL = "https://github.com/steveyegge/beads/releases/download"
+
+// downloadTimeout bounds each release asset download.
+const downloadTimeout = 2 * time.Minute
+
+// Paths within the encrypted volume.
+const (
+	// BinDir holds tools installed by capsule; it is on the container PATH as /claude-env/bin.
+	BinDir = "bin"
+
+	// BinaryName is the bd executable name.
+	BinaryName = "bd"
+
+	// versionFile records the installed bd version, since the Linux binary cannot run on the host.
+	versionFile = ".bd-version"
+
+	// projectDir is the per-repository beads directory under repos/<repoID>.
+	projectDir = ".beads"
+)
+
+// ProjectUsage is the on-disk size of one project's beads data.
+type ProjectUsage struct {
+	RepoID string
+	Bytes  int64
+}
+
+// Status describes the bd installation and per-project databases in a volume.
+type Status struct {
+	PinnedVersion    string
+	InstalledVersion string // Empty if bd is not installed
+	Projects         []ProjectUsage
+}
+
+// UpToDate reports whether the installed bd matches the pinned version.
+func (s *Status) UpToDate() bool {
+	return s.InstalledVersion == s.PinnedVersion
+}
+
+// BinaryPath returns the host path of the bd binary in the mounted volume.
+func BinaryPath(mountPoint string) string {
+	return filepath.Join(mountPoint, BinDir, BinaryName)
+}
+
+// InstalledVersion returns the bd version installed in the volume, or "" if none.
+func InstalledVersion(mountPoint string) string {
+	if _, err := os.Stat(BinaryPath(mountPoint)); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(mountPoint, BinDir, versionFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// assetName returns the release archive name for a Linux architecture.
+func assetName(version, arch string) string {
+	return fmt.Sprintf("beads_%s_linux_%s.tar.gz", version, arch)
+}
+
+// Install downloads the pinned bd release for the container architecture and
+// writes it to the volume. The archive is verified against the release checksums.
+// The container shares the host CPU architecture under Docker Desktop.
+func Install(mountPoint string) error {
+	return install(mountPoint, fmt.Sprintf("%s/v%s", releaseBaseURL, Version), Version, runtime.GOARCH)
+}
+
+// install installs bd version for arch from the release assets at baseURL.
+func install(mountPoint, baseURL, version, arch string) error {
+	client := &http.Client{Timeout: downloadTimeout}
+	asset := assetName(version, arch)
+
+	checksums, err := download(client, baseURL+"/checksums.txt")
+	if err != nil {
+		return fmt.Errorf("failed to download bd checksums: %w", err)
+	}
+	expected, err := findChecksum(checksums, asset)
+	if err != nil {
+		return err
+	}
+
+	archive, err := download(client, baseURL+"/"+asset)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset, err)
+	}
+	if err := verifyChecksum(archive, expected); err != nil {
+		return fmt.Errorf("%s: %w", asset, err)
+	}
+
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return fmt.Errorf("failed to extract bd from %s: %w", asset, err)
+	}
+
+	binDir := filepath.Join(mountPoint, BinDir)
+	if err := os.MkdirAll(binDir, constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", binDir, err)
+	}
+
+	// Write to a temp file and rename so a running container never sees a partial binary
+	binPath := BinaryPath(mountPoint)
+	tmpPath := binPath + ".tmp"
+	if err := os.WriteFile(tmpPath, binary, constants.ExecutablePermissions); err != nil {
+		return fmt.Errorf("failed to write bd: %w", err)
+	}
+	if err := os.Rename(tmpPath, binPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to install bd: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(binDir, versionFile), []byte(version+"\n"), constants.PublicFilePermissions); err != nil {
+		return fmt.Errorf("failed to record bd version: %w", err)
+	}
+
+	return nil
+}
+
+// download fetches url and returns the response body.
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// findChecksum returns the sha256 for asset from a goreleaser checksums.txt.
+func findChecksum(checksums []byte, asset string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == asset {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum listed for %s", asset)
+}
+
+// verifyChecksum checks data against a hex-encoded sha256.
+func verifyChecksum(data []byte, expected string) error {
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// extractBinary returns the bd executable from a release tarball. Entries
+// with absolute paths or ".." components are rejected rather than skipped: a
+// release archive never has them.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return nil, fmt.Errorf("archive entry %q is outside the archive", header.Name)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == BinaryName {
+			return io.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain %s", BinaryName)
+}
+
+// GetStatus reports the installed bd version and the size of each project's beads data.
+func GetStatus(mountPoint string) (*Status, error) {
+	status := &Status{
+		PinnedVersion:    Version,
+		InstalledVersion: InstalledVersion(mountPoint),
+	}
+
+	reposDir := filepath.Join(mountPoint, repo.ReposDir)
+	entries, err := os.ReadDir(reposDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", reposDir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		beadsDir := filepath.Join(reposDir, entry.Name(), projectDir)
+		if _, err := os.Stat(beadsDir); err != nil {
+			continue
+		}
+		usage, err := repo.DiskUsage(beadsDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", beadsDir, err)
+		}
+		status.Projects = append(status.Projects, ProjectUsage{RepoID: entry.Name(), Bytes: usage.Bytes})
+	}
+	return status, nil
+}
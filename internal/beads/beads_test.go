@@ -0,0 +1,295 @@
+package beads
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarball returns a gzipped tar of the given entries, in order.
+func tarball(t *testing.T, entries ...tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, header := range entries {
+		content := "content of " + header.Name
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(content))
+		}
+		if header.Mode == 0 {
+			header.Mode = 0755
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFindChecksum(t *testing.T) {
+	checksums := []byte("aaa  beads_0.29.0_darwin_arm64.tar.gz\n" +
+		"bbb  beads_0.29.0_linux_arm64.tar.gz\n" +
+		"malformed line with extra fields\n" +
+		"ccc  beads_0.29.0_linux_amd64.tar.gz\n")
+	tests := []struct {
+		asset   string
+		want    string
+		wantErr bool
+	}{
+		{asset: "beads_0.29.0_linux_arm64.tar.gz", want: "bbb"},
+		{asset: "beads_0.29.0_linux_amd64.tar.gz", want: "ccc"},
+		{asset: "beads_0.29.0_linux_386.tar.gz", wantErr: true},
+		{asset: "beads_0.29.0_linux", wantErr: true}, // No prefix matches
+	}
+	for _, tt := range tests {
+		got, err := findChecksum(checksums, tt.asset)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("findChecksum(%s) = %q, %v; want %q, error %v", tt.asset, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256Hex(data)
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{"match", sum, false},
+		{"upper case", strings.ToUpper(sum), false},
+		{"mismatch", sha256Hex([]byte("tampered")), true},
+		{"truncated", sum[:32], true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		if err := verifyChecksum(data, tt.expected); (err != nil) != tt.wantErr {
+			t.Errorf("%s: verifyChecksum() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tar.Header
+		want    string
+		wantErr string
+	}{
+		{
+			name: "top level",
+			entries: []tar.Header{
+				{Name: "README.md", Typeflag: tar.TypeReg},
+				{Name: "bd", Typeflag: tar.TypeReg},
+			},
+			want: "content of bd",
+		},
+		{
+			name: "in a directory",
+			entries: []tar.Header{
+				{Name: "beads/", Typeflag: tar.TypeDir},
+				{Name: "beads/bd", Typeflag: tar.TypeReg},
+			},
+			want: "content of beads/bd",
+		},
+		{
+			name:    "parent traversal",
+			entries: []tar.Header{{Name: "../../bin/bd", Typeflag: tar.TypeReg}},
+			wantErr: "outside the archive",
+		},
+		{
+			name: "traversal before the binary",
+			entries: []tar.Header{
+				{Name: "beads/../../.profile", Typeflag: tar.TypeReg},
+				{Name: "bd", Typeflag: tar.TypeReg},
+			},
+			wantErr: "outside the archive",
+		},
+		{
+			name:    "absolute path",
+			entries: []tar.Header{{Name: "/usr/local/bin/bd", Typeflag: tar.TypeReg}},
+			wantErr: "outside the archive",
+		},
+		{
+			name:    "symlink named bd",
+			entries: []tar.Header{{Name: "bd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+			wantErr: "does not contain bd",
+		},
+		{
+			name:    "missing",
+			entries: []tar.Header{{Name: "README.md", Typeflag: tar.TypeReg}},
+			wantErr: "does not contain bd",
+		},
+	}
+	for _, tt := range tests {
+		got, err := extractBinary(tarball(t, tt.entries...))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: extractBinary() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: extractBinary() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := extractBinary([]byte("not gzip")); err == nil {
+		t.Error("extractBinary() accepted an archive that isn't gzipped")
+	}
+}
+
+// releaseServer serves checksums.txt and one archive as a bd release.
+func releaseServer(t *testing.T, asset string, archive []byte, checksum string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/checksums.txt":
+			w.Write([]byte(checksum + "  " + asset + "\n"))
+		case "/" + asset:
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInstall(t *testing.T) {
+	asset := assetName("1.2.3", "arm64")
+	archive := tarball(t, tar.Header{Name: "bd", Typeflag: tar.TypeReg})
+
+	mountPoint := t.TempDir()
+	server := releaseServer(t, asset, archive, sha256Hex(archive))
+	if err := install(mountPoint, server.URL, "1.2.3", "arm64"); err != nil {
+		t.Fatalf("install() error = %v", err)
+	}
+	if got, err := os.ReadFile(BinaryPath(mountPoint)); err != nil || string(got) != "content of bd" {
+		t.Errorf("installed bd = %q, %v", got, err)
+	}
+	if got := InstalledVersion(mountPoint); got != "1.2.3" {
+		t.Errorf("InstalledVersion() = %q, want 1.2.3", got)
+	}
+
+	// A tampered archive is rejected before anything is written
+	mountPoint = t.TempDir()
+	server = releaseServer(t, asset, archive, sha256Hex([]byte("the real archive")))
+	err := install(mountPoint, server.URL, "1.2.3", "arm64")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("install() error = %v, want a checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(mountPoint, BinDir)); !os.IsNotExist(err) {
+		t.Errorf("install() wrote to the volume after a checksum mismatch: %v", err)
+	}
+
+	// No checksum for the asset is a failure, not a skipped check
+	server = releaseServer(t, assetName("1.2.3", "amd64"), archive, sha256Hex(archive))
+	if err := install(t.TempDir(), server.URL, "1.2.3", "arm64"); err == nil || !strings.Contains(err.Error(), "no checksum") {
+		t.Errorf("install() error = %v, want no checksum listed", err)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(mountPoint string)
+		installed string
+		upToDate  bool
+		projects  []ProjectUsage
+	}{
+		{
+			name:  "empty volume",
+			setup: func(string) {},
+		},
+		{
+			name: "version file without binary",
+			setup: func(m string) {
+				writeFile(filepath.Join(m, BinDir, versionFile), Version+"\n")
+			},
+		},
+		{
+			name: "binary without version file",
+			setup: func(m string) {
+				writeFile(BinaryPath(m), "bd")
+			},
+		},
+		{
+			name: "pinned version",
+			setup: func(m string) {
+				writeFile(BinaryPath(m), "bd")
+				writeFile(filepath.Join(m, BinDir, versionFile), " "+Version+"\n")
+			},
+			installed: Version,
+			upToDate:  true,
+		},
+		{
+			name: "older version and projects",
+			setup: func(m string) {
+				writeFile(BinaryPath(m), "bd")
+				writeFile(filepath.Join(m, BinDir, versionFile), "0.1.0\n")
+				writeFile(filepath.Join(m, "repos", "alpha", projectDir, "beads.db"), "12345")
+				writeFile(filepath.Join(m, "repos", "beta", "docs", "notes.md"), "no beads here")
+				writeFile(filepath.Join(m, "repos", "stray-file"), "x")
+			},
+			installed: "0.1.0",
+			projects:  []ProjectUsage{{RepoID: "alpha", Bytes: 5}},
+		},
+	}
+	for _, tt := range tests {
+		mountPoint := t.TempDir()
+		tt.setup(mountPoint)
+		status, err := GetStatus(mountPoint)
+		if err != nil {
+			t.Fatalf("%s: GetStatus() error = %v", tt.name, err)
+		}
+		if status.PinnedVersion != Version || status.InstalledVersion != tt.installed || status.UpToDate() != tt.upToDate {
+			t.Errorf("%s: GetStatus() = %+v, want installed %q, up to date %v", tt.name, status, tt.installed, tt.upToDate)
+		}
+		if len(status.Projects) != len(tt.projects) {
+			t.Errorf("%s: projects = %+v, want %+v", tt.name, status.Projects, tt.projects)
+			continue
+		}
+		for i, p := range tt.projects {
+			if status.Projects[i] != p {
+				t.Errorf("%s: projects[%d] = %+v, want %+v", tt.name, i, status.Projects[i], p)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package bench times the operations a capsule session is made of: mounting
+// and unmounting the volume, starting the container, and running a command in
+// it. Runs are appended to ~/.capsule/bench.jsonl with the Docker version they
+// ran against, so a slowdown after a Docker Desktop or macOS update shows up
+// against the runs before it.
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// FileName is the benchmark history under the capsule config directory.
+const FileName = "bench.jsonl"
+
+// Operations timed.
+const (
+	OpMount          = "mount"
+	OpContainerStart = "container_start"
+	OpExec           = "exec"
+	OpUnmount        = "unmount"
+)
+
+// RegressionThreshold is how much slower than the previous run an operation's
+// median must be to be reported as a regression.
+const RegressionThreshold = 0.25
+
+// Timing summarizes the samples of one operation, in milliseconds.
+type Timing struct {
+	Op       string  `json:"op"`
+	Samples  int     `json:"samples"`
+	MedianMS float64 `json:"median_ms"`
+	MinMS    float64 `json:"min_ms"`
+	MaxMS    float64 `json:"max_ms"`
+}
+
+// Run is one benchmark run, a line of the history file.
+type Run struct {
+	Time          time.Time `json:"time"`
+	Version       string    `json:"version"`        // capsule's
+	DockerVersion string    `json:"docker_version"` // The daemon's
+	Iterations    int       `json:"iterations"`
+	Timings       []Timing  `json:"timings"`
+}
+
+// Timing returns the run's timing of op.
+func (r Run) Timing(op string) (Timing, bool) {
+	for _, t := range r.Timings {
+		if t.Op == op {
+			return t, true
+		}
+	}
+	return Timing{}, false
+}
+
+// Change returns how much slower cur's median is than prev's, as a fraction
+// of prev's: 0.5 is 50% slower, -0.2 is 20% faster.
+func Change(prev, cur Timing) float64 {
+	if prev.MedianMS <= 0 {
+		return 0
+	}
+	return (cur.MedianMS - prev.MedianMS) / prev.MedianMS
+}
+
+// Recorder collects samples of each operation in the order they are first
+// timed.
+type Recorder struct {
+	ops     []string
+	samples map[string][]time.Duration
+}
+
+// Time runs f and records how long it took as a sample of op, unless it
+// fails.
+func (r *Recorder) Time(op string, f func() error) error {
+	start := time.Now()
+	if err := f(); err != nil {
+		return err
+	}
+	r.Add(op, time.Since(start))
+	return nil
+}
+
+// Add records d as a sample of op.
+func (r *Recorder) Add(op string, d time.Duration) {
+	if r.samples == nil {
+		r.samples = make(map[string][]time.Duration)
+	}
+	if _, ok := r.samples[op]; !ok {
+		r.ops = append(r.ops, op)
+	}
+	r.samples[op] = append(r.samples[op], d)
+}
+
+// Timings summarizes the samples of each operation.
+func (r *Recorder) Timings() []Timing {
+	timings := make([]Timing, 0, len(r.ops))
+	for _, op := range r.ops {
+		timings = append(timings, Summarize(op, r.samples[op]))
+	}
+	return timings
+}
+
+// Summarize returns the median, fastest, and slowest of samples.
+func Summarize(op string, samples []time.Duration) Timing {
+	t := Timing{Op: op, Samples: len(samples)}
+	if len(samples) == 0 {
+		return t
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+	t.MedianMS = milliseconds(median)
+	t.MinMS = milliseconds(sorted[0])
+	t.MaxMS = milliseconds(sorted[len(sorted)-1])
+	return t
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// DefaultPath returns ~/.capsule/bench.jsonl.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, FileName), nil
+}
+
+// Append writes a run to the history file, creating it if needed.
+func Append(path string, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode benchmark run: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, constants.FilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads the history file, oldest run first. A missing file is an empty
+// history, and malformed lines are skipped.
+func Load(path string) ([]Run, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil || run.Time.IsZero() {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return runs, nil
+}
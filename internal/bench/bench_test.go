@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	samples := []time.Duration{300 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 900 * time.Millisecond}
+	got := Summarize(OpMount, samples)
+	want := Timing{Op: OpMount, Samples: 4, MedianMS: 275, MinMS: 100, MaxMS: 900}
+	if got != want {
+		t.Errorf("Summarize() = %+v, want %+v", got, want)
+	}
+	if got := Summarize(OpExec, samples[:3]); got.MedianMS != 250 {
+		t.Errorf("Summarize() of 3 samples median = %v, want 250", got.MedianMS)
+	}
+	if got := Summarize(OpExec, nil); got.Samples != 0 || got.MedianMS != 0 {
+		t.Errorf("Summarize(nil) = %+v", got)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var r Recorder
+	r.Add(OpMount, 2*time.Second)
+	r.Add(OpExec, 40*time.Millisecond)
+	r.Add(OpMount, 4*time.Second)
+	if err := r.Time(OpUnmount, func() error { return errors.New("busy") }); err == nil {
+		t.Error("Time() dropped the error")
+	}
+
+	timings := r.Timings()
+	if len(timings) != 2 || timings[0].Op != OpMount || timings[1].Op != OpExec {
+		t.Fatalf("Timings() = %+v, want mount then exec, no failed unmount", timings)
+	}
+	if timings[0].MedianMS != 3000 {
+		t.Errorf("mount median = %v, want 3000", timings[0].MedianMS)
+	}
+}
+
+func TestChange(t *testing.T) {
+	prev := Timing{MedianMS: 200}
+	if got := Change(prev, Timing{MedianMS: 300}); got != 0.5 {
+		t.Errorf("Change() = %v, want 0.5", got)
+	}
+	if got := Change(Timing{}, Timing{MedianMS: 300}); got != 0 {
+		t.Errorf("Change() without a previous median = %v, want 0", got)
+	}
+}
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, dockerVersion := range []string{"27.3.1", "27.4.0"} {
+		run := Run{
+			Time:          base.Add(time.Duration(i) * time.Hour),
+			DockerVersion: dockerVersion,
+			Iterations:    3,
+			Timings:       []Timing{{Op: OpMount, Samples: 3, MedianMS: 1200}},
+		}
+		if err := Append(path, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("not json\n")
+	f.Close()
+
+	runs, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[1].DockerVersion != "27.4.0" {
+		t.Fatalf("Load() = %+v", runs)
+	}
+	if timing, ok := runs[0].Timing(OpMount); !ok || timing.MedianMS != 1200 {
+		t.Errorf("Timing(mount) = %+v, %v", timing, ok)
+	}
+
+	if runs, err := Load(filepath.Join(t.TempDir(), FileName)); err != nil || runs != nil {
+		t.Errorf("Load() of a missing file = %v, %v", runs, err)
+	}
+}
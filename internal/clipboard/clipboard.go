@@ -0,0 +1,173 @@
+// Package clipboard bridges the container's pbcopy and pbpaste to the host
+// clipboard over a unix socket, so text copied inside the capsule lands on
+// the macOS clipboard.
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/hostsocket"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+const (
+	// ContainerSocketPath is where the bridge socket is mounted inside the container.
+	ContainerSocketPath = "/run/capsule/clipboard.sock"
+
+	// maxSize bounds what one copy or paste carries.
+	maxSize = 8 << 20
+
+	// commandTimeout bounds a pbcopy or pbpaste run on the host.
+	commandTimeout = 10 * time.Second
+)
+
+// Mode says which way the bridge lets text flow.
+type Mode string
+
+const (
+	// ModeOff runs no bridge.
+	ModeOff Mode = ""
+	// ModeCopy lets the container write the host clipboard but not read it.
+	ModeCopy Mode = "copy"
+	// ModeCopyPaste also lets the container read the host clipboard.
+	ModeCopyPaste Mode = "copy-paste"
+)
+
+// ParseMode returns the mode named s. "off" is ModeOff.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeOff, "off":
+		return ModeOff, nil
+	case ModeCopy, ModeCopyPaste:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown clipboard mode %q (use %s, %s, or off)", s, ModeCopy, ModeCopyPaste)
+}
+
+// Request is sent by the pbcopy and pbpaste shims.
+type Request struct {
+	Op   string `json:"op"` // "copy" or "paste"
+	Data []byte `json:"data,omitempty"`
+}
+
+// Response carries pasted text, or why the request failed.
+type Response struct {
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Server copies to and pastes from the host clipboard on behalf of a container.
+type Server struct {
+	Mode         Mode
+	CopyProgram  string // Host command that reads the clipboard's new contents from stdin
+	PasteProgram string // Host command that prints the clipboard
+
+	socket *hostsocket.Listener
+}
+
+// SocketPath returns ~/.capsule/run/<containerName>-clipboard.sock.
+func SocketPath(containerName string) (string, error) {
+	return hostsocket.Path(containerName, "clipboard")
+}
+
+// Listen creates the bridge socket, using the host's pbcopy and pbpaste. Call
+// Serve to handle requests and Close when done.
+func Listen(socketPath string, mode Mode) (*Server, error) {
+	if mode == ModeOff {
+		return nil, fmt.Errorf("clipboard mode is required")
+	}
+	socket, err := hostsocket.Listen(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Mode:         mode,
+		CopyProgram:  "pbcopy",
+		PasteProgram: "pbpaste",
+		socket:       socket,
+	}, nil
+}
+
+// Serve handles copy and paste requests until Close is called.
+func (s *Server) Serve() error {
+	return s.socket.Serve(s.handle)
+}
+
+// Close stops the bridge and removes the socket.
+func (s *Server) Close() error {
+	return s.socket.Close()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	var req Request
+	// Base64 in JSON grows the data by a third
+	if err := json.NewDecoder(io.LimitReader(conn, maxSize*2)).Decode(&req); err != nil {
+		hostsocket.Reply(conn, Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	var resp Response
+	var err error
+	switch req.Op {
+	case "copy":
+		err = s.copy(req.Data)
+	case "paste":
+		resp.Data, err = s.paste()
+	default:
+		err = fmt.Errorf("unknown operation %q", req.Op)
+	}
+	if err != nil {
+		resp = Response{Error: err.Error()}
+	}
+	hostsocket.Reply(conn, resp)
+}
+
+// copy replaces the host clipboard with data.
+func (s *Server) copy(data []byte) error {
+	if len(data) > maxSize {
+		return fmt.Errorf("%d bytes is more than the %d MB clipboard limit", len(data), maxSize>>20)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.CopyProgram)
+	done := logging.Command(cmd)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("%s failed: %v %s", s.CopyProgram, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// paste returns the host clipboard, if the mode allows the container to read it.
+func (s *Server) paste() ([]byte, error) {
+	if s.Mode != ModeCopyPaste {
+		return nil, fmt.Errorf("pasting from the host is off; start with --clipboard=%s to allow it", ModeCopyPaste)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.PasteProgram)
+	done := logging.Command(cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v %s", s.PasteProgram, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if stdout.Len() > maxSize {
+		return nil, fmt.Errorf("the clipboard holds more than the %d MB limit", maxSize>>20)
+	}
+	return stdout.Bytes(), nil
+}
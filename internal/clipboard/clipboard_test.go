@@ -0,0 +1,82 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for s, want := range map[string]Mode{"": ModeOff, "off": ModeOff, "copy": ModeCopy, "copy-paste": ModeCopyPaste} {
+		if got, err := ParseMode(s); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseMode("paste"); err == nil {
+		t.Error("ParseMode(paste) succeeded, want error")
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	clipboardFile := filepath.Join(dir, "clipboard")
+
+	// Fake pbcopy and pbpaste backed by a file
+	copyProgram := filepath.Join(dir, "fake-pbcopy")
+	pasteProgram := filepath.Join(dir, "fake-pbpaste")
+	if err := os.WriteFile(copyProgram, []byte("#!/bin/sh\ncat > "+clipboardFile+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pasteProgram, []byte("#!/bin/sh\ncat "+clipboardFile+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	socketPath := filepath.Join(dir, "clipboard.sock")
+	srv, err := Listen(socketPath, ModeCopy)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer srv.Close()
+	srv.CopyProgram = copyProgram
+	srv.PasteProgram = pasteProgram
+	go srv.Serve()
+
+	if resp := send(t, socketPath, Request{Op: "copy", Data: []byte("hello from the capsule")}); resp.Error != "" {
+		t.Fatalf("copy error = %s", resp.Error)
+	}
+	if data, _ := os.ReadFile(clipboardFile); string(data) != "hello from the capsule" {
+		t.Errorf("host clipboard = %q", data)
+	}
+
+	// Copy-only mode never reads the host clipboard
+	if resp := send(t, socketPath, Request{Op: "paste"}); resp.Error == "" || resp.Data != nil {
+		t.Errorf("paste in copy mode = %+v, want an error", resp)
+	}
+	srv.Mode = ModeCopyPaste
+	if resp := send(t, socketPath, Request{Op: "paste"}); resp.Error != "" || string(resp.Data) != "hello from the capsule" {
+		t.Errorf("paste = %+v", resp)
+	}
+
+	if resp := send(t, socketPath, Request{Op: "clear"}); resp.Error == "" {
+		t.Error("unknown operation succeeded, want error")
+	}
+}
+
+func send(t *testing.T, socketPath string, req Request) Response {
+	t.Helper()
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
@@ -7,7 +7,16 @@ var VolumeStructure = []string{
 	"claude-context",      // .claude conversation history
 	"bootstrap",           // Templates and starting files
 	"repos",               // Per-repository documentation and context
+	"bin",                 // Tools installed by capsule (bd), on the container PATH
 	"home",                // User home directory (persists Claude credentials, shell history, etc.)
 	"home/.claude",        // Claude Code configuration directory
 	"home/.claude/skills", // Skills directory for doc-sync and other extensions
 }
+
+// IdentityStructure defines the directory structure inside an identity
+// volume, which holds only the credentials a data volume's sessions use.
+var IdentityStructure = []string{
+	"auth",         // API keys, authentication tokens
+	"config",       // Volume metadata
+	"home/.claude", // Claude Code's OAuth login
+}
@@ -0,0 +1,368 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// SettingsFile is the user settings file under the capsule config directory.
+const SettingsFile = "config.json"
+
+// Settings holds user preferences read from ~/.capsule/config.json.
+type Settings struct {
+	// MountDir is where encrypted volumes are mounted. A leading ~ is
+	// expanded to the home directory. Empty means ~/.capsule/mounts.
+	MountDir string `json:"mount_dir,omitempty"`
+
+	// LowSpacePercent is the free space, as a percentage of the volume,
+	// below which a session warns. Zero means DefaultLowSpacePercent; a
+	// negative value turns the warning off.
+	LowSpacePercent int `json:"low_space_percent,omitempty"`
+
+	// AutoExpandMaxGB, if set, lets a session grow a nearly full volume,
+	// doubling its size each time up to this many GB.
+	AutoExpandMaxGB int `json:"auto_expand_max_gb,omitempty"`
+
+	// ImageFlavor is the image variant start and build-image use when no
+	// --flavor is given: slim, standard, or full. Empty means standard.
+	ImageFlavor string `json:"image_flavor,omitempty"`
+
+	// BaseImage replaces the Docker image's base (node:20-slim), e.g. with an
+	// internal hardened image. It must be Debian-based and provide Node and npm.
+	BaseImage string `json:"base_image,omitempty"`
+
+	// ClaudeCodeVersion pins the Claude Code release installed in the image:
+	// an exact release such as "1.0.58", or "latest" or "stable". Empty
+	// installs whatever is latest when the image is built.
+	ClaudeCodeVersion string `json:"claude_code_version,omitempty"`
+
+	// BuildArgs are passed to every image build as --build-arg KEY=VALUE.
+	BuildArgs map[string]string `json:"build_args,omitempty"`
+
+	// CacheFrom lists images every image build may reuse layers from.
+	CacheFrom []string `json:"cache_from,omitempty"`
+
+	// NoHostProxy stops the host's HTTP(S)_PROXY and related variables from
+	// being passed to image builds and containers.
+	NoHostProxy bool `json:"no_host_proxy,omitempty"`
+
+	// CACerts lists PEM files of extra CA certificates, e.g. for a
+	// TLS-intercepting proxy, trusted by image builds and containers. A
+	// leading ~ is expanded to the home directory.
+	CACerts []string `json:"ca_certs,omitempty"`
+
+	// DNS lists nameserver IPs for containers, e.g. split-horizon resolvers
+	// that know internal registries, and DNSSearch the search domains.
+	DNS       []string `json:"dns,omitempty"`
+	DNSSearch []string `json:"dns_search,omitempty"`
+
+	// Dotfiles lists files and directories under the host's home directory,
+	// e.g. ".vimrc", copied into the volume's home at every start. An entry
+	// "HOST:HOME" copies ~/HOST to HOME instead, such as a trimmed-down
+	// shell config kept for the capsule.
+	Dotfiles []string `json:"dotfiles,omitempty"`
+
+	// RecordSessions records every interactive shell session into the
+	// volume, as if 'capsule start --record' were given.
+	RecordSessions bool `json:"record_sessions,omitempty"`
+
+	// Clipboard bridges pbcopy and pbpaste in the container to the host
+	// clipboard: "copy" lets the container write it, "copy-paste" also lets
+	// it read it. Empty or "off" runs no bridge.
+	Clipboard string `json:"clipboard,omitempty"`
+
+	// NoBrowserBridge stops URLs opened in the container (xdg-open, open,
+	// $BROWSER) from being opened in the host browser.
+	NoBrowserBridge bool `json:"no_browser_bridge,omitempty"`
+
+	// Restart is the container restart policy start and run use when no
+	// --restart is given: "no" or "unless-stopped". Empty means no.
+	Restart string `json:"restart,omitempty"`
+
+	// KeepAlive leaves the container running when the shell 'capsule start'
+	// opened exits, as if 'capsule start --keep-alive' were given: a
+	// duration such as "30m" stops it once no shell has been open that long,
+	// and "always" leaves it until 'capsule stop' or 'capsule lock'. Empty or
+	// "off" stops it when the shell exits.
+	KeepAlive string `json:"keep_alive,omitempty"`
+
+	// Volumes names volumes, so --volume can be given a name instead of a
+	// sparse image path, e.g. {"work": "~/Volumes/work.sparseimage"}. A
+	// leading ~ is expanded to the home directory.
+	Volumes map[string]string `json:"volumes,omitempty"`
+
+	// IdentityVolume is the identity volume sessions mount next to the data
+	// volume, for its auth/ directory and Claude Code login: a name from
+	// volumes or a path, with a leading ~ expanded. Empty keeps credentials
+	// in the data volume.
+	IdentityVolume string `json:"identity_volume,omitempty"`
+
+	// StaleVolumeMonths is how long a volume may go unmounted before status
+	// warns that it looks abandoned. Zero means DefaultStaleVolumeMonths; a
+	// negative value turns the warning off.
+	StaleVolumeMonths int `json:"stale_volume_months,omitempty"`
+}
+
+// KeepAliveAlways is the keep_alive value that leaves the container running
+// until it is stopped explicitly.
+const KeepAliveAlways = "always"
+
+// ParseKeepAlive parses a keep_alive value. keep reports whether the
+// container should be left running; grace is how long it may then go without
+// a shell before it is stopped, or 0 to leave it running until stopped.
+func ParseKeepAlive(value string) (keep bool, grace time.Duration, err error) {
+	switch value {
+	case "", "off", "0":
+		return false, 0, nil
+	case KeepAliveAlways:
+		return true, 0, nil
+	}
+	grace, err = time.ParseDuration(value)
+	if err != nil || grace < 0 {
+		return false, 0, fmt.Errorf("keep-alive must be off, %s, or a duration such as 30m, got %q", KeepAliveAlways, value)
+	}
+	if grace == 0 {
+		return false, 0, nil
+	}
+	return true, grace, nil
+}
+
+// DefaultLowSpacePercent is the free space below which a session warns.
+const DefaultLowSpacePercent = 10
+
+// DefaultStaleVolumeMonths is how long a volume may go unmounted before
+// status warns about it.
+const DefaultStaleVolumeMonths = 6
+
+// DefaultSettingsPath returns ~/.capsule/config.json.
+func DefaultSettingsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, SettingsFile), nil
+}
+
+// LoadSettings reads the settings file at path. A missing file is the defaults.
+func LoadSettings(path string) (*Settings, error) {
+	settings := &Settings{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// Validate checks the settings that have limits.
+func (s *Settings) Validate() error {
+	if s.LowSpacePercent >= 100 {
+		return fmt.Errorf("low_space_percent must be below 100, got %d", s.LowSpacePercent)
+	}
+	if s.AutoExpandMaxGB < 0 || s.AutoExpandMaxGB > constants.MaxVolumeSizeGB {
+		return fmt.Errorf("auto_expand_max_gb must be between 0 and %d, got %d", constants.MaxVolumeSizeGB, s.AutoExpandMaxGB)
+	}
+	return nil
+}
+
+// SaveSettings writes settings to path, replacing the file atomically.
+func SaveSettings(path string, settings *Settings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDefaultSettings reads the settings file at DefaultSettingsPath.
+func LoadDefaultSettings() (*Settings, error) {
+	path, err := DefaultSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadSettings(path)
+}
+
+// LowSpaceThreshold returns the free space percentage below which a session
+// warns, or 0 if the warning is off.
+func (s *Settings) LowSpaceThreshold() int {
+	switch {
+	case s.LowSpacePercent < 0:
+		return 0
+	case s.LowSpacePercent == 0:
+		return DefaultLowSpacePercent
+	default:
+		return s.LowSpacePercent
+	}
+}
+
+// StaleVolumeThreshold returns how many months a volume may go unmounted
+// before status warns, or 0 if the warning is off.
+func (s *Settings) StaleVolumeThreshold() int {
+	switch {
+	case s.StaleVolumeMonths < 0:
+		return 0
+	case s.StaleVolumeMonths == 0:
+		return DefaultStaleVolumeMonths
+	default:
+		return s.StaleVolumeMonths
+	}
+}
+
+// ImageBuildArgs returns the configured build arguments as KEY=VALUE,
+// sorted by key, with base_image last as BASE_IMAGE so it takes precedence.
+func (s *Settings) ImageBuildArgs() []string {
+	var args []string
+	for _, key := range slices.Sorted(maps.Keys(s.BuildArgs)) {
+		args = append(args, key+"="+s.BuildArgs[key])
+	}
+	if s.BaseImage != "" {
+		args = append(args, "BASE_IMAGE="+s.BaseImage)
+	}
+	return args
+}
+
+// ResolveMountDir returns the absolute mount directory for a user whose home
+// directory is homeDir.
+func (s *Settings) ResolveMountDir(homeDir string) (string, error) {
+	if s.MountDir == "" {
+		return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.MountsSubdir), nil
+	}
+	return expandPath("mount_dir", s.MountDir, homeDir)
+}
+
+// VolumePaths returns the named volumes with their paths made absolute, for
+// a user whose home directory is homeDir.
+func (s *Settings) VolumePaths(homeDir string) (map[string]string, error) {
+	paths := make(map[string]string, len(s.Volumes))
+	for name, path := range s.Volumes {
+		expanded, err := expandPath("volumes."+name, path, homeDir)
+		if err != nil {
+			return nil, err
+		}
+		paths[name] = expanded
+	}
+	return paths, nil
+}
+
+// MoveVolume points the named volumes at from to to, written with a leading
+// ~ when it is in homeDir, and returns their names, sorted.
+func (s *Settings) MoveVolume(homeDir, from, to string) ([]string, error) {
+	paths, err := s.VolumePaths(homeDir)
+	if err != nil {
+		return nil, err
+	}
+	value := homePath(homeDir, to)
+	var names []string
+	for name, path := range paths {
+		if path == filepath.Clean(from) {
+			s.Volumes[name] = value
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// IdentityVolumePath returns the identity_volume setting with a leading ~
+// expanded, for a user whose home directory is homeDir. A value without a
+// path separator is a volume name and is returned as is.
+func (s *Settings) IdentityVolumePath(homeDir string) (string, error) {
+	if !strings.ContainsRune(s.IdentityVolume, filepath.Separator) {
+		return s.IdentityVolume, nil
+	}
+	return expandPath("identity_volume", s.IdentityVolume, homeDir)
+}
+
+// MoveIdentityVolume points identity_volume at to if it is the path from,
+// written with a leading ~ when to is in homeDir, and reports whether it
+// did. A name is left alone: MoveVolume updates the volume it names.
+func (s *Settings) MoveIdentityVolume(homeDir, from, to string) (bool, error) {
+	path, err := s.IdentityVolumePath(homeDir)
+	if err != nil || path != filepath.Clean(from) {
+		return false, err
+	}
+	s.IdentityVolume = homePath(homeDir, to)
+	return true, nil
+}
+
+// CACertPaths returns the absolute paths of the ca_certs files for a user
+// whose home directory is homeDir.
+func (s *Settings) CACertPaths(homeDir string) ([]string, error) {
+	paths := make([]string, len(s.CACerts))
+	for i, path := range s.CACerts {
+		expanded, err := expandPath("ca_certs", path, homeDir)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = expanded
+	}
+	return paths, nil
+}
+
+// homePath returns path with a leading ~ in place of homeDir, if it is in
+// homeDir.
+func homePath(homeDir, path string) string {
+	if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "~/" + rel
+	}
+	return path
+}
+
+// expandPath expands a leading ~ in the setting named key and requires the
+// result to be absolute.
+func expandPath(key, path, homeDir string) (string, error) {
+	expanded := path
+	switch {
+	case path == "~":
+		expanded = homeDir
+	case strings.HasPrefix(path, "~/"):
+		expanded = filepath.Join(homeDir, path[2:])
+	}
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("%s must be an absolute path or start with ~/, got %q", key, path)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// MountDir returns the mount directory configured in ~/.capsule/config.json,
+// or ~/.capsule/mounts if none is set.
+func MountDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	settings, err := LoadSettings(filepath.Join(homeDir, constants.CapsuleConfigDir, SettingsFile))
+	if err != nil {
+		return "", err
+	}
+	return settings.ResolveMountDir(homeDir)
+}
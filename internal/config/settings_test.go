@@ -0,0 +1,205 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+
+	settings, err := LoadSettings(filepath.Join(dir, "missing.json"))
+	if err != nil || settings.MountDir != "" {
+		t.Errorf("LoadSettings(missing) = %+v, %v, want defaults", settings, err)
+	}
+
+	path := filepath.Join(dir, SettingsFile)
+	if err := os.WriteFile(path, []byte(`{"mount_dir": "~/mnt/capsule"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = LoadSettings(path)
+	if err != nil || settings.MountDir != "~/mnt/capsule" {
+		t.Errorf("LoadSettings() = %+v, %v", settings, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"mount_dir": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSettings(path); err == nil {
+		t.Error("LoadSettings(malformed) succeeded, want error")
+	}
+}
+
+func TestSaveSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capsule", SettingsFile)
+	want := &Settings{ImageFlavor: "slim", DNS: []string{"10.0.0.2"}}
+	if err := SaveSettings(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadSettings(path)
+	if err != nil || got.ImageFlavor != "slim" || !slices.Equal(got.DNS, want.DNS) {
+		t.Errorf("LoadSettings() after SaveSettings() = %+v, %v", got, err)
+	}
+}
+
+func TestResolveMountDir(t *testing.T) {
+	tests := []struct {
+		mountDir string
+		want     string
+		wantErr  bool
+	}{
+		{"", "/Users/me/.capsule/mounts", false},
+		{"~", "/Users/me", false},
+		{"~/mnt/capsule/", "/Users/me/mnt/capsule", false},
+		{"/Volumes", "/Volumes", false},
+		{"mounts", "", true},
+		{"~other/mounts", "", true},
+	}
+	for _, tt := range tests {
+		s := &Settings{MountDir: tt.mountDir}
+		got, err := s.ResolveMountDir("/Users/me")
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ResolveMountDir(%q) = %q, %v, want %q (error: %v)", tt.mountDir, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLowSpaceSettings(t *testing.T) {
+	for percent, want := range map[int]int{0: DefaultLowSpacePercent, 5: 5, -1: 0} {
+		if got := (&Settings{LowSpacePercent: percent}).LowSpaceThreshold(); got != want {
+			t.Errorf("LowSpaceThreshold() with low_space_percent %d = %d, want %d", percent, got, want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), SettingsFile)
+	for _, data := range []string{`{"low_space_percent": 100}`, `{"auto_expand_max_gb": 500}`, `{"auto_expand_max_gb": -1}`} {
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSettings(path); err == nil {
+			t.Errorf("LoadSettings(%s) succeeded, want error", data)
+		}
+	}
+}
+
+func TestStaleVolumeThreshold(t *testing.T) {
+	for months, want := range map[int]int{0: DefaultStaleVolumeMonths, 12: 12, -1: 0} {
+		if got := (&Settings{StaleVolumeMonths: months}).StaleVolumeThreshold(); got != want {
+			t.Errorf("StaleVolumeThreshold() with stale_volume_months %d = %d, want %d", months, got, want)
+		}
+	}
+}
+
+func TestImageBuildArgs(t *testing.T) {
+	settings := &Settings{
+		BaseImage: "registry.internal/node:20-hardened",
+		BuildArgs: map[string]string{"GO_VERSION": "1.24.1", "APT_MIRROR": "http://mirror.internal"},
+	}
+	want := []string{"APT_MIRROR=http://mirror.internal", "GO_VERSION=1.24.1", "BASE_IMAGE=registry.internal/node:20-hardened"}
+	if got := settings.ImageBuildArgs(); !slices.Equal(got, want) {
+		t.Errorf("ImageBuildArgs() = %q, want %q", got, want)
+	}
+	if got := (&Settings{}).ImageBuildArgs(); len(got) != 0 {
+		t.Errorf("ImageBuildArgs() with no settings = %q, want none", got)
+	}
+}
+
+func TestCACertPaths(t *testing.T) {
+	settings := &Settings{CACerts: []string{"~/certs/proxy.pem", "/etc/corp/root.pem"}}
+	got, err := settings.CACertPaths("/Users/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/Users/me/certs/proxy.pem", "/etc/corp/root.pem"}
+	if !slices.Equal(got, want) {
+		t.Errorf("CACertPaths() = %q, want %q", got, want)
+	}
+
+	settings.CACerts = []string{"certs/proxy.pem"}
+	if _, err := settings.CACertPaths("/Users/me"); err == nil {
+		t.Error("CACertPaths() with a relative path succeeded, want error")
+	}
+}
+
+func TestVolumePaths(t *testing.T) {
+	settings := &Settings{Volumes: map[string]string{"work": "~/Volumes/work.sparseimage", "oss": "/Volumes/External/oss.sparseimage"}}
+	got, err := settings.VolumePaths("/Users/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"work": "/Users/me/Volumes/work.sparseimage", "oss": "/Volumes/External/oss.sparseimage"}
+	if !maps.Equal(got, want) {
+		t.Errorf("VolumePaths() = %q, want %q", got, want)
+	}
+
+	settings.Volumes = map[string]string{"work": "work.sparseimage"}
+	if _, err := settings.VolumePaths("/Users/me"); err == nil {
+		t.Error("VolumePaths() with a relative path succeeded, want error")
+	}
+}
+
+func TestMoveVolume(t *testing.T) {
+	settings := &Settings{Volumes: map[string]string{
+		"work":  "~/Volumes/work.sparseimage",
+		"alias": "/Users/me/Volumes/work.sparseimage",
+		"oss":   "/Volumes/External/oss.sparseimage",
+	}}
+	names, err := settings.MoveVolume("/Users/me", "/Users/me/Volumes/work.sparseimage", "/Users/me/.capsule/volumes/work.sparseimage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"alias", "work"}) {
+		t.Errorf("MoveVolume() = %v, want [alias work]", names)
+	}
+	want := map[string]string{
+		"work":  "~/.capsule/volumes/work.sparseimage",
+		"alias": "~/.capsule/volumes/work.sparseimage",
+		"oss":   "/Volumes/External/oss.sparseimage",
+	}
+	if !maps.Equal(settings.Volumes, want) {
+		t.Errorf("Volumes after MoveVolume() = %q, want %q", settings.Volumes, want)
+	}
+}
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantKeep  bool
+		wantGrace time.Duration
+		wantErr   bool
+	}{
+		{value: "", wantKeep: false},
+		{value: "off", wantKeep: false},
+		{value: "0s", wantKeep: false},
+		{value: "always", wantKeep: true},
+		{value: "30m", wantKeep: true, wantGrace: 30 * time.Minute},
+		{value: "-5m", wantErr: true},
+		{value: "forever", wantErr: true},
+	}
+	for _, tt := range tests {
+		keep, grace, err := ParseKeepAlive(tt.value)
+		if (err != nil) != tt.wantErr || keep != tt.wantKeep || grace != tt.wantGrace {
+			t.Errorf("ParseKeepAlive(%q) = %v, %v, %v; want %v, %v, error %v", tt.value, keep, grace, err, tt.wantKeep, tt.wantGrace, tt.wantErr)
+		}
+	}
+}
+
+func TestMoveIdentityVolume(t *testing.T) {
+	settings := &Settings{IdentityVolume: "~/Volumes/identity.sparseimage"}
+	moved, err := settings.MoveIdentityVolume("/Users/me", "/Users/me/Volumes/identity.sparseimage", "/Users/me/.capsule/volumes/identity.sparseimage")
+	if err != nil || !moved || settings.IdentityVolume != "~/.capsule/volumes/identity.sparseimage" {
+		t.Errorf("MoveIdentityVolume() = %v, %v; identity_volume %q", moved, err, settings.IdentityVolume)
+	}
+
+	settings = &Settings{IdentityVolume: "identity"}
+	if path, err := settings.IdentityVolumePath("/Users/me"); err != nil || path != "identity" {
+		t.Errorf("IdentityVolumePath() of a name = %q, %v", path, err)
+	}
+	if moved, err := settings.MoveIdentityVolume("/Users/me", "/Users/me/identity", "/tmp/identity"); err != nil || moved {
+		t.Errorf("MoveIdentityVolume() of a name = %v, %v; want it left alone", moved, err)
+	}
+}
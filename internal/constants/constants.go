@@ -1,6 +1,9 @@
 package constants
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 // Volume-related constants
 const (
@@ -15,6 +18,43 @@ const (
 
 	// VolumesSubdir is the subdirectory under CapsuleConfigDir for volumes.
 	VolumesSubdir = "volumes"
+
+	// RunSubdir is the subdirectory under CapsuleConfigDir holding sockets.
+	RunSubdir = "run"
+
+	// MountsSubdir is the default mount directory for volumes under CapsuleConfigDir.
+	MountsSubdir = "mounts"
+
+	// MountPointNamePrefix starts the directory name of every volume mount point.
+	MountPointNamePrefix = "Capsule-"
+
+	// LegacyMountDir is where volumes were mounted before the mount directory
+	// became configurable. Volumes still mounted there are recognized.
+	LegacyMountDir = "/Volumes"
+)
+
+// Container constants
+const (
+	// ContainerNamePrefix starts the name of every capsule workspace container.
+	ContainerNamePrefix = "claude-"
+)
+
+// Docker labels capsule sets on the containers and images it creates, so
+// they can be found by what they are rather than by name.
+const (
+	// LabelVersion is the capsule version that created the container or
+	// built the image. Every labeled container and image has it.
+	LabelVersion = "capsule.version"
+
+	// LabelVolume is the path of the encrypted volume the container uses.
+	LabelVolume = "capsule.volume"
+
+	// LabelWorkspace is the container's workspace paths, separated by the OS
+	// path list separator.
+	LabelWorkspace = "capsule.workspace"
+
+	// LabelRepoID is the container's repository IDs, separated by commas.
+	LabelRepoID = "capsule.repo-id"
 )
 
 // Shadow documentation constants
@@ -31,6 +71,24 @@ const (
 	MaxVolumeSizeGB = 100
 )
 
+// Password policy for new volume passwords
+const (
+	// MinPasswordLength is the shortest volume password accepted.
+	MinPasswordLength = 8
+
+	// MinPasswordScore is the lowest strength score (0-4) accepted for a new volume password.
+	MinPasswordScore = 2
+)
+
+// Status display
+const (
+	// StatusWatchInterval is how often 'capsule status --watch' refreshes by default.
+	StatusWatchInterval = 2 * time.Second
+
+	// StatsInterval is how often 'capsule stats' refreshes by default.
+	StatsInterval = 2 * time.Second
+)
+
 // File permissions
 const (
 	// DirPermissions is the default permission mode for directories.
@@ -0,0 +1,137 @@
+// Package daemon serves a local HTTP API on a unix socket so menu-bar apps and
+// editor extensions can query and control capsules without running the CLI for
+// every action.
+//
+// The socket is created with mode 0600 in a 0700 directory, so only the user
+// running the daemon can connect. Requests and responses are JSON:
+//
+//	GET    /v1/status            volumes and running sessions
+//	POST   /v1/unlock            mount a volume: {"volume": "...", "password": "..."}
+//	POST   /v1/lock              stop sessions and unmount: {"volume": "..."} or {"all": true}
+//	GET    /v1/sessions          running capsule containers
+//	DELETE /v1/sessions/{name}   stop a container, leaving the volume mounted
+//	POST   /v1/events            report a CLI event for metrics: {"type": "mount", "seconds": 2.1}
+//
+// With ListenMetrics, the daemon also serves Prometheus metrics over HTTP on a
+// loopback address.
+//
+// Errors are returned as {"error": "..."} with a 4xx or 5xx status.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// SocketName is the daemon's socket under ~/.capsule/run.
+const SocketName = "daemon.sock"
+
+// APIVersion prefixes every route.
+const APIVersion = "v1"
+
+// ErrNotFound is returned by a Backend when the named volume or session does
+// not exist; the server maps it to 404.
+var ErrNotFound = errors.New("not found")
+
+// Volume describes a capsule volume image.
+type Volume struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	Mounted    bool   `json:"mounted"`
+	MountPoint string `json:"mount_point,omitempty"`
+}
+
+// Session is a running capsule container.
+type Session struct {
+	Container string    `json:"container"`
+	Workspace string    `json:"workspace,omitempty"`
+	StartedAt time.Time `json:"started_at,omitzero"`
+}
+
+// Status is the response to GET /v1/status.
+type Status struct {
+	Version  string    `json:"version"`
+	Volumes  []Volume  `json:"volumes"`
+	Sessions []Session `json:"sessions"`
+}
+
+// UnlockRequest is the body of POST /v1/unlock. Volume is an absolute path
+// or a volume name; empty means the default volume in ~/.capsule/volumes.
+type UnlockRequest struct {
+	Volume   string `json:"volume,omitempty"`
+	Password string `json:"password"`
+}
+
+// UnlockResponse reports where the volume is mounted.
+type UnlockResponse struct {
+	Volume         string `json:"volume"`
+	MountPoint     string `json:"mount_point"`
+	AlreadyMounted bool   `json:"already_mounted"`
+}
+
+// LockRequest is the body of POST /v1/lock. All locks every mounted volume;
+// otherwise Volume is a path or name as in UnlockRequest, empty meaning the
+// default volume.
+type LockRequest struct {
+	Volume string `json:"volume,omitempty"`
+	All    bool   `json:"all,omitempty"`
+}
+
+// LockResponse counts what was secured.
+type LockResponse struct {
+	ContainersStopped int `json:"containers_stopped"`
+	VolumesLocked     int `json:"volumes_locked"`
+}
+
+// Event types accepted by POST /v1/events.
+const (
+	EventMount          = "mount"           // A volume was mounted; Seconds is how long it took
+	EventContainerStart = "container_start" // A container started; Seconds is how long it took
+	EventAutoLock       = "autolock"        // Volumes were locked automatically; Reason is the trigger
+	EventUnlockFailed   = "unlock_failed"   // A mount failed
+)
+
+// Event is the body of POST /v1/events, which the CLI uses to report what it
+// did outside the daemon so the metrics cover every capsule command.
+type Event struct {
+	Type    string  `json:"type"`
+	Seconds float64 `json:"seconds,omitempty"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// errorResponse is the body of every failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Backend performs the operations behind the API.
+type Backend interface {
+	Status(ctx context.Context) (*Status, error)
+
+	// Unlock mounts the volume. The password is cleared by the server afterwards.
+	Unlock(ctx context.Context, volumePath string, password *terminal.SecurePassword) (*UnlockResponse, error)
+
+	// Lock stops the sessions using the volume (or every session) and unmounts it.
+	Lock(ctx context.Context, req LockRequest) (*LockResponse, error)
+
+	Sessions(ctx context.Context) ([]Session, error)
+
+	// StopSession stops and removes a capsule container.
+	StopSession(ctx context.Context, container string) error
+}
+
+// DefaultSocketPath returns ~/.capsule/run/daemon.sock.
+func DefaultSocketPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, SocketName), nil
+}
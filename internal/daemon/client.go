@@ -0,0 +1,169 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// clientTimeout covers the slowest request: an unlock with Argon2id key stretching.
+const clientTimeout = 5 * time.Minute
+
+// Client talks to a daemon over its unix socket.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a client for the daemon listening on socketPath.
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport, Timeout: clientTimeout}}
+}
+
+// Status returns the volumes and running sessions.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.do(ctx, http.MethodGet, "/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Unlock mounts a volume. An empty volumePath means the default volume.
+func (c *Client) Unlock(ctx context.Context, volumePath string, password *terminal.SecurePassword) (*UnlockResponse, error) {
+	body, err := encodeUnlockRequest(volumePath, password.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	defer clear(body)
+
+	var resp UnlockResponse
+	if err := c.send(ctx, http.MethodPost, "/unlock", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// encodeUnlockRequest encodes an UnlockRequest by hand, so the password goes
+// from its locked buffer into the returned body without a string copy that
+// can't be zeroed. The body is sized up front so appending never leaves a
+// partial copy behind; the caller clears it once sent.
+func encodeUnlockRequest(volumePath string, password []byte) ([]byte, error) {
+	var prefix []byte
+	if volumePath != "" {
+		volume, err := json.Marshal(volumePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		prefix = append(append([]byte(`{"volume":`), volume...), ',')
+	} else {
+		prefix = []byte("{")
+	}
+
+	const hex = "0123456789abcdef"
+	body := make([]byte, 0, len(prefix)+len(`"password":""}`)+6*len(password))
+	body = append(body, prefix...)
+	body = append(body, `"password":"`...)
+	for _, b := range password {
+		switch {
+		case b == '"' || b == '\\':
+			body = append(body, '\\', b)
+		case b < 0x20:
+			body = append(body, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+		default:
+			body = append(body, b)
+		}
+	}
+	return append(body, `"}`...), nil
+}
+
+// Lock stops sessions and unmounts a volume, or every volume if req.All is set.
+func (c *Client) Lock(ctx context.Context, req LockRequest) (*LockResponse, error) {
+	var resp LockResponse
+	if err := c.do(ctx, http.MethodPost, "/lock", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Sessions returns the running capsule containers.
+func (c *Client) Sessions(ctx context.Context) ([]Session, error) {
+	var sessions []Session
+	if err := c.do(ctx, http.MethodGet, "/sessions", nil, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// StopSession stops a capsule container.
+func (c *Client) StopSession(ctx context.Context, container string) error {
+	return c.do(ctx, http.MethodDelete, "/sessions/"+container, nil, nil)
+}
+
+// ReportEvent records a CLI event in the daemon's metrics.
+func (c *Client) ReportEvent(ctx context.Context, event Event) error {
+	return c.do(ctx, http.MethodPost, "/events", event, nil)
+}
+
+// do sends in, encoded as JSON if non-nil, and decodes the JSON response
+// into out, if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	return c.send(ctx, method, path, data, out)
+}
+
+// send sends body, a JSON document if non-nil, and decodes the JSON response
+// into out, if non-nil. The request is finished with body when send returns.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://capsule/"+APIVersion+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach capsule daemon (is 'capsule daemon' running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr errorResponse
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("daemon: %s", apiErr.Error)
+		}
+		return fmt.Errorf("daemon: %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode daemon response: %w", err)
+	}
+	return nil
+}
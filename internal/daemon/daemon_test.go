@@ -0,0 +1,302 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+type fakeBackend struct {
+	mounted  map[string]string
+	sessions []Session
+	password string
+	names    map[string]string // Volume names and the paths they resolve to
+	locked   string            // The volume of the last lock
+}
+
+// resolve returns the path a requested volume refers to, as the real backend
+// does with the volumes setting.
+func (f *fakeBackend) resolve(volume string) string {
+	if path, ok := f.names[volume]; ok {
+		return path
+	}
+	return volume
+}
+
+func (f *fakeBackend) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Version: "test", Sessions: f.sessions}
+	for path, mountPoint := range f.mounted {
+		status.Volumes = append(status.Volumes, Volume{Path: path, Exists: true, Mounted: true, MountPoint: mountPoint})
+	}
+	return status, nil
+}
+
+func (f *fakeBackend) Unlock(ctx context.Context, volumePath string, password *terminal.SecurePassword) (*UnlockResponse, error) {
+	if password.String() != f.password {
+		return nil, fmt.Errorf("wrong password")
+	}
+	volumePath = f.resolve(volumePath)
+	if volumePath == "" {
+		volumePath = "/default.sparseimage"
+	}
+	if mountPoint, ok := f.mounted[volumePath]; ok {
+		return &UnlockResponse{Volume: volumePath, MountPoint: mountPoint, AlreadyMounted: true}, nil
+	}
+	f.mounted[volumePath] = "/Volumes/Capsule-test"
+	return &UnlockResponse{Volume: volumePath, MountPoint: "/Volumes/Capsule-test"}, nil
+}
+
+func (f *fakeBackend) Lock(ctx context.Context, req LockRequest) (*LockResponse, error) {
+	f.locked = f.resolve(req.Volume)
+	resp := &LockResponse{ContainersStopped: len(f.sessions), VolumesLocked: len(f.mounted)}
+	f.sessions = nil
+	f.mounted = map[string]string{}
+	return resp, nil
+}
+
+func (f *fakeBackend) Sessions(ctx context.Context) ([]Session, error) {
+	return f.sessions, nil
+}
+
+func (f *fakeBackend) StopSession(ctx context.Context, container string) error {
+	for i, s := range f.sessions {
+		if s.Container == container {
+			f.sessions = append(f.sessions[:i], f.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("container %s: %w", container, ErrNotFound)
+}
+
+// startServer runs a daemon for backend and returns a client connected to it.
+func startServer(t *testing.T, backend Backend) (*Client, string) {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, too short for some TempDirs
+	dir, err := os.MkdirTemp("", "capsule-daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "run", SocketName)
+
+	server, err := Listen(socketPath, backend)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+	return NewClient(socketPath), socketPath
+}
+
+func TestDaemonAPI(t *testing.T) {
+	backend := &fakeBackend{
+		mounted:  map[string]string{},
+		sessions: []Session{{Container: "claude-abc", Workspace: "/src/project"}},
+		password: "correct horse",
+	}
+	client, socketPath := startServer(t, backend)
+	ctx := context.Background()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+
+	wrong := terminal.NewSecurePassword([]byte("nope"))
+	defer wrong.Clear()
+	if _, err := client.Unlock(ctx, "", wrong); err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("Unlock() with a wrong password error = %v", err)
+	}
+
+	password := terminal.NewSecurePassword([]byte("correct horse"))
+	defer password.Clear()
+	unlocked, err := client.Unlock(ctx, "", password)
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if unlocked.Volume != "/default.sparseimage" || unlocked.MountPoint != "/Volumes/Capsule-test" || unlocked.AlreadyMounted {
+		t.Errorf("Unlock() = %+v", unlocked)
+	}
+
+	for _, relative := range []string{"relative.sparseimage", "dir/work"} {
+		if _, err := client.Unlock(ctx, relative, password); err == nil {
+			t.Errorf("Unlock(%q) should reject a relative volume path", relative)
+		}
+		if _, err := client.Lock(ctx, LockRequest{Volume: relative}); err == nil {
+			t.Errorf("Lock(%q) should reject a relative volume path", relative)
+		}
+	}
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status.Volumes) != 1 || !status.Volumes[0].Mounted || len(status.Sessions) != 1 {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	if err := client.StopSession(ctx, "claude-missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("StopSession(missing) error = %v", err)
+	}
+	if err := client.StopSession(ctx, "postgres"); err == nil {
+		t.Error("StopSession() should refuse non-capsule containers")
+	}
+	if err := client.StopSession(ctx, "claude-abc"); err != nil {
+		t.Errorf("StopSession() error = %v", err)
+	}
+	sessions, err := client.Sessions(ctx)
+	if err != nil || len(sessions) != 0 {
+		t.Errorf("Sessions() = %v, %v, want none", sessions, err)
+	}
+
+	if _, err := client.Lock(ctx, LockRequest{All: true, Volume: "/x"}); err == nil {
+		t.Error("Lock() should reject all with a volume")
+	}
+	locked, err := client.Lock(ctx, LockRequest{All: true})
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if locked.VolumesLocked != 1 {
+		t.Errorf("Lock() = %+v", locked)
+	}
+}
+
+func TestDaemonVolumeNames(t *testing.T) {
+	backend := &fakeBackend{
+		mounted:  map[string]string{},
+		password: "correct horse",
+		names:    map[string]string{"work": "/Volumes/External/work.sparseimage"},
+	}
+	client, _ := startServer(t, backend)
+	ctx := context.Background()
+
+	password := terminal.NewSecurePassword([]byte("correct horse"))
+	defer password.Clear()
+	unlocked, err := client.Unlock(ctx, "work", password)
+	if err != nil {
+		t.Fatalf("Unlock(work) error = %v", err)
+	}
+	if unlocked.Volume != "/Volumes/External/work.sparseimage" {
+		t.Errorf("Unlock(work) volume = %q, want the configured path", unlocked.Volume)
+	}
+
+	if _, err := client.Lock(ctx, LockRequest{Volume: "work"}); err != nil {
+		t.Fatalf("Lock(work) error = %v", err)
+	}
+	if backend.locked != "/Volumes/External/work.sparseimage" {
+		t.Errorf("Lock(work) locked %q, want the configured path", backend.locked)
+	}
+}
+
+func TestEncodeUnlockRequest(t *testing.T) {
+	tests := []UnlockRequest{
+		{Password: "hunter2"},
+		{Volume: "/Users/me/capsule.sparseimage", Password: "hunter2"},
+		{Volume: `/tmp/odd "name"`, Password: `quote" back\slash`},
+		{Password: "tab\tnew\nline\x00nul\x1f"},
+		{Password: "pässwörd 密码 🔑"},
+		{Password: "<html>&amp;"},
+	}
+	for _, want := range tests {
+		body, err := encodeUnlockRequest(want.Volume, []byte(want.Password))
+		if err != nil {
+			t.Fatalf("encodeUnlockRequest(%q) error = %v", want.Password, err)
+		}
+		var got UnlockRequest
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("encodeUnlockRequest(%q) = %s, not JSON: %v", want.Password, body, err)
+		}
+		if got != want {
+			t.Errorf("encodeUnlockRequest() decodes to %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestListenRefusesRunningDaemon(t *testing.T) {
+	_, socketPath := startServer(t, &fakeBackend{mounted: map[string]string{}})
+	if _, err := Listen(socketPath, &fakeBackend{}); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("Listen() on a live socket error = %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	for _, e := range []Event{
+		{Type: EventMount, Seconds: 1.5},
+		{Type: EventMount, Seconds: 3},
+		{Type: EventContainerStart, Seconds: 200},
+		{Type: EventAutoLock, Reason: "screen-lock"},
+		{Type: EventAutoLock, Reason: "screen-lock"},
+		{Type: EventUnlockFailed},
+	} {
+		if err := m.Record(e); err != nil {
+			t.Fatalf("Record(%+v) error = %v", e, err)
+		}
+	}
+	if err := m.Record(Event{Type: "bogus"}); err == nil {
+		t.Error("Record() should reject unknown event types")
+	}
+
+	status := &Status{
+		Version:  "1.2.3",
+		Volumes:  []Volume{{Path: "/a", Mounted: true}, {Path: "/b"}},
+		Sessions: []Session{{Container: "claude-abc"}},
+	}
+	var out strings.Builder
+	m.Write(&out, status, time.Now())
+	for _, want := range []string{
+		`capsule_build_info{version="1.2.3"} 1`,
+		"capsule_volumes_mounted 1\n",
+		"capsule_sessions_active 1\n",
+		`capsule_mount_duration_seconds_bucket{le="1"} 0`,
+		`capsule_mount_duration_seconds_bucket{le="2"} 1`,
+		`capsule_mount_duration_seconds_bucket{le="5"} 2`,
+		"capsule_mount_duration_seconds_sum 4.5\n",
+		"capsule_mount_duration_seconds_count 2\n",
+		`capsule_container_start_duration_seconds_bucket{le="120"} 0`,
+		`capsule_container_start_duration_seconds_bucket{le="+Inf"} 1`,
+		"capsule_unlock_failures_total 1\n",
+		`capsule_autolock_events_total{reason="screen-lock"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestReportEvent(t *testing.T) {
+	client, _ := startServer(t, &fakeBackend{mounted: map[string]string{}})
+	ctx := context.Background()
+	if err := client.ReportEvent(ctx, Event{Type: EventContainerStart, Seconds: 4}); err != nil {
+		t.Errorf("ReportEvent() error = %v", err)
+	}
+	if err := client.ReportEvent(ctx, Event{Type: "bogus"}); err == nil {
+		t.Error("ReportEvent() should reject unknown event types")
+	}
+}
+
+func TestListenMetricsRequiresLoopback(t *testing.T) {
+	server := &Server{}
+	for _, addr := range []string{"0.0.0.0:9477", ":9477", "192.168.1.5:9477", "nohost"} {
+		if err := server.ListenMetrics(addr); err == nil {
+			t.Errorf("ListenMetrics(%q) should fail", addr)
+		}
+	}
+}
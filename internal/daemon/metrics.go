@@ -0,0 +1,204 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsPath is where the metrics listener serves the Prometheus text format.
+const MetricsPath = "/metrics"
+
+// latencyBuckets are histogram upper bounds in seconds. Mounts take a few
+// seconds of key stretching; container starts can take much longer when
+// Docker Desktop is cold.
+var latencyBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120}
+
+// histogram is a cumulative Prometheus histogram.
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+func (h *histogram) observe(seconds float64) {
+	i := sort.SearchFloat64s(latencyBuckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative uint64
+	for i, bound := range latencyBuckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// Metrics accumulates the daemon's counters and latencies. Mounts the daemon
+// performs are observed directly; the CLI reports the rest through POST
+// /v1/events.
+type Metrics struct {
+	mu           sync.Mutex
+	started      time.Time
+	mount        *histogram
+	start        *histogram
+	autoLocks    map[string]uint64
+	unlockErrors uint64
+}
+
+// NewMetrics returns empty metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		started:   time.Now(),
+		mount:     newHistogram(),
+		start:     newHistogram(),
+		autoLocks: make(map[string]uint64),
+	}
+}
+
+// Record adds an event.
+func (m *Metrics) Record(event Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch event.Type {
+	case EventMount:
+		m.mount.observe(event.Seconds)
+	case EventContainerStart:
+		m.start.observe(event.Seconds)
+	case EventAutoLock:
+		m.autoLocks[event.Reason]++
+	case EventUnlockFailed:
+		m.unlockErrors++
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
+
+// Write renders the metrics in the Prometheus text format. Gauges come from
+// status, which the caller fetches at scrape time.
+func (m *Metrics) Write(w io.Writer, status *Status, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP capsule_build_info Capsule version.\n# TYPE capsule_build_info gauge\n")
+	fmt.Fprintf(w, "capsule_build_info{version=%q} 1\n", status.Version)
+	fmt.Fprintf(w, "# HELP capsule_daemon_uptime_seconds Time since the daemon started.\n# TYPE capsule_daemon_uptime_seconds gauge\n")
+	fmt.Fprintf(w, "capsule_daemon_uptime_seconds %d\n", int64(now.Sub(m.started).Seconds()))
+
+	mounted := 0
+	for _, v := range status.Volumes {
+		if v.Mounted {
+			mounted++
+		}
+	}
+	fmt.Fprintf(w, "# HELP capsule_volumes_mounted Capsule volumes currently mounted.\n# TYPE capsule_volumes_mounted gauge\n")
+	fmt.Fprintf(w, "capsule_volumes_mounted %d\n", mounted)
+	fmt.Fprintf(w, "# HELP capsule_sessions_active Capsule containers currently running.\n# TYPE capsule_sessions_active gauge\n")
+	fmt.Fprintf(w, "capsule_sessions_active %d\n", len(status.Sessions))
+
+	m.mount.write(w, "capsule_mount_duration_seconds", "Time to mount an encrypted volume.")
+	m.start.write(w, "capsule_container_start_duration_seconds", "Time to start a capsule container.")
+
+	fmt.Fprintf(w, "# HELP capsule_unlock_failures_total Volume mounts that failed, including wrong passwords.\n# TYPE capsule_unlock_failures_total counter\n")
+	fmt.Fprintf(w, "capsule_unlock_failures_total %d\n", m.unlockErrors)
+
+	fmt.Fprintf(w, "# HELP capsule_autolock_events_total Automatic locks by trigger.\n# TYPE capsule_autolock_events_total counter\n")
+	reasons := make([]string, 0, len(m.autoLocks))
+	for reason := range m.autoLocks {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "capsule_autolock_events_total{reason=%q} %d\n", reason, m.autoLocks[reason])
+	}
+}
+
+// ListenMetrics opens a TCP listener for GET /metrics, served alongside the
+// API by Serve. Only loopback addresses are accepted: the metrics are
+// unauthenticated.
+func (s *Server) ListenMetrics(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid metrics address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("metrics address %q must be on localhost (127.0.0.1 or ::1)", addr)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+MetricsPath, s.handleMetrics)
+	s.metricsListener = listener
+	s.metricsHTTP = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return nil
+}
+
+// MetricsAddr returns the address metrics are served on, or "" if disabled.
+func (s *Server) MetricsAddr() string {
+	if s.metricsListener == nil {
+		return ""
+	}
+	return s.metricsListener.Addr().String()
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	status, err := s.backend.Status(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.Write(w, status, time.Now())
+}
+
+func (s *Server) handleEvent(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := decodeRequest(r, &event); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if event.Seconds < 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "seconds must not be negative"})
+		return
+	}
+	if err := s.metrics.Record(event); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveMetrics serves the metrics listener until ctx is cancelled.
+func (s *Server) serveMetrics(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		s.metricsHTTP.Shutdown(shutdownCtx)
+	}()
+	if err := s.metricsHTTP.Serve(s.metricsListener); !errors.Is(err, http.ErrServerClosed) {
+		slog.Warn("daemon metrics listener stopped", "error", err)
+	}
+}
@@ -0,0 +1,260 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+const (
+	// maxRequestBody bounds request bodies; none carries more than a path and a password.
+	maxRequestBody = 64 << 10
+
+	// shutdownTimeout is how long in-flight requests get to finish on shutdown.
+	shutdownTimeout = 10 * time.Second
+)
+
+// Server serves the API for a Backend on a unix socket.
+type Server struct {
+	backend    Backend
+	socketPath string
+	listener   net.Listener
+	http       *http.Server
+	metrics    *Metrics
+
+	// Optional TCP listener for GET /metrics; see ListenMetrics
+	metricsListener net.Listener
+	metricsHTTP     *http.Server
+
+	// mu serializes operations that mount, unmount, or stop containers, so two
+	// clients can't race hdiutil against itself
+	mu sync.Mutex
+}
+
+// Listen creates the daemon socket. It fails if another daemon is already
+// listening on it, and replaces a socket left behind by one that crashed.
+func Listen(socketPath string, backend Backend) (*Server, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(socketPath), err)
+	}
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a capsule daemon is already listening on %s", socketPath)
+	}
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, constants.FilePermissions); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to secure %s: %w", socketPath, err)
+	}
+
+	s := &Server{backend: backend, socketPath: socketPath, listener: listener, metrics: NewMetrics()}
+	s.http = &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// SocketPath returns the path the server is listening on.
+func (s *Server) SocketPath() string {
+	return s.socketPath
+}
+
+// Serve handles requests until ctx is cancelled, then waits for in-flight
+// requests and removes the socket.
+func (s *Server) Serve(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.http.Serve(s.listener) }()
+	if s.metricsHTTP != nil {
+		go s.serveMetrics(ctx)
+	}
+
+	select {
+	case err := <-errCh:
+		os.Remove(s.socketPath)
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := s.http.Shutdown(shutdownCtx)
+	os.Remove(s.socketPath)
+	if serveErr := <-errCh; !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return err
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	prefix := "/" + APIVersion
+	mux.HandleFunc("GET "+prefix+"/status", s.handleStatus)
+	mux.HandleFunc("POST "+prefix+"/unlock", s.handleUnlock)
+	mux.HandleFunc("POST "+prefix+"/lock", s.handleLock)
+	mux.HandleFunc("GET "+prefix+"/sessions", s.handleSessions)
+	mux.HandleFunc("DELETE "+prefix+"/sessions/{name}", s.handleStopSession)
+	mux.HandleFunc("POST "+prefix+"/events", s.handleEvent)
+	return mux
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.backend.Status(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	var req UnlockRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if req.Password == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "password is required"})
+		return
+	}
+	if !validVolume(req.Volume) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidVolume})
+		return
+	}
+	// The decoded string stays on the heap until collected; the locked copy is
+	// what reaches hdiutil
+	password := terminal.NewSecurePassword([]byte(req.Password))
+	defer password.Clear()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	started := time.Now()
+	resp, err := s.backend.Unlock(r.Context(), req.Volume, password)
+	if err != nil {
+		slog.Warn("daemon unlock failed", "volume", req.Volume, "error", err)
+		if !errors.Is(err, ErrNotFound) {
+			s.metrics.Record(Event{Type: EventUnlockFailed})
+		}
+		writeError(w, err)
+		return
+	}
+	if !resp.AlreadyMounted {
+		s.metrics.Record(Event{Type: EventMount, Seconds: time.Since(started).Seconds()})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	var req LockRequest
+	if err := decodeRequest(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if req.All && req.Volume != "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "all and volume cannot be used together"})
+		return
+	}
+	if !validVolume(req.Volume) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidVolume})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, err := s.backend.Lock(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.backend.Sessions(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []Session{}
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleStopSession(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !strings.HasPrefix(name, constants.ContainerNamePrefix) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("%q is not a capsule container", name)})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.backend.StopSession(r.Context(), name); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errInvalidVolume is the error for a volume validVolume rejects.
+const errInvalidVolume = "volume must be an absolute path or a volume name"
+
+// validVolume reports whether a requested volume is empty, an absolute path,
+// or a bare volume name for the backend to resolve. Relative paths are
+// rejected: the daemon's working directory isn't the client's.
+func validVolume(v string) bool {
+	if v == "" || filepath.IsAbs(v) {
+		return true
+	}
+	return !strings.ContainsRune(v, filepath.Separator) && !volume.HasImageExt(v)
+}
+
+// decodeRequest reads a JSON body into v, rejecting unknown fields. An empty
+// body leaves v at its zero value.
+func decodeRequest(r *http.Request, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeError maps a backend error to a status code.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("daemon failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// HealthCheckInterval is how often a HealthWatch checks the container.
+const HealthCheckInterval = 30 * time.Second
+
+// SymlinkTarget is a workspace whose _docs symlink a HealthWatch keeps working.
+type SymlinkTarget struct {
+	RepoID       string
+	WorkspaceDir string
+}
+
+// HealthWatch re-checks a session's container while it runs. If the container
+// was restarted, or one of its _docs symlinks no longer points at the shadow
+// docs, it runs SetupWorkspaceSymlink again so the session doesn't silently
+// lose them. A container that stopped is left alone; the session notices that
+// itself.
+type HealthWatch struct {
+	Manager       DockerManager
+	ContainerName string
+	Symlinks      []SymlinkTarget // In the order they were set up
+	Interval      time.Duration
+
+	// OnRepair runs after a repair, with why it was needed and the error, if
+	// the repair failed.
+	OnRepair func(reason string, err error)
+}
+
+// Run checks the container every Interval until ctx is canceled.
+func (w *HealthWatch) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = HealthCheckInterval
+	}
+	startedAt, err := w.Manager.StartedAt(ctx, w.ContainerName)
+	if err != nil {
+		slog.Debug("container health check failed", "container", w.ContainerName, "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			startedAt = w.check(ctx, startedAt)
+		}
+	}
+}
+
+// check runs one health check, given when the container was last seen to
+// start, and returns the start time to compare against next time.
+func (w *HealthWatch) check(ctx context.Context, startedAt time.Time) time.Time {
+	if !w.Manager.IsRunning(ctx, w.ContainerName) {
+		return startedAt
+	}
+
+	reason := ""
+	if current, err := w.Manager.StartedAt(ctx, w.ContainerName); err != nil {
+		slog.Debug("container health check failed", "container", w.ContainerName, "error", err)
+		return startedAt
+	} else if !current.Equal(startedAt) {
+		if !startedAt.IsZero() {
+			reason = "the container restarted"
+		}
+		startedAt = current
+	}
+	for _, s := range w.Symlinks {
+		if reason != "" {
+			break
+		}
+		ok, err := w.Manager.WorkspaceSymlinkOK(ctx, w.ContainerName, s.RepoID, s.WorkspaceDir)
+		if err != nil {
+			slog.Debug("container health check failed", "container", w.ContainerName, "error", err)
+			return startedAt
+		}
+		if !ok {
+			reason = fmt.Sprintf("%s/_docs was broken", s.WorkspaceDir)
+		}
+	}
+	if reason == "" {
+		return startedAt
+	}
+
+	slog.Warn("repairing workspace symlinks", "container", w.ContainerName, "reason", reason)
+	var repairErr error
+	for _, s := range w.Symlinks {
+		if repairErr = w.Manager.SetupWorkspaceSymlink(ctx, w.ContainerName, s.RepoID, s.WorkspaceDir); repairErr != nil {
+			break
+		}
+	}
+	if ctx.Err() == nil && w.OnRepair != nil {
+		w.OnRepair(reason, repairErr)
+	}
+	return startedAt
+}
@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeHealthManager answers the calls a HealthWatch makes.
+type fakeHealthManager struct {
+	DockerManager
+	running   bool
+	startedAt time.Time
+	broken    map[string]bool // By workspace dir
+	setups    []string
+}
+
+func (f *fakeHealthManager) IsRunning(context.Context, string) bool { return f.running }
+
+func (f *fakeHealthManager) StartedAt(context.Context, string) (time.Time, error) {
+	return f.startedAt, nil
+}
+
+func (f *fakeHealthManager) WorkspaceSymlinkOK(_ context.Context, _, _, workspaceDir string) (bool, error) {
+	return !f.broken[workspaceDir], nil
+}
+
+func (f *fakeHealthManager) SetupWorkspaceSymlink(_ context.Context, _, _, workspaceDir string) error {
+	f.setups = append(f.setups, workspaceDir)
+	delete(f.broken, workspaceDir)
+	return nil
+}
+
+func TestHealthWatchCheck(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	manager := &fakeHealthManager{running: true, startedAt: start, broken: map[string]bool{}}
+	var reasons []string
+	w := &HealthWatch{
+		Manager:       manager,
+		ContainerName: "claude-abc",
+		Symlinks:      []SymlinkTarget{{RepoID: "b", WorkspaceDir: "/workspaces/b"}, {RepoID: "a", WorkspaceDir: "/workspaces/a"}},
+		OnRepair:      func(reason string, err error) { reasons = append(reasons, reason) },
+	}
+
+	// Healthy: nothing to do
+	seen := w.check(ctx, start)
+	if len(manager.setups) != 0 {
+		t.Fatalf("healthy container repaired: %v", manager.setups)
+	}
+
+	// A broken symlink repairs every workspace, in setup order
+	manager.broken["/workspaces/a"] = true
+	seen = w.check(ctx, seen)
+	if len(manager.setups) != 2 || manager.setups[0] != "/workspaces/b" || manager.setups[1] != "/workspaces/a" {
+		t.Errorf("setups after broken symlink = %v", manager.setups)
+	}
+
+	// A restart repairs too, once
+	manager.setups = nil
+	manager.startedAt = start.Add(time.Minute)
+	seen = w.check(ctx, seen)
+	seen = w.check(ctx, seen)
+	if len(manager.setups) != 2 {
+		t.Errorf("setups after restart = %v", manager.setups)
+	}
+	if len(reasons) != 2 || reasons[0] != "/workspaces/a/_docs was broken" || reasons[1] != "the container restarted" {
+		t.Errorf("repair reasons = %q", reasons)
+	}
+
+	// A stopped container is left alone
+	manager.setups = nil
+	manager.running = false
+	manager.broken["/workspaces/b"] = true
+	w.check(ctx, seen)
+	if len(manager.setups) != 0 {
+		t.Errorf("stopped container repaired: %v", manager.setups)
+	}
+}
@@ -1,10 +1,14 @@
 package docker
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // validDockerNamePattern validates Docker container and image names.
@@ -12,6 +16,9 @@ import (
 // Image names may also contain a tag suffix (e.g., "image:tag").
 var validDockerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
 
+// dnsDomainPattern matches a DNS search domain such as corp.example.com.
+var dnsDomainPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*\.?$`)
+
 // ValidateDockerName checks if a name is valid for Docker container/image.
 func ValidateDockerName(name string) error {
 	if name == "" {
@@ -48,12 +55,137 @@ func validatePath(path, fieldName string) error {
 	return nil
 }
 
+// Container paths where workspaces are mounted.
+const (
+	ContainerWorkspaceDir  = "/workspace"  // Single workspace
+	ContainerWorkspacesDir = "/workspaces" // Parent of named workspaces in a multi-workspace container
+)
+
+// ContainerCABundlePath is where ContainerConfig.CABundle is mounted.
+const ContainerCABundlePath = "/etc/capsule/ca-certificates.pem"
+
+// Workspace is one of several projects mounted into a multi-workspace container.
+type Workspace struct {
+	Name   string // Directory name under /workspaces
+	Path   string // Host path
+	RepoID string
+}
+
+// ContainerPath returns where the workspace is mounted inside the container.
+func (w Workspace) ContainerPath() string {
+	return ContainerWorkspacesDir + "/" + w.Name
+}
+
+// RestartPolicy says whether Docker brings a container back after the
+// daemon restarts.
+type RestartPolicy string
+
+const (
+	// RestartNo leaves the container stopped; capsule starts it again when
+	// it re-attaches the session.
+	RestartNo RestartPolicy = "no"
+
+	// RestartUnlessStopped has Docker start the container again when the
+	// daemon comes back, unless capsule stopped it.
+	RestartUnlessStopped RestartPolicy = "unless-stopped"
+)
+
+// ParseRestartPolicy returns the policy named s. Empty is RestartNo.
+func ParseRestartPolicy(s string) (RestartPolicy, error) {
+	switch RestartPolicy(s) {
+	case "", RestartNo:
+		return RestartNo, nil
+	case RestartUnlessStopped:
+		return RestartUnlessStopped, nil
+	}
+	return "", fmt.Errorf("unknown restart policy %q (use %s or %s)", s, RestartNo, RestartUnlessStopped)
+}
+
 // ContainerConfig holds configuration for starting a container.
 type ContainerConfig struct {
 	ImageName        string
 	ContainerName    string
 	VolumeMountPoint string
 	WorkspacePath    string
+
+	// Workspaces, when set, mounts each workspace at /workspaces/<name> instead
+	// of mounting WorkspacePath at /workspace.
+	Workspaces []Workspace
+
+	// Untrusted mounts only this repository's folder and the volume's bin
+	// directory instead of the whole volume, so the credential home and
+	// auth directories never enter the container.
+	Untrusted bool
+	RepoID    string // Required when Untrusted
+
+	// AuthDir, when set, is mounted at /claude-env/auth in place of the
+	// volume's auth directory, e.g. a RAM disk holding this session's keys.
+	AuthDir string
+
+	// IdentityMountPoint, when set, is where the identity volume is mounted.
+	// Its auth/ directory and Claude Code login are mounted over the data
+	// volume's, so credentials live only in the identity volume. AuthDir
+	// still replaces auth/ if both are set.
+	IdentityMountPoint string
+
+	// SigningSocket is a host unix socket mounted at SigningSocketTarget.
+	SigningSocket       string
+	SigningSocketTarget string
+
+	// ClipboardSocket is a host unix socket mounted at ClipboardSocketTarget
+	// for the container's pbcopy and pbpaste.
+	ClipboardSocket       string
+	ClipboardSocketTarget string
+
+	// OpenSocket is a host unix socket mounted at OpenSocketTarget for the
+	// container's xdg-open, which opens URLs in the host browser.
+	OpenSocket       string
+	OpenSocketTarget string
+
+	// CABundle, when set, is a host PEM file of extra CA certificates mounted
+	// read-only at ContainerCABundlePath. Node, and so Claude Code, trusts
+	// them even in an image built before they were configured.
+	CABundle string
+
+	// DNS lists nameserver IPs for the container, replacing Docker's
+	// defaults, and DNSSearch the domains to search for short names.
+	DNS       []string
+	DNSSearch []string
+
+	// Env holds extra KEY=VALUE environment variables for the container.
+	Env []string
+
+	// Restart is the container's restart policy. Empty is RestartNo.
+	Restart RestartPolicy
+
+	// VolumePath and CapsuleVersion are recorded in the container's labels,
+	// along with its workspaces and repository IDs.
+	VolumePath     string
+	CapsuleVersion string
+}
+
+// repoIDs returns the repository IDs whose folders the container uses.
+func (c *ContainerConfig) repoIDs() []string {
+	if len(c.Workspaces) == 0 {
+		return []string{c.RepoID}
+	}
+	ids := make([]string, len(c.Workspaces))
+	for i, w := range c.Workspaces {
+		ids[i] = w.RepoID
+	}
+	return ids
+}
+
+// workspacePaths returns the host paths of the container's workspaces.
+func (c *ContainerConfig) workspacePaths() []string {
+	if len(c.Workspaces) == 0 {
+		return []string{c.WorkspacePath}
+	}
+	paths := make([]string, len(c.Workspaces))
+	for i, w := range c.Workspaces {
+		paths[i] = w.Path
+	}
+	return paths
 }
 
 // Validate checks that the container configuration is valid.
@@ -74,35 +206,203 @@ func (c *ContainerConfig) Validate() error {
 	if err := validatePath(c.WorkspacePath, "workspace path"); err != nil {
 		return err
 	}
+	// Validate named workspaces
+	names := make(map[string]bool)
+	for _, w := range c.Workspaces {
+		if !validDockerNamePattern.MatchString(w.Name) {
+			return fmt.Errorf("invalid workspace name %q: must start with alphanumeric and contain only [a-zA-Z0-9_.-]", w.Name)
+		}
+		if names[w.Name] {
+			return fmt.Errorf("duplicate workspace name %q", w.Name)
+		}
+		names[w.Name] = true
+		if err := validatePath(w.Path, "workspace path"); err != nil {
+			return err
+		}
+	}
+	if c.AuthDir != "" {
+		if c.Untrusted {
+			return fmt.Errorf("untrusted containers do not mount an auth directory")
+		}
+		if err := validatePath(c.AuthDir, "auth directory"); err != nil {
+			return err
+		}
+	}
+	if err := ValidateDNS(c.DNS, c.DNSSearch); err != nil {
+		return err
+	}
+	if _, err := ParseRestartPolicy(string(c.Restart)); err != nil {
+		return err
+	}
+	if c.CABundle != "" {
+		if err := validatePath(c.CABundle, "CA bundle"); err != nil {
+			return err
+		}
+	}
+	if c.SigningSocket != "" {
+		if err := validatePath(c.SigningSocket, "signing socket"); err != nil {
+			return err
+		}
+		if err := validatePath(c.SigningSocketTarget, "signing socket target"); err != nil {
+			return err
+		}
+	}
+	if c.ClipboardSocket != "" {
+		if err := validatePath(c.ClipboardSocket, "clipboard socket"); err != nil {
+			return err
+		}
+		if err := validatePath(c.ClipboardSocketTarget, "clipboard socket target"); err != nil {
+			return err
+		}
+	}
+	if c.OpenSocket != "" {
+		if err := validatePath(c.OpenSocket, "open socket"); err != nil {
+			return err
+		}
+		if err := validatePath(c.OpenSocketTarget, "open socket target"); err != nil {
+			return err
+		}
+	}
+	if c.Untrusted {
+		for _, repoID := range c.repoIDs() {
+			if repoID == "" {
+				return fmt.Errorf("repo ID is required for untrusted containers")
+			}
+			if strings.Contains(filepath.Clean(repoID), "..") || filepath.IsAbs(repoID) {
+				return fmt.Errorf("invalid repo ID: %q", repoID)
+			}
+		}
+	}
 	return nil
 }
 
-// DockerManager handles container operations.
+// ValidateDNS checks that servers are IP addresses and domains are DNS names.
+func ValidateDNS(servers, domains []string) error {
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid DNS server %q: must be an IP address", server)
+		}
+	}
+	for _, domain := range domains {
+		if !dnsDomainPattern.MatchString(domain) {
+			return fmt.Errorf("invalid DNS search domain %q", domain)
+		}
+	}
+	return nil
+}
+
+// ExitedContainer is a stopped container and docker's description of its state.
+type ExitedContainer struct {
+	Name   string
+	Status string // e.g. "Exited (137) 2 minutes ago"
+}
+
+// ExecOptions describes a command run with ExecCommand.
+type ExecOptions struct {
+	Command []string
+	Dir     string   // Working directory; empty is the image's WORKDIR
+	Env     []string // KEY=VALUE pairs set only for the command
+
+	// Stdin, if set, is attached to the command's standard input.
+	// Stdout and Stderr receive its output; nil discards it.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// LogOptions describes the output of Logs.
+type LogOptions struct {
+	Follow     bool   // Keep streaming new output until ctx is canceled
+	Tail       int    // Lines from the end to show; 0 shows everything
+	Since      string // Only output after this timestamp or duration, e.g. "10m"
+	Timestamps bool
+
+	// Stdout and Stderr receive the container's output streams.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DockerManager handles container operations. Canceling ctx stops the docker
+// command a method is running.
 type DockerManager interface {
 	// Start creates and starts a container with the given configuration.
-	Start(config ContainerConfig) error
+	Start(ctx context.Context, config ContainerConfig) error
 
 	// Stop stops and removes the container.
-	Stop(containerName string) error
+	Stop(ctx context.Context, containerName string) error
 
 	// IsRunning checks if a container with the given name is running.
-	IsRunning(containerName string) bool
+	IsRunning(ctx context.Context, containerName string) bool
+
+	// ListRunning returns the names of running capsule containers: those with
+	// capsule's labels, and unlabeled ones from older versions named claude-.
+	ListRunning(ctx context.Context) ([]string, error)
+
+	// ListLabeled returns the names of running containers created by capsule,
+	// found by the labels Start sets rather than by name. Containers created
+	// before capsule labeled them are not included.
+	ListLabeled(ctx context.Context) ([]string, error)
+
+	// ListExited returns capsule containers that have stopped but were not
+	// removed, which means they exited on their own rather than through Stop.
+	ListExited(ctx context.Context) ([]ExitedContainer, error)
+
+	// StartedAt returns when the container was last started.
+	StartedAt(ctx context.Context, containerName string) (time.Time, error)
+
+	// ExecSessions returns how many docker exec processes, such as shells,
+	// are running in the container.
+	ExecSessions(ctx context.Context, containerName string) (int, error)
+
+	// Labels returns the container's labels.
+	Labels(ctx context.Context, containerName string) (map[string]string, error)
+
+	// WorkspaceMount returns the host path mounted at /workspace in the container.
+	WorkspaceMount(ctx context.Context, containerName string) (string, error)
+
+	// MountSources returns the host paths bind-mounted into the container.
+	MountSources(ctx context.Context, containerName string) ([]string, error)
 
 	// Exec runs an interactive shell in the container and waits for it to exit.
-	Exec(containerName string) error
+	// env holds KEY=VALUE pairs set only for the shell, not in the container config.
+	// A non-empty record is a file the session is recorded to.
+	Exec(ctx context.Context, containerName string, env []string, record string) error
+
+	// ExecCommand runs a command in the container without a terminal and waits for it to exit.
+	ExecCommand(ctx context.Context, containerName string, opts ExecOptions) error
+
+	// Stats returns the container's current resource usage.
+	Stats(ctx context.Context, containerName string) (ContainerStats, error)
+
+	// Logs writes the container's output, as docker logs does.
+	Logs(ctx context.Context, containerName string, opts LogOptions) error
 
-	// SetupWorkspaceSymlink creates the _docs symlink inside the container.
-	SetupWorkspaceSymlink(containerName, repoID string) error
+	// CopyTo extracts a tar stream into dir in the container, as docker cp does.
+	CopyTo(ctx context.Context, containerName, dir string, archive io.Reader) error
+
+	// CopyFrom writes path in the container to w as a tar stream.
+	CopyFrom(ctx context.Context, containerName, path string, w io.Writer) error
+
+	// SetupWorkspaceSymlink creates the _docs symlink in workspaceDir inside the container.
+	SetupWorkspaceSymlink(ctx context.Context, containerName, repoID, workspaceDir string) error
+
+	// WorkspaceSymlinkOK reports whether the _docs symlink in workspaceDir
+	// inside the container still points at the repository's shadow docs.
+	WorkspaceSymlinkOK(ctx context.Context, containerName, repoID, workspaceDir string) (bool, error)
 
 	// RemoveContainer forcibly removes a container (running or stopped).
-	RemoveContainer(containerName string) error
+	RemoveContainer(ctx context.Context, containerName string) error
+
+	// WaitForDaemon polls until the Docker daemon answers, giving up after timeout.
+	WaitForDaemon(ctx context.Context, timeout time.Duration) error
 
-	// CheckTmpFileSharing verifies Docker Desktop is running and can access file mounts.
-	CheckTmpFileSharing() error
+	// CheckFileSharing verifies Docker Desktop is running and can bind mount dir,
+	// the directory encrypted volumes are mounted under.
+	CheckFileSharing(ctx context.Context, dir string) error
 
 	// RefreshMountCache forces Docker Desktop to refresh its VirtioFS cache for a mount point.
-	RefreshMountCache(mountPoint string) error
+	RefreshMountCache(ctx context.Context, mountPoint string) error
 
 	// ClearVMCache drops the Linux VM's kernel cache to fix VirtioFS stale mount issues.
-	ClearVMCache() error
+	ClearVMCache(ctx context.Context) error
 }
@@ -0,0 +1,36 @@
+package docker
+
+import "testing"
+
+func TestValidateDNS(t *testing.T) {
+	if err := ValidateDNS([]string{"10.0.0.53", "fd00::53"}, []string{"corp.example.com", "internal."}); err != nil {
+		t.Errorf("ValidateDNS() = %v", err)
+	}
+	if err := ValidateDNS([]string{"dns.corp"}, nil); err == nil {
+		t.Error("ValidateDNS() accepted a hostname as a server")
+	}
+	for _, domain := range []string{"-corp.com", "corp..com", "corp com", "--dns=8.8.8.8"} {
+		if err := ValidateDNS(nil, []string{domain}); err == nil {
+			t.Errorf("ValidateDNS() accepted search domain %q", domain)
+		}
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RestartPolicy
+		wantErr bool
+	}{
+		{in: "", want: RestartNo},
+		{in: "no", want: RestartNo},
+		{in: "unless-stopped", want: RestartUnlessStopped},
+		{in: "always", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRestartPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRestartPolicy(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
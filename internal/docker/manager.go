@@ -1,14 +1,26 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jeanhaley32/claude-capsule/internal/auth"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
 	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
 )
 
 // Timeout configuration for Docker commands
@@ -29,27 +41,50 @@ const (
 	CacheRefreshDelay = 2 * time.Second // Wait for Docker VirtioFS cache to refresh
 )
 
+// Reconnect configuration for when the Docker daemon goes away mid-session
+const (
+	DaemonReconnectTimeout = 2 * time.Minute // Long enough for Docker Desktop to restart
+	daemonPollInterval     = 2 * time.Second
+)
+
 const (
 	DefaultImageName     = "claude-capsule:latest"
 	DefaultContainerName = "claude-capsule"
 )
 
+// ImageName returns the image built for an image flavor and bootstrap
+// template, e.g. claude-capsule-go-slim:latest. The standard flavor without a
+// template is DefaultImageName.
+func ImageName(f embedded.Flavor, t embedded.Template) string {
+	name := "claude-capsule"
+	if t != "" {
+		name += "-" + string(t)
+	}
+	if f != embedded.DefaultFlavor {
+		name += "-" + string(f)
+	}
+	return name + ":latest"
+}
+
 // Manager implements DockerManager using the Docker CLI.
-type Manager struct{}
+type Manager struct {
+	desktopOnce sync.Once
+	desktop     bool
+}
 
 // NewManager creates a new Docker manager.
 func NewManager() *Manager {
 	return &Manager{}
 }
 
-func (m *Manager) Start(config ContainerConfig) error {
+func (m *Manager) Start(ctx context.Context, config ContainerConfig) error {
 	// Validate configuration
 	if err := config.Validate(); err != nil {
 		return fmt.Errorf("invalid container config: %w", err)
 	}
 
 	// Check if Docker is running
-	if err := m.checkDockerRunning(); err != nil {
+	if err := m.checkDockerRunning(ctx); err != nil {
 		return err
 	}
 
@@ -60,13 +95,13 @@ func (m *Manager) Start(config ContainerConfig) error {
 	}
 
 	// Check if container already exists
-	if m.containerExists(config.ContainerName) {
-		if m.IsRunning(config.ContainerName) {
+	if m.containerExists(ctx, config.ContainerName) {
+		if m.IsRunning(ctx, config.ContainerName) {
 			// Already running, nothing to do
 			return nil
 		}
 		// Exists but not running, remove it
-		if err := m.RemoveContainer(config.ContainerName); err != nil {
+		if err := m.RemoveContainer(ctx, config.ContainerName); err != nil {
 			return fmt.Errorf("failed to remove existing container: %w", err)
 		}
 	}
@@ -75,39 +110,145 @@ func (m *Manager) Start(config ContainerConfig) error {
 	// Override entrypoint since Dockerfile uses /bin/bash which doesn't work with tail command
 	// Set HOME to encrypted volume so credentials and user data persist
 	startTimeout := 30 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
-	defer cancel()
+
+	if config.Untrusted {
+		// Create the folders the untrusted container mounts from the volume
+		for _, dir := range config.untrustedDirs() {
+			if err := os.MkdirAll(dir, constants.DirPermissions); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+		}
+	}
+	args := RunArgs(config)
+
+	// A daemon restart can interrupt 'docker run' after it created the
+	// container, so remove any partial container before trying again
+	runPolicy := DaemonRetry
+	runPolicy.OnRetry = func(error, int) {
+		_ = m.RemoveContainer(ctx, config.ContainerName)
+	}
+	err := runPolicy.Do(ctx, "start container", func(int) error {
+		cmdCtx, cancel := context.WithTimeout(ctx, startTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(cmdCtx, "docker", args...)
+		done := logging.Command(cmd)
+
+		// Capture stderr so callers can classify the failure
+		output, err := cmd.CombinedOutput()
+		done(err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("container start interrupted: %w", ctx.Err())
+			}
+			if cmdCtx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("container start timed out after %v", startTimeout)
+			}
+			return fmt.Errorf("failed to start container: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("container started", "container", config.ContainerName, "image", config.ImageName)
+
+	return nil
+}
+
+// RunArgs returns the 'docker run' arguments, after "docker", that Start uses
+// to create the container described by config.
+func RunArgs(config ContainerConfig) []string {
+	args := []string{"run", "-d", "--name", config.ContainerName,
+		"--label", constants.LabelVersion + "=" + config.CapsuleVersion,
+		"--label", constants.LabelVolume + "=" + config.VolumePath,
+		"--label", constants.LabelWorkspace + "=" + strings.Join(config.workspacePaths(), string(os.PathListSeparator)),
+		"--label", constants.LabelRepoID + "=" + strings.Join(config.repoIDs(), ","),
+	}
+	if config.Untrusted {
+		// Only these repositories' docs and the installed tools; HOME stays in the container
+		binDir := filepath.Join(config.VolumeMountPoint, "bin")
+		for _, repoID := range config.repoIDs() {
+			repoDir := filepath.Join(config.VolumeMountPoint, "repos", repoID)
+			args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=/claude-env/repos/%s,consistency=delegated", repoDir, repoID))
+		}
+		args = append(args,
+			"--mount", fmt.Sprintf("type=bind,source=%s,target=/claude-env/bin,readonly", binDir),
+			"-e", "HOME=/home/claude",
+		)
+	} else {
+		volumeMount := fmt.Sprintf("type=bind,source=%s,target=/claude-env,consistency=delegated", config.VolumeMountPoint)
+		args = append(args, "--mount", volumeMount, "-e", "HOME=/claude-env/home")
+		authDir := config.AuthDir
+		if authDir == "" && config.IdentityMountPoint != "" {
+			authDir = filepath.Join(config.IdentityMountPoint, "auth")
+		}
+		if authDir != "" {
+			args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=/claude-env/auth", authDir))
+		}
+		if config.IdentityMountPoint != "" {
+			args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=/claude-env/%s",
+				filepath.Join(config.IdentityMountPoint, auth.OAuthCredentialsFile), auth.OAuthCredentialsFile))
+		}
+	}
 
 	// Use --mount with consistency=delegated to reduce Docker Desktop caching issues
 	// delegated mode gives container authority over filesystem state
-	volumeMount := fmt.Sprintf("type=bind,source=%s,target=/claude-env,consistency=delegated", config.VolumeMountPoint)
-	workspaceMount := fmt.Sprintf("type=bind,source=%s,target=/workspace,consistency=delegated", config.WorkspacePath)
-
-	cmd := exec.CommandContext(ctx, "docker", "run",
-		"-d",
-		"--name", config.ContainerName,
-		"--mount", volumeMount,
-		"--mount", workspaceMount,
-		"-w", "/workspace",
-		"-e", "HOME=/claude-env/home",
+	workDir := ContainerWorkspaceDir
+	if len(config.Workspaces) > 0 {
+		for _, w := range config.Workspaces {
+			args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s,consistency=delegated", w.Path, w.ContainerPath()))
+		}
+		workDir = ContainerWorkspacesDir
+	} else {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s,consistency=delegated", config.WorkspacePath, ContainerWorkspaceDir))
+	}
+	if config.SigningSocket != "" {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s", config.SigningSocket, config.SigningSocketTarget))
+	}
+	if config.ClipboardSocket != "" {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s", config.ClipboardSocket, config.ClipboardSocketTarget))
+	}
+	if config.OpenSocket != "" {
+		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s", config.OpenSocket, config.OpenSocketTarget))
+	}
+	for _, server := range config.DNS {
+		args = append(args, "--dns", server)
+	}
+	for _, domain := range config.DNSSearch {
+		args = append(args, "--dns-search", domain)
+	}
+	if config.CABundle != "" {
+		args = append(args,
+			"--mount", fmt.Sprintf("type=bind,source=%s,target=%s,readonly", config.CABundle, ContainerCABundlePath),
+			"-e", "NODE_EXTRA_CA_CERTS="+ContainerCABundlePath)
+	}
+	for _, env := range config.Env {
+		args = append(args, "-e", env)
+	}
+	if config.Restart == RestartUnlessStopped {
+		args = append(args, "--restart", string(RestartUnlessStopped))
+	}
+	args = append(args,
+		"-w", workDir,
 		"--entrypoint", "tail",
 		config.ImageName,
 		"-f", "/dev/null", // Keep container running
 	)
+	return args
+}
 
-	// Capture stderr to include in error message for retry logic
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("container start timed out after %v", startTimeout)
-		}
-		return fmt.Errorf("failed to start container: %w: %s", err, strings.TrimSpace(string(output)))
+// untrustedDirs returns the volume folders an untrusted container mounts,
+// which must exist before it is created.
+func (c *ContainerConfig) untrustedDirs() []string {
+	dirs := []string{filepath.Join(c.VolumeMountPoint, "bin")}
+	for _, repoID := range c.repoIDs() {
+		dirs = append(dirs, filepath.Join(c.VolumeMountPoint, "repos", repoID))
 	}
-
-	return nil
+	return dirs
 }
 
-func (m *Manager) Stop(containerName string) error {
+func (m *Manager) Stop(ctx context.Context, containerName string) error {
 	if containerName == "" {
 		containerName = DefaultContainerName
 	}
@@ -118,33 +259,36 @@ func (m *Manager) Stop(containerName string) error {
 	}
 
 	// Check if container exists
-	if !m.containerExists(containerName) {
+	if !m.containerExists(ctx, containerName) {
 		return nil // Nothing to stop
 	}
 
 	// Stop container with timeout
-	if err := m.runCommandWithTimeout(defaultCommandTimeout, "docker", "stop", containerName); err != nil {
+	if err := m.runCommandWithTimeout(ctx, defaultCommandTimeout, "docker", "stop", containerName); err != nil {
 		// Try to force stop - log but don't fail if kill also fails
 		// The container may have already stopped between the stop and kill commands
-		if killErr := m.runCommandWithTimeout(defaultCommandTimeout, "docker", "kill", containerName); killErr != nil {
+		if killErr := m.runCommandWithTimeout(ctx, defaultCommandTimeout, "docker", "kill", containerName); killErr != nil {
 			// Only return error if container still exists after both attempts
-			if m.containerExists(containerName) && m.IsRunning(containerName) {
+			if m.containerExists(ctx, containerName) && m.IsRunning(ctx, containerName) {
 				return fmt.Errorf("failed to stop container: stop error: %v, kill error: %v", err, killErr)
 			}
 		}
 	}
 
 	// Remove container
-	return m.RemoveContainer(containerName)
+	if err := m.RemoveContainer(ctx, containerName); err != nil {
+		return err
+	}
+	slog.Info("container stopped", "container", containerName)
+	return nil
 }
 
-func (m *Manager) IsRunning(containerName string) bool {
+func (m *Manager) IsRunning(ctx context.Context, containerName string) bool {
 	if containerName == "" {
 		containerName = DefaultContainerName
 	}
 
-	cmd := exec.Command("docker", "inspect", "-f", "{{.State.Running}}", containerName)
-	output, err := cmd.Output()
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f", "{{.State.Running}}", containerName)
 	if err != nil {
 		return false
 	}
@@ -152,25 +296,308 @@ func (m *Manager) IsRunning(containerName string) bool {
 	return strings.TrimSpace(string(output)) == "true"
 }
 
+// ListLabeled returns the names of running containers that carry capsule's
+// labels.
+func (m *Manager) ListLabeled(ctx context.Context) ([]string, error) {
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "ps",
+		"--filter", "label="+constants.LabelVersion, "--format", "{{.Names}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// containerListFormat is the docker ps format parseContainerList reads.
+const containerListFormat = "{{.Names}}\t{{.Status}}\t{{.Labels}}"
+
+// parseContainerList returns the capsule containers in docker ps output in
+// containerListFormat: those with capsule's labels, whatever their name, and
+// those created before capsule labeled containers, by their claude- name.
+func parseContainerList(output string) []ExitedContainer {
+	var containers []ExitedContainer
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 3)
+		if fields[0] == "" {
+			continue
+		}
+		labeled := false
+		if len(fields) == 3 {
+			for _, label := range strings.Split(fields[2], ",") {
+				if key, _, _ := strings.Cut(label, "="); key == constants.LabelVersion {
+					labeled = true
+				}
+			}
+		}
+		if !labeled && !strings.HasPrefix(fields[0], constants.ContainerNamePrefix) {
+			continue
+		}
+		c := ExitedContainer{Name: fields[0]}
+		if len(fields) > 1 {
+			c.Status = fields[1]
+		}
+		containers = append(containers, c)
+	}
+	return containers
+}
+
+// ListRunning returns the names of running capsule containers.
+func (m *Manager) ListRunning(ctx context.Context) ([]string, error) {
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "ps", "--format", containerListFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	var names []string
+	for _, c := range parseContainerList(string(output)) {
+		names = append(names, c.Name)
+	}
+	return names, nil
+}
+
+// ListExited returns capsule containers that have stopped but were not removed.
+func (m *Manager) ListExited(ctx context.Context) ([]ExitedContainer, error) {
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "ps", "-a",
+		"--filter", "status=exited", "--format", containerListFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return parseContainerList(string(output)), nil
+}
+
+// StartedAt returns when the container was last started.
+func (m *Manager) StartedAt(ctx context.Context, containerName string) (time.Time, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f", "{{.State.StartedAt}}", containerName)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	startedAt, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(output)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse start time of %s: %w", containerName, err)
+	}
+	return startedAt, nil
+}
+
+// ExecSessions returns how many docker exec processes, such as shells opened
+// by start or enter, are running in the container.
+func (m *Manager) ExecSessions(ctx context.Context, containerName string) (int, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f", "{{len .ExecIDs}}", containerName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse exec sessions of %s: %w", containerName, err)
+	}
+	return count, nil
+}
+
+// Labels returns the container's labels.
+func (m *Manager) Labels(ctx context.Context, containerName string) (map[string]string, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f", "{{json .Config.Labels}}", containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(output, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels of %s: %w", containerName, err)
+	}
+	return labels, nil
+}
+
+// WorkspaceMount returns the host path mounted at /workspace in the container.
+func (m *Manager) WorkspaceMount(ctx context.Context, containerName string) (string, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f",
+		`{{range .Mounts}}{{if eq .Destination "`+ContainerWorkspaceDir+`"}}{{.Source}}{{end}}{{end}}`, containerName)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// MountSources returns the host paths bind-mounted into the container.
+func (m *Manager) MountSources(ctx context.Context, containerName string) ([]string, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "inspect", "-f",
+		`{{range .Mounts}}{{println .Source}}{{end}}`, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	var sources []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			sources = append(sources, line)
+		}
+	}
+	return sources, nil
+}
+
 // Exec runs an interactive shell in the container and waits for it to exit.
 // This allows cleanup to happen after the user exits the shell.
-func (m *Manager) Exec(containerName string) error {
+//
+// env is passed by name only (-e NAME) with values in the docker CLI's own
+// environment, so they never appear in process arguments or the container's
+// stored config. If record is set, script(1) also writes the session there.
+func (m *Manager) Exec(ctx context.Context, containerName string, env []string, record string) error {
 	if containerName == "" {
 		containerName = DefaultContainerName
 	}
 
-	cmd := exec.Command("docker", "exec", "-it", containerName, "/usr/bin/fish")
+	args := []string{"exec", "-it"}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		args = append(args, "-e", name)
+	}
+	args = append(args, containerName, "/usr/bin/fish")
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	if record != "" {
+		cmd = exec.CommandContext(ctx, "script", scriptArgs(runtime.GOOS, record, append([]string{"docker"}, args...))...)
+	}
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
+	done := logging.Command(cmd)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	// Run and wait for user to exit
-	return cmd.Run()
+	err := cmd.Run()
+	done(err)
+	return err
+}
+
+// scriptArgs returns the script(1) arguments that run command while writing
+// the terminal session to record, flushing as it goes. BSD script on macOS
+// takes the command as arguments; util-linux script takes one shell string.
+func scriptArgs(goos, record string, command []string) []string {
+	if goos == "darwin" {
+		return append([]string{"-q", "-F", record}, command...)
+	}
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return []string{"-q", "-f", "-c", strings.Join(quoted, " "), record}
+}
+
+// ExecCommand runs a command in the container without a terminal and waits
+// for it to exit. Env is passed by name only, as with Exec.
+func (m *Manager) ExecCommand(ctx context.Context, containerName string, opts ExecOptions) error {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+
+	args := []string{"exec"}
+	if opts.Stdin != nil {
+		args = append(args, "-i")
+	}
+	if opts.Dir != "" {
+		args = append(args, "-w", opts.Dir)
+	}
+	for _, kv := range opts.Env {
+		name, _, _ := strings.Cut(kv, "=")
+		args = append(args, "-e", name)
+	}
+	args = append(args, containerName)
+	args = append(args, opts.Command...)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	done := logging.Command(cmd)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	err := cmd.Run()
+	done(err)
+	return err
+}
+
+// Logs writes the container's output, as docker logs does.
+func (m *Manager) Logs(ctx context.Context, containerName string, opts LogOptions) error {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	args := []string{"logs"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	args = append(args, containerName)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	done := logging.Command(cmd)
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	err := cmd.Run()
+	done(err)
+	return err
+}
+
+// CopyTo extracts a tar stream into dir in the container.
+func (m *Manager) CopyTo(ctx context.Context, containerName, dir string, archive io.Reader) error {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	return m.copy(ctx, archive, nil, "-", containerName+":"+dir)
+}
+
+// CopyFrom writes path in the container to w as a tar stream.
+func (m *Manager) CopyFrom(ctx context.Context, containerName, path string, w io.Writer) error {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	return m.copy(ctx, nil, w, containerName+":"+path, "-")
+}
+
+func (m *Manager) copy(ctx context.Context, stdin io.Reader, stdout io.Writer, src, dst string) error {
+	cmd := exec.CommandContext(ctx, "docker", "cp", src, dst)
+	done := logging.Command(cmd)
+	var stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("docker cp failed: %s", msg)
+		}
+		return fmt.Errorf("docker cp failed: %w", err)
+	}
+	return nil
 }
 
-// SetupWorkspaceSymlink creates the _docs symlink inside the container.
+// SetupWorkspaceSymlink creates the _docs symlink in workspaceDir inside the container.
 // It waits for the container to be ready and then runs the setup script.
-func (m *Manager) SetupWorkspaceSymlink(containerName, repoID string) error {
+func (m *Manager) SetupWorkspaceSymlink(ctx context.Context, containerName, repoID, workspaceDir string) error {
 	if containerName == "" {
 		containerName = DefaultContainerName
 	}
@@ -179,99 +606,152 @@ func (m *Manager) SetupWorkspaceSymlink(containerName, repoID string) error {
 	}
 
 	// Wait for container to be running with retry
-	for i := 0; i < containerReadyMaxRetries; i++ {
-		if m.IsRunning(containerName) {
-			break
-		}
-		if i == containerReadyMaxRetries-1 {
-			return fmt.Errorf("container %s not running after %d retries", containerName, containerReadyMaxRetries)
+	err := containerReadyRetry.Do(ctx, "wait for container", func(int) error {
+		if !m.IsRunning(ctx, containerName) {
+			return errNotRunning
 		}
-		time.Sleep(containerReadyRetryDelay)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("container %s not running after %d retries", containerName, containerReadyMaxRetries)
 	}
 
 	// Run the setup script inside the container
-	ctx, cancel := context.WithTimeout(context.Background(), defaultCommandTimeout)
-	defer cancel()
+	return DaemonRetry.Do(ctx, "setup workspace symlink", func(int) error {
+		cmdCtx, cancel := context.WithTimeout(ctx, defaultCommandTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(cmdCtx, "docker", "exec", containerName,
+			"setup-workspace-symlink.sh", repoID, workspaceDir)
+		done := logging.Command(cmd)
+		output, err := cmd.CombinedOutput()
+		done(err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if cmdCtx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("symlink setup timed out after %v", defaultCommandTimeout)
+			}
+			return fmt.Errorf("failed to setup workspace symlink: %w\nOutput: %s", err, string(output))
+		}
+		return nil
+	})
+}
 
-	cmd := exec.CommandContext(ctx, "docker", "exec", containerName,
-		"setup-workspace-symlink.sh", repoID)
-	output, err := cmd.CombinedOutput()
+// WorkspaceSymlinkOK reports whether the _docs symlink in workspaceDir inside
+// the container still points at the repository's shadow docs directory.
+func (m *Manager) WorkspaceSymlinkOK(ctx context.Context, containerName, repoID, workspaceDir string) (bool, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	link := workspaceDir + "/_docs"
+	target := "/claude-env/repos/" + repoID
+	_, err := m.getCommandOutputWithTimeout(ctx, quickCommandTimeout, "docker", "exec", containerName,
+		"sh", "-c", `[ "$(readlink "$1")" = "$2" ] && [ -d "$1/" ]`, "sh", link, target)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("symlink setup timed out after %v", defaultCommandTimeout)
-		}
-		return fmt.Errorf("failed to setup workspace symlink: %w\nOutput: %s", err, string(output))
+		return false, fmt.Errorf("failed to check %s in %s: %w", link, containerName, err)
 	}
-
-	return nil
+	return true, nil
 }
 
-// runCommandWithTimeout runs a command with a timeout.
-func (m *Manager) runCommandWithTimeout(timeout time.Duration, name string, args ...string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	err := cmd.Run()
-
-	if ctx.Err() == context.DeadlineExceeded {
-		return fmt.Errorf("command timed out after %v", timeout)
-	}
+// runCommandWithTimeout runs a command with a timeout, retrying while the
+// Docker daemon restarts.
+func (m *Manager) runCommandWithTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) error {
+	_, err := m.getCommandOutputWithTimeout(ctx, timeout, name, args...)
 	return err
 }
 
-// getCommandOutputWithTimeout runs a command and returns output with a timeout.
-func (m *Manager) getCommandOutputWithTimeout(timeout time.Duration, name string, args ...string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, name, args...)
-	output, err := cmd.Output()
-
-	if ctx.Err() == context.DeadlineExceeded {
-		return nil, fmt.Errorf("command timed out after %v", timeout)
+// getCommandOutputWithTimeout runs a command and returns output with a timeout,
+// retrying while the Docker daemon restarts. Errors include the command's
+// stderr so they can be classified.
+func (m *Manager) getCommandOutputWithTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	var output []byte
+	err := DaemonRetry.Do(ctx, strings.Join(append([]string{name}, args...), " "), func(int) error {
+		cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(cmdCtx, name, args...)
+		done := logging.Command(cmd)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		var err error
+		output, err = cmd.Output()
+		done(err)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("command timed out after %v", timeout)
+		}
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%w: %s", err, msg)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return output, err
+	return output, nil
 }
 
 // checkDockerRunning verifies Docker daemon is running.
-func (m *Manager) checkDockerRunning() error {
-	if err := m.runCommandWithTimeout(defaultCommandTimeout, "docker", "info"); err != nil {
-		return fmt.Errorf("Docker is not running. Please start Docker Desktop: %w", err)
+func (m *Manager) checkDockerRunning(ctx context.Context) error {
+	if err := m.runCommandWithTimeout(ctx, defaultCommandTimeout, "docker", "info"); err != nil {
+		return fmt.Errorf("%w. Please start Docker Desktop: %w", ErrDaemonUnavailable, err)
 	}
 	return nil
 }
 
-// CheckTmpFileSharing verifies Docker Desktop is running and can access file mounts.
-// We mount encrypted volumes to /Volumes via hdiutil, which has system entitlements.
-func (m *Manager) CheckTmpFileSharing() error {
-	// Just verify Docker is running and can do basic file mounts
-	// We can't test /Volumes directly (protected by macOS), but hdiutil can mount there
-	// Test with /tmp to verify Docker's file sharing is working in general
-	ctx, cancel := context.WithTimeout(context.Background(), quickCommandTimeout)
+// WaitForDaemon polls until the Docker daemon answers, giving up after timeout.
+func (m *Manager) WaitForDaemon(ctx context.Context, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.runCommandWithTimeout(waitCtx, quickCommandTimeout, "docker", "info"); err == nil {
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("Docker did not come back within %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
 
-	cmd := exec.CommandContext(ctx, "docker", "run", "--rm",
-		"-v", "/tmp:/test:ro",
-		"alpine", "ls", "/test")
-
-	output, err := cmd.CombinedOutput()
+// CheckFileSharing verifies Docker Desktop is running and can bind mount dir,
+// the directory encrypted volumes are mounted under. Volume mount points are
+// created inside it, so if dir is shared, they are too.
+func (m *Manager) CheckFileSharing(ctx context.Context, dir string) error {
+	output, err := m.runHelper(ctx, false, []string{"-v", dir + ":/test:ro"}, "/test")
 	if err != nil {
-		return fmt.Errorf(`Docker cannot access host filesystem for file sharing.
+		if ctx.Err() != nil {
+			return fmt.Errorf("file sharing check interrupted: %w", ctx.Err())
+		}
+		return fmt.Errorf(`Docker cannot access %s for file sharing.
 
-Please ensure Docker Desktop is running and file sharing is enabled:
+Please ensure Docker Desktop is running and shares this directory:
   1. Open Docker Desktop
   2. Go to Settings (gear icon) → Resources → File sharing
-  3. Verify file sharing is enabled
+  3. Verify %s or one of its parents is listed, or add it
   4. Click "Apply & Restart" if you make changes
 
-Error: %s`, strings.TrimSpace(string(output)))
-	}
+Or set mount_dir in ~/.capsule/config.json to a shared directory.
 
-	// If we got output, the mount worked
-	_ = output
+Error: %s`, dir, dir, strings.TrimSpace(string(output)))
+	}
 	return nil
 }
 
@@ -279,48 +759,88 @@ Error: %s`, strings.TrimSpace(string(output)))
 // This is necessary because Docker Desktop's VirtioFS layer caches mount information,
 // and encrypted volumes that appear/disappear can cause stale cache entries.
 // By running a container that mounts the specific path, we force VirtioFS to re-scan.
-func (m *Manager) RefreshMountCache(mountPoint string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), quickCommandTimeout)
-	defer cancel()
-
-	// Mount the actual path we'll be using - this forces VirtioFS to refresh its view
-	cmd := exec.CommandContext(ctx, "docker", "run", "--rm",
-		"-v", mountPoint+":/refresh-check:ro",
-		"alpine", "ls", "/refresh-check")
-
-	_, err := cmd.CombinedOutput()
-	// We don't care about the output, just that Docker accessed the path
-	// This refreshes VirtioFS's internal cache for this mount point
-	if err != nil {
-		// If this fails, the actual mount will likely fail too, but we'll let that
-		// error be reported with more context
+// Other runtimes don't cache mounts this way, so nothing is run for them.
+func (m *Manager) RefreshMountCache(ctx context.Context, mountPoint string) error {
+	if !m.isDockerDesktop(ctx) {
 		return nil
 	}
-
+	// Mount the actual path we'll be using - this forces VirtioFS to refresh its view.
+	// If this fails, the actual mount will likely fail too, but we'll let that
+	// error be reported with more context.
+	m.runHelper(ctx, false, []string{"-v", mountPoint + ":/refresh-check:ro"}, "/refresh-check")
 	return nil
 }
 
 // ClearVMCache drops the Linux VM's kernel cache to release VirtioFS file handles.
 // This clears page cache, dentries, and inodes which may hold stale references
-// to mount points that have been unmounted and remounted.
-func (m *Manager) ClearVMCache() error {
-	ctx, cancel := context.WithTimeout(context.Background(), quickCommandTimeout)
-	defer cancel()
+// to mount points that have been unmounted and remounted. Like
+// RefreshMountCache, it does nothing outside Docker Desktop.
+func (m *Manager) ClearVMCache(ctx context.Context) error {
+	if !m.isDockerDesktop(ctx) {
+		return nil
+	}
+	output, err := m.runHelper(ctx, true, nil, "-drop-caches")
+	if err != nil {
+		return fmt.Errorf("failed to clear VM cache: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
 
-	// echo 3 drops page cache, dentries, and inodes
-	cmd := exec.CommandContext(ctx, "docker", "run", "--privileged", "--rm",
-		"alpine", "sh", "-c", "echo 3 > /proc/sys/vm/drop_caches")
+// runHelper runs a throwaway container from the helper image with args, and
+// the docker run options in runOptions, e.g. bind mounts, returning its
+// combined output. The helper image is imported the first time.
+func (m *Manager) runHelper(ctx context.Context, privileged bool, runOptions []string, args ...string) ([]byte, error) {
+	image, err := embedded.EnsureHelperImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, quickCommandTimeout)
+	defer cancel()
 
+	runArgs := []string{"run", "--rm"}
+	if privileged {
+		runArgs = append(runArgs, "--privileged")
+	}
+	runArgs = append(runArgs, runOptions...)
+	cmd := exec.CommandContext(ctx, "docker", append(append(runArgs, image), args...)...)
+	done := logging.Command(cmd)
 	output, err := cmd.CombinedOutput()
+	done(err)
+	return output, err
+}
+
+// isDockerDesktop reports whether the daemon is Docker Desktop's, whose
+// VirtioFS file sharing caches mounts. It asks the daemon once per Manager;
+// if it can't be asked, it assumes Docker Desktop, so the caches are still
+// cleared.
+func (m *Manager) isDockerDesktop(ctx context.Context) bool {
+	m.desktopOnce.Do(func() {
+		output, err := m.getCommandOutputWithTimeout(ctx, quickCommandTimeout, "docker", "info", "--format", "{{.OperatingSystem}}")
+		m.desktop = err != nil || isDockerDesktopOS(string(output))
+		slog.Debug("docker runtime", "operating_system", strings.TrimSpace(string(output)), "docker_desktop", m.desktop)
+	})
+	return m.desktop
+}
+
+// isDockerDesktopOS reports whether the operating system 'docker info'
+// reports is Docker Desktop's VM.
+func isDockerDesktopOS(operatingSystem string) bool {
+	return strings.Contains(operatingSystem, "Docker Desktop")
+}
+
+// ServerVersion returns the Docker daemon's version, which changes with each
+// Docker Desktop update.
+func (m *Manager) ServerVersion(ctx context.Context) (string, error) {
+	output, err := m.getCommandOutputWithTimeout(ctx, quickCommandTimeout, "docker", "version", "--format", "{{.Server.Version}}")
 	if err != nil {
-		return fmt.Errorf("failed to clear VM cache: %w: %s", err, strings.TrimSpace(string(output)))
+		return "", fmt.Errorf("%w: %w", ErrDaemonUnavailable, err)
 	}
-	return nil
+	return strings.TrimSpace(string(output)), nil
 }
 
 // containerExists checks if a container exists (running or stopped).
-func (m *Manager) containerExists(containerName string) bool {
-	output, err := m.getCommandOutputWithTimeout(defaultCommandTimeout, "docker", "ps", "-a", "-q", "-f", "name=^"+containerName+"$")
+func (m *Manager) containerExists(ctx context.Context, containerName string) bool {
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "ps", "-a", "-q", "-f", "name=^"+containerName+"$")
 	if err != nil {
 		return false
 	}
@@ -328,6 +848,6 @@ func (m *Manager) containerExists(containerName string) bool {
 }
 
 // RemoveContainer forcibly removes a container (running or stopped).
-func (m *Manager) RemoveContainer(containerName string) error {
-	return m.runCommandWithTimeout(defaultCommandTimeout, "docker", "rm", "-f", containerName)
+func (m *Manager) RemoveContainer(ctx context.Context, containerName string) error {
+	return m.runCommandWithTimeout(ctx, defaultCommandTimeout, "docker", "rm", "-f", containerName)
 }
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+func TestScriptArgs(t *testing.T) {
+	command := []string{"docker", "exec", "-it", "claude-abc", "/usr/bin/fish"}
+
+	got := scriptArgs("darwin", "/tmp/s.log", command)
+	want := []string{"-q", "-F", "/tmp/s.log", "docker", "exec", "-it", "claude-abc", "/usr/bin/fish"}
+	if !slices.Equal(got, want) {
+		t.Errorf("scriptArgs(darwin) = %q, want %q", got, want)
+	}
+
+	got = scriptArgs("linux", "/tmp/s.log", []string{"echo", "it's"})
+	want = []string{"-q", "-f", "-c", `'echo' 'it'\''s'`, "/tmp/s.log"}
+	if !slices.Equal(got, want) {
+		t.Errorf("scriptArgs(linux) = %q, want %q", got, want)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	config := ContainerConfig{
+		ImageName:        "claude-capsule:latest",
+		ContainerName:    "claude-abc",
+		VolumeMountPoint: "/mnt/Capsule-abc",
+		WorkspacePath:    "/src/project",
+		RepoID:           "abc",
+		Restart:          RestartUnlessStopped,
+		VolumePath:       "/vol/capsule.sparseimage",
+		CapsuleVersion:   "0.3.0",
+	}
+	want := []string{
+		"run", "-d", "--name", "claude-abc",
+		"--label", constants.LabelVersion + "=0.3.0",
+		"--label", constants.LabelVolume + "=/vol/capsule.sparseimage",
+		"--label", constants.LabelWorkspace + "=/src/project",
+		"--label", constants.LabelRepoID + "=abc",
+		"--mount", "type=bind,source=/mnt/Capsule-abc,target=/claude-env,consistency=delegated",
+		"-e", "HOME=/claude-env/home",
+		"--mount", "type=bind,source=/src/project,target=" + ContainerWorkspaceDir + ",consistency=delegated",
+		"--restart", "unless-stopped",
+		"-w", ContainerWorkspaceDir,
+		"--entrypoint", "tail",
+		"claude-capsule:latest",
+		"-f", "/dev/null",
+	}
+	if got := RunArgs(config); !slices.Equal(got, want) {
+		t.Errorf("RunArgs() = %q, want %q", got, want)
+	}
+
+	identity := config
+	identity.IdentityMountPoint = "/mnt/Capsule-id"
+	got := RunArgs(identity)
+	for _, mount := range []string{
+		"type=bind,source=/mnt/Capsule-id/auth,target=/claude-env/auth",
+		"type=bind,source=/mnt/Capsule-id/home/.claude/.credentials.json,target=/claude-env/home/.claude/.credentials.json",
+	} {
+		if !slices.Contains(got, mount) {
+			t.Errorf("RunArgs(identity) = %q, want %s", got, mount)
+		}
+	}
+
+	config.Untrusted = true
+	got = RunArgs(config)
+	if !slices.Contains(got, "HOME=/home/claude") || slices.Contains(got, "HOME=/claude-env/home") {
+		t.Errorf("RunArgs(untrusted) = %q, want the container's own HOME", got)
+	}
+	if !slices.Contains(got, "type=bind,source=/mnt/Capsule-abc/repos/abc,target=/claude-env/repos/abc,consistency=delegated") {
+		t.Errorf("RunArgs(untrusted) = %q, want only the repo's folder mounted", got)
+	}
+}
+
+func TestParseContainerList(t *testing.T) {
+	output := "claude-abc\tUp 2 hours\t" + constants.LabelVersion + "=0.3.0," + constants.LabelRepoID + "=abc\n" +
+		"renamed\tExited (0) 1 minute ago\t" + constants.LabelVersion + "=0.3.0\n" +
+		"claude-old\tUp 3 days\t\n" +
+		"postgres\tUp 1 hour\tcom.example.team=db\n"
+	got := parseContainerList(output)
+	want := []ExitedContainer{
+		{Name: "claude-abc", Status: "Up 2 hours"},
+		{Name: "renamed", Status: "Exited (0) 1 minute ago"},
+		{Name: "claude-old", Status: "Up 3 days"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseContainerList() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIsDockerDesktopOS(t *testing.T) {
+	tests := map[string]bool{
+		"Docker Desktop\n":   true,
+		"Ubuntu 24.04.1 LTS": false,
+		"OrbStack":           false,
+		"":                   false,
+	}
+	for os, want := range tests {
+		if got := isDockerDesktopOS(os); got != want {
+			t.Errorf("isDockerDesktopOS(%q) = %v, want %v", os, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// proxyVars are the proxy settings passed from the host to image builds and
+// containers. Docker treats these as predefined build args, so they are
+// never recorded in the image's history.
+var proxyVars = []string{
+	"HTTP_PROXY", "HTTPS_PROXY", "FTP_PROXY", "ALL_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "ftp_proxy", "all_proxy", "no_proxy",
+}
+
+// dockerHostAlias is how containers and builds reach the host under Docker Desktop.
+const dockerHostAlias = "host.docker.internal"
+
+// HostProxyEnv returns the host's proxy settings as KEY=VALUE: the proxy
+// variables in capsule's environment or, if none are set, the macOS system
+// proxy for the current network. A proxy on the host's loopback address is
+// rewritten to host.docker.internal, since inside a container localhost is
+// the container itself.
+func HostProxyEnv(ctx context.Context) []string {
+	if env := proxyEnv(os.LookupEnv); len(env) > 0 {
+		return env
+	}
+	return systemProxyEnv(ctx)
+}
+
+// systemProxyEnv returns the macOS system proxy settings ('scutil --proxy')
+// as proxy variables, or nil if there are none or they can't be read.
+func systemProxyEnv(ctx context.Context) []string {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, quickCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "scutil", "--proxy")
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		slog.Debug("failed to read system proxy settings", "error", err)
+		return nil
+	}
+	settings := parseScutilProxy(string(output))
+	if settings["ProxyAutoConfigEnable"] == "1" {
+		slog.Warn("system proxy uses a PAC file, which can't be passed to containers; set HTTPS_PROXY instead")
+	}
+	vars := systemProxyVars(settings)
+	return proxyEnv(func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	})
+}
+
+// parseScutilProxy returns the keys of 'scutil --proxy' output. Array
+// entries, such as ExceptionsList, are joined with commas.
+func parseScutilProxy(output string) map[string]string {
+	settings := map[string]string{}
+	var array string
+	var items []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, ok := strings.Cut(line, " : ")
+		switch {
+		case array != "" && line == "}":
+			settings[array] = strings.Join(items, ",")
+			array, items = "", nil
+		case array != "" && ok:
+			items = append(items, value)
+		case ok && value == "<array> {":
+			array = key
+		case ok:
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// systemProxyVars maps macOS proxy settings to proxy variables, in both
+// cases since tools disagree on which they read.
+func systemProxyVars(settings map[string]string) map[string]string {
+	vars := map[string]string{}
+	set := func(name, value string) {
+		vars[name] = value
+		vars[strings.ToLower(name)] = value
+	}
+	proxy := func(kind, scheme string) string {
+		host, port := settings[kind+"Proxy"], settings[kind+"Port"]
+		if settings[kind+"Enable"] != "1" || host == "" {
+			return ""
+		}
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+		return scheme + "://" + host
+	}
+	if p := proxy("HTTP", "http"); p != "" {
+		set("HTTP_PROXY", p)
+	}
+	// Clients reach an HTTPS proxy with CONNECT over plain HTTP
+	if p := proxy("HTTPS", "http"); p != "" {
+		set("HTTPS_PROXY", p)
+	}
+	if p := proxy("SOCKS", "socks5"); p != "" {
+		set("ALL_PROXY", p)
+	}
+	if len(vars) == 0 {
+		return vars
+	}
+	// "*.corp" means every host under corp, which NO_PROXY spells ".corp"
+	var exceptions []string
+	for _, host := range strings.Split(settings["ExceptionsList"], ",") {
+		if host = strings.TrimPrefix(host, "*"); host != "" {
+			exceptions = append(exceptions, host)
+		}
+	}
+	if len(exceptions) > 0 {
+		set("NO_PROXY", strings.Join(exceptions, ","))
+	}
+	return vars
+}
+
+func proxyEnv(lookup func(string) (string, bool)) []string {
+	var env []string
+	for _, name := range proxyVars {
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+		if !strings.EqualFold(name, "NO_PROXY") {
+			value = rewriteLoopbackProxy(value)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// rewriteLoopbackProxy points a proxy URL on localhost at the Docker host.
+// Values that aren't URLs are returned unchanged.
+func rewriteLoopbackProxy(value string) string {
+	raw := value
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return value
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return value
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(dockerHostAlias, port)
+	} else {
+		u.Host = dockerHostAlias
+	}
+	if !strings.Contains(value, "://") {
+		return strings.TrimPrefix(u.String(), "http://")
+	}
+	return u.String()
+}
@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrorClass groups Docker CLI failures by how a caller should react to them.
+type ErrorClass int
+
+const (
+	// ErrorPermanent failures won't go away by trying again.
+	ErrorPermanent ErrorClass = iota
+	// ErrorDaemonRestarting means Docker Desktop is restarting or briefly
+	// dropped its API socket; the same command usually succeeds seconds later.
+	ErrorDaemonRestarting
+	// ErrorMountCache means Docker Desktop's VirtioFS layer holds a stale
+	// entry for a bind mount source, typically an encrypted volume that was
+	// remounted. Retrying only helps after the mount is released and recreated.
+	ErrorMountCache
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorDaemonRestarting:
+		return "daemon restarting"
+	case ErrorMountCache:
+		return "mount cache"
+	default:
+		return "permanent"
+	}
+}
+
+// Messages the Docker CLI and Docker Desktop print for each transient class,
+// matched case-insensitively against the error and its captured stderr.
+var (
+	daemonRestartingMessages = []string{
+		"daemon is restarting",
+		"is restarting, wait until",
+		"error during connect",
+		"connection reset by peer",
+		"502 bad gateway",
+		"503 service unavailable",
+		"unexpected eof",
+	}
+	// daemonUnavailableMessages mean no daemon answered at all
+	daemonUnavailableMessages = []string{
+		"cannot connect to the docker daemon",
+		"is the docker daemon running",
+	}
+	mountCacheMessages = []string{
+		"file exists",
+		"stale nfs file handle",
+		"stale file handle",
+	}
+)
+
+// Classify reports which class err belongs to. A daemon that isn't running
+// at all is permanent: the user has to start Docker Desktop.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorPermanent
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range mountCacheMessages {
+		if strings.Contains(msg, m) {
+			return ErrorMountCache
+		}
+	}
+	for _, m := range daemonRestartingMessages {
+		if strings.Contains(msg, m) {
+			return ErrorDaemonRestarting
+		}
+	}
+	return ErrorPermanent
+}
+
+// IsMountCacheError reports whether err is a stale Docker Desktop mount cache entry.
+func IsMountCacheError(err error) bool {
+	return Classify(err) == ErrorMountCache
+}
+
+// IsDaemonRestarting reports whether err came from a Docker daemon that is
+// temporarily unavailable.
+func IsDaemonRestarting(err error) bool {
+	return Classify(err) == ErrorDaemonRestarting
+}
+
+// ErrDaemonUnavailable is returned when Docker isn't running.
+var ErrDaemonUnavailable = errors.New("Docker is not running")
+
+// IsDaemonUnavailable reports whether err means the Docker daemon can't be
+// reached, because it isn't running or is restarting.
+func IsDaemonUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrDaemonUnavailable) || IsDaemonRestarting(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range daemonUnavailableMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy retries an operation with exponential backoff.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first.
+	Attempts int
+	// BaseDelay is the wait before the first retry; each retry doubles it.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between tries.
+	MaxDelay time.Duration
+	// Retryable decides whether an error is worth another try.
+	Retryable func(error) bool
+	// OnRetry, if set, runs after a retryable failure and before the wait,
+	// e.g. to release whatever the failed attempt left behind.
+	OnRetry func(err error, attempt int)
+
+	sleep func(time.Duration) // Replaced in tests
+}
+
+// Default policies for Docker operations.
+var (
+	// DaemonRetry rides out a Docker Desktop restart, about 7.5s in total.
+	DaemonRetry = RetryPolicy{
+		Attempts:  5,
+		BaseDelay: 500 * time.Millisecond,
+		MaxDelay:  4 * time.Second,
+		Retryable: IsDaemonRestarting,
+	}
+	// MountCacheRetry gives Docker Desktop time to refresh its VirtioFS cache
+	// between tries. Callers recreate the mount in OnRetry.
+	MountCacheRetry = RetryPolicy{
+		Attempts:  3,
+		BaseDelay: CacheRefreshDelay,
+		MaxDelay:  8 * time.Second,
+		Retryable: func(err error) bool {
+			return IsMountCacheError(err) || IsDaemonRestarting(err)
+		},
+	}
+)
+
+// Delay returns the wait before retry number attempt (1 for the first retry).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Do runs fn until it succeeds, fails with an error the policy doesn't retry,
+// runs out of attempts, or ctx is canceled. fn receives the attempt number,
+// starting at 0, so it can redo any setup a retry needs. The last error is
+// returned.
+func (p RetryPolicy) Do(ctx context.Context, op string, fn func(attempt int) error) error {
+	attempts := max(p.Attempts, 1)
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err = fn(attempt); err == nil {
+			return nil
+		}
+		if attempt == attempts-1 || ctx.Err() != nil || p.Retryable == nil || !p.Retryable(err) {
+			break
+		}
+		delay := p.Delay(attempt + 1)
+		slog.Warn("retrying docker operation", "op", op, "attempt", attempt+1, "class", Classify(err).String(), "delay", delay, "error", err)
+		if p.OnRetry != nil {
+			p.OnRetry(err, attempt+1)
+		}
+		if p.sleep != nil {
+			p.sleep(delay)
+			continue
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// errNotRunning is returned while waiting for a container to come up.
+var errNotRunning = errors.New("container not running")
+
+// containerReadyRetry polls for a container to start at a fixed interval.
+var containerReadyRetry = RetryPolicy{
+	Attempts:  containerReadyMaxRetries,
+	BaseDelay: containerReadyRetryDelay,
+	MaxDelay:  containerReadyRetryDelay,
+	Retryable: func(err error) bool { return errors.Is(err, errNotRunning) },
+}
@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, ErrorPermanent},
+		{errors.New("failed to start container: exit status 125: docker: Error response from daemon: error while creating mount source path '/Volumes/Capsule-1a2b3c4d': mkdir /Volumes/Capsule-1a2b3c4d: file exists."), ErrorMountCache},
+		{errors.New("exit status 1: Error response from daemon: stat /host_mnt/Volumes/Capsule-1a2b3c4d: stale NFS file handle"), ErrorMountCache},
+		{errors.New("exit status 1: Error response from daemon: Container 3f2a is restarting, wait until the container is running"), ErrorDaemonRestarting},
+		{errors.New("exit status 1: request returned 502 Bad Gateway for API route and version"), ErrorDaemonRestarting},
+		{errors.New("exit status 1: read unix @->/var/run/docker.sock: read: connection reset by peer"), ErrorDaemonRestarting},
+		{errors.New("exit status 1: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"), ErrorPermanent},
+		{errors.New("exit status 1: Error: No such object: claude-capsule"), ErrorPermanent},
+		{fmt.Errorf("wrapped: %w", errors.New("File Exists")), ErrorMountCache},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsDaemonUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{fmt.Errorf("%w. Please start Docker Desktop: exit status 1", ErrDaemonUnavailable), true},
+		{errors.New("exit status 1: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"), true},
+		{errors.New("exit status 1: read unix @->/var/run/docker.sock: read: connection reset by peer"), true},
+		{errors.New("exit status 1: Error: No such object: claude-capsule"), false},
+	}
+	for _, tt := range tests {
+		if got := IsDaemonUnavailable(tt.err); got != tt.want {
+			t.Errorf("IsDaemonUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 500 * time.Millisecond, MaxDelay: 4 * time.Second}
+	want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var slept []time.Duration
+	var retried []int
+	p := RetryPolicy{
+		Attempts:  4,
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+		Retryable: IsDaemonRestarting,
+		OnRetry:   func(_ error, attempt int) { retried = append(retried, attempt) },
+		sleep:     func(d time.Duration) { slept = append(slept, d) },
+	}
+
+	restarting := errors.New("Error response from daemon: daemon is restarting")
+	calls := 0
+	err := p.Do(context.Background(), "test", func(attempt int) error {
+		if attempt != calls {
+			t.Errorf("attempt = %d, want %d", attempt, calls)
+		}
+		calls++
+		if calls < 3 {
+			return restarting
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls, want success after 3", err, calls)
+	}
+	if len(slept) != 2 || slept[0] != time.Second || slept[1] != 2*time.Second {
+		t.Errorf("slept %v, want [1s 2s]", slept)
+	}
+	if len(retried) != 2 || retried[0] != 1 || retried[1] != 2 {
+		t.Errorf("OnRetry attempts = %v, want [1 2]", retried)
+	}
+
+	// Permanent errors are returned at once
+	calls, slept = 0, nil
+	permanent := errors.New("No such object: claude-capsule")
+	if err := p.Do(context.Background(), "test", func(int) error { calls++; return permanent }); err != permanent || calls != 1 || len(slept) != 0 {
+		t.Errorf("Do(permanent) = %v after %d calls and %d sleeps", err, calls, len(slept))
+	}
+
+	// The last error is returned once attempts run out
+	calls, slept = 0, nil
+	if err := p.Do(context.Background(), "test", func(int) error { calls++; return restarting }); err != restarting || calls != 4 || len(slept) != 3 {
+		t.Errorf("Do(always restarting) = %v after %d calls and %d sleeps", err, calls, len(slept))
+	}
+}
+
+func TestRetryPolicyDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := RetryPolicy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour, Retryable: IsDaemonRestarting}
+	restarting := errors.New("Error response from daemon: daemon is restarting")
+
+	// A canceled context stops the wait between attempts
+	calls := 0
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := p.Do(ctx, "test", func(int) error { calls++; return restarting }); err != restarting || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want the first error after 1 call", err, calls)
+	}
+
+	// ...and no further attempt starts once it is canceled
+	calls = 0
+	if err := p.Do(ctx, "test", func(int) error { calls++; return restarting }); err != restarting || calls != 1 {
+		t.Errorf("Do(canceled) = %v after %d calls, want 1 call", err, calls)
+	}
+}
@@ -0,0 +1,152 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContainerStats is a snapshot of a container's resource usage.
+type ContainerStats struct {
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryBytes   int64   `json:"memory_bytes"`
+	MemoryLimit   int64   `json:"memory_limit_bytes"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetRxBytes    int64   `json:"net_rx_bytes"`
+	NetTxBytes    int64   `json:"net_tx_bytes"`
+	BlockRead     int64   `json:"block_read_bytes"`
+	BlockWrite    int64   `json:"block_write_bytes"`
+	PIDs          int     `json:"pids"`
+}
+
+// Stats returns the container's current resource usage.
+func (m *Manager) Stats(ctx context.Context, containerName string) (ContainerStats, error) {
+	if containerName == "" {
+		containerName = DefaultContainerName
+	}
+	output, err := m.getCommandOutputWithTimeout(ctx, defaultCommandTimeout, "docker", "stats",
+		"--no-stream", "--format", "{{json .}}", containerName)
+	if err != nil {
+		return ContainerStats{}, fmt.Errorf("failed to read container stats: %w", err)
+	}
+	return parseStats(output)
+}
+
+// parseStats reads one line of 'docker stats --format {{json .}}', which
+// reports every figure as display text such as "12.5MiB / 7.6GiB".
+func parseStats(output []byte) (ContainerStats, error) {
+	var raw struct {
+		Name     string
+		CPUPerc  string
+		MemPerc  string
+		MemUsage string
+		NetIO    string
+		BlockIO  string
+		PIDs     string
+	}
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return ContainerStats{}, fmt.Errorf("failed to parse container stats: %w", err)
+	}
+
+	stats := ContainerStats{Name: raw.Name}
+	var errs []string
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+		}
+	}
+	var err error
+	stats.CPUPercent, err = parsePercent(raw.CPUPerc)
+	check("CPUPerc", err)
+	stats.MemoryPercent, err = parsePercent(raw.MemPerc)
+	check("MemPerc", err)
+	stats.MemoryBytes, stats.MemoryLimit, err = parseSizePair(raw.MemUsage)
+	check("MemUsage", err)
+	stats.NetRxBytes, stats.NetTxBytes, err = parseSizePair(raw.NetIO)
+	check("NetIO", err)
+	stats.BlockRead, stats.BlockWrite, err = parseSizePair(raw.BlockIO)
+	check("BlockIO", err)
+	if !isPlaceholder(raw.PIDs) {
+		stats.PIDs, err = strconv.Atoi(raw.PIDs)
+		check("PIDs", err)
+	}
+	if len(errs) > 0 {
+		return stats, fmt.Errorf("failed to parse container stats: %s", strings.Join(errs, "; "))
+	}
+	return stats, nil
+}
+
+func parsePercent(s string) (float64, error) {
+	if isPlaceholder(s) {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+}
+
+// isPlaceholder reports whether s is docker's "--" for a figure it doesn't
+// have, as for a container that is starting or stopping.
+func isPlaceholder(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s == "--"
+}
+
+// parseSizePair parses "used / total" pairs such as "1.2MB / 3.4GB".
+func parseSizePair(s string) (int64, int64, error) {
+	first, second, ok := strings.Cut(s, "/")
+	if !ok {
+		if isPlaceholder(s) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("invalid pair %q", s)
+	}
+	a, err := parseSize(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := parseSize(second)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// sizeUnits are the suffixes docker prints: decimal for I/O, binary for memory.
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// parseSize parses a size such as "12.5MiB" or "0B" into bytes.
+func parseSize(s string) (int64, error) {
+	if isPlaceholder(s) {
+		return 0, nil
+	}
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit, ok := sizeUnits[s[i:]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(math.Round(n * unit)), nil
+}
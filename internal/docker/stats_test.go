@@ -0,0 +1,63 @@
+package docker
+
+import "testing"
+
+func TestParseStats(t *testing.T) {
+	line := `{"BlockIO":"4.1MB / 12.3kB","CPUPerc":"3.25%","Container":"abc","ID":"abc","MemPerc":"1.50%","MemUsage":"120MiB / 7.5GiB","Name":"claude-abc","NetIO":"1.5kB / 0B","PIDs":"17"}`
+	got, err := parseStats([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ContainerStats{
+		Name:          "claude-abc",
+		CPUPercent:    3.25,
+		MemoryBytes:   120 << 20,
+		MemoryLimit:   15 << 29,
+		MemoryPercent: 1.5,
+		NetRxBytes:    1500,
+		NetTxBytes:    0,
+		BlockRead:     4100000,
+		BlockWrite:    12300,
+		PIDs:          17,
+	}
+	if got != want {
+		t.Errorf("parseStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseStatsStopped(t *testing.T) {
+	line := `{"BlockIO":"--","CPUPerc":"--","MemPerc":"--","MemUsage":"-- / --","Name":"claude-abc","NetIO":"--","PIDs":"--"}`
+	got, err := parseStats([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ContainerStats{Name: "claude-abc"}); got != want {
+		t.Errorf("parseStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0B", want: 0},
+		{in: "512B", want: 512},
+		{in: "1.5kB", want: 1500},
+		{in: " 2MiB ", want: 2 << 20},
+		{in: "1GB", want: 1e9},
+		{in: "MB", wantErr: true},
+		{in: "3XB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package docsync
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ValidGenres is the controlled vocabulary for document genres.
+var ValidGenres = []string{
+	"overview",     // _overview.md - service/component summary
+	"gotchas",      // _gotchas.md - known pitfalls
+	"architecture", // _architecture.md - design details
+	"deep-dive",    // _deep_dive.md - comprehensive analysis
+	"adr",          // ADR-NNN - architecture decision record
+	"runbook",      // operational procedure
+	"rfc",          // request for comments / proposal
+	"guide",        // how-to guide
+	"reference",    // reference documentation
+}
+
+// maxTagSuggestions limits how many similar tags are suggested for a new tag.
+const maxTagSuggestions = 5
+
+// Document is a row from the document index.
+type Document struct {
+	Path      string `json:"path"`
+	Title     string `json:"title"`
+	Genre     string `json:"genre,omitempty"`
+	Tags      string `json:"tags,omitempty"`
+	UpdatedAt string `json:"updated_at"`
+	DaysStale int    `json:"days_stale,omitempty"`
+}
+
+// Tag is a subject tag with its usage count.
+type Tag struct {
+	Name      string `json:"name"`
+	DocCount  int    `json:"doc_count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// IndexStats summarizes the document index.
+type IndexStats struct {
+	TotalDocs int `json:"total_documents"`
+	StaleDocs int `json:"stale_documents"`
+	NeverRead int `json:"never_accessed"`
+	TotalTags int `json:"total_tags"`
+	UsedTags  int `json:"tags_in_use"`
+}
+
+// RegisterResult describes a successful registration.
+type RegisterResult struct {
+	Path  string   `json:"path"`
+	Title string   `json:"title"`
+	Genre string   `json:"genre"`
+	Tags  []string `json:"tags"`
+	// Suggestions maps new tags to similar existing tags, to discourage duplicates.
+	Suggestions map[string][]string `json:"suggestions,omitempty"`
+}
+
+// IsValidGenre reports whether genre is in the controlled vocabulary.
+func IsValidGenre(genre string) bool {
+	return slices.Contains(ValidGenres, strings.ToLower(genre))
+}
+
+// Register adds or updates a document in the index. Genre (controlled) and at least
+// one tag (free-form) are required. If title is empty it is read from the first
+// "# " heading of the file.
+func (s *Store) Register(path, genre string, tags []string, title string) (*RegisterResult, error) {
+	if genre == "" {
+		return nil, fmt.Errorf("genre required. Valid genres: %s", strings.Join(ValidGenres, ", "))
+	}
+	genre = strings.ToLower(genre)
+	if !IsValidGenre(genre) {
+		return nil, fmt.Errorf("invalid genre '%s'. Valid genres: %s", genre, strings.Join(ValidGenres, ", "))
+	}
+
+	cleanTags, suggestions, err := s.ValidateTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(cleanTags) == 0 {
+		return nil, fmt.Errorf("at least one subject tag required")
+	}
+
+	if title == "" {
+		title = readTitle(filepath.Join(s.docsRoot, path))
+	}
+
+	ts := now()
+	var titleArg any
+	if title != "" {
+		titleArg = title
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", path, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO documents (path, title, genre, created_at, updated_at, accessed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			updated_at = ?,
+			title = COALESCE(?, title),
+			genre = COALESCE(?, genre)`,
+		path, titleArg, genre, ts, ts, ts, ts, titleArg, genre); err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", path, err)
+	}
+
+	var docID int64
+	if err := tx.QueryRow("SELECT id FROM documents WHERE path = ?", path).Scan(&docID); err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", path, err)
+	}
+
+	for _, tag := range cleanTags {
+		if err := attachTag(tx, docID, tag, ts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", path, err)
+	}
+
+	return &RegisterResult{Path: path, Title: title, Genre: genre, Tags: cleanTags, Suggestions: suggestions}, nil
+}
+
+// attachTag creates a tag on demand and links it to a document.
+func attachTag(tx *sql.Tx, docID int64, tag, ts string) error {
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, ?)", tag, ts); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tag, err)
+	}
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO document_tags (document_id, tag_id)
+		SELECT ?, id FROM tags WHERE name = ?`, docID, tag); err != nil {
+		return fmt.Errorf("failed to tag document with %s: %w", tag, err)
+	}
+	return nil
+}
+
+// readTitle returns the text of the first "# " heading in a file, or "" if none.
+func readTitle(fullPath string) string {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:])
+		}
+	}
+	return ""
+}
+
+// ExistingTags returns all tag names in alphabetical order.
+func (s *Store) ExistingTags() ([]string, error) {
+	rows, err := s.db.Query("SELECT name FROM tags ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// SuggestSimilarTags finds existing tags that look like duplicates of tag.
+func (s *Store) SuggestSimilarTags(tag string) ([]string, error) {
+	existing, err := s.ExistingTags()
+	if err != nil {
+		return nil, err
+	}
+	return similarTags(tag, existing), nil
+}
+
+// similarTags matches on substrings, a shared 3-character prefix, or shared words.
+func similarTags(tag string, existing []string) []string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tagWords := splitTagWords(tag)
+
+	var suggestions []string
+	for _, e := range existing {
+		if e == tag {
+			continue
+		}
+		switch {
+		case strings.Contains(e, tag) || strings.Contains(tag, e):
+			suggestions = append(suggestions, e)
+		case len(tag) >= 3 && strings.HasPrefix(e, tag[:3]):
+			suggestions = append(suggestions, e)
+		default:
+			for _, w := range splitTagWords(e) {
+				if slices.Contains(tagWords, w) {
+					suggestions = append(suggestions, e)
+					break
+				}
+			}
+		}
+		if len(suggestions) == maxTagSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
+
+func splitTagWords(tag string) []string {
+	return strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(tag))
+}
+
+// ValidateTags normalizes tags and returns suggestions for new tags that resemble
+// existing ones. Suggestions are advisory; the tags are still used as given.
+func (s *Store) ValidateTags(tags []string) ([]string, map[string][]string, error) {
+	existing, err := s.ExistingTags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var clean []string
+	suggestions := make(map[string][]string)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		clean = append(clean, tag)
+		if !slices.Contains(existing, tag) {
+			if similar := similarTags(tag, existing); len(similar) > 0 {
+				suggestions[tag] = similar
+			}
+		}
+	}
+	return clean, suggestions, nil
+}
+
+// IsIndexed reports whether a document is in the index.
+func (s *Store) IsIndexed(path string) (bool, error) {
+	var one int
+	err := s.db.QueryRow("SELECT 1 FROM documents WHERE path = ?", path).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query index: %w", err)
+	}
+	return true, nil
+}
+
+// MarkRead updates a document's accessed_at. Returns false if it is not indexed.
+func (s *Store) MarkRead(path string) (bool, error) {
+	res, err := s.db.Exec("UPDATE documents SET accessed_at = ? WHERE path = ?", now(), path)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark %s as read: %w", path, err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// MarkUpdated updates a document's updated_at and accessed_at.
+// Returns the new timestamp, or "" if the document is not indexed.
+func (s *Store) MarkUpdated(path string) (string, error) {
+	ts := now()
+	res, err := s.db.Exec("UPDATE documents SET updated_at = ?, accessed_at = ? WHERE path = ?", ts, ts, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to mark %s as updated: %w", path, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", nil
+	}
+	return ts, nil
+}
+
+// AddTags attaches tags to an indexed document. Returns false if it is not indexed.
+func (s *Store) AddTags(path string, tags []string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to tag %s: %w", path, err)
+	}
+	defer tx.Rollback()
+
+	var docID int64
+	err = tx.QueryRow("SELECT id FROM documents WHERE path = ?", path).Scan(&docID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to tag %s: %w", path, err)
+	}
+
+	ts := now()
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			if err := attachTag(tx, docID, tag, ts); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, tx.Commit()
+}
+
+// CreateTags adds standalone tags so they can be reused before any document uses them.
+func (s *Store) CreateTags(tags []string) error {
+	ts := now()
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag == "" {
+			continue
+		}
+		if _, err := s.db.Exec("INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, ?)", tag, ts); err != nil {
+			return fmt.Errorf("failed to create tag %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// SearchTags returns tag names containing partial.
+func (s *Store) SearchTags(partial string) ([]string, error) {
+	rows, err := s.db.Query("SELECT name FROM tags WHERE name LIKE ? ORDER BY name", "%"+strings.ToLower(partial)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tags: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to search tags: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// ListTags returns all tags with document counts, most used first.
+func (s *Store) ListTags() ([]Tag, error) {
+	rows, err := s.db.Query(`
+		SELECT t.name, COUNT(dt.document_id), COALESCE(t.created_at, '')
+		FROM tags t
+		LEFT JOIN document_tags dt ON t.id = dt.tag_id
+		GROUP BY t.id
+		ORDER BY COUNT(dt.document_id) DESC, t.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Name, &t.DocCount, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// GenreUsage returns the number of documents per valid genre.
+func (s *Store) GenreUsage() (map[string]int, error) {
+	rows, err := s.db.Query("SELECT genre, COUNT(*) FROM documents WHERE genre IS NOT NULL GROUP BY genre")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count genres: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int, len(ValidGenres))
+	for _, g := range ValidGenres {
+		usage[g] = 0
+	}
+	for rows.Next() {
+		var genre string
+		var count int
+		if err := rows.Scan(&genre, &count); err != nil {
+			return nil, fmt.Errorf("failed to count genres: %w", err)
+		}
+		if _, ok := usage[genre]; ok {
+			usage[genre] = count
+		}
+	}
+	return usage, rows.Err()
+}
+
+// QueryStale returns documents not updated in more than days days, oldest first.
+func (s *Store) QueryStale(days int) ([]Document, error) {
+	return s.queryDocuments(`
+		SELECT path, COALESCE(title, ''), COALESCE(genre, ''), '', updated_at,
+			CAST(julianday('now', 'localtime') - julianday(updated_at) AS INTEGER)
+		FROM documents
+		WHERE julianday('now', 'localtime') - julianday(updated_at) > ?
+		ORDER BY updated_at ASC`, days)
+}
+
+// QueryByTag returns documents carrying tag, most recently updated first.
+func (s *Store) QueryByTag(tag string) ([]Document, error) {
+	return s.queryDocuments(`
+		SELECT d.path, COALESCE(d.title, ''), COALESCE(d.genre, ''), '', d.updated_at, 0
+		FROM documents d
+		JOIN document_tags dt ON d.id = dt.document_id
+		JOIN tags t ON dt.tag_id = t.id
+		WHERE t.name = ?
+		ORDER BY d.updated_at DESC`, strings.ToLower(tag))
+}
+
+// QueryAll returns every indexed document with its tags.
+func (s *Store) QueryAll() ([]Document, error) {
+	return s.SearchDocuments(nil, "", 0)
+}
+
+// SearchDocuments returns indexed documents filtered by genre and any of tags.
+// A limit of 0 means no limit.
+func (s *Store) SearchDocuments(tags []string, genre string, limit int) ([]Document, error) {
+	query := `
+		SELECT d.path, COALESCE(d.title, ''), COALESCE(d.genre, ''),
+			COALESCE(GROUP_CONCAT(t.name, ', '), ''), d.updated_at, 0
+		FROM documents d
+		LEFT JOIN document_tags dt ON d.id = dt.document_id
+		LEFT JOIN tags t ON dt.tag_id = t.id
+		WHERE 1=1`
+	var args []any
+
+	if genre != "" {
+		query += " AND d.genre = ?"
+		args = append(args, strings.ToLower(genre))
+	}
+	if len(tags) > 0 {
+		query += " AND t.name IN (" + strings.TrimSuffix(strings.Repeat("?,", len(tags)), ",") + ")"
+		for _, tag := range tags {
+			args = append(args, strings.ToLower(tag))
+		}
+	}
+	query += " GROUP BY d.id ORDER BY d.updated_at DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return s.queryDocuments(query, args...)
+}
+
+func (s *Store) queryDocuments(query string, args ...any) ([]Document, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var d Document
+		if err := rows.Scan(&d.Path, &d.Title, &d.Genre, &d.Tags, &d.UpdatedAt, &d.DaysStale); err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// QueryUntracked returns markdown files under the docs root that are not indexed.
+func (s *Store) QueryUntracked() ([]string, error) {
+	rows, err := s.db.Query("SELECT path FROM documents")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index: %w", err)
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to query index: %w", err)
+		}
+		indexed[path] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	files, err := MarkdownFiles(s.docsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var untracked []string
+	for _, f := range files {
+		if !indexed[f] {
+			untracked = append(untracked, f)
+		}
+	}
+	sort.Strings(untracked)
+	return untracked, nil
+}
+
+// UpdatePath renames a document in the index after it has been moved.
+func (s *Store) UpdatePath(oldPath, newPath string) error {
+	if _, err := s.db.Exec("UPDATE documents SET path = ? WHERE path = ?", newPath, oldPath); err != nil {
+		return fmt.Errorf("failed to update index path: %w", err)
+	}
+	return nil
+}
+
+// IndexStatistics returns counts describing the document index.
+func (s *Store) IndexStatistics() (*IndexStats, error) {
+	var st IndexStats
+	err := s.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM documents),
+			(SELECT COUNT(*) FROM documents WHERE julianday('now', 'localtime') - julianday(updated_at) > 30),
+			(SELECT COUNT(*) FROM documents WHERE accessed_at = created_at),
+			(SELECT COUNT(*) FROM tags),
+			(SELECT COUNT(DISTINCT tag_id) FROM document_tags)`).
+		Scan(&st.TotalDocs, &st.StaleDocs, &st.NeverRead, &st.TotalTags, &st.UsedTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	return &st, nil
+}
@@ -0,0 +1,168 @@
+package docsync
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestStore returns an initialized store over an empty docs root.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	return store
+}
+
+func writeDoc(t *testing.T, docsRoot, path, content string) {
+	t.Helper()
+	full := filepath.Join(docsRoot, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimilarTags(t *testing.T) {
+	existing := []string{"auth", "authentication", "api-gateway", "database", "db-migrations", "routing"}
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"auth", []string{"authentication"}},          // substring; itself is skipped
+		{"authn", []string{"auth", "authentication"}}, // substring either way
+		{"data", []string{"database"}},                // substring
+		{"rout", []string{"routing"}},                 // prefix
+		{"gateway_config", []string{"api-gateway"}},   // shared word
+		{"kubernetes", nil},                           // nothing alike
+		{"  AUTH  ", []string{"authentication"}},      // normalized first
+		{"db", []string{"db-migrations"}},             // substring of a word
+	}
+	for _, tt := range tests {
+		if got := similarTags(tt.tag, existing); !slices.Equal(got, tt.want) {
+			t.Errorf("similarTags(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+
+	many := []string{"api1", "api2", "api3", "api4", "api5", "api6"}
+	if got := similarTags("api", many); len(got) != maxTagSuggestions {
+		t.Errorf("similarTags() returned %d suggestions, want at most %d", len(got), maxTagSuggestions)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	store := newTestStore(t)
+	writeDoc(t, store.DocsRoot(), "auth/_overview.md", "# Auth Service\n\nText.\n")
+
+	if _, err := store.Register("auth/_overview.md", "", []string{"auth"}, ""); err == nil {
+		t.Error("Register() should require a genre")
+	}
+	if _, err := store.Register("auth/_overview.md", "novel", []string{"auth"}, ""); err == nil {
+		t.Error("Register() should reject a genre outside the vocabulary")
+	}
+	if _, err := store.Register("auth/_overview.md", "overview", []string{" ", ""}, ""); err == nil {
+		t.Error("Register() should require a tag")
+	}
+
+	result, err := store.Register("auth/_overview.md", "Overview", []string{"Auth", " API "}, "")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if result.Title != "Auth Service" || result.Genre != "overview" || !slices.Equal(result.Tags, []string{"auth", "api"}) {
+		t.Errorf("Register() = %+v", result)
+	}
+
+	// Registering again updates the document rather than adding another
+	if _, err := store.Register("auth/_overview.md", "overview", []string{"authentication"}, "Auth"); err != nil {
+		t.Fatalf("Register() again error = %v", err)
+	}
+	docs, err := store.QueryAll()
+	if err != nil {
+		t.Fatalf("QueryAll() error = %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Auth" || docs[0].Tags != "api, auth, authentication" && docs[0].Tags != "auth, api, authentication" {
+		t.Errorf("QueryAll() = %+v, want one document with three tags", docs)
+	}
+
+	// A new tag close to an existing one comes with suggestions
+	writeDoc(t, store.DocsRoot(), "runbooks/deploy.md", "# Deploy\n")
+	result, err = store.Register("runbooks/deploy.md", "runbook", []string{"auth-tokens"}, "")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if suggestions := result.Suggestions["auth-tokens"]; !slices.Contains(suggestions, "auth") {
+		t.Errorf("Register() suggestions = %v, want auth suggested for auth-tokens", result.Suggestions)
+	}
+}
+
+func TestIndexQueries(t *testing.T) {
+	store := newTestStore(t)
+	root := store.DocsRoot()
+	writeDoc(t, root, "auth/_overview.md", "# Auth\n")
+	writeDoc(t, root, "adrs/ADR-001-jwt.md", "# ADR-001: JWT\n")
+	writeDoc(t, root, "notes.md", "# Notes\n")
+	writeDoc(t, root, ".hidden/secret.md", "# Hidden\n")
+
+	if _, err := store.Register("auth/_overview.md", "overview", []string{"auth"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Register("adrs/ADR-001-jwt.md", "adr", []string{"auth", "jwt"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if indexed, err := store.IsIndexed("notes.md"); err != nil || indexed {
+		t.Errorf("IsIndexed(notes.md) = %v, %v, want false", indexed, err)
+	}
+	untracked, err := store.QueryUntracked()
+	if err != nil || !slices.Equal(untracked, []string{"notes.md"}) {
+		t.Errorf("QueryUntracked() = %v, %v, want [notes.md]", untracked, err)
+	}
+
+	byTag, err := store.QueryByTag("AUTH")
+	if err != nil || len(byTag) != 2 {
+		t.Errorf("QueryByTag(AUTH) = %+v, %v, want both documents", byTag, err)
+	}
+	adrs, err := store.SearchDocuments([]string{"jwt"}, "adr", 0)
+	if err != nil || len(adrs) != 1 || adrs[0].Path != "adrs/ADR-001-jwt.md" {
+		t.Errorf("SearchDocuments(jwt, adr) = %+v, %v", adrs, err)
+	}
+	if limited, err := store.SearchDocuments(nil, "", 1); err != nil || len(limited) != 1 {
+		t.Errorf("SearchDocuments() with limit 1 = %+v, %v", limited, err)
+	}
+
+	usage, err := store.GenreUsage()
+	if err != nil || usage["overview"] != 1 || usage["adr"] != 1 || usage["runbook"] != 0 {
+		t.Errorf("GenreUsage() = %v, %v", usage, err)
+	}
+	tags, err := store.ListTags()
+	if err != nil || len(tags) != 2 || tags[0].Name != "auth" || tags[0].DocCount != 2 {
+		t.Errorf("ListTags() = %+v, %v, want auth first with 2 documents", tags, err)
+	}
+
+	if ok, err := store.AddTags("notes.md", []string{"misc"}); err != nil || ok {
+		t.Errorf("AddTags() on an unindexed document = %v, %v, want false", ok, err)
+	}
+	if err := store.UpdatePath("auth/_overview.md", "identity/_overview.md"); err != nil {
+		t.Fatal(err)
+	}
+	if indexed, _ := store.IsIndexed("identity/_overview.md"); !indexed {
+		t.Error("UpdatePath() did not move the document in the index")
+	}
+
+	stats, err := store.IndexStatistics()
+	if err != nil {
+		t.Fatalf("IndexStatistics() error = %v", err)
+	}
+	if stats.TotalDocs != 2 || stats.TotalTags != 2 || stats.UsedTags != 2 || stats.StaleDocs != 0 {
+		t.Errorf("IndexStatistics() = %+v", stats)
+	}
+}
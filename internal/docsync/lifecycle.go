@@ -0,0 +1,141 @@
+package docsync
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Reference is a line in one document that mentions another document's path.
+type Reference struct {
+	Path string
+	Line int
+}
+
+// Archive inserts a deprecation notice below the document's title.
+func Archive(docsRoot, path, reason string) error {
+	fullPath := filepath.Join(docsRoot, path)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("file not found: %s", path)
+	}
+
+	because := "."
+	if reason != "" {
+		because = ": " + reason + "."
+	}
+	notice := fmt.Sprintf("\n> [!WARNING] Deprecated\n> This document is deprecated%s\n> Archived on %s.\n",
+		because, time.Now().Format("2006-01-02"))
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			lines = append(lines[:i+1], append([]string{notice}, lines[i+1:]...)...)
+			break
+		}
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if err := os.WriteFile(fullPath, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// FindReferences returns every line in other documents that mentions path.
+func FindReferences(docsRoot, path string) ([]Reference, error) {
+	files, err := MarkdownFiles(docsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Reference
+	for _, f := range files {
+		if f == path {
+			continue
+		}
+		lines, err := readLines(filepath.Join(docsRoot, f))
+		if err != nil {
+			return nil, err
+		}
+		// Relative variants ("./x", "../x") all contain the bare path
+		for i, line := range lines {
+			if strings.Contains(line, path) {
+				refs = append(refs, Reference{Path: f, Line: i + 1})
+			}
+		}
+	}
+	return refs, nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Migrate moves a document, rewrites references to it, and updates the index.
+// Returns the documents whose references were rewritten.
+func (s *Store) Migrate(oldPath, newPath string, updateRefs bool) ([]string, error) {
+	oldFull := filepath.Join(s.docsRoot, oldPath)
+	newFull := filepath.Join(s.docsRoot, newPath)
+
+	if _, err := os.Stat(oldFull); err != nil {
+		return nil, fmt.Errorf("source not found: %s", oldPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(newFull), constants.DirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(newPath), err)
+	}
+	if err := os.Rename(oldFull, newFull); err != nil {
+		return nil, fmt.Errorf("failed to move %s: %w", oldPath, err)
+	}
+
+	var updated []string
+	if updateRefs {
+		refs, err := FindReferences(s.docsRoot, oldPath)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			if seen[ref.Path] {
+				continue
+			}
+			seen[ref.Path] = true
+
+			refFull := filepath.Join(s.docsRoot, ref.Path)
+			data, err := os.ReadFile(refFull)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", ref.Path, err)
+			}
+			content := strings.ReplaceAll(string(data), oldPath, newPath)
+			if err := os.WriteFile(refFull, []byte(content), constants.PublicFilePermissions); err != nil {
+				return nil, fmt.Errorf("failed to update %s: %w", ref.Path, err)
+			}
+			updated = append(updated, ref.Path)
+		}
+	}
+
+	if err := s.UpdatePath(oldPath, newPath); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
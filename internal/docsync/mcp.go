@@ -0,0 +1,688 @@
+package docsync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MCP protocol constants.
+const (
+	mcpProtocolVersion = "2024-11-05"
+	mcpServerName      = "doc-sync"
+	mcpServerVersion   = "1.0.0"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInternalError  = -32603
+)
+
+// Preview lengths for MCP results, which are returned in full to the model.
+const (
+	mcpSearchPreviewLength  = 500
+	mcpRecentPreviewLength  = 200
+	mcpAddPreviewLength     = 100
+	mcpContextPreviewLength = 100
+	mcpDocSearchLimit       = 50
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// mcpTool is a tool exposed over MCP. The handler receives the raw arguments object.
+type mcpTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	handler     func(s *Server, args json.RawMessage) (any, error)
+}
+
+// Server is a Model Context Protocol server exposing doc-sync tools over stdio.
+type Server struct {
+	docsRoot string
+	logger   *log.Logger
+}
+
+// NewServer creates an MCP server for the given _docs directory.
+// Logs go to stderr because stdout carries the JSON-RPC stream.
+func NewServer(docsRoot string) *Server {
+	return &Server{
+		docsRoot: docsRoot,
+		logger:   log.New(os.Stderr, "doc-sync-mcp: ", log.LstdFlags),
+	}
+}
+
+// Serve reads newline-delimited JSON-RPC requests from r and writes responses to w
+// until r is exhausted.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.logger.Println("Doc-Sync MCP Server starting...")
+
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			s.logger.Printf("Invalid JSON: %v", err)
+			if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: rpcParseError, Message: "Parse error"}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp := s.handle(req); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req rpcRequest) *rpcResponse {
+	id := req.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := &rpcResponse{JSONRPC: "2.0", ID: id}
+
+	switch req.Method {
+	case "initialize":
+		resp.Result = map[string]any{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": mcpServerName, "version": mcpServerVersion},
+		}
+	case "notifications/initialized":
+		return nil
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		resp.Result = map[string]any{"tools": mcpTools}
+	case "tools/call":
+		result, err := s.callTool(req.Params)
+		if err != nil {
+			s.logger.Printf("Error handling %s: %v", req.Method, err)
+			resp.Error = &rpcError{Code: rpcInternalError, Message: err.Error()}
+			return resp
+		}
+		resp.Result = result
+	default:
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: "Method not found: " + req.Method}
+	}
+	return resp
+}
+
+func (s *Server) callTool(params json.RawMessage) (any, error) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, fmt.Errorf("invalid tools/call params: %w", err)
+	}
+	if len(call.Arguments) == 0 {
+		call.Arguments = json.RawMessage("{}")
+	}
+
+	for _, tool := range mcpTools {
+		if tool.Name != call.Name {
+			continue
+		}
+		result, err := tool.handler(s, call.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		text, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s result: %w", call.Name, err)
+		}
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": string(text)}},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown tool: %s", call.Name)
+}
+
+// withStore opens the index for a single tool call. The database is opened per call
+// so a long-running server never holds a lock while the volume is in use elsewhere.
+func (s *Server) withStore(fn func(*Store) (any, error)) (any, error) {
+	store, err := OpenExisting(s.docsRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// decodeArgs unmarshals tool arguments into v.
+func decodeArgs(args json.RawMessage, v any) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func preview(content string, n int) string {
+	if len(content) > n {
+		return content[:n] + "..."
+	}
+	return content
+}
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "doc_search",
+		Description: "Search documentation by genre, tags, and/or text content",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"genre":{"type":"string","description":"Document genre to filter by (overview, adr, runbook, etc.)"},` +
+			`"tags":{"type":"array","items":{"type":"string"},"description":"Subject tags to filter by (matches any)"},` +
+			`"text":{"type":"string","description":"Text to search for in content"}}}`),
+		handler: (*Server).docSearch,
+	},
+	{
+		Name:        "doc_stats",
+		Description: "Get documentation index statistics",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		handler:     (*Server).docStats,
+	},
+	{
+		Name:        "doc_query_stale",
+		Description: "Find documents not updated in N days",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"days":{"type":"integer","description":"Days threshold (default 30)","default":30}}}`),
+		handler: (*Server).docQueryStale,
+	},
+	{
+		Name:        "doc_query_untracked",
+		Description: "Find documents in _docs/ not in the index",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		handler:     (*Server).docQueryUntracked,
+	},
+	{
+		Name:        "doc_validate",
+		Description: "Validate a document against conventions",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"path":{"type":"string","description":"Path to document (relative to _docs/)"}},"required":["path"]}`),
+		handler: (*Server).docValidate,
+	},
+	{
+		Name:        "doc_register",
+		Description: "Register a document in the index with genre and tags",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"path":{"type":"string","description":"Path to document (relative to _docs/)"},` +
+			`"genre":{"type":"string","description":"Document genre (overview, gotchas, adr, runbook, etc.)"},` +
+			`"tags":{"type":"array","items":{"type":"string"},"description":"Subject tags (at least one required, free-form)"}},` +
+			`"required":["path","genre","tags"]}`),
+		handler: (*Server).docRegister,
+	},
+	{
+		Name:        "doc_mark_updated",
+		Description: "Mark a document as updated (refresh timestamp)",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"path":{"type":"string","description":"Path to document (relative to _docs/)"}},"required":["path"]}`),
+		handler: (*Server).docMarkUpdated,
+	},
+	{
+		Name:        "doc_list_tags",
+		Description: "List all subject tags with usage counts",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		handler:     (*Server).docListTags,
+	},
+	{
+		Name:        "doc_list_genres",
+		Description: "List valid document genres with usage counts",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		handler:     (*Server).docListGenres,
+	},
+	{
+		Name:        "doc_suggest_tags",
+		Description: "Find existing tags matching a partial string (for autocomplete)",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"partial":{"type":"string","description":"Partial tag name to search for"}},"required":["partial"]}`),
+		handler: (*Server).docSuggestTags,
+	},
+	{
+		Name: "memory_search",
+		Description: "Search memory chunks using full-text search. " +
+			"Use this to recall past discussions, decisions, and documentation context.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"query":{"type":"string","description":"Search query (words are OR'd together for broad matching)"},` +
+			`"tags":{"type":"array","items":{"type":"string"},"description":"Optional tags to filter results"},` +
+			`"limit":{"type":"integer","description":"Maximum results (default 10)","default":10}},"required":["query"]}`),
+		handler: (*Server).memorySearch,
+	},
+	{
+		Name: "memory_add",
+		Description: "Add a note or memory to the system. " +
+			"Use this to capture important discussions, decisions, or context for future retrieval.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"content":{"type":"string","description":"The text content to store"},` +
+			`"tags":{"type":"array","items":{"type":"string"},"description":"Subject tags (at least one required)"},` +
+			`"source":{"type":"string","description":"Source identifier (defaults to session:YYYY-MM-DD)"},` +
+			`"chunk_type":{"type":"string","enum":["note","decision","session"],"description":"Type of memory (default: note)"}},` +
+			`"required":["content","tags"]}`),
+		handler: (*Server).memoryAdd,
+	},
+	{
+		Name:        "memory_stats",
+		Description: "Get memory system statistics (total chunks, sources, types)",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+		handler:     (*Server).memoryStats,
+	},
+	{
+		Name:        "memory_recent",
+		Description: "Get recently added memories",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"days":{"type":"integer","description":"Number of days to look back (default: 7)"},` +
+			`"limit":{"type":"integer","description":"Maximum number of results (default: 10)"}}}`),
+		handler: (*Server).memoryRecent,
+	},
+	{
+		Name: "memory_refresh",
+		Description: "Refresh doc chunks from source files. " +
+			"Deletes stale chunks and re-ingests current content. Preserves notes/decisions.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"path":{"type":"string","description":"Path to refresh (relative to _docs/). Omit for all docs."}}}`),
+		handler: (*Server).memoryRefresh,
+	},
+}
+
+func (s *Server) docSearch(args json.RawMessage) (any, error) {
+	var in struct {
+		Tags  []string `json:"tags"`
+		Genre string   `json:"genre"`
+		Text  string   `json:"text"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		var results []any
+
+		if len(in.Tags) > 0 || in.Genre != "" || in.Text == "" {
+			limit := 0
+			if len(in.Tags) == 0 && in.Genre == "" {
+				limit = mcpDocSearchLimit
+			}
+			docs, err := store.SearchDocuments(in.Tags, in.Genre, limit)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range docs {
+				results = append(results, d)
+			}
+		}
+
+		if in.Text != "" {
+			matches, err := grepDocs(s.docsRoot, in.Text)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, matches...)
+		}
+
+		return map[string]any{"count": len(results), "documents": results}, nil
+	})
+}
+
+// grepDocs returns the first line in each document that contains text, case-insensitively.
+func grepDocs(docsRoot, text string) ([]any, error) {
+	files, err := MarkdownFiles(docsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	needle := strings.ToLower(text)
+	var matches []any
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(docsRoot, f))
+		if err != nil {
+			continue
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if strings.Contains(strings.ToLower(line), needle) {
+				matches = append(matches, map[string]any{
+					"path":    f,
+					"line":    i + 1,
+					"context": preview(strings.TrimSpace(line), mcpContextPreviewLength),
+				})
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (s *Server) docStats(json.RawMessage) (any, error) {
+	return s.withStore(func(store *Store) (any, error) {
+		stats, err := store.IndexStatistics()
+		if err != nil {
+			return nil, err
+		}
+		tags, err := store.ListTags()
+		if err != nil {
+			return nil, err
+		}
+		breakdown := make(map[string]int, len(tags))
+		for _, t := range tags {
+			breakdown[t.Name] = t.DocCount
+		}
+		return map[string]any{
+			"total_documents": stats.TotalDocs,
+			"stale_documents": stats.StaleDocs,
+			"never_accessed":  stats.NeverRead,
+			"total_tags":      stats.TotalTags,
+			"tags_in_use":     stats.UsedTags,
+			"tag_breakdown":   breakdown,
+		}, nil
+	})
+}
+
+func (s *Server) docQueryStale(args json.RawMessage) (any, error) {
+	in := struct {
+		Days int `json:"days"`
+	}{Days: 30}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		docs, err := store.QueryStale(in.Days)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"threshold_days": in.Days, "count": len(docs), "documents": docs}, nil
+	})
+}
+
+func (s *Server) docQueryUntracked(json.RawMessage) (any, error) {
+	return s.withStore(func(store *Store) (any, error) {
+		untracked, err := store.QueryUntracked()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"count": len(untracked), "documents": untracked}, nil
+	})
+}
+
+func (s *Server) docValidate(args json.RawMessage) (any, error) {
+	var in struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(s.docsRoot, in.Path)); err != nil {
+		return map[string]any{"valid": false, "error": "File not found: " + in.Path}, nil
+	}
+	result := Validate(s.docsRoot, in.Path)
+	return map[string]any{
+		"path":          result.Path,
+		"doc_type":      result.DocType,
+		"valid":         result.IsValid(),
+		"error_count":   result.ErrorCount(),
+		"warning_count": result.WarningCount(),
+		"issues":        result.Issues,
+	}, nil
+}
+
+func (s *Server) docRegister(args json.RawMessage) (any, error) {
+	var in struct {
+		Path  string   `json:"path"`
+		Genre string   `json:"genre"`
+		Tags  []string `json:"tags"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	if in.Genre == "" {
+		return map[string]any{"success": false, "error": "Genre is required", "valid_genres": ValidGenres}, nil
+	}
+	if !IsValidGenre(in.Genre) {
+		return map[string]any{"success": false, "error": "Invalid genre: " + in.Genre, "valid_genres": ValidGenres}, nil
+	}
+	if len(in.Tags) == 0 {
+		return map[string]any{"success": false, "error": "At least one tag is required"}, nil
+	}
+	if _, err := os.Stat(filepath.Join(s.docsRoot, in.Path)); err != nil {
+		return map[string]any{"success": false, "error": "File not found: " + in.Path}, nil
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		result, err := store.Register(in.Path, in.Genre, in.Tags, "")
+		if err != nil {
+			return map[string]any{"success": false, "error": err.Error()}, nil
+		}
+		return map[string]any{
+			"success": true, "path": result.Path, "title": result.Title,
+			"genre": result.Genre, "tags": result.Tags,
+		}, nil
+	})
+}
+
+func (s *Server) docMarkUpdated(args json.RawMessage) (any, error) {
+	var in struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		ts, err := store.MarkUpdated(in.Path)
+		if err != nil {
+			return nil, err
+		}
+		if ts == "" {
+			return map[string]any{"success": false, "error": "Document not in index: " + in.Path}, nil
+		}
+		return map[string]any{"success": true, "path": in.Path, "updated_at": ts}, nil
+	})
+}
+
+func (s *Server) docListTags(json.RawMessage) (any, error) {
+	return s.withStore(func(store *Store) (any, error) {
+		tags, err := store.ListTags()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"count": len(tags), "tags": tags}, nil
+	})
+}
+
+func (s *Server) docListGenres(json.RawMessage) (any, error) {
+	return s.withStore(func(store *Store) (any, error) {
+		usage, err := store.GenreUsage()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"valid_genres": ValidGenres, "usage": usage}, nil
+	})
+}
+
+func (s *Server) docSuggestTags(args json.RawMessage) (any, error) {
+	var in struct {
+		Partial string `json:"partial"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		matches, err := store.SearchTags(in.Partial)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			matches = []string{}
+		}
+		return map[string]any{"query": in.Partial, "matches": matches}, nil
+	})
+}
+
+func (s *Server) memorySearch(args json.RawMessage) (any, error) {
+	in := struct {
+		Query string   `json:"query"`
+		Tags  []string `json:"tags"`
+		Limit int      `json:"limit"`
+	}{Limit: 10}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		results, err := store.Search(in.Query, in.Tags, in.Limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range results {
+			results[i].Content = preview(results[i].Content, mcpSearchPreviewLength)
+		}
+		if results == nil {
+			results = []Result{}
+		}
+		return map[string]any{"query": in.Query, "count": len(results), "results": results}, nil
+	})
+}
+
+func (s *Server) memoryAdd(args json.RawMessage) (any, error) {
+	in := struct {
+		Content   string   `json:"content"`
+		Tags      []string `json:"tags"`
+		Source    string   `json:"source"`
+		ChunkType string   `json:"chunk_type"`
+	}{ChunkType: "note"}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(in.Content) == "" {
+		return map[string]any{"success": false, "error": "Content cannot be empty"}, nil
+	}
+	if len(in.Tags) == 0 {
+		return map[string]any{"success": false, "error": "At least one tag required"}, nil
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		added, err := store.Add(in.Content, in.Tags, in.Source, in.ChunkType)
+		if err != nil {
+			return nil, err
+		}
+		if !added {
+			return map[string]any{"success": false, "error": "Duplicate content (already exists)"}, nil
+		}
+		source := in.Source
+		if source == "" {
+			source = "session:" + time.Now().Format("2006-01-02")
+		}
+		return map[string]any{
+			"success":         true,
+			"source":          source,
+			"type":            in.ChunkType,
+			"tags":            in.Tags,
+			"content_preview": preview(in.Content, mcpAddPreviewLength),
+		}, nil
+	})
+}
+
+func (s *Server) memoryStats(json.RawMessage) (any, error) {
+	return s.withStore(func(store *Store) (any, error) {
+		stats, err := store.MemoryStatistics()
+		if err != nil {
+			return nil, err
+		}
+		top := make(map[string]int, len(stats.TopSources))
+		for _, sc := range stats.TopSources {
+			top[sc.Source] = sc.Count
+		}
+		return map[string]any{
+			"total_chunks":   stats.TotalChunks,
+			"unique_sources": stats.UniqueSources,
+			"by_type":        stats.ByType,
+			"top_sources":    top,
+		}, nil
+	})
+}
+
+func (s *Server) memoryRecent(args json.RawMessage) (any, error) {
+	in := struct {
+		Days  int `json:"days"`
+		Limit int `json:"limit"`
+	}{Days: 7, Limit: 10}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		results, err := store.Recent(in.Days, in.Limit)
+		if err != nil {
+			return nil, err
+		}
+		recent := make([]map[string]any, 0, len(results))
+		for _, r := range results {
+			date := r.CreatedAt
+			if len(date) > 10 {
+				date = date[:10]
+			}
+			recent = append(recent, map[string]any{
+				"date":     date,
+				"type":     r.Type,
+				"tags":     r.Tags,
+				"source":   r.Source,
+				"preview":  preview(strings.ReplaceAll(r.Content, "\n", " "), mcpRecentPreviewLength),
+				"age_days": r.AgeDays,
+				"is_stale": r.IsStale,
+			})
+		}
+		return recent, nil
+	})
+}
+
+func (s *Server) memoryRefresh(args json.RawMessage) (any, error) {
+	var in struct {
+		Path string `json:"path"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+
+	return s.withStore(func(store *Store) (any, error) {
+		return store.Refresh(in.Path)
+	})
+}
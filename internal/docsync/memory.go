@@ -0,0 +1,470 @@
+package docsync
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StaleThresholdDays is the age after which a memory is flagged as stale.
+const StaleThresholdDays = 90
+
+// minChunkLength skips headings and fragments too small to be useful on their own.
+const minChunkLength = 50
+
+// ResultPreviewLength limits how much of each result is printed by WriteResults.
+const ResultPreviewLength = 300
+
+// ChunkTypeDoc marks chunks ingested from documents. Other types (note, decision, ...)
+// are added directly and survive a refresh.
+const ChunkTypeDoc = "doc"
+
+// pathTagHints are inferred as tags when a document is ingested without explicit tags.
+var pathTagHints = []string{"infra", "agents", "apps", "shared", "pipelines", "ecs", "lambda"}
+
+// Result is a single memory chunk returned from a search or recent query.
+type Result struct {
+	Content   string `json:"content"`
+	Source    string `json:"source"`
+	Section   string `json:"section"`
+	Tags      string `json:"tags"`
+	Type      string `json:"type"`
+	CreatedAt string `json:"created_at"`
+	AgeDays   int    `json:"age_days"`
+	IsStale   bool   `json:"is_stale"`
+}
+
+// SourceCount is the number of chunks from a single source.
+type SourceCount struct {
+	Source string `json:"source"`
+	Count  int    `json:"count"`
+}
+
+// MemoryStats summarizes the memory store.
+type MemoryStats struct {
+	TotalChunks   int            `json:"total_chunks"`
+	ByType        map[string]int `json:"by_type"`
+	TopSources    []SourceCount  `json:"top_sources"`
+	UniqueSources int            `json:"unique_sources"`
+}
+
+// RefreshResult reports what a refresh changed.
+type RefreshResult struct {
+	Deleted  int `json:"deleted"`
+	Ingested int `json:"ingested"`
+	Sources  int `json:"sources"`
+}
+
+// chunk is a section of a document prepared for ingestion.
+type chunk struct {
+	content string
+	section string
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkDocument splits markdown by headings. Each chunk's section is the heading
+// path leading to it, e.g. "Overview > Setup".
+func chunkDocument(content string) []chunk {
+	var chunks []chunk
+	var current []string
+	var headings []string
+
+	flush := func(fallback string) {
+		text := strings.TrimSpace(strings.Join(current, "\n"))
+		if len(text) > minChunkLength {
+			section := fallback
+			if len(headings) > 0 {
+				section = strings.Join(headings, " > ")
+			}
+			chunks = append(chunks, chunk{content: text, section: section})
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			if len(current) > 0 {
+				flush("Introduction")
+			}
+
+			trimmed := strings.TrimLeft(line, "#")
+			level := len(line) - len(trimmed)
+			if level-1 < len(headings) {
+				headings = headings[:level-1]
+			}
+			headings = append(headings, strings.TrimSpace(trimmed))
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		flush("Content")
+	}
+
+	return chunks
+}
+
+// inferTags derives tags from well-known path components.
+func inferTags(path string) []string {
+	var tags []string
+	lower := strings.ToLower(path)
+	for _, hint := range pathTagHints {
+		if strings.Contains(lower, hint) {
+			tags = append(tags, hint)
+		}
+	}
+	if len(tags) == 0 {
+		tags = []string{"general"}
+	}
+	return tags
+}
+
+// insertChunk stores a chunk unless identical content already exists.
+// Returns false for duplicates.
+func insertChunk(tx *sql.Tx, content, source, section string, tags []string, chunkType, ts string) (bool, error) {
+	contentHash := hashContent(content)
+
+	var existing int64
+	err := tx.QueryRow("SELECT id FROM chunk_meta WHERE content_hash = ?", contentHash).Scan(&existing)
+	if err == nil {
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to check for duplicate memory: %w", err)
+	}
+
+	// Tags are comma-separated in metadata and space-separated for FTS
+	res, err := tx.Exec(`
+		INSERT INTO chunk_meta (source, section, tags, chunk_type, created_at, content_hash)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		source, section, strings.Join(tags, ","), chunkType, ts, contentHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to store memory: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return false, fmt.Errorf("failed to store memory: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO chunks_fts (rowid, content, source, section, tags)
+		VALUES (?, ?, ?, ?, ?)`,
+		id, content, source, section, strings.Join(tags, " ")); err != nil {
+		return false, fmt.Errorf("failed to index memory: %w", err)
+	}
+	return true, nil
+}
+
+// Ingest chunks a document under the docs root into memory and returns the number
+// of new chunks. Tags are inferred from the path when none are given.
+func (s *Store) Ingest(path string, tags []string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(s.docsRoot, path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	chunks := chunkDocument(string(data))
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+	if len(tags) == 0 {
+		tags = inferTags(path)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to ingest %s: %w", path, err)
+	}
+	defer tx.Rollback()
+
+	ts := now()
+	inserted := 0
+	for _, c := range chunks {
+		ok, err := insertChunk(tx, c.content, path, c.section, tags, ChunkTypeDoc, ts)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to ingest %s: %w", path, err)
+	}
+	return inserted, nil
+}
+
+// Add stores a note directly. Source defaults to today's session.
+// Returns false if identical content is already stored.
+func (s *Store) Add(content string, tags []string, source, chunkType string) (bool, error) {
+	if strings.TrimSpace(content) == "" {
+		return false, fmt.Errorf("content cannot be empty")
+	}
+	if len(tags) == 0 {
+		return false, fmt.Errorf("at least one tag required")
+	}
+	if source == "" {
+		source = "session:" + time.Now().Format("2006-01-02")
+	}
+	if chunkType == "" {
+		chunkType = "note"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to add memory: %w", err)
+	}
+	defer tx.Rollback()
+
+	added, err := insertChunk(tx, content, source, "Note", tags, chunkType, now())
+	if err != nil || !added {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Search runs a full-text query against memory, optionally widened by tags.
+// Words are OR'd together for broad matching. If FTS rejects the query, a
+// substring match is used instead.
+func (s *Store) Search(query string, tags []string, limit int) ([]Result, error) {
+	rows, err := s.db.Query(`
+		SELECT c.content, c.source, c.section, c.tags, m.chunk_type, m.created_at
+		FROM chunks_fts c
+		JOIN chunk_meta m ON c.rowid = m.id
+		WHERE chunks_fts MATCH ?
+		ORDER BY bm25(chunks_fts)
+		LIMIT ?`, buildFTSQuery(query, tags), limit)
+	if err != nil {
+		// FTS syntax errors (e.g. stray operators) fall back to substring matching
+		pattern := "%" + query + "%"
+		rows, err = s.db.Query(`
+			SELECT c.content, c.source, c.section, c.tags, m.chunk_type, m.created_at
+			FROM chunks_fts c
+			JOIN chunk_meta m ON c.rowid = m.id
+			WHERE c.content LIKE ? OR c.tags LIKE ?
+			ORDER BY m.created_at DESC
+			LIMIT ?`, pattern, pattern, limit)
+		if err != nil {
+			return nil, fmt.Errorf("memory search failed: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	return scanResults(rows, time.Now())
+}
+
+// buildFTSQuery converts a free-text query and optional tags into an FTS5 MATCH expression.
+func buildFTSQuery(query string, tags []string) string {
+	var words []string
+	for _, w := range strings.Fields(query) {
+		words = append(words, strings.ReplaceAll(w, `"`, `""`))
+	}
+
+	ftsQuery := query
+	if len(words) > 0 {
+		ftsQuery = strings.Join(words, " OR ")
+	}
+
+	if len(tags) > 0 {
+		ftsQuery = fmt.Sprintf("(%s) OR tags:(%s)", ftsQuery, strings.Join(tags, " OR "))
+	}
+
+	return ftsQuery
+}
+
+// Recent returns memories added within the last days days, newest first.
+func (s *Store) Recent(days, limit int) ([]Result, error) {
+	rows, err := s.db.Query(`
+		SELECT c.content, c.source, c.section, c.tags, m.chunk_type, m.created_at
+		FROM chunks_fts c
+		JOIN chunk_meta m ON c.rowid = m.id
+		WHERE date(m.created_at) >= date('now', 'localtime', ?)
+		ORDER BY m.created_at DESC
+		LIMIT ?`, fmt.Sprintf("-%d days", days), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent memories: %w", err)
+	}
+	defer rows.Close()
+
+	return scanResults(rows, time.Now())
+}
+
+// scanResults reads result rows and annotates each with its age relative to now.
+func scanResults(rows *sql.Rows, now time.Time) ([]Result, error) {
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var section, tags, chunkType sql.NullString
+		if err := rows.Scan(&r.Content, &r.Source, &section, &tags, &chunkType, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read memory result: %w", err)
+		}
+		r.Section = section.String
+		r.Tags = tags.String
+		r.Type = chunkType.String
+		r.AgeDays = ageDays(r.CreatedAt, now)
+		r.IsStale = r.AgeDays > StaleThresholdDays
+
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory results: %w", err)
+	}
+	return results, nil
+}
+
+// MemoryStatistics returns chunk counts by type and source.
+func (s *Store) MemoryStatistics() (*MemoryStats, error) {
+	stats := &MemoryStats{ByType: make(map[string]int)}
+
+	if err := s.db.QueryRow("SELECT COUNT(*), COUNT(DISTINCT source) FROM chunk_meta").
+		Scan(&stats.TotalChunks, &stats.UniqueSources); err != nil {
+		return nil, fmt.Errorf("failed to read memory statistics: %w", err)
+	}
+
+	rows, err := s.db.Query("SELECT COALESCE(chunk_type, ''), COUNT(*) FROM chunk_meta GROUP BY chunk_type")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory statistics: %w", err)
+	}
+	for rows.Next() {
+		var chunkType string
+		var count int
+		if err := rows.Scan(&chunkType, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read memory statistics: %w", err)
+		}
+		stats.ByType[chunkType] = count
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT source, COUNT(*) AS count FROM chunk_meta
+		GROUP BY source ORDER BY count DESC LIMIT 10`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory statistics: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sc SourceCount
+		if err := rows.Scan(&sc.Source, &sc.Count); err != nil {
+			return nil, fmt.Errorf("failed to read memory statistics: %w", err)
+		}
+		stats.TopSources = append(stats.TopSources, sc)
+	}
+
+	return stats, rows.Err()
+}
+
+// Refresh re-ingests document chunks from their source files. Notes and decisions
+// are preserved. If path is empty, every previously ingested document is refreshed.
+func (s *Store) Refresh(path string) (*RefreshResult, error) {
+	sources := []string{path}
+	if path == "" {
+		var err error
+		sources, err = s.docSources()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RefreshResult{Sources: len(sources)}
+	for _, source := range sources {
+		deleted, err := s.deleteDocChunks(source)
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted += deleted
+
+		if _, err := os.Stat(filepath.Join(s.docsRoot, source)); err != nil {
+			continue
+		}
+		ingested, err := s.Ingest(source, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.Ingested += ingested
+	}
+
+	return result, nil
+}
+
+func (s *Store) docSources() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT source FROM chunk_meta WHERE chunk_type = ?", ChunkTypeDoc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memory sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []string
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, fmt.Errorf("failed to list memory sources: %w", err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func (s *Store) deleteDocChunks(source string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear chunks for %s: %w", source, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM chunks_fts WHERE rowid IN
+			(SELECT id FROM chunk_meta WHERE source = ? AND chunk_type = ?)`, source, ChunkTypeDoc); err != nil {
+		return 0, fmt.Errorf("failed to clear chunks for %s: %w", source, err)
+	}
+	res, err := tx.Exec("DELETE FROM chunk_meta WHERE source = ? AND chunk_type = ?", source, ChunkTypeDoc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear chunks for %s: %w", source, err)
+	}
+	n, _ := res.RowsAffected()
+
+	return int(n), tx.Commit()
+}
+
+// WriteResults prints search results in the human-readable format shared by doctool
+// inside the container and capsule on the host.
+func WriteResults(w io.Writer, query string, results []Result) {
+	if len(results) == 0 {
+		fmt.Fprintf(w, "No results for '%s'\n", query)
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d results for '%s':\n\n", len(results), query)
+	for i, r := range results {
+		staleWarning := ""
+		if r.IsStale {
+			staleWarning = fmt.Sprintf(" [STALE: %d days]", r.AgeDays)
+		}
+		fmt.Fprintf(w, "─── Result %d%s ───\n", i+1, staleWarning)
+
+		ageStr := fmt.Sprintf("%d days ago", r.AgeDays)
+		if r.AgeDays == 1 {
+			ageStr = "1 day ago"
+		}
+		fmt.Fprintf(w, "Source: %s (%s)\n", r.Source, ageStr)
+		fmt.Fprintf(w, "Section: %s\n", r.Section)
+		fmt.Fprintf(w, "Tags: %s\n", r.Tags)
+		fmt.Fprintf(w, "Type: %s\n", r.Type)
+
+		content := r.Content
+		if len(content) > ResultPreviewLength {
+			content = content[:ResultPreviewLength] + "..."
+		}
+		fmt.Fprintf(w, "\n%s\n\n", content)
+	}
+}
@@ -0,0 +1,127 @@
+package docsync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		tags  []string
+		want  string
+	}{
+		{"auth", nil, "auth"},
+		{"ecs routing", nil, "ecs OR routing"},
+		{`say "hi"`, nil, `say OR ""hi""`},
+		{"auth", []string{"api", "jwt"}, "(auth) OR tags:(api OR jwt)"},
+	}
+
+	for _, tt := range tests {
+		got := buildFTSQuery(tt.query, tt.tags)
+		if got != tt.want {
+			t.Errorf("buildFTSQuery(%q, %v) = %q, want %q", tt.query, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestChunkDocument(t *testing.T) {
+	content := `# Service
+
+Short.
+
+## Setup
+
+Install the dependencies and run the bootstrap script before anything else.
+
+### Database
+
+Create the schema with the migration tool, then seed the reference tables.
+`
+	chunks := chunkDocument(content)
+	if len(chunks) != 2 {
+		t.Fatalf("chunkDocument() returned %d chunks, want 2", len(chunks))
+	}
+	if chunks[0].section != "Service > Setup" {
+		t.Errorf("chunks[0].section = %q, want %q", chunks[0].section, "Service > Setup")
+	}
+	if chunks[1].section != "Service > Setup > Database" {
+		t.Errorf("chunks[1].section = %q, want %q", chunks[1].section, "Service > Setup > Database")
+	}
+}
+
+func TestStore_Search(t *testing.T) {
+	docsRoot := t.TempDir()
+
+	store, err := Open(docsRoot)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := store.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if _, err := store.Add("Chose JWT over sessions for the API", []string{"auth", "api"}, "", "decision"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	// Backdate the memory so it is reported as stale
+	old := time.Now().AddDate(0, 0, -120).Format(timestampLayout)
+	if _, err := store.db.Exec("UPDATE chunk_meta SET created_at = ?", old); err != nil {
+		t.Fatalf("failed to backdate memory: %v", err)
+	}
+	store.Close()
+
+	store, err = OpenReadOnly(docsRoot)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	defer store.Close()
+
+	results, err := store.Search("JWT", nil, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if results[0].Type != "decision" {
+		t.Errorf("Search() type = %v, want decision", results[0].Type)
+	}
+	if !results[0].IsStale {
+		t.Errorf("Search() IsStale = false for %d day old memory", results[0].AgeDays)
+	}
+
+	results, err = store.Search("kubernetes", nil, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Search() returned %d results for unmatched query, want 0", len(results))
+	}
+}
+
+func TestStore_AddDuplicate(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+	if err := store.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	added, err := store.Add("Use ripgrep for search", []string{"tools"}, "", "")
+	if err != nil || !added {
+		t.Fatalf("Add() = %v, %v; want true, nil", added, err)
+	}
+	added, err = store.Add("Use ripgrep for search", []string{"tools"}, "", "")
+	if err != nil || added {
+		t.Errorf("Add() duplicate = %v, %v; want false, nil", added, err)
+	}
+}
+
+func TestOpenReadOnly_MissingDatabase(t *testing.T) {
+	_, err := OpenReadOnly(t.TempDir())
+	if err == nil {
+		t.Error("OpenReadOnly() expected error for missing database, got nil")
+	}
+}
@@ -0,0 +1,160 @@
+package docsync
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+//go:embed schema.sql
+var Schema string
+
+// DBFileName is the name of the index database inside each repo's _docs directory.
+const DBFileName = ".doc-index.db"
+
+// DefaultDocsRoot is the _docs directory as seen from inside the container.
+const DefaultDocsRoot = "/workspace/_docs"
+
+// timestampLayout matches Python's datetime.isoformat(), which older volumes were
+// populated with. New timestamps are written in the same local, zone-less format so
+// both generations of data sort and compare consistently.
+const timestampLayout = "2006-01-02T15:04:05.999999"
+
+// Store wraps the doc-sync SQLite database for a single _docs directory.
+type Store struct {
+	db       *sql.DB
+	docsRoot string
+}
+
+// DBPath returns the database path for a _docs directory.
+func DBPath(docsRoot string) string {
+	return filepath.Join(docsRoot, DBFileName)
+}
+
+// RepoDocsRoot returns the host path of a repo's _docs directory on a mounted volume.
+// Inside the container /workspace/_docs is a symlink to /claude-env/repos/<repoID>.
+func RepoDocsRoot(mountPoint, repoID string) string {
+	return filepath.Join(mountPoint, "repos", repoID)
+}
+
+// Open opens (creating if necessary) the database for docsRoot.
+func Open(docsRoot string) (*Store, error) {
+	return open(docsRoot, "")
+}
+
+// OpenExisting opens the database for docsRoot, failing if it has not been initialized.
+func OpenExisting(docsRoot string) (*Store, error) {
+	if _, err := os.Stat(DBPath(docsRoot)); err != nil {
+		return nil, fmt.Errorf("database not found: %s. Run 'doctool index init' first", DBPath(docsRoot))
+	}
+	return open(docsRoot, "")
+}
+
+// OpenReadOnly opens an existing database without write access.
+// Used by the host-side CLI so a query can never modify the volume.
+func OpenReadOnly(docsRoot string) (*Store, error) {
+	if _, err := os.Stat(DBPath(docsRoot)); err != nil {
+		return nil, fmt.Errorf("memory database not found at %s: %w", DBPath(docsRoot), err)
+	}
+	return open(docsRoot, "?mode=ro")
+}
+
+func open(docsRoot, query string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+DBPath(docsRoot)+query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// A single connection keeps PRAGMAs and transactions on the same handle
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &Store{db: db, docsRoot: docsRoot}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DocsRoot returns the _docs directory this store indexes.
+func (s *Store) DocsRoot() string {
+	return s.docsRoot
+}
+
+// Init creates all tables and indexes. Safe to run repeatedly.
+func (s *Store) Init() error {
+	if _, err := s.db.Exec(Schema); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return nil
+}
+
+// now returns the current time formatted for storage.
+func now() string {
+	return time.Now().Format(timestampLayout)
+}
+
+// parseTimestamp parses a stored timestamp, returning ok=false if it is malformed.
+func parseTimestamp(value string) (time.Time, bool) {
+	t, err := time.ParseInLocation(timestampLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ageDays returns the number of whole days between a stored timestamp and now.
+func ageDays(value string, now time.Time) int {
+	created, ok := parseTimestamp(value)
+	if !ok {
+		return 0
+	}
+	return int(now.Sub(created).Hours() / 24)
+}
+
+// MarkdownFiles returns all .md files under docsRoot relative to it, skipping hidden paths.
+func MarkdownFiles(docsRoot string) ([]string, error) {
+	// _docs is usually a symlink into the volume; WalkDir does not follow a symlinked root
+	root, err := filepath.EvalSymlinks(docsRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to resolve %s: %w", docsRoot, err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", docsRoot, err)
+	}
+	return files, nil
+}
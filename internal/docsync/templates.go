@@ -0,0 +1,99 @@
+package docsync
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.md
+var templateFS embed.FS
+
+// TemplateTypes lists the document types that can be created from a template.
+var TemplateTypes = []string{"overview", "gotchas", "architecture", "deep-dive", "adr", "runbook"}
+
+// shadowTypes are per-component documents kept under shadow/<name>/.
+var shadowTypes = []string{"overview", "gotchas", "architecture", "deep-dive"}
+
+var adrNumberPattern = regexp.MustCompile(`^ADR-(\d+)`)
+
+// NextADRNumber returns the number for the next ADR under docsRoot/adrs.
+func NextADRNumber(docsRoot string) int {
+	entries, err := os.ReadDir(filepath.Join(docsRoot, "adrs"))
+	if err != nil {
+		return 1
+	}
+
+	highest := 0
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		if m := adrNumberPattern.FindStringSubmatch(e.Name()); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > highest {
+				highest = n
+			}
+		}
+	}
+	return highest + 1
+}
+
+// RenderTemplate fills the template for docType and returns the path (relative to
+// docsRoot) where it belongs along with its content.
+func RenderTemplate(docsRoot, docType, name, description string) (string, string, error) {
+	if !slices.Contains(TemplateTypes, docType) {
+		return "", "", fmt.Errorf("unknown doc type: %s. Valid: %s", docType, strings.Join(TemplateTypes, ", "))
+	}
+
+	tmpl, err := templateFS.ReadFile("templates/" + docType + ".md")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s template: %w", docType, err)
+	}
+
+	slug := strings.ReplaceAll(strings.ToLower(name), " ", "-")
+	title := name
+	number := ""
+
+	var path string
+	switch {
+	case docType == "adr":
+		n := NextADRNumber(docsRoot)
+		number = fmt.Sprintf("%03d", n)
+		path = fmt.Sprintf("adrs/ADR-%s-%s.md", number, slug)
+	case docType == "runbook":
+		path = "runbooks/" + slug + ".md"
+	case slices.Contains(shadowTypes, docType):
+		path = fmt.Sprintf("shadow/%s/_%s.md", name, strings.ReplaceAll(docType, "-", "_"))
+		title = titleCase(strings.ReplaceAll(filepath.Base(name), "_", " "))
+	}
+
+	if description == "" {
+		description = "(Add description)"
+	}
+
+	content := strings.NewReplacer(
+		"{title}", title,
+		"{name}", filepath.Base(name),
+		"{path}", name,
+		"{description}", description,
+		"{date}", time.Now().Format("2006-01-02"),
+		"{number}", number,
+	).Replace(string(tmpl))
+
+	return path, content, nil
+}
+
+// titleCase capitalizes the first letter of each word and lowercases the rest.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,254 @@
+package docsync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// maxLineLength is the longest prose line accepted without an info-level issue.
+const maxLineLength = 120
+
+// RequiredSections lists the "## " sections expected for each document type.
+var RequiredSections = map[string][]string{
+	"overview":     {"Quick Operations", "Purpose", "Key Files", "Architecture", "Dependencies"},
+	"gotchas":      {}, // Just needs numbered items
+	"architecture": {"System Diagram", "Components"},
+	"deep-dive":    {"Overview", "Architecture"},
+	"adr":          {"Status", "Context", "Decision", "Consequences"},
+	"runbook":      {"Risk Level", "Prerequisites", "Procedure", "Rollback", "Verification"},
+}
+
+var (
+	headingPattern      = regexp.MustCompile(`(?m)^##\s+(.+)$`)
+	gotchaNumberPattern = regexp.MustCompile(`(?m)^###\s+\d+\.`)
+	adrStatusPattern    = regexp.MustCompile(`\*\*Status:\*\*`)
+	runbookRiskPattern  = regexp.MustCompile(`(?i)\*\*Risk Level:\*\*`)
+	linkPattern         = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+)
+
+// Issue is a single validation problem.
+type Issue struct {
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// ValidationResult collects the issues found in one document.
+type ValidationResult struct {
+	Path    string  `json:"path"`
+	DocType string  `json:"doc_type"`
+	Issues  []Issue `json:"issues"`
+}
+
+// IsValid reports whether the document has no errors.
+func (r *ValidationResult) IsValid() bool {
+	return r.ErrorCount() == 0
+}
+
+// ErrorCount returns the number of error-level issues.
+func (r *ValidationResult) ErrorCount() int {
+	return r.count(SeverityError)
+}
+
+// WarningCount returns the number of warning-level issues.
+func (r *ValidationResult) WarningCount() int {
+	return r.count(SeverityWarning)
+}
+
+func (r *ValidationResult) count(severity string) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ValidationResult) add(severity, rule, message string, line int) {
+	r.Issues = append(r.Issues, Issue{Path: r.Path, Severity: severity, Rule: rule, Message: message, Line: line})
+}
+
+// DetectType infers a document's type from its path.
+func DetectType(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.Contains(lower, "_overview.md"):
+		return "overview"
+	case strings.Contains(lower, "_gotchas.md"):
+		return "gotchas"
+	case strings.Contains(lower, "_architecture.md"):
+		return "architecture"
+	case strings.Contains(lower, "_deep_dive.md"):
+		return "deep-dive"
+	case strings.HasPrefix(lower, "adrs/") || strings.Contains(lower, "adr-"):
+		return "adr"
+	case strings.HasPrefix(lower, "runbooks/"):
+		return "runbook"
+	default:
+		return "generic"
+	}
+}
+
+// Validate checks a document (relative to docsRoot) against the documentation conventions.
+func Validate(docsRoot, path string) *ValidationResult {
+	data, err := os.ReadFile(filepath.Join(docsRoot, path))
+	if err != nil {
+		result := &ValidationResult{Path: path, DocType: "unknown"}
+		result.add(SeverityError, "exists", "File does not exist", 0)
+		return result
+	}
+	content := string(data)
+	lines := strings.Split(content, "\n")
+
+	docType := DetectType(path)
+	result := &ValidationResult{Path: path, DocType: docType}
+
+	if !strings.HasPrefix(strings.TrimSpace(content), "# ") {
+		result.add(SeverityError, "has-title", "Document must start with a # heading", 1)
+	}
+
+	if required, ok := RequiredSections[docType]; ok {
+		var headings []string
+		for _, m := range headingPattern.FindAllStringSubmatch(content, -1) {
+			headings = append(headings, strings.ToLower(m[1]))
+		}
+		for _, section := range required {
+			found := false
+			for _, h := range headings {
+				if strings.Contains(h, strings.ToLower(section)) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				result.add(SeverityWarning, "required-section", "Missing required section: "+section, 0)
+			}
+		}
+	}
+
+	if docType == "gotchas" && !gotchaNumberPattern.MatchString(content) {
+		result.add(SeverityWarning, "gotcha-numbered", "Gotchas should have numbered items (### 1. Issue Name)", 0)
+	}
+	if docType == "adr" && !adrStatusPattern.MatchString(content) {
+		result.add(SeverityError, "adr-status", "ADR must have **Status:** field", 0)
+	}
+	if docType == "runbook" && !runbookRiskPattern.MatchString(content) {
+		result.add(SeverityWarning, "runbook-risk", "Runbook should have **Risk Level:** field", 0)
+	}
+
+	// A section is empty if the next non-blank line within a few lines is another heading
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		for j := i + 1; j < min(i+5, len(lines)); j++ {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+			if strings.HasPrefix(lines[j], "#") {
+				result.add(SeverityInfo, "empty-section", "Section appears empty: "+strings.TrimSpace(line), i+1)
+			}
+			break
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) > maxLineLength && !strings.HasPrefix(line, "|") && !strings.HasPrefix(line, "```") {
+			result.add(SeverityInfo, "line-length",
+				fmt.Sprintf("Line exceeds %d characters (%d chars)", maxLineLength, len(line)), i+1)
+		}
+	}
+
+	return result
+}
+
+// ValidateAll validates every markdown file under docsRoot.
+func ValidateAll(docsRoot string) ([]*ValidationResult, error) {
+	files, err := MarkdownFiles(docsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ValidationResult, 0, len(files))
+	for _, f := range files {
+		results = append(results, Validate(docsRoot, f))
+	}
+	return results, nil
+}
+
+// Link is an internal markdown link and the line it appears on.
+type Link struct {
+	Target string
+	Line   int
+}
+
+// FindLinks returns the internal links in content. External URLs, anchors, and
+// mailto links are skipped.
+func FindLinks(content string) []Link {
+	var links []Link
+	for i, line := range strings.Split(content, "\n") {
+		for _, m := range linkPattern.FindAllStringSubmatch(line, -1) {
+			target := m[2]
+			if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") ||
+				strings.HasPrefix(target, "#") || strings.HasPrefix(target, "mailto:") {
+				continue
+			}
+			links = append(links, Link{Target: target, Line: i + 1})
+		}
+	}
+	return links
+}
+
+// CheckLinks returns an issue for each internal link in path that does not resolve.
+func CheckLinks(docsRoot, path string) []Issue {
+	fullPath := filepath.Join(docsRoot, path)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return []Issue{{Path: path, Severity: SeverityError, Rule: "exists", Message: "File does not exist"}}
+	}
+
+	var issues []Issue
+	docDir := filepath.Dir(fullPath)
+	for _, link := range FindLinks(string(data)) {
+		target, _, _ := strings.Cut(link.Target, "#")
+		if target == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(docDir, target)); err != nil {
+			issues = append(issues, Issue{
+				Path: path, Severity: SeverityError, Rule: "broken-link",
+				Message: "Broken link: " + target, Line: link.Line,
+			})
+		}
+	}
+	return issues
+}
+
+// CheckAllLinks checks links in every markdown file, returning only files with issues.
+func CheckAllLinks(docsRoot string) (map[string][]Issue, error) {
+	files, err := MarkdownFiles(docsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]Issue)
+	for _, f := range files {
+		if issues := CheckLinks(docsRoot, f); len(issues) > 0 {
+			results[f] = issues
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,142 @@
+package docsync
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := map[string]string{
+		"auth/_overview.md":     "overview",
+		"auth/_GOTCHAS.md":      "gotchas",
+		"auth/_architecture.md": "architecture",
+		"auth/_deep_dive.md":    "deep-dive",
+		"adrs/0001-jwt.md":      "adr",
+		"decisions/ADR-002.md":  "adr",
+		"runbooks/deploy.md":    "runbook",
+		"notes.md":              "generic",
+	}
+	for path, want := range tests {
+		if got := DetectType(path); got != want {
+			t.Errorf("DetectType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// rules returns the rules of a result's issues.
+func rules(result *ValidationResult) []string {
+	var names []string
+	for _, i := range result.Issues {
+		names = append(names, i.Rule)
+	}
+	return names
+}
+
+func TestValidate(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		path    string
+		content string
+		want    []string // Rules reported, in order
+		valid   bool
+	}{
+		{
+			path:    "notes.md",
+			content: "# Notes\n\nSome text.\n",
+			valid:   true,
+		},
+		{
+			path:    "untitled.md",
+			content: "Some text.\n",
+			want:    []string{"has-title"},
+		},
+		{
+			path:    "adrs/ADR-001.md",
+			content: "# ADR-001\n\n## Status\n\nAccepted\n\n## Context\n\nx\n\n## Decision\n\nx\n\n## Consequences\n\nx\n",
+			want:    []string{"adr-status"},
+		},
+		{
+			path:    "adrs/ADR-002.md",
+			content: "# ADR-002\n\n**Status:** Accepted\n\n## Status\n\nx\n\n## Context\n\nx\n",
+			want:    []string{"required-section", "required-section"},
+			valid:   true,
+		},
+		{
+			path:    "svc/_gotchas.md",
+			content: "# Gotchas\n\n### Timeouts\n\nx\n",
+			want:    []string{"gotcha-numbered"},
+			valid:   true,
+		},
+		{
+			path:    "runbooks/restart.md",
+			content: "# Restart\n\n## Risk Level\n\nLow\n\n## Prerequisites\n\nx\n\n## Procedure\n\n## Rollback\n\nx\n\n## Verification\n\nx\n",
+			want:    []string{"runbook-risk", "empty-section"},
+			valid:   true,
+		},
+		{
+			path:    "long.md",
+			content: "# Long\n\n" + string(make([]byte, 0)) + longLine(121) + "\n| " + longLine(200) + " |\n",
+			want:    []string{"line-length"},
+			valid:   true,
+		},
+	}
+	for _, tt := range tests {
+		writeDoc(t, root, tt.path, tt.content)
+		result := Validate(root, tt.path)
+		if got := rules(result); !slices.Equal(got, tt.want) {
+			t.Errorf("Validate(%s) rules = %v, want %v", tt.path, got, tt.want)
+		}
+		if result.IsValid() != tt.valid {
+			t.Errorf("Validate(%s).IsValid() = %v, want %v", tt.path, result.IsValid(), tt.valid)
+		}
+	}
+
+	missing := Validate(root, "missing.md")
+	if missing.IsValid() || !slices.Equal(rules(missing), []string{"exists"}) {
+		t.Errorf("Validate(missing.md) = %+v, want an exists error", missing)
+	}
+
+	results, err := ValidateAll(root)
+	if err != nil {
+		t.Fatalf("ValidateAll() error = %v", err)
+	}
+	if len(results) != len(tests) {
+		t.Errorf("ValidateAll() returned %d results, want %d", len(results), len(tests))
+	}
+}
+
+func longLine(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 'x'
+	}
+	return string(b)
+}
+
+func TestFindLinks(t *testing.T) {
+	content := "See [setup](setup.md) and [API](../api/_overview.md#auth).\n" +
+		"[site](https://example.com) [top](#intro) [mail](mailto:a@b.c)\n"
+	want := []Link{{Target: "setup.md", Line: 1}, {Target: "../api/_overview.md#auth", Line: 1}}
+	if got := FindLinks(content); !slices.Equal(got, want) {
+		t.Errorf("FindLinks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	root := t.TempDir()
+	writeDoc(t, root, "svc/setup.md", "# Setup\n")
+	writeDoc(t, root, "svc/_overview.md", "# Svc\n\n[setup](setup.md#install) [gone](gone.md)\n[anchor](#only)\n")
+
+	issues := CheckLinks(root, "svc/_overview.md")
+	if len(issues) != 1 || issues[0].Rule != "broken-link" || issues[0].Message != "Broken link: gone.md" || issues[0].Line != 3 {
+		t.Errorf("CheckLinks() = %+v, want one broken link to gone.md on line 3", issues)
+	}
+
+	all, err := CheckAllLinks(root)
+	if err != nil {
+		t.Fatalf("CheckAllLinks() error = %v", err)
+	}
+	if len(all) != 1 || len(all["svc/_overview.md"]) != 1 {
+		t.Errorf("CheckAllLinks() = %+v, want only svc/_overview.md", all)
+	}
+}
@@ -0,0 +1,183 @@
+// Package dotfiles copies a curated set of host dotfiles into the volume's
+// home directory, so the shell in the capsule feels familiar without the
+// host's home directory ever being mounted.
+package dotfiles
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/transfer"
+)
+
+// VolumeHome is the container user's home directory, relative to the volume root.
+const VolumeHome = "home"
+
+// maxTotalBytes caps how much one sync copies, so listing a large directory
+// by mistake doesn't fill the volume.
+const maxTotalBytes = 10 << 20
+
+// blocked lists paths under the home directory that hold credentials or
+// capsule's own state. They are never copied from, or written over in, the
+// volume's home.
+var blocked = []string{
+	".ssh", ".gnupg", ".aws", ".azure", ".kube", ".docker",
+	".config/gcloud", ".config/gh", ".netrc", ".git-credentials",
+	".npmrc", ".pypirc", ".claude", ".capsule",
+}
+
+// Entry maps a file or directory under the host's home directory to a path
+// under the volume's home.
+type Entry struct {
+	Host string // Relative to the host's home directory
+	Home string // Relative to the volume's home directory
+}
+
+// Parse parses a dotfiles setting. "PATH" copies ~/PATH to the same place in
+// the volume's home; "HOST:HOME" copies ~/HOST to HOME, e.g. a trimmed-down
+// shell config kept for the capsule.
+func Parse(spec string) (Entry, error) {
+	host, home, ok := strings.Cut(spec, ":")
+	if !ok {
+		home = host
+	}
+	entry := Entry{Host: strings.TrimPrefix(host, "~/"), Home: strings.TrimPrefix(home, "~/")}
+	for _, path := range []string{entry.Host, entry.Home} {
+		if err := checkPath(path); err != nil {
+			return Entry{}, fmt.Errorf("invalid dotfile %q: %w", spec, err)
+		}
+	}
+	return entry, nil
+}
+
+// checkPath requires path to be under the home directory and not blocked.
+func checkPath(path string) error {
+	if path == "" || filepath.IsAbs(path) {
+		return fmt.Errorf("path must be relative to the home directory")
+	}
+	clean := filepath.Clean(path)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("path must be inside the home directory")
+	}
+	for _, b := range blocked {
+		if clean == b || strings.HasPrefix(clean, b+"/") {
+			return fmt.Errorf("%s may hold credentials and is never copied", b)
+		}
+	}
+	return nil
+}
+
+// Result reports what Sync did.
+type Result struct {
+	Updated []string // Files written because they were new or changed
+	Missing []string // Entries not found on the host
+}
+
+// Sync copies entries from hostHome into the home directory of the volume
+// mounted at mountPoint. The host is the source of truth: files whose
+// content changed are overwritten, unchanged files are left alone, and
+// nothing in the volume is deleted.
+func Sync(hostHome, mountPoint string, entries []Entry) (Result, error) {
+	var result Result
+	var total int64
+	volumeHome := filepath.Join(mountPoint, VolumeHome)
+
+	for _, entry := range entries {
+		src := filepath.Join(hostHome, entry.Host)
+		// Stat follows symlinks, so dotfiles managed by stow or chezmoi work
+		info, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			result.Missing = append(result.Missing, entry.Host)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		dst := filepath.Join(volumeHome, entry.Home)
+		if !info.IsDir() {
+			if err := syncFile(src, volumeHome, dst, entry.Home, info, &total, &result); err != nil {
+				return result, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Links inside a directory could lead anywhere on the host
+			if !d.IsDir() && !d.Type().IsRegular() {
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return syncFile(path, volumeHome, filepath.Join(dst, rel), filepath.Join(entry.Home, rel), info, &total, &result)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// syncFile copies src to dst, under volumeHome, unless dst already has the
+// same content, recording name in the result when it writes. The container
+// can write to volumeHome, so symlinks there are never followed: a symlinked
+// parent directory is refused, and a symlink at dst is replaced.
+func syncFile(src, volumeHome, dst, name string, info os.FileInfo, total *int64, result *Result) error {
+	*total += info.Size()
+	if *total > maxTotalBytes {
+		return fmt.Errorf("dotfiles exceed %d MB; list individual files instead of large directories", maxTotalBytes>>20)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := transfer.CheckParents(volumeHome, dst); err != nil {
+		return err
+	}
+	if existing, err := os.Lstat(dst); err == nil && existing.Mode().IsRegular() {
+		if current, err := os.ReadFile(dst); err == nil && bytes.Equal(current, data) {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+
+	// Write beside dst and rename over it, which replaces a symlink rather
+	// than following it
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".capsule-dotfile-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	result.Updated = append(result.Updated, name)
+	return nil
+}
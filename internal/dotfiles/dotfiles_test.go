@@ -0,0 +1,145 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Entry
+		wantErr bool
+	}{
+		{spec: ".vimrc", want: Entry{Host: ".vimrc", Home: ".vimrc"}},
+		{spec: "~/.tmux.conf", want: Entry{Host: ".tmux.conf", Home: ".tmux.conf"}},
+		{spec: ".zshrc.capsule:.zshrc", want: Entry{Host: ".zshrc.capsule", Home: ".zshrc"}},
+		{spec: ".config/nvim", want: Entry{Host: ".config/nvim", Home: ".config/nvim"}},
+		{spec: "/etc/passwd", wantErr: true},
+		{spec: "../other/.vimrc", wantErr: true},
+		{spec: ".", wantErr: true},
+		{spec: ".ssh/config", wantErr: true},
+		{spec: ".aws", wantErr: true},
+		{spec: ".config/gh/hosts.yml", wantErr: true},
+		{spec: ".vimrc:.claude/settings.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	hostHome := t.TempDir()
+	mountPoint := t.TempDir()
+	writeFile(t, filepath.Join(hostHome, ".vimrc"), "set number\n")
+	writeFile(t, filepath.Join(hostHome, ".zshrc.capsule"), "alias ll='ls -l'\n")
+	writeFile(t, filepath.Join(hostHome, ".config/nvim/init.lua"), "vim.o.number = true\n")
+	if err := os.Symlink(filepath.Join(hostHome, ".vimrc"), filepath.Join(hostHome, ".config/nvim/link.vim")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Entry{
+		{Host: ".vimrc", Home: ".vimrc"},
+		{Host: ".zshrc.capsule", Home: ".zshrc"},
+		{Host: ".config/nvim", Home: ".config/nvim"},
+		{Host: ".tmux.conf", Home: ".tmux.conf"},
+	}
+	result, err := Sync(hostHome, mountPoint, entries)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := Result{
+		Updated: []string{".vimrc", ".zshrc", ".config/nvim/init.lua"},
+		Missing: []string{".tmux.conf"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("Sync() = %+v, want %+v", result, want)
+	}
+	volumeHome := filepath.Join(mountPoint, VolumeHome)
+	if data, err := os.ReadFile(filepath.Join(volumeHome, ".zshrc")); err != nil || string(data) != "alias ll='ls -l'\n" {
+		t.Errorf(".zshrc = %q, %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(volumeHome, ".config/nvim/link.vim")); !os.IsNotExist(err) {
+		t.Errorf("symlink inside a directory was copied: %v", err)
+	}
+
+	// Only files that changed on the host are written again
+	writeFile(t, filepath.Join(hostHome, ".vimrc"), "set number\nset hlsearch\n")
+	result, err = Sync(hostHome, mountPoint, entries[:3])
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{".vimrc"}) {
+		t.Errorf("second Sync() updated %v, want [.vimrc]", result.Updated)
+	}
+}
+
+func TestSyncDoesNotFollowSymlinks(t *testing.T) {
+	hostHome := t.TempDir()
+	mountPoint := t.TempDir()
+	outside := t.TempDir()
+	volumeHome := filepath.Join(mountPoint, VolumeHome)
+	writeFile(t, filepath.Join(hostHome, ".zshrc"), "export EDITOR=vim\n")
+	writeFile(t, filepath.Join(hostHome, ".config/nvim/init.lua"), "vim.o.number = true\n")
+
+	// A session planted a link at the file and at a parent directory
+	authorizedKeys := filepath.Join(outside, "authorized_keys")
+	writeFile(t, authorizedKeys, "ssh-ed25519 AAAA host\n")
+	if err := os.Chmod(authorizedKeys, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(volumeHome, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(authorizedKeys, filepath.Join(volumeHome, ".zshrc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(volumeHome, ".config")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link at the file is replaced, and the target left alone
+	result, err := Sync(hostHome, mountPoint, []Entry{{Host: ".zshrc", Home: ".zshrc"}})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{".zshrc"}) {
+		t.Errorf("Sync() updated %v, want [.zshrc]", result.Updated)
+	}
+	if info, err := os.Lstat(filepath.Join(volumeHome, ".zshrc")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf(".zshrc is not a regular file: %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(authorizedKeys); string(data) != "ssh-ed25519 AAAA host\n" {
+		t.Errorf("symlink target was overwritten: %q", data)
+	}
+	if info, _ := os.Stat(authorizedKeys); info.Mode().Perm() != 0600 {
+		t.Errorf("symlink target mode = %o, want 600", info.Mode().Perm())
+	}
+
+	// A linked parent directory is refused
+	if _, err := Sync(hostHome, mountPoint, []Entry{{Host: ".config/nvim", Home: ".config/nvim"}}); err == nil {
+		t.Error("Sync() wrote through a symlinked directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "nvim")); !os.IsNotExist(err) {
+		t.Errorf("Sync() created files through a symlinked directory: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,200 @@
+package embedded
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// Entries of an image archive. The metadata comes first so it can be read
+// without scanning past the image.
+const (
+	archiveMetadataName = "capsule-image.json"
+	archiveImageName    = "image.tar"
+)
+
+// archiveFormat is the version of the image archive layout.
+const archiveFormat = 1
+
+// ImageArchive describes the image in an archive written by ExportImage.
+type ImageArchive struct {
+	Format         int       `json:"format"`
+	CapsuleVersion string    `json:"capsule_version"`
+	Image          string    `json:"image"`
+	ImageID        string    `json:"image_id"`
+	Flavor         Flavor    `json:"flavor"`
+	Template       Template  `json:"template,omitempty"`
+	Created        time.Time `json:"created"`
+}
+
+// ImageID returns the ID of a local Docker image.
+func ImageID(ctx context.Context, imageName string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Id}}", imageName)
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return "", fmt.Errorf("image %s not found: %w", imageName, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ExportImage writes meta.Image to an archive at path: the metadata followed
+// by the output of docker save. ImageID and Created are filled in.
+func ExportImage(ctx context.Context, path string, meta ImageArchive) error {
+	id, err := ImageID(ctx, meta.Image)
+	if err != nil {
+		return err
+	}
+	meta.Format = archiveFormat
+	meta.ImageID = id
+	meta.Created = time.Now().UTC()
+	metadata, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode image metadata: %w", err)
+	}
+
+	// tar needs each entry's size up front, so the image is saved to a
+	// temporary file next to the archive first
+	saved, err := os.CreateTemp(filepath.Dir(path), ".capsule-image-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(saved.Name())
+	defer saved.Close()
+
+	cmd := exec.CommandContext(ctx, "docker", "save", meta.Image)
+	cmd.Stdout = saved
+	cmd.Stderr = os.Stderr
+	done := logging.Command(cmd)
+	err = cmd.Run()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("docker save failed: %w", err)
+	}
+	info, err := saved.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := saved.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Write to a temporary name so an interrupted export never leaves a
+	// truncated archive at path
+	partial := path + ".partial"
+	out, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.PublicFilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(partial)
+
+	tw := tar.NewWriter(out)
+	err = writeArchiveEntry(tw, archiveMetadataName, int64(len(metadata)), bytes.NewReader(metadata))
+	if err == nil {
+		err = writeArchiveEntry(tw, archiveImageName, info.Size(), saved)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(partial, path)
+}
+
+func writeArchiveEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// ReadImageArchive returns the metadata of the archive at path.
+func ReadImageArchive(path string) (ImageArchive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageArchive{}, err
+	}
+	defer f.Close()
+	meta, _, err := readArchiveMetadata(tar.NewReader(f))
+	return meta, err
+}
+
+// readArchiveMetadata reads the metadata entry at the start of an archive
+// and returns the reader positioned at the next entry.
+func readArchiveMetadata(tr *tar.Reader) (ImageArchive, *tar.Reader, error) {
+	var meta ImageArchive
+	header, err := tr.Next()
+	if err != nil || header.Name != archiveMetadataName {
+		return meta, nil, errors.New("not a capsule image archive (export one with 'capsule image export')")
+	}
+	if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+		return meta, nil, fmt.Errorf("invalid image metadata: %w", err)
+	}
+	if meta.Format != archiveFormat {
+		return meta, nil, fmt.Errorf("unsupported image archive format %d; upgrade capsule", meta.Format)
+	}
+	return meta, tr, nil
+}
+
+// ImportImage loads the image in the archive at path into Docker and checks
+// that it has the ID recorded at export.
+func ImportImage(ctx context.Context, path string) (ImageArchive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageArchive{}, err
+	}
+	defer f.Close()
+
+	meta, tr, err := readArchiveMetadata(tar.NewReader(f))
+	if err != nil {
+		return meta, err
+	}
+	header, err := tr.Next()
+	if err != nil || header.Name != archiveImageName {
+		return meta, fmt.Errorf("image archive %s has no image", path)
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", "load")
+	cmd.Stdin = tr
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	done := logging.Command(cmd)
+	err = cmd.Run()
+	done(err)
+	if err != nil {
+		return meta, fmt.Errorf("docker load failed: %w", err)
+	}
+
+	id, err := ImageID(ctx, meta.Image)
+	if err != nil {
+		return meta, err
+	}
+	if id != meta.ImageID {
+		return meta, fmt.Errorf("loaded image %s has ID %s, but the archive recorded %s", meta.Image, id, meta.ImageID)
+	}
+	return meta, nil
+}
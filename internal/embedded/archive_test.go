@@ -0,0 +1,62 @@
+package embedded
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadImageArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capsule-image.tar")
+	want := ImageArchive{
+		Format:         archiveFormat,
+		CapsuleVersion: "1.4.0",
+		Image:          "claude-capsule-go-slim:latest",
+		ImageID:        "sha256:abc",
+		Flavor:         FlavorSlim,
+		Template:       TemplateGo,
+	}
+	metadata, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := writeArchiveEntry(tw, archiveMetadataName, int64(len(metadata)), strings.NewReader(string(metadata))); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeArchiveEntry(tw, archiveImageName, 5, strings.NewReader("image")); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	got, err := ReadImageArchive(path)
+	if err != nil {
+		t.Fatalf("ReadImageArchive() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("ReadImageArchive() = %+v, want %+v", got, want)
+	}
+
+	// A plain docker save archive has no capsule metadata
+	plain := filepath.Join(dir, "plain.tar")
+	f, err = os.Create(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw = tar.NewWriter(f)
+	writeArchiveEntry(tw, "manifest.json", 2, strings.NewReader("[]"))
+	tw.Close()
+	f.Close()
+	if _, err := ReadImageArchive(plain); err == nil || !strings.Contains(err.Error(), "not a capsule image archive") {
+		t.Errorf("ReadImageArchive(docker save archive) error = %v", err)
+	}
+}
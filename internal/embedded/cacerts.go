@@ -0,0 +1,110 @@
+package embedded
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// caCertsDir is the build context directory holding extra CA certificates.
+const caCertsDir = "ca-certs"
+
+// caCertsLayers adds the certificates in caCertsDir to the image's trust
+// store. They are installed right after FROM so every later download,
+// including through a TLS-intercepting proxy, trusts them. Node and Python
+// keep their own CA lists, so they are pointed at the system bundle.
+const caCertsLayers = `
+# Extra CA certificates (ca_certs in ~/.capsule/config.json)
+COPY ` + caCertsDir + `/ /usr/local/share/ca-certificates/capsule/
+RUN apt-get update && apt-get install -y --no-install-recommends ca-certificates && \
+    update-ca-certificates && \
+    rm -rf /var/lib/apt/lists/*
+ENV NODE_EXTRA_CA_CERTS=/etc/ssl/certs/ca-certificates.crt
+ENV REQUESTS_CA_BUNDLE=/etc/ssl/certs/ca-certificates.crt
+ENV PIP_CERT=/etc/ssl/certs/ca-certificates.crt
+ENV SSL_CERT_FILE=/etc/ssl/certs/ca-certificates.crt
+`
+
+// ReadCACerts reads PEM files of CA certificates and returns them as one
+// bundle. Every file must hold at least one certificate.
+func ReadCACerts(paths []string) ([]byte, error) {
+	var bundle bytes.Buffer
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		certs, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, cert := range certs {
+			pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert})
+		}
+	}
+	return bundle.Bytes(), nil
+}
+
+// parseCertificates returns the DER bytes of each certificate in PEM data.
+func parseCertificates(data []byte) ([][]byte, error) {
+	var certs [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, block.Bytes)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return certs, nil
+}
+
+// writeCACerts writes each certificate in bundle to its own .crt file under
+// contextDir/ca-certs, the layout update-ca-certificates expects.
+func writeCACerts(contextDir string, bundle []byte) error {
+	certs, err := parseCertificates(bundle)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(contextDir, caCertsDir)
+	if err := os.MkdirAll(dir, constants.DirPermissions); err != nil {
+		return err
+	}
+	for i, cert := range certs {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+		path := filepath.Join(dir, fmt.Sprintf("capsule-%d.crt", i+1))
+		if err := os.WriteFile(path, data, constants.PublicFilePermissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withCACerts inserts caCertsLayers after the Dockerfile's first FROM line.
+func withCACerts(dockerfile []byte) []byte {
+	lines := bytes.SplitAfter(dockerfile, []byte("\n"))
+	var out []byte
+	inserted := false
+	for _, line := range lines {
+		out = append(out, line...)
+		if !inserted && bytes.HasPrefix(line, []byte("FROM ")) {
+			out = append(out, caCertsLayers...)
+			inserted = true
+		}
+	}
+	return out
+}
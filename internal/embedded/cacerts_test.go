@@ -0,0 +1,83 @@
+package embedded
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCACert returns a PEM-encoded self-signed CA certificate.
+func testCACert(t *testing.T, name string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestReadCACerts(t *testing.T) {
+	dir := t.TempDir()
+	proxyCA := filepath.Join(dir, "proxy.pem")
+	internalCAs := filepath.Join(dir, "internal.pem")
+	os.WriteFile(proxyCA, testCACert(t, "Proxy CA"), 0644)
+	os.WriteFile(internalCAs, append(testCACert(t, "Root CA"), testCACert(t, "Issuing CA")...), 0644)
+
+	bundle, err := ReadCACerts([]string{proxyCA, internalCAs})
+	if err != nil {
+		t.Fatalf("ReadCACerts() error = %v", err)
+	}
+	if n := strings.Count(string(bundle), "BEGIN CERTIFICATE"); n != 3 {
+		t.Errorf("bundle has %d certificates, want 3", n)
+	}
+
+	// update-ca-certificates wants one certificate per .crt file
+	contextDir := t.TempDir()
+	if err := writeCACerts(contextDir, bundle); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(contextDir, caCertsDir, "*.crt"))
+	if len(files) != 3 {
+		t.Errorf("writeCACerts wrote %d files, want 3", len(files))
+	}
+
+	notPEM := filepath.Join(dir, "key.txt")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0644)
+	if _, err := ReadCACerts([]string{notPEM}); err == nil {
+		t.Error("ReadCACerts(non-PEM file) succeeded, want error")
+	}
+}
+
+func TestWithCACerts(t *testing.T) {
+	dockerfile := string(withCACerts(Dockerfile(FlavorSlim, "")))
+	from := strings.Index(dockerfile, "FROM ${BASE_IMAGE}")
+	certs := strings.Index(dockerfile, "COPY "+caCertsDir+"/")
+	install := strings.Index(dockerfile, "apt-get install -y --no-install-recommends \\")
+	if from < 0 || certs < from || install < certs {
+		t.Errorf("CA certificates should be installed right after FROM, before any package installs")
+	}
+	if strings.Count(dockerfile, "COPY "+caCertsDir+"/") != 1 {
+		t.Error("CA certificate layers inserted more than once")
+	}
+}
@@ -11,6 +11,7 @@ You are running inside a secure, containerized environment. Your access is inten
 
 **You CAN access:**
 - /workspace/ - The current project (mounted from the host)
+- /workspaces/<name>/ - Instead of /workspace/, when several projects are mounted together
 - /claude-env/ - Your encrypted home directory and data
 
 **You CANNOT access:**
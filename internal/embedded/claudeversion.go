@@ -0,0 +1,101 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// ClaudeCodeVersionArg is the build argument that pins the Claude Code
+// release npm installs. It defaults to "latest".
+const ClaudeCodeVersionArg = "CLAUDE_CODE_VERSION"
+
+// ClaudeCodeVersionLabel records the CLAUDE_CODE_VERSION an image was built
+// with, so a changed pin can be noticed without starting a container.
+const ClaudeCodeVersionLabel = "capsule.claude-code-version"
+
+// claudeCodePackage is the npm package the image installs.
+const claudeCodePackage = "@anthropic-ai/claude-code"
+
+// claudeCodeVersionPattern matches what a pin may be: an npm dist-tag capsule
+// knows, or an exact release such as 1.0.58.
+var claudeCodeVersionPattern = regexp.MustCompile(`^(latest|stable|\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?)$`)
+
+// releasePattern finds a version number in 'claude --version' or 'npm view' output.
+var releasePattern = regexp.MustCompile(`\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?`)
+
+// ValidateClaudeCodeVersion checks that v is latest, stable, or an exact release.
+func ValidateClaudeCodeVersion(v string) error {
+	if !claudeCodeVersionPattern.MatchString(v) {
+		return fmt.Errorf("Claude Code version %q must be latest, stable, or an exact release such as 1.0.58", v)
+	}
+	return nil
+}
+
+// IsExactRelease reports whether a pin names one release rather than a dist-tag.
+func IsExactRelease(v string) bool {
+	return v != "" && releasePattern.FindString(v) == v
+}
+
+// ImageClaudeCodePin returns the CLAUDE_CODE_VERSION an image was built with,
+// or "" for an image built before capsule recorded it.
+func ImageClaudeCodePin(ctx context.Context, imageName string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--format",
+		`{{ index .Config.Labels "`+ClaudeCodeVersionLabel+`" }}`, imageName)
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", imageName, err)
+	}
+	pin := strings.TrimSpace(string(output))
+	if pin == "<no value>" {
+		pin = ""
+	}
+	return pin, nil
+}
+
+// InstalledClaudeCodeVersion returns the Claude Code release installed in an image.
+func InstalledClaudeCodeVersion(ctx context.Context, imageName string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "run", "--rm", "--entrypoint", "claude", imageName, "--version")
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return "", fmt.Errorf("failed to run claude --version in %s: %w", imageName, err)
+	}
+	return parseRelease(string(output))
+}
+
+// LatestClaudeCodeVersion asks the npm registry for the newest Claude Code
+// release, using the npm in an image so the host needs no Node install. env
+// is passed to the container, e.g. proxy settings.
+func LatestClaudeCodeVersion(ctx context.Context, imageName string, env []string) (string, error) {
+	args := []string{"run", "--rm"}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, "--entrypoint", "npm", imageName, "view", claudeCodePackage, "version")
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the latest Claude Code release: %w", err)
+	}
+	return parseRelease(string(output))
+}
+
+// parseRelease returns the first version number in output, e.g. 1.0.58 from
+// "1.0.58 (Claude Code)".
+func parseRelease(output string) (string, error) {
+	release := releasePattern.FindString(output)
+	if release == "" {
+		return "", fmt.Errorf("no version number in %q", strings.TrimSpace(output))
+	}
+	return release, nil
+}
@@ -0,0 +1,34 @@
+package embedded
+
+import "testing"
+
+func TestValidateClaudeCodeVersion(t *testing.T) {
+	for _, v := range []string{"latest", "stable", "1.0.58", "2.0.0-beta.1"} {
+		if err := ValidateClaudeCodeVersion(v); err != nil {
+			t.Errorf("ValidateClaudeCodeVersion(%q) error = %v", v, err)
+		}
+	}
+	for _, v := range []string{"", "next", "^1.0.0", "1.0", "1.0.58; rm -rf /"} {
+		if err := ValidateClaudeCodeVersion(v); err == nil {
+			t.Errorf("ValidateClaudeCodeVersion(%q) succeeded, want error", v)
+		}
+	}
+	if !IsExactRelease("1.0.58") || IsExactRelease("latest") || IsExactRelease("") {
+		t.Error("IsExactRelease() distinguishes releases from dist-tags incorrectly")
+	}
+}
+
+func TestParseRelease(t *testing.T) {
+	tests := map[string]string{
+		"1.0.58 (Claude Code)\n": "1.0.58",
+		"2.0.0-beta.1\n":         "2.0.0-beta.1",
+	}
+	for output, want := range tests {
+		if got, err := parseRelease(output); err != nil || got != want {
+			t.Errorf("parseRelease(%q) = %q, %v, want %q", output, got, err, want)
+		}
+	}
+	if _, err := parseRelease("command not found"); err == nil {
+		t.Error("parseRelease() without a version succeeded, want error")
+	}
+}
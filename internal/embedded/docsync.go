@@ -1,23 +1,52 @@
 package embedded
 
 import (
-	_ "embed"
+	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
 )
 
-//go:embed docsync/doctool.py
-var DoctoolPy []byte
+// DoctoolBinaries holds the Linux doctool builds produced by "make doctool",
+// and the capsule-helper builds produced by "make helper". A plain "go build"
+// embeds only bin/.gitkeep: WriteDocSyncFiles then installs a placeholder
+// doctool and returns ErrDoctoolNotEmbedded, and EnsureHelperImage fails.
+//
+//go:embed all:bin
+var DoctoolBinaries embed.FS
+
+// DoctoolArchitectures are the container architectures doctool is built for.
+var DoctoolArchitectures = []string{"amd64", "arm64"}
+
+// DoctoolWrapper selects the doctool binary matching the container architecture.
+const DoctoolWrapper = `#!/bin/sh
+# Generated by capsule: runs the doctool build for this container's architecture.
+case "$(uname -m)" in
+    x86_64|amd64) arch=amd64 ;;
+    aarch64|arm64) arch=arm64 ;;
+    *) echo "doctool: unsupported architecture $(uname -m)" >&2; exit 1 ;;
+esac
+exec "$(dirname "$0")/bin/doctool-linux-$arch" "$@"
+`
 
-//go:embed docsync/mcp_server.py
-var MCPServerPy []byte
+// doctoolPlaceholder stands in for DoctoolWrapper when capsule was built
+// without the doctool binaries, so the skill and MCP server fail with a
+// message instead of a missing file.
+const doctoolPlaceholder = `#!/bin/sh
+# Generated by capsule: this capsule build does not include doctool.
+echo "doctool: not included in this capsule build; build capsule with 'make build' and run 'capsule upgrade'" >&2
+exit 1
+`
 
-//go:embed docsync/schema.sql
-var SchemaSql []byte
+// ErrDoctoolNotEmbedded is returned by WriteDocSyncFiles when capsule was
+// built without the doctool binaries, as a plain "go build" does. The rest of
+// the skill is still installed.
+var ErrDoctoolNotEmbedded = errors.New("doctool is not embedded in this build (build capsule with 'make build')")
 
 //go:embed docsync/SKILL.md
 var SkillMd []byte
@@ -87,17 +116,17 @@ Decision content here...
 
 ` + "```bash" + `
 # Search memory (results include age)
-python3 ~/.claude/skills/doc-sync/doctool.py memory search "ECS routing"
+~/.claude/skills/doc-sync/doctool memory search "ECS routing"
 
 # Add a decision
-python3 ~/.claude/skills/doc-sync/doctool.py memory add "Decision text" \
+~/.claude/skills/doc-sync/doctool memory add "Decision text" \
     --tags topic,area --type decision
 
 # Check recent additions (shows age)
-python3 ~/.claude/skills/doc-sync/doctool.py memory recent
+~/.claude/skills/doc-sync/doctool memory recent
 
 # Look back further
-python3 ~/.claude/skills/doc-sync/doctool.py memory recent --days 30
+~/.claude/skills/doc-sync/doctool memory recent --days 30
 ` + "```" + `
 
 **MCP tools**: ` + "`memory_search`" + `, ` + "`memory_add`" + `, ` + "`memory_recent`" + `
@@ -160,6 +189,7 @@ bd search "keyword"  # Search issues
 ### Storage
 
 - Task data: ` + "`/claude-env/repos/<project>/.beads/`" + `
+- bd binary: ` + "`/claude-env/bin/bd`" + ` (managed from the host with ` + "`capsule beads`" + `)
 - Memory data: ` + "`/workspace/_docs/.doc-index.db`" + ` (shared with doc-sync)
 - Per-project isolated — switching repos uses a separate database
 - Local-only on the encrypted volume — never touches git or GitHub
@@ -177,8 +207,8 @@ bd search "keyword"  # Search issues
 const SettingsJSON = `{
   "mcpServers": {
     "doc-sync": {
-      "command": "python3",
-      "args": ["/claude-env/home/.claude/skills/doc-sync/mcp_server.py"]
+      "command": "/claude-env/home/.claude/skills/doc-sync/doctool",
+      "args": ["mcp"]
     }
   }
 }
@@ -193,25 +223,56 @@ const TaskMgrSkillDir = "home/.claude/skills/task-mgr"
 // VersionFile is the path within the encrypted volume for version tracking.
 const VersionFile = "home/.claude/VERSION"
 
-// WriteDocSyncFiles writes the doc-sync skill files to the mounted volume.
-// Files are written with executable permissions for Python scripts.
+// WriteDocSyncFiles writes the doc-sync skill files to the mounted volume:
+// the doctool binaries for each supported architecture, a wrapper script that
+// picks the right one, and the skill documentation.
+//
+// If this build has no doctool binaries, binaries already on the volume are
+// kept; without them the wrapper is a placeholder that explains how to get
+// doctool. Either way the other files are written and ErrDoctoolNotEmbedded
+// is returned, which callers report as a warning.
 func WriteDocSyncFiles(mountPoint string) error {
+	return writeDocSyncFiles(mountPoint, DoctoolBinaries)
+}
+
+func writeDocSyncFiles(mountPoint string, binaries fs.FS) error {
 	skillDir := filepath.Join(mountPoint, DocSyncSkillDir)
+	binDir := filepath.Join(skillDir, "bin")
 
 	// Create the skill directory
-	if err := os.MkdirAll(skillDir, constants.DirPermissions); err != nil {
+	if err := os.MkdirAll(binDir, constants.DirPermissions); err != nil {
 		return fmt.Errorf("failed to create skill directory: %w", err)
 	}
 
+	var missing error
+	wrapper := DoctoolWrapper
+	for _, arch := range DoctoolArchitectures {
+		if _, err := fs.Stat(binaries, "bin/doctool-linux-"+arch); err != nil {
+			missing = ErrDoctoolNotEmbedded
+		}
+	}
+	if missing == nil {
+		for _, arch := range DoctoolArchitectures {
+			name := "doctool-linux-" + arch
+			content, err := fs.ReadFile(binaries, "bin/"+name)
+			if err != nil {
+				return fmt.Errorf("failed to read embedded %s: %w", name, err)
+			}
+			if err := os.WriteFile(filepath.Join(binDir, name), content, constants.ExecutablePermissions); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+	} else if !DoctoolInstalled(mountPoint) {
+		wrapper = doctoolPlaceholder
+	}
+
 	// File definitions: path, content, permissions
 	files := []struct {
 		name    string
 		content []byte
 		perm    os.FileMode
 	}{
-		{"doctool.py", DoctoolPy, constants.ExecutablePermissions},
-		{"mcp_server.py", MCPServerPy, constants.ExecutablePermissions},
-		{"schema.sql", SchemaSql, constants.PublicFilePermissions},
+		{"doctool", []byte(wrapper), constants.ExecutablePermissions},
 		{"SKILL.md", SkillMd, constants.PublicFilePermissions},
 	}
 
@@ -222,7 +283,26 @@ func WriteDocSyncFiles(mountPoint string) error {
 		}
 	}
 
-	return nil
+	// Remove the Python implementation left behind by older versions
+	for _, name := range []string{"doctool.py", "mcp_server.py", "schema.sql"} {
+		if err := os.Remove(filepath.Join(skillDir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove legacy %s: %w", name, err)
+		}
+	}
+
+	return missing
+}
+
+// DoctoolInstalled reports whether the mounted volume holds a doctool binary
+// for every supported architecture.
+func DoctoolInstalled(mountPoint string) bool {
+	binDir := filepath.Join(mountPoint, DocSyncSkillDir, "bin")
+	for _, arch := range DoctoolArchitectures {
+		if _, err := os.Stat(filepath.Join(binDir, "doctool-linux-"+arch)); err != nil {
+			return false
+		}
+	}
+	return true
 }
 
 // WriteTaskMgrFiles writes the task-mgr skill files to the mounted volume.
@@ -0,0 +1,57 @@
+package embedded
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriteDocSyncFiles(t *testing.T) {
+	mountPoint := t.TempDir()
+	binaries := fstest.MapFS{}
+	for _, arch := range DoctoolArchitectures {
+		binaries["bin/doctool-linux-"+arch] = &fstest.MapFile{Data: []byte("doctool " + arch)}
+	}
+	if err := writeDocSyncFiles(mountPoint, binaries); err != nil {
+		t.Fatalf("writeDocSyncFiles() error = %v", err)
+	}
+	skillDir := filepath.Join(mountPoint, DocSyncSkillDir)
+	if got, err := os.ReadFile(filepath.Join(skillDir, "doctool")); err != nil || string(got) != DoctoolWrapper {
+		t.Errorf("doctool = %q, %v; want the wrapper", got, err)
+	}
+	if !DoctoolInstalled(mountPoint) {
+		t.Error("DoctoolInstalled() = false after installing the binaries")
+	}
+
+	// A build without the binaries keeps the ones already installed
+	err := writeDocSyncFiles(mountPoint, fstest.MapFS{"bin/.gitkeep": {}})
+	if !errors.Is(err, ErrDoctoolNotEmbedded) {
+		t.Fatalf("writeDocSyncFiles() error = %v, want ErrDoctoolNotEmbedded", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(skillDir, "doctool")); string(got) != DoctoolWrapper {
+		t.Errorf("doctool = %q, want the wrapper kept for the installed binaries", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(skillDir, "bin", "doctool-linux-arm64")); string(got) != "doctool arm64" {
+		t.Errorf("installed binary = %q, want it kept", got)
+	}
+}
+
+func TestWriteDocSyncFilesNotEmbedded(t *testing.T) {
+	mountPoint := t.TempDir()
+	err := writeDocSyncFiles(mountPoint, fstest.MapFS{"bin/.gitkeep": {}})
+	if !errors.Is(err, ErrDoctoolNotEmbedded) {
+		t.Fatalf("writeDocSyncFiles() error = %v, want ErrDoctoolNotEmbedded", err)
+	}
+	skillDir := filepath.Join(mountPoint, DocSyncSkillDir)
+	if got, _ := os.ReadFile(filepath.Join(skillDir, "doctool")); string(got) != doctoolPlaceholder {
+		t.Errorf("doctool = %q, want the placeholder", got)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, "SKILL.md")); err != nil {
+		t.Errorf("SKILL.md not written: %v", err)
+	}
+	if DoctoolInstalled(mountPoint) {
+		t.Error("DoctoolInstalled() = true without binaries")
+	}
+}
@@ -1,21 +1,86 @@
 package embedded
 
 import (
+	"bufio"
+	"context"
 	_ "embed"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/progress"
 )
 
-//go:embed Dockerfile
-var Dockerfile []byte
+// buildOutputTailLines is how much docker build output is shown when a build
+// behind a progress indicator fails.
+const buildOutputTailLines = 30
+
+// BaseImageArg is the build argument that selects the Dockerfile's base image.
+const BaseImageArg = "BASE_IMAGE"
+
+// buildArgNamePattern matches a Dockerfile ARG name.
+var buildArgNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateBuildArg checks that arg has the form KEY=VALUE.
+func ValidateBuildArg(arg string) error {
+	key, _, ok := strings.Cut(arg, "=")
+	if !ok {
+		return fmt.Errorf("build arg %q must be KEY=VALUE", arg)
+	}
+	if !buildArgNamePattern.MatchString(key) {
+		return fmt.Errorf("build arg %q: invalid name %q", arg, key)
+	}
+	return nil
+}
+
+// BuildOptions selects what BuildImage builds and how.
+type BuildOptions struct {
+	Flavor   Flavor
+	Template Template
+
+	// BuildArgs are KEY=VALUE pairs passed as --build-arg; for a repeated key
+	// the last one wins.
+	BuildArgs []string
+
+	// CacheFrom lists images whose layers may be reused, e.g. a team image
+	// pushed to a registry. Every build embeds inline cache metadata, so any
+	// capsule image can serve as a cache source.
+	CacheFrom []string
+
+	// NoCache rebuilds every layer, ignoring local and CacheFrom caches.
+	NoCache bool
+
+	// CACerts is a PEM bundle of extra CA certificates to trust (see ReadCACerts).
+	CACerts []byte
+
+	// ClaudeCodeVersion pins the Claude Code release installed in the image
+	// (see ValidateClaudeCodeVersion). Empty installs the latest.
+	ClaudeCodeVersion string
+
+	// CapsuleVersion is recorded in the image's capsule.version label.
+	CapsuleVersion string
+}
+
+// BuildImage builds the Docker image from the embedded Dockerfiles with
+// BuildKit. Canceling ctx stops the build. Returns nil if successful, error otherwise.
+func BuildImage(ctx context.Context, imageName string, opts BuildOptions) error {
+	for _, arg := range opts.BuildArgs {
+		if err := ValidateBuildArg(arg); err != nil {
+			return err
+		}
+	}
+	if opts.ClaudeCodeVersion != "" {
+		if err := ValidateClaudeCodeVersion(opts.ClaudeCodeVersion); err != nil {
+			return err
+		}
+	}
 
-// BuildImage builds the Docker image from the embedded Dockerfile.
-// Returns nil if successful, error otherwise.
-func BuildImage(imageName string) error {
 	// Create temp directory for build context
 	tempDir, err := os.MkdirTemp("", "capsule-build-*")
 	if err != nil {
@@ -23,26 +88,111 @@ func BuildImage(imageName string) error {
 	}
 	defer os.RemoveAll(tempDir)
 
-	// Write Dockerfile to temp directory
+	// Write Dockerfile and CA certificates to temp directory
+	dockerfile := Dockerfile(opts.Flavor, opts.Template)
+	if len(opts.CACerts) > 0 {
+		if err := writeCACerts(tempDir, opts.CACerts); err != nil {
+			return fmt.Errorf("failed to write CA certificates: %w", err)
+		}
+		dockerfile = withCACerts(dockerfile)
+	}
 	dockerfilePath := filepath.Join(tempDir, "Dockerfile")
-	if err := os.WriteFile(dockerfilePath, Dockerfile, constants.FilePermissions); err != nil {
+	if err := os.WriteFile(dockerfilePath, dockerfile, constants.FilePermissions); err != nil {
 		return fmt.Errorf("failed to write Dockerfile: %w", err)
 	}
 
 	// Build the image
-	cmd := exec.Command("docker", "build", "-t", imageName, tempDir)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, "docker", dockerBuildArgs(imageName, tempDir, opts)...)
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	done := logging.Command(cmd)
 
-	if err := cmd.Run(); err != nil {
+	indicator := progress.Start(os.Stdout, "Building Docker image "+imageName)
+	if !indicator.Interactive() {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+	} else {
+		err = runBuildWithProgress(cmd, indicator)
+	}
+	done(err)
+	indicator.Done(err)
+	if err != nil {
 		return fmt.Errorf("failed to build Docker image: %w", err)
 	}
 
 	return nil
 }
 
+// dockerBuildArgs returns the docker arguments to build imageName from the
+// Dockerfile in contextDir.
+func dockerBuildArgs(imageName, contextDir string, opts BuildOptions) []string {
+	args := []string{"build", "-t", imageName, "--build-arg", "BUILDKIT_INLINE_CACHE=1"}
+	for _, arg := range opts.BuildArgs {
+		args = append(args, "--build-arg", arg)
+	}
+	if opts.ClaudeCodeVersion != "" {
+		args = append(args, "--build-arg", ClaudeCodeVersionArg+"="+opts.ClaudeCodeVersion)
+	}
+	if opts.CapsuleVersion != "" {
+		args = append(args, "--label", constants.LabelVersion+"="+opts.CapsuleVersion)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	} else {
+		for _, image := range opts.CacheFrom {
+			args = append(args, "--cache-from", image)
+		}
+	}
+	return append(args, contextDir)
+}
+
+// runBuildWithProgress runs docker build with its output hidden behind the
+// indicator, which shows the current step. If the build fails, the last lines
+// of output are printed so the error is still visible.
+func runBuildWithProgress(cmd *exec.Cmd, indicator *progress.Indicator) error {
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		waitErr <- err
+	}()
+
+	var tail []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		tail = append(tail, line)
+		if len(tail) > buildOutputTailLines {
+			tail = tail[1:]
+		}
+		if step, total, instruction, ok := progress.ParseBuildStep(line); ok {
+			indicator.SetPercent(100 * float64(step-1) / float64(total))
+			indicator.SetDetail(fmt.Sprintf("[%d/%d] %s", step, total, instruction))
+		}
+	}
+	// Keep draining if a line was too long, so docker never blocks on the pipe
+	io.Copy(io.Discard, reader)
+
+	err := <-waitErr
+	if err != nil {
+		indicator.Done(err)
+		fmt.Fprintln(os.Stderr, strings.Join(tail, "\n"))
+	}
+	return err
+}
+
 // ImageExists checks if a Docker image exists locally.
 func ImageExists(imageName string) bool {
 	cmd := exec.Command("docker", "image", "inspect", imageName)
-	return cmd.Run() == nil
+	done := logging.Command(cmd)
+	err := cmd.Run()
+	done(err)
+	return err == nil
 }
@@ -0,0 +1,42 @@
+package embedded
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateBuildArg(t *testing.T) {
+	for _, arg := range []string{"GO_VERSION=1.24.1", "BASE_IMAGE=registry.internal/node:20", "EMPTY="} {
+		if err := ValidateBuildArg(arg); err != nil {
+			t.Errorf("ValidateBuildArg(%q) = %v", arg, err)
+		}
+	}
+	for _, arg := range []string{"GO_VERSION", "=1.24", "GO-VERSION=1", "1X=2"} {
+		if err := ValidateBuildArg(arg); err == nil {
+			t.Errorf("ValidateBuildArg(%q) succeeded, want error", arg)
+		}
+	}
+}
+
+func TestDockerBuildArgs(t *testing.T) {
+	opts := BuildOptions{
+		BuildArgs: []string{"GO_VERSION=1.24.1"},
+		CacheFrom: []string{"registry.internal/claude-capsule:latest"},
+
+		ClaudeCodeVersion: "1.0.58",
+		CapsuleVersion:    "0.3.0",
+	}
+	got := dockerBuildArgs("claude-capsule:latest", "/tmp/ctx", opts)
+	want := []string{"build", "-t", "claude-capsule:latest", "--build-arg", "BUILDKIT_INLINE_CACHE=1",
+		"--build-arg", "GO_VERSION=1.24.1", "--build-arg", "CLAUDE_CODE_VERSION=1.0.58", "--label", "capsule.version=0.3.0", "--cache-from", "registry.internal/claude-capsule:latest", "/tmp/ctx"}
+	if !slices.Equal(got, want) {
+		t.Errorf("dockerBuildArgs() = %q, want %q", got, want)
+	}
+
+	// --no-cache ignores cache sources
+	opts.NoCache = true
+	got = dockerBuildArgs("claude-capsule:latest", "/tmp/ctx", opts)
+	if !slices.Contains(got, "--no-cache") || slices.Contains(got, "--cache-from") {
+		t.Errorf("dockerBuildArgs() with NoCache = %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package embedded
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+// Flavor is a variant of the Docker image, trading build time for tools.
+type Flavor string
+
+const (
+	// FlavorSlim has only what capsule and Claude Code need, for quick sandboxes.
+	FlavorSlim Flavor = "slim"
+	// FlavorStandard adds gh, a Nerd Font, and the Starship prompt.
+	FlavorStandard Flavor = "standard"
+	// FlavorFull adds compilers, Go, and Python tooling on top of standard.
+	FlavorFull Flavor = "full"
+)
+
+// DefaultFlavor is used when neither a flag nor the config chooses one.
+const DefaultFlavor = FlavorStandard
+
+// Flavors lists the available image flavors.
+var Flavors = []Flavor{FlavorSlim, FlavorStandard, FlavorFull}
+
+//go:embed dockerfiles/*.Dockerfile
+var dockerfileFS embed.FS
+
+// ParseFlavor returns the flavor named s, ignoring case. An empty s is DefaultFlavor.
+func ParseFlavor(s string) (Flavor, error) {
+	if s == "" {
+		return DefaultFlavor, nil
+	}
+	for _, f := range Flavors {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown image flavor %q (use %s)", s, joinNames(Flavors))
+}
+
+// Dockerfile returns the Dockerfile for an image flavor with a template's
+// toolchain layers: the flavor's packages, the layers every flavor shares,
+// then the template's layers.
+func Dockerfile(f Flavor, t Template) []byte {
+	dockerfile := readDockerfile(dockerfileFS, "dockerfiles/"+string(f)+".Dockerfile")
+	dockerfile = append(dockerfile, '\n')
+	dockerfile = append(dockerfile, readDockerfile(dockerfileFS, "dockerfiles/common.Dockerfile")...)
+	if t != "" {
+		dockerfile = append(dockerfile, "\n# Template: "+string(t)+"\n"...)
+		dockerfile = append(dockerfile, readDockerfile(templateFS, "templates/"+string(t)+".Dockerfile")...)
+	}
+	return dockerfile
+}
+
+// readDockerfile returns a copy of an embedded Dockerfile part. The names
+// come from the Flavor and Template constants, so a missing part is a bug.
+func readDockerfile(fsys embed.FS, name string) []byte {
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("embedded %s is missing", name))
+	}
+	return data
+}
+
+// joinNames lists names for an error message, e.g. "slim, standard, full".
+func joinNames[T ~string](names []T) string {
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = string(n)
+	}
+	return strings.Join(parts, ", ")
+}
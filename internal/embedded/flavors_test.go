@@ -0,0 +1,48 @@
+package embedded
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDockerfile(t *testing.T) {
+	for _, flavor := range Flavors {
+		for _, tmpl := range append([]Template{""}, Templates...) {
+			dockerfile := string(Dockerfile(flavor, tmpl))
+			if !strings.Contains(dockerfile, "FROM ${"+BaseImageArg+"}") {
+				t.Errorf("%s/%q Dockerfile doesn't build on %s", flavor, tmpl, BaseImageArg)
+			}
+			if !strings.Contains(dockerfile, "/usr/local/bin/setup-workspace-symlink.sh") {
+				t.Errorf("%s/%q Dockerfile is missing the shared layers", flavor, tmpl)
+			}
+			// Template layers install as root; the container must still run as claude
+			users := linesWithPrefix(dockerfile, "USER ")
+			if len(users) == 0 || users[len(users)-1] != "USER claude" {
+				t.Errorf("%s/%q Dockerfile ends as %v, want USER claude", flavor, tmpl, users)
+			}
+		}
+	}
+}
+
+func TestParseFlavor(t *testing.T) {
+	if got, err := ParseFlavor("SLIM"); err != nil || got != FlavorSlim {
+		t.Errorf("ParseFlavor(SLIM) = %q, %v", got, err)
+	}
+	if got, err := ParseFlavor(""); err != nil || got != DefaultFlavor {
+		t.Errorf("ParseFlavor(\"\") = %q, %v; want %s", got, err, DefaultFlavor)
+	}
+	if _, err := ParseFlavor("huge"); err == nil {
+		t.Error("ParseFlavor(huge) succeeded, want error")
+	}
+}
+
+// linesWithPrefix returns the lines of s that start with prefix.
+func linesWithPrefix(s, prefix string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,91 @@
+package embedded
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// HelperImage is the image capsule runs its throwaway check and cache
+// refresh containers from. It holds only the capsule-helper binary.
+const HelperImage = "capsule-helper:latest"
+
+// helperBinaryPrefix names the embedded helper binaries, suffixed with the
+// architecture.
+const helperBinaryPrefix = "capsule-helper-linux-"
+
+// helperPath is where the helper binary is in the image.
+const helperPath = "capsule-helper"
+
+// helperHashLabel records which helper binary the image holds, so a capsule
+// with a different one imports it again.
+const helperHashLabel = "capsule.helper"
+
+// EnsureHelperImage returns the helper image, importing it from the embedded
+// helper binary if it is missing or holds another build of the helper. The
+// image is imported rather than built or pulled, so it needs no base image
+// and no network. A replaced image is left dangling for 'capsule gc'.
+func EnsureHelperImage(ctx context.Context) (string, error) {
+	binary, err := DoctoolBinaries.ReadFile("bin/" + helperBinaryPrefix + runtime.GOARCH)
+	if err != nil {
+		return "", fmt.Errorf("capsule-helper is not embedded in this build (build capsule with 'make build'): %w", err)
+	}
+	sum := sha256.Sum256(binary)
+	hash := hex.EncodeToString(sum[:6])
+
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", `{{index .Config.Labels "`+helperHashLabel+`"}}`, HelperImage)
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err == nil && strings.TrimSpace(string(output)) == hash {
+		return HelperImage, nil
+	}
+
+	archive, err := helperArchive(binary)
+	if err != nil {
+		return "", err
+	}
+	cmd = exec.CommandContext(ctx, "docker", "import",
+		"--change", `ENTRYPOINT ["/`+helperPath+`"]`,
+		"--change", "LABEL "+helperHashLabel+"="+hash,
+		"-", HelperImage)
+	cmd.Stdin = bytes.NewReader(archive)
+	done = logging.Command(cmd)
+	output, err = cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return "", fmt.Errorf("failed to import the helper image: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return HelperImage, nil
+}
+
+// helperArchive returns a tarball holding only the helper binary, the root
+// filesystem of the helper image.
+func helperArchive(binary []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	header := &tar.Header{
+		Name: helperPath,
+		Mode: int64(constants.ExecutablePermissions),
+		Size: int64(len(binary)),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("failed to write helper image: %w", err)
+	}
+	if _, err := tw.Write(binary); err != nil {
+		return nil, fmt.Errorf("failed to write helper image: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write helper image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
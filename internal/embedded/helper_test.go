@@ -0,0 +1,30 @@
+package embedded
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestHelperArchive(t *testing.T) {
+	archive, err := helperArchive([]byte("binary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(bytes.NewReader(archive))
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Name != helperPath || header.Mode != 0755 {
+		t.Errorf("entry = %s mode %o, want %s mode 755", header.Name, header.Mode, helperPath)
+	}
+	content, err := io.ReadAll(tr)
+	if err != nil || string(content) != "binary" {
+		t.Errorf("content = %q, %v", content, err)
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("archive has more than the helper: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// imageLabelFilters select images capsule built. Current builds carry the
+// capsule version label; older ones the Claude Code or only the maintainer
+// label. Helper images carry the helper's label.
+var imageLabelFilters = []string{
+	"label=" + constants.LabelVersion,
+	"label=" + helperHashLabel,
+	"label=" + ClaudeCodeVersionLabel,
+	"label=maintainer=jeanhaley32",
+}
+
+// DanglingImage is an untagged image left behind when a capsule image was rebuilt.
+type DanglingImage struct {
+	ID      string
+	Created string // e.g. "3 weeks ago"
+	Size    string // As docker reports it, e.g. "1.2GB"
+}
+
+// DanglingImages returns untagged images capsule built.
+func DanglingImages(ctx context.Context) ([]DanglingImage, error) {
+	seen := make(map[string]bool)
+	var images []DanglingImage
+	for _, label := range imageLabelFilters {
+		cmd := exec.CommandContext(ctx, "docker", "images", "--filter", "dangling=true",
+			"--filter", label, "--format", "{{.ID}}\t{{.CreatedSince}}\t{{.Size}}")
+		done := logging.Command(cmd)
+		output, err := cmd.Output()
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images: %w", err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Split(strings.TrimSpace(line), "\t")
+			if len(fields) != 3 || seen[fields[0]] {
+				continue
+			}
+			seen[fields[0]] = true
+			images = append(images, DanglingImage{ID: fields[0], Created: fields[1], Size: fields[2]})
+		}
+	}
+	return images, nil
+}
+
+// RemoveImage deletes a local image by ID. It fails if a container still uses it.
+func RemoveImage(ctx context.Context, id string) error {
+	cmd := exec.CommandContext(ctx, "docker", "image", "rm", id)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to remove image %s: %w: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
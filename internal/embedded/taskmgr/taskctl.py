@@ -14,13 +14,37 @@ Usage:
 import subprocess
 import sys
 import re
+import json
 import argparse
 from datetime import datetime
 from pathlib import Path
 
-# Import DocMemory from doc-sync skill
-sys.path.insert(0, str(Path.home() / ".claude/skills/doc-sync"))
-from doctool import DocMemory
+# doc-sync's doctool binary owns the memory database
+DOCTOOL = str(Path.home() / ".claude/skills/doc-sync/doctool")
+
+
+class DocMemory:
+    """Thin wrapper over `doctool memory` subcommands."""
+
+    def add(self, content, tags, source=None, chunk_type="note"):
+        cmd = [DOCTOOL, "memory", "add", "--tags", ",".join(tags), "--type", chunk_type]
+        if source:
+            cmd += ["--source", source]
+        result = subprocess.run(cmd, input=content, capture_output=True, text=True)
+        if result.returncode != 0:
+            print(f"Warning: memory add failed: {result.stderr.strip()}", file=sys.stderr)
+            return False
+        return True
+
+    def search(self, query, tags=None, limit=10):
+        cmd = [DOCTOOL, "memory", "search", query, "--limit", str(limit), "--json"]
+        if tags:
+            cmd += ["--tags", ",".join(tags)]
+        result = subprocess.run(cmd, capture_output=True, text=True)
+        if result.returncode != 0:
+            print(f"Warning: memory search failed: {result.stderr.strip()}", file=sys.stderr)
+            return []
+        return json.loads(result.stdout or "[]")
 
 
 def run_bd(*args):
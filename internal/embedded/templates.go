@@ -0,0 +1,87 @@
+package embedded
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Template is a language preset chosen at bootstrap. It adds toolchains to the
+// Docker image and guidance to CLAUDE.md. The empty Template is the plain image.
+type Template string
+
+const (
+	TemplateGo     Template = "go"
+	TemplateNode   Template = "node"
+	TemplatePython Template = "python"
+	TemplateML     Template = "ml"
+)
+
+// Templates lists the available presets.
+var Templates = []Template{TemplateGo, TemplateNode, TemplatePython, TemplateML}
+
+// TemplateFile is the path within the encrypted volume that records the
+// volume's template, so start knows which image to use.
+const TemplateFile = "config/template"
+
+//go:embed templates/*.Dockerfile templates/*.md
+var templateFS embed.FS
+
+// ParseTemplate returns the template named s, ignoring case. An empty s is
+// the plain image.
+func ParseTemplate(s string) (Template, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, t := range Templates {
+		if strings.EqualFold(s, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown template %q (use %s)", s, joinNames(Templates))
+}
+
+// ClaudeMD returns the template's section for CLAUDE.md, or "" for the plain image.
+func (t Template) ClaudeMD() string {
+	if t == "" {
+		return ""
+	}
+	guidance, err := templateFS.ReadFile("templates/" + string(t) + ".md")
+	if err != nil {
+		panic(fmt.Sprintf("embedded template %s has no CLAUDE.md guidance", t))
+	}
+	return string(guidance)
+}
+
+// WriteTemplateFile records the volume's template. Nothing is written for the plain image.
+func WriteTemplateFile(mountPoint string, t Template) error {
+	if t == "" {
+		return nil
+	}
+	path := filepath.Join(mountPoint, TemplateFile)
+	if err := os.WriteFile(path, []byte(string(t)+"\n"), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
+
+// ReadTemplateFile returns the template the volume mounted at mountPoint was
+// bootstrapped with. Volumes without one use the plain image.
+func ReadTemplateFile(mountPoint string) (Template, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, TemplateFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	t, err := ParseTemplate(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s in volume: %w", TemplateFile, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,48 @@
+package embedded
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	for _, tmpl := range Templates {
+		if tmpl.ClaudeMD() == "" {
+			t.Errorf("%s has no CLAUDE.md guidance", tmpl)
+		}
+	}
+	var plain Template
+	if plain.ClaudeMD() != "" {
+		t.Error("the empty template should add no CLAUDE.md guidance")
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	if got, err := ParseTemplate("Go"); err != nil || got != TemplateGo {
+		t.Errorf("ParseTemplate(Go) = %q, %v", got, err)
+	}
+	if got, err := ParseTemplate(""); err != nil || got != "" {
+		t.Errorf("ParseTemplate(\"\") = %q, %v", got, err)
+	}
+	if _, err := ParseTemplate("rust"); err == nil {
+		t.Error("ParseTemplate(rust) succeeded, want error")
+	}
+}
+
+func TestTemplateFile(t *testing.T) {
+	mountPoint := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mountPoint, "config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ReadTemplateFile(mountPoint); err != nil || got != "" {
+		t.Errorf("ReadTemplateFile without a file = %q, %v; want plain image", got, err)
+	}
+	if err := WriteTemplateFile(mountPoint, TemplatePython); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadTemplateFile(mountPoint); err != nil || got != TemplatePython {
+		t.Errorf("ReadTemplateFile = %q, %v; want python", got, err)
+	}
+}
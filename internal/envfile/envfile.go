@@ -0,0 +1,99 @@
+package envfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VolumePrefix marks an env file stored inside the encrypted volume,
+// e.g. volume:work.env reads <mount>/config/env/work.env.
+const VolumePrefix = "volume:"
+
+// VolumeDir is where env files are kept inside the volume, relative to the volume root.
+const VolumeDir = "config/env"
+
+// namePattern matches valid environment variable names.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseAssignment validates a KEY=VALUE entry. A bare KEY takes its value from
+// the host environment and is dropped if unset, matching docker's -e.
+func ParseAssignment(entry string) (string, bool, error) {
+	name, value, hasValue := strings.Cut(entry, "=")
+	if !namePattern.MatchString(name) {
+		return "", false, fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if hasValue {
+		return name + "=" + value, true, nil
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return name + "=" + value, true, nil
+	}
+	return "", false, nil
+}
+
+// Parse reads KEY=VALUE lines in docker's --env-file format: blank lines and
+// lines starting with # are ignored, and values are taken literally.
+func Parse(r io.Reader) ([]string, error) {
+	var env []string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, ok, err := ParseAssignment(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if ok {
+			env = append(env, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// IsVolumePath reports whether path refers to an env file inside the volume.
+func IsVolumePath(path string) bool {
+	return strings.HasPrefix(path, VolumePrefix)
+}
+
+// Resolve returns the host path of an env file. Volume paths are resolved
+// under mountPoint and may not escape VolumeDir.
+func Resolve(path, mountPoint string) (string, error) {
+	if !IsVolumePath(path) {
+		return path, nil
+	}
+	name := strings.TrimPrefix(path, VolumePrefix)
+	clean := filepath.Clean(name)
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid volume env file %q: must be a path under %s", name, VolumeDir)
+	}
+	return filepath.Join(mountPoint, VolumeDir, clean), nil
+}
+
+// ParseFile reads an env file, resolving volume paths under mountPoint.
+func ParseFile(path, mountPoint string) ([]string, error) {
+	hostPath, err := Resolve(path, mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	env, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return env, nil
+}
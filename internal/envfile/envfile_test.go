@@ -0,0 +1,67 @@
+package envfile
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("CAPSULE_TEST_HOST", "from-host")
+
+	input := `# comment
+NODE_ENV=development
+
+  DATABASE_URL=postgres://localhost/db?sslmode=disable
+QUOTED="kept literally"
+EMPTY=
+CAPSULE_TEST_HOST
+CAPSULE_TEST_UNSET
+`
+	env, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []string{
+		"NODE_ENV=development",
+		"DATABASE_URL=postgres://localhost/db?sslmode=disable",
+		`QUOTED="kept literally"`,
+		"EMPTY=",
+		"CAPSULE_TEST_HOST=from-host",
+	}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Parse() = %q, want %q", env, want)
+	}
+
+	if _, err := Parse(strings.NewReader("OK=1\nBAD-NAME=2\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Parse() with invalid name error = %v, want line 2 error", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	mountPoint := "/Volumes/Capsule-abc"
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{".capsule.env", ".capsule.env", false},
+		{"volume:work.env", filepath.Join(mountPoint, VolumeDir, "work.env"), false},
+		{"volume:clients/acme.env", filepath.Join(mountPoint, VolumeDir, "clients/acme.env"), false},
+		{"volume:../auth/api-key", "", true},
+		{"volume:/etc/passwd", "", true},
+		{"volume:", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Resolve(tt.path, mountPoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Package envmanifest describes a capsule environment in YAML so the same
+// environment can be bootstrapped on another machine: the volume's size,
+// cipher, filesystem, template, and context files, the skills installed in
+// it, and the portable settings in ~/.capsule/config.json.
+//
+// It is unrelated to package manifest, which signs a volume's layout to
+// detect tampering.
+package envmanifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// format is the version of the manifest layout.
+const format = 1
+
+// SkillsDir is where skills are installed in the volume.
+const SkillsDir = "home/.claude/skills"
+
+// PortableSettings are the config.json keys a manifest carries: those that
+// mean the same on another machine and hold no secrets. Build arguments,
+// which often carry tokens, and host paths such as mount_dir, ca_certs,
+// dotfiles, volumes, and identity_volume stay behind.
+var PortableSettings = []string{
+	"low_space_percent",
+	"auto_expand_max_gb",
+	"image_flavor",
+	"base_image",
+	"claude_code_version",
+	"cache_from",
+	"no_host_proxy",
+	"dns",
+	"dns_search",
+	"record_sessions",
+	"clipboard",
+	"no_browser_bridge",
+	"restart",
+	"keep_alive",
+	"stale_volume_months",
+}
+
+// Manifest describes an environment.
+type Manifest struct {
+	Format         int      `yaml:"format"`
+	CapsuleVersion string   `yaml:"capsule_version,omitempty"`
+	Volume         Volume   `yaml:"volume"`
+	Skills         []string `yaml:"skills,omitempty"`
+
+	// Settings holds the PortableSettings of config.json as they are
+	// written, so its keys match the file's.
+	Settings map[string]any `yaml:"settings,omitempty"`
+}
+
+// Volume describes how the volume is bootstrapped. Empty fields take
+// bootstrap's defaults.
+type Volume struct {
+	SizeGB       int                `yaml:"size_gb"`
+	Encryption   volume.Encryption  `yaml:"encryption,omitempty"`
+	Filesystem   volume.Filesystem  `yaml:"filesystem,omitempty"`
+	Format       volume.ImageFormat `yaml:"format,omitempty"`
+	Template     embedded.Template  `yaml:"template,omitempty"`
+	Argon2       bool               `yaml:"argon2,omitempty"`
+	ContextFiles []string           `yaml:"context_files,omitempty"`
+}
+
+// New returns a manifest with the given volume, skills, and the portable
+// ones of settings.
+func New(version string, v Volume, skills []string, settings *config.Settings) (*Manifest, error) {
+	m := &Manifest{Format: format, CapsuleVersion: version, Volume: v, Skills: skills}
+	all, err := settingsMap(settings)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range all {
+		if slices.Contains(PortableSettings, key) {
+			if m.Settings == nil {
+				m.Settings = make(map[string]any)
+			}
+			m.Settings[key] = value
+		}
+	}
+	return m, nil
+}
+
+// settingsMap returns settings as config.json holds them.
+func settingsMap(settings *config.Settings) (map[string]any, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode settings: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode settings: %w", err)
+	}
+	return m, nil
+}
+
+// Parse reads and checks a manifest.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Format == 0 || m.Format > format {
+		return nil, fmt.Errorf("unsupported manifest format %d (this capsule reads format %d)", m.Format, format)
+	}
+	v := m.Volume
+	if v.SizeGB < constants.MinVolumeSizeGB || v.SizeGB > constants.MaxVolumeSizeGB {
+		return nil, fmt.Errorf("volume.size_gb must be between %d and %d, got %d", constants.MinVolumeSizeGB, constants.MaxVolumeSizeGB, v.SizeGB)
+	}
+	if v.Encryption != "" {
+		if _, err := volume.ParseEncryption(string(v.Encryption)); err != nil {
+			return nil, fmt.Errorf("volume.encryption: %w", err)
+		}
+	}
+	if v.Filesystem != "" {
+		if _, err := volume.ParseFilesystem(string(v.Filesystem)); err != nil {
+			return nil, fmt.Errorf("volume.filesystem: %w", err)
+		}
+	}
+	if v.Format != "" {
+		if _, err := volume.ParseImageFormat(string(v.Format)); err != nil {
+			return nil, fmt.Errorf("volume.format: %w", err)
+		}
+	}
+	if _, err := embedded.ParseTemplate(string(v.Template)); err != nil {
+		return nil, fmt.Errorf("volume.template: %w", err)
+	}
+	if _, err := m.DecodeSettings(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Marshal returns the manifest as YAML.
+func (m *Manifest) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeSettings returns the manifest's settings as config.json would load
+// them.
+func (m *Manifest) DecodeSettings() (*config.Settings, error) {
+	return decodeSettings(m.Settings)
+}
+
+// MergeSettings returns current with its portable settings replaced by the
+// manifest's. The rest, such as build arguments and host paths, are kept.
+// Keys a hand-edited manifest adds outside PortableSettings are ignored.
+func (m *Manifest) MergeSettings(current *config.Settings) (*config.Settings, error) {
+	merged, err := settingsMap(current)
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = make(map[string]any)
+	}
+	for _, key := range PortableSettings {
+		delete(merged, key)
+		if value, ok := m.Settings[key]; ok {
+			merged[key] = value
+		}
+	}
+	return decodeSettings(merged)
+}
+
+func decodeSettings(values map[string]any) (*config.Settings, error) {
+	settings := &config.Settings{}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ImagePath returns volumePath with the extension of the manifest's image
+// format.
+func (m *Manifest) ImagePath(volumePath string) string {
+	if m.Volume.Format == "" {
+		return volume.WithFormat(volumePath, volume.DefaultImageFormat)
+	}
+	return volume.WithFormat(volumePath, m.Volume.Format)
+}
+
+// BootstrapConfig returns the bootstrap configuration the manifest describes
+// for a volume at volumePath, in the format its extension names. Context
+// files that don't exist on this machine are left out and returned as
+// missing.
+func (m *Manifest) BootstrapConfig(volumePath string) (cfg volume.BootstrapConfig, missing []string) {
+	cfg = volume.BootstrapConfig{
+		VolumePath: volumePath,
+		SizeGB:     m.Volume.SizeGB,
+		StretchKey: m.Volume.Argon2,
+		Encryption: m.Volume.Encryption,
+		Filesystem: m.Volume.Filesystem,
+		Format:     volume.FormatOf(volumePath),
+		Template:   m.Volume.Template,
+	}
+	for _, path := range m.Volume.ContextFiles {
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, path)
+			continue
+		}
+		cfg.ContextFiles = append(cfg.ContextFiles, path)
+	}
+	return cfg, missing
+}
+
+// ListSkills returns the names of the skills installed in the volume mounted
+// at mountPoint, sorted.
+func ListSkills(mountPoint string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(mountPoint, SkillsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skills: %w", err)
+	}
+	var skills []string
+	for _, e := range entries {
+		if e.IsDir() {
+			skills = append(skills, e.Name())
+		}
+	}
+	slices.Sort(skills)
+	return skills, nil
+}
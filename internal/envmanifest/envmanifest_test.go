@@ -0,0 +1,149 @@
+package envmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+func TestRoundTrip(t *testing.T) {
+	settings := &config.Settings{ImageFlavor: "slim", DNS: []string{"10.0.0.2"}, LowSpacePercent: 15}
+	v := Volume{SizeGB: 4, Encryption: volume.EncryptionAES128, Template: "go", Argon2: true}
+	m, err := New("1.2.0", v, []string{"doc-sync", "task-mgr"}, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "image_flavor: slim") {
+		t.Errorf("Marshal() does not use config.json's keys:\n%s", data)
+	}
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Volume.SizeGB != 4 || got.Volume.Encryption != volume.EncryptionAES128 || got.Volume.Template != "go" || !got.Volume.Argon2 {
+		t.Errorf("Parse() volume = %+v", got.Volume)
+	}
+	if !slices.Equal(got.Skills, m.Skills) {
+		t.Errorf("Parse() skills = %v, want %v", got.Skills, m.Skills)
+	}
+	decoded, err := got.DecodeSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ImageFlavor != "slim" || decoded.LowSpacePercent != 15 || !slices.Equal(decoded.DNS, settings.DNS) {
+		t.Errorf("DecodeSettings() = %+v", decoded)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"volume: {size_gb: 2}",
+		"format: 2\nvolume: {size_gb: 2}",
+		"format: 1\nvolume: {size_gb: 0}",
+		"format: 1\nvolume: {size_gb: 2, encryption: des}",
+		"format: 1\nvolume: {size_gb: 2, template: cobol}",
+		"format: 1\nvolume: {size_gb: 2, format: dmg}",
+		"format: 1\nvolume: {size_gb: 2}\nsettings: {low_space_percent: 100}",
+		"format: [",
+	}
+	for _, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", data)
+		}
+	}
+}
+
+func TestBootstrapConfig(t *testing.T) {
+	present := filepath.Join(t.TempDir(), "CONTEXT.md")
+	if err := os.WriteFile(present, []byte("# context\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	absent := filepath.Join(t.TempDir(), "MISSING.md")
+	m := &Manifest{Format: format, Volume: Volume{SizeGB: 2, Argon2: true, ContextFiles: []string{present, absent}}}
+
+	cfg, missing := m.BootstrapConfig("/vol/capsule.sparseimage")
+	if cfg.VolumePath != "/vol/capsule.sparseimage" || cfg.SizeGB != 2 || !cfg.StretchKey {
+		t.Errorf("BootstrapConfig() = %+v", cfg)
+	}
+	if !slices.Equal(cfg.ContextFiles, []string{present}) || !slices.Equal(missing, []string{absent}) {
+		t.Errorf("BootstrapConfig() context files = %v, missing %v", cfg.ContextFiles, missing)
+	}
+
+	m.Volume.Format = volume.FormatSparseBundle
+	path := m.ImagePath("/vol/capsule.sparseimage")
+	if cfg, _ := m.BootstrapConfig(path); path != "/vol/capsule.sparsebundle" || cfg.Format != volume.FormatSparseBundle {
+		t.Errorf("BootstrapConfig(ImagePath()) = %s, %s; want a sparse bundle", path, cfg.Format)
+	}
+}
+
+func TestListSkills(t *testing.T) {
+	mountPoint := t.TempDir()
+	if skills, err := ListSkills(mountPoint); err != nil || skills != nil {
+		t.Errorf("ListSkills() without a skills directory = %v, %v", skills, err)
+	}
+	for _, name := range []string{"task-mgr", "doc-sync"} {
+		if err := os.MkdirAll(filepath.Join(mountPoint, SkillsDir, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(mountPoint, SkillsDir, "README.md"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	skills, err := ListSkills(mountPoint)
+	if err != nil || !slices.Equal(skills, []string{"doc-sync", "task-mgr"}) {
+		t.Errorf("ListSkills() = %v, %v", skills, err)
+	}
+}
+
+func TestSettingsLeaveSecretsBehind(t *testing.T) {
+	settings := &config.Settings{
+		ImageFlavor: "slim",
+		BuildArgs:   map[string]string{"NPM_TOKEN": "npm_s3cr3t"},
+		MountDir:    "/Users/alice/mounts",
+		Volumes:     map[string]string{"work": "/Users/alice/work.sparseimage"},
+	}
+	m, err := New("1.2.0", Volume{SizeGB: 2}, nil, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"npm_s3cr3t", "NPM_TOKEN", "build_args", "/Users/alice"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("Marshal() contains %q:\n%s", leaked, data)
+		}
+	}
+	if !strings.Contains(string(data), "image_flavor: slim") {
+		t.Errorf("Marshal() lost a portable setting:\n%s", data)
+	}
+
+	// Applying keeps the target machine's own build arguments and paths
+	current := &config.Settings{
+		ImageFlavor: "full",
+		Restart:     "unless-stopped",
+		BuildArgs:   map[string]string{"NPM_TOKEN": "theirs"},
+		MountDir:    "/Users/bob/mounts",
+	}
+	merged, err := m.MergeSettings(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.ImageFlavor != "slim" || merged.Restart != "" {
+		t.Errorf("MergeSettings() = %+v, want the manifest's portable settings", merged)
+	}
+	if merged.BuildArgs["NPM_TOKEN"] != "theirs" || merged.MountDir != "/Users/bob/mounts" {
+		t.Errorf("MergeSettings() = %+v, want the current build args and mount dir", merged)
+	}
+}
@@ -0,0 +1,113 @@
+package gitidentity
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/transfer"
+)
+
+// Paths inside the encrypted volume.
+const (
+	// GitConfigFile is the container user's global git config, relative to the volume root.
+	GitConfigFile = "home/.gitconfig"
+
+	// CredentialsFile stores credentials saved by git's store helper, relative to the volume root.
+	CredentialsFile = "auth/git-credentials"
+
+	// containerVolumeRoot is where the volume is mounted inside the container.
+	containerVolumeRoot = "/claude-env"
+)
+
+// Identity is the commit author identity copied from the host.
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// HostIdentity reads user.name and user.email as git resolves them for
+// workspacePath, so repository-level overrides are honored.
+func HostIdentity(workspacePath string) (Identity, error) {
+	name, err := gitConfigGet(workspacePath, "user.name")
+	if err != nil {
+		return Identity{}, err
+	}
+	email, err := gitConfigGet(workspacePath, "user.email")
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Name: name, Email: email}, nil
+}
+
+// HostSigningKey returns the OpenPGP user.signingkey configured for workspacePath.
+func HostSigningKey(workspacePath string) (string, error) {
+	if format, _ := gitConfigGet(workspacePath, "gpg.format"); format != "" && format != "openpgp" {
+		return "", fmt.Errorf("gpg.format %q is not supported; only OpenPGP signing can be proxied", format)
+	}
+	return gitConfigGet(workspacePath, "user.signingkey")
+}
+
+// HostGPGProgram returns the host's gpg.program, or "gpg" if unset.
+func HostGPGProgram(workspacePath string) string {
+	if program, err := gitConfigGet(workspacePath, "gpg.program"); err == nil {
+		return program
+	}
+	return "gpg"
+}
+
+// Configure sets the identity and a credential helper backed by the volume in
+// the container user's git config. Other settings in the file are kept.
+func Configure(mountPoint string, id Identity) error {
+	configPath := filepath.Join(mountPoint, GitConfigFile)
+	// The container can write to the volume; git config and chmod would
+	// follow a link it left there to a file on the host
+	for _, path := range []string{configPath, filepath.Join(mountPoint, CredentialsFile)} {
+		if err := transfer.CheckParents(mountPoint, path); err != nil {
+			return err
+		}
+	}
+	if info, err := os.Lstat(configPath); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("refusing to write %s: not a regular file", configPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(configPath), err)
+	}
+	if err := os.MkdirAll(filepath.Join(mountPoint, filepath.Dir(CredentialsFile)), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	helper := "store --file=" + containerVolumeRoot + "/" + CredentialsFile
+	settings := [][2]string{
+		{"user.name", id.Name},
+		{"user.email", id.Email},
+		{"credential.helper", helper},
+	}
+	for _, kv := range settings {
+		cmd := exec.Command("git", "config", "--file", configPath, kv[0], kv[1])
+		done := logging.Command(cmd)
+		output, err := cmd.CombinedOutput()
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to set %s: %w: %s", kv[0], err, strings.TrimSpace(string(output)))
+		}
+	}
+	return os.Chmod(configPath, constants.PublicFilePermissions)
+}
+
+// gitConfigGet returns a required git config value for dir.
+func gitConfigGet(dir, key string) (string, error) {
+	cmd := exec.Command("git", "-C", dir, "config", "--get", key)
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	value := strings.TrimSpace(string(output))
+	if err != nil || value == "" {
+		return "", fmt.Errorf("%s is not set in your git config (set it with: git config --global %s ...)", key, key)
+	}
+	return value, nil
+}
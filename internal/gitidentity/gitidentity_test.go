@@ -0,0 +1,103 @@
+package gitidentity
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	workspace := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", workspace},
+		{"-C", workspace, "config", "user.name", "Jane Doe"},
+		{"-C", workspace, "config", "user.email", "jane@example.com"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	id, err := HostIdentity(workspace)
+	if err != nil {
+		t.Fatalf("HostIdentity() error = %v", err)
+	}
+	if id.Name != "Jane Doe" || id.Email != "jane@example.com" {
+		t.Fatalf("HostIdentity() = %+v", id)
+	}
+
+	mountPoint := t.TempDir()
+	configPath := filepath.Join(mountPoint, GitConfigFile)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "config", "--file", configPath, "core.editor", "vim").CombinedOutput(); err != nil {
+		t.Fatalf("git config: %v\n%s", err, out)
+	}
+	if err := Configure(mountPoint, id); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	want := map[string]string{
+		"user.name":         "Jane Doe",
+		"user.email":        "jane@example.com",
+		"credential.helper": "store --file=/claude-env/auth/git-credentials",
+		"core.editor":       "vim",
+	}
+	for key, value := range want {
+		out, err := exec.Command("git", "config", "--file", configPath, "--get", key).Output()
+		if err != nil {
+			t.Fatalf("git config --get %s: %v", key, err)
+		}
+		if got := strings.TrimSpace(string(out)); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestConfigureRefusesSymlinks(t *testing.T) {
+	id := Identity{Name: "Jane Doe", Email: "jane@example.com"}
+	outside := t.TempDir()
+	hostFile := filepath.Join(outside, "config")
+	if err := os.WriteFile(hostFile, []byte("[core]\n\teditor = vim\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session replaced .gitconfig with a link to a host file
+	mountPoint := t.TempDir()
+	configPath := filepath.Join(mountPoint, GitConfigFile)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(hostFile, configPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(mountPoint, id); err == nil {
+		t.Error("Configure() wrote through a symlinked .gitconfig")
+	}
+
+	// Or linked the home directory somewhere else
+	mountPoint = t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(mountPoint, filepath.Dir(GitConfigFile))); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(mountPoint, id); err == nil {
+		t.Error("Configure() wrote through a symlinked home directory")
+	}
+
+	if data, _ := os.ReadFile(hostFile); string(data) != "[core]\n\teditor = vim\n" {
+		t.Errorf("host file = %q, want it untouched", data)
+	}
+	if info, _ := os.Stat(hostFile); info.Mode().Perm() != 0600 {
+		t.Errorf("host file mode = %o, want 600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(outside, ".gitconfig")); !os.IsNotExist(err) {
+		t.Errorf("Configure() created a file through the link: %v", err)
+	}
+}
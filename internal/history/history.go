@@ -0,0 +1,293 @@
+// Package history records capsule sessions in ~/.capsule/history.jsonl so time
+// spent in each workspace can be reviewed and usage audited.
+//
+// The file is append-only JSON lines. A session writes a start event when its
+// shell opens and an end event when it exits; a session with no end event was
+// interrupted, or is still running.
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// FileName is the history file under the capsule config directory.
+const FileName = "history.jsonl"
+
+// Event types in the history file.
+const (
+	EventStart = "start"
+	EventEnd   = "end"
+)
+
+// Session statuses.
+const (
+	StatusCompleted   = "completed"   // Shell exited normally
+	StatusFailed      = "failed"      // Shell exited with an error
+	StatusRunning     = "running"     // No end yet and the container is still up
+	StatusInterrupted = "interrupted" // No end was recorded
+)
+
+// Event is one line of the history file.
+type Event struct {
+	Event      string    `json:"event"`
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Workspaces []string  `json:"workspaces,omitempty"`
+	RepoIDs    []string  `json:"repo_ids,omitempty"`
+	Container  string    `json:"container,omitempty"`
+	Untrusted  bool      `json:"untrusted,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+}
+
+// Session is a start event joined with its end event, if any.
+type Session struct {
+	ID         string    `json:"id"`
+	Workspaces []string  `json:"workspaces"`
+	RepoIDs    []string  `json:"repo_ids,omitempty"`
+	Container  string    `json:"container"`
+	Untrusted  bool      `json:"untrusted,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end,omitzero"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+	Status     string    `json:"status"`
+}
+
+// Duration returns how long the session lasted, or has lasted so far if it is
+// running. Interrupted sessions have no known duration.
+func (s Session) Duration(now time.Time) time.Duration {
+	switch {
+	case !s.End.IsZero():
+		return s.End.Sub(s.Start)
+	case s.Status == StatusRunning:
+		return now.Sub(s.Start)
+	default:
+		return 0
+	}
+}
+
+// DefaultPath returns ~/.capsule/history.jsonl.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, FileName), nil
+}
+
+// NewID returns a random session ID.
+func NewID() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Append writes an event to the history file, creating it if needed.
+func Append(path string, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode history event: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, constants.FilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	// One write per line keeps concurrent sessions from interleaving
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads the history file and joins events into sessions, oldest first.
+// Sessions without an end event get StatusInterrupted; isRunning, if non-nil,
+// is asked whether their container is still up. A missing file is an empty
+// history, and malformed lines are skipped.
+func Load(path string, isRunning func(container string) bool) ([]Session, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	byID := make(map[string]*Session)
+	var order []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == "" {
+			continue
+		}
+		switch e.Event {
+		case EventStart:
+			if _, ok := byID[e.ID]; ok {
+				continue
+			}
+			byID[e.ID] = &Session{
+				ID:         e.ID,
+				Workspaces: e.Workspaces,
+				RepoIDs:    e.RepoIDs,
+				Container:  e.Container,
+				Untrusted:  e.Untrusted,
+				Start:      e.Time,
+			}
+			order = append(order, e.ID)
+		case EventEnd:
+			if s, ok := byID[e.ID]; ok {
+				s.End = e.Time
+				s.ExitCode = e.ExitCode
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	sessions := make([]Session, 0, len(order))
+	for _, id := range order {
+		s := byID[id]
+		switch {
+		case !s.End.IsZero() && (s.ExitCode == nil || *s.ExitCode == 0):
+			s.Status = StatusCompleted
+		case !s.End.IsZero():
+			s.Status = StatusFailed
+		case isRunning != nil && isRunning(s.Container):
+			s.Status = StatusRunning
+		default:
+			s.Status = StatusInterrupted
+		}
+		sessions = append(sessions, *s)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+
+	// Only the latest session on a container can still be running
+	latest := make(map[string]int)
+	for i, s := range sessions {
+		latest[s.Container] = i
+	}
+	for i := range sessions {
+		if sessions[i].Status == StatusRunning && latest[sessions[i].Container] != i {
+			sessions[i].Status = StatusInterrupted
+		}
+	}
+	return sessions, nil
+}
+
+// Filter selects sessions. Zero fields match everything.
+type Filter struct {
+	Since     time.Time
+	Workspace string // Sessions with a workspace at or under this path
+	Container string
+	Status    string
+	Limit     int // Keep only the most recent Limit sessions
+}
+
+// Apply returns the sessions that match, keeping their order.
+func (f Filter) Apply(sessions []Session) []Session {
+	var matched []Session
+	for _, s := range sessions {
+		if !f.Since.IsZero() && s.Start.Before(f.Since) {
+			continue
+		}
+		if f.Container != "" && s.Container != f.Container {
+			continue
+		}
+		if f.Status != "" && s.Status != f.Status {
+			continue
+		}
+		if f.Workspace != "" && !hasWorkspaceUnder(s.Workspaces, f.Workspace) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return matched
+}
+
+// hasWorkspaceUnder reports whether any workspace is dir or inside it.
+func hasWorkspaceUnder(workspaces []string, dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, w := range workspaces {
+		w = filepath.Clean(w)
+		if w == dir || strings.HasPrefix(w, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AbandonedRepoIDs returns the repo IDs whose recorded workspaces no longer
+// exist, according to exists. A repo ID with any surviving workspace, or one
+// history never recorded, is not abandoned.
+func AbandonedRepoIDs(sessions []Session, exists func(path string) bool) []string {
+	alive := make(map[string]bool)
+	for _, s := range sessions {
+		for i, id := range s.RepoIDs {
+			if i >= len(s.Workspaces) {
+				// Without its workspace the ID can't be checked, so keep it
+				alive[id] = true
+				continue
+			}
+			if _, ok := alive[id]; !ok {
+				alive[id] = false
+			}
+			if !alive[id] && exists(s.Workspaces[i]) {
+				alive[id] = true
+			}
+		}
+	}
+
+	var abandoned []string
+	for id, ok := range alive {
+		if !ok {
+			abandoned = append(abandoned, id)
+		}
+	}
+	sort.Strings(abandoned)
+	return abandoned
+}
+
+// ParseSince parses a --since value: a duration back from now ("36h", "7d",
+// "2w") or a date ("2026-01-31").
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(value); n > 1 {
+		if multiple, ok := unit[value[n-1]]; ok {
+			count, err := strconv.Atoi(value[:n-1])
+			if err == nil && count >= 0 {
+				return now.Add(-time.Duration(count) * multiple), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since value %q: use a duration like 36h, 7d, or 2w, or a date like 2026-01-31", value)
+}
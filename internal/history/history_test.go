@@ -0,0 +1,143 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	events := []Event{
+		{Event: EventStart, ID: "a", Time: base, Workspaces: []string{"/src/api"}, Container: "claude-api"},
+		{Event: EventEnd, ID: "a", Time: base.Add(time.Hour), ExitCode: intPtr(0)},
+		{Event: EventStart, ID: "b", Time: base.Add(2 * time.Hour), Workspaces: []string{"/src/web"}, Container: "claude-web"},
+		{Event: EventEnd, ID: "b", Time: base.Add(3 * time.Hour), ExitCode: intPtr(2)},
+		{Event: EventStart, ID: "c", Time: base.Add(4 * time.Hour), Workspaces: []string{"/src/api"}, Container: "claude-api"},
+		{Event: EventStart, ID: "d", Time: base.Add(5 * time.Hour), Workspaces: []string{"/src/api"}, Container: "claude-api"},
+	}
+	for _, e := range events {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	// A malformed line is skipped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("not json\n")
+	f.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("history file mode = %o, want 600", info.Mode().Perm())
+	}
+
+	running := func(container string) bool { return container == "claude-api" }
+	sessions, err := Load(path, running)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(sessions) != 4 {
+		t.Fatalf("Load() returned %d sessions, want 4", len(sessions))
+	}
+
+	want := map[string]string{"a": StatusCompleted, "b": StatusFailed, "c": StatusInterrupted, "d": StatusRunning}
+	for _, s := range sessions {
+		if s.Status != want[s.ID] {
+			t.Errorf("session %s status = %s, want %s", s.ID, s.Status, want[s.ID])
+		}
+	}
+	if d := sessions[0].Duration(base); d != time.Hour {
+		t.Errorf("Duration() = %v, want 1h", d)
+	}
+	if d := sessions[3].Duration(base.Add(6 * time.Hour)); d != time.Hour {
+		t.Errorf("running Duration() = %v, want 1h", d)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	sessions, err := Load(filepath.Join(t.TempDir(), FileName), nil)
+	if err != nil || sessions != nil {
+		t.Errorf("Load(missing) = %v, %v", sessions, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	sessions := []Session{
+		{ID: "1", Workspaces: []string{"/src/api"}, Container: "claude-api", Start: base, Status: StatusCompleted},
+		{ID: "2", Workspaces: []string{"/src/api/sub"}, Container: "claude-sub", Start: base.Add(time.Hour), Status: StatusFailed},
+		{ID: "3", Workspaces: []string{"/src/apiary"}, Container: "claude-apiary", Start: base.Add(2 * time.Hour), Status: StatusCompleted},
+	}
+
+	ids := func(ss []Session) string {
+		var out string
+		for _, s := range ss {
+			out += s.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{}, "123"},
+		{Filter{Workspace: "/src/api"}, "12"},
+		{Filter{Since: base.Add(30 * time.Minute)}, "23"},
+		{Filter{Container: "claude-apiary"}, "3"},
+		{Filter{Status: StatusCompleted}, "13"},
+		{Filter{Limit: 2}, "23"},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.filter.Apply(sessions)); got != tt.want {
+			t.Errorf("%+v.Apply() = %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestAbandonedRepoIDs(t *testing.T) {
+	sessions := []Session{
+		{ID: "1", Workspaces: []string{"/gone/api", "/src/web"}, RepoIDs: []string{"api", "web"}},
+		{ID: "2", Workspaces: []string{"/gone/old"}, RepoIDs: []string{"old"}},
+		{ID: "3", Workspaces: []string{"/gone/web-copy"}, RepoIDs: []string{"web"}},
+		{ID: "4", RepoIDs: []string{"unknown"}},
+	}
+	exists := func(path string) bool { return !strings.HasPrefix(path, "/gone/") }
+
+	got := AbandonedRepoIDs(sessions, exists)
+	if want := []string{"api", "old"}; !slices.Equal(got, want) {
+		t.Errorf("AbandonedRepoIDs = %q, want %q", got, want)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"":           {},
+		"36h":        now.Add(-36 * time.Hour),
+		"7d":         now.Add(-7 * 24 * time.Hour),
+		"2w":         now.Add(-14 * 24 * time.Hour),
+		"2026-03-01": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for input, want := range tests {
+		got, err := ParseSince(input, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseSince(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, bad := range []string{"yesterday", "-3d", "d"} {
+		if _, err := ParseSince(bad, now); err == nil {
+			t.Errorf("ParseSince(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package hostsocket serves the unix sockets that bridge a container to a
+// service on the host, such as the signing proxy or the clipboard. Each
+// bridge mounts its socket into the container and handles the connections;
+// this package owns the socket itself.
+package hostsocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Path returns ~/.capsule/run/<containerName>-<name>.sock.
+func Path(containerName, name string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, containerName+"-"+name+".sock"), nil
+}
+
+// Listener accepts connections on a socket only the user can connect to.
+type Listener struct {
+	path     string
+	listener net.Listener
+}
+
+// Listen creates the socket at path. Call Serve to handle connections and
+// Close when done.
+func Listen(path string) (*Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	// A socket left by a crashed session would make Listen fail
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, constants.FilePermissions); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to secure %s: %w", path, err)
+	}
+	return &Listener{path: path, listener: listener}, nil
+}
+
+// Serve calls handle for each connection, each in its own goroutine, until
+// Close is called. The connection is closed when handle returns.
+func (l *Listener) Serve(handle func(conn net.Conn)) error {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			handle(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections and removes the socket.
+func (l *Listener) Close() error {
+	err := l.listener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// Reply writes resp to conn as JSON. The shims report a broken connection
+// themselves, so a failed write is not an error here.
+func Reply(conn net.Conn, resp any) {
+	_ = json.NewEncoder(conn).Encode(resp)
+}
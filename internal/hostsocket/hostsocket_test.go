@@ -0,0 +1,61 @@
+package hostsocket
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListener(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run", "claude-test-echo.sock")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// A crashed session left its socket behind
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket = %v, %v; want a socket with mode 600", info, err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- l.Serve(func(conn net.Conn) {
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			Reply(conn, map[string]string{"echo": line})
+		})
+	}()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	conn.Close()
+	if err != nil || reply != `{"echo":"hello\n"}`+"\n" {
+		t.Errorf("reply = %q, %v", reply, err)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() after Close = %v, want nil", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Close() left the socket: %v", err)
+	}
+}
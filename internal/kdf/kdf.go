@@ -0,0 +1,144 @@
+// Package kdf stretches the volume password with Argon2id before it reaches
+// hdiutil. hdiutil's own key derivation is outside capsule's control; running the
+// password through a memory-hard function first makes each offline guess against
+// a stolen image cost hundreds of megabytes of memory as well as time.
+//
+// The derived key, hex-encoded, is the passphrase hdiutil sees. The salt and
+// cost parameters are stored next to the image because they are needed before
+// the volume can be opened.
+package kdf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+const (
+	// FileSuffix is appended to the image path to name its KDF parameters.
+	FileSuffix = ".kdf.json"
+
+	// Argon2id is the only supported algorithm.
+	Argon2id = "argon2id"
+
+	// Defaults follow the OWASP guidance for Argon2id, with extra memory since
+	// this runs once per mount rather than per request.
+	defaultTime      = 3
+	defaultMemoryKiB = 256 * 1024
+	defaultThreads   = 4
+	defaultKeyLen    = 32
+	saltSize         = 16
+)
+
+// Params are the Argon2id settings used to derive a volume's passphrase.
+type Params struct {
+	Algorithm string `json:"algorithm"`
+	Salt      string `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memory_kib"`
+	Threads   uint8  `json:"threads"`
+	KeyLen    uint32 `json:"key_len"`
+}
+
+// Path returns the KDF parameter file for a volume image.
+func Path(volumePath string) string {
+	return volumePath + FileSuffix
+}
+
+// New returns default parameters with a fresh random salt.
+func New() (*Params, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate KDF salt: %w", err)
+	}
+	return &Params{
+		Algorithm: Argon2id,
+		Salt:      hex.EncodeToString(salt),
+		Time:      defaultTime,
+		MemoryKiB: defaultMemoryKiB,
+		Threads:   defaultThreads,
+		KeyLen:    defaultKeyLen,
+	}, nil
+}
+
+// Load reads the KDF parameters for a volume image. It returns nil without an
+// error if the volume does not use key stretching.
+func Load(volumePath string) (*Params, error) {
+	data, err := os.ReadFile(Path(volumePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KDF parameters: %w", err)
+	}
+
+	var p Params
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse KDF parameters %s: %w", Path(volumePath), err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid KDF parameters %s: %w", Path(volumePath), err)
+	}
+	return &p, nil
+}
+
+// Save writes the parameters next to the volume image.
+func (p *Params) Save(volumePath string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal KDF parameters: %w", err)
+	}
+	path := Path(volumePath)
+	if err := os.WriteFile(path, append(data, '\n'), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate rejects unknown algorithms and parameters too weak or too large to be
+// legitimate, so an edited file cannot silently downgrade or exhaust memory.
+func (p *Params) Validate() error {
+	if p.Algorithm != Argon2id {
+		return fmt.Errorf("unsupported algorithm %q", p.Algorithm)
+	}
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil || len(salt) < saltSize {
+		return fmt.Errorf("salt must be at least %d hex-encoded bytes", saltSize)
+	}
+	if p.Time < 1 || p.Time > 100 {
+		return fmt.Errorf("time must be between 1 and 100, got %d", p.Time)
+	}
+	if p.MemoryKiB < 64*1024 || p.MemoryKiB > 4*1024*1024 {
+		return fmt.Errorf("memory must be between 64 MiB and 4 GiB, got %d KiB", p.MemoryKiB)
+	}
+	if p.Threads < 1 {
+		return fmt.Errorf("threads must be at least 1")
+	}
+	if p.KeyLen < 16 || p.KeyLen > 64 {
+		return fmt.Errorf("key length must be between 16 and 64 bytes, got %d", p.KeyLen)
+	}
+	return nil
+}
+
+// Derive stretches the password and returns the hex-encoded key as the
+// passphrase to give hdiutil. The caller must Clear the result.
+func (p *Params) Derive(password *terminal.SecurePassword) (*terminal.SecurePassword, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	salt, _ := hex.DecodeString(p.Salt)
+
+	key := argon2.IDKey(password.Bytes(), salt, p.Time, p.MemoryKiB, p.Threads, p.KeyLen)
+
+	encoded := make([]byte, hex.EncodedLen(len(key)))
+	hex.Encode(encoded, key)
+	clear(key)
+	return terminal.NewSecurePassword(encoded), nil // zeroes encoded
+}
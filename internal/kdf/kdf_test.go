@@ -0,0 +1,89 @@
+package kdf
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// testParams keeps derivation fast while staying within Validate's limits.
+func testParams(t *testing.T) *Params {
+	t.Helper()
+	p, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	p.Time = 1
+	p.MemoryKiB = 64 * 1024
+	return p
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	volumePath := filepath.Join(t.TempDir(), "capsule.sparseimage")
+
+	if p, err := Load(volumePath); err != nil || p != nil {
+		t.Fatalf("Load() without a file = %v, %v; want nil, nil", p, err)
+	}
+
+	p := testParams(t)
+	if err := p.Save(volumePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(volumePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if *loaded != *p {
+		t.Errorf("Load() = %+v, want %+v", loaded, p)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	p := testParams(t)
+	derive := func(p *Params, password string) string {
+		t.Helper()
+		key, err := p.Derive(terminal.NewSecurePassword([]byte(password)))
+		if err != nil {
+			t.Fatalf("Derive() error = %v", err)
+		}
+		defer key.Clear()
+		return key.String()
+	}
+
+	first := derive(p, "correct horse")
+	if len(first) != int(p.KeyLen)*2 {
+		t.Errorf("Derive() length = %d, want %d hex characters", len(first), p.KeyLen*2)
+	}
+	if again := derive(p, "correct horse"); again != first {
+		t.Error("Derive() is not deterministic for the same password and salt")
+	}
+	if other := derive(p, "correct horsE"); other == first {
+		t.Error("Derive() returned the same key for different passwords")
+	}
+
+	resalted := testParams(t)
+	if other := derive(resalted, "correct horse"); other == first {
+		t.Error("Derive() returned the same key for different salts")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]func(*Params){
+		"algorithm":  func(p *Params) { p.Algorithm = "scrypt" },
+		"short salt": func(p *Params) { p.Salt = "abcd" },
+		"low memory": func(p *Params) { p.MemoryKiB = 1024 },
+		"zero time":  func(p *Params) { p.Time = 0 },
+		"no threads": func(p *Params) { p.Threads = 0 },
+		"short key":  func(p *Params) { p.KeyLen = 8 },
+	}
+	for name, edit := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := testParams(t)
+			edit(p)
+			if err := p.Validate(); err == nil {
+				t.Errorf("Validate() accepted %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Package logging configures the slog logger shared by capsule's packages.
+//
+// Packages log through slog's default logger. Setup points it at stderr, filtered
+// by --log-level, and at a size-rotated file under ~/.capsule/logs that keeps at
+// least info-level records for postmortems. User-facing output stays on
+// stdout and stderr as before; the logger records what capsule did to get there.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+const (
+	// LogsSubdir is the directory under CapsuleConfigDir holding log files.
+	LogsSubdir = "logs"
+
+	// FileName is the active log file; rotated files get a .1, .2, ... suffix.
+	FileName = "capsule.log"
+
+	// Off disables the log file when passed as --log-file.
+	Off = "off"
+
+	// DefaultLevel is the stderr level when --log-level is not set.
+	DefaultLevel = "warn"
+
+	// maxFileSize is the size at which the log file is rotated.
+	maxFileSize = 5 << 20
+
+	// maxBackups is how many rotated files are kept.
+	maxBackups = 3
+)
+
+// Options select where log records go.
+type Options struct {
+	// Level filters records written to stderr: debug, info, warn, or error.
+	Level string
+
+	// File is the log file path. Empty means DefaultPath; Off disables it.
+	File string
+
+	// Stderr receives filtered records. Nil means os.Stderr.
+	Stderr io.Writer
+
+	// Verbose traces every external command on Stderr: its arguments when it
+	// starts, then its exit status and how long it took.
+	Verbose bool
+}
+
+// tracer writes --verbose command traces; out is nil when tracing is off.
+var tracer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// DefaultPath returns ~/.capsule/logs/capsule.log.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, LogsSubdir, FileName), nil
+}
+
+// ParseLevel converts a --log-level value to a slog level.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level %q: use debug, info, warn, or error", level)
+	}
+}
+
+// Setup installs the default logger and returns a function that closes the log
+// file. An invalid level is an error; a log file that can't be opened only
+// produces a warning, so logging never stops a command from running.
+func Setup(opts Options) (func(), error) {
+	if opts.Level == "" {
+		opts.Level = DefaultLevel
+	}
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	stderr := opts.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	handlers := []slog.Handler{
+		slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}),
+	}
+	closeFile := func() {}
+
+	if opts.File != Off {
+		path := opts.File
+		if path == "" {
+			path, err = DefaultPath()
+		}
+		var file *RotatingFile
+		if err == nil {
+			file, err = OpenRotating(path, maxFileSize, maxBackups)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: logging to file disabled: %v\n", err)
+		} else {
+			handlers = append(handlers, slog.NewTextHandler(file, &slog.HandlerOptions{
+				Level: min(level, slog.LevelInfo),
+			}))
+			closeFile = func() { file.Close() }
+		}
+	}
+
+	slog.SetDefault(slog.New(fanout(handlers)).With("pid", os.Getpid()))
+
+	tracer.mu.Lock()
+	tracer.out = nil
+	if opts.Verbose {
+		tracer.out = stderr
+	}
+	tracer.mu.Unlock()
+	return closeFile, nil
+}
+
+// Command logs a command about to run, at debug level, and returns a function
+// to call with the command's error once it has finished, which logs its exit
+// status and duration. A failure is also logged at info level, so the log file
+// keeps it. Values passed with -e or --env are redacted since they can carry
+// secrets; passwords go to hdiutil on stdin and never appear in the arguments.
+func Command(cmd *exec.Cmd) (done func(err error)) {
+	argv := strings.Join(RedactArgs(cmd.Args), " ")
+	attrs := []any{"argv", argv}
+	if cmd.Dir != "" {
+		attrs = append(attrs, "dir", cmd.Dir)
+	}
+	slog.Debug("exec", attrs...)
+	trace("%s", argv)
+
+	start := time.Now()
+	return func(err error) {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		status := exitStatus(err)
+		level := slog.LevelDebug
+		if err != nil {
+			level = slog.LevelInfo
+		}
+		slog.Log(context.Background(), level, "exec finished", "argv", argv, "status", status, "duration", elapsed)
+		trace("%s after %s: %s", status, elapsed, argv)
+	}
+}
+
+// exitStatus describes how a command ended, given the error from running it.
+func exitStatus(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "exit 0"
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return fmt.Sprintf("exit %d", exitErr.ExitCode())
+	case errors.As(err, &exitErr):
+		return exitErr.String()
+	default:
+		return fmt.Sprintf("failed (%v)", err)
+	}
+}
+
+// trace writes one --verbose line, if tracing is on.
+func trace(format string, args ...any) {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	if tracer.out != nil {
+		fmt.Fprintf(tracer.out, "[exec] "+format+"\n", args...)
+	}
+}
+
+// RedactArgs returns a copy of args with the values of environment assignments
+// (-e KEY=VALUE, --env KEY=VALUE, --env=KEY=VALUE) and build args
+// (--build-arg KEY=VALUE), which may hold proxy credentials, replaced by "***".
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		if strings.HasPrefix(arg, "--env=") {
+			redacted[i] = "--env=" + redactAssignment(strings.TrimPrefix(arg, "--env="))
+			continue
+		}
+		if (arg == "-e" || arg == "--env" || arg == "--build-arg") && i+1 < len(redacted) {
+			redacted[i+1] = redactAssignment(redacted[i+1])
+		}
+	}
+	return redacted
+}
+
+// redactAssignment hides the value of KEY=VALUE, keeping the key.
+func redactAssignment(kv string) string {
+	name, _, ok := strings.Cut(kv, "=")
+	if !ok {
+		return kv
+	}
+	return name + "=***"
+}
+
+// fanoutHandler sends each record to every handler that accepts its level.
+type fanoutHandler []slog.Handler
+
+func fanout(handlers []slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return fanoutHandler(handlers)
+}
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warn":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for input, want := range tests {
+		got, err := ParseLevel(input)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) should fail")
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"docker", "run", "-e", "HOME=/claude-env/home", "--env", "TOKEN=abc", "--env=KEY=secret", "-e", "NAME", "--build-arg", "HTTPS_PROXY=http://u:p@proxy", "image"}
+	want := []string{"docker", "run", "-e", "HOME=***", "--env", "TOKEN=***", "--env=KEY=***", "-e", "NAME", "--build-arg", "HTTPS_PROXY=***", "image"}
+	got := RedactArgs(args)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactArgs() = %q, want %q", got, want)
+	}
+	if args[3] != "HOME=/claude-env/home" {
+		t.Error("RedactArgs() modified its input")
+	}
+}
+
+func TestSetupLevels(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", FileName)
+	var stderr bytes.Buffer
+	closeLog, err := Setup(Options{Level: "warn", File: path, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	slog.Debug("debug message")
+	slog.Info("info message")
+	slog.Warn("warn message")
+	closeLog()
+
+	if strings.Contains(stderr.String(), "info message") || !strings.Contains(stderr.String(), "warn message") {
+		t.Errorf("stderr should only have warnings, got:\n%s", stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "info message") || strings.Contains(string(data), "debug message") {
+		t.Errorf("log file should record info and above, got:\n%s", data)
+	}
+
+	if _, err := Setup(Options{Level: "loud", File: Off}); err == nil {
+		t.Error("Setup() should reject an invalid level")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	r, err := OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenRotating() error = %v", err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	r.Close()
+
+	expect := map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	}
+	for file, want := range expect {
+		data, err := os.ReadFile(file)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(file), data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("only maxBackups rotated files should be kept")
+	}
+
+	// A file already over the limit is rotated on open
+	os.WriteFile(path, []byte("0123456789abc"), 0o600)
+	r, err = OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenRotating() error = %v", err)
+	}
+	defer r.Close()
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "0123456789abc" {
+		t.Errorf("oversized file should be rotated on open, .1 = %q", data)
+	}
+}
+
+func TestCommandTrace(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var stderr bytes.Buffer
+	closeLog, err := Setup(Options{Level: "warn", File: Off, Stderr: &stderr, Verbose: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeLog()
+	defer Setup(Options{Level: "warn", File: Off})
+
+	cmd := exec.Command("sh", "-c", "exit 3", "sh", "-e", "TOKEN=secret")
+	done := Command(cmd)
+	done(cmd.Run())
+
+	got := stderr.String()
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("trace = %q, want a start and a finish line", got)
+	}
+	if !strings.HasPrefix(lines[0], "[exec] sh -c exit 3 sh -e TOKEN=***") {
+		t.Errorf("start line = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "[exec] exit 3 after ") {
+		t.Errorf("finish line = %q", lines[1])
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("trace leaked a redacted value: %q", got)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	if got := exitStatus(nil); got != "exit 0" {
+		t.Errorf("exitStatus(nil) = %q", got)
+	}
+	err := exec.Command("capsule-no-such-program").Run()
+	if got := exitStatus(err); !strings.HasPrefix(got, "failed (") {
+		t.Errorf("exitStatus(not found) = %q", got)
+	}
+}
@@ -0,0 +1,317 @@
+// Package manifest records what a capsule volume looked like when it was last
+// trusted, so a replaced image or an altered encryption header is noticed on mount.
+//
+// The manifest is stored next to the image and signed with an HMAC keyed from
+// the volume password, so it cannot be rewritten to match a swapped image
+// without knowing the password.
+package manifest
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+const (
+	// FileSuffix is appended to the image path to name its manifest.
+	FileSuffix = ".manifest.json"
+
+	// VolumeIDFile holds the volume's random ID inside the encrypted volume.
+	VolumeIDFile = "config/volume-id"
+
+	// formatVersion is bumped when the signed fields change.
+	formatVersion = 1
+
+	// keyIterations is the PBKDF2 cost for deriving the signing key from the password.
+	keyIterations = 210000
+	saltSize      = 16
+)
+
+// Header is the part of the encryption header reported by 'hdiutil isencrypted'.
+// A new image has a new UUID; adding or removing passphrases changes the counts.
+type Header struct {
+	UUID            string `json:"uuid"`
+	PassphraseCount int    `json:"passphrase_count"`
+	PublicKeyCount  int    `json:"public_key_count"`
+}
+
+// Manifest describes a volume as it was when the manifest was signed.
+type Manifest struct {
+	Format         int       `json:"format"`
+	VolumeID       string    `json:"volume_id"`
+	Header         Header    `json:"header"`
+	CreatedAt      time.Time `json:"created_at"`
+	CapsuleVersion string    `json:"capsule_version,omitempty"`
+	LayoutSHA256   string    `json:"layout_sha256"`
+	Salt           string    `json:"salt"`
+	Signature      string    `json:"signature"`
+}
+
+// Path returns the manifest path for a volume image.
+func Path(volumePath string) string {
+	return volumePath + FileSuffix
+}
+
+// Load reads the manifest for a volume image. It returns nil without an error
+// if the volume has no manifest yet.
+func Load(volumePath string) (*Manifest, error) {
+	data, err := os.ReadFile(Path(volumePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse volume manifest %s: %w", Path(volumePath), err)
+	}
+	return &m, nil
+}
+
+// Write signs a manifest for the mounted volume and saves it next to the image.
+// The volume ID is created inside the volume if it does not exist yet, and the
+// creation time of an existing manifest is kept.
+func Write(volumePath, mountPoint string, header Header, password []byte, version string) (*Manifest, error) {
+	volumeID, err := ensureVolumeID(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := LayoutHash(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	if existing, err := Load(volumePath); err == nil && existing != nil && !existing.CreatedAt.IsZero() {
+		createdAt = existing.CreatedAt
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate manifest salt: %w", err)
+	}
+
+	m := &Manifest{
+		Format:         formatVersion,
+		VolumeID:       volumeID,
+		Header:         header,
+		CreatedAt:      createdAt,
+		CapsuleVersion: version,
+		LayoutSHA256:   layout,
+		Salt:           hex.EncodeToString(salt),
+	}
+	signature, err := m.sign(password)
+	if err != nil {
+		return nil, err
+	}
+	m.Signature = signature
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal volume manifest: %w", err)
+	}
+	path := Path(volumePath)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), constants.FilePermissions); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Verify compares the manifest against the mounted volume and its current
+// encryption header. It returns one message per problem; none means the volume
+// matches what was signed.
+func (m *Manifest) Verify(mountPoint string, header Header, password []byte) ([]string, error) {
+	var problems []string
+
+	expected, err := m.sign(password)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(expected), []byte(m.Signature)) {
+		problems = append(problems, "manifest signature is invalid: the manifest was edited or signed with a different password")
+	}
+
+	if header.UUID != m.Header.UUID {
+		problems = append(problems, fmt.Sprintf("image UUID changed from %s to %s: the volume file was replaced", m.Header.UUID, header.UUID))
+	}
+	if header.PassphraseCount != m.Header.PassphraseCount || header.PublicKeyCount != m.Header.PublicKeyCount {
+		problems = append(problems, fmt.Sprintf("encryption header changed: %d passphrase(s) and %d public key(s), expected %d and %d",
+			header.PassphraseCount, header.PublicKeyCount, m.Header.PassphraseCount, m.Header.PublicKeyCount))
+	}
+
+	volumeID, err := readVolumeID(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if volumeID != m.VolumeID {
+		problems = append(problems, "volume ID does not match: this is not the volume the manifest was created for")
+	}
+
+	layout, err := LayoutHash(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	if layout != m.LayoutSHA256 {
+		problems = append(problems, "top-level layout changed: expected capsule directories are missing or replaced")
+	}
+
+	return problems, nil
+}
+
+// sign computes the manifest's HMAC over every field except the signature.
+func (m *Manifest) sign(password []byte) (string, error) {
+	salt, err := hex.DecodeString(m.Salt)
+	if err != nil || len(salt) == 0 {
+		return "", fmt.Errorf("volume manifest has an invalid salt")
+	}
+	key := pbkdf2.Key(password, salt, keyIterations, sha256.Size, sha256.New)
+	defer clear(key)
+
+	unsigned := *m
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal volume manifest: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// LayoutHash hashes which of the expected top-level volume directories exist
+// and what kind of file each one is.
+func LayoutHash(mountPoint string) (string, error) {
+	h := sha256.New()
+	for _, name := range topLevelDirs() {
+		kind := "missing"
+		info, err := os.Lstat(filepath.Join(mountPoint, name))
+		switch {
+		case err == nil && info.IsDir():
+			kind = "dir"
+		case err == nil:
+			kind = info.Mode().Type().String()
+		case !os.IsNotExist(err):
+			return "", fmt.Errorf("failed to inspect %s: %w", name, err)
+		}
+		fmt.Fprintf(h, "%s\t%s\n", name, kind)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// topLevelDirs returns the sorted first path elements of config.VolumeStructure.
+func topLevelDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, dir := range config.VolumeStructure {
+		top, _, _ := strings.Cut(dir, "/")
+		if !seen[top] {
+			seen[top] = true
+			dirs = append(dirs, top)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// ensureVolumeID returns the volume's ID, creating it on first use.
+func ensureVolumeID(mountPoint string) (string, error) {
+	id, err := readVolumeID(mountPoint)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate volume ID: %w", err)
+	}
+	id = hex.EncodeToString(buf)
+
+	path := filepath.Join(mountPoint, VolumeIDFile)
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), constants.FilePermissions); err != nil {
+		return "", fmt.Errorf("failed to write volume ID: %w", err)
+	}
+	return id, nil
+}
+
+// Issued reports whether a manifest has been signed for the mounted volume:
+// Write gives a volume its ID, so a volume with one but no manifest next to
+// its image has lost it.
+func Issued(mountPoint string) (bool, error) {
+	id, err := readVolumeID(mountPoint)
+	return id != "", err
+}
+
+// readVolumeID returns the volume's ID, or "" if it has none.
+func readVolumeID(mountPoint string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, VolumeIDFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read volume ID: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ParseIsEncrypted parses the output of 'hdiutil isencrypted <image>'.
+func ParseIsEncrypted(output string) (Header, error) {
+	var header Header
+	encrypted := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch key {
+		case "encrypted":
+			encrypted = strings.EqualFold(value, "YES")
+		case "uuid":
+			header.UUID = value
+		case "passphrase-count":
+			header.PassphraseCount, _ = strconv.Atoi(value)
+		case "public-key-count", "private-key-count":
+			// hdiutil has used both names for the certificate key count
+			if n, err := strconv.Atoi(value); err == nil && n > header.PublicKeyCount {
+				header.PublicKeyCount = n
+			}
+		}
+	}
+
+	if !encrypted {
+		return Header{}, fmt.Errorf("image is not encrypted")
+	}
+	if header.UUID == "" {
+		return Header{}, fmt.Errorf("hdiutil did not report an image UUID")
+	}
+	return header, nil
+}
@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+)
+
+const sampleIsEncrypted = `encrypted: YES
+blocksize: 4096
+uuid: 6F1C7B4E-2B7A-4C8E-9D0A-31A2F1C9E4B5
+private-key-count: 0
+passphrase-count: 1
+max-key-count: 1
+version: 2
+`
+
+func TestParseIsEncrypted(t *testing.T) {
+	header, err := ParseIsEncrypted(sampleIsEncrypted)
+	if err != nil {
+		t.Fatalf("ParseIsEncrypted() error = %v", err)
+	}
+	want := Header{UUID: "6F1C7B4E-2B7A-4C8E-9D0A-31A2F1C9E4B5", PassphraseCount: 1}
+	if header != want {
+		t.Errorf("ParseIsEncrypted() = %+v, want %+v", header, want)
+	}
+
+	if _, err := ParseIsEncrypted("encrypted: NO\n"); err == nil {
+		t.Error("ParseIsEncrypted() on an unencrypted image should fail")
+	}
+}
+
+func newVolume(t *testing.T) (volumePath, mountPoint string) {
+	t.Helper()
+	dir := t.TempDir()
+	mountPoint = filepath.Join(dir, "mnt")
+	for _, d := range config.VolumeStructure {
+		if err := os.MkdirAll(filepath.Join(mountPoint, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "capsule.sparseimage"), mountPoint
+}
+
+func TestWriteAndVerify(t *testing.T) {
+	volumePath, mountPoint := newVolume(t)
+	header := Header{UUID: "AAAA", PassphraseCount: 1}
+
+	if m, err := Load(volumePath); err != nil || m != nil {
+		t.Fatalf("Load() before Write = %v, %v; want nil, nil", m, err)
+	}
+
+	written, err := Write(volumePath, mountPoint, header, []byte("secret"), "0.3.0")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	m, err := Load(volumePath)
+	if err != nil || m == nil {
+		t.Fatalf("Load() = %v, %v", m, err)
+	}
+	if m.VolumeID != written.VolumeID || m.CapsuleVersion != "0.3.0" {
+		t.Errorf("Load() = %+v, want %+v", m, written)
+	}
+
+	problems, err := m.Verify(mountPoint, header, []byte("secret"))
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Verify() = %v, %v; want no problems", problems, err)
+	}
+
+	tests := []struct {
+		name     string
+		header   Header
+		password []byte
+		edit     func(*Manifest)
+		want     string
+	}{
+		{name: "replaced image", header: Header{UUID: "BBBB", PassphraseCount: 1}, password: []byte("secret"), want: "image UUID changed"},
+		{name: "added passphrase", header: Header{UUID: "AAAA", PassphraseCount: 2}, password: []byte("secret"), want: "encryption header changed"},
+		{name: "wrong password", header: header, password: []byte("other"), want: "signature is invalid"},
+		{name: "edited manifest", header: Header{UUID: "BBBB", PassphraseCount: 1}, password: []byte("secret"), edit: func(m *Manifest) { m.Header.UUID = "BBBB" }, want: "signature is invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := Load(volumePath)
+			if tt.edit != nil {
+				tt.edit(m)
+			}
+			problems, err := m.Verify(mountPoint, tt.header, tt.password)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !strings.Contains(strings.Join(problems, "\n"), tt.want) {
+				t.Errorf("Verify() = %v, want a problem containing %q", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsVolumeChanges(t *testing.T) {
+	volumePath, mountPoint := newVolume(t)
+	header := Header{UUID: "AAAA", PassphraseCount: 1}
+	m, err := Write(volumePath, mountPoint, header, []byte("secret"), "")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(mountPoint, "repos")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mountPoint, VolumeIDFile), []byte("other\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := m.Verify(mountPoint, header, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	got := strings.Join(problems, "\n")
+	for _, want := range []string{"volume ID does not match", "top-level layout changed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Verify() = %v, want a problem containing %q", problems, want)
+		}
+	}
+
+	// Re-signing keeps the original creation time and the volume's ID
+	resigned, err := Write(volumePath, mountPoint, header, []byte("secret"), "")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !resigned.CreatedAt.Equal(m.CreatedAt) || resigned.VolumeID != "other" {
+		t.Errorf("re-signed manifest = %+v", resigned)
+	}
+}
+
+func TestIssued(t *testing.T) {
+	volumePath, mountPoint := newVolume(t)
+	if issued, err := Issued(mountPoint); err != nil || issued {
+		t.Fatalf("Issued() before Write = %v, %v; want false", issued, err)
+	}
+	if _, err := Write(volumePath, mountPoint, Header{UUID: "AAAA"}, []byte("secret"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the manifest doesn't make the volume look new
+	if err := os.Remove(Path(volumePath)); err != nil {
+		t.Fatal(err)
+	}
+	if issued, err := Issued(mountPoint); err != nil || !issued {
+		t.Errorf("Issued() after the manifest was deleted = %v, %v; want true", issued, err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package migrate finds what the older claude-env tool left behind under its
+// old names (volume files, the ClaudeEnv mount, the portable-claude container
+// and image) so 'capsule migrate' can move them to capsule's naming, and
+// upgrades volumes made by older versions, e.g. when a backup is restored.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+const (
+	// LegacyVolumeFile is the volume file name claude-env created.
+	LegacyVolumeFile = "claude-env.sparseimage"
+
+	// LegacyConfigDir is claude-env's config directory under home.
+	LegacyConfigDir = ".claude-env"
+
+	// LegacyMountPoint is where claude-env mounted its volume.
+	LegacyMountPoint = "/Volumes/ClaudeEnv"
+
+	// LegacyContainerName is the single container claude-env ran.
+	LegacyContainerName = "portable-claude"
+
+	// LegacyImageName is the image claude-env built.
+	LegacyImageName = "portable-claude"
+
+	// legacyVersionName starts the VERSION file claude-env wrote.
+	legacyVersionName = "claude-env"
+)
+
+// VolumeMove is a legacy volume and where capsule expects it.
+type VolumeMove struct {
+	From string
+	To   string
+}
+
+// FindVolumes returns the legacy volumes that exist: those in claude-env's
+// config directory and capsule's volume directory, which move to capsule's
+// default volume, and those in dirs, which are renamed in place.
+func FindVolumes(homeDir string, dirs []string) []VolumeMove {
+	globalVolume := filepath.Join(homeDir, constants.CapsuleConfigDir, constants.VolumesSubdir, constants.MacOSVolumeFile)
+	candidates := []VolumeMove{
+		{From: filepath.Join(homeDir, LegacyConfigDir, constants.VolumesSubdir, LegacyVolumeFile), To: globalVolume},
+		{From: filepath.Join(homeDir, LegacyConfigDir, LegacyVolumeFile), To: globalVolume},
+		{From: filepath.Join(filepath.Dir(globalVolume), LegacyVolumeFile), To: globalVolume},
+	}
+	for _, dir := range dirs {
+		candidates = append(candidates, VolumeMove{
+			From: filepath.Join(dir, LegacyVolumeFile),
+			To:   filepath.Join(dir, constants.MacOSVolumeFile),
+		})
+	}
+
+	var moves []VolumeMove
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c.From] {
+			continue
+		}
+		seen[c.From] = true
+		if _, err := os.Stat(c.From); err == nil {
+			moves = append(moves, c)
+		}
+	}
+	return moves
+}
+
+// MoveVolume moves a legacy volume into place with its sidecars (the
+// manifest, key stretching parameters, and any other <image>.* file), so it
+// can still be unlocked and verified, copying when the move crosses
+// filesystems. A volume already at the destination is left alone.
+func MoveVolume(m VolumeMove) error {
+	if _, err := os.Stat(m.To); err == nil {
+		return fmt.Errorf("skipping %s: %s already exists", m.From, m.To)
+	}
+	if err := volume.MoveImage(m.From, m.To); err != nil {
+		return fmt.Errorf("failed to move %s: %w", m.From, err)
+	}
+	return nil
+}
+
+// RewriteVersion returns the content of a VERSION file with claude-env's
+// name replaced by capsule's, recording version. ok is false if the file
+// wasn't written by claude-env.
+func RewriteVersion(content, version string) (rewritten string, ok bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] != legacyVersionName {
+		return content, false
+	}
+	return fmt.Sprintf("capsule %s\n", version), true
+}
+
+// RewriteVersionFile rewrites the VERSION file in a mounted volume if
+// claude-env wrote it, reporting whether it did.
+func RewriteVersionFile(mountPoint, version string) (bool, error) {
+	path := filepath.Join(mountPoint, embedded.VersionFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read VERSION: %w", err)
+	}
+	rewritten, ok := RewriteVersion(string(data), version)
+	if !ok {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(rewritten), constants.PublicFilePermissions); err != nil {
+		return false, fmt.Errorf("failed to write VERSION: %w", err)
+	}
+	return true, nil
+}
+
+// Containers returns the names of legacy containers, running or not.
+func Containers(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "ps", "-a",
+		"--filter", "name=^"+LegacyContainerName+"$", "--format", "{{.Names}}")
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// Images returns the legacy images as REPOSITORY:TAG, or by ID if untagged.
+func Images(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "images", LegacyImageName, "--format", "{{.Repository}}:{{.Tag}}\t{{.ID}}")
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []string
+	for _, line := range strings.Split(string(output), "\n") {
+		ref, id, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(ref, ":<none>") {
+			ref = id
+		}
+		images = append(images, ref)
+	}
+	return images, nil
+}
@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+func TestFindVolumes(t *testing.T) {
+	homeDir, project, empty := t.TempDir(), t.TempDir(), t.TempDir()
+	legacyGlobal := filepath.Join(homeDir, LegacyConfigDir, constants.VolumesSubdir, LegacyVolumeFile)
+	legacyLocal := filepath.Join(project, LegacyVolumeFile)
+	for _, path := range []string{legacyGlobal, legacyLocal} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := FindVolumes(homeDir, []string{project, empty, project})
+	want := []VolumeMove{
+		{From: legacyGlobal, To: filepath.Join(homeDir, constants.CapsuleConfigDir, constants.VolumesSubdir, constants.MacOSVolumeFile)},
+		{From: legacyLocal, To: filepath.Join(project, constants.MacOSVolumeFile)},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindVolumes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRewriteVersion(t *testing.T) {
+	if got, ok := RewriteVersion("claude-env 0.1.0\n", "0.3.0"); !ok || got != "capsule 0.3.0\n" {
+		t.Errorf("RewriteVersion(claude-env) = %q, %v", got, ok)
+	}
+	if got, ok := RewriteVersion("capsule 0.2.0\n", "0.3.0"); ok || got != "capsule 0.2.0\n" {
+		t.Errorf("RewriteVersion(capsule) = %q, %v; want it unchanged", got, ok)
+	}
+	if _, ok := RewriteVersion("", "0.3.0"); ok {
+		t.Error("RewriteVersion(empty) rewrote it")
+	}
+}
+
+func TestRewriteVersionFile(t *testing.T) {
+	mountPoint := t.TempDir()
+	if ok, err := RewriteVersionFile(mountPoint, "0.3.0"); ok || err != nil {
+		t.Errorf("RewriteVersionFile() without a VERSION = %v, %v; want false, nil", ok, err)
+	}
+
+	path := filepath.Join(mountPoint, embedded.VersionFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("claude-env 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := RewriteVersionFile(mountPoint, "0.3.0"); !ok || err != nil {
+		t.Fatalf("RewriteVersionFile() = %v, %v", ok, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "capsule 0.3.0\n" {
+		t.Errorf("VERSION = %q after rewrite", data)
+	}
+}
+
+func TestMoveVolume(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, LegacyVolumeFile)
+	to := filepath.Join(dir, "volumes", constants.MacOSVolumeFile)
+	files := map[string]string{
+		"":                    "image",
+		manifest.FileSuffix:   "manifest",
+		kdf.FileSuffix:        "kdf",
+		".reencrypt.progress": "other",
+	}
+	for suffix, content := range files {
+		if err := os.WriteFile(from+suffix, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := MoveVolume(VolumeMove{From: from, To: to}); err != nil {
+		t.Fatalf("MoveVolume() error = %v", err)
+	}
+	for suffix, content := range files {
+		if _, err := os.Stat(from + suffix); !os.IsNotExist(err) {
+			t.Errorf("%s is still there", from+suffix)
+		}
+		got, err := os.ReadFile(to + suffix)
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v, want %q", to+suffix, got, err, content)
+		}
+	}
+
+	// A volume already in place is not overwritten
+	if err := os.WriteFile(from, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveVolume(VolumeMove{From: from, To: to}); err == nil {
+		t.Error("MoveVolume() should refuse to overwrite an existing volume")
+	}
+	if got, _ := os.ReadFile(to); string(got) != "image" {
+		t.Errorf("%s = %q, want it untouched", to, got)
+	}
+}
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+)
+
+// InstalledVersion returns the capsule version recorded in the VERSION file
+// of the volume mounted at mountPoint, or "" if it has none.
+func InstalledVersion(mountPoint string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, embedded.VersionFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read VERSION: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return "", nil
+	}
+	return fields[1], nil
+}
+
+// UpgradeVolume brings the volume mounted at mountPoint up to version, as
+// when a backup made by an older capsule is restored: claude-env's VERSION
+// is renamed, directories added to the layout since are created, and the
+// installed skills and settings are refreshed if the volume records another
+// version. It returns a description of each step it took.
+func UpgradeVolume(mountPoint, version string) ([]string, error) {
+	var steps []string
+	rewritten, err := RewriteVersionFile(mountPoint, version)
+	if err != nil {
+		return steps, err
+	}
+	if rewritten {
+		steps = append(steps, "Rewrote claude-env's VERSION for capsule "+version)
+	}
+
+	for _, dir := range config.VolumeStructure {
+		path := filepath.Join(mountPoint, dir)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(path, constants.DirPermissions); err != nil {
+			return steps, fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		steps = append(steps, "Created "+dir)
+	}
+
+	installed, err := InstalledVersion(mountPoint)
+	if err != nil {
+		return steps, err
+	}
+	// A volume set up by a build without doctool is retried until it has it
+	if installed == version && embedded.DoctoolInstalled(mountPoint) {
+		return steps, nil
+	}
+	if err := embedded.WriteDocSyncFiles(mountPoint); errors.Is(err, embedded.ErrDoctoolNotEmbedded) {
+		steps = append(steps, fmt.Sprintf("Skipped doctool: %v", err))
+	} else if err != nil {
+		return steps, fmt.Errorf("failed to update doc-sync: %w", err)
+	}
+	if err := embedded.WriteTaskMgrFiles(mountPoint); err != nil {
+		return steps, fmt.Errorf("failed to update task-mgr: %w", err)
+	}
+	if err := embedded.WriteSettingsJSON(mountPoint); err != nil {
+		return steps, fmt.Errorf("failed to update settings.json: %w", err)
+	}
+	if err := embedded.WriteVersionFile(mountPoint, version); err != nil {
+		return steps, err
+	}
+	from := installed
+	if from == "" {
+		from = "an unknown version"
+	}
+	return append(steps, fmt.Sprintf("Updated doc-sync, task-mgr, and settings.json from %s to %s", from, version)), nil
+}
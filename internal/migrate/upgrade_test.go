@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+)
+
+func TestUpgradeVolume(t *testing.T) {
+	mountPoint := t.TempDir()
+	path := filepath.Join(mountPoint, embedded.VersionFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("claude-env 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(mountPoint, "home", ".claude", "skills")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The VERSION rewrite already records 0.3.0, but doctool is missing, so
+	// the skill files are installed again
+	steps, err := UpgradeVolume(mountPoint, "0.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) == 0 || steps[0] != "Rewrote claude-env's VERSION for capsule 0.3.0" || !slices.Contains(steps, "Created bin") {
+		t.Errorf("UpgradeVolume() steps = %q", steps)
+	}
+	if _, err := os.Stat(filepath.Join(mountPoint, "home", ".claude", "skills")); err != nil {
+		t.Errorf("UpgradeVolume() didn't create the skills directory: %v", err)
+	}
+	if got, err := InstalledVersion(mountPoint); err != nil || got != "0.3.0" {
+		t.Errorf("InstalledVersion() = %q, %v; want 0.3.0", got, err)
+	}
+
+	// This test binary embeds no doctool, so stand in for a full build's
+	for _, arch := range embedded.DoctoolArchitectures {
+		binary := filepath.Join(mountPoint, embedded.DocSyncSkillDir, "bin", "doctool-linux-"+arch)
+		if err := os.WriteFile(binary, []byte("doctool"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if steps, err := UpgradeVolume(mountPoint, "0.3.0"); err != nil || len(steps) != 0 {
+		t.Errorf("UpgradeVolume() of an upgraded volume = %q, %v; want no steps", steps, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package mounttable records which volume each workspace's container runs
+// from in ~/.capsule/run/mounts.json, so that with several volumes mounted at
+// once, commands run in a workspace act on its volume rather than on whichever
+// one the path rules or the first mount found would pick.
+//
+// An entry is added when 'capsule start' creates a container and dropped when
+// its volume is locked. Entries whose volume was unmounted some other way are
+// dropped by Prune, so the table never has to be trusted on its own: callers
+// check that the volume is still mounted where it says.
+package mounttable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// FileName is the name of the table in ~/.capsule/run.
+const FileName = "mounts.json"
+
+// Workspace is a workspace whose container runs from a volume.
+type Workspace struct {
+	Container string    `json:"container"`
+	Paths     []string  `json:"paths"`
+	Started   time.Time `json:"started"`
+}
+
+// Mount is a mounted volume and the workspaces using it.
+type Mount struct {
+	VolumePath string      `json:"volume_path"`
+	MountPoint string      `json:"mount_point"`
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// Table is the content of the table file.
+type Table struct {
+	Mounts []Mount `json:"mounts"`
+}
+
+// Path returns ~/.capsule/run/mounts.json.
+func Path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, FileName), nil
+}
+
+// Bind records that the workspace's container runs from the volume at
+// volumePath, mounted at mountPoint. A container runs from one volume, so it
+// is removed from any other.
+func (t *Table) Bind(volumePath, mountPoint string, w Workspace) {
+	volumePath = filepath.Clean(volumePath)
+	t.unbind(w.Container)
+	for i := range t.Mounts {
+		if t.Mounts[i].VolumePath == volumePath {
+			t.Mounts[i].MountPoint = mountPoint
+			t.Mounts[i].Workspaces = append(t.Mounts[i].Workspaces, w)
+			return
+		}
+	}
+	t.Mounts = append(t.Mounts, Mount{VolumePath: volumePath, MountPoint: mountPoint, Workspaces: []Workspace{w}})
+}
+
+// unbind removes container from every mount.
+func (t *Table) unbind(container string) {
+	for i := range t.Mounts {
+		t.Mounts[i].Workspaces = slices.DeleteFunc(t.Mounts[i].Workspaces, func(w Workspace) bool {
+			return w.Container == container
+		})
+	}
+}
+
+// Forget removes the volume at volumePath, once it is unmounted.
+func (t *Table) Forget(volumePath string) {
+	volumePath = filepath.Clean(volumePath)
+	t.Mounts = slices.DeleteFunc(t.Mounts, func(m Mount) bool {
+		return m.VolumePath == volumePath
+	})
+}
+
+// Prune removes the volumes that are no longer mounted where the table says,
+// according to mounted, which maps each mounted volume's image path to its
+// mount point.
+func (t *Table) Prune(mounted map[string]string) {
+	t.Mounts = slices.DeleteFunc(t.Mounts, func(m Mount) bool {
+		return mounted[m.VolumePath] != m.MountPoint
+	})
+}
+
+// ForContainer returns the mount whose volume container runs from.
+func (t *Table) ForContainer(container string) (Mount, bool) {
+	for _, m := range t.Mounts {
+		for _, w := range m.Workspaces {
+			if w.Container == container {
+				return m, true
+			}
+		}
+	}
+	return Mount{}, false
+}
+
+// ForVolume returns the mount of the volume at volumePath.
+func (t *Table) ForVolume(volumePath string) (Mount, bool) {
+	volumePath = filepath.Clean(volumePath)
+	for _, m := range t.Mounts {
+		if m.VolumePath == volumePath {
+			return m, true
+		}
+	}
+	return Mount{}, false
+}
+
+// Containers returns the containers of the workspaces using the mount.
+func (m Mount) Containers() []string {
+	names := make([]string, len(m.Workspaces))
+	for i, w := range m.Workspaces {
+		names[i] = w.Container
+	}
+	return names
+}
+
+// Load reads the table at path. A missing file is an empty table.
+func Load(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Table{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var t Table
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Update applies change to the table at path and writes it back. Concurrent
+// starts and locks update it in turn: each holds a lock on the table while it
+// reads and writes it.
+func Update(path string, change func(*Table)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, constants.FilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	t, err := Load(path)
+	if err != nil {
+		return err
+	}
+	change(t)
+	return write(path, t)
+}
+
+// write replaces the table atomically, so a crash mid-write leaves the
+// previous table rather than a truncated file.
+func write(path string, t *Table) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode mount table: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mounttable
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	var table Table
+	table.Bind("/vol/work.sparseimage", "/mnt/Capsule-work", Workspace{Container: "claude-a", Paths: []string{"/src/a"}})
+	table.Bind("/vol/work.sparseimage", "/mnt/Capsule-work", Workspace{Container: "claude-b", Paths: []string{"/src/b"}})
+	table.Bind("/vol/home.sparseimage", "/mnt/Capsule-home", Workspace{Container: "claude-c", Paths: []string{"/src/c"}})
+
+	m, ok := table.ForContainer("claude-b")
+	if !ok || m.VolumePath != "/vol/work.sparseimage" || m.MountPoint != "/mnt/Capsule-work" {
+		t.Errorf("ForContainer(claude-b) = %+v, %v", m, ok)
+	}
+	if got := m.Containers(); !slices.Equal(got, []string{"claude-a", "claude-b"}) {
+		t.Errorf("Containers() = %v", got)
+	}
+
+	// Starting a workspace from another volume moves it
+	table.Bind("/vol/home.sparseimage", "/mnt/Capsule-home", Workspace{Container: "claude-a", Paths: []string{"/src/a"}})
+	if m, _ := table.ForContainer("claude-a"); m.VolumePath != "/vol/home.sparseimage" {
+		t.Errorf("ForContainer(claude-a) after rebinding = %+v", m)
+	}
+	if m, _ := table.ForVolume("/vol/work.sparseimage"); !slices.Equal(m.Containers(), []string{"claude-b"}) {
+		t.Errorf("ForVolume(work) containers = %v, want [claude-b]", m.Containers())
+	}
+}
+
+func TestForgetAndPrune(t *testing.T) {
+	var table Table
+	table.Bind("/vol/a.sparseimage", "/mnt/Capsule-a", Workspace{Container: "claude-a"})
+	table.Bind("/vol/b.sparseimage", "/mnt/Capsule-b", Workspace{Container: "claude-b"})
+	table.Bind("/vol/c.sparseimage", "/mnt/Capsule-c", Workspace{Container: "claude-c"})
+
+	table.Forget("/vol/a.sparseimage")
+	if _, ok := table.ForContainer("claude-a"); ok {
+		t.Error("ForContainer(claude-a) found a forgotten volume")
+	}
+
+	// b was remounted elsewhere, and c is no longer mounted
+	table.Prune(map[string]string{"/vol/b.sparseimage": "/mnt/Capsule-b2"})
+	if len(table.Mounts) != 0 {
+		t.Errorf("Prune() left %+v", table.Mounts)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", FileName)
+	table, err := Load(path)
+	if err != nil || len(table.Mounts) != 0 {
+		t.Fatalf("Load() of a missing table = %+v, %v", table, err)
+	}
+
+	err = Update(path, func(table *Table) {
+		table.Bind("/vol/a.sparseimage", "/mnt/Capsule-a", Workspace{Container: "claude-a", Paths: []string{"/src/a"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Update(path, func(table *Table) {
+		table.Bind("/vol/b.sparseimage", "/mnt/Capsule-b", Workspace{Container: "claude-b", Paths: []string{"/src/b"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Mounts) != 2 {
+		t.Fatalf("Load() = %+v, want both volumes", table.Mounts)
+	}
+	if m, ok := table.ForContainer("claude-a"); !ok || !slices.Equal(m.Workspaces[0].Paths, []string{"/src/a"}) {
+		t.Errorf("ForContainer(claude-a) = %+v, %v", m, ok)
+	}
+}
@@ -0,0 +1,164 @@
+// Package notify shows macOS user notifications for capsule lifecycle events,
+// and decides when a long-running watcher should show them.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// sendTimeout bounds osascript, which can stall if Notification Center is busy.
+const sendTimeout = 10 * time.Second
+
+// Title prefixes every capsule notification.
+const Title = "Capsule"
+
+// displayScript takes the title and message as arguments so they never need
+// AppleScript quoting.
+var displayScript = []string{
+	"-e", "on run argv",
+	"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+	"-e", "end run",
+}
+
+// Send shows a notification through Notification Center.
+func Send(title, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, displayScript...), title, message)
+	cmd := exec.CommandContext(ctx, "osascript", args...)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to show notification: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Notification is a message a Monitor wants shown.
+type Notification struct {
+	Title   string
+	Message string
+}
+
+// ExitedContainer is a capsule container that stopped without 'capsule stop'
+// or 'capsule lock', which remove their containers.
+type ExitedContainer struct {
+	Name   string
+	Status string // As reported by docker ps, e.g. "Exited (137) 2 minutes ago"
+}
+
+// Monitor turns periodic observations into notifications, each shown once:
+// a container that exited on its own, and a volume that has stayed mounted
+// longer than UnlockedWarning. Mount times are measured from when the monitor
+// first saw the mount.
+type Monitor struct {
+	// UnlockedWarning is how long a volume may stay mounted before a warning.
+	// Zero disables the warning.
+	UnlockedWarning time.Duration
+
+	mountedSince map[string]time.Time
+	warned       map[string]bool
+	reported     map[string]bool
+}
+
+// NewMonitor returns a monitor that warns about volumes mounted longer than unlockedWarning.
+func NewMonitor(unlockedWarning time.Duration) *Monitor {
+	return &Monitor{
+		UnlockedWarning: unlockedWarning,
+		mountedSince:    make(map[string]time.Time),
+		warned:          make(map[string]bool),
+		reported:        make(map[string]bool),
+	}
+}
+
+// Check records the current mount points and exited containers and returns the
+// notifications that are newly due.
+func (m *Monitor) Check(now time.Time, mountPoints []string, exited []ExitedContainer) []Notification {
+	var notes []Notification
+
+	current := make(map[string]bool, len(mountPoints))
+	for _, mountPoint := range mountPoints {
+		current[mountPoint] = true
+		if _, ok := m.mountedSince[mountPoint]; !ok {
+			m.mountedSince[mountPoint] = now
+		}
+	}
+	// A volume that was locked starts a new clock when it is mounted again
+	for mountPoint := range m.mountedSince {
+		if !current[mountPoint] {
+			delete(m.mountedSince, mountPoint)
+			delete(m.warned, mountPoint)
+		}
+	}
+	if m.UnlockedWarning > 0 {
+		for _, mountPoint := range sortedKeys(m.mountedSince) {
+			since := m.mountedSince[mountPoint]
+			if m.warned[mountPoint] || now.Sub(since) < m.UnlockedWarning {
+				continue
+			}
+			m.warned[mountPoint] = true
+			notes = append(notes, Notification{
+				Title:   Title + ": volume still unlocked",
+				Message: fmt.Sprintf("%s has been mounted for %s. Run 'capsule lock' when you're done.", mountPoint, formatHours(now.Sub(since))),
+			})
+		}
+	}
+
+	seen := make(map[string]bool, len(exited))
+	for _, c := range exited {
+		seen[c.Name] = true
+		if m.reported[c.Name] {
+			continue
+		}
+		m.reported[c.Name] = true
+		notes = append(notes, Notification{
+			Title:   Title + ": container stopped unexpectedly",
+			Message: fmt.Sprintf("%s: %s. The volume is still mounted.", c.Name, c.Status),
+		})
+	}
+	// Report the same name again if it is restarted and crashes again
+	for name := range m.reported {
+		if !seen[name] {
+			delete(m.reported, name)
+		}
+	}
+
+	return notes
+}
+
+// Locked returns the notification for an automatic lock.
+func Locked(reason string, volumes, containers int) Notification {
+	return Notification{
+		Title:   Title + ": locked",
+		Message: fmt.Sprintf("%s: locked %d volume(s) and stopped %d container(s).", reason, volumes, containers),
+	}
+}
+
+// formatHours renders a duration as hours and minutes.
+func formatHours(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if minutes == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
+}
+
+func sortedKeys(m map[string]time.Time) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonitorUnlockedWarning(t *testing.T) {
+	m := NewMonitor(4 * time.Hour)
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	mounts := []string{"/Volumes/Capsule-abc"}
+
+	if notes := m.Check(start, mounts, nil); len(notes) != 0 {
+		t.Fatalf("first check should not warn, got %v", notes)
+	}
+	if notes := m.Check(start.Add(3*time.Hour), mounts, nil); len(notes) != 0 {
+		t.Fatalf("should not warn before the limit, got %v", notes)
+	}
+
+	notes := m.Check(start.Add(4*time.Hour+30*time.Minute), mounts, nil)
+	if len(notes) != 1 || !strings.Contains(notes[0].Message, "4h30m") {
+		t.Fatalf("expected one warning after 4h30m, got %v", notes)
+	}
+	if notes := m.Check(start.Add(5*time.Hour), mounts, nil); len(notes) != 0 {
+		t.Errorf("should warn only once per mount, got %v", notes)
+	}
+
+	// Locking and remounting restarts the clock
+	m.Check(start.Add(6*time.Hour), nil, nil)
+	if notes := m.Check(start.Add(7*time.Hour), mounts, nil); len(notes) != 0 {
+		t.Errorf("remount should restart the clock, got %v", notes)
+	}
+	if notes := m.Check(start.Add(11*time.Hour), mounts, nil); len(notes) != 1 {
+		t.Errorf("expected a warning 4h after the remount, got %v", notes)
+	}
+}
+
+func TestMonitorWarningDisabled(t *testing.T) {
+	m := NewMonitor(0)
+	start := time.Now()
+	m.Check(start, []string{"/Volumes/Capsule-abc"}, nil)
+	if notes := m.Check(start.Add(100*time.Hour), []string{"/Volumes/Capsule-abc"}, nil); len(notes) != 0 {
+		t.Errorf("zero UnlockedWarning should disable warnings, got %v", notes)
+	}
+}
+
+func TestMonitorExitedContainers(t *testing.T) {
+	m := NewMonitor(0)
+	now := time.Now()
+	crashed := []ExitedContainer{{Name: "claude-abc", Status: "Exited (137) 1 minute ago"}}
+
+	notes := m.Check(now, nil, crashed)
+	if len(notes) != 1 || !strings.Contains(notes[0].Message, "claude-abc") || !strings.Contains(notes[0].Message, "Exited (137)") {
+		t.Fatalf("expected one crash notification, got %v", notes)
+	}
+	if notes := m.Check(now, nil, crashed); len(notes) != 0 {
+		t.Errorf("a crash should be reported once, got %v", notes)
+	}
+
+	// Once the container is gone, a later crash is new
+	m.Check(now, nil, nil)
+	if notes := m.Check(now, nil, crashed); len(notes) != 1 {
+		t.Errorf("a new crash should be reported, got %v", notes)
+	}
+}
@@ -0,0 +1,245 @@
+// Package openbridge opens URLs from the container in the host browser. When
+// a URL is an OAuth flow whose redirect goes to localhost, as Claude Code's
+// login does, the callback port is forwarded from the host into the
+// container so the flow completes.
+package openbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/hostsocket"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+const (
+	// ContainerSocketPath is where the bridge socket is mounted inside the container.
+	ContainerSocketPath = "/run/capsule/open.sock"
+
+	// ShimPath is the xdg-open replacement in the image; BROWSER points at it.
+	ShimPath = "/usr/local/bin/capsule-open"
+
+	// maxRequestSize bounds a request; URLs are short.
+	maxRequestSize = 64 << 10
+
+	// callbackWindow is how long a callback port stays forwarded, long
+	// enough to sign in.
+	callbackWindow = 10 * time.Minute
+
+	// openTimeout bounds the host's open command.
+	openTimeout = 10 * time.Second
+)
+
+// relayScript runs in the container for each forwarded connection, joining
+// docker exec's stdin and stdout to the callback server.
+const relayScript = `import socket, sys, threading
+s = socket.create_connection(("localhost", int(sys.argv[1])))
+def send():
+    while data := sys.stdin.buffer.read1(65536):
+        s.sendall(data)
+    s.shutdown(socket.SHUT_WR)
+threading.Thread(target=send, daemon=True).start()
+while data := s.recv(65536):
+    sys.stdout.buffer.write(data)
+    sys.stdout.buffer.flush()
+`
+
+// Request is sent by the shim.
+type Request struct {
+	URL string `json:"url"`
+}
+
+// Response says whether the URL was opened.
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Server opens URLs in the host browser on behalf of a container.
+type Server struct {
+	ContainerName string
+	Program       string // Host command that opens a URL
+
+	// relay connects conn to port inside the container; tests replace it.
+	relay func(ctx context.Context, port int, conn net.Conn) error
+
+	socket *hostsocket.Listener
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	forwarded map[int]bool
+}
+
+// SocketPath returns ~/.capsule/run/<containerName>-open.sock.
+func SocketPath(containerName string) (string, error) {
+	return hostsocket.Path(containerName, "open")
+}
+
+// Listen creates the bridge socket for containerName, opening URLs with the
+// host's open command. Call Serve to handle requests and Close when done.
+func Listen(socketPath, containerName string) (*Server, error) {
+	socket, err := hostsocket.Listen(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		ContainerName: containerName,
+		Program:       "open",
+		socket:        socket,
+		ctx:           ctx,
+		cancel:        cancel,
+		forwarded:     make(map[int]bool),
+	}
+	s.relay = s.dockerRelay
+	return s, nil
+}
+
+// Serve opens URLs until Close is called.
+func (s *Server) Serve() error {
+	return s.socket.Serve(s.handle)
+}
+
+// Close stops the server and any callback forwarding, and removes the socket.
+func (s *Server) Close() error {
+	s.cancel()
+	return s.socket.Close()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	var req Request
+	var resp Response
+	if err := json.NewDecoder(io.LimitReader(conn, maxRequestSize)).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if err := s.open(req.URL); err != nil {
+		resp.Error = err.Error()
+	}
+	hostsocket.Reply(conn, resp)
+}
+
+// open forwards the URL's localhost callback, if it has one, then opens the
+// URL in the host browser.
+func (s *Server) open(rawURL string) error {
+	if err := ValidateURL(rawURL); err != nil {
+		return err
+	}
+	if port, ok := CallbackPort(rawURL); ok {
+		if err := s.forwardCallback(port); err != nil {
+			// The browser still opens; most logins also offer a code to paste
+			slog.Warn("failed to forward OAuth callback", "port", port, "error", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, openTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.Program, rawURL)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("%s failed: %v %s", s.Program, err, output)
+	}
+	return nil
+}
+
+// ValidateURL accepts only http and https URLs, so the container can't make
+// the host open files or launch apps through other schemes.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("only http and https URLs can be opened on the host (got %q)", rawURL)
+	}
+	return nil
+}
+
+// CallbackPort returns the port of a redirect_uri pointing at localhost, as
+// in an OAuth authorization URL from a CLI that listens for the callback.
+func CallbackPort(rawURL string) (int, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, false
+	}
+	redirect, err := url.Parse(u.Query().Get("redirect_uri"))
+	if err != nil || redirect.Scheme != "http" {
+		return 0, false
+	}
+	switch redirect.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+	default:
+		return 0, false
+	}
+	port, err := strconv.Atoi(redirect.Port())
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, false
+	}
+	return port, true
+}
+
+// forwardCallback listens on port on the host's loopback interface for
+// callbackWindow, relaying each connection to the same port in the container.
+func (s *Server) forwardCallback(port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forwarded[port] {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	s.forwarded[port] = true
+	slog.Info("forwarding OAuth callback", "port", port, "container", s.ContainerName)
+
+	ctx, cancel := context.WithTimeout(s.ctx, callbackWindow)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		s.mu.Lock()
+		delete(s.forwarded, port)
+		s.mu.Unlock()
+	}()
+	go func() {
+		defer cancel()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := s.relay(ctx, port, conn); err != nil {
+					slog.Warn("OAuth callback relay failed", "port", port, "error", err)
+				}
+			}()
+		}
+	}()
+	return nil
+}
+
+// dockerRelay relays conn to port inside the container through docker exec,
+// so the container needs no published ports.
+func (s *Server) dockerRelay(ctx context.Context, port int, conn net.Conn) error {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-i", s.ContainerName, "python3", "-c", relayScript, strconv.Itoa(port))
+	done := logging.Command(cmd)
+	cmd.Stdin = conn
+	cmd.Stdout = conn
+	// A browser may keep the connection open after the response; stop
+	// waiting for it once the relay has exited
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	done(err)
+	return err
+}
@@ -0,0 +1,130 @@
+package openbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	for _, u := range []string{"https://claude.ai/oauth/authorize?x=1", "http://localhost:3000/"} {
+		if err := ValidateURL(u); err != nil {
+			t.Errorf("ValidateURL(%q) error = %v", u, err)
+		}
+	}
+	for _, u := range []string{"file:///etc/passwd", "javascript:alert(1)", "vscode://open", "/Applications/Calculator.app", "https://"} {
+		if err := ValidateURL(u); err == nil {
+			t.Errorf("ValidateURL(%q) succeeded, want error", u)
+		}
+	}
+}
+
+func TestCallbackPort(t *testing.T) {
+	tests := []struct {
+		url  string
+		port int
+		ok   bool
+	}{
+		{"https://claude.ai/oauth/authorize?client_id=x&redirect_uri=http%3A%2F%2Flocalhost%3A54545%2Fcallback", 54545, true},
+		{"https://example.com/auth?redirect_uri=http://127.0.0.1:8080/cb", 8080, true},
+		{"https://example.com/auth?redirect_uri=https://example.com/cb", 0, false},
+		{"https://example.com/auth?redirect_uri=http://localhost/cb", 0, false},
+		{"https://example.com/docs", 0, false},
+	}
+	for _, tt := range tests {
+		port, ok := CallbackPort(tt.url)
+		if port != tt.port || ok != tt.ok {
+			t.Errorf("CallbackPort(%q) = %d, %v, want %d, %v", tt.url, port, ok, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	opened := filepath.Join(dir, "opened")
+	program := filepath.Join(dir, "fake-open")
+	if err := os.WriteFile(program, []byte("#!/bin/sh\necho \"$1\" > "+opened+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The "container's" callback server, reached through a relay that dials it directly
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "signed in: "+r.URL.Query().Get("code"))
+	}))
+	defer callback.Close()
+
+	socketPath := filepath.Join(dir, "open.sock")
+	srv, err := Listen(socketPath, "claude-test")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer srv.Close()
+	srv.Program = program
+	srv.relay = func(ctx context.Context, port int, conn net.Conn) error {
+		upstream, err := net.Dial("tcp", callback.Listener.Addr().String())
+		if err != nil {
+			return err
+		}
+		defer upstream.Close()
+		go io.Copy(upstream, conn)
+		_, err = io.Copy(conn, upstream)
+		return err
+	}
+	go srv.Serve()
+
+	// Pick a free host port for the callback
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	authURL := "https://claude.ai/oauth/authorize?redirect_uri=http://localhost:" + strconv.Itoa(port) + "/callback"
+	if resp := send(t, socketPath, authURL); resp.Error != "" {
+		t.Fatalf("open error = %s", resp.Error)
+	}
+	if data, _ := os.ReadFile(opened); strings.TrimSpace(string(data)) != authURL {
+		t.Errorf("opened %q, want %q", data, authURL)
+	}
+
+	// The browser's redirect to the host port reaches the container's server
+	httpResp, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + "/callback?code=abc")
+	if err != nil {
+		t.Fatalf("callback request failed: %v", err)
+	}
+	body, _ := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if string(body) != "signed in: abc" {
+		t.Errorf("callback response = %q", body)
+	}
+
+	if resp := send(t, socketPath, "file:///etc/passwd"); resp.Error == "" {
+		t.Error("opening a file URL succeeded, want error")
+	}
+}
+
+func send(t *testing.T, socketPath, rawURL string) Response {
+	t.Helper()
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(Request{URL: rawURL}); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
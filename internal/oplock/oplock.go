@@ -0,0 +1,165 @@
+// Package oplock keeps capsule invocations from racing each other. A lock is
+// an flock(2) on a file under ~/.capsule/run/locks, one per volume and one per
+// workspace, so two starts in the same workspace, or a start and an unlock of
+// the same volume, cannot both mount or create containers at once.
+//
+// The kernel releases an flock when its process exits, however it exits, so a
+// crashed capsule never leaves a lock held. The file also records who holds
+// it, which names the other operation in the error and shows when a lock was
+// left over from a process that is gone.
+package oplock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// locksSubdir is the subdirectory of the run directory holding lock files.
+const locksSubdir = "locks"
+
+// Holder describes the process holding a lock.
+type Holder struct {
+	PID       int       `json:"pid"`
+	Operation string    `json:"operation"`
+	Started   time.Time `json:"started"`
+}
+
+// alive reports whether the holder's process is still running.
+func (h Holder) alive() bool {
+	if h.PID <= 0 {
+		return false
+	}
+	err := syscall.Kill(h.PID, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// BusyError is returned by Acquire when another process holds the lock.
+type BusyError struct {
+	What   string  // What the lock protects, e.g. "this workspace"
+	Holder *Holder // Nil if the holder hasn't recorded itself yet
+}
+
+func (e *BusyError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("another capsule operation is in progress on %s; wait for it to finish", e.What)
+	}
+	return fmt.Sprintf("another capsule operation is in progress on %s ('%s', pid %d, started %s); wait for it to finish",
+		e.What, e.Holder.Operation, e.Holder.PID, e.Holder.Started.Local().Format(time.TimeOnly))
+}
+
+// Lock is a held lock. Release it when the operation is done.
+type Lock struct {
+	file *os.File
+}
+
+// Dir returns ~/.capsule/run/locks.
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, locksSubdir), nil
+}
+
+// VolumeName returns the lock file name for a volume. Volume paths are hashed,
+// since they are full paths.
+func VolumeName(volumePath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(volumePath)))
+	return "volume-" + hex.EncodeToString(sum[:6]) + ".lock"
+}
+
+// WorkspaceName returns the lock file name for the workspace whose container
+// is containerName.
+func WorkspaceName(containerName string) string {
+	return "workspace-" + containerName + ".lock"
+}
+
+// Acquire takes the lock at path for operation, e.g. "capsule start", without
+// waiting. If another process holds it, the error is a *BusyError naming what
+// the lock protects (what) and the holder.
+func Acquire(path, what, operation string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	// Go opens files close-on-exec, so processes capsule starts never inherit the lock
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, constants.FilePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder := readHolder(file)
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &BusyError{What: what, Holder: holder}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// A holder recorded in a lock nobody holds was left by a process that
+	// exited without releasing it
+	if stale := readHolder(file); stale != nil && !stale.alive() {
+		slog.Info("replacing stale lock", "path", path, "pid", stale.PID, "operation", stale.Operation)
+	}
+	l := &Lock{file: file}
+	if err := l.record(Holder{PID: os.Getpid(), Operation: operation, Started: time.Now().UTC()}); err != nil {
+		l.Release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Release clears the holder and releases the lock. The file is kept: removing
+// it would let a process that opened it before the removal lock a file nobody
+// else can see. Release may be called more than once.
+func (l *Lock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	file.Truncate(0)
+	// Closing the file releases the flock
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// record writes h to the lock file.
+func (l *Lock) record(h Holder) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to encode lock holder: %w", err)
+	}
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if _, err := l.file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// readHolder returns the holder recorded in file, or nil if none is recorded.
+func readHolder(file *os.File) *Holder {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<16))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil
+	}
+	return &h
+}
@@ -0,0 +1,64 @@
+package oplock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", WorkspaceName("claude-abc"))
+	first, err := Acquire(path, "this workspace", "capsule start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Acquire(path, "this workspace", "capsule stop")
+	var busy *BusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("second Acquire() error = %v, want *BusyError", err)
+	}
+	if busy.Holder == nil || busy.Holder.PID != os.Getpid() || busy.Holder.Operation != "capsule start" {
+		t.Errorf("BusyError.Holder = %+v", busy.Holder)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Release(); err != nil {
+		t.Errorf("second Release() = %v", err)
+	}
+	second, err := Acquire(path, "this workspace", "capsule stop")
+	if err != nil {
+		t.Fatalf("Acquire() after Release() = %v", err)
+	}
+	second.Release()
+}
+
+func TestAcquireStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), VolumeName("/vol/capsule.sparseimage"))
+	// A holder recorded by a process that is gone, but nobody holds the flock
+	stale := `{"pid":2147483647,"operation":"capsule unlock","started":"2026-01-02T03:04:05Z"}`
+	if err := os.WriteFile(path, []byte(stale), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Acquire(path, "this volume", "capsule start")
+	if err != nil {
+		t.Fatalf("Acquire() over a stale lock = %v", err)
+	}
+	defer l.Release()
+	if h := readHolder(l.file); h == nil || h.PID != os.Getpid() || h.Operation != "capsule start" {
+		t.Errorf("holder after Acquire() = %+v", h)
+	}
+}
+
+func TestVolumeName(t *testing.T) {
+	if VolumeName("/a/capsule.sparseimage") != VolumeName("/a/./capsule.sparseimage") {
+		t.Error("VolumeName() differs for equivalent paths")
+	}
+	if VolumeName("/a/capsule.sparseimage") == VolumeName("/b/capsule.sparseimage") {
+		t.Error("VolumeName() is the same for different volumes")
+	}
+}
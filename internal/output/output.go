@@ -0,0 +1,106 @@
+// Package output defines capsule's machine-readable command output. With
+// --output json, a command prints one JSON envelope naming the schema version
+// and the command, holding the command's result. Schema returns the JSON
+// Schema describing every envelope; 'capsule schema' prints it.
+//
+// Version changes only when a field is removed or changes meaning. New fields
+// may be added to a version, so consumers should ignore fields they don't know.
+package output
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Version is the schema version of the envelopes this build writes.
+const Version = 1
+
+// Format is how a command prints its result.
+type Format string
+
+const (
+	// FormatText prints KEY=VALUE lines.
+	FormatText Format = "text"
+
+	// FormatJSON prints a JSON envelope.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format named s. Empty is FormatText.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (use %s or %s)", s, FormatText, FormatJSON)
+}
+
+// Envelope wraps a command's result.
+type Envelope struct {
+	Version int    `json:"version"`
+	Command string `json:"command"`
+	Result  any    `json:"result"`
+}
+
+// Statuses reported in results.
+const (
+	StatusMounted        = "mounted"
+	StatusAlreadyMounted = "already_mounted"
+	StatusLocked         = "locked"
+	StatusNotMounted     = "not_mounted"
+	StatusPartial        = "partial" // Some containers or volumes could not be secured
+	StatusPass           = "pass"
+	StatusFail           = "fail"
+	StatusSkip           = "skip" // The check doesn't apply here, or depends on one that failed
+)
+
+// UnlockResult is the result of 'capsule unlock'.
+type UnlockResult struct {
+	Status     string `json:"status"` // StatusMounted or StatusAlreadyMounted
+	MountPoint string `json:"mount_point"`
+	VolumePath string `json:"volume_path"`
+}
+
+// LockResult is the result of 'capsule lock', with or without --all.
+type LockResult struct {
+	Status            string   `json:"status"`       // StatusLocked, StatusNotMounted, or StatusPartial
+	VolumePaths       []string `json:"volume_paths"` // Volumes locked, or the one that was not mounted
+	ContainersStopped int      `json:"containers_stopped"`
+	VolumesLocked     int      `json:"volumes_locked"`
+}
+
+// PreflightResult is the result of 'capsule preflight'.
+type PreflightResult struct {
+	Status string           `json:"status"` // StatusPass, or StatusFail if any check failed
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// PreflightCheck is the outcome of one preflight check.
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`            // StatusPass, StatusFail, or StatusSkip
+	Version string `json:"version,omitempty"` // The tool's version, for checks of a tool
+	Detail  string `json:"detail"`
+}
+
+// Write prints result for command as a JSON envelope on one line.
+func Write(w io.Writer, command string, result any) error {
+	data, err := json.Marshal(Envelope{Version: Version, Command: command, Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+//go:embed schema.json
+var schema []byte
+
+// Schema returns the JSON Schema of the envelopes.
+func Schema() []byte {
+	return schema
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, "unlock", UnlockResult{Status: StatusMounted, MountPoint: "/m/Capsule-abc", VolumePath: "/v/capsule.sparseimage"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":1,"command":"unlock","result":{"status":"mounted","mount_point":"/m/Capsule-abc","volume_path":"/v/capsule.sparseimage"}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %s, want %s", buf.String(), want)
+	}
+}
+
+// TestSchemaMatchesResults keeps schema.json in step with the result types.
+func TestSchemaMatchesResults(t *testing.T) {
+	var doc struct {
+		Properties struct {
+			Version struct {
+				Const int `json:"const"`
+			} `json:"version"`
+		} `json:"properties"`
+		Defs map[string]struct {
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &doc); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if doc.Properties.Version.Const != Version {
+		t.Errorf("schema version = %d, want %d", doc.Properties.Version.Const, Version)
+	}
+
+	results := map[string]any{"unlock": UnlockResult{}, "lock": LockResult{}, "preflight": PreflightResult{}, "preflight_check": PreflightCheck{}}
+	for command, result := range results {
+		def, ok := doc.Defs[command]
+		if !ok {
+			t.Errorf("schema has no $defs/%s", command)
+			continue
+		}
+		var fields []string
+		typ := reflect.TypeOf(result)
+		for i := range typ.NumField() {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+		var properties []string
+		for name := range def.Properties {
+			properties = append(properties, name)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("$defs/%s properties = %v, want %v", command, properties, fields)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatText, "text": FormatText, "json": FormatJSON} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("ParseFormat(yaml) succeeded")
+	}
+}
@@ -0,0 +1,149 @@
+// Package plugin finds and runs external capsule subcommands. As with git and
+// kubectl, an executable named capsule-<name> on PATH is run for 'capsule <name>'
+// when <name> is not a built-in command. The plugin receives the resolved
+// session details in CAPSULE_* environment variables, never the volume password.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Prefix starts the executable name of every plugin.
+const Prefix = "capsule-"
+
+// Environment variables passed to plugins.
+const (
+	EnvVersion          = "CAPSULE_VERSION"
+	EnvBinary           = "CAPSULE_BINARY"
+	EnvVolumePath       = "CAPSULE_VOLUME_PATH"
+	EnvMountPoint       = "CAPSULE_MOUNT_POINT"
+	EnvContainerName    = "CAPSULE_CONTAINER_NAME"
+	EnvContainerRunning = "CAPSULE_CONTAINER_RUNNING"
+	EnvWorkspace        = "CAPSULE_WORKSPACE"
+	EnvRepoID           = "CAPSULE_REPO_ID"
+)
+
+// validName matches plugin names: they become part of a file name, so no
+// separators or leading punctuation.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Plugin is an external subcommand.
+type Plugin struct {
+	Name string // Subcommand name, without the prefix
+	Path string // Absolute path of the executable
+}
+
+// Context describes the session a plugin runs in. Fields that could not be
+// resolved are empty; a plugin that needs the volume mounted should check
+// CAPSULE_MOUNT_POINT and ask the user to run 'capsule unlock'.
+type Context struct {
+	Version          string
+	Binary           string
+	VolumePath       string
+	MountPoint       string
+	ContainerName    string
+	ContainerRunning bool
+	Workspace        string
+	RepoID           string
+}
+
+// Env returns the context as KEY=VALUE pairs.
+func (c Context) Env() []string {
+	return []string{
+		EnvVersion + "=" + c.Version,
+		EnvBinary + "=" + c.Binary,
+		EnvVolumePath + "=" + c.VolumePath,
+		EnvMountPoint + "=" + c.MountPoint,
+		EnvContainerName + "=" + c.ContainerName,
+		EnvContainerRunning + "=" + fmt.Sprint(c.ContainerRunning),
+		EnvWorkspace + "=" + c.Workspace,
+		EnvRepoID + "=" + c.RepoID,
+	}
+}
+
+// ValidName reports whether name can be a plugin name.
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Lookup finds the plugin for a subcommand name on PATH. It returns nil without
+// an error if there is none.
+func Lookup(name string) (*Plugin, error) {
+	if !ValidName(name) {
+		return nil, nil
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up plugin %s: %w", name, err)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return &Plugin{Name: name, Path: path}, nil
+}
+
+// List returns the plugins found in the directories of pathList (in PATH
+// format), sorted by name. As with command lookup, the first directory wins;
+// later executables with the same name are returned as shadowed.
+func List(pathList string) (plugins []Plugin, shadowed []Plugin) {
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), Prefix)
+			if !ok || !ValidName(name) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			p := Plugin{Name: name, Path: path}
+			if seen[name] {
+				shadowed = append(shadowed, p)
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, p)
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, shadowed
+}
+
+// Command returns the command that runs the plugin with args, connected to
+// the terminal, with the context added to the environment.
+func (p *Plugin) Command(args []string, ctx Context) *exec.Cmd {
+	cmd := exec.Command(p.Path, args...)
+	cmd.Env = append(os.Environ(), ctx.Env()...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// isExecutable reports whether path is a regular file (after symlinks) with an
+// execute bit set.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
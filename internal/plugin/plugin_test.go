@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeExecutable(t *testing.T, dir, name string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestList(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeExecutable(t, first, "capsule-backup", 0o755)
+	writeExecutable(t, first, "capsule-notes", 0o644) // not executable
+	writeExecutable(t, first, "other-tool", 0o755)
+	writeExecutable(t, first, "capsule-.hidden", 0o755)
+	os.Mkdir(filepath.Join(first, "capsule-dir"), 0o755)
+	writeExecutable(t, second, "capsule-backup", 0o755)
+	writeExecutable(t, second, "capsule-sync", 0o755)
+
+	plugins, shadowed := List(first + string(os.PathListSeparator) + second)
+
+	var names []string
+	for _, p := range plugins {
+		names = append(names, p.Name)
+	}
+	if !slices.Equal(names, []string{"backup", "sync"}) {
+		t.Errorf("List() names = %v, want [backup sync]", names)
+	}
+	if plugins[0].Path != filepath.Join(first, "capsule-backup") {
+		t.Errorf("first directory on PATH should win, got %s", plugins[0].Path)
+	}
+	if len(shadowed) != 1 || shadowed[0].Path != filepath.Join(second, "capsule-backup") {
+		t.Errorf("List() shadowed = %v", shadowed)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutable(t, dir, "capsule-backup", 0o755)
+	t.Setenv("PATH", dir)
+
+	p, err := Lookup("backup")
+	if err != nil || p == nil {
+		t.Fatalf("Lookup(backup) = %v, %v", p, err)
+	}
+	if p.Path != filepath.Join(dir, "capsule-backup") {
+		t.Errorf("Lookup(backup).Path = %s", p.Path)
+	}
+
+	for _, name := range []string{"missing", "../backup", "-backup", ""} {
+		if p, err := Lookup(name); p != nil || err != nil {
+			t.Errorf("Lookup(%q) = %v, %v, want nil", name, p, err)
+		}
+	}
+}
+
+func TestContextEnv(t *testing.T) {
+	env := Context{
+		Version:          "1.0.0",
+		VolumePath:       "/vol/capsule.sparseimage",
+		ContainerName:    "claude-abc",
+		ContainerRunning: true,
+	}.Env()
+
+	for _, want := range []string{
+		"CAPSULE_VERSION=1.0.0",
+		"CAPSULE_VOLUME_PATH=/vol/capsule.sparseimage",
+		"CAPSULE_MOUNT_POINT=",
+		"CAPSULE_CONTAINER_NAME=claude-abc",
+		"CAPSULE_CONTAINER_RUNNING=true",
+	} {
+		if !slices.Contains(env, want) {
+			t.Errorf("Env() missing %s: %v", want, env)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package preflight checks that a machine has what capsule needs: the tools
+// it runs, a Docker daemon that can see the mount directory, and disk space
+// for volumes. Each check passes, fails, or is skipped, so scripts and CI
+// runners can gate on the report before running capsule.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// commandTimeout bounds each version query, so a hung daemon fails its check
+// instead of hanging the report.
+const commandTimeout = 10 * time.Second
+
+// Checker runs the preflight checks. The function fields are what the checks
+// touch outside the process; New fills them in with the real ones.
+type Checker struct {
+	GOOS         string // The operating system to check for
+	MountDir     string // Where volumes are mounted; Docker must be able to share it
+	VolumeDir    string // Where volume images are, or will be, kept
+	MinFreeBytes int64  // Free space VolumeDir's disk needs
+
+	// LookPath finds an executable, like exec.LookPath.
+	LookPath func(file string) (string, error)
+
+	// Command runs a command and returns its stdout.
+	Command func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	// FileSharing reports whether Docker can bind mount dir.
+	FileSharing func(ctx context.Context, dir string) error
+
+	// Capacity returns the size of the filesystem holding path.
+	Capacity func(path string) (volume.Capacity, error)
+}
+
+// New returns a Checker for this machine.
+func New(mountDir, volumeDir string, minFreeBytes int64, fileSharing func(ctx context.Context, dir string) error) *Checker {
+	return &Checker{
+		GOOS:         runtime.GOOS,
+		MountDir:     mountDir,
+		VolumeDir:    volumeDir,
+		MinFreeBytes: minFreeBytes,
+		LookPath:     exec.LookPath,
+		Command:      runCommand,
+		FileSharing:  fileSharing,
+		Capacity:     volume.FilesystemCapacity,
+	}
+}
+
+// Check runs every check in order and returns the report. Checks that need
+// the Docker daemon are skipped when it isn't reachable.
+func (c *Checker) Check(ctx context.Context) output.PreflightResult {
+	result := output.PreflightResult{Status: output.StatusPass}
+	add := func(check output.PreflightCheck) {
+		if check.Status == output.StatusFail {
+			result.Status = output.StatusFail
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	add(c.checkPlatform())
+	docker := c.checkDocker(ctx)
+	add(docker)
+	daemon := c.checkDaemon(ctx, docker.Status == output.StatusPass)
+	add(daemon)
+	add(c.checkHdiutil(ctx))
+	add(c.checkCryptsetup(ctx))
+	add(c.checkFileSharing(ctx, daemon.Status == output.StatusPass))
+	add(c.checkDiskSpace())
+	return result
+}
+
+// Failed returns the names of the checks that failed.
+func Failed(result output.PreflightResult) []string {
+	var names []string
+	for _, check := range result.Checks {
+		if check.Status == output.StatusFail {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+func (c *Checker) checkPlatform() output.PreflightCheck {
+	check := output.PreflightCheck{Name: "platform"}
+	if c.GOOS != "darwin" {
+		return fail(check, fmt.Sprintf("capsule supports only macOS, not %s", c.GOOS))
+	}
+	return pass(check, "macOS")
+}
+
+func (c *Checker) checkDocker(ctx context.Context) output.PreflightCheck {
+	check := output.PreflightCheck{Name: "docker"}
+	path, err := c.LookPath("docker")
+	if err != nil {
+		return fail(check, "docker is not on PATH; install Docker Desktop")
+	}
+	out, err := c.Command(ctx, path, "version", "--format", "{{.Client.Version}}")
+	// docker version exits non-zero without a daemon, but still prints the client's version
+	check.Version = strings.TrimSpace(string(out))
+	if check.Version == "" {
+		return fail(check, fmt.Sprintf("%s did not report its version: %v", path, err))
+	}
+	return pass(check, path)
+}
+
+func (c *Checker) checkDaemon(ctx context.Context, haveDocker bool) output.PreflightCheck {
+	check := output.PreflightCheck{Name: "docker_daemon"}
+	if !haveDocker {
+		return skip(check, "needs the docker CLI")
+	}
+	out, err := c.Command(ctx, "docker", "version", "--format", "{{.Server.Version}}")
+	check.Version = strings.TrimSpace(string(out))
+	if err != nil || check.Version == "" {
+		return fail(check, "the Docker daemon isn't running or can't be reached; start Docker Desktop")
+	}
+	return pass(check, "running")
+}
+
+func (c *Checker) checkHdiutil(ctx context.Context) output.PreflightCheck {
+	check := output.PreflightCheck{Name: "hdiutil"}
+	if c.GOOS != "darwin" {
+		return skip(check, "only used on macOS")
+	}
+	path, err := c.LookPath("hdiutil")
+	if err != nil {
+		return fail(check, "hdiutil is not on PATH; encrypted volumes can't be created or mounted")
+	}
+	out, err := c.Command(ctx, path, "info")
+	if err != nil {
+		return fail(check, fmt.Sprintf("hdiutil info failed: %v", err))
+	}
+	check.Version = hdiutilVersion(string(out))
+	return pass(check, path)
+}
+
+// checkCryptsetup checks for the LUKS tool on Linux, where volumes would be
+// LUKS images rather than disk images.
+func (c *Checker) checkCryptsetup(ctx context.Context) output.PreflightCheck {
+	check := output.PreflightCheck{Name: "cryptsetup"}
+	if c.GOOS != "linux" {
+		return skip(check, "only used on Linux")
+	}
+	path, err := c.LookPath("cryptsetup")
+	if err != nil {
+		return fail(check, "cryptsetup is not on PATH")
+	}
+	out, err := c.Command(ctx, path, "--version")
+	if err != nil {
+		return fail(check, fmt.Sprintf("cryptsetup --version failed: %v", err))
+	}
+	check.Version = cryptsetupVersion(string(out))
+	return pass(check, path)
+}
+
+func (c *Checker) checkFileSharing(ctx context.Context, haveDaemon bool) output.PreflightCheck {
+	check := output.PreflightCheck{Name: "file_sharing"}
+	if !haveDaemon {
+		return skip(check, "needs the Docker daemon")
+	}
+	if err := c.FileSharing(ctx, c.MountDir); err != nil {
+		return fail(check, firstLine(err.Error()))
+	}
+	return pass(check, fmt.Sprintf("Docker can mount %s", c.MountDir))
+}
+
+func (c *Checker) checkDiskSpace() output.PreflightCheck {
+	check := output.PreflightCheck{Name: "disk_space"}
+	dir := existingDir(c.VolumeDir)
+	capacity, err := c.Capacity(dir)
+	if err != nil {
+		return fail(check, err.Error())
+	}
+	detail := fmt.Sprintf("%s free on the disk holding %s, %s needed", gigabytes(capacity.FreeBytes), c.VolumeDir, gigabytes(c.MinFreeBytes))
+	if capacity.FreeBytes < c.MinFreeBytes {
+		return fail(check, detail)
+	}
+	return pass(check, detail)
+}
+
+func pass(check output.PreflightCheck, detail string) output.PreflightCheck {
+	check.Status, check.Detail = output.StatusPass, detail
+	return check
+}
+
+func fail(check output.PreflightCheck, detail string) output.PreflightCheck {
+	check.Status, check.Detail = output.StatusFail, detail
+	return check
+}
+
+func skip(check output.PreflightCheck, detail string) output.PreflightCheck {
+	check.Status, check.Detail = output.StatusSkip, detail
+	return check
+}
+
+// hdiutilVersion returns the DiskImages framework version from 'hdiutil
+// info', whose first line reads "framework : 671.140.2".
+func hdiutilVersion(info string) string {
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "framework" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// cryptsetupVersion returns the version from 'cryptsetup --version', which
+// prints "cryptsetup 2.6.1 flags: UDEV BLKID ...".
+func cryptsetupVersion(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// existingDir returns dir or its nearest existing parent, so the disk space
+// check works before the volume directory is created.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func gigabytes(n int64) string {
+	return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+}
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	done := logging.Command(cmd)
+	out, err := cmd.Output()
+	done(err)
+	return out, err
+}
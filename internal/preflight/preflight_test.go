@@ -0,0 +1,126 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/output"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// fakeChecker returns a Checker for a Mac with docker and hdiutil installed,
+// a running daemon that shares the mount directory, and 10 GB free.
+func fakeChecker() *Checker {
+	return &Checker{
+		GOOS:         "darwin",
+		MountDir:     "/Users/me/.capsule/mounts",
+		VolumeDir:    "/Users/me/.capsule/volumes",
+		MinFreeBytes: 2 << 30,
+		LookPath: func(file string) (string, error) {
+			if file == "cryptsetup" {
+				return "", exec.ErrNotFound
+			}
+			return "/usr/bin/" + file, nil
+		},
+		Command: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			switch strings.Join(append([]string{name}, args...), " ") {
+			case "/usr/bin/docker version --format {{.Client.Version}}":
+				return []byte("27.3.1\n"), nil
+			case "docker version --format {{.Server.Version}}":
+				return []byte("27.3.1\n"), nil
+			case "/usr/bin/hdiutil info":
+				return []byte("framework       : 671.140.2\ndriver          : 671.140.2\n"), nil
+			}
+			return nil, errors.New("unexpected command")
+		},
+		FileSharing: func(ctx context.Context, dir string) error { return nil },
+		Capacity: func(path string) (volume.Capacity, error) {
+			return volume.Capacity{TotalBytes: 100 << 30, FreeBytes: 10 << 30}, nil
+		},
+	}
+}
+
+func statuses(result output.PreflightResult) map[string]string {
+	m := make(map[string]string)
+	for _, check := range result.Checks {
+		m[check.Name] = check.Status
+	}
+	return m
+}
+
+func TestCheckPasses(t *testing.T) {
+	result := fakeChecker().Check(context.Background())
+	if result.Status != output.StatusPass {
+		t.Errorf("Status = %s, want pass: %+v", result.Status, result.Checks)
+	}
+	got := statuses(result)
+	want := map[string]string{
+		"platform":      output.StatusPass,
+		"docker":        output.StatusPass,
+		"docker_daemon": output.StatusPass,
+		"hdiutil":       output.StatusPass,
+		"cryptsetup":    output.StatusSkip,
+		"file_sharing":  output.StatusPass,
+		"disk_space":    output.StatusPass,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %q, want %q", name, got[name], status)
+		}
+	}
+	if result.Checks[3].Version != "671.140.2" {
+		t.Errorf("hdiutil version = %q", result.Checks[3].Version)
+	}
+}
+
+func TestCheckWithoutDaemon(t *testing.T) {
+	c := fakeChecker()
+	command := c.Command
+	c.Command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if slices.Contains(args, "{{.Server.Version}}") {
+			return nil, errors.New("Cannot connect to the Docker daemon")
+		}
+		return command(ctx, name, args...)
+	}
+	c.FileSharing = func(ctx context.Context, dir string) error {
+		t.Error("file sharing checked without a daemon")
+		return nil
+	}
+	c.Capacity = func(path string) (volume.Capacity, error) {
+		return volume.Capacity{FreeBytes: 1 << 30}, nil
+	}
+
+	result := c.Check(context.Background())
+	if result.Status != output.StatusFail {
+		t.Errorf("Status = %s, want fail", result.Status)
+	}
+	if got := statuses(result)["file_sharing"]; got != output.StatusSkip {
+		t.Errorf("file_sharing = %q, want skip", got)
+	}
+	if got := Failed(result); !slices.Equal(got, []string{"docker_daemon", "disk_space"}) {
+		t.Errorf("Failed() = %v", got)
+	}
+}
+
+func TestCheckOtherPlatform(t *testing.T) {
+	c := fakeChecker()
+	c.GOOS = "linux"
+	result := c.Check(context.Background())
+	got := statuses(result)
+	if got["platform"] != output.StatusFail || got["hdiutil"] != output.StatusSkip || got["cryptsetup"] != output.StatusFail {
+		t.Errorf("statuses on linux = %v", got)
+	}
+}
+
+func TestCryptsetupVersion(t *testing.T) {
+	if got := cryptsetupVersion("cryptsetup 2.6.1 flags: UDEV BLKID KEYRING\n"); got != "2.6.1" {
+		t.Errorf("cryptsetupVersion() = %q", got)
+	}
+	if got := cryptsetupVersion(""); got != "" {
+		t.Errorf("cryptsetupVersion(\"\") = %q", got)
+	}
+}
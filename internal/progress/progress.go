@@ -0,0 +1,211 @@
+// Package progress shows a spinner, with a percentage when one is known, for
+// operations that can run for minutes: creating large volumes and building the
+// Docker image.
+//
+// On a terminal the indicator redraws a single line in place. Anywhere else it
+// prints one line when the operation starts and one when it ends, so logs and
+// CI output stay readable.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// redrawInterval is how often a terminal indicator advances its spinner.
+const redrawInterval = 100 * time.Millisecond
+
+// maxDetailWidth truncates the detail text so the line doesn't wrap.
+const maxDetailWidth = 60
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Indicator reports the progress of one operation.
+type Indicator struct {
+	w     io.Writer
+	label string
+	tty   bool
+	start time.Time
+
+	mu      sync.Mutex
+	percent float64 // Negative while unknown
+	detail  string
+	frame   int
+	stopped bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start begins an indicator for label on f, animated if f is a terminal.
+func Start(f *os.File, label string) *Indicator {
+	return start(f, label, term.IsTerminal(int(f.Fd())))
+}
+
+// Discard returns an Indicator that draws nothing, for callers asked to be quiet.
+func Discard(label string) *Indicator {
+	return start(io.Discard, label, false)
+}
+
+func start(w io.Writer, label string, tty bool) *Indicator {
+	i := &Indicator{
+		w:       w,
+		label:   label,
+		tty:     tty,
+		start:   time.Now(),
+		percent: -1,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if !tty {
+		fmt.Fprintf(w, "%s...\n", label)
+		close(i.done)
+		return i
+	}
+
+	go func() {
+		defer close(i.done)
+		ticker := time.NewTicker(redrawInterval)
+		defer ticker.Stop()
+		for {
+			i.redraw()
+			select {
+			case <-i.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return i
+}
+
+// Interactive reports whether the indicator is redrawing a terminal line.
+// Callers can use it to decide whether to hide raw command output.
+func (i *Indicator) Interactive() bool {
+	return i.tty
+}
+
+// SetPercent records completion from 0 to 100. A negative value means unknown.
+func (i *Indicator) SetPercent(percent float64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.percent = min(percent, 100)
+}
+
+// SetDetail shows what the operation is doing now, e.g. the current build step.
+func (i *Indicator) SetDetail(detail string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.detail = detail
+}
+
+// Done stops the indicator and reports success, or failure if err is non-nil.
+// Calling it more than once has no effect.
+func (i *Indicator) Done(err error) {
+	i.mu.Lock()
+	if i.stopped {
+		i.mu.Unlock()
+		return
+	}
+	i.stopped = true
+	i.mu.Unlock()
+
+	if i.tty {
+		close(i.stop)
+	}
+	<-i.done
+
+	elapsed := formatElapsed(time.Since(i.start))
+	switch {
+	case i.tty && err != nil:
+		fmt.Fprintf(i.w, "\r\033[K✗ %s failed (%s)\n", i.label, elapsed)
+	case i.tty:
+		fmt.Fprintf(i.w, "\r\033[K✓ %s (%s)\n", i.label, elapsed)
+	case err != nil:
+		fmt.Fprintf(i.w, "%s failed after %s\n", i.label, elapsed)
+	default:
+		fmt.Fprintf(i.w, "%s done in %s\n", i.label, elapsed)
+	}
+}
+
+func (i *Indicator) redraw() {
+	i.mu.Lock()
+	line := i.render(time.Since(i.start))
+	i.frame++
+	i.mu.Unlock()
+	fmt.Fprint(i.w, "\r\033[K"+line)
+}
+
+// render formats the indicator line. The caller holds mu.
+func (i *Indicator) render(elapsed time.Duration) string {
+	var b strings.Builder
+	b.WriteString(spinnerFrames[i.frame%len(spinnerFrames)])
+	b.WriteString(" ")
+	b.WriteString(i.label)
+	if i.percent >= 0 {
+		fmt.Fprintf(&b, " %3.0f%%", i.percent)
+	}
+	fmt.Fprintf(&b, " (%s)", formatElapsed(elapsed))
+	if i.detail != "" {
+		detail := i.detail
+		if len(detail) > maxDetailWidth {
+			detail = detail[:maxDetailWidth-3] + "..."
+		}
+		b.WriteString("  ")
+		b.WriteString(detail)
+	}
+	return b.String()
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// ParsePuppetStrings reads a line of hdiutil -puppetstrings output, returning
+// the percentage from "PERCENT:42.5". hdiutil reports -1 while it cannot
+// estimate, which is passed through as unknown.
+func ParsePuppetStrings(line string) (float64, bool) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(line), "PERCENT:")
+	if !ok {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return percent, true
+}
+
+// buildStepPattern matches "Step 3/12 : RUN ..." from the classic builder and
+// "#7 [3/12] RUN ..." or "#7 [stage 3/12] RUN ..." from BuildKit.
+var buildStepPattern = regexp.MustCompile(`^(?:Step (\d+)/(\d+) : |#\d+ \[(?:\S+ )?(\d+)/(\d+)\] )(.*)$`)
+
+// ParseBuildStep reads a line of docker build output, returning the step
+// number, the number of steps, and the step's instruction.
+func ParseBuildStep(line string) (step, total int, instruction string, ok bool) {
+	m := buildStepPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return 0, 0, "", false
+	}
+	stepText, totalText := m[1], m[2]
+	if stepText == "" {
+		stepText, totalText = m[3], m[4]
+	}
+	step, _ = strconv.Atoi(stepText)
+	total, _ = strconv.Atoi(totalText)
+	if total == 0 {
+		return 0, 0, "", false
+	}
+	return step, total, strings.TrimSpace(m[5]), true
+}
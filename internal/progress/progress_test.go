@@ -0,0 +1,99 @@
+package progress
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePuppetStrings(t *testing.T) {
+	tests := []struct {
+		line    string
+		percent float64
+		ok      bool
+	}{
+		{"PERCENT:42.500000", 42.5, true},
+		{"PERCENT:-1.000000", -1, true},
+		{"  PERCENT:100\n", 100, true},
+		{"MESSAGE:Creating...", 0, false},
+		{"PERCENT:abc", 0, false},
+		{"created: /tmp/capsule.sparseimage", 0, false},
+	}
+	for _, tt := range tests {
+		percent, ok := ParsePuppetStrings(tt.line)
+		if percent != tt.percent || ok != tt.ok {
+			t.Errorf("ParsePuppetStrings(%q) = %v, %v, want %v, %v", tt.line, percent, ok, tt.percent, tt.ok)
+		}
+	}
+}
+
+func TestParseBuildStep(t *testing.T) {
+	tests := []struct {
+		line        string
+		step, total int
+		instruction string
+		ok          bool
+	}{
+		{"Step 3/12 : RUN apt-get update", 3, 12, "RUN apt-get update", true},
+		{"#7 [3/12] RUN npm install -g @anthropic-ai/claude-code", 3, 12, "RUN npm install -g @anthropic-ai/claude-code", true},
+		{"#5 [builder 2/5] COPY . .", 2, 5, "COPY . .", true},
+		{"#7 0.512 Reading package lists...", 0, 0, "", false},
+		{"#1 [internal] load build definition from Dockerfile", 0, 0, "", false},
+		{"Successfully built 0123456789ab", 0, 0, "", false},
+	}
+	for _, tt := range tests {
+		step, total, instruction, ok := ParseBuildStep(tt.line)
+		if step != tt.step || total != tt.total || instruction != tt.instruction || ok != tt.ok {
+			t.Errorf("ParseBuildStep(%q) = %d, %d, %q, %v, want %d, %d, %q, %v",
+				tt.line, step, total, instruction, ok, tt.step, tt.total, tt.instruction, tt.ok)
+		}
+	}
+}
+
+func TestIndicatorRender(t *testing.T) {
+	i := &Indicator{label: "Creating volume", percent: -1}
+	if got := i.render(5 * time.Second); got != "⠋ Creating volume (5s)" {
+		t.Errorf("render() = %q", got)
+	}
+
+	i.percent = 42.4
+	i.frame = 1
+	i.detail = strings.Repeat("x", 80)
+	got := i.render(75 * time.Second)
+	want := "⠙ Creating volume  42% (1m15s)  " + strings.Repeat("x", maxDetailWidth-3) + "..."
+	if got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+}
+
+func TestIndicatorNonInteractive(t *testing.T) {
+	var out strings.Builder
+	i := start(&out, "Building image", false)
+	i.SetPercent(50)
+	i.SetDetail("RUN apt-get update")
+	i.Done(nil)
+	i.Done(errors.New("ignored"))
+
+	if got := out.String(); got != "Building image...\nBuilding image done in 0s\n" {
+		t.Errorf("non-interactive output = %q", got)
+	}
+
+	out.Reset()
+	start(&out, "Building image", false).Done(errors.New("boom"))
+	if got := out.String(); !strings.HasSuffix(got, "Building image failed after 0s\n") {
+		t.Errorf("non-interactive failure output = %q", got)
+	}
+}
+
+func TestIndicatorInteractive(t *testing.T) {
+	var out strings.Builder
+	i := start(&out, "Creating volume", true)
+	i.Done(nil)
+
+	// The spinner draws at least once before Done replaces it
+	got := out.String()
+	if !strings.HasPrefix(got, "\r\033[K⠋ Creating volume (0s)") || !strings.HasSuffix(got, "\r\033[K✓ Creating volume (0s)\n") {
+		t.Errorf("interactive output = %q", got)
+	}
+}
@@ -0,0 +1,513 @@
+// Package remotebackup uploads a locked volume image to object storage and
+// downloads it again, so losing the machine doesn't mean losing the volume.
+//
+// The image is split into chunks that are encrypted on this machine with
+// AES-256-GCM, under a key stretched from a backup password with Argon2id,
+// before they leave it. The storage provider sees only ciphertext and the
+// backup's size. A backup is laid out under its ID, a UTC timestamp:
+//
+//	<prefix>/<id>/chunk-00000, chunk-00001, ...
+//	<prefix>/<id>/kdf.json, manifest.json   (the image's sidecars, if any)
+//	<prefix>/<id>/index.json                (written last)
+//
+// The index records each chunk's size and the SHA-256 of its ciphertext, and
+// is authenticated with the same key, so a restore detects a wrong password,
+// a missing or altered chunk, and an edited index. A backup without an index
+// is incomplete. Uploads record their progress in a local state file, so an
+// interrupted backup resumes with the chunks it hasn't uploaded.
+package remotebackup
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+	"github.com/jeanhaley32/claude-capsule/internal/volume"
+)
+
+// chunkSize is how much of the image each chunk holds. It is a variable so
+// tests can use small chunks.
+var chunkSize int64 = 64 << 20
+
+const (
+	// IndexKey names a backup's index within its ID.
+	IndexKey = "index.json"
+
+	// idFormat names backups so they sort by creation time.
+	idFormat = "20060102T150405Z"
+
+	format = 1
+)
+
+// sidecarName returns the name a portable sidecar is backed up under: its
+// suffix without the leading dot, as in kdf.json.
+func sidecarName(suffix string) string {
+	return strings.TrimPrefix(suffix, ".")
+}
+
+// Chunk describes one encrypted object.
+type Chunk struct {
+	Size   int64  `json:"size"`   // Plaintext bytes
+	SHA256 string `json:"sha256"` // Of the uploaded ciphertext
+}
+
+// Index describes a complete backup.
+type Index struct {
+	Format         int              `json:"format"`
+	ID             string           `json:"id"`
+	Created        time.Time        `json:"created"`
+	CapsuleVersion string           `json:"capsule_version,omitempty"`
+	KDF            kdf.Params       `json:"kdf"`
+	ImageSize      int64            `json:"image_size"`
+	Chunks         []Chunk          `json:"chunks"`
+	Sidecars       map[string]Chunk `json:"sidecars,omitempty"`
+	MAC            string           `json:"mac"`
+}
+
+// state is an upload in progress, kept in the local state file.
+type state struct {
+	Remote       string     `json:"remote"`
+	VolumePath   string     `json:"volume_path"`
+	ImageSize    int64      `json:"image_size"`
+	ImageModTime time.Time  `json:"image_mod_time"`
+	ID           string     `json:"id"`
+	KDF          kdf.Params `json:"kdf"`
+	Check        string     `json:"check"` // Identifies the key without revealing it
+	Chunks       []Chunk    `json:"chunks"`
+}
+
+// BackupOptions configures Backup.
+type BackupOptions struct {
+	Store      Store
+	Remote     string // For the state file, to tell remotes apart
+	VolumePath string
+	Password   *terminal.SecurePassword
+	Version    string
+
+	// StatePath is the local file recording the upload's progress.
+	StatePath string
+
+	// Progress, if set, is called after each chunk is uploaded.
+	Progress func(done, total int)
+}
+
+// Backup uploads the image at opts.VolumePath and its sidecars, resuming an
+// interrupted upload of the same, unchanged image with the same password.
+// The image must not be mounted.
+func Backup(ctx context.Context, opts BackupOptions) (*Index, error) {
+	info, err := os.Stat(opts.VolumePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a volume image file; remote backups do not support sparse bundles yet", opts.VolumePath)
+	}
+	st, key, err := resumeOrBegin(opts, info)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+
+	f, err := os.Open(opts.VolumePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open volume: %w", err)
+	}
+	defer f.Close()
+
+	total := int((info.Size() + chunkSize - 1) / chunkSize)
+	if _, err := f.Seek(int64(len(st.Chunks))*chunkSize, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read volume: %w", err)
+	}
+	buf := make([]byte, chunkSize)
+	for i := len(st.Chunks); i < total; i++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to read volume: %w", err)
+		}
+		chunk, err := put(ctx, opts.Store, key, st.ID, chunkName(i), buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		st.Chunks = append(st.Chunks, chunk)
+		if err := writeState(opts.StatePath, st); err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, total)
+		}
+	}
+
+	index := &Index{
+		Format:         format,
+		ID:             st.ID,
+		Created:        time.Now().UTC(),
+		CapsuleVersion: opts.Version,
+		KDF:            st.KDF,
+		ImageSize:      info.Size(),
+		Chunks:         st.Chunks,
+	}
+	for _, suffix := range volume.PortableSidecarSuffixes {
+		path := opts.VolumePath + suffix
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		name := sidecarName(suffix)
+		chunk, err := put(ctx, opts.Store, key, st.ID, name, data)
+		if err != nil {
+			return nil, err
+		}
+		if index.Sidecars == nil {
+			index.Sidecars = make(map[string]Chunk)
+		}
+		index.Sidecars[name] = chunk
+	}
+	if index.MAC, err = index.mac(key); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := opts.Store.Put(ctx, path.Join(st.ID, IndexKey), data); err != nil {
+		return nil, fmt.Errorf("failed to upload backup index: %w", err)
+	}
+	if err := os.Remove(opts.StatePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove %s: %w", opts.StatePath, err)
+	}
+	return index, nil
+}
+
+// resumeOrBegin returns the state of an interrupted upload of the same
+// image to the same remote, or of a new one, and the key to encrypt with.
+func resumeOrBegin(opts BackupOptions, info os.FileInfo) (*state, []byte, error) {
+	st, err := readState(opts.StatePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if st != nil && st.Remote == opts.Remote && st.VolumePath == opts.VolumePath &&
+		st.ImageSize == info.Size() && st.ImageModTime.Equal(info.ModTime().UTC()) {
+		key, err := deriveKey(&st.KDF, opts.Password)
+		if err != nil {
+			return nil, nil, err
+		}
+		if hmac.Equal([]byte(check(key)), []byte(st.Check)) {
+			return st, key, nil
+		}
+		clear(key)
+		return nil, nil, fmt.Errorf("the password differs from the one the interrupted backup %s used; remove %s to start over", st.ID, opts.StatePath)
+	}
+
+	params, err := kdf.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := deriveKey(params, opts.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	st = &state{
+		Remote:       opts.Remote,
+		VolumePath:   opts.VolumePath,
+		ImageSize:    info.Size(),
+		ImageModTime: info.ModTime().UTC(),
+		ID:           time.Now().UTC().Format(idFormat),
+		KDF:          *params,
+		Check:        check(key),
+	}
+	if err := writeState(opts.StatePath, st); err != nil {
+		clear(key)
+		return nil, nil, err
+	}
+	return st, key, nil
+}
+
+// StatePath returns ~/.capsule/run/backup-<hash>.json for uploads of
+// volumePath to remote. Both are hashed, since they are a path and a URL.
+func StatePath(remote, volumePath string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(remote + "\n" + filepath.Clean(volumePath)))
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, "backup-"+hex.EncodeToString(sum[:6])+".json"), nil
+}
+
+// List returns the IDs of the complete backups in store, oldest first.
+func List(ctx context.Context, store Store) ([]string, error) {
+	keys, err := store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, key := range keys {
+		if id, ok := strings.CutSuffix(key, "/"+IndexKey); ok && !strings.Contains(id, "/") {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Restore downloads backup id to volumePath, writing its sidecars first and
+// each file through a temporary name, as volume.RestoreImage does. Every
+// chunk is checked against the index before it is written.
+func Restore(ctx context.Context, store Store, id, volumePath string, password *terminal.SecurePassword, progress func(done, total int)) (*Index, error) {
+	data, err := store.Get(ctx, path.Join(id, IndexKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup index: %w", err)
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid backup index: %w", err)
+	}
+	if index.Format != format || index.ID != id {
+		return nil, fmt.Errorf("unsupported backup index (format %d, id %q)", index.Format, index.ID)
+	}
+	key, err := deriveKey(&index.KDF, password)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	mac, err := index.mac(key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(mac), []byte(index.MAC)) {
+		return nil, fmt.Errorf("wrong password, or the backup index was modified")
+	}
+
+	if _, err := os.Stat(volumePath); err == nil {
+		return nil, fmt.Errorf("volume already exists at %s", volumePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(volumePath), constants.DirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(volumePath), err)
+	}
+	for _, suffix := range volume.PortableSidecarSuffixes {
+		name := sidecarName(suffix)
+		chunk, ok := index.Sidecars[name]
+		if !ok {
+			continue
+		}
+		data, err := get(ctx, store, key, id, name, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(volumePath+suffix, data); err != nil {
+			return nil, err
+		}
+	}
+
+	tmp := volumePath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.FilePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	err = func() error {
+		var written int64
+		for i, chunk := range index.Chunks {
+			data, err := get(ctx, store, key, id, chunkName(i), chunk)
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(data); err != nil {
+				return fmt.Errorf("failed to write %s: %w", tmp, err)
+			}
+			written += int64(len(data))
+			if progress != nil {
+				progress(i+1, len(index.Chunks))
+			}
+		}
+		if written != index.ImageSize {
+			return fmt.Errorf("backup %s holds %d bytes, but its index records %d", id, written, index.ImageSize)
+		}
+		return f.Close()
+	}()
+	if err == nil {
+		err = os.Rename(tmp, volumePath)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+	return &index, nil
+}
+
+// put encrypts data and uploads it as id/name.
+func put(ctx context.Context, store Store, key []byte, id, name string, data []byte) (Chunk, error) {
+	sealed, err := seal(key, id+"/"+name, data)
+	if err != nil {
+		return Chunk{}, err
+	}
+	if err := store.Put(ctx, path.Join(id, name), sealed); err != nil {
+		return Chunk{}, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	sum := sha256.Sum256(sealed)
+	return Chunk{Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// get downloads id/name, checks it against chunk, and decrypts it.
+func get(ctx context.Context, store Store, key []byte, id, name string, chunk Chunk) ([]byte, error) {
+	sealed, err := store.Get(ctx, path.Join(id, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	sum := sha256.Sum256(sealed)
+	if hex.EncodeToString(sum[:]) != chunk.SHA256 {
+		return nil, fmt.Errorf("%s of backup %s is corrupt: its checksum does not match the index", name, id)
+	}
+	data, err := open(key, id+"/"+name, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("%s of backup %s: %w", name, id, err)
+	}
+	if int64(len(data)) != chunk.Size {
+		return nil, fmt.Errorf("%s of backup %s holds %d bytes, but the index records %d", name, id, len(data), chunk.Size)
+	}
+	return data, nil
+}
+
+// seal encrypts data, binding it to its name so chunks can't be swapped.
+// The random nonce is prepended to the ciphertext.
+func seal(key []byte, name string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(data)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, data, []byte(name)), nil
+}
+
+// open decrypts what seal produced for name.
+func open(key []byte, name string, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is truncated")
+	}
+	data, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return data, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey stretches the backup password into an AES-256 key.
+func deriveKey(params *kdf.Params, password *terminal.SecurePassword) ([]byte, error) {
+	if params.KeyLen != 32 {
+		return nil, fmt.Errorf("backup key must be 32 bytes, got %d", params.KeyLen)
+	}
+	derived, err := params.Derive(password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup key parameters: %w", err)
+	}
+	defer derived.Clear()
+	key := make([]byte, params.KeyLen)
+	if _, err := hex.Decode(key, derived.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to derive backup key: %w", err)
+	}
+	return key, nil
+}
+
+// subkey derives a key for one purpose from the backup key.
+func subkey(key []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// check identifies key in the state file.
+func check(key []byte) string {
+	return hex.EncodeToString(subkey(key, "check"))
+}
+
+// mac authenticates everything in the index but the MAC itself.
+func (i *Index) mac(key []byte) (string, error) {
+	unsigned := *i
+	unsigned.MAC = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	h := hmac.New(sha256.New, subkey(key, "index"))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func chunkName(i int) string {
+	return fmt.Sprintf("chunk-%05d", i)
+}
+
+func readState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &st, nil
+}
+
+// writeState replaces the state file atomically, so a crash leaves the
+// previous chunk count rather than a truncated file.
+func writeState(path string, st *state) error {
+	if err := os.MkdirAll(filepath.Dir(path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup state: %w", err)
+	}
+	return writeFile(path, data)
+}
+
+// writeFile writes data to path through a temporary file.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
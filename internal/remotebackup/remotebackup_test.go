@@ -0,0 +1,204 @@
+package remotebackup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// memStore is a Store in memory. Puts fail once failAfter objects are
+// stored, if it is set.
+type memStore struct {
+	objects   map[string][]byte
+	failAfter int
+}
+
+func (s *memStore) Put(_ context.Context, key string, data []byte) error {
+	if s.failAfter > 0 && len(s.objects) >= s.failAfter {
+		return errors.New("connection reset")
+	}
+	s.objects[key] = bytes.Clone(data)
+	return nil
+}
+
+func (s *memStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return bytes.Clone(data), nil
+}
+
+func (s *memStore) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func password(s string) *terminal.SecurePassword {
+	return terminal.NewSecurePassword([]byte(s))
+}
+
+// newImage writes a fake volume image with a KDF sidecar.
+func newImage(t *testing.T) (volumePath string, content []byte) {
+	t.Helper()
+	chunkSize = 1024
+	t.Cleanup(func() { chunkSize = 64 << 20 })
+
+	volumePath = filepath.Join(t.TempDir(), "capsule.sparseimage")
+	content = bytes.Repeat([]byte("encrypted volume "), 200) // 3400 bytes, 4 chunks
+	if err := os.WriteFile(volumePath, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(kdf.Path(volumePath), []byte(`{"algorithm":"argon2id"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return volumePath, content
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	volumePath, content := newImage(t)
+	store := &memStore{objects: make(map[string][]byte)}
+	opts := BackupOptions{
+		Store:      store,
+		Remote:     "s3://bucket/capsule",
+		VolumePath: volumePath,
+		Password:   password("backup password"),
+		StatePath:  filepath.Join(t.TempDir(), "state.json"),
+	}
+	index, err := Backup(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Chunks) != 4 || index.ImageSize != int64(len(content)) {
+		t.Errorf("Backup() index has %d chunks of %d bytes", len(index.Chunks), index.ImageSize)
+	}
+	for key, data := range store.objects {
+		if bytes.Contains(data, []byte("encrypted volume")) {
+			t.Errorf("%s holds plaintext", key)
+		}
+	}
+	if _, err := os.Stat(opts.StatePath); !os.IsNotExist(err) {
+		t.Errorf("state file left behind after a complete backup: %v", err)
+	}
+	ids, err := List(context.Background(), store)
+	if err != nil || !slices.Equal(ids, []string{index.ID}) {
+		t.Errorf("List() = %v, %v; want [%s]", ids, err, index.ID)
+	}
+
+	restored := filepath.Join(t.TempDir(), "restored.sparseimage")
+	if _, err := Restore(context.Background(), store, index.ID, restored, password("wrong"), nil); err == nil {
+		t.Error("Restore() with the wrong password succeeded")
+	}
+	if _, err := Restore(context.Background(), store, index.ID, restored, password("backup password"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(restored); !bytes.Equal(got, content) {
+		t.Error("restored image differs from the original")
+	}
+	if got, _ := os.ReadFile(kdf.Path(restored)); string(got) != `{"algorithm":"argon2id"}` {
+		t.Errorf("restored KDF sidecar = %q", got)
+	}
+}
+
+func TestRestoreDetectsTampering(t *testing.T) {
+	volumePath, _ := newImage(t)
+	store := &memStore{objects: make(map[string][]byte)}
+	index, err := Backup(context.Background(), BackupOptions{
+		Store:      store,
+		VolumePath: volumePath,
+		Password:   password("backup password"),
+		StatePath:  filepath.Join(t.TempDir(), "state.json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swapping two chunks keeps each one intact but breaks the order
+	first, second := index.ID+"/chunk-00000", index.ID+"/chunk-00001"
+	store.objects[first], store.objects[second] = store.objects[second], store.objects[first]
+	restored := filepath.Join(t.TempDir(), "restored.sparseimage")
+	if _, err := Restore(context.Background(), store, index.ID, restored, password("backup password"), nil); err == nil {
+		t.Error("Restore() of swapped chunks succeeded")
+	}
+	if _, err := os.Stat(restored); !os.IsNotExist(err) {
+		t.Errorf("failed restore left an image behind: %v", err)
+	}
+}
+
+func TestBackupResumes(t *testing.T) {
+	volumePath, content := newImage(t)
+	store := &memStore{objects: make(map[string][]byte), failAfter: 2}
+	opts := BackupOptions{
+		Store:      store,
+		Remote:     "s3://bucket/capsule",
+		VolumePath: volumePath,
+		Password:   password("backup password"),
+		StatePath:  filepath.Join(t.TempDir(), "state.json"),
+	}
+	if _, err := Backup(context.Background(), opts); err == nil {
+		t.Fatal("Backup() succeeded despite the failing store")
+	}
+
+	opts.Password = password("another password")
+	if _, err := Backup(context.Background(), opts); err == nil {
+		t.Error("Backup() resumed with a different password")
+	}
+
+	store.failAfter = 0
+	var uploaded []int
+	opts.Password = password("backup password")
+	opts.Progress = func(done, total int) { uploaded = append(uploaded, done) }
+	index, err := Backup(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(uploaded, []int{3, 4}) {
+		t.Errorf("resumed backup uploaded chunks %v, want [3 4]", uploaded)
+	}
+
+	restored := filepath.Join(t.TempDir(), "restored.sparseimage")
+	if _, err := Restore(context.Background(), store, index.ID, restored, opts.Password, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(restored); !bytes.Equal(got, content) {
+		t.Error("image restored from a resumed backup differs from the original")
+	}
+}
+
+func TestParseRemote(t *testing.T) {
+	r, err := ParseRemote("s3://bucket/team/capsule/")
+	if err != nil || r.Bucket != "bucket" || r.Prefix != "team/capsule" || r.String() != "s3://bucket/team/capsule" {
+		t.Errorf("ParseRemote() = %+v, %v", r, err)
+	}
+	for _, value := range []string{"gs://bucket/prefix", "s3://", "/backups"} {
+		if _, err := ParseRemote(value); err == nil {
+			t.Errorf("ParseRemote(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestParseListing(t *testing.T) {
+	output := `2026-10-16 12:00:00   67108864 capsule/20261016T120000Z/chunk-00000
+2026-10-16 12:00:05        512 capsule/20261016T120000Z/index.json
+2026-10-16 12:00:05        512 other/file name.txt
+`
+	got := parseListing(output, "capsule")
+	want := []string{"20261016T120000Z/chunk-00000", "20261016T120000Z/index.json"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseListing() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package remotebackup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// S3Scheme prefixes S3 remotes.
+const S3Scheme = "s3://"
+
+// Remote is where backups are kept: a bucket and a key prefix in it.
+type Remote struct {
+	Bucket string
+	Prefix string
+}
+
+// ParseRemote parses a --remote value of the form s3://bucket/prefix.
+func ParseRemote(value string) (Remote, error) {
+	rest, ok := strings.CutPrefix(value, S3Scheme)
+	if !ok {
+		return Remote{}, fmt.Errorf("invalid remote %q: only %sbucket/prefix is supported", value, S3Scheme)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return Remote{}, fmt.Errorf("invalid remote %q: missing bucket", value)
+	}
+	return Remote{Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// String returns the remote as s3://bucket/prefix.
+func (r Remote) String() string {
+	if r.Prefix == "" {
+		return S3Scheme + r.Bucket
+	}
+	return S3Scheme + r.Bucket + "/" + r.Prefix
+}
+
+// Store holds a remote's objects, named by keys relative to its prefix.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// List returns the keys under prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// S3 is a Store backed by the AWS CLI (aws), so it uses the same
+// credentials, profiles, and endpoint settings as the rest of the user's
+// AWS tooling.
+type S3 struct {
+	Remote Remote
+}
+
+// url returns the s3:// URL of key.
+func (s *S3) url(key string) string {
+	return s.Remote.String() + "/" + key
+}
+
+// Put implements Store.
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.aws(ctx, bytes.NewReader(data), "s3", "cp", "--only-show-errors", "-", s.url(key))
+	return err
+}
+
+// Get implements Store.
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.aws(ctx, nil, "s3", "cp", "--only-show-errors", s.url(key), "-")
+}
+
+// List implements Store.
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	out, err := s.aws(ctx, nil, "s3", "ls", "--recursive", s.url(prefix))
+	if err != nil {
+		// aws exits 1 when nothing matches
+		var awsErr *awsError
+		if errors.As(err, &awsErr) && awsErr.code == 1 && awsErr.stderr == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseListing(string(out), s.Remote.Prefix), nil
+}
+
+// awsError is a failed aws command.
+type awsError struct {
+	args   []string
+	code   int
+	stderr string
+}
+
+func (e *awsError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("aws %s failed: %s", strings.Join(e.args[:2], " "), e.stderr)
+	}
+	return fmt.Sprintf("aws %s failed with exit code %d", strings.Join(e.args[:2], " "), e.code)
+}
+
+// aws runs the AWS CLI and returns its output.
+func (s *S3) aws(ctx context.Context, stdin *bytes.Reader, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return nil, fmt.Errorf("AWS CLI (aws) not found; install it from https://aws.amazon.com/cli/")
+	}
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	done := logging.Command(cmd)
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		code := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		return nil, &awsError{args: args, code: code, stderr: strings.TrimSpace(stderr.String())}
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseListing returns the keys in 'aws s3 ls --recursive' output, relative
+// to prefix. Each line is "date time size key".
+func parseListing(output, prefix string) []string {
+	var keys []string
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		// The key is everything after the size, spaces included
+		key := strings.TrimLeft(line[strings.Index(line, fields[2])+len(fields[2]):], " ")
+		if prefix != "" {
+			var ok bool
+			if key, ok = strings.CutPrefix(key, prefix+"/"); !ok {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
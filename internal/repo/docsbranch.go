@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Git config keys (set in the workspace repository) that control shadow docs sync.
+const (
+	// DocsSyncKey enables mirroring shadow docs to a branch on stop.
+	DocsSyncKey = "capsule.docsSync"
+
+	// DocsBranchKey overrides the branch the docs are committed to.
+	DocsBranchKey = "capsule.docsBranch"
+
+	// DocsRemoteKey, when set, is a remote name or URL the branch is pushed to
+	// after each sync. Point it at a separate docs repository to keep the
+	// branch out of the project's own remote.
+	DocsRemoteKey = "capsule.docsRemote"
+)
+
+// DefaultDocsBranch is the branch shadow docs are mirrored to.
+const DefaultDocsBranch = "capsule/docs"
+
+// docsSyncExcludes are shadow-docs paths that never belong in git: the
+// SQLite index and its journals, and the per-project beads database.
+var docsSyncExcludes = []string{
+	":(exclude).doc-index.db*",
+	":(exclude).beads",
+}
+
+// DocsSyncOptions configures mirroring of shadow docs to a git branch.
+type DocsSyncOptions struct {
+	Enabled bool
+	Branch  string
+	Remote  string // Empty to commit locally only
+}
+
+// DocsSyncResult describes the outcome of a sync.
+type DocsSyncResult struct {
+	Branch  string
+	Commit  string // Empty if nothing changed
+	Pushed  bool
+	Changed bool
+}
+
+// LoadDocsSyncOptions reads the docs sync settings from the workspace's git config.
+func LoadDocsSyncOptions(workspacePath string) DocsSyncOptions {
+	opts := DocsSyncOptions{Branch: DefaultDocsBranch}
+
+	if out, err := git(workspacePath, nil, "config", "--type=bool", "--get", DocsSyncKey); err == nil {
+		opts.Enabled = out == "true"
+	}
+	if out, err := git(workspacePath, nil, "config", "--get", DocsBranchKey); err == nil && out != "" {
+		opts.Branch = out
+	}
+	if out, err := git(workspacePath, nil, "config", "--get", DocsRemoteKey); err == nil {
+		opts.Remote = out
+	}
+	return opts
+}
+
+// SyncDocsBranch commits the contents of docsDir to opts.Branch in the workspace
+// repository without touching its working tree, index, or checked-out branch.
+// A commit is only created when the docs differ from the branch tip.
+func SyncDocsBranch(workspacePath, docsDir string, opts DocsSyncOptions) (*DocsSyncResult, error) {
+	branch := opts.Branch
+	if branch == "" {
+		branch = DefaultDocsBranch
+	}
+	result := &DocsSyncResult{Branch: branch}
+	ref := "refs/heads/" + branch
+
+	if _, err := git(workspacePath, nil, "check-ref-format", ref); err != nil {
+		return nil, fmt.Errorf("invalid docs branch name %q", branch)
+	}
+	if _, err := os.Stat(docsDir); err != nil {
+		return nil, fmt.Errorf("shadow docs not found at %s: %w", docsDir, err)
+	}
+
+	gitDir, err := git(workspacePath, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, fmt.Errorf("workspace is not a git repository: %w", err)
+	}
+
+	// Stage the docs into a throwaway index so the user's index is untouched
+	indexFile, err := os.CreateTemp("", "capsule-docs-index-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	indexPath := indexFile.Name()
+	indexFile.Close()
+	os.Remove(indexPath) // git refuses to read an empty index file
+	defer os.Remove(indexPath)
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	addArgs := append([]string{"--git-dir", gitDir, "--work-tree", docsDir, "add", "--all", "--force", "--", "."}, docsSyncExcludes...)
+	if _, err := git(docsDir, env, addArgs...); err != nil {
+		return nil, fmt.Errorf("failed to stage shadow docs: %w", err)
+	}
+
+	tree, err := git(workspacePath, env, "write-tree")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write docs tree: %w", err)
+	}
+
+	parent, _ := git(workspacePath, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if parent != "" {
+		parentTree, err := git(workspacePath, nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", branch, err)
+		}
+		if parentTree == tree {
+			return result, nil
+		}
+	}
+
+	commitArgs := []string{"commit-tree", tree, "-m", "Sync shadow docs " + time.Now().Format(time.RFC3339)}
+	if parent != "" {
+		commitArgs = append(commitArgs, "-p", parent)
+	}
+	commit, err := git(workspacePath, nil, commitArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit shadow docs: %w", err)
+	}
+
+	// Passing the old value makes the update fail if the branch moved underneath us
+	if _, err := git(workspacePath, nil, "update-ref", "-m", "capsule: sync shadow docs", ref, commit, parent); err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", branch, err)
+	}
+	result.Commit = commit
+	result.Changed = true
+
+	if opts.Remote != "" {
+		if _, err := git(workspacePath, nil, "push", "--quiet", opts.Remote, ref+":"+ref); err != nil {
+			return result, fmt.Errorf("committed %s but failed to push to %s: %w", branch, opts.Remote, err)
+		}
+		result.Pushed = true
+	}
+
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initDocsRepo creates a workspace repository with one commit, and a shadow
+// docs directory outside it.
+func initDocsRepo(t *testing.T) (workspace, docsDir string, run func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	// SyncDocsBranch runs git with the process environment
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	workspace = filepath.Join(dir, "workspace")
+	docsDir = filepath.Join(dir, "docs")
+	run = func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", workspace}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", workspace).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	writeTestFile(t, filepath.Join(workspace, "main.go"), "package main\n")
+	run("add", "main.go")
+	run("commit", "-q", "-m", "init")
+
+	writeTestFile(t, filepath.Join(docsDir, "_overview.md"), "# Overview\n")
+	writeTestFile(t, filepath.Join(docsDir, "auth", "_gotchas.md"), "# Gotchas\n")
+	writeTestFile(t, filepath.Join(docsDir, ".doc-index.db"), "sqlite")
+	writeTestFile(t, filepath.Join(docsDir, ".beads", "beads.db"), "sqlite")
+	return workspace, docsDir, run
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncDocsBranch(t *testing.T) {
+	workspace, docsDir, run := initDocsRepo(t)
+	opts := DocsSyncOptions{Enabled: true}
+
+	// The first sync creates the branch from the docs alone
+	result, err := SyncDocsBranch(workspace, docsDir, opts)
+	if err != nil {
+		t.Fatalf("SyncDocsBranch() error = %v", err)
+	}
+	if !result.Changed || result.Commit == "" || result.Branch != DefaultDocsBranch || result.Pushed {
+		t.Errorf("SyncDocsBranch() = %+v, want a new commit on %s", result, DefaultDocsBranch)
+	}
+	if got := run("rev-parse", DefaultDocsBranch); got != result.Commit {
+		t.Errorf("%s = %s, want %s", DefaultDocsBranch, got, result.Commit)
+	}
+	if got := run("ls-tree", "-r", "--name-only", DefaultDocsBranch); got != "_overview.md\nauth/_gotchas.md" {
+		t.Errorf("branch files = %q, want the docs without the index or beads", got)
+	}
+	if got := run("log", "--format=%H", DefaultDocsBranch); got != result.Commit {
+		t.Errorf("branch history = %q, want a single root commit", got)
+	}
+
+	// Syncing unchanged docs is a no-op
+	first := result.Commit
+	result, err = SyncDocsBranch(workspace, docsDir, opts)
+	if err != nil {
+		t.Fatalf("SyncDocsBranch() again error = %v", err)
+	}
+	if result.Changed || result.Commit != "" {
+		t.Errorf("SyncDocsBranch() with no changes = %+v, want no commit", result)
+	}
+	if got := run("rev-parse", DefaultDocsBranch); got != first {
+		t.Errorf("%s moved to %s on a no-op sync", DefaultDocsBranch, got)
+	}
+
+	// Changed docs are committed on top of the branch
+	writeTestFile(t, filepath.Join(docsDir, "_overview.md"), "# Overview\n\nUpdated.\n")
+	result, err = SyncDocsBranch(workspace, docsDir, opts)
+	if err != nil {
+		t.Fatalf("SyncDocsBranch() after a change error = %v", err)
+	}
+	if !result.Changed {
+		t.Fatalf("SyncDocsBranch() after a change = %+v, want a commit", result)
+	}
+	if got := run("rev-parse", result.Commit+"^"); got != first {
+		t.Errorf("new commit's parent = %s, want %s", got, first)
+	}
+}
+
+func TestSyncDocsBranchLeavesWorktreeAlone(t *testing.T) {
+	workspace, docsDir, run := initDocsRepo(t)
+
+	// Leave a staged change, an unstaged change, and an untracked file
+	writeTestFile(t, filepath.Join(workspace, "staged.go"), "package main\n")
+	run("add", "staged.go")
+	writeTestFile(t, filepath.Join(workspace, "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(workspace, "untracked.txt"), "scratch\n")
+
+	head := run("rev-parse", "HEAD")
+	status := run("status", "--porcelain")
+	staged := run("diff", "--cached", "--name-only")
+
+	if _, err := SyncDocsBranch(workspace, docsDir, DocsSyncOptions{Enabled: true, Branch: "docs/shadow"}); err != nil {
+		t.Fatalf("SyncDocsBranch() error = %v", err)
+	}
+
+	if got := run("rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+	if got := run("symbolic-ref", "--short", "HEAD"); got != "main" {
+		t.Errorf("checked-out branch = %s, want main", got)
+	}
+	if got := run("status", "--porcelain"); got != status {
+		t.Errorf("status = %q, want %q", got, status)
+	}
+	if got := run("diff", "--cached", "--name-only"); got != staged {
+		t.Errorf("staged files = %q, want %q", got, staged)
+	}
+	if got, err := os.ReadFile(filepath.Join(workspace, "main.go")); err != nil || string(got) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("main.go = %q, %v; want the unstaged change kept", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "_overview.md")); !os.IsNotExist(err) {
+		t.Errorf("docs were written into the worktree: %v", err)
+	}
+	if got := run("ls-tree", "-r", "--name-only", "docs/shadow"); got != "_overview.md\nauth/_gotchas.md" {
+		t.Errorf("branch files = %q", got)
+	}
+}
+
+func TestSyncDocsBranchInvalidBranch(t *testing.T) {
+	workspace, docsDir, _ := initDocsRepo(t)
+	if _, err := SyncDocsBranch(workspace, docsDir, DocsSyncOptions{Branch: "bad..name"}); err == nil {
+		t.Error("SyncDocsBranch() accepted an invalid branch name")
+	}
+	if _, err := SyncDocsBranch(workspace, filepath.Join(docsDir, "missing"), DocsSyncOptions{}); err == nil {
+		t.Error("SyncDocsBranch() accepted a missing docs directory")
+	}
+}
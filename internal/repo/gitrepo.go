@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrNotRepository is returned when a path is not inside a git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// ErrBareRepository is returned for a bare repository, which has no working
+// tree to mount into a container.
+var ErrBareRepository = errors.New("bare git repository has no working tree; run capsule from a clone or worktree")
+
+// errNoOrigin is returned when a repository has no remote named origin.
+var errNoOrigin = errors.New("repository has no origin remote")
+
+// Checkout describes the repository containing a path.
+type Checkout struct {
+	Root     string // Working tree root, with symlinks resolved; empty for a bare repository
+	Bare     bool
+	Branch   string // Checked-out branch; empty when HEAD is detached or unborn
+	Detached bool   // HEAD points at a commit rather than a branch
+	Head     string // Abbreviated commit HEAD points at; empty before the first commit
+}
+
+// Describe returns the checkout state of the repository containing path.
+// It reads the repository with go-git, so it works without git installed.
+func Describe(path string) (*Checkout, error) {
+	r, err := openRepository(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout := &Checkout{}
+	wt, err := r.Worktree()
+	switch {
+	case errors.Is(err, gogit.ErrIsBareRepository):
+		checkout.Bare = true
+	case err != nil:
+		return nil, fmt.Errorf("failed to read worktree of %s: %w", path, err)
+	default:
+		checkout.Root = resolveSymlinks(wt.Filesystem.Root())
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		// Before the first commit HEAD names a branch that doesn't exist yet
+		if ref, err := r.Storer.Reference(plumbing.HEAD); err == nil && ref.Type() == plumbing.SymbolicReference {
+			checkout.Branch = ref.Target().Short()
+		}
+		return checkout, nil
+	}
+	checkout.Head = head.Hash().String()[:7]
+	if head.Name().IsBranch() {
+		checkout.Branch = head.Name().Short()
+	} else {
+		checkout.Detached = true
+	}
+	return checkout, nil
+}
+
+// String summarizes the checkout for status output.
+func (c *Checkout) String() string {
+	switch {
+	case c.Bare:
+		return "bare repository (no working tree)"
+	case c.Detached:
+		return "detached HEAD at " + c.Head
+	case c.Branch != "" && c.Head == "":
+		return c.Branch + " (no commits yet)"
+	default:
+		return c.Branch
+	}
+}
+
+// openRepository opens the repository containing path, or the bare repository
+// at path. Linked worktrees read their remotes and config from the main
+// repository.
+func openRepository(path string) (*gogit.Repository, error) {
+	r, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		// Searching for .git misses a bare repository, whose directory is the git dir
+		r, err = gogit.PlainOpen(path)
+	}
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotRepository)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository at %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// repositoryRoot returns the working tree root of the repository containing
+// path. If go-git can't read the repository (e.g. it uses an extension go-git
+// doesn't support), the git CLI is tried instead.
+func repositoryRoot(path string) (string, error) {
+	checkout, err := Describe(path)
+	if err == nil {
+		if checkout.Bare {
+			return "", fmt.Errorf("%s: %w", path, ErrBareRepository)
+		}
+		return checkout.Root, nil
+	}
+	if errors.Is(err, ErrNotRepository) {
+		return "", err
+	}
+
+	slog.Debug("go-git could not read repository, trying git", "path", path, "error", err)
+	root, cliErr := git(path, nil, "rev-parse", "--show-toplevel")
+	if cliErr != nil {
+		return "", fmt.Errorf("%w (git: %v)", err, cliErr)
+	}
+	return root, nil
+}
+
+// originURL returns the URL of the repository's origin remote, falling back to
+// the git CLI if go-git can't read the repository.
+func originURL(path string) (string, error) {
+	r, err := openRepository(path)
+	if err == nil {
+		remote, err := r.Remote("origin")
+		if errors.Is(err, gogit.ErrRemoteNotFound) {
+			return "", errNoOrigin
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read origin remote: %w", err)
+		}
+		if urls := remote.Config().URLs; len(urls) > 0 && urls[0] != "" {
+			return urls[0], nil
+		}
+		return "", errNoOrigin
+	}
+	if errors.Is(err, ErrNotRepository) {
+		return "", err
+	}
+
+	slog.Debug("go-git could not read repository, trying git", "path", path, "error", err)
+	url, cliErr := git(path, nil, "remote", "get-url", "origin")
+	if cliErr != nil {
+		return "", fmt.Errorf("%w (git: %v)", err, cliErr)
+	}
+	return url, nil
+}
+
+// resolveSymlinks returns path with symlinks resolved, matching the canonical
+// paths git itself reports, or path unchanged if it can't be resolved.
+func resolveSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
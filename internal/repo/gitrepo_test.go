@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// initGoGitRepo creates a repository with one commit and an origin remote
+// without needing git installed.
+func initGoGitRepo(t *testing.T) (string, *gogit.Repository) {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gogit.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/user/repo.git"}}); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("init", &gogit.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return root, r
+}
+
+func TestDescribe(t *testing.T) {
+	root, r := initGoGitRepo(t)
+	sub := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	checkout, err := Describe(sub)
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	if checkout.Root != root || checkout.Bare || checkout.Detached || checkout.Branch != "master" || len(checkout.Head) != 7 {
+		t.Errorf("Describe() = %+v, want branch master at %s", checkout, root)
+	}
+	if got := checkout.String(); got != "master" {
+		t.Errorf("String() = %q, want master", got)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, _ := r.Worktree()
+	if err := wt.Checkout(&gogit.CheckoutOptions{Hash: head.Hash()}); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err = Describe(root)
+	if err != nil {
+		t.Fatalf("Describe(detached) error = %v", err)
+	}
+	if !checkout.Detached || checkout.Branch != "" || checkout.String() != "detached HEAD at "+head.Hash().String()[:7] {
+		t.Errorf("Describe(detached) = %+v (%s)", checkout, checkout)
+	}
+
+	if _, err := Describe(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Describe(not a repo) error = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestDescribeUnbornAndBare(t *testing.T) {
+	empty := t.TempDir()
+	if _, err := gogit.PlainInit(empty, false); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err := Describe(empty)
+	if err != nil {
+		t.Fatalf("Describe(empty) error = %v", err)
+	}
+	if checkout.Branch != "master" || checkout.Head != "" || checkout.String() != "master (no commits yet)" {
+		t.Errorf("Describe(empty) = %+v", checkout)
+	}
+
+	bare := t.TempDir()
+	if _, err := gogit.PlainInit(bare, true); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err = Describe(bare)
+	if err != nil {
+		t.Fatalf("Describe(bare) error = %v", err)
+	}
+	if !checkout.Bare || checkout.Root != "" {
+		t.Errorf("Describe(bare) = %+v, want a bare repository", checkout)
+	}
+	if _, err := NewIdentifier().GetWorkspaceRoot(bare); !errors.Is(err, ErrBareRepository) {
+		t.Errorf("GetWorkspaceRoot(bare) error = %v, want ErrBareRepository", err)
+	}
+}
+
+func TestIdentifierWithGoGit(t *testing.T) {
+	root, _ := initGoGitRepo(t)
+	sub := filepath.Join(root, "docs")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	id := NewIdentifierWithPolicy(WorktreeShared)
+	if got, err := id.GetWorkspaceRoot(sub); err != nil || got != root {
+		t.Errorf("GetWorkspaceRoot() = %q, %v, want %q", got, err, root)
+	}
+	if got, err := id.GetRepoID(root); err != nil || got != "github.com-user-repo" {
+		t.Errorf("GetRepoID() = %q, %v, want github.com-user-repo", got, err)
+	}
+
+	// Without an origin remote the directory name is used
+	plain := filepath.Join(t.TempDir(), "my project")
+	if _, err := gogit.PlainInit(plain, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := id.GetRepoID(plain); err != nil || got != "my-project" {
+		t.Errorf("GetRepoID(no origin) = %q, %v, want my-project", got, err)
+	}
+}
@@ -1,13 +1,20 @@
 package repo
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
 )
 
 // Pre-compiled regexes for sanitization (compiled once at package init)
@@ -23,20 +30,217 @@ const maxIdentifierLength = 100
 // ShortIDLength is the length of the short unique identifier (8 hex chars = 4 bytes)
 const ShortIDLength = 8
 
+// WorktreePolicy controls how linked git worktrees map to shadow docs and containers.
+type WorktreePolicy string
+
+const (
+	// WorktreeShared gives every worktree of a repository the same _docs and container.
+	WorktreeShared WorktreePolicy = "shared"
+
+	// WorktreePerWorktree gives each linked worktree its own _docs and container.
+	WorktreePerWorktree WorktreePolicy = "per-worktree"
+)
+
+// WorktreePolicyKey is the git config key that sets the default policy for a repository.
+// It lives in the common config, so it applies to all of the repository's worktrees.
+const WorktreePolicyKey = "capsule.worktreePolicy"
+
+// maxWorktreeNameLength caps the worktree name portion of a per-worktree ID.
+const maxWorktreeNameLength = 32
+
+// worktreeSuffix separates the repository ID from the worktree name in per-worktree IDs.
+const worktreeSuffix = "-wt-"
+
+// ParseWorktreePolicy validates a policy name. An empty string is returned as-is,
+// meaning "use the repository's configured policy".
+func ParseWorktreePolicy(s string) (WorktreePolicy, error) {
+	switch p := WorktreePolicy(s); p {
+	case "", WorktreeShared, WorktreePerWorktree:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid worktree policy %q (valid: %s, %s)", s, WorktreeShared, WorktreePerWorktree)
+	}
+}
+
+// WorktreeInfo describes the git worktree containing a path.
+type WorktreeInfo struct {
+	Linked   bool   // True for worktrees created with 'git worktree add'
+	Name     string // Worktree name (empty for the main worktree)
+	MainRoot string // Root of the main worktree
+}
+
+// DetectWorktree reports whether path is inside a linked git worktree.
+func DetectWorktree(path string) (*WorktreeInfo, error) {
+	gitDir, err := git(path, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	commonDir, err := git(path, nil, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git common dir: %w", err)
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(path, commonDir)
+	}
+
+	info := &WorktreeInfo{MainRoot: filepath.Dir(filepath.Clean(commonDir))}
+	if filepath.Clean(gitDir) != filepath.Clean(commonDir) {
+		info.Linked = true
+		info.Name = filepath.Base(gitDir)
+	}
+	return info, nil
+}
+
+// SubprojectKey is the git config key listing monorepo subprojects (one path
+// per value, relative to the repository root). Working inside a listed path
+// selects that subproject automatically.
+const SubprojectKey = "capsule.subproject"
+
 // DefaultIdentifier implements Identifier using git commands.
-type DefaultIdentifier struct{}
+type DefaultIdentifier struct {
+	// Policy overrides the repository's configured worktree policy when set.
+	Policy WorktreePolicy
 
-// NewIdentifier creates a new repository identifier.
+	// Subproject, when set, is a path relative to the repository root whose
+	// _docs and memory are kept separate: the repo ID becomes "repoID/subpath".
+	Subproject string
+}
+
+// NewIdentifier creates a new repository identifier that follows each
+// repository's configured worktree policy.
 func NewIdentifier() *DefaultIdentifier {
 	return &DefaultIdentifier{}
 }
 
+// NewIdentifierWithPolicy creates a repository identifier with an explicit worktree policy.
+func NewIdentifierWithPolicy(policy WorktreePolicy) *DefaultIdentifier {
+	return &DefaultIdentifier{Policy: policy}
+}
+
+// ResolvePolicy returns the worktree policy in effect for workspacePath:
+// the explicit policy if set, else the repository's git config, else shared.
+func (d *DefaultIdentifier) ResolvePolicy(workspacePath string) WorktreePolicy {
+	if d.Policy != "" {
+		return d.Policy
+	}
+	if out, err := git(workspacePath, nil, "config", "--get", WorktreePolicyKey); err == nil {
+		if policy, err := ParseWorktreePolicy(out); err == nil && policy != "" {
+			return policy
+		}
+	}
+	return WorktreeShared
+}
+
+// GetRepoID returns the repository ID for the workspace. Under the per-worktree
+// policy, linked worktrees get the worktree name appended so their _docs and
+// container are separate from the main checkout. A subproject adds "/subpath".
 func (d *DefaultIdentifier) GetRepoID(workspacePath string) (string, error) {
-	// Try to get git remote URL
-	cmd := exec.Command("git", "-C", workspacePath, "remote", "get-url", "origin")
-	output, err := cmd.Output()
+	repoID, err := d.worktreeRepoID(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	if d.Subproject == "" {
+		return repoID, nil
+	}
+
+	subpath, err := NormalizeSubproject(d.Subproject)
+	if err != nil {
+		return "", err
+	}
+	return repoID + "/" + subpath, nil
+}
+
+// NormalizeSubproject converts a repository-relative path into the filesystem-safe
+// subpath used in subproject IDs (e.g. "services/API v2" -> "services/API-v2").
+func NormalizeSubproject(subproject string) (string, error) {
+	cleaned := filepath.ToSlash(filepath.Clean(subproject))
+	if filepath.IsAbs(subproject) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("subproject must be a path inside the repository: %s", subproject)
+	}
+
+	segments := strings.Split(cleaned, "/")
+	for i, segment := range segments {
+		segments[i] = strings.TrimLeft(sanitizeName(segment), ".")
+		if segments[i] == "" {
+			return "", fmt.Errorf("invalid subproject path: %s", subproject)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// DetectSubproject returns the configured subproject (see SubprojectKey) that
+// contains path, preferring the most specific match. Returns "" if none applies.
+func DetectSubproject(path string) string {
+	root, err := git(path, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	configured, err := git(path, nil, "config", "--get-all", SubprojectKey)
+	if err != nil || configured == "" {
+		return ""
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	// Resolve symlinks so the comparison matches git's canonical toplevel
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	best := ""
+	for _, sub := range strings.Split(configured, "\n") {
+		sub = strings.Trim(filepath.ToSlash(filepath.Clean(strings.TrimSpace(sub))), "/")
+		if sub == "" || sub == "." {
+			continue
+		}
+		if (rel == sub || strings.HasPrefix(rel, sub+"/")) && len(sub) > len(best) {
+			best = sub
+		}
+	}
+	return best
+}
+
+// worktreeRepoID returns the repository ID with the worktree policy applied.
+func (d *DefaultIdentifier) worktreeRepoID(workspacePath string) (string, error) {
+	repoID, err := d.baseRepoID(workspacePath)
+	if err != nil {
+		return "", err
+	}
+
+	if d.ResolvePolicy(workspacePath) != WorktreePerWorktree {
+		return repoID, nil
+	}
+	info, err := DetectWorktree(workspacePath)
+	if err != nil || !info.Linked {
+		return repoID, nil
+	}
+
+	name := sanitizeName(info.Name)
+	if len(name) > maxWorktreeNameLength {
+		name = name[:maxWorktreeNameLength]
+	}
+	suffix := worktreeSuffix + name
+	if len(repoID)+len(suffix) > maxIdentifierLength {
+		repoID = strings.TrimRight(repoID[:maxIdentifierLength-len(suffix)], "-")
+	}
+	return repoID + suffix, nil
+}
+
+// baseRepoID identifies the repository itself, ignoring worktrees.
+func (d *DefaultIdentifier) baseRepoID(workspacePath string) (string, error) {
+	url, err := originURL(workspacePath)
 	if err != nil {
 		// Not a git repo or no remote, use directory name
+		if !errors.Is(err, ErrNotRepository) {
+			slog.Info("using directory name as repository ID", "path", workspacePath, "reason", err)
+		}
 		absPath, err := filepath.Abs(workspacePath)
 		if err != nil {
 			return "", fmt.Errorf("failed to get absolute path: %w", err)
@@ -44,43 +248,47 @@ func (d *DefaultIdentifier) GetRepoID(workspacePath string) (string, error) {
 		return sanitizeName(filepath.Base(absPath)), nil
 	}
 
-	return normalizeRemoteURL(strings.TrimSpace(string(output))), nil
+	return normalizeRemoteURL(url), nil
 }
 
-// getShortID returns a short unique identifier for the workspace.
-// This is a hash-based ID suitable for container names and mount paths.
-// Format: 8 character hex string (e.g., "a1b2c3d4")
-func (d *DefaultIdentifier) getShortID(workspacePath string) (string, error) {
-	repoID, err := d.GetRepoID(workspacePath)
-	if err != nil {
-		return "", err
-	}
+// sha256Hex returns the hex-encoded SHA-256 of s.
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
 
-	// Hash the repoID to get a consistent short identifier
-	hash := sha256.Sum256([]byte(repoID))
-	return hex.EncodeToString(hash[:])[:ShortIDLength], nil
+// ContainerName returns the container name for a repository ID.
+// The short ID is a hash of the repo ID, suitable for container names and mount paths.
+// Format: "claude-<shortID>" (e.g., "claude-a1b2c3d4")
+func ContainerName(repoID string) string {
+	return constants.ContainerNamePrefix + sha256Hex(repoID)[:ShortIDLength]
 }
 
 // GetContainerName returns the container name for the workspace.
-// Format: "claude-<shortID>" (e.g., "claude-a1b2c3d4")
 func (d *DefaultIdentifier) GetContainerName(workspacePath string) (string, error) {
-	shortID, err := d.getShortID(workspacePath)
+	repoID, err := d.GetRepoID(workspacePath)
 	if err != nil {
 		return "", err
 	}
-	return "claude-" + shortID, nil
+	return ContainerName(repoID), nil
 }
 
+// GetWorkspaceRoot returns the repository root containing path, or path itself
+// outside a repository. A bare repository is an error: it has nothing to mount.
 func (d *DefaultIdentifier) GetWorkspaceRoot(path string) (string, error) {
-	// Try to get git root
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel")
-	output, err := cmd.Output()
+	root, err := repositoryRoot(path)
+	if errors.Is(err, ErrBareRepository) {
+		return "", err
+	}
 	if err != nil {
 		// Not a git repo, return the provided path
+		if !errors.Is(err, ErrNotRepository) {
+			slog.Warn("could not find repository root, using the directory itself", "path", path, "error", err)
+		}
 		return filepath.Abs(path)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return root, nil
 }
 
 // normalizeRemoteURL converts a git remote URL to a filesystem-safe identifier.
@@ -133,3 +341,21 @@ func sanitizeName(name string) string {
 
 	return name
 }
+
+// git runs a git command in dir with extra environment variables and returns trimmed stdout.
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", filepath.Clean(dir)}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	done := logging.Command(cmd)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	done(err)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
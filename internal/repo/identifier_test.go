@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepoWithWorktree creates a repository with an origin remote and a linked worktree.
+func initRepoWithWorktree(t *testing.T) (mainRoot, worktreeRoot string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	mainRoot = filepath.Join(dir, "main")
+	worktreeRoot = filepath.Join(dir, "feature")
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("init", "-q", mainRoot)
+	run("-C", mainRoot, "remote", "add", "origin", "git@github.com:user/repo.git")
+	run("-C", mainRoot, "commit", "-q", "--allow-empty", "-m", "init")
+	run("-C", mainRoot, "worktree", "add", "-q", "-b", "feature", worktreeRoot)
+	return mainRoot, worktreeRoot
+}
+
+func TestDetectWorktree(t *testing.T) {
+	mainRoot, worktreeRoot := initRepoWithWorktree(t)
+
+	info, err := DetectWorktree(mainRoot)
+	if err != nil {
+		t.Fatalf("DetectWorktree(main) error = %v", err)
+	}
+	if info.Linked {
+		t.Errorf("DetectWorktree(main).Linked = true, want false")
+	}
+
+	info, err = DetectWorktree(worktreeRoot)
+	if err != nil {
+		t.Fatalf("DetectWorktree(worktree) error = %v", err)
+	}
+	if !info.Linked || info.Name != "feature" {
+		t.Errorf("DetectWorktree(worktree) = %+v, want linked worktree named feature", info)
+	}
+}
+
+func TestGetRepoID_WorktreePolicy(t *testing.T) {
+	mainRoot, worktreeRoot := initRepoWithWorktree(t)
+
+	tests := []struct {
+		name      string
+		policy    WorktreePolicy
+		workspace string
+		want      string
+	}{
+		{"shared main", WorktreeShared, mainRoot, "github.com-user-repo"},
+		{"shared worktree", WorktreeShared, worktreeRoot, "github.com-user-repo"},
+		{"per-worktree main", WorktreePerWorktree, mainRoot, "github.com-user-repo"},
+		{"per-worktree worktree", WorktreePerWorktree, worktreeRoot, "github.com-user-repo-wt-feature"},
+		{"default is shared", "", worktreeRoot, "github.com-user-repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewIdentifierWithPolicy(tt.policy).GetRepoID(tt.workspace)
+			if err != nil {
+				t.Fatalf("GetRepoID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetRepoID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The policy can also come from the repository's git config
+	if out, err := exec.Command("git", "-C", mainRoot, "config", WorktreePolicyKey, string(WorktreePerWorktree)).CombinedOutput(); err != nil {
+		t.Fatalf("git config: %v\n%s", err, out)
+	}
+	got, err := NewIdentifier().GetRepoID(worktreeRoot)
+	if err != nil {
+		t.Fatalf("GetRepoID() error = %v", err)
+	}
+	if got != "github.com-user-repo-wt-feature" {
+		t.Errorf("GetRepoID() with configured policy = %q, want per-worktree ID", got)
+	}
+}
+
+func TestNormalizeSubproject(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"services/api", "services/api", false},
+		{"services/api/", "services/api", false},
+		{"./web", "web", false},
+		{"services/API v2", "services/API-v2", false},
+		{"..", "", true},
+		{"../other", "", true},
+		{"/abs/path", "", true},
+		{".", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeSubproject(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeSubproject(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSubproject(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSubproject(t *testing.T) {
+	mainRoot, _ := initRepoWithWorktree(t)
+	for _, sub := range []string{"services", "services/api"} {
+		if out, err := exec.Command("git", "-C", mainRoot, "config", "--add", SubprojectKey, sub).CombinedOutput(); err != nil {
+			t.Fatalf("git config: %v\n%s", err, out)
+		}
+	}
+	nested := filepath.Join(mainRoot, "services", "api", "handlers")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DetectSubproject(nested); got != "services/api" {
+		t.Errorf("DetectSubproject(nested) = %q, want services/api", got)
+	}
+	if got := DetectSubproject(mainRoot); got != "" {
+		t.Errorf("DetectSubproject(root) = %q, want empty", got)
+	}
+
+	identifier := &DefaultIdentifier{Subproject: "services/api"}
+	got, err := identifier.GetRepoID(mainRoot)
+	if err != nil {
+		t.Fatalf("GetRepoID() error = %v", err)
+	}
+	if got != "github.com-user-repo/services/api" {
+		t.Errorf("GetRepoID() = %q, want github.com-user-repo/services/api", got)
+	}
+}
@@ -5,6 +5,7 @@ type Identifier interface {
 	// GetRepoID returns a unique, filesystem-safe identifier for the repository.
 	// For git repos, this is derived from the remote URL.
 	// For non-git directories, this is derived from the directory name.
+	// Linked worktrees share the repository's ID unless the per-worktree policy applies.
 	GetRepoID(workspacePath string) (string, error)
 
 	// GetWorkspaceRoot returns the root directory of the workspace.
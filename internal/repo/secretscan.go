@@ -0,0 +1,15 @@
+package repo
+
+// SecretScanKey sets the default for 'capsule stop' and 'capsule lock' to scan
+// the workspace for leaked credentials: "warn", "block", or "off".
+const SecretScanKey = "capsule.secretScan"
+
+// LoadSecretScanMode reads the secret scan setting from the workspace's git
+// config, returning "" if it is not set.
+func LoadSecretScanMode(workspacePath string) string {
+	out, err := git(workspacePath, nil, "config", "--get", SecretScanKey)
+	if err != nil {
+		return ""
+	}
+	return out
+}
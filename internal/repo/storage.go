@@ -0,0 +1,233 @@
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Paths within the encrypted volume.
+const (
+	// ReposDir holds per-repository data (shadow docs, memory, beads).
+	ReposDir = "repos"
+
+	// ArchiveDir holds compressed archives of repository folders removed from ReposDir.
+	ArchiveDir = "archive/repos"
+)
+
+// Usage summarizes the files under a directory.
+type Usage struct {
+	Bytes        int64
+	Files        int
+	LastModified time.Time // Most recent modification of any entry
+}
+
+// StoredRepo is a per-repository folder in the encrypted volume.
+type StoredRepo struct {
+	ID   string
+	Path string
+	Usage
+}
+
+// ValidateRepoID rejects IDs that could escape the repos directory.
+// Subproject IDs ("repoID/subpath") are validated segment by segment.
+func ValidateRepoID(repoID string) error {
+	if repoID == "" {
+		return fmt.Errorf("repository ID is required")
+	}
+	for _, segment := range strings.Split(repoID, "/") {
+		if segment != sanitizeName(segment) || strings.HasPrefix(segment, ".") {
+			return fmt.Errorf("invalid repository ID: %s", repoID)
+		}
+	}
+	return nil
+}
+
+// DiskUsage walks dir and totals the size of regular files.
+func DiskUsage(dir string) (Usage, error) {
+	var usage Usage
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(usage.LastModified) {
+			usage.LastModified = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			usage.Bytes += info.Size()
+			usage.Files++
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// ListStored returns every repository folder in the mounted volume, sorted by ID.
+func ListStored(mountPoint string) ([]StoredRepo, error) {
+	reposDir := filepath.Join(mountPoint, ReposDir)
+	entries, err := os.ReadDir(reposDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", reposDir, err)
+	}
+
+	var repos []StoredRepo
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateRepoID(entry.Name()) != nil {
+			continue
+		}
+		stored, err := GetStored(mountPoint, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, *stored)
+	}
+	return repos, nil
+}
+
+// GetStored returns the repository folder for repoID.
+func GetStored(mountPoint, repoID string) (*StoredRepo, error) {
+	if err := ValidateRepoID(repoID); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(mountPoint, ReposDir, repoID)
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no data for repository: %s", repoID)
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	usage, err := DiskUsage(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", path, err)
+	}
+	return &StoredRepo{ID: repoID, Path: path, Usage: usage}, nil
+}
+
+// RemoveStored deletes the repository folder for repoID.
+func RemoveStored(mountPoint, repoID string) error {
+	stored, err := GetStored(mountPoint, repoID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(stored.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", stored.Path, err)
+	}
+	return nil
+}
+
+// ArchiveStored compresses the repository folder for repoID into ArchiveDir and
+// then removes it. Returns the path of the archive.
+func ArchiveStored(mountPoint, repoID string) (string, error) {
+	stored, err := GetStored(mountPoint, repoID)
+	if err != nil {
+		return "", err
+	}
+
+	archiveDir := filepath.Join(mountPoint, ArchiveDir)
+	if err := os.MkdirAll(archiveDir, constants.DirPermissions); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", archiveDir, err)
+	}
+
+	archiveName := strings.ReplaceAll(repoID, "/", "--")
+	archivePath := filepath.Join(archiveDir, fmt.Sprintf("%s-%s.tar.gz", archiveName, time.Now().Format("20060102-150405")))
+	if err := writeTarGz(archivePath, stored.Path, repoID); err != nil {
+		os.Remove(archivePath)
+		return "", fmt.Errorf("failed to archive %s: %w", repoID, err)
+	}
+
+	if err := os.RemoveAll(stored.Path); err != nil {
+		return archivePath, fmt.Errorf("archived to %s but failed to remove %s: %w", archivePath, stored.Path, err)
+	}
+	return archivePath, nil
+}
+
+// writeTarGz writes the contents of srcDir to a gzipped tarball rooted at prefix.
+func writeTarGz(archivePath, srcDir, prefix string) error {
+	f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, constants.FilePermissions)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// Sockets (e.g. the bd daemon's) and pipes cannot be archived and are recreated on use
+		if info.Mode()&(os.ModeSocket|os.ModeNamedPipe) != 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestValidateRepoID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"github.com-user-repo", false},
+		{"my_project.v2", false},
+		{"", true},
+		{"..", true},
+		{".beads", true},
+		{"github.com-user-mono/services/api", false},
+		{"a/../b", true},
+		{"a//b", true},
+		{"a/.hidden", true},
+		{"../etc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateRepoID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRepoID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArchiveStored(t *testing.T) {
+	mountPoint := t.TempDir()
+	repoDir := filepath.Join(mountPoint, ReposDir, "github.com-user-repo")
+	if err := os.MkdirAll(filepath.Join(repoDir, ".beads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "notes.md"), []byte("# Notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, ".beads", "beads.db"), []byte("db"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := ListStored(mountPoint)
+	if err != nil {
+		t.Fatalf("ListStored() error = %v", err)
+	}
+	if len(repos) != 1 || repos[0].Files != 2 || repos[0].Bytes != 10 {
+		t.Fatalf("ListStored() = %+v, want one repo with 2 files and 10 bytes", repos)
+	}
+
+	archivePath, err := ArchiveStored(mountPoint, "github.com-user-repo")
+	if err != nil {
+		t.Fatalf("ArchiveStored() error = %v", err)
+	}
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		t.Errorf("repository folder still exists after archive")
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if header.Typeflag == tar.TypeReg {
+			names = append(names, header.Name)
+		}
+	}
+	sort.Strings(names)
+	want := []string{"github.com-user-repo/.beads/beads.db", "github.com-user-repo/notes.md"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("archive files = %v, want %v", names, want)
+	}
+}
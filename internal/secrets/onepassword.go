@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// OnePasswordScheme prefixes 1Password secret references.
+const OnePasswordScheme = "op://"
+
+// opReadTimeout allows time for 1Password to prompt for biometric unlock.
+const opReadTimeout = 60 * time.Second
+
+// Secret maps a secret reference to the environment variable it is injected as.
+type Secret struct {
+	Ref     string // e.g. op://vault/item/field
+	EnvName string
+}
+
+// ParseSpec parses a --secret value of the form op://vault/item/field=ENV_NAME.
+func ParseSpec(spec string) (Secret, error) {
+	idx := strings.LastIndex(spec, "=")
+	if idx < 0 {
+		return Secret{}, fmt.Errorf("invalid secret %q: expected op://vault/item/field=ENV_NAME", spec)
+	}
+	s := Secret{Ref: spec[:idx], EnvName: spec[idx+1:]}
+
+	if !strings.HasPrefix(s.Ref, OnePasswordScheme) || len(s.Ref) == len(OnePasswordScheme) {
+		return Secret{}, fmt.Errorf("invalid secret %q: reference must start with %s", spec, OnePasswordScheme)
+	}
+	if !envNamePattern.MatchString(s.EnvName) {
+		return Secret{}, fmt.Errorf("invalid secret %q: %q is not a valid environment variable name", spec, s.EnvName)
+	}
+	return s, nil
+}
+
+// ParseSpecs parses several --secret values, rejecting duplicate variable names.
+func ParseSpecs(specs []string) ([]Secret, error) {
+	seen := make(map[string]bool)
+	secrets := make([]Secret, 0, len(specs))
+	for _, spec := range specs {
+		s, err := ParseSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[s.EnvName] {
+			return nil, fmt.Errorf("secret variable %s specified more than once", s.EnvName)
+		}
+		seen[s.EnvName] = true
+		secrets = append(secrets, s)
+	}
+	return secrets, nil
+}
+
+// OnePassword resolves secret references with the 1Password CLI (op).
+type OnePassword struct {
+	Secrets []Secret
+}
+
+// Name implements Provider.
+func (p *OnePassword) Name() string {
+	return "1Password"
+}
+
+// Resolve implements Provider.
+func (p *OnePassword) Resolve(ctx context.Context) ([]string, error) {
+	if _, err := exec.LookPath("op"); err != nil {
+		return nil, fmt.Errorf("1Password CLI (op) not found; install it from https://developer.1password.com/docs/cli/")
+	}
+
+	env := make([]string, 0, len(p.Secrets))
+	for _, s := range p.Secrets {
+		value, err := readOnePassword(ctx, s.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", s.EnvName, err)
+		}
+		env = append(env, s.EnvName+"="+value)
+	}
+	return env, nil
+}
+
+// Close implements Provider. 1Password values do not expire with the session.
+func (p *OnePassword) Close() error {
+	return nil
+}
+
+// readOnePassword runs 'op read' for a single reference.
+func readOnePassword(ctx context.Context, ref string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, opReadTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "op", "read", "--no-newline", ref)
+	done := logging.Command(cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("op read timed out after %v", opReadTimeout)
+		}
+		return "", fmt.Errorf("op read: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
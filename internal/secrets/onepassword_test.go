@@ -0,0 +1,36 @@
+package secrets
+
+import "testing"
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Secret
+		wantErr bool
+	}{
+		{"op://Private/Anthropic/credential=ANTHROPIC_API_KEY", Secret{"op://Private/Anthropic/credential", "ANTHROPIC_API_KEY"}, false},
+		{"op://vault/item/section/field=_TOKEN2", Secret{"op://vault/item/section/field", "_TOKEN2"}, false},
+		{"op://vault/item/field", Secret{}, true},
+		{"vault/item/field=TOKEN", Secret{}, true},
+		{"op://=TOKEN", Secret{}, true},
+		{"op://vault/item/field=2TOKEN", Secret{}, true},
+		{"op://vault/item/field=MY-TOKEN", Secret{}, true},
+		{"op://vault/item/field=", Secret{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSpec(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseSpecs([]string{"op://a/b/c=TOKEN", "op://a/b/d=TOKEN"}); err == nil {
+		t.Errorf("ParseSpecs() with duplicate names: want error")
+	}
+}
@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// envNamePattern matches valid environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Provider resolves secrets on the host into environment variables for a session.
+type Provider interface {
+	// Name identifies the provider in messages.
+	Name() string
+
+	// Resolve fetches the secrets and returns NAME=value pairs.
+	Resolve(ctx context.Context) ([]string, error)
+
+	// Close releases anything held for the session, such as leases.
+	Close() error
+}
+
+// Session holds secrets resolved from several providers. Values are only kept in memory.
+type Session struct {
+	providers []Provider
+	env       []string
+}
+
+// Open resolves every provider. On error, providers already resolved are closed.
+func Open(ctx context.Context, providers []Provider) (*Session, error) {
+	s := &Session{}
+	seen := make(map[string]string)
+	for _, p := range providers {
+		env, err := p.Resolve(ctx)
+		if err != nil {
+			p.Close()
+			s.Close()
+			return nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		s.providers = append(s.providers, p)
+
+		for _, name := range Names(env) {
+			if other, ok := seen[name]; ok {
+				s.Close()
+				return nil, fmt.Errorf("secret variable %s is set by both %s and %s", name, other, p.Name())
+			}
+			seen[name] = p.Name()
+		}
+		s.env = append(s.env, env...)
+	}
+	return s, nil
+}
+
+// Env returns the resolved NAME=value pairs.
+func (s *Session) Env() []string {
+	return s.env
+}
+
+// Close closes every provider, returning the first error.
+func (s *Session) Close() error {
+	var firstErr error
+	for _, p := range s.providers {
+		if err := p.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+	s.providers = nil
+	return firstErr
+}
+
+// Names returns the variable names of env entries, for display without values.
+func Names(env []string) []string {
+	names := make([]string, len(env))
+	for i, kv := range env {
+		names[i], _, _ = strings.Cut(kv, "=")
+	}
+	return names
+}
@@ -0,0 +1,302 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault environment variables, matching the vault CLI.
+const (
+	VaultAddrEnv      = "VAULT_ADDR"
+	VaultTokenEnv     = "VAULT_TOKEN"
+	VaultNamespaceEnv = "VAULT_NAMESPACE"
+
+	// vaultTokenFile is where 'vault login' stores the token, relative to home.
+	vaultTokenFile = ".vault-token"
+
+	vaultRequestTimeout = 30 * time.Second
+
+	// minRenewInterval keeps a very short lease from renewing in a tight loop.
+	minRenewInterval = 5 * time.Second
+)
+
+// unsafeEnvCharRegex matches characters replaced when turning a Vault key into a variable name.
+var unsafeEnvCharRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Vault reads a secret path from HashiCorp Vault over its HTTP API. Each key in
+// the secret becomes an upper-cased environment variable. If the secret has a
+// renewable lease (dynamic credentials), the lease is renewed while the session
+// runs and revoked on Close.
+type Vault struct {
+	Addr      string
+	Token     string
+	Namespace string
+	Path      string // e.g. secret/claude or aws/creds/claude
+
+	// OnRenewError is called when a background renewal fails. Optional.
+	OnRenewError func(error)
+
+	leaseID string
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// vaultResponse is the subset of a Vault read response capsule uses.
+type vaultResponse struct {
+	LeaseID       string         `json:"lease_id"`
+	LeaseDuration int            `json:"lease_duration"`
+	Renewable     bool           `json:"renewable"`
+	Data          map[string]any `json:"data"`
+	Errors        []string       `json:"errors"`
+}
+
+// NewVault configures a Vault provider for path from VAULT_ADDR and VAULT_TOKEN
+// (or ~/.vault-token, as written by 'vault login').
+func NewVault(path string) (*Vault, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("vault path is required")
+	}
+
+	addr := os.Getenv(VaultAddrEnv)
+	if addr == "" {
+		return nil, fmt.Errorf("%s is not set", VaultAddrEnv)
+	}
+
+	token := os.Getenv(VaultTokenEnv)
+	if token == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(homeDir, vaultTokenFile)); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no Vault token: set %s or run 'vault login'", VaultTokenEnv)
+	}
+
+	return &Vault{
+		Addr:      strings.TrimRight(addr, "/"),
+		Token:     token,
+		Namespace: os.Getenv(VaultNamespaceEnv),
+		Path:      path,
+	}, nil
+}
+
+// Name implements Provider.
+func (v *Vault) Name() string {
+	return "Vault " + v.Path
+}
+
+// Resolve implements Provider. It reads the secret and, for renewable leases,
+// starts renewing in the background until Close.
+func (v *Vault) Resolve(ctx context.Context) ([]string, error) {
+	resp, err := v.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := vaultEnv(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	v.leaseID = resp.LeaseID
+	if resp.LeaseID != "" && resp.Renewable && resp.LeaseDuration > 0 {
+		renewCtx, cancel := context.WithCancel(context.Background())
+		v.cancel = cancel
+		v.wg.Add(1)
+		go v.keepAlive(renewCtx, time.Duration(resp.LeaseDuration)*time.Second)
+	}
+	return env, nil
+}
+
+// Close implements Provider. It stops renewal and revokes the lease, so
+// short-lived credentials end with the session.
+func (v *Vault) Close() error {
+	if v.cancel != nil {
+		v.cancel()
+		v.wg.Wait()
+		v.cancel = nil
+	}
+	if v.leaseID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRequestTimeout)
+	defer cancel()
+	err := v.do(ctx, http.MethodPut, "sys/leases/revoke", map[string]any{"lease_id": v.leaseID}, nil)
+	v.leaseID = ""
+	if err != nil {
+		return fmt.Errorf("failed to revoke lease: %w", err)
+	}
+	return nil
+}
+
+// read fetches the secret. KV version 2 mounts are tried at <mount>/data/<path>
+// if the plain path is not found, so 'secret/claude' works like 'vault kv get'.
+func (v *Vault) read(ctx context.Context) (*vaultResponse, error) {
+	var resp vaultResponse
+	err := v.do(ctx, http.MethodGet, v.Path, nil, &resp)
+	if errorIsNotFound(err) {
+		if mount, rest, ok := strings.Cut(v.Path, "/"); ok && !strings.HasPrefix(rest, "data/") {
+			resp = vaultResponse{}
+			err = v.do(ctx, http.MethodGet, mount+"/data/"+rest, nil, &resp)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", v.Path, err)
+	}
+
+	// KV version 2 wraps the secret in data.data alongside data.metadata
+	if inner, ok := resp.Data["data"].(map[string]any); ok {
+		if _, hasMetadata := resp.Data["metadata"]; hasMetadata {
+			resp.Data = inner
+		}
+	}
+	return &resp, nil
+}
+
+// keepAlive renews the lease at half its duration until ctx is cancelled.
+func (v *Vault) keepAlive(ctx context.Context, leaseDuration time.Duration) {
+	defer v.wg.Done()
+	for {
+		interval := leaseDuration / 2
+		if interval < minRenewInterval {
+			interval = minRenewInterval
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		var resp vaultResponse
+		reqCtx, cancel := context.WithTimeout(ctx, vaultRequestTimeout)
+		err := v.do(reqCtx, http.MethodPut, "sys/leases/renew", map[string]any{
+			"lease_id":  v.leaseID,
+			"increment": int(leaseDuration.Seconds()),
+		}, &resp)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && v.OnRenewError != nil {
+				v.OnRenewError(fmt.Errorf("failed to renew Vault lease for %s: %w", v.Path, err))
+			}
+			continue
+		}
+		if resp.LeaseDuration > 0 {
+			leaseDuration = time.Duration(resp.LeaseDuration) * time.Second
+		}
+	}
+}
+
+// vaultError is a non-2xx response from Vault.
+type vaultError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func (e *vaultError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("vault returned HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("vault returned HTTP %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+func errorIsNotFound(err error) bool {
+	vErr, ok := err.(*vaultError)
+	return ok && vErr.StatusCode == http.StatusNotFound
+}
+
+// do sends an API request and decodes the JSON response into out (if non-nil).
+func (v *Vault) do(ctx context.Context, method, apiPath string, body any, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.Addr+"/v1/"+apiPath, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	req.Header.Set("X-Vault-Request", "true")
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := (&http.Client{Timeout: vaultRequestTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp vaultResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return &vaultError{StatusCode: resp.StatusCode, Messages: errResp.Errors}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
+
+// vaultEnv converts secret data into NAME=value pairs sorted by name.
+// Non-string values are JSON-encoded.
+func vaultEnv(data map[string]any) ([]string, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("secret has no data")
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		name := EnvName(k)
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("secret key %q cannot be used as an environment variable", k)
+		}
+
+		value, ok := data[k].(string)
+		if !ok {
+			encoded, err := json.Marshal(data[k])
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode secret key %q: %w", k, err)
+			}
+			value = string(encoded)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// EnvName converts a secret key such as "api-key" into an environment variable name (API_KEY).
+func EnvName(key string) string {
+	return strings.ToUpper(strings.Trim(unsafeEnvCharRegex.ReplaceAllString(key, "_"), "_"))
+}
@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves a KV version 2 secret and a dynamic secret with a renewable lease.
+type fakeVault struct {
+	mu      sync.Mutex
+	renewed int
+	revoked []string
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != "test-token" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/secret/data/claude":
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data":     map[string]any{"api-key": "sk-123", "retries": 3},
+				"metadata": map[string]any{"version": 1},
+			},
+		})
+	case "/v1/aws/creds/claude":
+		json.NewEncoder(w).Encode(map[string]any{
+			"lease_id":       "aws/creds/claude/abc",
+			"lease_duration": 1,
+			"renewable":      true,
+			"data":           map[string]any{"access_key": "AKIA", "secret_key": "shh"},
+		})
+	case "/v1/sys/leases/renew":
+		f.renewed++
+		json.NewEncoder(w).Encode(map[string]any{"lease_duration": 1})
+	case "/v1/sys/leases/revoke":
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		f.revoked = append(f.revoked, body["lease_id"].(string))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"errors": []string{}})
+	}
+}
+
+func TestVaultKV(t *testing.T) {
+	server := httptest.NewServer(&fakeVault{})
+	defer server.Close()
+
+	v := &Vault{Addr: server.URL, Token: "test-token", Path: "secret/claude"}
+	env, err := v.Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got := strings.Join(env, ","); got != "API_KEY=sk-123,RETRIES=3" {
+		t.Errorf("Resolve() = %s", got)
+	}
+	if err := v.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	v = &Vault{Addr: server.URL, Token: "wrong", Path: "secret/claude"}
+	if _, err := v.Resolve(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Resolve() with bad token error = %v, want permission denied", err)
+	}
+}
+
+func TestVaultLease(t *testing.T) {
+	fake := &fakeVault{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	v := &Vault{Addr: server.URL, Token: "test-token", Path: "aws/creds/claude"}
+	session, err := Open(context.Background(), []Provider{v})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := strings.Join(session.Env(), ","); got != "ACCESS_KEY=AKIA,SECRET_KEY=shh" {
+		t.Errorf("Env() = %s", got)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.revoked) != 1 || fake.revoked[0] != "aws/creds/claude/abc" {
+		t.Errorf("revoked leases = %v, want the session's lease", fake.revoked)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"api-key":        "API_KEY",
+		"access_key":     "ACCESS_KEY",
+		"Token":          "TOKEN",
+		"nested.key.one": "NESTED_KEY_ONE",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package secretscan
+
+import "regexp"
+
+// Rule detects one kind of credential.
+type Rule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+}
+
+// Rules are the built-in detection rules. Patterns match the documented token
+// formats closely to keep false positives rare; generic "password = ..." style
+// matching is deliberately left out.
+var Rules = []Rule{
+	{"anthropic-api-key", "Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"openai-api-key", "OpenAI API key", regexp.MustCompile(`\bsk-(?:(?:proj|svcacct|admin)-[A-Za-z0-9_-]{20,}|[A-Za-z0-9]{48}\b)`)},
+	{"aws-access-key-id", "AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", "AWS secret access key", regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{"github-token", "GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{50,})`)},
+	{"gitlab-token", "GitLab personal access token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", "Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"google-api-key", "Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"stripe-secret-key", "Stripe live secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+	{"npm-token", "npm access token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"huggingface-token", "Hugging Face token", regexp.MustCompile(`\bhf_[A-Za-z0-9]{34,}`)},
+	{"vault-token", "HashiCorp Vault token", regexp.MustCompile(`\bhv[sb]\.[A-Za-z0-9_-]{24,}`)},
+	{"private-key", "Private key", regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+}
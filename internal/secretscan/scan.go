@@ -0,0 +1,152 @@
+// Package secretscan looks for credentials in the workspace. The workspace is
+// the unencrypted side of a capsule session, so an API key pasted into a file or
+// a token written by a tool inside the container stays on the host disk after
+// the volume is locked.
+package secretscan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// AllowComment on a line suppresses findings on it, for test fixtures and examples.
+const AllowComment = "capsule:allow-secret"
+
+const (
+	// DefaultMaxFileSize skips large files, which are rarely hand-edited secrets.
+	DefaultMaxFileSize = 1 << 20
+
+	// binarySniffLen is how much of a file is checked for NUL bytes.
+	binarySniffLen = 8000
+)
+
+// skipDirs are never descended into: version control data and dependency trees.
+var skipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+	".venv":        true,
+	"__pycache__":  true,
+}
+
+// Options limits what Scan reads.
+type Options struct {
+	// Since skips files not modified after this time. Zero scans every file.
+	Since time.Time
+
+	// MaxFileSize skips larger files. Zero means DefaultMaxFileSize.
+	MaxFileSize int64
+}
+
+// Finding is one suspected credential.
+type Finding struct {
+	Path        string // Relative to the scanned root
+	Line        int
+	RuleID      string
+	Description string
+	Redacted    string // The match with most of it hidden
+}
+
+// Scan walks root and returns suspected credentials sorted by path and line.
+// Symlinks are not followed, so the _docs link into the volume is skipped.
+func Scan(root string, opts Options) ([]Finding, error) {
+	maxSize := opts.MaxFileSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxFileSize
+	}
+
+	var findings []Finding
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped rather than aborting the scan
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxSize || info.Size() == 0 {
+			return nil
+		}
+		if !opts.Since.IsZero() && !info.ModTime().After(opts.Since) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		findings = append(findings, ScanContent(rel, data)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// ScanContent checks one file's contents. Binary content is skipped.
+func ScanContent(path string, data []byte) []Finding {
+	if bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0 {
+		return nil
+	}
+
+	var findings []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Bytes()
+		if bytes.Contains(text, []byte(AllowComment)) {
+			continue
+		}
+		for _, rule := range Rules {
+			for _, match := range rule.Pattern.FindAll(text, -1) {
+				findings = append(findings, Finding{
+					Path:        path,
+					Line:        line,
+					RuleID:      rule.ID,
+					Description: rule.Description,
+					Redacted:    Redact(string(match)),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// Redact keeps enough of a match to recognize it and hides the rest.
+func Redact(match string) string {
+	const shown = 8
+	if len(match) <= shown*2 {
+		return match[:min(len(match), 4)] + "…"
+	}
+	return fmt.Sprintf("%s…(%d chars)", match[:shown], len(match))
+}
@@ -0,0 +1,162 @@
+package signproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/hostsocket"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+const (
+	// ContainerSocketPath is where the proxy socket is mounted inside the container.
+	ContainerSocketPath = "/run/capsule/sign.sock"
+
+	// ShimPath is the gpg.program shim in the image that forwards to the socket.
+	ShimPath = "/usr/local/bin/capsule-gpg"
+
+	// signTimeout allows time for a hardware key to be touched or a PIN entered.
+	signTimeout = 2 * time.Minute
+
+	// maxPayloadSize bounds the commit or tag object accepted for signing.
+	maxPayloadSize = 16 << 20
+)
+
+// Request is sent by the shim: the gpg arguments git used and the data to sign.
+type Request struct {
+	Args    []string `json:"args"`
+	Payload []byte   `json:"payload"`
+}
+
+// Response carries the host gpg output back to the shim.
+type Response struct {
+	Stdout   []byte `json:"stdout"`
+	Stderr   []byte `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Server signs payloads with a fixed host key on behalf of a container.
+// Only detached signing with that key is allowed; the container cannot run
+// other gpg operations or choose a different key.
+type Server struct {
+	SigningKey string
+	Program    string // Host gpg program
+
+	socket *hostsocket.Listener
+}
+
+// SocketPath returns ~/.capsule/run/<containerName>-sign.sock.
+func SocketPath(containerName string) (string, error) {
+	return hostsocket.Path(containerName, "sign")
+}
+
+// Listen creates the proxy socket. Call Serve to handle requests and Close when done.
+func Listen(socketPath, signingKey, program string) (*Server, error) {
+	if signingKey == "" {
+		return nil, fmt.Errorf("signing key is required")
+	}
+	if program == "" {
+		program = "gpg"
+	}
+	socket, err := hostsocket.Listen(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{SigningKey: signingKey, Program: program, socket: socket}, nil
+}
+
+// Serve handles connections until Close is called.
+func (s *Server) Serve() error {
+	return s.socket.Serve(s.handle)
+}
+
+// Close stops the server and removes the socket.
+func (s *Server) Close() error {
+	return s.socket.Close()
+}
+
+// GitEnv returns environment variables that point git in the container at the
+// shim and turn on signing for commits and tags, without touching any config file.
+func (s *Server) GitEnv() []string {
+	settings := [][2]string{
+		{"gpg.format", "openpgp"},
+		{"gpg.program", ShimPath},
+		{"user.signingkey", s.SigningKey},
+		{"commit.gpgsign", "true"},
+		{"tag.gpgsign", "true"},
+	}
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(settings))}
+	for i, kv := range settings {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]))
+	}
+	return env
+}
+
+func (s *Server) handle(conn net.Conn) {
+	var req Request
+	if err := json.NewDecoder(io.LimitReader(conn, maxPayloadSize*2)).Decode(&req); err != nil {
+		hostsocket.Reply(conn, errorResponse("invalid request: %v", err))
+		return
+	}
+	hostsocket.Reply(conn, s.sign(req))
+}
+
+// sign runs the host gpg for an allowed request.
+func (s *Server) sign(req Request) Response {
+	if err := ValidateArgs(req.Args, s.SigningKey); err != nil {
+		return errorResponse("%v", err)
+	}
+	if len(req.Payload) > maxPayloadSize {
+		return errorResponse("payload too large (%d bytes)", len(req.Payload))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), signTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Program, req.Args...)
+	done := logging.Command(cmd)
+	cmd.Stdin = bytes.NewReader(req.Payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	resp := Response{}
+	err := cmd.Run()
+	done(err)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return errorResponse("failed to run %s: %v", s.Program, err)
+		}
+		resp.ExitCode = exitErr.ExitCode()
+	}
+	resp.Stdout = stdout.Bytes()
+	resp.Stderr = stderr.Bytes()
+	return resp
+}
+
+// ValidateArgs accepts only the detached-sign invocation git uses for OpenPGP
+// signing (gpg --status-fd=2 -bsau <key>), and only with the proxy's key.
+func ValidateArgs(args []string, signingKey string) error {
+	if len(args) != 3 || args[0] != "--status-fd=2" || args[1] != "-bsau" {
+		return fmt.Errorf("capsule signing proxy only supports signing (got gpg %q)", args)
+	}
+	if args[2] != signingKey {
+		return fmt.Errorf("signing key %q is not allowed (proxy signs with %q)", args[2], signingKey)
+	}
+	return nil
+}
+
+func errorResponse(format string, args ...any) Response {
+	return Response{Stderr: []byte("capsule-gpg: " + fmt.Sprintf(format, args...) + "\n"), ExitCode: 2}
+}
@@ -0,0 +1,78 @@
+package signproxy
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"git sign", []string{"--status-fd=2", "-bsau", "ABCD1234"}, false},
+		{"other key", []string{"--status-fd=2", "-bsau", "FFFF0000"}, true},
+		{"export", []string{"--export-secret-keys", "ABCD1234"}, true},
+		{"verify", []string{"--status-fd=1", "--verify", "/tmp/sig", "-"}, true},
+		{"empty", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArgs(tt.args, "ABCD1234")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+
+	// Fake gpg: echo the payload back as the "signature" and report success on stderr
+	program := filepath.Join(dir, "fake-gpg")
+	script := "#!/bin/sh\nprintf 'SIG:'\ncat\necho '[GNUPG:] SIG_CREATED' >&2\n"
+	if err := os.WriteFile(program, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := Listen(filepath.Join(dir, "sign.sock"), "ABCD1234", program)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer srv.Close()
+	go srv.Serve()
+
+	call := func(req Request) Response {
+		t.Helper()
+		conn, err := net.Dial("unix", filepath.Join(dir, "sign.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err := json.NewEncoder(conn).Encode(req); err != nil {
+			t.Fatal(err)
+		}
+		var resp Response
+		if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := call(Request{Args: []string{"--status-fd=2", "-bsau", "ABCD1234"}, Payload: []byte("tree abc\n")})
+	if resp.ExitCode != 0 || string(resp.Stdout) != "SIG:tree abc\n" || !strings.Contains(string(resp.Stderr), "SIG_CREATED") {
+		t.Errorf("sign response = %+v", resp)
+	}
+
+	resp = call(Request{Args: []string{"--export-secret-keys"}})
+	if resp.ExitCode == 0 || len(resp.Stdout) != 0 {
+		t.Errorf("disallowed request response = %+v, want failure", resp)
+	}
+}
@@ -0,0 +1,219 @@
+// Package startstate records how far 'capsule start' got in a state file under
+// ~/.capsule/run, so that when a start crashes or is killed, the next one can
+// undo exactly what it left behind.
+//
+// A start moves through its phases in order, and each phase names the last
+// step that completed:
+//
+//	mounted → cache-refreshed → container-created → symlinked → attached
+//
+// The file is removed when the start finishes or fails normally. One that is
+// left behind by a process that is no longer running describes an
+// interrupted start, and Rollback says how to clean it up.
+package startstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// Phase is the last step of a start that completed.
+type Phase string
+
+const (
+	// PhaseStarting means nothing has changed yet.
+	PhaseStarting Phase = "starting"
+
+	// PhaseMounted means the volume is mounted, by this start or an earlier one.
+	PhaseMounted Phase = "mounted"
+
+	// PhaseCacheRefreshed means Docker's view of the mount was refreshed and
+	// the container is about to be created.
+	PhaseCacheRefreshed Phase = "cache-refreshed"
+
+	// PhaseContainerCreated means the container is running.
+	PhaseContainerCreated Phase = "container-created"
+
+	// PhaseSymlinked means the _docs symlinks are set up in the container.
+	PhaseSymlinked Phase = "symlinked"
+
+	// PhaseAttached means the shell or headless prompt is running.
+	PhaseAttached Phase = "attached"
+)
+
+// phases lists the phases in the order a start moves through them.
+var phases = []Phase{PhaseStarting, PhaseMounted, PhaseCacheRefreshed, PhaseContainerCreated, PhaseSymlinked, PhaseAttached}
+
+// before reports whether p comes earlier in a start than other.
+func (p Phase) before(other Phase) bool {
+	return slices.Index(phases, p) < slices.Index(phases, other)
+}
+
+// State is the content of a state file.
+type State struct {
+	Container  string    `json:"container"`
+	VolumePath string    `json:"volume_path"`
+	MountPoint string    `json:"mount_point,omitempty"`
+	Phase      Phase     `json:"phase"`
+	PID        int       `json:"pid"`
+	Updated    time.Time `json:"updated"`
+
+	// MountedVolume is set when this start mounted the volume itself rather
+	// than reusing a mount, so rolling back should unmount it.
+	MountedVolume bool `json:"mounted_volume,omitempty"`
+}
+
+// Step is one action that undoes part of an interrupted start.
+type Step string
+
+const (
+	// StepRemoveContainer removes the workspace's container, which may be
+	// only partly created.
+	StepRemoveContainer Step = "remove-container"
+
+	// StepUnmount unmounts the volume at MountPoint.
+	StepUnmount Step = "unmount"
+)
+
+// Rollback returns the steps that undo a start which stopped in s.Phase.
+// Once the container is about to be created it may exist, so it is removed.
+// A volume the start mounted is unmounted, unless the session was attached:
+// a session that ends normally leaves the volume mounted for quick re-entry,
+// so an interrupted one does too.
+func (s State) Rollback() []Step {
+	var steps []Step
+	if !s.Phase.before(PhaseCacheRefreshed) {
+		steps = append(steps, StepRemoveContainer)
+	}
+	if s.MountedVolume && s.MountPoint != "" && s.Phase != PhaseAttached && !s.Phase.before(PhaseMounted) {
+		steps = append(steps, StepUnmount)
+	}
+	return steps
+}
+
+// Alive reports whether the process that wrote s is still running.
+func (s State) Alive() bool {
+	if s.PID <= 0 {
+		return false
+	}
+	err := syscall.Kill(s.PID, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Path returns ~/.capsule/run/<container>-start.json.
+func Path(containerName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, containerName+"-start.json"), nil
+}
+
+// Load reads a state file. A missing file returns nil and no error.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Tracker records a start's progress in its state file.
+type Tracker struct {
+	path  string
+	state State
+}
+
+// Begin writes a state file for a new start of containerName.
+func Begin(path, containerName, volumePath string) (*Tracker, error) {
+	t := &Tracker{path: path, state: State{
+		Container:  containerName,
+		VolumePath: volumePath,
+		Phase:      PhaseStarting,
+		PID:        os.Getpid(),
+	}}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := t.write(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// State returns the recorded state.
+func (t *Tracker) State() State {
+	return t.state
+}
+
+// Phase returns the last phase recorded.
+func (t *Tracker) Phase() Phase {
+	return t.state.Phase
+}
+
+// Advance records that the start reached phase, after applying update (if
+// non-nil) to the state. Phases only move forward; a retry that goes back to
+// an earlier step must call Retreat first.
+func (t *Tracker) Advance(phase Phase, update func(*State)) error {
+	if !t.state.Phase.before(phase) {
+		return fmt.Errorf("cannot move start from %s to %s", t.state.Phase, phase)
+	}
+	if update != nil {
+		update(&t.state)
+	}
+	t.state.Phase = phase
+	return t.write()
+}
+
+// Retreat moves the recorded phase back to phase, for a retry that undid
+// later steps, e.g. removing the container and unmounting to remount.
+func (t *Tracker) Retreat(phase Phase) error {
+	if t.state.Phase.before(phase) {
+		return fmt.Errorf("cannot move start back from %s to %s", t.state.Phase, phase)
+	}
+	t.state.Phase = phase
+	return t.write()
+}
+
+// Close removes the state file. It is called once the start has finished or
+// has been rolled back, so only a crash leaves the file behind.
+func (t *Tracker) Close() error {
+	if err := os.Remove(t.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", t.path, err)
+	}
+	return nil
+}
+
+// write replaces the state file atomically, so a crash mid-write leaves the
+// previous phase rather than a truncated file.
+func (t *Tracker) write() error {
+	t.state.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode start state: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", t.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package startstate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		phase   Phase
+		mounted bool
+		want    []Step
+	}{
+		{phase: PhaseStarting, mounted: false, want: nil},
+		{phase: PhaseMounted, mounted: false, want: nil},
+		{phase: PhaseMounted, mounted: true, want: []Step{StepUnmount}},
+		{phase: PhaseCacheRefreshed, mounted: true, want: []Step{StepRemoveContainer, StepUnmount}},
+		{phase: PhaseContainerCreated, mounted: false, want: []Step{StepRemoveContainer}},
+		{phase: PhaseSymlinked, mounted: true, want: []Step{StepRemoveContainer, StepUnmount}},
+		{phase: PhaseAttached, mounted: true, want: []Step{StepRemoveContainer}},
+	}
+	for _, tt := range tests {
+		s := State{Phase: tt.phase, MountedVolume: tt.mounted, MountPoint: "/mnt/Capsule-abc"}
+		if got := s.Rollback(); !slices.Equal(got, tt.want) {
+			t.Errorf("Rollback() at %s (mounted %v) = %v, want %v", tt.phase, tt.mounted, got, tt.want)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "claude-abc-start.json")
+	tracker, err := Begin(path, "claude-abc", "/vol/capsule.sparseimage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tracker.Advance(PhaseMounted, func(s *State) {
+		s.MountPoint = "/mnt/Capsule-abc"
+		s.MountedVolume = true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Advance(PhaseCacheRefreshed, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Advance(PhaseMounted, nil); err == nil {
+		t.Error("Advance() to an earlier phase succeeded, want error")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Phase != PhaseCacheRefreshed || loaded.MountPoint != "/mnt/Capsule-abc" || !loaded.MountedVolume ||
+		loaded.Container != "claude-abc" || loaded.PID != os.Getpid() {
+		t.Errorf("Load() = %+v", loaded)
+	}
+	if !loaded.Alive() {
+		t.Error("Alive() = false for this process")
+	}
+
+	if err := tracker.Retreat(PhaseMounted); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Advance(PhaseCacheRefreshed, nil); err != nil {
+		t.Errorf("Advance() after Retreat() failed: %v", err)
+	}
+
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := Load(path); err != nil || loaded != nil {
+		t.Errorf("Load() after Close() = %+v, %v; want nil, nil", loaded, err)
+	}
+}
+
+func TestAliveWithoutPID(t *testing.T) {
+	if (State{}).Alive() {
+		t.Error("Alive() = true for a state without a PID")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
 )
 
 // Timeout for state detection commands
@@ -32,14 +33,18 @@ type EnvironmentState struct {
 // Detector checks the state of the environment.
 type Detector struct {
 	volumePath    string
+	mountPoint    string
 	containerName string
 	workspacePath string
 }
 
-// NewDetector creates a new state detector.
-func NewDetector(volumePath, containerName, workspacePath string) *Detector {
+// NewDetector creates a new state detector for the volume at volumePath,
+// mounted at mountPoint, or "" if it isn't. Other capsule volumes may be
+// mounted at the same time, so the caller looks up this one's mount point.
+func NewDetector(volumePath, mountPoint, containerName, workspacePath string) *Detector {
 	return &Detector{
 		volumePath:    volumePath,
+		mountPoint:    mountPoint,
 		containerName: containerName,
 		workspacePath: workspacePath,
 	}
@@ -71,25 +76,17 @@ func (d *Detector) Detect() *EnvironmentState {
 	return state
 }
 
-// checkVolumeMounted checks if the ClaudeEnv volume is mounted.
-// This mirrors the logic in volume/macos.go findMountPoint().
+// checkVolumeMounted checks if the volume is mounted at its mount point.
 func (d *Detector) checkVolumeMounted() (string, bool) {
-	// Check for unique mount points in /tmp (current approach)
-	entries, err := os.ReadDir("/tmp")
-	if err == nil {
-		for _, entry := range entries {
-			if strings.HasPrefix(entry.Name(), "capsule-") && entry.IsDir() {
-				mountPoint := filepath.Join("/tmp", entry.Name())
-				// Verify it's actually mounted by checking for content
-				contents, err := os.ReadDir(mountPoint)
-				if err == nil && len(contents) > 0 {
-					return mountPoint, true
-				}
-			}
-		}
+	if d.mountPoint == "" {
+		return "", false
 	}
-
-	return "", false
+	// Verify it's actually mounted by checking for content
+	contents, err := os.ReadDir(d.mountPoint)
+	if err != nil || len(contents) == 0 {
+		return "", false
+	}
+	return d.mountPoint, true
 }
 
 // checkContainer checks if the container exists and is running.
@@ -99,7 +96,9 @@ func (d *Detector) checkContainer() (exists bool, running bool) {
 
 	// Check if container exists
 	cmd := exec.CommandContext(ctx, "docker", "ps", "-a", "-q", "-f", "name=^"+d.containerName+"$")
+	done := logging.Command(cmd)
 	output, err := cmd.Output()
+	done(err)
 	if err != nil || len(strings.TrimSpace(string(output))) == 0 {
 		return false, false
 	}
@@ -108,7 +107,9 @@ func (d *Detector) checkContainer() (exists bool, running bool) {
 
 	// Check if container is running
 	cmd = exec.CommandContext(ctx, "docker", "inspect", "-f", "{{.State.Running}}", d.containerName)
+	done = logging.Command(cmd)
 	output, err = cmd.Output()
+	done(err)
 	if err != nil {
 		return exists, false
 	}
@@ -147,9 +148,12 @@ func CheckDockerRunning() error {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "docker", "info")
+	done := logging.Command(cmd)
 	cmd.Stdout = nil
 	cmd.Stderr = nil
-	return cmd.Run()
+	err := cmd.Run()
+	done(err)
+	return err
 }
 
 // CheckImageExists verifies a Docker image exists locally.
@@ -158,7 +162,10 @@ func CheckImageExists(imageName string) bool {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", imageName)
+	done := logging.Command(cmd)
 	cmd.Stdout = nil
 	cmd.Stderr = nil
-	return cmd.Run() == nil
+	err := cmd.Run()
+	done(err)
+	return err == nil
 }
@@ -0,0 +1,39 @@
+package state
+
+// Field is one labelled line of status output, e.g. "Mounted" / "Yes (~/.capsule/mounts/Capsule-abc)".
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Transition is a field whose value changed between two refreshes. From is
+// empty for a field that just appeared, and To for one that disappeared.
+type Transition struct {
+	Name string
+	From string
+	To   string
+}
+
+// Diff returns the transitions from prev to cur, in the order of cur followed
+// by any fields only prev had.
+func Diff(prev, cur []Field) []Transition {
+	before := make(map[string]string, len(prev))
+	for _, f := range prev {
+		before[f.Name] = f.Value
+	}
+
+	var transitions []Transition
+	seen := make(map[string]bool, len(cur))
+	for _, f := range cur {
+		seen[f.Name] = true
+		if old, ok := before[f.Name]; !ok || old != f.Value {
+			transitions = append(transitions, Transition{Name: f.Name, From: old, To: f.Value})
+		}
+	}
+	for _, f := range prev {
+		if !seen[f.Name] {
+			transitions = append(transitions, Transition{Name: f.Name, From: f.Value})
+		}
+	}
+	return transitions
+}
@@ -0,0 +1,32 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	prev := []Field{
+		{"Mounted", "No"},
+		{"Container", "Not created"},
+		{"Worktree", "feature (policy: shared)"},
+	}
+	cur := []Field{
+		{"Mounted", "Yes (/Volumes/Capsule-abc)"},
+		{"Container", "Not created"},
+		{"Docker", "Running"},
+	}
+
+	want := []Transition{
+		{Name: "Mounted", From: "No", To: "Yes (/Volumes/Capsule-abc)"},
+		{Name: "Docker", To: "Running"},
+		{Name: "Worktree", From: "feature (policy: shared)"},
+	}
+	if got := Diff(prev, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+
+	if got := Diff(cur, cur); len(got) != 0 {
+		t.Errorf("Diff() of identical fields = %+v, want none", got)
+	}
+}
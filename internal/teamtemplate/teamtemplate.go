@@ -0,0 +1,261 @@
+// Package teamtemplate packs the shareable parts of a capsule volume into a
+// gzipped tarball a team can start from: CLAUDE.md, settings.json, skills,
+// the language template, and each repository's shadow docs.
+//
+// Credentials never go in. The OAuth login and auth/ aren't among the
+// shared paths, settings.json loses the keys that hold or fetch secrets, and
+// any file that looks like it contains a credential is left out. Transcripts
+// and the memory index are history rather than setup, so they stay behind
+// too.
+package teamtemplate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/docsync"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+	"github.com/jeanhaley32/claude-capsule/internal/secretscan"
+	"github.com/jeanhaley32/claude-capsule/internal/transcript"
+	"github.com/jeanhaley32/claude-capsule/internal/transfer"
+)
+
+// MetadataName is the first entry of a template, describing the rest.
+const MetadataName = "capsule-template.json"
+
+// format is the version of the template layout.
+const format = 1
+
+// settingsFile is Claude Code's settings in the volume.
+const settingsFile = "home/.claude/settings.json"
+
+// Shared lists the volume paths a template carries. Directories are shared
+// with everything under them.
+var Shared = []string{
+	"home/.claude/CLAUDE.md",
+	settingsFile,
+	"home/.claude/skills",
+	embedded.TemplateFile,
+	repo.ReposDir,
+}
+
+// settingsSecretKeys are the settings.json keys that hold credentials or
+// run commands to fetch them.
+var settingsSecretKeys = []string{"env", "apiKeyHelper", "awsAuthRefresh", "awsCredentialExport", "otelHeadersHelper"}
+
+// Metadata describes a template.
+type Metadata struct {
+	Format         int               `json:"format"`
+	CapsuleVersion string            `json:"capsule_version"`
+	Template       embedded.Template `json:"template,omitempty"`
+	Created        time.Time         `json:"created"`
+}
+
+// Skipped is a file left out of a template because it looks like it holds
+// a credential.
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// Export writes a template of the volume mounted at mountPoint to w.
+// Format and Created are filled in.
+func Export(w io.Writer, mountPoint string, meta Metadata) ([]Skipped, error) {
+	meta.Format = format
+	meta.Created = time.Now().UTC()
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template metadata: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, MetadataName, data, constants.PublicFilePermissions); err != nil {
+		return nil, err
+	}
+
+	var skipped []Skipped
+	for _, shared := range Shared {
+		err := filepath.WalkDir(filepath.Join(mountPoint, shared), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if isHistory(d.Name()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// Symlinks could point out of the volume
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(mountPoint, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", rel, err)
+			}
+			if rel == settingsFile {
+				if content, err = sanitizeSettings(content); err != nil {
+					return err
+				}
+			}
+			if findings := secretscan.ScanContent(rel, content); len(findings) > 0 {
+				skipped = append(skipped, Skipped{Path: rel, Reason: fmt.Sprintf("%s on line %d", findings[0].Description, findings[0].Line)})
+				return nil
+			}
+			return writeEntry(tw, rel, content, info.Mode().Perm())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write template: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write template: %w", err)
+	}
+	return skipped, nil
+}
+
+// Import extracts a template into the volume mounted at mountPoint,
+// replacing files at the same paths, and returns its metadata and the
+// paths it wrote. Entries outside the shared paths are rejected.
+func Import(r io.Reader, mountPoint string) (*Metadata, []string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a capsule template: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil || header.Name != MetadataName {
+		return nil, nil, fmt.Errorf("not a capsule template: %s is missing", MetadataName)
+	}
+	var meta Metadata
+	if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", MetadataName, err)
+	}
+	if meta.Format > format {
+		return nil, nil, fmt.Errorf("template format %d is newer than this capsule supports (%d); upgrade capsule", meta.Format, format)
+	}
+
+	var written []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &meta, written, fmt.Errorf("failed to read template: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !isShared(header.Name) {
+			return &meta, written, fmt.Errorf("template entry %q is outside the shared paths", header.Name)
+		}
+		target := filepath.Join(mountPoint, filepath.FromSlash(header.Name))
+		// Sessions can write to repos/ and the skills; never follow a link one left
+		if err := transfer.CheckParents(mountPoint, target); err != nil {
+			return &meta, written, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), constants.DirPermissions); err != nil {
+			return &meta, written, fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+		}
+		if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+			if err := os.Remove(target); err != nil {
+				return &meta, written, fmt.Errorf("failed to replace %s: %w", header.Name, err)
+			}
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.FileMode(header.Mode).Perm())
+		if err != nil {
+			return &meta, written, fmt.Errorf("failed to write %s: %w", header.Name, err)
+		}
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return &meta, written, fmt.Errorf("failed to write %s: %w", header.Name, err)
+		}
+		written = append(written, header.Name)
+	}
+	return &meta, written, nil
+}
+
+// isHistory reports whether a file or directory named name records past
+// sessions rather than setup: transcripts and the memory index.
+func isHistory(name string) bool {
+	return name == transcript.SessionsDir || strings.HasPrefix(name, docsync.DBFileName)
+}
+
+// isShared reports whether a template entry is a clean path under one of the
+// shared paths.
+func isShared(name string) bool {
+	if name != path.Clean(name) || path.IsAbs(name) || strings.HasPrefix(name, "../") {
+		return false
+	}
+	for _, shared := range Shared {
+		if name == shared || strings.HasPrefix(name, shared+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeSettings removes the keys of settings.json that hold credentials.
+func sanitizeSettings(data []byte) ([]byte, error) {
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", settingsFile, err)
+	}
+	for _, key := range settingsSecretKeys {
+		delete(settings, key)
+	}
+	sanitized, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", settingsFile, err)
+	}
+	return append(sanitized, '\n'), nil
+}
+
+// writeEntry adds a regular file to the tarball.
+func writeEntry(tw *tar.Writer, name string, data []byte, perm fs.FileMode) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(perm),
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
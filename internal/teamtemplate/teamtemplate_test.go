@@ -0,0 +1,150 @@
+package teamtemplate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"home/.claude/CLAUDE.md":            "# Team rules\n",
+		"home/.claude/settings.json":        `{"model": "opus", "env": {"ANTHROPIC_API_KEY": "x"}, "apiKeyHelper": "/bin/key"}`,
+		"home/.claude/.credentials.json":    `{"token": "oauth"}`,
+		"home/.claude/skills/lint/SKILL.md": "lint skill\n",
+		"auth/api-key":                      "key\n",
+		"repos/app/architecture.md":         "layers\n",
+		"repos/app/leaked.md":               "key: sk-ant-api03-" + strings.Repeat("a", 90) + "\n",
+		"repos/app/.doc-index.db":           "index",
+		"repos/app/sessions/20260101-1.log": "transcript",
+		"config/template":                   "go\n",
+	} {
+		writeFile(t, filepath.Join(src, name), content)
+	}
+
+	var buf bytes.Buffer
+	skipped, err := Export(&buf, src, Metadata{CapsuleVersion: "0.3.0", Template: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0].Path != "repos/app/leaked.md" {
+		t.Errorf("Export() skipped = %+v, want only repos/app/leaked.md", skipped)
+	}
+
+	dst := t.TempDir()
+	meta, written, err := Import(bytes.NewReader(buf.Bytes()), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Format != format || meta.CapsuleVersion != "0.3.0" || meta.Template != "go" {
+		t.Errorf("Import() metadata = %+v", meta)
+	}
+	slices.Sort(written)
+	want := []string{"config/template", "home/.claude/CLAUDE.md", "home/.claude/settings.json", "home/.claude/skills/lint/SKILL.md", "repos/app/architecture.md"}
+	if !slices.Equal(written, want) {
+		t.Errorf("Import() wrote %q, want %q", written, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "home/.claude/settings.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := settings["env"]; ok || settings["model"] != "opus" || settings["apiKeyHelper"] != nil {
+		t.Errorf("imported settings.json = %s, want only model", data)
+	}
+}
+
+func TestImportRejectsUnsharedPaths(t *testing.T) {
+	for _, name := range []string{"auth/api-key", "repos/../auth/api-key", "/etc/passwd"} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		if err := writeEntry(tw, MetadataName, []byte(`{"format": 1}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeEntry(tw, name, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		gz.Close()
+
+		if _, _, err := Import(&buf, t.TempDir()); err == nil {
+			t.Errorf("Import() of an entry %q succeeded, want error", name)
+		}
+	}
+}
+
+func TestImportDoesNotFollowSymlinks(t *testing.T) {
+	template := func(names ...string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		if err := writeEntry(tw, MetadataName, []byte(`{"format": 1}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := writeEntry(tw, name, []byte("from the template"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tw.Close()
+		gz.Close()
+		return &buf
+	}
+	outside := t.TempDir()
+	hostFile := filepath.Join(outside, "authorized_keys")
+	writeFile(t, hostFile, "ssh-ed25519 AAAA host\n")
+
+	// A link at the file is replaced, not written through
+	dst := t.TempDir()
+	target := filepath.Join(dst, "repos", "proj", "_docs", "_overview.md")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(hostFile, target); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Import(template("repos/proj/_docs/_overview.md"), dst); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if info, err := os.Lstat(target); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("%s is not a regular file: %v, %v", target, info, err)
+	}
+
+	// A linked directory is refused
+	dst = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dst, "repos"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dst, "repos", "proj")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Import(template("repos/proj/authorized_keys"), dst); err == nil {
+		t.Error("Import() wrote through a symlinked directory")
+	}
+
+	if data, _ := os.ReadFile(hostFile); string(data) != "ssh-ed25519 AAAA host\n" {
+		t.Errorf("host file = %q, want it untouched", data)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package terminal
+
+// allocLocked falls back to the heap where memory locking is unavailable.
+func allocLocked(n int) ([]byte, []byte) {
+	if n == 0 {
+		return nil, nil
+	}
+	return make([]byte, n), nil
+}
+
+func freeLocked(mem []byte) {
+	clear(mem)
+}
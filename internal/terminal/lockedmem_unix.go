@@ -0,0 +1,39 @@
+//go:build unix
+
+package terminal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocLocked returns a zeroed n-byte buffer mapped outside the Go heap and
+// locked into RAM, so the garbage collector never copies it and the kernel never
+// writes it to swap. The second result is the whole mapping, to pass to
+// freeLocked. If the mapping or lock fails (e.g. RLIMIT_MEMLOCK is exhausted),
+// the buffer comes from the heap and the mapping is nil.
+func allocLocked(n int) ([]byte, []byte) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	pageSize := os.Getpagesize()
+	size := (n + pageSize - 1) / pageSize * pageSize
+	mem, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, n), nil
+	}
+	if err := unix.Mlock(mem); err != nil {
+		_ = unix.Munmap(mem)
+		return make([]byte, n), nil
+	}
+	return mem[:n:n], mem
+}
+
+// freeLocked zeroes, unlocks, and unmaps a mapping from allocLocked.
+func freeLocked(mem []byte) {
+	clear(mem)
+	_ = unix.Munlock(mem)
+	_ = unix.Munmap(mem)
+}
@@ -1,26 +1,57 @@
 package terminal
 
 import (
-	"bufio"
 	"bytes"
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"os"
+	"os/user"
+	"runtime"
 	"strings"
 	"syscall"
+	"unicode/utf8"
 
 	"golang.org/x/term"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
 )
 
+// maxPasswordAttempts is how many weak passwords ReadPasswordConfirmSecure
+// rejects before giving up.
+const maxPasswordAttempts = 3
+
 // PasswordEnvVar is the environment variable name for the volume password.
 const PasswordEnvVar = "CAPSULE_PASSWORD"
 
-// SecurePassword wraps a password with the ability to clear it from memory.
+// maxPasswordBytes bounds a password read from stdin.
+const maxPasswordBytes = 4096
+
+// SecurePassword holds a password in a buffer locked into RAM (see allocLocked)
+// that can be cleared from memory. Pass it to other processes with Reader or
+// WriteTo rather than String, which makes a copy that can't be cleared.
 type SecurePassword struct {
-	data []byte
+	data []byte // the password; a prefix of mem when locked
+	mem  []byte // locked mapping backing data, nil if data is on the heap
 }
 
-// String returns the password as a string.
+// NewSecurePassword copies data into a locked buffer and zeroes data.
+func NewSecurePassword(data []byte) *SecurePassword {
+	s := &SecurePassword{}
+	s.data, s.mem = allocLocked(len(data))
+	copy(s.data, data)
+	clear(data)
+
+	// Release the locked mapping if a caller forgets to Clear
+	if s.mem != nil {
+		runtime.SetFinalizer(s, (*SecurePassword).Clear)
+	}
+	return s
+}
+
+// String returns a copy of the password as a string. The copy lives on the
+// garbage-collected heap and cannot be cleared, so use it only where an API
+// requires a string.
 func (s *SecurePassword) String() string {
 	if s.data == nil {
 		return ""
@@ -28,15 +59,26 @@ func (s *SecurePassword) String() string {
 	return string(s.data)
 }
 
-// Clear zeros out the password data in memory.
+// Bytes returns the locked buffer itself, not a copy. Callers must not retain
+// or modify it, and it is invalid after Clear.
+func (s *SecurePassword) Bytes() []byte {
+	return s.data
+}
+
+// Equal reports whether two passwords match, in constant time.
+func (s *SecurePassword) Equal(other *SecurePassword) bool {
+	return subtle.ConstantTimeCompare(s.data, other.data) == 1
+}
+
+// Clear zeros out the password data in memory and releases its locked buffer.
 // Should be called when the password is no longer needed.
 func (s *SecurePassword) Clear() {
-	if s.data != nil {
-		for i := range s.data {
-			s.data[i] = 0
-		}
-		s.data = nil
+	clear(s.data)
+	if s.mem != nil {
+		freeLocked(s.mem)
+		runtime.SetFinalizer(s, nil)
 	}
+	s.data, s.mem = nil, nil
 }
 
 // Len returns the length of the password.
@@ -44,15 +86,18 @@ func (s *SecurePassword) Len() int {
 	return len(s.data)
 }
 
-// Reader returns an io.Reader for the password bytes.
-// This avoids creating a string copy of the password.
+// Reader returns an io.Reader over the password bytes without copying them.
+// Use it as a command's Stdin: exec writes it straight into the pipe.
 func (s *SecurePassword) Reader() io.Reader {
-	if s.data == nil {
-		return bytes.NewReader(nil)
-	}
 	return bytes.NewReader(s.data)
 }
 
+// WriteTo writes the password to w without an intermediate copy.
+func (s *SecurePassword) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(s.data)
+	return int64(n), err
+}
+
 // IsTerminal returns true if stdin is a terminal.
 func IsTerminal() bool {
 	return term.IsTerminal(int(syscall.Stdin))
@@ -72,15 +117,41 @@ func ReadPasswordSecure(prompt string) (*SecurePassword, error) {
 		return nil, fmt.Errorf("failed to read password: %w", err)
 	}
 
-	return &SecurePassword{data: password}, nil
+	return NewSecurePassword(password), nil
 }
 
-// ReadPasswordConfirmSecure prompts for a password twice, verifies they match,
-// and returns a SecurePassword that can be cleared from memory.
+// ReadPasswordConfirmSecure prompts for a new password, shows a strength meter,
+// re-prompts if it is too weak, then asks for it again and verifies they match.
+// Returns a SecurePassword that can be cleared from memory.
 func ReadPasswordConfirmSecure(prompt, confirmPrompt string) (*SecurePassword, error) {
-	password, err := ReadPasswordSecure(prompt)
-	if err != nil {
-		return nil, err
+	var password *SecurePassword
+	for attempt := 1; password == nil; attempt++ {
+		candidate, err := ReadPasswordSecure(prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		// The estimator works on strings, so this is the one uncleared copy
+		// a new password leaves on the heap
+		strength := EstimateStrength(candidate.String(), currentUserInputs()...)
+		fmt.Println(strength.Meter())
+		problem := passwordProblem(candidate.Bytes(), strength)
+		if problem == "" {
+			if strength.Warning != "" {
+				fmt.Printf("Tip: %s.\n", strength.Warning)
+			}
+			password = candidate
+			break
+		}
+
+		candidate.Clear()
+		fmt.Printf("Password rejected: %s.\n", problem)
+		for _, suggestion := range strength.Suggestions {
+			fmt.Printf("  - %s\n", suggestion)
+		}
+		if attempt == maxPasswordAttempts {
+			return nil, fmt.Errorf("password too weak")
+		}
 	}
 
 	confirm, err := ReadPasswordSecure(confirmPrompt)
@@ -89,7 +160,7 @@ func ReadPasswordConfirmSecure(prompt, confirmPrompt string) (*SecurePassword, e
 		return nil, err
 	}
 
-	if password.String() != confirm.String() {
+	if !password.Equal(confirm) {
 		password.Clear()
 		confirm.Clear()
 		return nil, fmt.Errorf("passwords do not match")
@@ -99,25 +170,146 @@ func ReadPasswordConfirmSecure(prompt, confirmPrompt string) (*SecurePassword, e
 	return password, nil
 }
 
-// ReadPasswordFromStdinSecure reads a password from stdin and returns a SecurePassword.
+// ReadNewPasswordNonInteractive reads a new password from stdin if useStdin is
+// set, otherwise from CAPSULE_PASSWORD, and rejects it if it is too weak. There is
+// no confirmation prompt, so it never touches the terminal.
+func ReadNewPasswordNonInteractive(useStdin bool) (*SecurePassword, error) {
+	var password *SecurePassword
+	if useStdin {
+		var err error
+		if password, err = ReadPasswordFromStdinSecure(); err != nil {
+			return nil, err
+		}
+	} else if password = ReadPasswordFromEnvSecure(); password == nil {
+		return nil, fmt.Errorf("no password provided: pipe it with --password-stdin or set %s", PasswordEnvVar)
+	}
+
+	if err := CheckNewPassword(password); err != nil {
+		password.Clear()
+		return nil, err
+	}
+	return password, nil
+}
+
+// CheckNewPassword returns an error explaining why password is unacceptable for
+// a new volume, or nil.
+func CheckNewPassword(password *SecurePassword) error {
+	strength := EstimateStrength(password.String(), currentUserInputs()...)
+	if problem := passwordProblem(password.Bytes(), strength); problem != "" {
+		return fmt.Errorf("password rejected: %s", problem)
+	}
+	return nil
+}
+
+// passwordProblem explains why a new password with the given strength is
+// unacceptable, or returns "".
+func passwordProblem(password []byte, strength Strength) string {
+	if utf8.RuneCount(password) < constants.MinPasswordLength {
+		return fmt.Sprintf("it must be at least %d characters", constants.MinPasswordLength)
+	}
+	if strength.Score >= constants.MinPasswordScore {
+		return ""
+	}
+	if strength.Warning != "" {
+		return fmt.Sprintf("it is %s. %s", strength.Label(), strength.Warning)
+	}
+	return fmt.Sprintf("it is %s", strength.Label())
+}
+
+// currentUserInputs returns the login and full name of the current user, which
+// an attacker would try early.
+func currentUserInputs() []string {
+	u, err := user.Current()
+	if err != nil {
+		return nil
+	}
+	inputs := []string{u.Username}
+	inputs = append(inputs, strings.Fields(u.Name)...)
+	return inputs
+}
+
+// ReadPasswordFromStdinSecure reads a password line from stdin and returns a SecurePassword.
+// Stdin is read a byte at a time so no buffer retains a copy past the first newline.
 func ReadPasswordFromStdinSecure() (*SecurePassword, error) {
-	reader := bufio.NewReader(os.Stdin)
-	password, err := reader.ReadString('\n')
+	buf := make([]byte, 0, maxPasswordBytes)
+	defer clear(buf[:cap(buf)])
+
+	var b [1]byte
+	for {
+		n, err := os.Stdin.Read(b[:])
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			if len(buf) == cap(buf) {
+				return nil, fmt.Errorf("failed to read password from stdin: longer than %d bytes", maxPasswordBytes)
+			}
+			buf = append(buf, b[0])
+		}
+		if err == io.EOF && len(buf) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+	}
+	b[0] = 0
+
+	return NewSecurePassword(buf), nil
+}
+
+// ReadPasswordFromFileSecure reads a password from a file that only its owner can
+// read. A single trailing newline is dropped. Files readable by group or others
+// are rejected, as the password would be exposed to other users.
+func ReadPasswordFromFileSecure(path string) (*SecurePassword, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read password from stdin: %w", err)
+		return nil, fmt.Errorf("failed to open password file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat password file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("password file %s is not a regular file", path)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return nil, fmt.Errorf("password file %s has mode %04o: it must not be accessible by group or others (chmod 600 %s)", path, perm, path)
+	}
+
+	buf := make([]byte, maxPasswordBytes+2) // room for a trailing CRLF
+	defer clear(buf)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read password file: %w", err)
+	}
+	if err == nil {
+		return nil, fmt.Errorf("password file %s is longer than %d bytes", path, maxPasswordBytes)
+	}
+
+	data := buf[:n]
+	if len(data) > 0 && data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+		if len(data) > 0 && data[len(data)-1] == '\r' {
+			data = data[:len(data)-1]
+		}
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("password file %s is empty", path)
 	}
-	trimmed := strings.TrimSuffix(password, "\n")
-	return &SecurePassword{data: []byte(trimmed)}, nil
+	return NewSecurePassword(data), nil
 }
 
 // ReadPasswordFromEnvSecure reads the password from CAPSULE_PASSWORD and returns a SecurePassword.
-// Returns nil if not set.
+// Returns nil if not set. The process environment keeps its own copy.
 func ReadPasswordFromEnvSecure() *SecurePassword {
 	env := os.Getenv(PasswordEnvVar)
 	if env == "" {
 		return nil
 	}
-	return &SecurePassword{data: []byte(env)}
+	return NewSecurePassword([]byte(env))
 }
 
 // ReadPasswordMultiSourceSecure attempts to read password from multiple sources.
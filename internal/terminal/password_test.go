@@ -0,0 +1,128 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecurePassword(t *testing.T) {
+	src := []byte("hunter2hunter2")
+	p := NewSecurePassword(src)
+	defer p.Clear()
+
+	if !bytes.Equal(src, make([]byte, len(src))) {
+		t.Error("NewSecurePassword() should zero the source slice")
+	}
+	if got, _ := io.ReadAll(p.Reader()); string(got) != "hunter2hunter2" {
+		t.Errorf("Reader() = %q", got)
+	}
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil || buf.String() != "hunter2hunter2" {
+		t.Errorf("WriteTo() = %q, %v", buf.String(), err)
+	}
+
+	same := NewSecurePassword([]byte("hunter2hunter2"))
+	defer same.Clear()
+	other := NewSecurePassword([]byte("hunter3hunter3"))
+	defer other.Clear()
+	if !p.Equal(same) || p.Equal(other) {
+		t.Error("Equal() compared passwords incorrectly")
+	}
+
+	// A locked buffer is unmapped by Clear, so only a heap buffer can be inspected after
+	onHeap := p.mem == nil
+	data := p.Bytes()
+	p.Clear()
+	if p.Len() != 0 || p.Bytes() != nil {
+		t.Error("Clear() should empty the password")
+	}
+	if onHeap && !bytes.Equal(data, make([]byte, len(data))) {
+		t.Error("Clear() should zero a heap-backed buffer")
+	}
+	p.Clear() // clearing twice is safe
+}
+
+func TestReadNewPasswordNonInteractiveFromEnv(t *testing.T) {
+	t.Setenv(PasswordEnvVar, "")
+	if _, err := ReadNewPasswordNonInteractive(false); err == nil {
+		t.Error("expected an error without a password source")
+	}
+
+	t.Setenv(PasswordEnvVar, "password")
+	if _, err := ReadNewPasswordNonInteractive(false); err == nil {
+		t.Error("expected a weak password to be rejected")
+	}
+
+	t.Setenv(PasswordEnvVar, "correct horse battery staple")
+	p, err := ReadNewPasswordNonInteractive(false)
+	if err != nil {
+		t.Fatalf("ReadNewPasswordNonInteractive() error = %v", err)
+	}
+	defer p.Clear()
+	if p.String() != "correct horse battery staple" {
+		t.Errorf("password = %q", p.String())
+	}
+}
+
+func TestReadPasswordFromFileSecure(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		want    string
+		wantErr bool
+	}{
+		{"trailing newline", "s3cret pass\n", 0o600, "s3cret pass", false},
+		{"crlf", "s3cret pass\r\n", 0o400, "s3cret pass", false},
+		{"no newline", "s3cret pass", 0o600, "s3cret pass", false},
+		{"inner newline kept", "line1\nline2\n", 0o600, "line1\nline2", false},
+		{"group readable", "s3cret pass\n", 0o640, "", true},
+		{"world readable", "s3cret pass\n", 0o604, "", true},
+		{"empty", "\n", 0o600, "", true},
+		{"too long", strings.Repeat("a", maxPasswordBytes+3), 0o600, "", true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := write(fmt.Sprintf("pw%d", i), tt.content, tt.perm)
+			p, err := ReadPasswordFromFileSecure(path)
+			if tt.wantErr {
+				if err == nil {
+					p.Clear()
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPasswordFromFileSecure() error = %v", err)
+			}
+			defer p.Clear()
+			if p.String() != tt.want {
+				t.Errorf("password = %q, want %q", p.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadPasswordFromFileSecure(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if _, err := ReadPasswordFromFileSecure(dir); err == nil {
+		t.Error("expected an error for a directory")
+	}
+}
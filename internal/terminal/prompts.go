@@ -78,3 +78,21 @@ func PromptIntWithDefault(question string, defaultVal int) (int, error) {
 		return num, nil
 	}
 }
+
+// PromptConfirm asks a yes/no question and returns the answer.
+// Returns false without prompting when stdin is not a terminal.
+func PromptConfirm(question string) (bool, error) {
+	if !IsTerminal() {
+		return false, nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s [y/N]: ", question)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes", nil
+}
@@ -0,0 +1,572 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Password strength estimation in the style of zxcvbn: the password is split into
+// the cheapest sequence of guessable patterns (common passwords, dictionary words,
+// repeats, sequences, keyboard rows, dates) and unmatched characters, and the
+// score reflects how many guesses an attacker needs to reach it.
+
+// offlineGuessesPerSecond models an offline attack on the volume image with a
+// slow password hash, the threat a stolen sparseimage faces.
+const offlineGuessesPerSecond = 1e4
+
+// strengthLabels names each score.
+var strengthLabels = [...]string{"very weak", "weak", "fair", "strong", "very strong"}
+
+// defaultUserInputs are words an attacker targeting a capsule volume would try first.
+var defaultUserInputs = []string{"capsule", "claude", "anthropic", "volume", "sparseimage"}
+
+// keyboardRows are the US QWERTY rows used to spot keyboard walks.
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// l33tSubstitutions maps common character substitutions back to letters.
+var l33tSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+	'|': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+// Strength is an estimate of how hard a password is to guess.
+type Strength struct {
+	Score       int     // 0 (very weak) to 4 (very strong)
+	Guesses     float64 // log10 of the estimated number of guesses
+	Warning     string  // the most guessable pattern found, if any
+	Suggestions []string
+}
+
+type patternKind int
+
+const (
+	bruteforcePattern patternKind = iota
+	commonPasswordPattern
+	dictionaryPattern
+	userInputPattern
+	repeatPattern
+	sequencePattern
+	keyboardPattern
+	datePattern
+)
+
+// pattern is a guessable run of runes [i, j) costing log10 guesses.
+type pattern struct {
+	i, j      int
+	kind      patternKind
+	guesses   float64
+	l33t      bool
+	capitals  bool
+	wholeWord bool
+}
+
+// EstimateStrength scores a password. userInputs are extra words (such as the
+// user's name) treated as the most guessable dictionary entries.
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Warning: "The password is empty"}
+	}
+
+	inputs := make(map[string]int)
+	for i, word := range append(append([]string{}, userInputs...), defaultUserInputs...) {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if _, ok := inputs[word]; word != "" && !ok {
+			inputs[word] = i + 1
+		}
+	}
+
+	// best[k] is the fewest log10 guesses for the first k runes; via[k] is the
+	// pattern ending there (nil for a bruteforced rune)
+	patterns := findPatterns(runes, inputs)
+	best := make([]float64, len(runes)+1)
+	via := make([]*pattern, len(runes)+1)
+	for k := 1; k <= len(runes); k++ {
+		best[k] = best[k-1] + 1 // one unmatched character: ten guesses
+		for idx := range patterns {
+			p := &patterns[idx]
+			if p.j != k {
+				continue
+			}
+			if cost := best[p.i] + p.guesses; cost < best[k] {
+				best[k] = cost
+				via[k] = p
+			}
+		}
+	}
+
+	var used []*pattern
+	for k := len(runes); k > 0; {
+		if p := via[k]; p != nil {
+			used = append(used, p)
+			k = p.i
+		} else {
+			k--
+		}
+	}
+
+	guesses := best[len(runes)]
+	s := Strength{Guesses: guesses, Score: scoreForGuesses(guesses)}
+	s.Warning, s.Suggestions = feedback(s.Score, used, len(runes))
+	return s
+}
+
+// scoreForGuesses maps log10 guesses onto a 0-4 scale. The thresholds are higher
+// than zxcvbn's because a stolen image can be attacked offline without rate limits:
+// a 4 takes decades at offlineGuessesPerSecond.
+func scoreForGuesses(guesses float64) int {
+	switch {
+	case guesses < 6:
+		return 0
+	case guesses < 8:
+		return 1
+	case guesses < 10:
+		return 2
+	case guesses < 13:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Label names the score ("weak", "strong", ...).
+func (s Strength) Label() string {
+	return strengthLabels[s.Score]
+}
+
+// CrackTime estimates how long an offline attack takes to guess the password.
+func (s Strength) CrackTime() string {
+	seconds := math.Pow(10, s.Guesses) / offlineGuessesPerSecond
+	const (
+		minute  = 60
+		hour    = 60 * minute
+		day     = 24 * hour
+		year    = 365 * day
+		century = 100 * year
+	)
+	plural := func(n float64, unit string) string {
+		count := int(math.Round(n))
+		if count == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", count, unit)
+	}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds < minute:
+		return plural(seconds, "second")
+	case seconds < hour:
+		return plural(seconds/minute, "minute")
+	case seconds < day:
+		return plural(seconds/hour, "hour")
+	case seconds < year:
+		return plural(seconds/day, "day")
+	case seconds < century:
+		return plural(seconds/year, "year")
+	default:
+		return "centuries"
+	}
+}
+
+// Meter renders the score as a bar with its label and estimated crack time.
+func (s Strength) Meter() string {
+	const width = 20
+	filled := (s.Score + 1) * width / len(strengthLabels)
+	return fmt.Sprintf("Strength: [%s%s] %s (offline crack time: %s)",
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled), s.Label(), s.CrackTime())
+}
+
+// findPatterns returns every guessable pattern in the password.
+func findPatterns(runes []rune, inputs map[string]int) []pattern {
+	var patterns []pattern
+	patterns = append(patterns, dictionaryPatterns(runes, inputs)...)
+	patterns = append(patterns, repeatPatterns(runes)...)
+	patterns = append(patterns, sequencePatterns(runes)...)
+	patterns = append(patterns, keyboardPatterns(runes)...)
+	patterns = append(patterns, datePatterns(runes)...)
+	return patterns
+}
+
+// dictionaryPatterns finds common passwords, dictionary words, and user inputs,
+// including reversed, capitalized, and l33t-substituted forms.
+func dictionaryPatterns(runes []rune, inputs map[string]int) []pattern {
+	lower := []rune(strings.ToLower(string(runes)))
+	unleet := make([]rune, len(lower))
+	for i, r := range lower {
+		if sub, ok := l33tSubstitutions[r]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = r
+		}
+	}
+
+	var patterns []pattern
+	for i := 0; i < len(runes); i++ {
+		for j := i + 3; j <= len(runes); j++ {
+			for _, form := range []struct {
+				text     []rune
+				l33t     bool
+				reversed bool
+			}{
+				{text: lower[i:j]},
+				{text: unleet[i:j], l33t: true},
+				{text: reverseRunes(lower[i:j]), reversed: true},
+			} {
+				word := string(form.text)
+				if form.l33t && word == string(lower[i:j]) {
+					continue
+				}
+				kind, rank := lookupWord(word, inputs)
+				if rank == 0 {
+					continue
+				}
+
+				guesses := float64(rank) * capitalVariations(runes[i:j])
+				if form.l33t {
+					guesses *= l33tVariations(lower[i:j], unleet[i:j])
+				}
+				if form.reversed {
+					guesses *= 2
+				}
+				p := pattern{i: i, j: j, kind: kind, l33t: form.l33t,
+					capitals: capitalVariations(runes[i:j]) > 1, wholeWord: i == 0 && j == len(runes)}
+				p.guesses = submatchGuesses(guesses, p, len(runes))
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
+
+// lookupWord returns the best-ranked list containing word.
+func lookupWord(word string, inputs map[string]int) (patternKind, int) {
+	if rank, ok := inputs[word]; ok {
+		return userInputPattern, rank
+	}
+	if rank, ok := commonPasswordRanks[word]; ok {
+		return commonPasswordPattern, rank
+	}
+	if rank, ok := commonWordRanks[word]; ok {
+		return dictionaryPattern, rank
+	}
+	return bruteforcePattern, 0
+}
+
+// capitalVariations counts the ways to capitalize a word: lowercase, first
+// letter, and all caps are cheap; mixed case costs more.
+func capitalVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && unicode.IsUpper(word[0])) || (upper == 1 && unicode.IsUpper(word[len(word)-1])) {
+		return 2
+	}
+	variations := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return variations
+}
+
+// l33tVariations counts the ways to choose which letters were substituted.
+func l33tVariations(original, unleet []rune) float64 {
+	substituted := 0
+	for i := range original {
+		if original[i] != unleet[i] {
+			substituted++
+		}
+	}
+	return math.Max(2, math.Pow(2, float64(substituted)))
+}
+
+// submatchGuesses applies zxcvbn's floor for a pattern that is only part of the
+// password, and converts the estimate to log10.
+func submatchGuesses(guesses float64, p pattern, passwordLen int) float64 {
+	if p.j-p.i < passwordLen {
+		floor := 50.0
+		if p.j-p.i == 1 {
+			floor = 10
+		}
+		guesses = math.Max(guesses, floor)
+	}
+	return math.Log10(math.Max(guesses, 1))
+}
+
+// repeatPatterns finds a character or a chunk repeated back to back ("aaaa", "abcabc").
+func repeatPatterns(runes []rune) []pattern {
+	var patterns []pattern
+	for i := 0; i < len(runes); i++ {
+		for unit := 1; i+2*unit <= len(runes); unit++ {
+			count := 1
+			for i+(count+1)*unit <= len(runes) && string(runes[i+count*unit:i+(count+1)*unit]) == string(runes[i:i+unit]) {
+				count++
+			}
+			if count < 2 || count*unit < 3 {
+				continue
+			}
+
+			var base float64
+			if unit == 1 {
+				base = float64(cardinality(runes[i]))
+			} else if _, rank := lookupWord(strings.ToLower(string(runes[i:i+unit])), nil); rank > 0 {
+				base = float64(rank)
+			} else {
+				base = math.Pow(10, float64(unit))
+			}
+			p := pattern{i: i, j: i + count*unit, kind: repeatPattern}
+			p.guesses = submatchGuesses(base*float64(count), p, len(runes))
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// sequencePatterns finds runs stepping by one through letters or digits ("abcd", "9876").
+func sequencePatterns(runes []rune) []pattern {
+	var patterns []pattern
+	for i := 0; i+2 < len(runes); {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		if (delta == 1 || delta == -1) && sameClass(runes[i], runes[i+1]) {
+			for j+1 < len(runes) && runes[j+1]-runes[j] == delta && sameClass(runes[j], runes[j+1]) {
+				j++
+			}
+		}
+		if length := j - i + 1; length >= 3 {
+			base := 26.0
+			if unicode.IsDigit(runes[i]) {
+				base = 10
+			}
+			if strings.ContainsRune("aAzZ019", runes[i]) {
+				base = 4
+			}
+			guesses := base * float64(length)
+			if delta < 0 {
+				guesses *= 2
+			}
+			p := pattern{i: i, j: j + 1, kind: sequencePattern}
+			p.guesses = submatchGuesses(guesses, p, len(runes))
+			patterns = append(patterns, p)
+			i = j + 1
+			continue
+		}
+		i++
+	}
+	return patterns
+}
+
+// keyboardPatterns finds straight walks along a keyboard row ("qwerty", "lkjh").
+func keyboardPatterns(runes []rune) []pattern {
+	lower := strings.ToLower(string(runes))
+	lowerRunes := []rune(lower)
+
+	var patterns []pattern
+	for i := 0; i+4 <= len(lowerRunes); i++ {
+		longest, reversed := 0, false
+		for j := i + 4; j <= len(lowerRunes); j++ {
+			walk := string(lowerRunes[i:j])
+			forward, backward := false, false
+			for _, row := range keyboardRows {
+				forward = forward || strings.Contains(row, walk)
+				backward = backward || strings.Contains(row, string(reverseRunes([]rune(walk))))
+			}
+			if !forward && !backward {
+				break
+			}
+			longest, reversed = j-i, !forward
+		}
+		if longest == 0 {
+			continue
+		}
+
+		// About 40 starting keys, walked in one of two directions
+		guesses := 40 * float64(longest)
+		if reversed {
+			guesses *= 2
+		}
+		p := pattern{i: i, j: i + longest, kind: keyboardPattern}
+		p.guesses = submatchGuesses(guesses, p, len(runes))
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// datePatterns finds years (1900-2049) and all-digit dates such as 31121999 or 991231.
+func datePatterns(runes []rune) []pattern {
+	currentYear := time.Now().Year()
+	yearSpace := func(year int) float64 {
+		return math.Max(math.Abs(float64(year-currentYear)), 20)
+	}
+
+	var patterns []pattern
+	for i := 0; i < len(runes); i++ {
+		for _, length := range []int{4, 6, 8} {
+			if i+length > len(runes) {
+				break
+			}
+			digits := string(runes[i : i+length])
+			if strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+				continue
+			}
+
+			var guesses float64
+			if length == 4 {
+				year := atoi(digits)
+				if year < 1900 || year > 2049 {
+					continue
+				}
+				guesses = yearSpace(year)
+			} else {
+				year, ok := parseDigitDate(digits)
+				if !ok {
+					continue
+				}
+				guesses = 365 * yearSpace(year)
+			}
+			p := pattern{i: i, j: i + length, kind: datePattern}
+			p.guesses = submatchGuesses(guesses, p, len(runes))
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parseDigitDate tries the day/month/year orders people use and returns the year.
+func parseDigitDate(digits string) (int, bool) {
+	validDay := func(day, month int) bool {
+		return month >= 1 && month <= 12 && day >= 1 && day <= 31
+	}
+	fullYear := func(yy int) int {
+		if yy < 50 {
+			return 2000 + yy
+		}
+		return 1900 + yy
+	}
+
+	if len(digits) == 8 {
+		if y := atoi(digits[4:]); y >= 1900 && y <= 2049 {
+			if validDay(atoi(digits[:2]), atoi(digits[2:4])) || validDay(atoi(digits[2:4]), atoi(digits[:2])) {
+				return y, true
+			}
+		}
+		if y := atoi(digits[:4]); y >= 1900 && y <= 2049 && validDay(atoi(digits[6:]), atoi(digits[4:6])) {
+			return y, true
+		}
+		return 0, false
+	}
+
+	if validDay(atoi(digits[:2]), atoi(digits[2:4])) || validDay(atoi(digits[2:4]), atoi(digits[:2])) {
+		return fullYear(atoi(digits[4:])), true
+	}
+	if validDay(atoi(digits[4:]), atoi(digits[2:4])) {
+		return fullYear(atoi(digits[:2])), true
+	}
+	return 0, false
+}
+
+// feedback explains the weakest part of the password and how to improve it.
+func feedback(score int, used []*pattern, length int) (string, []string) {
+	var warning string
+	var suggestions []string
+
+	// Warn about the longest pattern, which dominates the estimate
+	var worst *pattern
+	for _, p := range used {
+		if worst == nil || p.j-p.i > worst.j-worst.i {
+			worst = p
+		}
+	}
+	if worst != nil && score < 3 {
+		switch worst.kind {
+		case commonPasswordPattern:
+			warning = "This is a commonly used password"
+		case dictionaryPattern:
+			if worst.wholeWord {
+				warning = "A single word is easy to guess"
+			} else {
+				warning = "Common words are easy to guess on their own"
+			}
+		case userInputPattern:
+			warning = "Avoid your name and words related to capsule"
+		case repeatPattern:
+			warning = `Repeats like "aaa" or "abcabc" are easy to guess`
+		case sequencePattern:
+			warning = "Sequences like abc or 6543 are easy to guess"
+		case keyboardPattern:
+			warning = "Straight rows of keys are easy to guess"
+		case datePattern:
+			warning = "Dates and years are easy to guess"
+		}
+		if worst.capitals {
+			suggestions = append(suggestions, "Capitalization doesn't help very much")
+		}
+		if worst.l33t {
+			suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+		}
+	}
+
+	if score < 3 {
+		suggestions = append(suggestions, "Use a passphrase of four or more uncommon words")
+		if length < 16 {
+			suggestions = append(suggestions, "Longer is better: add another word or two")
+		}
+	}
+	return warning, suggestions
+}
+
+// cardinality is the size of the character class r belongs to.
+func cardinality(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLetter(r):
+		return 26
+	default:
+		return 33
+	}
+}
+
+// sameClass reports whether two runes are both digits, lowercase, or uppercase letters.
+func sameClass(a, b rune) bool {
+	return (unicode.IsDigit(a) && unicode.IsDigit(b)) ||
+		(unicode.IsLower(a) && unicode.IsLower(b)) ||
+		(unicode.IsUpper(a) && unicode.IsUpper(b))
+}
+
+func reverseRunes(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// atoi parses an all-digit string.
+func atoi(digits string) int {
+	n := 0
+	for _, r := range digits {
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		password    string
+		maxScore    int
+		minScore    int
+		wantWarning string
+	}{
+		{password: "password", maxScore: 0, wantWarning: "commonly used password"},
+		{password: "P@ssw0rd", maxScore: 0, wantWarning: "commonly used password"},
+		{password: "qwertyuiop", maxScore: 0},
+		{password: "aaaaaaaaaaaa", maxScore: 0, wantWarning: "Repeats"},
+		{password: "zxcvbnm,./", maxScore: 0, wantWarning: "rows of keys"},
+		{password: "01011990", maxScore: 0, wantWarning: "Dates"},
+		{password: "capsule2024", maxScore: 0, wantWarning: "related to capsule"},
+		{password: "Tr0ub4dor&3", minScore: 3, maxScore: 4},
+		{password: "correct horse battery staple", minScore: 4, maxScore: 4},
+		{password: "xK9#mQ2$vL7!pR4z", minScore: 4, maxScore: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			s := EstimateStrength(tt.password)
+			if s.Score < tt.minScore || s.Score > tt.maxScore {
+				t.Errorf("Score = %d (10^%.1f guesses), want %d-%d", s.Score, s.Guesses, tt.minScore, tt.maxScore)
+			}
+			if !strings.Contains(s.Warning, tt.wantWarning) {
+				t.Errorf("Warning = %q, want it to contain %q", s.Warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestEstimateStrengthUserInputs(t *testing.T) {
+	without := EstimateStrength("jeanhaley")
+	with := EstimateStrength("jeanhaley", "JeanHaley")
+	if with.Guesses >= without.Guesses {
+		t.Errorf("user input should lower the estimate: %.1f with, %.1f without", with.Guesses, without.Guesses)
+	}
+}
+
+func TestPasswordProblem(t *testing.T) {
+	check := func(password string) string {
+		return passwordProblem([]byte(password), EstimateStrength(password))
+	}
+	if problem := check("short"); !strings.Contains(problem, "at least") {
+		t.Errorf("passwordProblem(short) = %q, want a length problem", problem)
+	}
+	if problem := check("password123"); problem == "" {
+		t.Error("passwordProblem(password123) should reject a common password")
+	}
+	if problem := check("violet anchor drizzle"); problem != "" {
+		t.Errorf("passwordProblem() = %q, want none", problem)
+	}
+}
@@ -0,0 +1,78 @@
+package terminal
+
+import "strings"
+
+// commonPasswordList is ordered from most to least common; the position is the
+// password's rank. Drawn from public breach frequency lists.
+const commonPasswordList = `
+123456 password 12345678 qwerty 123456789 12345 1234 111111 1234567 dragon
+123123 baseball abc123 football monkey letmein 696969 shadow master 666666
+qwertyuiop 123321 mustang 1234567890 michael 654321 superman 1qaz2wsx 7777777 121212
+000000 qazwsx 123qwe killer trustno1 jordan jennifer zxcvbnm asdfgh hunter
+buster soccer harley batman andrew tigger sunshine iloveyou 2000 charlie
+robert thomas hockey ranger daniel starwars klaster 112233 george computer
+michelle jessica pepper 1111 zxcvbn 555555 11111111 131313 freedom 777777
+pass maggie 159753 aaaaaa ginger princess joshua cheese amanda summer
+love ashley nicole chelsea biteme matthew access yankees 987654321 dallas
+austin thunder taylor matrix minecraft welcome passw0rd password1 password123 admin
+login abc123456 qwerty123 1q2w3e4r solo 1q2w3e4r5t qwe123 666666666 secret
+changeme letmein1 welcome1 trustme default root toor guest administrator
+hello123 iloveyou1 sunshine1 princess1 football1 monkey1 charlie1 aa123456 q1w2e3r4t5y6 zaq12wsx
+`
+
+// commonWordList is ordered roughly by English word frequency, with words common
+// in passwords (names, places, keyboard words) mixed in.
+const commonWordList = `
+the and that have for not with you this but his from they say her she will one all
+would there their what out about who get which when make can like time just him know
+take people into year your good some could them see other than then now look only come
+its over think also back after use two how our work first well way even new want because
+any these give day most us man woman child world life hand part place case week company
+system program question government number night point home water room mother father area
+money story fact month lot right study book eye job word business issue side kind head
+house service friend power hour game line end member law car city community name
+president team minute idea kid body information school face others level office door
+health person art war history party result change morning reason research girl guy moment
+air teacher force education foot boy age policy music market sense nation plan college
+interest death experience effect class control care field development role effort rate
+heart drug show leader light voice wife police mind price report decision son view
+relationship town road arm difference value building action model season society tax
+director position player record paper space ground form event official matter center
+couple site project activity star table need court oil situation cost industry figure
+street image phone data picture practice piece land product doctor wall patient worker
+news test movie north love support technology step baby computer type attention film
+tree source organization hair window evidence population site truth bank bed blue red
+green black white yellow orange purple pink brown gray silver gold happy sunny summer
+winter spring autumn fall river ocean sea lake mountain forest garden flower rose lily
+apple banana cherry lemon orange mango peach berry coffee tea pizza chocolate cookie
+dog cat horse tiger lion bear wolf eagle dragon monkey rabbit mouse fish bird shark
+snake turtle panda fox owl duck chicken cow pig sheep goat zebra giraffe elephant
+king queen prince princess knight castle sword magic wizard angel devil ghost hero
+master secret hidden shadow storm thunder lightning fire ice snow rain wind cloud sun
+moon star planet galaxy universe space rocket pilot captain soldier hunter ranger
+correct horse battery staple purple monkey dishwasher table chair house window door
+alpha bravo delta echo foxtrot golf hotel india juliet kilo lima november oscar papa
+michael james john robert david william richard joseph thomas charles daniel matthew
+mary patricia jennifer linda elizabeth barbara susan jessica sarah karen nancy lisa
+london paris tokyo berlin newyork chicago boston texas california florida canada
+america england france germany china japan india brazil mexico russia australia
+football soccer baseball basketball hockey tennis golf boxing racing
+password welcome hello goodbye please thanks sorry friend family forever always never
+qwerty asdf zxcv admin login user guest root test demo temp letmein access
+`
+
+var (
+	commonPasswordRanks = rankWords(commonPasswordList)
+	commonWordRanks     = rankWords(commonWordList)
+)
+
+// rankWords maps each word to its 1-based position, keeping the first occurrence.
+func rankWords(list string) map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(list) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}
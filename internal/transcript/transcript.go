@@ -0,0 +1,169 @@
+// Package transcript keeps session transcripts in the encrypted volume, next
+// to each repository's shadow docs and memory.
+package transcript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/repo"
+)
+
+// SessionsDir holds a repository's transcripts, inside its folder under repo.ReposDir.
+const SessionsDir = "sessions"
+
+// fileTimeLayout names transcript files so they sort by start time.
+const fileTimeLayout = "20060102-150405"
+
+// Kind says what a transcript recorded.
+type Kind string
+
+const (
+	// KindRun is a 'capsule run' prompt and Claude's response.
+	KindRun Kind = "run"
+	// KindShell is an interactive shell session, recorded as a terminal typescript.
+	KindShell Kind = "shell"
+)
+
+// fileNamePattern matches transcript names, e.g. 20260302-091400-shell-1.log.
+var fileNamePattern = regexp.MustCompile(`^(\d{8}-\d{6})-([a-z]+)(?:-\d+)?\.log$`)
+
+// Info describes a stored transcript.
+type Info struct {
+	Name    string
+	Kind    Kind
+	Started time.Time
+	Bytes   int64
+}
+
+// Dir returns the directory holding repoID's transcripts in the volume
+// mounted at mountPoint.
+func Dir(mountPoint, repoID string) string {
+	return filepath.Join(mountPoint, repo.ReposDir, repoID, SessionsDir)
+}
+
+// Create creates a transcript for repoID named for when it started and its
+// kind, e.g. 20260302-091400-run.log. The file is readable only by its owner.
+func Create(mountPoint, repoID string, kind Kind, started time.Time) (*os.File, error) {
+	if err := repo.ValidateRepoID(repoID); err != nil {
+		return nil, err
+	}
+	dir := Dir(mountPoint, repoID)
+	if err := os.MkdirAll(dir, constants.DirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	base := started.UTC().Format(fileTimeLayout) + "-" + string(kind)
+	// O_EXCL keeps two sessions started in the same second apart
+	for i := 0; ; i++ {
+		name := base + ".log"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.log", base, i)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, constants.FilePermissions)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transcript: %w", err)
+		}
+		return f, nil
+	}
+}
+
+// List returns repoID's transcripts, oldest first. A repository without any
+// has none.
+func List(mountPoint, repoID string) ([]Info, error) {
+	if err := repo.ValidateRepoID(repoID); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(Dir(mountPoint, repoID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil || !entry.Type().IsRegular() {
+			continue
+		}
+		started, err := time.Parse(fileTimeLayout, match[1])
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, Info{Name: entry.Name(), Kind: Kind(match[2]), Started: started, Bytes: info.Size()})
+	}
+	// Within a second, the unsuffixed name came first, then -1, -2, ...
+	slices.SortFunc(infos, func(a, b Info) int {
+		if c := a.Started.Compare(b.Started); c != 0 {
+			return c
+		}
+		if c := len(a.Name) - len(b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos, nil
+}
+
+// Path returns the path of repoID's transcript named name, rejecting names
+// that aren't transcripts.
+func Path(mountPoint, repoID, name string) (string, error) {
+	if err := repo.ValidateRepoID(repoID); err != nil {
+		return "", err
+	}
+	if !fileNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid transcript name %q", name)
+	}
+	return filepath.Join(Dir(mountPoint, repoID), name), nil
+}
+
+// escapePattern matches terminal escape sequences: CSI (colors, cursor
+// movement), OSC (window titles), character set selection, and the
+// remaining two-byte escapes.
+var escapePattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[@-Z\\-_=>]`)
+
+// Clean turns a terminal typescript into plain text: escape sequences are
+// removed, backspaces erase, and a carriage return starts the line over.
+func Clean(data []byte) []byte {
+	data = escapePattern.ReplaceAll(data, nil)
+	var out []byte
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		text, newline := strings.CutSuffix(line, "\n")
+		text = strings.TrimRight(text, "\r")
+		if i := strings.LastIndexByte(text, '\r'); i >= 0 {
+			text = text[i+1:]
+		}
+		var kept []byte
+		for i := 0; i < len(text); i++ {
+			switch {
+			case text[i] == '\b':
+				if len(kept) > 0 {
+					kept = kept[:len(kept)-1]
+				}
+			case text[i] < ' ' && text[i] != '\t':
+				// Bells and other control characters don't print
+			default:
+				kept = append(kept, text[i])
+			}
+		}
+		out = append(out, kept...)
+		if newline {
+			out = append(out, '\n')
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	mountPoint := t.TempDir()
+	started := time.Date(2026, 3, 2, 9, 14, 0, 0, time.UTC)
+
+	var names []string
+	for range 2 {
+		f, err := Create(mountPoint, "github.com-acme-api", KindRun, started)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		f.Close()
+		names = append(names, filepath.Base(f.Name()))
+		if filepath.Dir(f.Name()) != Dir(mountPoint, "github.com-acme-api") {
+			t.Errorf("Create() wrote %s outside Dir()", f.Name())
+		}
+	}
+	if names[0] != "20260302-091400-run.log" || names[1] != "20260302-091400-run-1.log" {
+		t.Errorf("Create() names = %q", names)
+	}
+
+	if _, err := Create(mountPoint, "../escape", KindRun, started); err == nil {
+		t.Error("Create() with an invalid repository ID succeeded, want error")
+	}
+}
+
+func TestList(t *testing.T) {
+	mountPoint := t.TempDir()
+	if infos, err := List(mountPoint, "github.com-acme-api"); err != nil || len(infos) != 0 {
+		t.Fatalf("List() without transcripts = %v, %v", infos, err)
+	}
+
+	later := time.Date(2026, 3, 3, 13, 40, 0, 0, time.UTC)
+	earlier := time.Date(2026, 3, 2, 9, 14, 0, 0, time.UTC)
+	for _, c := range []struct {
+		kind    Kind
+		started time.Time
+	}{{KindShell, later}, {KindRun, earlier}, {KindRun, earlier}} {
+		f, err := Create(mountPoint, "github.com-acme-api", c.kind, c.started)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("hello\n")
+		f.Close()
+	}
+	if err := os.WriteFile(filepath.Join(Dir(mountPoint, "github.com-acme-api"), "notes.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := List(mountPoint, "github.com-acme-api")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	want := []string{"20260302-091400-run.log", "20260302-091400-run-1.log", "20260303-134000-shell.log"}
+	if !slices.Equal(names, want) {
+		t.Errorf("List() = %q, want %q", names, want)
+	}
+	if infos[2].Kind != KindShell || !infos[2].Started.Equal(later) || infos[2].Bytes != 6 {
+		t.Errorf("List()[2] = %+v", infos[2])
+	}
+
+	if _, err := Path(mountPoint, "github.com-acme-api", "../../auth/api-key"); err == nil {
+		t.Error("Path() with a path outside the transcripts succeeded, want error")
+	}
+}
+
+func TestClean(t *testing.T) {
+	typescript := "\x1b]0;fish /workspace\x07\x1b[32mclaude\x1b[0m@capsule ~> lss\b \b\r\n" +
+		"Downloading  10%\rDownloading 100%\r\n\x1b[?2004lbye\x07\n"
+	want := "claude@capsule ~> ls\nDownloading 100%\nbye\n"
+	if got := string(Clean([]byte(typescript))); got != want {
+		t.Errorf("Clean() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+// Package transfer copies files between the host and a capsule container as
+// tar streams, the format docker cp reads and writes.
+package transfer
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix marks a path inside the container, as in capsule:/workspace/notes.md.
+const Prefix = "capsule:"
+
+// WorkspaceDir is where relative container paths are resolved.
+const WorkspaceDir = "/workspace"
+
+// Location is one side of a copy.
+type Location struct {
+	Container bool   // Path is inside the container
+	Path      string // Absolute for container paths
+}
+
+// ParseLocation parses a cp argument. Container paths carry the capsule: prefix;
+// relative ones are taken from /workspace.
+func ParseLocation(arg string) (Location, error) {
+	p, ok := strings.CutPrefix(arg, Prefix)
+	if !ok {
+		if arg == "" {
+			return Location{}, fmt.Errorf("path is empty")
+		}
+		return Location{Path: arg}, nil
+	}
+	if p == "" {
+		p = "."
+	}
+	trailingSlash := strings.HasSuffix(p, "/") && p != "/"
+	if !path.IsAbs(p) {
+		p = path.Join(WorkspaceDir, p)
+	}
+	p = path.Clean(p)
+	if trailingSlash {
+		p += "/"
+	}
+	return Location{Container: true, Path: p}, nil
+}
+
+// String formats the location the way it was given on the command line.
+func (l Location) String() string {
+	if l.Container {
+		return Prefix + l.Path
+	}
+	return l.Path
+}
+
+// Size returns the number of bytes of regular file content under root.
+func Size(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Write writes src to w as a tar stream whose top-level entry is named name.
+// Directories are copied recursively; symlinks are stored as links.
+func Write(w io.Writer, src, name string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		entryName := path.Join(name, filepath.ToSlash(rel))
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if d.Type()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		header.Name = entryName
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		// Ownership is the container's business, not the host user's
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Extract unpacks a tar stream from docker cp into dir, renaming the top-level
+// entry to name. Entries that would land outside dir, or be written through a
+// symlink, are rejected.
+func Extract(r io.Reader, dir, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return fmt.Errorf("invalid destination name %q", name)
+	}
+	tr := tar.NewReader(r)
+	var root string
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		entry := path.Clean(header.Name)
+		if path.IsAbs(entry) || entry == ".." || strings.HasPrefix(entry, "../") {
+			return fmt.Errorf("archive entry %q is outside the destination", header.Name)
+		}
+		first, rest, _ := strings.Cut(entry, "/")
+		if root == "" {
+			root = first
+		} else if first != root {
+			return fmt.Errorf("archive entry %q is outside %s", header.Name, root)
+		}
+		target := filepath.Join(dir, name, filepath.FromSlash(rest))
+		if err := CheckParents(dir, target); err != nil {
+			return err
+		}
+
+		if err := extractEntry(tr, header, target); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+	}
+}
+
+// CheckParents refuses to write target if a directory between dir and target
+// is a symlink, which an archive, or a container writing into a volume, could
+// otherwise use to escape dir.
+func CheckParents(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	p := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through symlink %s", p)
+		}
+	}
+	return nil
+}
+
+func extractEntry(tr *tar.Reader, header *tar.Header, target string) error {
+	mode := header.FileInfo().Mode().Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		return os.Chmod(target, mode|0700)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		// Replace rather than follow whatever is already there
+		if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		return os.Chmod(target, mode)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return os.Symlink(header.Linkname, target)
+	default:
+		// Devices, fifos, and hard links have no place in a copy to the host
+		return nil
+	}
+}
+
+// CountingReader counts the bytes read through it and reports the running
+// total to Report, if set, after each read.
+type CountingReader struct {
+	R      io.Reader
+	N      int64
+	Report func(n int64)
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.N += int64(n)
+	if c.Report != nil && n > 0 {
+		c.Report(c.N)
+	}
+	return n, err
+}
@@ -0,0 +1,146 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    Location
+		wantErr bool
+	}{
+		{arg: "notes.md", want: Location{Path: "notes.md"}},
+		{arg: "capsule:/claude-env/home/x", want: Location{Container: true, Path: "/claude-env/home/x"}},
+		{arg: "capsule:build/out", want: Location{Container: true, Path: "/workspace/build/out"}},
+		{arg: "capsule:", want: Location{Container: true, Path: "/workspace"}},
+		{arg: "capsule:dist/", want: Location{Container: true, Path: "/workspace/dist/"}},
+		{arg: "capsule:../etc//hosts", want: Location{Container: true, Path: "/etc/hosts"}},
+		{arg: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocation(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLocation(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLocation(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestWriteExtract(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "a.txt"), "alpha")
+	writeFile(t, filepath.Join(src, "sub/b.txt"), "beta")
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := Size(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len("alpha")+len("beta")) {
+		t.Errorf("Size = %d, want 9", size)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, src, "data"); err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+	if err := Extract(&buf, dst, "copy"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"} {
+		got, err := os.ReadFile(filepath.Join(dst, "copy", name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "copy/link")); err != nil || target != "a.txt" {
+		t.Errorf("link = %q, %v; want a.txt", target, err)
+	}
+}
+
+func TestExtractSingleFile(t *testing.T) {
+	dst := t.TempDir()
+	archive := buildArchive(t, entry{name: "report.html", body: "<html>"})
+	if err := Extract(archive, dst, "renamed.html"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dst, "renamed.html")); err != nil || string(got) != "<html>" {
+		t.Errorf("renamed.html = %q, %v", got, err)
+	}
+}
+
+func TestExtractRejectsEscapes(t *testing.T) {
+	tests := map[string][]entry{
+		"parent":   {{name: "../evil", body: "x"}},
+		"absolute": {{name: "/tmp/evil", body: "x"}},
+		"second root": {
+			{name: "data/", dir: true},
+			{name: "other/evil", body: "x"},
+		},
+		"through symlink": {
+			{name: "data/", dir: true},
+			{name: "data/link", link: "/tmp"},
+			{name: "data/link/evil", body: "x"},
+		},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := Extract(buildArchive(t, entries...), dst, "data"); err == nil {
+				t.Error("Extract succeeded, want error")
+			}
+		})
+	}
+}
+
+type entry struct {
+	name, body, link string
+	dir              bool
+}
+
+func buildArchive(t *testing.T, entries ...entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			header.Typeflag, header.Mode = tar.TypeDir, 0755
+		case e.link != "":
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.link
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package trust
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// FileName is the trust allowlist file under the capsule config directory.
+const FileName = "trusted.json"
+
+// Workspace is a trusted workspace directory.
+type Workspace struct {
+	Path      string    `json:"path"`
+	TrustedAt time.Time `json:"trusted_at"`
+}
+
+// Store is the set of workspaces allowed to run with the credential volume mounted.
+type Store struct {
+	path       string
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// DefaultPath returns ~/.capsule/trusted.json.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, constants.CapsuleConfigDir, FileName), nil
+}
+
+// Load reads the trust store at path. A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	store := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// LoadDefault reads the trust store at DefaultPath.
+func LoadDefault() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// Canonicalize returns the absolute, symlink-resolved form of a workspace path,
+// so the same directory is always recorded and matched the same way.
+func Canonicalize(workspacePath string) (string, error) {
+	absPath, err := filepath.Abs(workspacePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", workspacePath, err)
+	}
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", workspacePath, err)
+	}
+	return resolved, nil
+}
+
+// IsTrusted reports whether workspacePath is in the allowlist.
+func (s *Store) IsTrusted(workspacePath string) bool {
+	canonical, err := Canonicalize(workspacePath)
+	if err != nil {
+		return false
+	}
+	for _, w := range s.Workspaces {
+		if w.Path == canonical {
+			return true
+		}
+	}
+	return false
+}
+
+// Add trusts workspacePath and saves the store. Adding a trusted path is a no-op.
+func (s *Store) Add(workspacePath string) error {
+	canonical, err := Canonicalize(workspacePath)
+	if err != nil {
+		return err
+	}
+	if s.IsTrusted(canonical) {
+		return nil
+	}
+
+	s.Workspaces = append(s.Workspaces, Workspace{Path: canonical, TrustedAt: time.Now().UTC()})
+	sort.Slice(s.Workspaces, func(i, j int) bool {
+		return s.Workspaces[i].Path < s.Workspaces[j].Path
+	})
+	return s.save()
+}
+
+// Remove revokes trust for workspacePath and saves the store.
+// Returns false if the path was not trusted.
+func (s *Store) Remove(workspacePath string) (bool, error) {
+	// Match the recorded form even if the directory no longer exists
+	canonical, err := Canonicalize(workspacePath)
+	if err != nil {
+		canonical, err = filepath.Abs(workspacePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve %s: %w", workspacePath, err)
+		}
+	}
+
+	for i, w := range s.Workspaces {
+		if w.Path == canonical {
+			s.Workspaces = append(s.Workspaces[:i], s.Workspaces[i+1:]...)
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+// save writes the store atomically with owner-only permissions.
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(s.path), err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trust store: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "config", FileName)
+	workspace := filepath.Join(dir, "project")
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(workspace, link); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Load(storePath)
+	if err != nil {
+		t.Fatalf("Load() on missing file error = %v", err)
+	}
+	if store.IsTrusted(workspace) {
+		t.Fatalf("IsTrusted() = true on empty store")
+	}
+
+	if err := store.Add(link); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(workspace); err != nil {
+		t.Fatalf("Add() second time error = %v", err)
+	}
+
+	reloaded, err := Load(storePath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(reloaded.Workspaces) != 1 {
+		t.Fatalf("Workspaces = %+v, want one entry", reloaded.Workspaces)
+	}
+	if !reloaded.IsTrusted(workspace) || !reloaded.IsTrusted(link) {
+		t.Errorf("IsTrusted() = false for trusted workspace")
+	}
+	if reloaded.IsTrusted(dir) {
+		t.Errorf("IsTrusted(parent) = true, want false")
+	}
+
+	removed, err := reloaded.Remove(workspace)
+	if err != nil || !removed {
+		t.Fatalf("Remove() = %v, %v; want true, nil", removed, err)
+	}
+	if reloaded.IsTrusted(workspace) {
+		t.Errorf("IsTrusted() = true after Remove")
+	}
+}
@@ -0,0 +1,112 @@
+package volume
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+// cloneSkipped are never copied between volumes: what identifies the volume
+// itself, and what macOS keeps at the root of every mounted filesystem.
+var cloneSkipped = []string{
+	manifest.VolumeIDFile,
+	MetadataFile,
+	".fseventsd",
+	".Spotlight-V100",
+	".Trashes",
+	".TemporaryItems",
+	".DocumentRevisions-V100",
+}
+
+// CloneContents copies the volume mounted at src into the volume mounted at
+// dst, replacing what dst already has at the same paths. Modes and symlinks
+// are kept. Paths in exclude, relative to the volume root, are skipped with
+// everything under them.
+func CloneContents(src, dst string, exclude []string) error {
+	skipped := append(slices.Clone(cloneSkipped), exclude...)
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if isExcluded(rel, skipped) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to create %s: %w", rel, err)
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", rel, err)
+			}
+			return nil
+		case d.Type().IsRegular():
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			return cloneFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets and pipes only mean something to a running container
+			return nil
+		}
+	})
+}
+
+// isExcluded reports whether rel is one of paths or inside one.
+func isExcluded(rel string, paths []string) bool {
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if rel == p || strings.HasPrefix(rel, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneFile copies the regular file src to dst with mode perm.
+func cloneFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,64 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+func TestCloneContents(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{
+		"home/.claude/CLAUDE.md":         "tuned",
+		"home/.claude/.credentials.json": "oauth",
+		"auth/api-key":                   "sk-secret",
+		"repos/app/notes.md":             "notes",
+		manifest.VolumeIDFile:            "source-id",
+		".fseventsd/0001":                "events",
+	} {
+		writeTestFile(t, filepath.Join(src, path), content)
+	}
+	if err := os.Chmod(filepath.Join(src, "home/.claude/CLAUDE.md"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("CLAUDE.md", filepath.Join(src, "home/.claude/link.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dst, "home/.claude/CLAUDE.md"), "default")
+	writeTestFile(t, filepath.Join(dst, manifest.VolumeIDFile), "new-id")
+
+	if err := CloneContents(src, dst, []string{"auth", "home/.claude/.credentials.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"home/.claude/CLAUDE.md": "tuned",
+		"home/.claude/link.md":   "tuned",
+		"repos/app/notes.md":     "notes",
+		manifest.VolumeIDFile:    "new-id",
+	} {
+		if data, err := os.ReadFile(filepath.Join(dst, path)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+		}
+	}
+	for _, path := range []string{"auth/api-key", "home/.claude/.credentials.json", ".fseventsd"} {
+		if _, err := os.Lstat(filepath.Join(dst, path)); !os.IsNotExist(err) {
+			t.Errorf("%s was copied: %v", path, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dst, "home/.claude/CLAUDE.md")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("CLAUDE.md mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+package volume
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// ConvertConfig holds configuration for converting a volume image to
+// another format.
+type ConvertConfig struct {
+	VolumePath string                   // Image to convert, which must not be mounted
+	Target     string                   // Path of the converted image, with the new format's extension
+	Format     ImageFormat              // Format to convert to
+	BandSizeMB int                      // Band size of a sparse bundle; DefaultBandSizeMB if zero
+	Encryption Encryption               // Cipher of the converted image; the original's if empty
+	Password   *terminal.SecurePassword // Password of the volume, which the converted image keeps
+}
+
+// Validate checks the configuration before anything is written.
+func (c *ConvertConfig) Validate() error {
+	if _, err := ParseImageFormat(string(c.Format)); err != nil {
+		return err
+	}
+	if FormatOf(c.VolumePath) == c.Format {
+		return fmt.Errorf("%s is already a %s", c.VolumePath, c.Format)
+	}
+	if !strings.HasSuffix(c.Target, c.Format.Ext()) {
+		return fmt.Errorf("a %s volume's path must end in %s, got %s", c.Format, c.Format.Ext(), c.Target)
+	}
+	if c.BandSizeMB != 0 {
+		if c.Format != FormatSparseBundle {
+			return fmt.Errorf("band size only applies to %s volumes", FormatSparseBundle)
+		}
+		if err := checkBandSize(c.BandSizeMB); err != nil {
+			return err
+		}
+	}
+	if c.Encryption != "" {
+		if _, err := ParseEncryption(string(c.Encryption)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bandSizeMB returns the configured band size or the default.
+func (c *ConvertConfig) bandSizeMB() int {
+	if c.BandSizeMB == 0 {
+		return DefaultBandSizeMB
+	}
+	return c.BandSizeMB
+}
+
+// Convert writes a copy of the image in another format, encrypted with the
+// same password, key stretching parameters, and cipher, records the format in
+// the copy's metadata, and signs a manifest for it. The original is left as
+// it is. If the conversion fails, nothing is left at the target.
+//
+// Without a configured cipher, the original is mounted read-only to read it
+// from its metadata; volumes bootstrapped before it was recorded get
+// DefaultEncryption.
+func (m *MacOSVolumeManager) Convert(ctx context.Context, cfg ConvertConfig) (err error) {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if m.Exists(cfg.Target) {
+		return fmt.Errorf("volume already exists at %s", cfg.Target)
+	}
+	if mountPoint := m.findMountPointForVolume(ctx, cfg.VolumePath); mountPoint != "" {
+		return fmt.Errorf("volume is mounted at %s; lock it before converting", mountPoint)
+	}
+	// Volumes bootstrapped with key stretching take the derived key as their passphrase
+	passphrase := cfg.Password
+	kdfParams, err := kdf.Load(cfg.VolumePath)
+	if err != nil {
+		return err
+	}
+	if kdfParams != nil {
+		derived, err := kdfParams.Derive(cfg.Password)
+		if err != nil {
+			return fmt.Errorf("failed to derive volume key: %w", err)
+		}
+		defer derived.Clear()
+		passphrase = derived
+	}
+
+	encryption := cfg.Encryption
+	if encryption == "" {
+		if encryption, err = m.imageEncryption(ctx, cfg.VolumePath, passphrase); err != nil {
+			return err
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			for _, path := range append([]string{cfg.Target}, PortableSidecars(cfg.Target)...) {
+				os.RemoveAll(path)
+			}
+		}
+	}()
+
+	convertCtx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
+	defer cancel()
+
+	args := []string{"convert", cfg.VolumePath,
+		"-format", cfg.Format.hdiutilFormat(),
+		"-encryption", string(encryption),
+		"-stdinpass",
+		"-puppetstrings",
+	}
+	if cfg.Format == FormatSparseBundle {
+		args = append(args, "-imagekey", bandSizeKey(cfg.bandSizeMB()))
+	}
+	cmd := exec.CommandContext(convertCtx, "hdiutil", append(args, "-o", cfg.Target)...)
+	// With -stdinpass, convert reads a null-terminated passphrase for the
+	// source and then another for the encrypted copy
+	nul := []byte{0}
+	cmd.Stdin = io.MultiReader(passphrase.Reader(), bytes.NewReader(nul), passphrase.Reader(), bytes.NewReader(nul))
+	cmd.Stderr = os.Stderr
+
+	done := logging.Command(cmd)
+	err = cmd.Run()
+	done(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("volume conversion interrupted: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to convert volume: %w", err)
+	}
+
+	// The copy is encrypted with the same derived key, so it needs the same parameters
+	if kdfParams != nil {
+		if err := copyFile(kdf.Path(cfg.VolumePath), kdf.Path(cfg.Target)); err != nil {
+			return err
+		}
+	}
+
+	// The manifest is bound to the image's encryption header, which is new
+	mountPoint, err := m.attach(ctx, cfg.Target, passphrase, false)
+	if err != nil {
+		return err
+	}
+	if err := recordFormat(mountPoint, cfg.Format); err != nil {
+		m.Unmount(ctx, mountPoint)
+		return err
+	}
+	if err := m.WriteManifest(ctx, cfg.Target, mountPoint, cfg.Password); err != nil {
+		m.Unmount(ctx, mountPoint)
+		return err
+	}
+	if err := m.Unmount(ctx, mountPoint); err != nil {
+		return err
+	}
+	slog.Info("volume converted", "volume", cfg.VolumePath, "target", cfg.Target, "format", cfg.Format, "encryption", encryption)
+	return nil
+}
+
+// imageEncryption mounts the image read-only to read its cipher from its
+// metadata.
+func (m *MacOSVolumeManager) imageEncryption(ctx context.Context, volumePath string, passphrase *terminal.SecurePassword) (Encryption, error) {
+	mountPoint, err := m.attach(ctx, volumePath, passphrase, true)
+	if err != nil {
+		return "", err
+	}
+	defer m.Unmount(context.WithoutCancel(ctx), mountPoint)
+	metadata, err := ReadMetadata(mountPoint)
+	if err != nil {
+		return "", err
+	}
+	if metadata == nil || metadata.Encryption == "" {
+		return DefaultEncryption, nil
+	}
+	return metadata.Encryption, nil
+}
+
+// recordFormat updates the format in the metadata of the volume mounted at
+// mountPoint, if it has metadata.
+func recordFormat(mountPoint string, format ImageFormat) error {
+	metadata, err := ReadMetadata(mountPoint)
+	if err != nil || metadata == nil {
+		return err
+	}
+	metadata.Format = format
+	return WriteMetadata(mountPoint, *metadata)
+}
@@ -3,6 +3,8 @@ package volume
 import (
 	"fmt"
 	"runtime"
+
+	"github.com/jeanhaley32/claude-capsule/internal/config"
 )
 
 // New creates a VolumeManager appropriate for the current operating system.
@@ -10,7 +12,11 @@ import (
 func New() (VolumeManager, error) {
 	switch runtime.GOOS {
 	case "darwin":
-		return NewMacOSVolumeManager(), nil
+		mountDir, err := config.MountDir()
+		if err != nil {
+			return nil, err
+		}
+		return NewMacOSVolumeManager(mountDir), nil
 	default:
 		return nil, fmt.Errorf("unsupported operating system: %s (only macOS is supported)", runtime.GOOS)
 	}
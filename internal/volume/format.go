@@ -0,0 +1,168 @@
+package volume
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Encryption is the cipher a new volume is encrypted with.
+type Encryption string
+
+const (
+	EncryptionAES128 Encryption = "AES-128"
+	EncryptionAES256 Encryption = "AES-256"
+)
+
+// DefaultEncryption is used when a bootstrap doesn't choose a cipher.
+const DefaultEncryption = EncryptionAES256
+
+// Encryptions lists the supported ciphers.
+var Encryptions = []Encryption{EncryptionAES128, EncryptionAES256}
+
+// ParseEncryption returns the cipher named s, ignoring case.
+func ParseEncryption(s string) (Encryption, error) {
+	for _, e := range Encryptions {
+		if strings.EqualFold(s, string(e)) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported encryption %q (use %s)", s, joinNames(Encryptions))
+}
+
+// Filesystem is the filesystem a new volume is formatted with.
+type Filesystem string
+
+const (
+	FilesystemAPFS              Filesystem = "APFS"
+	FilesystemCaseSensitiveAPFS Filesystem = "Case-sensitive APFS"
+	FilesystemHFSPlus           Filesystem = "HFS+"
+)
+
+// DefaultFilesystem is used when a bootstrap doesn't choose a filesystem.
+const DefaultFilesystem = FilesystemAPFS
+
+// Filesystems lists the supported filesystems.
+var Filesystems = []Filesystem{FilesystemAPFS, FilesystemCaseSensitiveAPFS, FilesystemHFSPlus}
+
+// ParseFilesystem returns the filesystem named s, ignoring case. "APFSX", the
+// short name diskutil uses, also selects case-sensitive APFS.
+func ParseFilesystem(s string) (Filesystem, error) {
+	if strings.EqualFold(s, "APFSX") {
+		return FilesystemCaseSensitiveAPFS, nil
+	}
+	for _, f := range Filesystems {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported filesystem %q (use %s)", s, joinNames(Filesystems))
+}
+
+// ImageFormat is how a volume image is stored on disk.
+type ImageFormat string
+
+const (
+	// FormatSparseImage stores the image in one file, which changes as a
+	// whole on every write as far as Time Machine and sync tools can tell.
+	FormatSparseImage ImageFormat = "sparseimage"
+
+	// FormatSparseBundle stores the image as a directory of fixed-size band
+	// files, so a small write changes only the bands it touches.
+	FormatSparseBundle ImageFormat = "sparsebundle"
+)
+
+// DefaultImageFormat is used when a bootstrap doesn't choose a format.
+const DefaultImageFormat = FormatSparseImage
+
+// ImageFormats lists the supported image formats.
+var ImageFormats = []ImageFormat{FormatSparseImage, FormatSparseBundle}
+
+// Band sizes of a sparse bundle, in MB. hdiutil's own default is 8 MB.
+const (
+	DefaultBandSizeMB = 8
+	MinBandSizeMB     = 1
+	MaxBandSizeMB     = 1024
+)
+
+// ParseImageFormat returns the image format named s, ignoring case and a
+// leading dot.
+func ParseImageFormat(s string) (ImageFormat, error) {
+	for _, f := range ImageFormats {
+		if strings.EqualFold(strings.TrimPrefix(s, "."), string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported image format %q (use %s)", s, joinNames(ImageFormats))
+}
+
+// FormatOf returns the format of the image at volumePath, from its extension.
+func FormatOf(volumePath string) ImageFormat {
+	if filepath.Ext(volumePath) == FormatSparseBundle.Ext() {
+		return FormatSparseBundle
+	}
+	return FormatSparseImage
+}
+
+// HasImageExt reports whether path ends in the extension of an image format.
+func HasImageExt(path string) bool {
+	return slices.ContainsFunc(ImageFormats, func(f ImageFormat) bool {
+		return strings.HasSuffix(path, f.Ext())
+	})
+}
+
+// Ext returns the extension of images in the format, e.g. ".sparsebundle".
+func (f ImageFormat) Ext() string {
+	return "." + string(f)
+}
+
+// WithFormat returns volumePath with its image extension replaced by the
+// one for format, or with it appended if it has neither.
+func WithFormat(volumePath string, format ImageFormat) string {
+	for _, f := range ImageFormats {
+		if base, ok := strings.CutSuffix(volumePath, f.Ext()); ok {
+			return base + format.Ext()
+		}
+	}
+	return volumePath + format.Ext()
+}
+
+// hdiutilType is the format's -type for 'hdiutil create'.
+func (f ImageFormat) hdiutilType() string {
+	if f == FormatSparseBundle {
+		return "SPARSEBUNDLE"
+	}
+	return "SPARSE"
+}
+
+// hdiutilFormat is the format's -format for 'hdiutil convert'.
+func (f ImageFormat) hdiutilFormat() string {
+	if f == FormatSparseBundle {
+		return "UDSB"
+	}
+	return "UDSP"
+}
+
+// bandSizeKey is the -imagekey that sets a sparse bundle's band size, which
+// hdiutil counts in 512-byte sectors.
+func bandSizeKey(mb int) string {
+	return fmt.Sprintf("sparse-band-size=%d", mb*2048)
+}
+
+// checkBandSize rejects band sizes outside what capsule allows.
+func checkBandSize(mb int) error {
+	if mb < MinBandSizeMB || mb > MaxBandSizeMB {
+		return fmt.Errorf("band size must be between %d and %d MB, got %d", MinBandSizeMB, MaxBandSizeMB, mb)
+	}
+	return nil
+}
+
+// joinNames lists names for an error message, e.g. "APFS, HFS+".
+func joinNames[T ~string](names []T) string {
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = string(n)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,117 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+func TestParseEncryption(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Encryption
+		wantErr bool
+	}{
+		{"AES-256", EncryptionAES256, false},
+		{"aes-128", EncryptionAES128, false},
+		{"AES-192", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEncryption(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseEncryption(%q) = %q, %v, want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFilesystem(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Filesystem
+		wantErr bool
+	}{
+		{"APFS", FilesystemAPFS, false},
+		{"case-sensitive apfs", FilesystemCaseSensitiveAPFS, false},
+		{"APFSX", FilesystemCaseSensitiveAPFS, false},
+		{"hfs+", FilesystemHFSPlus, false},
+		{"ext4", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilesystem(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseFilesystem(%q) = %q, %v, want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBootstrapConfigFormatDefaults(t *testing.T) {
+	cfg := BootstrapConfig{}
+	if cfg.encryption() != EncryptionAES256 || cfg.filesystem() != FilesystemAPFS {
+		t.Errorf("defaults = %s, %s, want AES-256, APFS", cfg.encryption(), cfg.filesystem())
+	}
+	cfg = BootstrapConfig{Encryption: EncryptionAES128, Filesystem: FilesystemCaseSensitiveAPFS}
+	if cfg.encryption() != EncryptionAES128 || cfg.filesystem() != FilesystemCaseSensitiveAPFS {
+		t.Errorf("configured = %s, %s", cfg.encryption(), cfg.filesystem())
+	}
+}
+
+func TestParseImageFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ImageFormat
+		wantErr bool
+	}{
+		{"sparseimage", FormatSparseImage, false},
+		{"SparseBundle", FormatSparseBundle, false},
+		{".sparsebundle", FormatSparseBundle, false},
+		{"dmg", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseImageFormat(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseImageFormat(%q) = %q, %v, want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	tests := []struct {
+		path   string
+		format ImageFormat
+		want   string
+	}{
+		{"/v/capsule.sparseimage", FormatSparseBundle, "/v/capsule.sparsebundle"},
+		{"/v/capsule.sparsebundle", FormatSparseImage, "/v/capsule.sparseimage"},
+		{"/v/capsule.sparseimage", FormatSparseImage, "/v/capsule.sparseimage"},
+		{"/v/capsule", FormatSparseBundle, "/v/capsule.sparsebundle"},
+	}
+	for _, tt := range tests {
+		if got := WithFormat(tt.path, tt.format); got != tt.want {
+			t.Errorf("WithFormat(%q, %s) = %q, want %q", tt.path, tt.format, got, tt.want)
+		}
+		if got := FormatOf(tt.want); got != tt.format {
+			t.Errorf("FormatOf(%q) = %s, want %s", tt.want, got, tt.format)
+		}
+	}
+}
+
+func TestBootstrapConfigValidateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BootstrapConfig
+		wantErr bool
+	}{
+		{"default", BootstrapConfig{VolumePath: "/v/capsule.sparseimage", SizeGB: 2}, false},
+		{"bundle", BootstrapConfig{VolumePath: "/v/capsule.sparsebundle", SizeGB: 2, Format: FormatSparseBundle, BandSizeMB: 64}, false},
+		{"extension mismatch", BootstrapConfig{VolumePath: "/v/capsule.sparseimage", SizeGB: 2, Format: FormatSparseBundle}, true},
+		{"band size on a sparseimage", BootstrapConfig{VolumePath: "/v/capsule.sparseimage", SizeGB: 2, BandSizeMB: 8}, true},
+		{"band size out of range", BootstrapConfig{VolumePath: "/v/capsule.sparsebundle", SizeGB: 2, Format: FormatSparseBundle, BandSizeMB: 4096}, true},
+	}
+	for _, tt := range tests {
+		tt.cfg.Password = terminal.NewSecurePassword([]byte("correct horse battery"))
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package volume
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
 	"github.com/jeanhaley32/claude-capsule/internal/terminal"
 )
 
@@ -12,8 +16,25 @@ type BootstrapConfig struct {
 	VolumePath   string // Full path to the volume file (not just directory)
 	SizeGB       int
 	Password     *terminal.SecurePassword
-	ContextFiles []string // Markdown files to extend Claude context
-	Version      string   // Capsule version for tracking installed components
+	ContextFiles []string          // Markdown files to extend Claude context
+	Version      string            // Capsule version for tracking installed components
+	StretchKey   bool              // Derive hdiutil's passphrase with Argon2id (see package kdf)
+	Encryption   Encryption        // Cipher; DefaultEncryption if empty
+	Filesystem   Filesystem        // Filesystem; DefaultFilesystem if empty
+	Template     embedded.Template // Language preset; the plain image if empty
+	Format       ImageFormat       // On-disk format; DefaultImageFormat if empty
+	BandSizeMB   int               // Band size of a sparse bundle; DefaultBandSizeMB if zero
+	Identity     bool              // Create an identity volume, holding only credentials
+
+	// Setup, if set, runs while the new volume is mounted, after the standard
+	// layout is created and before the manifest is signed.
+	Setup func(mountPoint string) error
+
+	// Verify remounts the volume after setup and checks it against its manifest.
+	Verify bool
+
+	// OnPhase, if set, is called as each phase of the bootstrap completes.
+	OnPhase func(phase string, elapsed time.Duration)
 }
 
 // Validate checks that the bootstrap configuration is valid.
@@ -28,24 +49,136 @@ func (c *BootstrapConfig) Validate() error {
 	if c.Password == nil || c.Password.Len() == 0 {
 		return fmt.Errorf("password is required")
 	}
+	if c.Encryption != "" {
+		if _, err := ParseEncryption(string(c.Encryption)); err != nil {
+			return err
+		}
+	}
+	if c.Filesystem != "" {
+		if _, err := ParseFilesystem(string(c.Filesystem)); err != nil {
+			return err
+		}
+	}
+	if c.Template != "" {
+		if _, err := embedded.ParseTemplate(string(c.Template)); err != nil {
+			return err
+		}
+	}
+	if c.Identity && (c.Template != "" || len(c.ContextFiles) > 0) {
+		return fmt.Errorf("an identity volume holds only credentials; it takes no template or context files")
+	}
+	if c.Format != "" {
+		if _, err := ParseImageFormat(string(c.Format)); err != nil {
+			return err
+		}
+	}
+	// hdiutil would add the extension itself, leaving the image somewhere else
+	if filepath.Ext(c.VolumePath) != c.imageFormat().Ext() {
+		return fmt.Errorf("a %s volume's path must end in %s, got %s", c.imageFormat(), c.imageFormat().Ext(), c.VolumePath)
+	}
+	if c.BandSizeMB != 0 {
+		if c.imageFormat() != FormatSparseBundle {
+			return fmt.Errorf("band size only applies to %s volumes", FormatSparseBundle)
+		}
+		if err := checkBandSize(c.BandSizeMB); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// VolumeManager handles OS-specific encrypted volume operations.
+// encryption returns the configured cipher or the default.
+func (c *BootstrapConfig) encryption() Encryption {
+	if c.Encryption == "" {
+		return DefaultEncryption
+	}
+	return c.Encryption
+}
+
+// imageFormat returns the configured image format or the default.
+func (c *BootstrapConfig) imageFormat() ImageFormat {
+	if c.Format == "" {
+		return DefaultImageFormat
+	}
+	return c.Format
+}
+
+// bandSizeMB returns the configured band size or the default.
+func (c *BootstrapConfig) bandSizeMB() int {
+	if c.BandSizeMB == 0 {
+		return DefaultBandSizeMB
+	}
+	return c.BandSizeMB
+}
+
+// filesystem returns the configured filesystem or the default.
+func (c *BootstrapConfig) filesystem() Filesystem {
+	if c.Filesystem == "" {
+		return DefaultFilesystem
+	}
+	return c.Filesystem
+}
+
+// MountedVolume is a mounted capsule volume.
+type MountedVolume struct {
+	ImagePath  string // Volume file; empty if only the mount point was found
+	MountPoint string
+}
+
+// VolumeManager handles OS-specific encrypted volume operations. Methods that
+// run hdiutil stop it when ctx is canceled.
 type VolumeManager interface {
 	// Bootstrap creates a new encrypted volume with the given configuration.
-	Bootstrap(config BootstrapConfig) error
+	Bootstrap(ctx context.Context, config BootstrapConfig) error
 
 	// Mount decrypts and mounts the volume, returning the mount point.
 	// The caller should clear the password after Mount returns.
-	Mount(volumePath string, password *terminal.SecurePassword) (mountPoint string, err error)
+	Mount(ctx context.Context, volumePath string, password *terminal.SecurePassword) (mountPoint string, err error)
+
+	// MountReadOnly is Mount for callers that only read the volume. A volume
+	// that is already mounted is returned as is, writable or not.
+	MountReadOnly(ctx context.Context, volumePath string, password *terminal.SecurePassword) (mountPoint string, err error)
 
-	// Unmount unmounts and closes the encrypted volume.
-	Unmount(mountPoint string) error
+	// Unmount unmounts and closes the encrypted volume, destroying its auth
+	// RAM disk if it has one.
+	Unmount(ctx context.Context, mountPoint string) error
+
+	// StageAuth copies the mounted volume's auth/ directory onto a small
+	// encrypted RAM disk and returns the RAM disk's mount point. Files written
+	// there never reach the volume, and the RAM disk is destroyed when the
+	// volume is unmounted.
+	StageAuth(ctx context.Context, mountPoint string) (string, error)
+
+	// Resize grows the volume's image and the filesystem inside it to sizeGB.
+	Resize(ctx context.Context, volumePath string, sizeGB int) error
 
 	// Exists checks if a volume file exists at the given path.
 	Exists(volumePath string) bool
 
 	// GetMountPoint returns the mount point for the specified volume if mounted, empty string otherwise.
-	GetMountPoint(volumePath string) string
+	GetMountPoint(ctx context.Context, volumePath string) string
+
+	// ListMounted returns every mounted capsule volume.
+	ListMounted(ctx context.Context) ([]MountedVolume, error)
+
+	// ListMountedUncached is ListMounted asking hdiutil rather than trusting
+	// the mount cache, for callers that remove what isn't mounted.
+	ListMountedUncached(ctx context.Context) ([]MountedVolume, error)
+
+	// RemoveStaleMountPoints removes leftover mount point directories that no
+	// volume is mounted on, returning the ones it removed. With a volumePath,
+	// only that volume's mount points are considered, so mounts other capsule
+	// processes are making are left alone.
+	RemoveStaleMountPoints(ctx context.Context, volumePath string) []string
+
+	// VerifyManifest checks the mounted volume against the manifest stored next to
+	// its image, returning one message per mismatch.
+	VerifyManifest(ctx context.Context, volumePath, mountPoint string, password *terminal.SecurePassword) ([]string, error)
+
+	// WriteManifest signs a manifest describing the mounted volume as it is now.
+	WriteManifest(ctx context.Context, volumePath, mountPoint string, password *terminal.SecurePassword) error
+
+	// Convert writes a copy of the unmounted volume in another image format,
+	// leaving the original as it is.
+	Convert(ctx context.Context, config ConvertConfig) error
 }
@@ -0,0 +1,56 @@
+package volume
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// LastMountSuffix is appended to the image path to name the file recording
+// when the volume was last mounted.
+const LastMountSuffix = ".last-mount"
+
+// LastMountPath returns the last-mount file for a volume image.
+func LastMountPath(volumePath string) string {
+	return volumePath + LastMountSuffix
+}
+
+// RecordMount records that the volume at volumePath was mounted at t.
+func RecordMount(volumePath string, t time.Time) error {
+	data := t.UTC().Format(time.RFC3339) + "\n"
+	if err := os.WriteFile(LastMountPath(volumePath), []byte(data), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to record volume mount: %w", err)
+	}
+	return nil
+}
+
+// LastUsed returns when the volume at volumePath was last mounted. Volumes
+// not mounted since this was recorded fall back to the image's modification
+// time, which a mount that writes anything also updates.
+func LastUsed(volumePath string) (time.Time, error) {
+	data, err := os.ReadFile(LastMountPath(volumePath))
+	if err == nil {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s: %w", LastMountPath(volumePath), err)
+		}
+		return t, nil
+	}
+	if !os.IsNotExist(err) {
+		return time.Time{}, fmt.Errorf("failed to read volume last mount: %w", err)
+	}
+	info, err := os.Stat(volumePath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read volume last mount: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+// IsStale reports whether a volume last used at lastUsed has gone unused
+// for at least months months as of now. months of 0 never counts as stale.
+func IsStale(lastUsed, now time.Time, months int) bool {
+	return months > 0 && !lastUsed.After(now.AddDate(0, -months, 0))
+}
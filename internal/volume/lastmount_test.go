@@ -0,0 +1,53 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLastUsed(t *testing.T) {
+	volumePath := filepath.Join(t.TempDir(), "capsule.sparseimage")
+	if _, err := LastUsed(volumePath); err == nil {
+		t.Error("LastUsed() for a missing volume succeeded, want error")
+	}
+
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.WriteFile(volumePath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(volumePath, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LastUsed(volumePath); err != nil || !got.Equal(modified) {
+		t.Errorf("LastUsed() without a record = %v, %v; want the image's modification time %v", got, err, modified)
+	}
+
+	mounted := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := RecordMount(volumePath, mounted); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LastUsed(volumePath); err != nil || !got.Equal(mounted) {
+		t.Errorf("LastUsed() = %v, %v; want %v", got, err, mounted)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		lastUsed time.Time
+		months   int
+		want     bool
+	}{
+		{lastUsed: now.AddDate(0, -7, 0), months: 6, want: true},
+		{lastUsed: now.AddDate(0, -6, 0), months: 6, want: true},
+		{lastUsed: now.AddDate(0, -5, 0), months: 6, want: false},
+		{lastUsed: now.AddDate(-2, 0, 0), months: 0, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsStale(tt.lastUsed, now, tt.months); got != tt.want {
+			t.Errorf("IsStale(%s, %d months) = %v, want %v", tt.lastUsed.Format("2006-01-02"), tt.months, got, tt.want)
+		}
+	}
+}
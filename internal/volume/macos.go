@@ -1,37 +1,52 @@
 package volume
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/jeanhaley32/claude-capsule/internal/beads"
 	"github.com/jeanhaley32/claude-capsule/internal/config"
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
 	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+	"github.com/jeanhaley32/claude-capsule/internal/progress"
 	"github.com/jeanhaley32/claude-capsule/internal/terminal"
 )
 
-// mountPointPrefix is the prefix for mount points in /Volumes (standard macOS location)
-const mountPointPrefix = "/Volumes/Capsule-"
-
 // Timeout for volume operations (hdiutil can be slow for large volumes)
 const volumeOperationTimeout = 5 * time.Minute
 
+// Timeout for each unmount or detach attempt
+const unmountTimeout = 30 * time.Second
+
 // MacOSVolumeManager implements VolumeManager using hdiutil for macOS.
-type MacOSVolumeManager struct{}
+type MacOSVolumeManager struct {
+	mounts   *mountCache
+	mountDir string // Directory volumes are mounted under
+}
 
-// NewMacOSVolumeManager creates a new macOS volume manager.
-func NewMacOSVolumeManager() *MacOSVolumeManager {
-	return &MacOSVolumeManager{}
+// NewMacOSVolumeManager creates a new macOS volume manager that mounts
+// volumes under mountDir.
+func NewMacOSVolumeManager(mountDir string) *MacOSVolumeManager {
+	return &MacOSVolumeManager{mounts: defaultMountCache(), mountDir: mountDir}
 }
 
-func (m *MacOSVolumeManager) Bootstrap(cfg BootstrapConfig) error {
+func (m *MacOSVolumeManager) Bootstrap(ctx context.Context, cfg BootstrapConfig) error {
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid bootstrap config: %w", err)
@@ -50,140 +65,574 @@ func (m *MacOSVolumeManager) Bootstrap(cfg BootstrapConfig) error {
 		return fmt.Errorf("failed to create parent directory %s: %w", parentDir, err)
 	}
 
+	phase := newPhaseTimer(cfg.OnPhase)
+
+	// With key stretching, hdiutil's passphrase is the Argon2id-derived key
+	passphrase := cfg.Password
+	var kdfParams *kdf.Params
+	if cfg.StretchKey {
+		params, err := kdf.New()
+		if err != nil {
+			return err
+		}
+		indicator := progress.Start(os.Stdout, "Deriving key with Argon2id")
+		derived, err := params.Derive(cfg.Password)
+		indicator.Done(err)
+		if err != nil {
+			return fmt.Errorf("failed to derive volume key: %w", err)
+		}
+		defer derived.Clear()
+		passphrase = derived
+		kdfParams = params
+		phase.done("derive key")
+		// Argon2 can't be interrupted, so check before starting hdiutil
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
 	// Create encrypted sparse image with timeout
 	// hdiutil create -size <size>g -encryption AES-256 -type SPARSE -fs APFS -volname ClaudeEnv -stdinpass <path>
-	ctx, cancel := context.WithTimeout(context.Background(), volumeOperationTimeout)
+	createCtx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "hdiutil", "create",
+	args := []string{"create",
 		"-size", fmt.Sprintf("%dg", cfg.SizeGB),
-		"-encryption", "AES-256",
-		"-type", "SPARSE",
-		"-fs", "APFS",
+		"-encryption", string(cfg.encryption()),
+		"-type", cfg.imageFormat().hdiutilType(),
+		"-fs", string(cfg.filesystem()),
 		"-volname", constants.MacOSVolumeName,
 		"-stdinpass",
-		volumePath,
-	)
-	cmd.Stdin = cfg.Password.Reader()
+		"-puppetstrings",
+	}
+	if cfg.imageFormat() == FormatSparseBundle {
+		args = append(args, "-imagekey", bandSizeKey(cfg.bandSizeMB()))
+	}
+	cmd := exec.CommandContext(createCtx, "hdiutil", append(args, volumePath)...)
+	cmd.Stdin = passphrase.Reader()
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+	indicator := progress.Start(os.Stdout, fmt.Sprintf("Creating %d GB encrypted image", cfg.SizeGB))
+	err := runWithPuppetStrings(cmd, indicator)
+	indicator.Done(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("volume creation interrupted: %w", ctx.Err())
+		}
+		if createCtx.Err() == context.DeadlineExceeded {
 			return fmt.Errorf("volume creation timed out after %v", volumeOperationTimeout)
 		}
 		return fmt.Errorf("failed to create encrypted volume: %w", err)
 	}
+	slog.Info("volume created", "volume", volumePath, "size_gb", cfg.SizeGB, "encryption", cfg.encryption(), "fs", cfg.filesystem(), "format", cfg.imageFormat(), "argon2", kdfParams != nil)
+	phase.done("create image")
+
+	// Mount reads the parameters to derive the same key
+	if kdfParams != nil {
+		if err := kdfParams.Save(volumePath); err != nil {
+			return err
+		}
+	}
 
-	// Mount the new volume to create directory structure
-	mountPoint, err := m.Mount(volumePath, cfg.Password)
+	// Attach with the passphrase already in hand: Mount would derive the key
+	// again and sign a manifest of the empty volume
+	mountPoint, err := m.attach(ctx, volumePath, passphrase, false)
 	if err != nil {
 		return fmt.Errorf("failed to mount new volume: %w", err)
 	}
+	phase.done("mount")
+
+	// Never leave the new volume mounted on failure, even once ctx is canceled
+	cleanupCtx := context.WithoutCancel(ctx)
 
 	// Create directory structure
 	if err := m.createDirectoryStructure(mountPoint, cfg); err != nil {
 		// Try to unmount even if directory creation fails
-		_ = m.Unmount(mountPoint)
+		_ = m.Unmount(cleanupCtx, mountPoint)
 		return fmt.Errorf("failed to create directory structure: %w", err)
 	}
+	if cfg.Setup != nil {
+		if err := cfg.Setup(mountPoint); err != nil {
+			_ = m.Unmount(cleanupCtx, mountPoint)
+			return err
+		}
+	}
+	phase.done("initialize")
+
+	// Sign the manifest now that the layout is in place
+	if err := m.WriteManifest(ctx, volumePath, mountPoint, cfg.Password); err != nil {
+		_ = m.Unmount(cleanupCtx, mountPoint)
+		return err
+	}
+	phase.done("sign manifest")
 
 	// Unmount the volume - APFS handles durability, unmount syncs data
-	if err := m.Unmount(mountPoint); err != nil {
+	if err := m.Unmount(cleanupCtx, mountPoint); err != nil {
 		return fmt.Errorf("failed to unmount volume after setup: %w", err)
 	}
+	phase.done("unmount")
+
+	if cfg.Verify {
+		if err := m.verifyRemount(ctx, volumePath, mountPoint, cfg.Password); err != nil {
+			return err
+		}
+		phase.done("verify")
+	}
 
 	return nil
 }
 
-func (m *MacOSVolumeManager) Mount(volumePath string, password *terminal.SecurePassword) (string, error) {
+// runWithPuppetStrings runs an hdiutil command that was given -puppetstrings,
+// feeding its PERCENT lines to the indicator.
+func runWithPuppetStrings(cmd *exec.Cmd, indicator *progress.Indicator) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	done := logging.Command(cmd)
+	if err := cmd.Start(); err != nil {
+		done(err)
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if percent, ok := progress.ParsePuppetStrings(scanner.Text()); ok {
+			indicator.SetPercent(percent)
+		}
+	}
+	err = cmd.Wait()
+	done(err)
+	return err
+}
+
+// verifyRemount mounts a freshly bootstrapped volume again and checks it
+// against the manifest just written, proving the password opens it and the
+// layout survived the unmount.
+func (m *MacOSVolumeManager) verifyRemount(ctx context.Context, volumePath, previousMount string, password *terminal.SecurePassword) error {
+	if err := waitForUnmount(previousMount, unmountSettleTimeout); err != nil {
+		return err
+	}
+
+	fmt.Println("Verifying volume...")
+	mountPoint, err := m.Mount(ctx, volumePath, password)
+	if err != nil {
+		return fmt.Errorf("verification failed: could not remount volume: %w", err)
+	}
+	problems, verifyErr := m.VerifyManifest(ctx, volumePath, mountPoint, password)
+	if err := m.Unmount(context.WithoutCancel(ctx), mountPoint); err != nil {
+		return fmt.Errorf("failed to unmount volume after verification: %w", err)
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("verification failed: %w", verifyErr)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("verification failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (m *MacOSVolumeManager) Mount(ctx context.Context, volumePath string, password *terminal.SecurePassword) (string, error) {
+	return m.mount(ctx, volumePath, password, false)
+}
+
+func (m *MacOSVolumeManager) MountReadOnly(ctx context.Context, volumePath string, password *terminal.SecurePassword) (string, error) {
+	return m.mount(ctx, volumePath, password, true)
+}
+
+func (m *MacOSVolumeManager) mount(ctx context.Context, volumePath string, password *terminal.SecurePassword, readOnly bool) (string, error) {
 	// Check if this specific volume is already mounted
-	if mountPoint := m.findMountPointForVolume(volumePath); mountPoint != "" {
+	if mountPoint := m.findMountPointForVolume(ctx, volumePath); mountPoint != "" {
 		return mountPoint, nil
 	}
 
-	// Generate a deterministic mount point in /Volumes based on the volume path
-	// Using /Volumes is the standard macOS location and works reliably with Docker Desktop
+	// Volumes bootstrapped with key stretching take the derived key as their passphrase
+	passphrase := password
+	kdfParams, err := kdf.Load(volumePath)
+	if err != nil {
+		return "", err
+	}
+	if kdfParams != nil {
+		derived, err := kdfParams.Derive(password)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive volume key: %w", err)
+		}
+		defer derived.Clear()
+		passphrase = derived
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+	}
+
+	mountPoint, err := m.attach(ctx, volumePath, passphrase, readOnly)
+	if err != nil {
+		return "", err
+	}
+	m.checkManifest(ctx, os.Stderr, volumePath, mountPoint, password)
+
+	return mountPoint, nil
+}
+
+// attach mounts the image with hdiutil's own passphrase, which is the derived
+// key for volumes with key stretching.
+func (m *MacOSVolumeManager) attach(ctx context.Context, volumePath string, passphrase *terminal.SecurePassword, readOnly bool) (string, error) {
+	// Generate a deterministic mount point in the mount directory based on the volume path
 	mountPoint := m.generateMountPoint(volumePath)
+	if err := os.MkdirAll(m.mountDir, constants.DirPermissions); err != nil {
+		return "", fmt.Errorf("failed to create mount directory %s: %w", m.mountDir, err)
+	}
 
-	// Mount with password via stdin
-	// hdiutil will create the mount point in /Volumes (it has system entitlements to do so)
-	ctx, cancel := context.WithTimeout(context.Background(), volumeOperationTimeout)
+	// Mount with password via stdin; hdiutil creates the mount point itself
+	attachCtx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "hdiutil", "attach", "-stdinpass", "-mountpoint", mountPoint, volumePath)
-	cmd.Stdin = password.Reader()
+	args := []string{"attach", "-stdinpass", "-mountpoint", mountPoint}
+	if readOnly {
+		args = append(args, "-readonly")
+	}
+	cmd := exec.CommandContext(attachCtx, "hdiutil", append(args, volumePath)...)
+	cmd.Stdin = passphrase.Reader()
 
+	done := logging.Command(cmd)
 	output, err := cmd.CombinedOutput()
+	done(err)
+	m.mounts.invalidate()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		slog.Warn("volume mount failed", "volume", volumePath, "error", err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("volume mount interrupted: %w", ctx.Err())
+		}
+		if attachCtx.Err() == context.DeadlineExceeded {
 			return "", fmt.Errorf("volume mount timed out after %v", volumeOperationTimeout)
 		}
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("failed to mount volume: %s", string(exitErr.Stderr))
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", attachError(string(output))
 		}
 		return "", fmt.Errorf("failed to mount volume: %w: %s", err, string(output))
 	}
 
+	slog.Info("volume mounted", "volume", volumePath, "mount_point", mountPoint, "read_only", readOnly)
+	if err := RecordMount(volumePath, time.Now()); err != nil {
+		slog.Debug("failed to record volume mount", "volume", volumePath, "error", err)
+	}
 	return mountPoint, nil
 }
 
+// Mount failures callers tell apart, e.g. to exit with a distinct status.
+var (
+	// ErrAuthFailed means the password did not open the volume.
+	ErrAuthFailed = errors.New("wrong password")
+
+	// ErrMountBusy means the volume or its mount point is in use elsewhere.
+	ErrMountBusy = errors.New("volume or mount point is in use")
+)
+
+// attachError turns the output of a failed hdiutil attach into an error,
+// wrapping ErrAuthFailed or ErrMountBusy when the output says which it is.
+func attachError(output string) error {
+	msg := strings.TrimSpace(output)
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "authentication error"):
+		return fmt.Errorf("%w (%s)", ErrAuthFailed, msg)
+	case strings.Contains(lower, "resource busy"):
+		return fmt.Errorf("%w (%s)", ErrMountBusy, msg)
+	}
+	return fmt.Errorf("failed to mount volume: %s", msg)
+}
+
+// errManifestMissing is the problem reported for a volume that had a manifest
+// and no longer does. Deleting the manifest must not be a way around it.
+const errManifestMissing = "manifest is missing, but one was issued for this volume"
+
+// checkManifest verifies the volume against its manifest after a mount, warning
+// loudly on w if anything changed. A volume mounted for the first time gets a
+// manifest; one whose manifest has gone missing gets the warning instead.
+// Problems never fail the mount: the password was accepted, and the user decides
+// whether to keep using the volume.
+func (m *MacOSVolumeManager) checkManifest(ctx context.Context, w io.Writer, volumePath, mountPoint string, password *terminal.SecurePassword) {
+	existing, err := manifest.Load(volumePath)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: %v\n", err)
+		return
+	}
+	if existing == nil {
+		issued, err := manifest.Issued(mountPoint)
+		if err != nil {
+			fmt.Fprintf(w, "Warning: %v\n", err)
+			return
+		}
+		if issued {
+			warnManifestMismatch(w, volumePath, []string{errManifestMissing})
+			return
+		}
+		if err := m.WriteManifest(ctx, volumePath, mountPoint, password); err != nil {
+			fmt.Fprintf(w, "Warning: %v\n", err)
+		}
+		return
+	}
+
+	problems, err := m.VerifyManifest(ctx, volumePath, mountPoint, password)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: could not verify volume manifest: %v\n", err)
+		return
+	}
+	if len(problems) == 0 {
+		return
+	}
+	warnManifestMismatch(w, volumePath, problems)
+}
+
+// warnManifestMismatch prints the manifest problems found on mount.
+func warnManifestMismatch(w io.Writer, volumePath string, problems []string) {
+	slog.Warn("volume manifest mismatch", "volume", volumePath, "problems", problems)
+	fmt.Fprintf(w, "\n!!! WARNING: %s does not match its manifest !!!\n", volumePath)
+	for _, problem := range problems {
+		fmt.Fprintf(w, "!!!   - %s\n", problem)
+	}
+	fmt.Fprintf(w, "!!! The image may have been replaced or tampered with since it was last used.\n")
+	fmt.Fprintf(w, "!!! If you made this change yourself, run 'capsule verify --accept'.\n\n")
+}
+
+// encryptionHeader reads the image's encryption header with 'hdiutil isencrypted'.
+func (m *MacOSVolumeManager) encryptionHeader(ctx context.Context, volumePath string) (manifest.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "hdiutil", "isencrypted", volumePath)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return manifest.Header{}, fmt.Errorf("failed to read encryption header: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return manifest.ParseIsEncrypted(string(output))
+}
+
+func (m *MacOSVolumeManager) VerifyManifest(ctx context.Context, volumePath, mountPoint string, password *terminal.SecurePassword) ([]string, error) {
+	existing, err := manifest.Load(volumePath)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		if issued, err := manifest.Issued(mountPoint); err != nil || issued {
+			return []string{errManifestMissing}, err
+		}
+		return []string{"volume has no manifest"}, nil
+	}
+	header, err := m.encryptionHeader(ctx, volumePath)
+	if err != nil {
+		return nil, err
+	}
+	return existing.Verify(mountPoint, header, password.Bytes())
+}
+
+func (m *MacOSVolumeManager) WriteManifest(ctx context.Context, volumePath, mountPoint string, password *terminal.SecurePassword) error {
+	header, err := m.encryptionHeader(ctx, volumePath)
+	if err != nil {
+		return err
+	}
+	version := ""
+	if data, err := os.ReadFile(filepath.Join(mountPoint, embedded.VersionFile)); err == nil {
+		version = strings.TrimPrefix(strings.TrimSpace(string(data)), "capsule ")
+	}
+	if _, err := manifest.Write(volumePath, mountPoint, header, password.Bytes(), version); err != nil {
+		return fmt.Errorf("failed to write volume manifest: %w", err)
+	}
+	return nil
+}
+
 // generateMountPoint creates a deterministic mount point path based on the volume file path.
 // This ensures the same volume always mounts to the same location, which works better
 // with Docker Desktop's VirtioFS caching.
 func (m *MacOSVolumeManager) generateMountPoint(volumePath string) string {
+	return MountPointFor(m.mountDir, volumePath)
+}
+
+// MountPointFor returns where the volume at volumePath is mounted under
+// mountDir when capsule mounts it.
+func MountPointFor(mountDir, volumePath string) string {
 	// Hash the volume path to get a deterministic, short identifier
 	hash := sha256.Sum256([]byte(volumePath))
 	shortHash := hex.EncodeToString(hash[:])[:12]
-	return mountPointPrefix + shortHash
+	return filepath.Join(mountDir, constants.MountPointNamePrefix+shortHash)
 }
 
-func (m *MacOSVolumeManager) Unmount(mountPoint string) error {
-	if mountPoint == "" {
-		// If no mount point specified, try to find any mounted claude-env volume
-		mountPoint = m.findAnyMountedVolume()
-		if mountPoint == "" {
-			return nil // Not mounted, nothing to do
+// mountDirs returns the directories capsule mount points may be in: the
+// configured mount directory, then the legacy location older capsule used.
+func (m *MacOSVolumeManager) mountDirs() []string {
+	if m.mountDir == constants.LegacyMountDir {
+		return []string{m.mountDir}
+	}
+	return []string{m.mountDir, constants.LegacyMountDir}
+}
+
+// isManagedMountPoint reports whether mountPoint is a directory capsule
+// created, and so may remove after unmounting.
+func (m *MacOSVolumeManager) isManagedMountPoint(mountPoint string) bool {
+	if !strings.HasPrefix(filepath.Base(mountPoint), constants.MountPointNamePrefix) {
+		return false
+	}
+	for _, dir := range m.mountDirs() {
+		if filepath.Dir(mountPoint) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// scanMountPoints returns the capsule mount points with content in the mount
+// directories. Empty directories are leftover mount points.
+func (m *MacOSVolumeManager) scanMountPoints() []string {
+	var found []string
+	for _, mountPoint := range m.mountPointDirs() {
+		if contents, err := os.ReadDir(mountPoint); err == nil && len(contents) > 0 {
+			found = append(found, mountPoint)
+		}
+	}
+	return found
+}
+
+// mountPointDirs returns every capsule mount point directory in the mount
+// directories, mounted or not.
+func (m *MacOSVolumeManager) mountPointDirs() []string {
+	var dirs []string
+	for _, dir := range m.mountDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
 		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), constants.MountPointNamePrefix) {
+				dirs = append(dirs, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return dirs
+}
+
+// attachedMountPoints returns the mount points hdiutil reports, or none if
+// hdiutil can't be asked. It bypasses the mount cache, as its callers remove
+// what isn't attached.
+func (m *MacOSVolumeManager) attachedMountPoints(ctx context.Context) map[string]bool {
+	attached := make(map[string]bool)
+	if mounted, err := m.hdiutilMounts(ctx, true); err == nil {
+		for _, v := range mounted {
+			attached[v.MountPoint] = true
+		}
+	}
+	return attached
+}
+
+// staleMountPoints returns the empty directories in dirs that have no volume
+// attached or mounted on them.
+func staleMountPoints(dirs []string, attached map[string]bool) []string {
+	var stale []string
+	for _, mountPoint := range dirs {
+		if attached[mountPoint] || isMountPoint(mountPoint) {
+			continue
+		}
+		if contents, err := os.ReadDir(mountPoint); err != nil || len(contents) > 0 {
+			if err == nil {
+				slog.Warn("mount point has files but no volume mounted", "mount_point", mountPoint)
+			}
+			continue
+		}
+		stale = append(stale, mountPoint)
+	}
+	return stale
+}
+
+// RemoveStaleMountPoints removes empty mount point directories left behind when
+// a volume was detached without capsule, e.g. after a crash, only those of
+// the volume at volumePath if it isn't empty. A directory that still has
+// files but no volume is reported and left alone.
+func (m *MacOSVolumeManager) RemoveStaleMountPoints(ctx context.Context, volumePath string) []string {
+	dirs := m.mountPointDirs()
+	if volumePath != "" {
+		name := filepath.Base(m.generateMountPoint(volumePath))
+		dirs = slices.DeleteFunc(dirs, func(dir string) bool { return filepath.Base(dir) != name })
+	}
+	var removed []string
+	for _, mountPoint := range staleMountPoints(dirs, m.attachedMountPoints(ctx)) {
+		if err := os.Remove(mountPoint); err != nil {
+			slog.Debug("failed to remove stale mount point", "mount_point", mountPoint, "error", err)
+			continue
+		}
+		slog.Info("removed stale mount point", "mount_point", mountPoint)
+		removed = append(removed, mountPoint)
+	}
+	return removed
+}
+
+func (m *MacOSVolumeManager) Unmount(ctx context.Context, mountPoint string) error {
+	// With several volumes mounted, guessing which one was meant could lock
+	// another workspace's volume
+	if mountPoint == "" {
+		return fmt.Errorf("no mount point given to unmount")
+	}
+
+	// An auth RAM disk goes first, so staged keys never outlive the volume.
+	// The volume is unmounted even if that fails.
+	ramErr := m.destroyAuthRAMDisk(ctx, mountPoint)
+	if err := m.detach(ctx, mountPoint); err != nil {
+		return err
 	}
+	return ramErr
+}
 
-	// Use shorter timeout for unmount operations
-	unmountTimeout := 30 * time.Second
+// detach unmounts the volume at mountPoint, forcing it if needed.
+func (m *MacOSVolumeManager) detach(ctx context.Context, mountPoint string) error {
+	// Whatever happens below, the cached mounts are no longer trustworthy
+	defer m.mounts.invalidate()
 
 	// Try diskutil unmount first (cleaner, forces sync)
-	diskutilCtx, diskutilCancel := context.WithTimeout(context.Background(), unmountTimeout)
+	diskutilCtx, diskutilCancel := context.WithTimeout(ctx, unmountTimeout)
 	defer diskutilCancel()
 
 	diskutilCmd := exec.CommandContext(diskutilCtx, "diskutil", "unmount", mountPoint)
-	if err := diskutilCmd.Run(); err == nil {
+	done := logging.Command(diskutilCmd)
+	err := diskutilCmd.Run()
+	done(err)
+	if err == nil {
 		// diskutil unmount succeeded, clean up mount point directory
-		if strings.HasPrefix(mountPoint, mountPointPrefix) {
+		if m.isManagedMountPoint(mountPoint) {
 			os.Remove(mountPoint)
 		}
+		slog.Info("volume unmounted", "mount_point", mountPoint)
 		return nil
 	}
+	slog.Debug("diskutil unmount failed, trying hdiutil detach", "mount_point", mountPoint, "error", err)
 
 	// Fall back to hdiutil detach
-	ctx, cancel := context.WithTimeout(context.Background(), unmountTimeout)
+	detachCtx, cancel := context.WithTimeout(ctx, unmountTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "hdiutil", "detach", mountPoint)
-	if err := cmd.Run(); err != nil {
+	cmd := exec.CommandContext(detachCtx, "hdiutil", "detach", mountPoint)
+	done = logging.Command(cmd)
+	err = cmd.Run()
+	done(err)
+	if err != nil {
 		// Try force detach with fresh context
-		forceCtx, forceCancel := context.WithTimeout(context.Background(), unmountTimeout)
+		forceCtx, forceCancel := context.WithTimeout(ctx, unmountTimeout)
 		defer forceCancel()
 
 		cmd = exec.CommandContext(forceCtx, "hdiutil", "detach", "-force", mountPoint)
-		if err := cmd.Run(); err != nil {
+		done = logging.Command(cmd)
+		err = cmd.Run()
+		done(err)
+		if err != nil {
 			if forceCtx.Err() == context.DeadlineExceeded {
 				return fmt.Errorf("volume unmount timed out after %v (even with force)", unmountTimeout)
 			}
 			return fmt.Errorf("failed to unmount volume (even with force): %w", err)
 		}
+		slog.Warn("volume force-detached", "mount_point", mountPoint)
 	}
+	slog.Info("volume unmounted", "mount_point", mountPoint)
 
-	// Clean up our mount point directory in /tmp
+	// Clean up our mount point directory
 	// Only remove if it's one of our managed mount points (safety check)
-	if strings.HasPrefix(mountPoint, mountPointPrefix) {
+	if m.isManagedMountPoint(mountPoint) {
 		os.Remove(mountPoint)
 	}
 
@@ -197,6 +646,9 @@ func (m *MacOSVolumeManager) Exists(volumePath string) bool {
 
 // createDirectoryStructure creates the required directories inside the mounted volume.
 func (m *MacOSVolumeManager) createDirectoryStructure(mountPoint string, cfg BootstrapConfig) error {
+	if cfg.Identity {
+		return createIdentityStructure(mountPoint, cfg)
+	}
 	for _, dir := range config.VolumeStructure {
 		path := filepath.Join(mountPoint, dir)
 		if err := os.MkdirAll(path, constants.DirPermissions); err != nil {
@@ -205,7 +657,7 @@ func (m *MacOSVolumeManager) createDirectoryStructure(mountPoint string, cfg Boo
 	}
 
 	// Build CLAUDE.md content
-	claudeMDContent := embedded.ClaudeMDTemplate
+	claudeMDContent := embedded.ClaudeMDTemplate + cfg.Template.ClaudeMD()
 
 	// Append context files
 	for _, ctxFile := range cfg.ContextFiles {
@@ -227,19 +679,33 @@ func (m *MacOSVolumeManager) createDirectoryStructure(mountPoint string, cfg Boo
 	}
 
 	// Install doc-sync skill and memory system
-	if err := embedded.WriteDocSyncFiles(mountPoint); err != nil {
+	if err := embedded.WriteDocSyncFiles(mountPoint); errors.Is(err, embedded.ErrDoctoolNotEmbedded) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; the doc-sync skill won't work until you upgrade with a full build.\n", err)
+	} else if err != nil {
 		return fmt.Errorf("failed to install doc-sync: %w", err)
 	}
 	if err := embedded.WriteTaskMgrFiles(mountPoint); err != nil {
 		return fmt.Errorf("failed to install task-mgr: %w", err)
 	}
+
+	// bd is downloaded, so a network failure should not abort the bootstrap
+	if err := beads.Install(mountPoint); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to install bd: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Run 'capsule beads install' to retry.\n")
+	}
 	if err := embedded.WriteSettingsJSON(mountPoint); err != nil {
 		return fmt.Errorf(`failed to write settings.json: %w
 
 Recovery: Manually add to ~/.claude/settings.json inside the container:
-  "mcpServers": { "doc-sync": { "command": "python3", "args": ["/claude-env/home/.claude/skills/doc-sync/mcp_server.py"] } }
+  "mcpServers": { "doc-sync": { "command": "/claude-env/home/.claude/skills/doc-sync/doctool", "args": ["mcp"] } }
 Or delete the volume and re-run bootstrap.`, err)
 	}
+	if err := embedded.WriteTemplateFile(mountPoint, cfg.Template); err != nil {
+		return err
+	}
+	if err := WriteMetadata(mountPoint, NewMetadata(cfg)); err != nil {
+		return err
+	}
 	if cfg.Version != "" {
 		if err := embedded.WriteVersionFile(mountPoint, cfg.Version); err != nil {
 			return fmt.Errorf("failed to write VERSION: %w", err)
@@ -249,31 +715,28 @@ Or delete the volume and re-run bootstrap.`, err)
 	return nil
 }
 
-// findAnyMountedVolume finds the mount point for any mounted capsule volume.
-// This is a fallback for cases where we don't know the specific volume path.
-func (m *MacOSVolumeManager) findAnyMountedVolume() string {
-	// Check for our mount points in /Volumes
-	entries, err := os.ReadDir("/Volumes")
-	if err != nil {
-		return ""
+// createIdentityStructure lays out an identity volume: the credential
+// directories and the volume's metadata, without the tools and context a
+// data volume gets.
+func createIdentityStructure(mountPoint string, cfg BootstrapConfig) error {
+	for _, dir := range config.IdentityStructure {
+		if err := os.MkdirAll(filepath.Join(mountPoint, dir), constants.DirPermissions); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
 	}
-
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "Capsule-") && entry.IsDir() {
-			mountPoint := filepath.Join("/Volumes", entry.Name())
-			// Verify it's actually mounted by checking for content
-			contents, err := os.ReadDir(mountPoint)
-			if err == nil && len(contents) > 0 {
-				return mountPoint
-			}
+	if err := WriteMetadata(mountPoint, NewMetadata(cfg)); err != nil {
+		return err
+	}
+	if cfg.Version != "" {
+		if err := embedded.WriteVersionFile(mountPoint, cfg.Version); err != nil {
+			return fmt.Errorf("failed to write VERSION: %w", err)
 		}
 	}
-
-	return ""
+	return nil
 }
 
 // findMountPointForVolume uses hdiutil info to find the mount point for a specific volume file.
-func (m *MacOSVolumeManager) findMountPointForVolume(volumePath string) string {
+func (m *MacOSVolumeManager) findMountPointForVolume(ctx context.Context, volumePath string) string {
 	if volumePath == "" {
 		return ""
 	}
@@ -284,61 +747,76 @@ func (m *MacOSVolumeManager) findMountPointForVolume(volumePath string) string {
 		return ""
 	}
 
-	// Use hdiutil info to get information about mounted disk images
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	mounted, err := m.hdiutilMounts(ctx, false)
+	if err != nil {
+		return ""
+	}
+	for _, v := range mounted {
+		if absImagePath, err := filepath.Abs(v.ImagePath); err == nil && absImagePath == absVolumePath {
+			return v.MountPoint
+		}
+	}
+	return ""
+}
+
+// hdiutilMounts returns the capsule volumes hdiutil reports as attached,
+// from the mount cache when it is fresh, unless uncached is set.
+func (m *MacOSVolumeManager) hdiutilMounts(ctx context.Context, uncached bool) ([]MountedVolume, error) {
+	mounted, generation, ok := m.mounts.load()
+	if ok && !uncached {
+		return mounted, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "hdiutil", "info")
+	cmd := exec.CommandContext(ctx, "hdiutil", "info", "-plist")
+	done := logging.Command(cmd)
 	output, err := cmd.Output()
+	done(err)
 	if err != nil {
-		return ""
+		return nil, fmt.Errorf("hdiutil info failed: %w", err)
+	}
+	mounted, err = parseHdiutilPlist(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
 	}
+	m.mounts.store(generation, mounted)
+	return mounted, nil
+}
 
-	// Parse hdiutil info output to find our volume
-	// Format is blocks of info separated by "================================================"
-	// Each block has "image-path : /path/to/image" and mount points
-	lines := strings.Split(string(output), "\n")
-	var currentImagePath string
-	var foundOurImage bool
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Check for image path
-		if strings.HasPrefix(line, "image-path") {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				currentImagePath = strings.TrimSpace(parts[1])
-				// Check if this is our volume (compare absolute paths)
-				absCurrentPath, err := filepath.Abs(currentImagePath)
-				if err == nil {
-					foundOurImage = absCurrentPath == absVolumePath
-				}
-			}
-		}
+// ListMounted returns every mounted capsule volume. Mount points in the mount
+// directories that hdiutil does not report (e.g. left by an older capsule) are
+// included with an empty ImagePath if a filesystem is mounted on them.
+func (m *MacOSVolumeManager) ListMounted(ctx context.Context) ([]MountedVolume, error) {
+	return m.listMounted(ctx, false)
+}
 
-		// If we found our image, look for mount point in /Volumes
-		if foundOurImage && strings.Contains(line, "/Volumes/Capsule-") {
-			// Line format: "/dev/diskXsY	Apple_APFS	/Volumes/Capsule-xxx"
-			fields := strings.Fields(line)
-			for _, field := range fields {
-				if strings.HasPrefix(field, "/Volumes/Capsule-") {
-					return field
-				}
-			}
-		}
+// ListMountedUncached is ListMounted without the mount cache.
+func (m *MacOSVolumeManager) ListMountedUncached(ctx context.Context) ([]MountedVolume, error) {
+	return m.listMounted(ctx, true)
+}
 
-		// Reset on separator
-		if strings.HasPrefix(line, "===") {
-			foundOurImage = false
-			currentImagePath = ""
-		}
+func (m *MacOSVolumeManager) listMounted(ctx context.Context, uncached bool) ([]MountedVolume, error) {
+	mounted, err := m.hdiutilMounts(ctx, uncached)
+	if err != nil {
+		return nil, err
 	}
 
-	return ""
+	known := make(map[string]bool)
+	for _, v := range mounted {
+		known[v.MountPoint] = true
+	}
+
+	for _, mountPoint := range m.scanMountPoints() {
+		if !known[mountPoint] && isMountPoint(mountPoint) {
+			mounted = append(mounted, MountedVolume{MountPoint: mountPoint})
+		}
+	}
+	return mounted, nil
 }
 
 // GetMountPoint returns the mount point for the specified volume if mounted, empty string otherwise.
-func (m *MacOSVolumeManager) GetMountPoint(volumePath string) string {
-	return m.findMountPointForVolume(volumePath)
+func (m *MacOSVolumeManager) GetMountPoint(ctx context.Context, volumePath string) string {
+	return m.findMountPointForVolume(ctx, volumePath)
 }
@@ -0,0 +1,362 @@
+package volume
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+func TestParseHdiutilPlist(t *testing.T) {
+	output := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>framework</key>
+	<string>671.100.2</string>
+	<key>images</key>
+	<array>
+		<dict>
+			<key>autodiskmount</key>
+			<true/>
+			<key>image-encrypted</key>
+			<true/>
+			<key>image-path</key>
+			<string>/Users/me/.capsule/volumes/capsule.sparseimage</string>
+			<key>owner-uid</key>
+			<integer>501</integer>
+			<key>system-entities</key>
+			<array>
+				<dict>
+					<key>content-hint</key>
+					<string>GUID_partition_scheme</string>
+					<key>dev-entry</key>
+					<string>/dev/disk4</string>
+				</dict>
+				<dict>
+					<key>content-hint</key>
+					<string>41504653-0000-11AA-AA11-00306543ECAC</string>
+					<key>dev-entry</key>
+					<string>/dev/disk5s1</string>
+					<key>mount-point</key>
+					<string>/Volumes/Capsule-1a2b3c4d</string>
+					<key>volume-kind</key>
+					<string>apfs</string>
+				</dict>
+			</array>
+		</dict>
+		<dict>
+			<key>image-path</key>
+			<string>/Users/me/Downloads/Installer.dmg</string>
+			<key>system-entities</key>
+			<array>
+				<dict>
+					<key>dev-entry</key>
+					<string>/dev/disk6s1</string>
+					<key>mount-point</key>
+					<string>/Volumes/Installer</string>
+				</dict>
+			</array>
+		</dict>
+		<dict>
+			<key>image-path</key>
+			<string>/Users/me/projects/app/capsule.sparseimage</string>
+			<key>system-entities</key>
+			<array>
+				<dict>
+					<key>mount-point</key>
+					<string>/Volumes/Capsule-5e6f7a8b</string>
+				</dict>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+
+	got, err := parseHdiutilPlist(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("parseHdiutilPlist() error = %v", err)
+	}
+	want := []MountedVolume{
+		{ImagePath: "/Users/me/.capsule/volumes/capsule.sparseimage", MountPoint: "/Volumes/Capsule-1a2b3c4d"},
+		{ImagePath: "/Users/me/projects/app/capsule.sparseimage", MountPoint: "/Volumes/Capsule-5e6f7a8b"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseHdiutilPlist() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseHdiutilPlist()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	empty := `<plist version="1.0"><dict><key>framework</key><string>1</string><key>images</key><array/></dict></plist>`
+	if got, err := parseHdiutilPlist(strings.NewReader(empty)); err != nil || len(got) != 0 {
+		t.Errorf("parseHdiutilPlist(no images) = %+v, %v", got, err)
+	}
+	if _, err := parseHdiutilPlist(strings.NewReader("framework : 671.100.2")); err == nil {
+		t.Error("parseHdiutilPlist() should reject non-plist output")
+	}
+}
+
+func TestMountCache(t *testing.T) {
+	dir := t.TempDir()
+	mountPoint := filepath.Join(dir, "Capsule-1a2b3c4d")
+	if err := os.Mkdir(mountPoint, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	cache := &mountCache{
+		path: filepath.Join(dir, "run", mountCacheFile),
+		ttl:  10 * time.Second,
+		now:  func() time.Time { return now },
+	}
+	mounts := []MountedVolume{{ImagePath: "/a/capsule.sparseimage", MountPoint: mountPoint}}
+	generation := func() uint64 {
+		_, g, _ := cache.load()
+		return g
+	}
+
+	if _, _, ok := cache.load(); ok {
+		t.Fatal("load() should miss before anything is stored")
+	}
+	cache.store(generation(), mounts)
+	if got, _, ok := cache.load(); !ok || len(got) != 1 || got[0] != mounts[0] {
+		t.Errorf("load() = %+v, %v, want the stored mounts", got, ok)
+	}
+
+	now = now.Add(11 * time.Second)
+	if _, _, ok := cache.load(); ok {
+		t.Error("load() should miss after the TTL")
+	}
+
+	cache.store(generation(), mounts)
+	cache.invalidate()
+	if _, _, ok := cache.load(); ok {
+		t.Error("load() should miss after invalidate()")
+	}
+
+	cache.store(generation(), mounts)
+	os.Remove(mountPoint)
+	if _, _, ok := cache.load(); ok {
+		t.Error("load() should miss once a cached mount point is gone")
+	}
+
+	// An empty result is cached too
+	cache.store(generation(), nil)
+	if got, _, ok := cache.load(); !ok || len(got) != 0 {
+		t.Errorf("load() = %+v, %v, want a cached empty list", got, ok)
+	}
+
+	disabled := &mountCache{ttl: time.Minute, now: time.Now}
+	disabled.store(0, mounts)
+	if _, _, ok := disabled.load(); ok {
+		t.Error("a cache without a path should never hit")
+	}
+}
+
+func TestMountCacheRefusesStaleStore(t *testing.T) {
+	dir := t.TempDir()
+	mountPoint := filepath.Join(dir, "Capsule-1a2b3c4d")
+	if err := os.Mkdir(mountPoint, 0700); err != nil {
+		t.Fatal(err)
+	}
+	cache := &mountCache{path: filepath.Join(dir, "run", mountCacheFile), ttl: 10 * time.Second, now: time.Now}
+
+	// One process misses and asks hdiutil; another mounts meanwhile
+	_, generation, ok := cache.load()
+	if ok {
+		t.Fatal("load() should miss before anything is stored")
+	}
+	cache.invalidate()
+	cache.store(generation, nil)
+	if got, _, ok := cache.load(); ok {
+		t.Errorf("load() = %+v, want a miss: the list predates the mount", got)
+	}
+
+	// The next miss stores what hdiutil reports after the mount
+	_, generation, _ = cache.load()
+	mounts := []MountedVolume{{ImagePath: "/a/capsule.sparseimage", MountPoint: mountPoint}}
+	cache.store(generation, mounts)
+	if got, _, ok := cache.load(); !ok || len(got) != 1 {
+		t.Errorf("load() = %+v, %v, want the stored mounts", got, ok)
+	}
+}
+
+func TestWaitForUnmount(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := waitForUnmount(filepath.Join(dir, "missing"), time.Second); err != nil {
+		t.Errorf("waitForUnmount(missing) error = %v", err)
+	}
+
+	mountPoint := filepath.Join(dir, "Capsule-1a2b3c4d")
+	os.Mkdir(mountPoint, 0700)
+	if err := waitForUnmount(mountPoint, time.Second); err != nil {
+		t.Errorf("waitForUnmount(empty dir) error = %v", err)
+	}
+
+	os.WriteFile(filepath.Join(mountPoint, "VERSION"), []byte("capsule 1.0\n"), 0600)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.RemoveAll(mountPoint)
+	}()
+	if err := waitForUnmount(mountPoint, 5*time.Second); err != nil {
+		t.Errorf("waitForUnmount() should return once the mount point is removed, got %v", err)
+	}
+
+	os.Mkdir(mountPoint, 0700)
+	os.WriteFile(filepath.Join(mountPoint, "VERSION"), []byte("capsule 1.0\n"), 0600)
+	if err := waitForUnmount(mountPoint, 100*time.Millisecond); err == nil {
+		t.Error("waitForUnmount() should time out while the volume is still mounted")
+	}
+}
+
+func TestMountPointsInMountDir(t *testing.T) {
+	mountDir := t.TempDir()
+	m := NewMacOSVolumeManager(mountDir)
+
+	mountPoint := m.generateMountPoint("/Users/me/.capsule/volumes/capsule.sparseimage")
+	if filepath.Dir(mountPoint) != mountDir || !strings.HasPrefix(filepath.Base(mountPoint), "Capsule-") {
+		t.Errorf("generateMountPoint() = %q, want Capsule-<hash> in %s", mountPoint, mountDir)
+	}
+	if m.generateMountPoint("/Users/me/projects/app/capsule.sparseimage") == mountPoint {
+		t.Error("generateMountPoint() gave two volumes the same mount point")
+	}
+
+	managed := map[string]bool{
+		mountPoint:                     true,
+		"/Volumes/Capsule-1a2b3c4d":    true, // mounted by an older capsule
+		"/Volumes/Installer":           false,
+		filepath.Join(mountDir, "etc"): false,
+		"/tmp/Capsule-1a2b3c4d":        false,
+	}
+	for path, want := range managed {
+		if got := m.isManagedMountPoint(path); got != want {
+			t.Errorf("isManagedMountPoint(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	// Only non-empty mount points count as mounted
+	if err := os.MkdirAll(filepath.Join(mountPoint, "auth"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(mountDir, "Capsule-leftover"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	found := m.scanMountPoints()
+	if len(found) == 0 || found[0] != mountPoint {
+		t.Errorf("scanMountPoints() = %v, want %s first", found, mountPoint)
+	}
+	for _, f := range found {
+		if filepath.Base(f) == "Capsule-leftover" {
+			t.Errorf("scanMountPoints() included empty mount point %s", f)
+		}
+	}
+}
+
+func TestStaleMountPoints(t *testing.T) {
+	mountDir := t.TempDir()
+	empty := filepath.Join(mountDir, "Capsule-empty")
+	attached := filepath.Join(mountDir, "Capsule-attached")
+	withFiles := filepath.Join(mountDir, "Capsule-files")
+	for _, dir := range []string{empty, attached, filepath.Join(withFiles, "repos")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := staleMountPoints([]string{empty, attached, withFiles}, map[string]bool{attached: true})
+	if len(got) != 1 || got[0] != empty {
+		t.Errorf("staleMountPoints() = %v, want [%s]", got, empty)
+	}
+}
+
+func TestImageSizeAndCapacity(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "capsule.sparseimage")
+	f, err := os.Create(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A sparse file: long, but with only one block written
+	if _, err := f.WriteAt([]byte("x"), 64<<20); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	size, err := ImageSize(image)
+	if err != nil {
+		t.Fatalf("ImageSize() error = %v", err)
+	}
+	if size <= 0 || size >= 64<<20 {
+		t.Errorf("ImageSize() = %d, want the allocated size of a sparse 64 MiB file", size)
+	}
+	if _, err := ImageSize(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ImageSize(missing) succeeded, want error")
+	}
+
+	capacity, err := FilesystemCapacity(dir)
+	if err != nil {
+		t.Fatalf("FilesystemCapacity() error = %v", err)
+	}
+	if capacity.TotalBytes <= 0 || capacity.UsedBytes > capacity.TotalBytes || capacity.FreeBytes > capacity.TotalBytes {
+		t.Errorf("FilesystemCapacity() = %+v", capacity)
+	}
+}
+
+func TestAttachError(t *testing.T) {
+	if err := attachError("hdiutil: attach failed - Authentication error\n"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("attachError(authentication) = %v, want ErrAuthFailed", err)
+	}
+	if err := attachError("hdiutil: attach failed - Resource busy\n"); !errors.Is(err, ErrMountBusy) {
+		t.Errorf("attachError(busy) = %v, want ErrMountBusy", err)
+	}
+	err := attachError("hdiutil: attach failed - image not recognized\n")
+	if errors.Is(err, ErrAuthFailed) || errors.Is(err, ErrMountBusy) {
+		t.Errorf("attachError(other) = %v, want neither sentinel", err)
+	}
+	if !strings.Contains(err.Error(), "image not recognized") {
+		t.Errorf("attachError(other) = %v, want hdiutil's message", err)
+	}
+}
+
+func TestCheckManifestMissing(t *testing.T) {
+	dir := t.TempDir()
+	volumePath := filepath.Join(dir, "capsule.sparseimage")
+	mountPoint := filepath.Join(dir, "mnt")
+	idPath := filepath.Join(mountPoint, manifest.VolumeIDFile)
+	if err := os.MkdirAll(filepath.Dir(idPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(idPath, []byte("0123abcd\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	password := terminal.NewSecurePassword([]byte("secret"))
+	defer password.Clear()
+
+	// The volume was given a manifest once, and it has been deleted
+	var out bytes.Buffer
+	m := &MacOSVolumeManager{}
+	m.checkManifest(context.Background(), &out, volumePath, mountPoint, password)
+	if !strings.Contains(out.String(), "!!! WARNING") || !strings.Contains(out.String(), errManifestMissing) || !strings.Contains(out.String(), "capsule verify --accept") {
+		t.Errorf("checkManifest() printed %q, want the tamper warning", out.String())
+	}
+	if _, err := os.Stat(manifest.Path(volumePath)); !os.IsNotExist(err) {
+		t.Errorf("checkManifest() signed a new manifest for a volume that lost its own: %v", err)
+	}
+
+	problems, err := m.VerifyManifest(context.Background(), volumePath, mountPoint, password)
+	if err != nil || len(problems) != 1 || problems[0] != errManifestMissing {
+		t.Errorf("VerifyManifest() = %v, %v; want the missing manifest", problems, err)
+	}
+}
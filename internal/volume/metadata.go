@@ -0,0 +1,104 @@
+package volume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+)
+
+// MetadataFile is the path within the encrypted volume that records how the
+// volume was bootstrapped.
+const MetadataFile = "config/volume.json"
+
+// Metadata describes how a volume was bootstrapped. It is written once and
+// is informational only; nothing reads it to decide how to start.
+type Metadata struct {
+	CreatedAt      time.Time         `json:"created_at"`
+	SizeGB         int               `json:"size_gb"`
+	CapsuleVersion string            `json:"capsule_version,omitempty"`
+	Encryption     Encryption        `json:"encryption"`
+	Filesystem     Filesystem        `json:"filesystem"`
+	Format         ImageFormat       `json:"format,omitempty"`
+	Identity       bool              `json:"identity,omitempty"`
+	Template       embedded.Template `json:"template,omitempty"`
+	ContextFiles   []string          `json:"context_files,omitempty"`
+}
+
+// NewMetadata returns the metadata for a volume bootstrapped now with cfg.
+func NewMetadata(cfg BootstrapConfig) Metadata {
+	return Metadata{
+		CreatedAt:      time.Now().UTC(),
+		SizeGB:         cfg.SizeGB,
+		CapsuleVersion: cfg.Version,
+		Encryption:     cfg.encryption(),
+		Filesystem:     cfg.filesystem(),
+		Format:         cfg.imageFormat(),
+		Identity:       cfg.Identity,
+		Template:       cfg.Template,
+		ContextFiles:   cfg.ContextFiles,
+	}
+}
+
+// String summarizes the metadata on one line for 'capsule status'.
+func (m Metadata) String() string {
+	parts := []string{
+		"created " + m.CreatedAt.Local().Format("2006-01-02"),
+		fmt.Sprintf("%d GB", m.SizeGB),
+		fmt.Sprintf("%s %s", m.Encryption, m.Filesystem),
+	}
+	if m.Identity {
+		parts = append(parts, "identity volume")
+	}
+	if m.Format != "" && m.Format != DefaultImageFormat {
+		parts = append(parts, string(m.Format))
+	}
+	if m.CapsuleVersion != "" {
+		parts = append(parts, "capsule "+m.CapsuleVersion)
+	}
+	if m.Template != "" {
+		parts = append(parts, "template "+string(m.Template))
+	}
+	if len(m.ContextFiles) > 0 {
+		names := make([]string, len(m.ContextFiles))
+		for i, path := range m.ContextFiles {
+			names[i] = filepath.Base(path)
+		}
+		parts = append(parts, "context "+strings.Join(names, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteMetadata writes m to the volume mounted at mountPoint.
+func WriteMetadata(mountPoint string, m Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode volume metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(mountPoint, MetadataFile), append(data, '\n'), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to write volume metadata: %w", err)
+	}
+	return nil
+}
+
+// ReadMetadata returns the metadata of the volume mounted at mountPoint, or
+// nil without an error for volumes bootstrapped before it was recorded.
+func ReadMetadata(mountPoint string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, MetadataFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume metadata: %w", err)
+	}
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s in volume: %w", MetadataFile, err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,42 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/embedded"
+)
+
+func TestMetadata(t *testing.T) {
+	mountPoint := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mountPoint, "config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ReadMetadata(mountPoint); err != nil || m != nil {
+		t.Fatalf("ReadMetadata() without a file = %+v, %v; want nil, nil", m, err)
+	}
+
+	m := NewMetadata(BootstrapConfig{
+		SizeGB:       4,
+		Version:      "0.3.0",
+		Template:     embedded.TemplateGo,
+		ContextFiles: []string{"/Users/me/notes/team.md"},
+	})
+	m.CreatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	if err := WriteMetadata(mountPoint, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMetadata(mountPoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Encryption != DefaultEncryption || got.Filesystem != DefaultFilesystem || got.SizeGB != 4 {
+		t.Errorf("ReadMetadata() = %+v", got)
+	}
+	want := "created 2026-03-01, 4 GB, AES-256 APFS, capsule 0.3.0, template go, context team.md"
+	if s := got.String(); s != want {
+		t.Errorf("String() = %q, want %q", s, want)
+	}
+}
@@ -0,0 +1,271 @@
+package volume
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// mountCacheFile caches 'hdiutil info' under ~/.capsule/run.
+const mountCacheFile = "mounts.json"
+
+// mountCacheTTL is how long a cached 'hdiutil info' is trusted. Capsule
+// invalidates the cache whenever it mounts or unmounts, so the TTL only bounds
+// how long a mount or eject made outside capsule goes unnoticed.
+const mountCacheTTL = 10 * time.Second
+
+// mountCacheEntry is the cache file's content. Generation counts
+// invalidations, so a list read from hdiutil before a mount or unmount is
+// never stored over the invalidation that mount or unmount made.
+type mountCacheEntry struct {
+	Generation uint64          `json:"generation"`
+	Time       time.Time       `json:"time"`
+	Mounts     []MountedVolume `json:"mounts"`
+}
+
+// mountCache stores the capsule volumes hdiutil reported as attached, so
+// commands that only need a mount point don't each pay for 'hdiutil info'.
+// Capsule processes share it; each holds a lock on it while it writes it.
+// A zero path disables caching.
+type mountCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// defaultMountCache returns the cache at ~/.capsule/run/mounts.json, or a
+// disabled cache if the home directory is unknown.
+func defaultMountCache() *mountCache {
+	c := &mountCache{ttl: mountCacheTTL, now: time.Now}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		c.path = filepath.Join(homeDir, constants.CapsuleConfigDir, constants.RunSubdir, mountCacheFile)
+	}
+	return c
+}
+
+// load returns the cached mounts if they are fresh and every cached mount
+// point still exists, and the cache's generation either way, for storing
+// what hdiutil reports instead.
+func (c *mountCache) load() ([]MountedVolume, uint64, bool) {
+	if c.path == "" {
+		return nil, 0, false
+	}
+	entry, ok := c.read()
+	if !ok {
+		return nil, entry.Generation, false
+	}
+	if age := c.now().Sub(entry.Time); age < 0 || age > c.ttl {
+		return nil, entry.Generation, false
+	}
+	// An eject from Finder removes the mount point; don't wait out the TTL
+	for _, v := range entry.Mounts {
+		if _, err := os.Stat(v.MountPoint); err != nil {
+			return nil, entry.Generation, false
+		}
+	}
+	return entry.Mounts, entry.Generation, true
+}
+
+// store writes mounts, read from hdiutil after load returned generation, to
+// the cache, unless the cache was invalidated since: the mounts may predate
+// that mount or unmount. Failures only cost the next command a fresh
+// 'hdiutil info', so they are ignored.
+func (c *mountCache) store(generation uint64, mounts []MountedVolume) {
+	if c.path == "" {
+		return
+	}
+	c.locked(func() {
+		if current, _ := c.read(); current.Generation != generation {
+			return
+		}
+		c.write(mountCacheEntry{Generation: generation, Time: c.now(), Mounts: mounts})
+	})
+}
+
+// invalidate drops the cache after a mount or unmount, moving it to a new
+// generation so lists read from hdiutil before it are not stored.
+func (c *mountCache) invalidate() {
+	if c.path == "" {
+		return
+	}
+	ok := c.locked(func() {
+		current, _ := c.read()
+		c.write(mountCacheEntry{Generation: current.Generation + 1})
+	})
+	if !ok {
+		os.Remove(c.path)
+	}
+}
+
+// read returns the cache file's content, and whether it could be read. A
+// missing or corrupt file is generation 0.
+func (c *mountCache) read() (mountCacheEntry, bool) {
+	var entry mountCacheEntry
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return mountCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// write replaces the cache file atomically.
+func (c *mountCache) write(entry mountCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, constants.FilePermissions); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// locked runs f holding the cache's lock, and reports whether it could take
+// the lock; if not, f isn't run.
+func (c *mountCache) locked(f func()) bool {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return false
+	}
+	lock, err := os.OpenFile(c.path+".lock", os.O_RDWR|os.O_CREATE, constants.FilePermissions)
+	if err != nil {
+		return false
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return false
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	f()
+	return true
+}
+
+// parseHdiutilPlist extracts capsule mounts from 'hdiutil info -plist' output:
+// a dict whose "images" array holds one dict per attached image, with its
+// "image-path" and a "system-entities" array of partitions, some of which
+// have a "mount-point".
+func parseHdiutilPlist(r io.Reader) ([]MountedVolume, error) {
+	root, err := decodePlist(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hdiutil info: %w", err)
+	}
+	info, ok := root.(map[string]any)
+	if !ok {
+		return nil, errors.New("failed to parse hdiutil info: top level is not a dict")
+	}
+
+	var mounted []MountedVolume
+	images, _ := info["images"].([]any)
+	for _, image := range images {
+		imageDict, _ := image.(map[string]any)
+		imagePath, _ := imageDict["image-path"].(string)
+		if imagePath == "" {
+			continue
+		}
+		entities, _ := imageDict["system-entities"].([]any)
+		for _, entity := range entities {
+			entityDict, _ := entity.(map[string]any)
+			mountPoint, _ := entityDict["mount-point"].(string)
+			if strings.HasPrefix(filepath.Base(mountPoint), constants.MountPointNamePrefix) {
+				mounted = append(mounted, MountedVolume{ImagePath: imagePath, MountPoint: mountPoint})
+				break
+			}
+		}
+	}
+	return mounted, nil
+}
+
+// ParseHdiutilInfo returns the capsule mounts in 'hdiutil info -plist'
+// output taken elsewhere, e.g. on another machine over SSH.
+func ParseHdiutilInfo(output []byte) ([]MountedVolume, error) {
+	return parseHdiutilPlist(bytes.NewReader(output))
+}
+
+// decodePlist decodes an XML property list into map[string]any for dicts,
+// []any for arrays, bool for booleans, and string for every other value.
+func decodePlist(r io.Reader) (any, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key string
+		for {
+			tok, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []any
+		for {
+			tok, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	default:
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return text, nil
+	}
+}
@@ -0,0 +1,141 @@
+package volume
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+// PortableSidecarSuffixes name the sidecars that travel with every copy of an
+// image, whether synced, backed up, restored, or converted: without its KDF
+// parameters a stretched volume cannot be unlocked, and without its manifest
+// it fails verification. The other sidecars, such as LastMountSuffix and the
+// last-sync record, describe this machine's copy; they move with the image
+// (see SidecarPaths) but are not copied.
+var PortableSidecarSuffixes = []string{kdf.FileSuffix, manifest.FileSuffix}
+
+// PortableSidecars returns the paths of the portable sidecars of the image at
+// volumePath, in the order of PortableSidecarSuffixes, whether or not they
+// exist.
+func PortableSidecars(volumePath string) []string {
+	paths := make([]string, len(PortableSidecarSuffixes))
+	for i, suffix := range PortableSidecarSuffixes {
+		paths[i] = volumePath + suffix
+	}
+	return paths
+}
+
+// SidecarPaths returns the files next to the image at volumePath that belong
+// to it: those named after the image with a suffix, such as its KDF
+// parameters, manifest, and last-mount record. It includes every portable
+// sidecar that exists.
+func SidecarPaths(volumePath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(volumePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", filepath.Dir(volumePath), err)
+	}
+	prefix := filepath.Base(volumePath) + "."
+	var paths []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) && e.Type().IsRegular() {
+			paths = append(paths, filepath.Join(filepath.Dir(volumePath), e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// MoveImage moves the image at src, with its sidecars, to dst. The sidecars
+// go first, so the image never appears at dst without them, and are moved
+// back if a later step fails. Moves across filesystems copy, then remove the
+// original. The volume must not be mounted.
+func MoveImage(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("failed to read volume: %w", err)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("a file already exists at %s", dst)
+	}
+	sidecars, err := SidecarPaths(src)
+	if err != nil {
+		return err
+	}
+	suffix := func(path string) string { return strings.TrimPrefix(path, src) }
+	for _, sidecar := range sidecars {
+		if _, err := os.Stat(dst + suffix(sidecar)); err == nil {
+			return fmt.Errorf("a file already exists at %s", dst+suffix(sidecar))
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+
+	var moved []string
+	rollback := func() {
+		for _, sidecar := range moved {
+			moveFile(dst+suffix(sidecar), sidecar)
+		}
+	}
+	for _, sidecar := range sidecars {
+		if err := moveFile(sidecar, dst+suffix(sidecar)); err != nil {
+			rollback()
+			return err
+		}
+		moved = append(moved, sidecar)
+	}
+	if err := moveFile(src, dst); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// moveFile renames src to dst, or copies it and removes src when they are on
+// different filesystems.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	if err := copyImage(src, dst); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to remove %s after copying it: %w", src, err)
+	}
+	return nil
+}
+
+// copyImage copies the file at src to dst, or the directory at src with the
+// files in it, as a sparse bundle is. A failed copy leaves nothing at dst.
+func copyImage(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, strings.TrimPrefix(path, src))
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, constants.DirPermissions)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		}
+		return fmt.Errorf("cannot copy %s: not a regular file", path)
+	})
+	if err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return nil
+}
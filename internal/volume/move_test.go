@@ -0,0 +1,106 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+func TestPortableSidecars(t *testing.T) {
+	volumePath := filepath.Join(t.TempDir(), "capsule.sparseimage")
+	want := []string{kdf.Path(volumePath), manifest.Path(volumePath)}
+	if got := PortableSidecars(volumePath); !slices.Equal(got, want) {
+		t.Errorf("PortableSidecars() = %v, want %v", got, want)
+	}
+
+	// Whatever travels with a copy also moves with the image
+	for _, path := range append([]string{volumePath, LastMountPath(volumePath)}, want...) {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved, err := SidecarPaths(volumePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range want {
+		if !slices.Contains(moved, path) {
+			t.Errorf("SidecarPaths() = %v, missing %s", moved, filepath.Base(path))
+		}
+	}
+	if !slices.Contains(moved, LastMountPath(volumePath)) {
+		t.Errorf("SidecarPaths() = %v, missing the last-mount record", moved)
+	}
+}
+
+func TestMoveImage(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "capsule.sparseimage")
+	unrelated := filepath.Join(filepath.Dir(src), "capsule.txt")
+	files := map[string]string{src: "image", kdf.Path(src): "params", LastMountPath(src): "2026-10-16T00:00:00Z\n", unrelated: "notes"}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(t.TempDir(), "volumes", "capsule.sparseimage")
+	if err := MoveImage(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{dst: "image", kdf.Path(dst): "params", LastMountPath(dst): "2026-10-16T00:00:00Z\n"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(path), data, err, want)
+		}
+	}
+	for _, path := range []string{src, kdf.Path(src), LastMountPath(src)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", filepath.Base(path), err)
+		}
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated file was moved: %v", err)
+	}
+}
+
+func TestMoveImageRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "a.sparseimage"), filepath.Join(dir, "b.sparseimage")
+	for _, path := range []string{src, kdf.Path(src), kdf.Path(dst)} {
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := MoveImage(src, dst); err == nil {
+		t.Error("MoveImage() over an existing sidecar succeeded")
+	}
+	if _, err := os.Stat(kdf.Path(src)); err != nil {
+		t.Errorf("failed move disturbed the source: %v", err)
+	}
+}
+
+func TestCopyImageBundle(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "capsule.sparsebundle")
+	band := filepath.Join("bands", "0")
+	for _, rel := range []string{"Info.plist", band} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(src, rel)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, rel), []byte(rel), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(t.TempDir(), "capsule.sparsebundle")
+	if err := copyImage(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"Info.plist", band} {
+		if data, err := os.ReadFile(filepath.Join(dst, rel)); err != nil || string(data) != rel {
+			t.Errorf("%s = %q, %v; want %q", rel, data, err, rel)
+		}
+	}
+}
@@ -2,24 +2,74 @@ package volume
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/jeanhaley32/claude-capsule/internal/config"
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
 )
 
 // PathResolver handles volume path resolution with priority rules.
 type PathResolver struct {
 	homeDir string
+	names   map[string]string // Named volumes from the volumes setting
 }
 
-// NewPathResolver creates a new PathResolver.
+// NewPathResolver creates a new PathResolver with the named volumes in
+// ~/.capsule/config.json.
 func NewPathResolver() (*PathResolver, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return &PathResolver{homeDir: homeDir}, nil
+	settings, err := config.LoadSettings(filepath.Join(homeDir, constants.CapsuleConfigDir, config.SettingsFile))
+	if err != nil {
+		return nil, err
+	}
+	names, err := settings.VolumePaths(homeDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volumes in %s: %w", config.SettingsFile, err)
+	}
+	return &PathResolver{homeDir: homeDir, names: names}, nil
+}
+
+// ResolveName returns the volume a --volume value refers to. A value that
+// looks like a path, or names an existing file, is used as is. Otherwise it
+// is a volume name: one set in the volumes setting, or else
+// <name>.sparseimage (or an existing <name>.sparsebundle) in the global
+// volume directory.
+func (p *PathResolver) ResolveName(value string) string {
+	if path, ok := p.names[value]; ok {
+		return path
+	}
+	if value == "" || strings.ContainsRune(value, filepath.Separator) || HasImageExt(value) {
+		return value
+	}
+	if _, err := os.Stat(value); err == nil {
+		return value
+	}
+	return preferBundle(filepath.Join(p.GetGlobalVolumeDir(), value+filepath.Ext(constants.MacOSVolumeFile)))
+}
+
+// preferBundle returns the sparse bundle next to the sparse image at path
+// if only the bundle exists, so a volume converted to a bundle is still
+// found where the sparse image was expected.
+func preferBundle(path string) string {
+	bundle := WithFormat(path, FormatSparseBundle)
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(bundle); err == nil {
+			return bundle
+		}
+	}
+	return path
+}
+
+// Names returns the names in the volumes setting, sorted.
+func (p *PathResolver) Names() []string {
+	return slices.Sorted(maps.Keys(p.names))
 }
 
 // GetGlobalVolumeDir returns the global volume directory path.
@@ -29,29 +79,32 @@ func (p *PathResolver) GetGlobalVolumeDir() string {
 }
 
 // GetDefaultVolumePath returns the default global volume path.
-// Returns: ~/.capsule/volumes/capsule.sparseimage
+// Returns: ~/.capsule/volumes/capsule.sparseimage, or capsule.sparsebundle
+// if only that exists
 func (p *PathResolver) GetDefaultVolumePath() string {
-	return filepath.Join(p.GetGlobalVolumeDir(), constants.MacOSVolumeFile)
+	return preferBundle(filepath.Join(p.GetGlobalVolumeDir(), constants.MacOSVolumeFile))
 }
 
 // GetLocalVolumePath returns the local volume path for a given directory.
-// Returns: {dir}/capsule.sparseimage
+// Returns: {dir}/capsule.sparseimage, or capsule.sparsebundle if only that
+// exists
 func (p *PathResolver) GetLocalVolumePath(dir string) string {
-	return filepath.Join(dir, constants.MacOSVolumeFile)
+	return preferBundle(filepath.Join(dir, constants.MacOSVolumeFile))
 }
 
 // ResolveVolumePath applies the volume resolution priority rules.
 // Priority:
-// 1. Explicit path (if provided) - use exactly what user specifies
+// 1. Explicit path or volume name (if provided) - see ResolveName
 // 2. Local volume ({cwd}/capsule.sparseimage) - if exists, use it
 // 3. Global volume (~/.capsule/volumes/capsule.sparseimage) - default
 //
 // Returns the resolved volume path and whether it exists.
 func (p *PathResolver) ResolveVolumePath(explicitPath, cwd string) (volumePath string, exists bool) {
-	// Priority 1: Explicit path
+	// Priority 1: Explicit path or volume name
 	if explicitPath != "" {
-		_, err := os.Stat(explicitPath)
-		return explicitPath, err == nil
+		volumePath := p.ResolveName(explicitPath)
+		_, err := os.Stat(volumePath)
+		return volumePath, err == nil
 	}
 
 	// Priority 2: Local volume
@@ -66,13 +119,59 @@ func (p *PathResolver) ResolveVolumePath(explicitPath, cwd string) (volumePath s
 	return globalPath, err == nil
 }
 
+// KnownVolumePaths returns the volumes that exist among those capsule could
+// resolve: every image in the global volume directory, the named
+// volumes, then the local volume of each of dirs, in order and without
+// duplicates.
+func (p *PathResolver) KnownVolumePaths(dirs []string) []string {
+	var paths []string
+	for _, format := range ImageFormats {
+		matches, _ := filepath.Glob(filepath.Join(p.GetGlobalVolumeDir(), "*"+format.Ext()))
+		paths = append(paths, matches...)
+	}
+	slices.Sort(paths)
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		seen[path] = true
+	}
+	candidates := make([]string, 0, len(p.names)+len(dirs))
+	for _, name := range p.Names() {
+		candidates = append(candidates, p.names[name])
+	}
+	for _, dir := range dirs {
+		candidates = append(candidates, p.GetLocalVolumePath(dir))
+	}
+	for _, path := range candidates {
+		if seen[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // VolumeNotFoundError provides a helpful error message showing both locations checked.
 type VolumeNotFoundError struct {
 	LocalPath  string
 	GlobalPath string
+
+	// Explicit is the --volume value, if one was given, and Names the
+	// volume names it could have been.
+	Explicit string
+	Names    []string
 }
 
 func (e *VolumeNotFoundError) Error() string {
+	if e.Explicit != "" {
+		known := "none are set in " + config.SettingsFile
+		if len(e.Names) > 0 {
+			known = "known names: " + strings.Join(e.Names, ", ")
+		}
+		return fmt.Sprintf("volume %q not found: it is neither an existing volume file nor a volume name (%s)\nRun 'capsule bootstrap' first or check --volume", e.Explicit, known)
+	}
 	return fmt.Sprintf("volume not found at:\n  - %s (local)\n  - %s (global)\nRun 'capsule bootstrap' first or specify --volume", e.LocalPath, e.GlobalPath)
 }
 
@@ -83,6 +182,8 @@ func (p *PathResolver) ResolveVolumePathStrict(explicitPath, cwd string) (string
 		return "", &VolumeNotFoundError{
 			LocalPath:  p.GetLocalVolumePath(cwd),
 			GlobalPath: p.GetDefaultVolumePath(),
+			Explicit:   explicitPath,
+			Names:      p.Names(),
 		}
 	}
 	return volumePath, nil
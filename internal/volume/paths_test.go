@@ -3,6 +3,7 @@ package volume
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/jeanhaley32/claude-capsule/internal/constants"
@@ -165,3 +166,107 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestPathResolver_ResolveName(t *testing.T) {
+	homeDir := t.TempDir()
+	resolver := &PathResolver{homeDir: homeDir, names: map[string]string{"work": "/Volumes/External/work.sparseimage"}}
+	globalDir := resolver.GetGlobalVolumeDir()
+
+	existing := filepath.Join(t.TempDir(), "plain")
+	if err := os.WriteFile(existing, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Dir(existing))
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "work", want: "/Volumes/External/work.sparseimage"},
+		{value: "oss", want: filepath.Join(globalDir, "oss.sparseimage")},
+		{value: "/custom/volume.sparseimage", want: "/custom/volume.sparseimage"},
+		{value: "sub/volume", want: "sub/volume"},
+		{value: "other.sparseimage", want: "other.sparseimage"},
+		{value: "plain", want: "plain"},
+	}
+	for _, tt := range tests {
+		if got := resolver.ResolveName(tt.value); got != tt.want {
+			t.Errorf("ResolveName(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPathResolver_ResolveVolumePathStrict_UnknownName(t *testing.T) {
+	resolver := &PathResolver{homeDir: t.TempDir(), names: map[string]string{"work": "/nonexistent/work.sparseimage"}}
+
+	_, err := resolver.ResolveVolumePathStrict("oss", t.TempDir())
+	notFoundErr, ok := err.(*VolumeNotFoundError)
+	if !ok {
+		t.Fatalf("ResolveVolumePathStrict() error = %v, want *VolumeNotFoundError", err)
+	}
+	if errMsg := notFoundErr.Error(); !contains(errMsg, `"oss"`) || !contains(errMsg, "known names: work") {
+		t.Errorf("VolumeNotFoundError.Error() should name the value and the known names, got: %s", errMsg)
+	}
+}
+
+func TestPathResolver_KnownVolumePaths(t *testing.T) {
+	homeDir := t.TempDir()
+	resolver := &PathResolver{homeDir: homeDir}
+
+	globalDir := resolver.GetGlobalVolumeDir()
+	if err := os.MkdirAll(globalDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	project, empty := t.TempDir(), t.TempDir()
+	named := filepath.Join(t.TempDir(), "oss.sparseimage")
+	resolver.names = map[string]string{
+		"oss":     named,
+		"missing": filepath.Join(empty, "missing.sparseimage"),
+		"default": resolver.GetDefaultVolumePath(),
+	}
+	for _, path := range []string{
+		resolver.GetDefaultVolumePath(),
+		filepath.Join(globalDir, "work.sparseimage"),
+		filepath.Join(globalDir, "notes.txt"),
+		resolver.GetLocalVolumePath(project),
+		named,
+	} {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := resolver.KnownVolumePaths([]string{project, empty, project})
+	want := []string{
+		resolver.GetDefaultVolumePath(),
+		filepath.Join(globalDir, "work.sparseimage"),
+		named,
+		resolver.GetLocalVolumePath(project),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("KnownVolumePaths() = %q, want %q", got, want)
+	}
+}
+
+func TestPathResolver_PrefersExistingBundle(t *testing.T) {
+	resolver := &PathResolver{homeDir: t.TempDir()}
+	project := t.TempDir()
+
+	image := resolver.GetLocalVolumePath(project)
+	bundle := WithFormat(image, FormatSparseBundle)
+	if err := os.Mkdir(bundle, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolver.GetLocalVolumePath(project); got != bundle {
+		t.Errorf("GetLocalVolumePath() with only a bundle = %q, want %q", got, bundle)
+	}
+
+	// A sparse image next to it still wins
+	if err := os.WriteFile(image, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolver.GetLocalVolumePath(project); got != image {
+		t.Errorf("GetLocalVolumePath() with both = %q, want %q", got, image)
+	}
+}
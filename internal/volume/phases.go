@@ -0,0 +1,50 @@
+package volume
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// unmountSettleTimeout bounds the wait for a detached volume's mount point to
+// disappear before it is mounted again.
+const unmountSettleTimeout = 10 * time.Second
+
+// unmountPollInterval is how often waitForUnmount checks the mount point.
+const unmountPollInterval = 50 * time.Millisecond
+
+// phaseTimer reports how long each step of a multi-step operation took.
+type phaseTimer struct {
+	report func(phase string, elapsed time.Duration)
+	last   time.Time
+}
+
+func newPhaseTimer(report func(phase string, elapsed time.Duration)) *phaseTimer {
+	return &phaseTimer{report: report, last: time.Now()}
+}
+
+// done reports the time since the previous phase ended.
+func (p *phaseTimer) done(phase string) {
+	now := time.Now()
+	if p.report != nil {
+		p.report(phase, now.Sub(p.last))
+	}
+	p.last = now
+}
+
+// waitForUnmount returns once mountPoint is gone or is an empty directory,
+// instead of sleeping a fixed time for the system to finish tearing down the
+// mount.
+func waitForUnmount(mountPoint string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, err := os.ReadDir(mountPoint)
+		if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s still exists %v after unmount", mountPoint, timeout)
+		}
+		time.Sleep(unmountPollInterval)
+	}
+}
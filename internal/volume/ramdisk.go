@@ -0,0 +1,219 @@
+package volume
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+	"github.com/jeanhaley32/claude-capsule/internal/terminal"
+)
+
+// authRAMDiskPrefix starts the mount point name of a volume's auth RAM disk.
+// It deliberately doesn't match MountPointNamePrefix, so the RAM disk is never
+// mistaken for a volume.
+const authRAMDiskPrefix = "CapsuleAuth-"
+
+// authRAMDiskSectors sizes the auth RAM disk in 512-byte sectors (32 MB),
+// enough for APFS metadata and a handful of small credential files.
+const authRAMDiskSectors = 65536
+
+// apfsContainerPattern finds the container disk in 'diskutil apfs createContainer' output,
+// e.g. "Created new APFS Container disk6".
+var apfsContainerPattern = regexp.MustCompile(`APFS Container (disk\d+)`)
+
+// authRAMDiskMountPoint returns where the auth RAM disk for the volume mounted
+// at mountPoint is mounted.
+func (m *MacOSVolumeManager) authRAMDiskMountPoint(mountPoint string) string {
+	id := strings.TrimPrefix(filepath.Base(mountPoint), constants.MountPointNamePrefix)
+	return filepath.Join(m.mountDir, authRAMDiskPrefix+id)
+}
+
+// StageAuth copies the volume's auth/ directory onto a small encrypted RAM
+// disk. If the volume already has one, it is reused as is.
+func (m *MacOSVolumeManager) StageAuth(ctx context.Context, mountPoint string) (string, error) {
+	authMount := m.authRAMDiskMountPoint(mountPoint)
+	if isMountPoint(authMount) {
+		return authMount, nil
+	}
+
+	device, err := m.createAuthRAMDisk(ctx, authMount)
+	if err != nil {
+		return "", err
+	}
+	if err := copyTree(filepath.Join(mountPoint, "auth"), authMount); err != nil {
+		m.detachRAMDisk(context.WithoutCancel(ctx), device)
+		return "", fmt.Errorf("failed to copy auth to RAM disk: %w", err)
+	}
+
+	slog.Info("auth staged on RAM disk", "mount_point", authMount, "device", device)
+	return authMount, nil
+}
+
+// createAuthRAMDisk attaches an unformatted RAM disk and formats it as an
+// encrypted APFS volume mounted at authMount, returning the RAM disk's device.
+// The passphrase is random and never stored: nothing outlives the RAM disk.
+func (m *MacOSVolumeManager) createAuthRAMDisk(ctx context.Context, authMount string) (string, error) {
+	ramCtx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ramCtx, "hdiutil", "attach", "-nomount", fmt.Sprintf("ram://%d", authRAMDiskSectors))
+	done := logging.Command(cmd)
+	output, err := cmd.Output()
+	done(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("RAM disk creation interrupted: %w", ctx.Err())
+		}
+		return "", fmt.Errorf("failed to create RAM disk: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("failed to create RAM disk: hdiutil printed no device")
+	}
+	device := fields[0]
+
+	if err := m.formatAuthRAMDisk(ramCtx, device, authMount); err != nil {
+		m.detachRAMDisk(context.WithoutCancel(ctx), device)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("RAM disk creation interrupted: %w", ctx.Err())
+		}
+		return "", err
+	}
+	if err := os.Chmod(authMount, 0700); err != nil {
+		m.detachRAMDisk(context.WithoutCancel(ctx), device)
+		return "", fmt.Errorf("failed to restrict %s: %w", authMount, err)
+	}
+	return device, nil
+}
+
+// formatAuthRAMDisk creates an APFS container on device and adds an encrypted
+// volume to it, mounted at authMount.
+func (m *MacOSVolumeManager) formatAuthRAMDisk(ctx context.Context, device, authMount string) error {
+	cmd := exec.CommandContext(ctx, "diskutil", "apfs", "createContainer", device)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to format RAM disk: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	container, err := parseAPFSContainer(string(output))
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("failed to generate RAM disk key: %w", err)
+	}
+	passphrase := terminal.NewSecurePassword([]byte(hex.EncodeToString(key)))
+	clear(key)
+	defer passphrase.Clear()
+
+	cmd = exec.CommandContext(ctx, "diskutil", "apfs", "addVolume", container, "APFS", "CapsuleAuth",
+		"-stdinpassphrase", "-mountpoint", authMount)
+	cmd.Stdin = passphrase.Reader()
+	done = logging.Command(cmd)
+	output, err = cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted RAM disk volume: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// parseAPFSContainer returns the container disk 'diskutil apfs createContainer' created.
+func parseAPFSContainer(output string) (string, error) {
+	match := apfsContainerPattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("failed to find APFS container in diskutil output: %s", strings.TrimSpace(output))
+	}
+	return match[1], nil
+}
+
+// destroyAuthRAMDisk detaches the auth RAM disk of the volume mounted at
+// mountPoint, if it has one. Detaching frees the memory, so its contents are gone.
+func (m *MacOSVolumeManager) destroyAuthRAMDisk(ctx context.Context, mountPoint string) error {
+	authMount := m.authRAMDiskMountPoint(mountPoint)
+	if !isMountPoint(authMount) {
+		return nil
+	}
+	if err := m.detachRAMDisk(ctx, authMount); err != nil {
+		return fmt.Errorf("failed to destroy auth RAM disk at %s: %w", authMount, err)
+	}
+	os.Remove(authMount)
+	slog.Info("auth RAM disk destroyed", "mount_point", authMount)
+	return nil
+}
+
+// detachRAMDisk force-detaches a RAM disk by device or mount point.
+func (m *MacOSVolumeManager) detachRAMDisk(ctx context.Context, target string) error {
+	detachCtx, cancel := context.WithTimeout(ctx, unmountTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(detachCtx, "hdiutil", "detach", "-force", target)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// isMountPoint reports whether path is the root of a mounted filesystem: its
+// device differs from its parent directory's.
+func isMountPoint(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	parent, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	parentStat, parentOK := parent.Sys().(*syscall.Stat_t)
+	return ok && parentOK && stat.Dev != parentStat.Dev
+}
+
+// copyTree copies the files under src into dst with owner-only permissions.
+// A missing src copies nothing.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == src {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0700)
+		case d.Type().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, constants.FilePermissions)
+		default:
+			// Symlinks could point back into the persistent volume
+			return nil
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAPFSContainer(t *testing.T) {
+	output := `Started APFS operation on disk5
+Creating a new empty APFS Container
+Unmounting Volumes
+Switching disk5 to APFS
+Creating APFS Container
+Created new APFS Container disk6
+Disk from APFS operation: disk6
+Finished APFS operation on disk5
+`
+	if got, err := parseAPFSContainer(output); err != nil || got != "disk6" {
+		t.Errorf("parseAPFSContainer() = %q, %v, want disk6", got, err)
+	}
+	if _, err := parseAPFSContainer("Error: -69808: Some information was unavailable"); err == nil {
+		t.Error("parseAPFSContainer(error output) succeeded, want error")
+	}
+}
+
+func TestAuthRAMDiskMountPoint(t *testing.T) {
+	m := NewMacOSVolumeManager("/Users/me/.capsule/mounts")
+	got := m.authRAMDiskMountPoint("/Users/me/.capsule/mounts/Capsule-1a2b3c4d5e6f")
+	if got != "/Users/me/.capsule/mounts/CapsuleAuth-1a2b3c4d5e6f" {
+		t.Errorf("authRAMDiskMountPoint() = %q", got)
+	}
+	// Volumes mounted by an older capsule still get their RAM disk in the mount directory
+	if got := m.authRAMDiskMountPoint("/Volumes/Capsule-1a2b3c4d"); got != "/Users/me/.capsule/mounts/CapsuleAuth-1a2b3c4d" {
+		t.Errorf("authRAMDiskMountPoint(legacy) = %q", got)
+	}
+	if m.isManagedMountPoint(got) {
+		t.Error("auth RAM disk mount point is mistaken for a volume mount point")
+	}
+	if isMountPoint(t.TempDir()) {
+		t.Error("isMountPoint(temp dir) = true, want false")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "auth")
+	dst := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "gh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "api-key"), []byte("sk-ant-test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "gh", "hosts.yml"), []byte("github.com: {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("copyTree() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "api-key"))
+	if err != nil || string(data) != "sk-ant-test" {
+		t.Errorf("api-key = %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "gh", "hosts.yml")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("gh/hosts.yml = %v, %v, want mode 0600", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "link")); !os.IsNotExist(err) {
+		t.Errorf("symlink was copied: %v", err)
+	}
+
+	// A volume without an auth directory stages nothing
+	if err := copyTree(filepath.Join(t.TempDir(), "missing"), dst); err != nil {
+		t.Errorf("copyTree(missing) error = %v", err)
+	}
+}
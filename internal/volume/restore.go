@@ -0,0 +1,71 @@
+package volume
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// RestoreImage copies a backed-up volume image to volumePath, along with the
+// portable sidecars kept next to it, if any: without its key stretching
+// parameters a stretched volume cannot be unlocked. Each file is copied
+// to a temporary name first, so an interrupted copy leaves nothing at
+// volumePath.
+func RestoreImage(backupPath, volumePath string) error {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("backup %s is not a volume image file; restoring sparse bundles is not supported", backupPath)
+	}
+	if _, err := os.Stat(volumePath); err == nil {
+		return fmt.Errorf("volume already exists at %s", volumePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(volumePath), constants.DirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(volumePath), err)
+	}
+
+	// The sidecars go first, so the image never appears without them
+	for _, suffix := range PortableSidecarSuffixes {
+		if _, err := os.Stat(backupPath + suffix); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(backupPath+suffix, volumePath+suffix); err != nil {
+			return err
+		}
+	}
+	return copyFile(backupPath, volumePath)
+}
+
+// copyFile copies src to dst through a temporary file in dst's directory.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.FilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
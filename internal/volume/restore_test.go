@@ -0,0 +1,40 @@
+package volume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeanhaley32/claude-capsule/internal/kdf"
+	"github.com/jeanhaley32/claude-capsule/internal/manifest"
+)
+
+func TestRestoreImage(t *testing.T) {
+	backupDir := t.TempDir()
+	backup := filepath.Join(backupDir, "capsule.sparseimage")
+	for path, content := range map[string]string{backup: "image", kdf.Path(backup): "params"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	volumePath := filepath.Join(t.TempDir(), "volumes", "capsule.sparseimage")
+	if err := RestoreImage(backup, volumePath); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{volumePath: "image", kdf.Path(volumePath): "params"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(path), data, err, want)
+		}
+	}
+	if _, err := os.Stat(manifest.Path(volumePath)); !os.IsNotExist(err) {
+		t.Errorf("RestoreImage() created a manifest the backup didn't have: %v", err)
+	}
+
+	if err := RestoreImage(backup, volumePath); err == nil {
+		t.Error("RestoreImage() over an existing volume succeeded, want error")
+	}
+	if err := RestoreImage(backupDir, filepath.Join(t.TempDir(), "other.sparseimage")); err == nil {
+		t.Error("RestoreImage() of a directory succeeded, want error")
+	}
+}
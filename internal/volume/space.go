@@ -0,0 +1,109 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+	"github.com/jeanhaley32/claude-capsule/internal/logging"
+)
+
+// SpaceCheckInterval is how often a session checks the volume's free space.
+const SpaceCheckInterval = 30 * time.Second
+
+// FreePercent returns free space as a whole-number percentage of the volume.
+func (c Capacity) FreePercent() int {
+	if c.TotalBytes <= 0 {
+		return 0
+	}
+	return int(c.FreeBytes * 100 / c.TotalBytes)
+}
+
+// SpaceWatch polls the free space of a mounted volume, so a session can act
+// before the volume fills up and writes fail with ENOSPC.
+type SpaceWatch struct {
+	MountPoint string
+	LowPercent int // Free space percentage below which OnLow runs
+	Interval   time.Duration
+
+	// OnLow runs when free space drops below LowPercent. It runs again only
+	// after free space has recovered, e.g. because the volume was expanded.
+	OnLow func(Capacity)
+
+	capacity func(string) (Capacity, error) // Replaced in tests
+}
+
+// Run checks free space every Interval until ctx is canceled.
+func (w *SpaceWatch) Run(ctx context.Context) {
+	capacity := w.capacity
+	if capacity == nil {
+		capacity = FilesystemCapacity
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = SpaceCheckInterval
+	}
+
+	low := false
+	check := func() {
+		c, err := capacity(w.MountPoint)
+		if err != nil {
+			slog.Debug("volume space check failed", "mount_point", w.MountPoint, "error", err)
+			return
+		}
+		isLow := c.FreePercent() < w.LowPercent
+		if isLow && !low {
+			slog.Warn("volume low on space", "mount_point", w.MountPoint, "free_bytes", c.FreeBytes, "total_bytes", c.TotalBytes)
+			w.OnLow(c)
+		}
+		low = isLow
+	}
+
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// ExpandedSizeGB returns the size to grow a volume of totalBytes to: double
+// its size, capped at maxGB and constants.MaxVolumeSizeGB. It returns 0 if
+// the volume can't grow any further.
+func ExpandedSizeGB(totalBytes int64, maxGB int) int {
+	currentGB := int((totalBytes + (1 << 30) - 1) >> 30)
+	next := min(2*currentGB, maxGB, constants.MaxVolumeSizeGB)
+	if next <= currentGB {
+		return 0
+	}
+	return next
+}
+
+// Resize grows the image at volumePath, and the filesystem inside it, to sizeGB.
+func (m *MacOSVolumeManager) Resize(ctx context.Context, volumePath string, sizeGB int) error {
+	resizeCtx, cancel := context.WithTimeout(ctx, volumeOperationTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(resizeCtx, "hdiutil", "resize", "-size", fmt.Sprintf("%dg", sizeGB), volumePath)
+	done := logging.Command(cmd)
+	output, err := cmd.CombinedOutput()
+	done(err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("volume resize interrupted: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to resize volume to %d GB: %w: %s", sizeGB, err, strings.TrimSpace(string(output)))
+	}
+	m.mounts.invalidate()
+	slog.Info("volume resized", "volume", volumePath, "size_gb", sizeGB)
+	return nil
+}
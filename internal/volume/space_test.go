@@ -0,0 +1,56 @@
+package volume
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSpaceWatch(t *testing.T) {
+	// Free space per check: fine, low, still low, recovered, low again
+	free := []int64{50, 5, 4, 60, 8}
+	checks := 0
+	var lows []int64
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := &SpaceWatch{
+		MountPoint: "/mnt",
+		LowPercent: 10,
+		Interval:   time.Millisecond,
+		OnLow:      func(c Capacity) { lows = append(lows, c.FreeBytes) },
+		capacity: func(string) (Capacity, error) {
+			c := Capacity{TotalBytes: 100, FreeBytes: free[checks]}
+			checks++
+			if checks == len(free) {
+				cancel()
+			}
+			return c, nil
+		},
+	}
+	w.Run(ctx)
+
+	if len(lows) != 2 || lows[0] != 5 || lows[1] != 8 {
+		t.Errorf("OnLow saw %v, want [5 8]: once per drop below the threshold", lows)
+	}
+}
+
+func TestExpandedSizeGB(t *testing.T) {
+	const gb = 1 << 30
+	tests := []struct {
+		totalBytes int64
+		maxGB      int
+		want       int
+	}{
+		{2 * gb, 10, 4},
+		{2*gb - 4096, 10, 4}, // filesystem overhead rounds up to the image size
+		{8 * gb, 10, 10},
+		{10 * gb, 10, 0},
+		{80 * gb, 500, 100},
+	}
+	for _, tt := range tests {
+		if got := ExpandedSizeGB(tt.totalBytes, tt.maxGB); got != tt.want {
+			t.Errorf("ExpandedSizeGB(%d, %d) = %d, want %d", tt.totalBytes, tt.maxGB, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package volume
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Capacity is the size of a mounted volume's filesystem.
+type Capacity struct {
+	TotalBytes int64 `json:"total_bytes"`
+	UsedBytes  int64 `json:"used_bytes"`
+	FreeBytes  int64 `json:"free_bytes"` // Available to unprivileged users
+}
+
+// ImageSize returns the space the image at volumePath takes on the host disk.
+// Sparse images only allocate what the volume has written, so this is
+// usually far below the volume's capacity. Sparse bundles are summed over
+// their band files.
+func ImageSize(volumePath string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(volumePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += allocatedBytes(info)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", volumePath, err)
+	}
+	return total, nil
+}
+
+// allocatedBytes returns the disk space a file occupies, which for sparse
+// files is less than its length.
+func allocatedBytes(info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Blocks) * 512
+	}
+	return info.Size()
+}
+
+// FilesystemCapacity returns the capacity of the filesystem mounted at mountPoint.
+func FilesystemCapacity(mountPoint string) (Capacity, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &st); err != nil {
+		return Capacity{}, fmt.Errorf("failed to read filesystem size of %s: %w", mountPoint, err)
+	}
+	blockSize := int64(st.Bsize)
+	return Capacity{
+		TotalBytes: int64(st.Blocks) * blockSize,
+		UsedBytes:  int64(st.Blocks-st.Bfree) * blockSize,
+		FreeBytes:  int64(st.Bavail) * blockSize,
+	}, nil
+}
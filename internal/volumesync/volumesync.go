@@ -0,0 +1,179 @@
+// Package volumesync keeps a volume image in step between two machines over
+// SSH, e.g. a desktop and a laptop. rsync's delta transfer sends only the
+// blocks of the image that changed, so a sync after a day's work moves
+// megabytes rather than the whole image.
+//
+// Both copies must be locked while they sync. After each sync, both machines
+// record the image's modification time and size in a .last-sync file next to
+// it; the next sync compares each side against that record to decide which
+// way to copy, and refuses when both sides changed.
+package volumesync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jeanhaley32/claude-capsule/internal/constants"
+)
+
+// SyncedSuffix is appended to the image path to name the file recording the
+// image as it was after the last sync.
+const SyncedSuffix = ".last-sync"
+
+// SyncedPath returns the last-sync file for a volume image.
+func SyncedPath(volumePath string) string {
+	return volumePath + SyncedSuffix
+}
+
+// Stamp identifies a version of an image by its modification time, in Unix
+// seconds as rsync preserves it, and size.
+type Stamp struct {
+	ModTime int64
+	Size    int64
+}
+
+// String formats the stamp as "mtime size", as ParseStamp reads it.
+func (s Stamp) String() string {
+	return fmt.Sprintf("%d %d", s.ModTime, s.Size)
+}
+
+// ParseStamp parses "mtime size", as written by String and by the macOS
+// 'stat -f "%m %z"'.
+func ParseStamp(value string) (Stamp, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return Stamp{}, fmt.Errorf("invalid image stamp %q", value)
+	}
+	modTime, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Stamp{}, fmt.Errorf("invalid image stamp %q: %w", value, err)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return Stamp{}, fmt.Errorf("invalid image stamp %q: %w", value, err)
+	}
+	return Stamp{ModTime: modTime, Size: size}, nil
+}
+
+// LocalStamp returns the stamp of the image at volumePath.
+func LocalStamp(volumePath string) (Stamp, error) {
+	info, err := os.Stat(volumePath)
+	if err != nil {
+		return Stamp{}, fmt.Errorf("failed to read volume: %w", err)
+	}
+	return Stamp{ModTime: info.ModTime().Unix(), Size: info.Size()}, nil
+}
+
+// ReadSynced returns the stamp recorded after the last sync of volumePath,
+// or nil if it was never synced.
+func ReadSynced(volumePath string) (*Stamp, error) {
+	data, err := os.ReadFile(SyncedPath(volumePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", SyncedPath(volumePath), err)
+	}
+	stamp, err := ParseStamp(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SyncedPath(volumePath), err)
+	}
+	return &stamp, nil
+}
+
+// WriteSynced records stamp as the image after a sync.
+func WriteSynced(volumePath string, stamp Stamp) error {
+	if err := os.WriteFile(SyncedPath(volumePath), []byte(stamp.String()+"\n"), constants.FilePermissions); err != nil {
+		return fmt.Errorf("failed to record sync: %w", err)
+	}
+	return nil
+}
+
+// Direction is which way a sync copies the image.
+type Direction string
+
+const (
+	// None means both sides already match.
+	None Direction = "none"
+
+	// Push copies this machine's image to the peer.
+	Push Direction = "push"
+
+	// Pull copies the peer's image to this machine.
+	Pull Direction = "pull"
+)
+
+// ConflictError reports that both images changed since the last sync, or
+// that they differ and were never synced, so either copy would lose work.
+type ConflictError struct {
+	NeverSynced bool
+}
+
+func (e *ConflictError) Error() string {
+	if e.NeverSynced {
+		return "the two images differ and have never been synced; choose which one to keep with --push or --pull"
+	}
+	return "both images changed since the last sync; choose which one to keep with --push or --pull (the other side's changes are lost)"
+}
+
+// Decide returns which way to sync, given each side's stamp and the one
+// recorded after the last sync. A nil stamp means that side has no image.
+func Decide(local, peer, synced *Stamp) (Direction, error) {
+	switch {
+	case local == nil && peer == nil:
+		return "", errors.New("neither machine has the volume")
+	case peer == nil:
+		return Push, nil
+	case local == nil:
+		return Pull, nil
+	}
+	if *local == *peer {
+		return None, nil
+	}
+	if synced == nil {
+		return "", &ConflictError{NeverSynced: true}
+	}
+	localChanged, peerChanged := *local != *synced, *peer != *synced
+	switch {
+	case localChanged && !peerChanged:
+		return Push, nil
+	case peerChanged && !localChanged:
+		return Pull, nil
+	default:
+		return "", &ConflictError{}
+	}
+}
+
+// RsyncArgs returns the rsync command that copies src to dst, one of which
+// is a host:path. --no-whole-file forces the delta transfer, which rsync
+// otherwise skips for local copies; without --inplace the destination is
+// replaced only once the copy is complete, so an interrupted sync leaves it
+// as it was.
+func RsyncArgs(src, dst string) []string {
+	return []string{"rsync", "--times", "--no-whole-file", "--progress", "-e", "ssh", src, dst}
+}
+
+// Quote quotes s for the peer's POSIX shell.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// PeerStampScript prints the stamp of the image at path on a macOS peer, or
+// nothing if there is none.
+func PeerStampScript(path string) string {
+	q := Quote(path)
+	return fmt.Sprintf("if [ -e %s ]; then stat -f '%%m %%z' %s; fi", q, q)
+}
+
+// PeerExistingScript prints which of paths exist on the peer, one per line.
+func PeerExistingScript(paths []string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "[ -e %[1]s ] && echo %[1]s; ", Quote(path))
+	}
+	b.WriteString("true")
+	return b.String()
+}
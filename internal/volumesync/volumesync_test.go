@@ -0,0 +1,67 @@
+package volumesync
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecide(t *testing.T) {
+	base := &Stamp{ModTime: 100, Size: 1 << 30}
+	local := &Stamp{ModTime: 200, Size: 1 << 30}
+	peer := &Stamp{ModTime: 300, Size: 1 << 30}
+	tests := []struct {
+		name                string
+		local, peer, synced *Stamp
+		want                Direction
+		conflict            bool
+	}{
+		{name: "peer has none", local: local, want: Push},
+		{name: "local has none", peer: peer, want: Pull},
+		{name: "same", local: base, peer: base, want: None},
+		{name: "same without record", local: local, peer: local, want: None},
+		{name: "local changed", local: local, peer: base, synced: base, want: Push},
+		{name: "peer changed", local: base, peer: peer, synced: base, want: Pull},
+		{name: "both changed", local: local, peer: peer, synced: base, conflict: true},
+		{name: "never synced", local: local, peer: peer, conflict: true},
+	}
+	for _, tt := range tests {
+		got, err := Decide(tt.local, tt.peer, tt.synced)
+		var conflict *ConflictError
+		if tt.conflict != errors.As(err, &conflict) || got != tt.want {
+			t.Errorf("%s: Decide() = %q, %v; want %q (conflict %v)", tt.name, got, err, tt.want, tt.conflict)
+		}
+	}
+	if _, err := Decide(nil, nil, nil); err == nil {
+		t.Error("Decide() without either image succeeded")
+	}
+}
+
+func TestSyncedRoundTrip(t *testing.T) {
+	volumePath := filepath.Join(t.TempDir(), "capsule.sparseimage")
+	if got, err := ReadSynced(volumePath); err != nil || got != nil {
+		t.Errorf("ReadSynced() before any sync = %v, %v", got, err)
+	}
+	want := Stamp{ModTime: 1760600000, Size: 2147483648}
+	if err := WriteSynced(volumePath, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadSynced(volumePath); err != nil || got == nil || *got != want {
+		t.Errorf("ReadSynced() = %v, %v; want %v", got, err, want)
+	}
+	if _, err := ParseStamp("1760600000"); err == nil {
+		t.Error("ParseStamp() without a size succeeded")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	path := "/Users/o'neil/my volumes/capsule.sparseimage"
+	out, err := exec.Command("sh", "-c", "printf %s "+Quote(path)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != path {
+		t.Errorf("sh read Quote(%q) as %q", path, out)
+	}
+}